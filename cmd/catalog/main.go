@@ -0,0 +1,144 @@
+// Command catalog imports and exports the product catalog from the command
+// line, using the same file formats as the admin import and export routes.
+//
+//	catalog import [-format csv|jsonl] [-dry-run] FILE
+//	catalog export [-format csv|jsonl] [FILE]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/config"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/storage"
+)
+
+const usage = `usage:
+  catalog import [-format csv|jsonl] [-dry-run] FILE
+  catalog export [-format csv|jsonl] [FILE]`
+
+func main() {
+	log.SetFlags(log.Ldate | log.Lshortfile)
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	db, err := storage.NewPostgresDB(config.Env.PostgresConnStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	productStore := product.NewStore(db)
+	// uploaded files are only kept in a blob store for background jobs, the
+	// command line imports synchronously
+	importer := product.NewImporter(product.NewService(productStore), productStore, nil)
+
+	switch os.Args[1] {
+	case "import":
+		os.Exit(runImport(importer, os.Args[2:]))
+	case "export":
+		os.Exit(runExport(importer, os.Args[2:]))
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+type catalogImporter interface {
+	Import(ctx context.Context, r io.Reader, format string, dryRun bool) (*product.ImportReport, error)
+	Export(ctx context.Context, w io.Writer, format string) error
+}
+
+// runImport prints the import report and fails when any row was rejected.
+func runImport(importer catalogImporter, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "file format, csv or jsonl, taken from the file extension by default")
+	dryRun := flags.Bool("dry-run", false, "only validate the rows")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	defer file.Close()
+
+	report, err := importer.Import(context.Background(), file, *format, *dryRun)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	if report.Failed > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// runExport writes the catalog to FILE, or to stdout without one.
+func runExport(importer catalogImporter, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "", "file format, csv or jsonl, taken from the file extension by default")
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	var out io.Writer = os.Stdout
+	if flags.NArg() == 1 {
+		path := flags.Arg(0)
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(path), ".")
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *format == "" {
+		*format = product.FormatCSV
+	}
+
+	if err := importer.Export(context.Background(), out, *format); err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	return 0
+}
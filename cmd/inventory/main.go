@@ -0,0 +1,80 @@
+// Command inventory runs stock maintenance tasks from the command line.
+//
+//	inventory reconcile
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/config"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/inventory"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/storage"
+)
+
+const usage = `usage:
+  inventory reconcile`
+
+func main() {
+	log.SetFlags(log.Ldate | log.Lshortfile)
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	db, err := storage.NewPostgresDB(config.Env.PostgresConnStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	inventoryService := inventory.NewService(inventory.NewStore(db))
+
+	switch os.Args[1] {
+	case "reconcile":
+		os.Exit(runReconcile(inventoryService, os.Args[2:]))
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+type reconciler interface {
+	Reconcile(ctx context.Context) (*inventory.Reconciliation, error)
+}
+
+// runReconcile prints the stock that drifted from the ledger of movements
+// and fails when there is any, so it can run as a scheduled check.
+func runReconcile(reconciler reconciler, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	reconciliation, err := reconciler.Reconcile(context.Background())
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(reconciliation); err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	if len(reconciliation.Drift) > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -6,8 +6,10 @@ import (
 
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/cmd/server"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/config"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/storage"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn"
 )
 
 var (
@@ -17,6 +19,45 @@ var (
 	refreshTokenSecret       = config.Env.RefreshTokenSecret
 	accessTokenExpiryInSecs  = config.Env.AccessTokenExpiryInSecs
 	refreshTokenExpiryInSecs = config.Env.RefreshTokenExpiryInSecs
+	entityTokenLifetimes     = map[string]auth.TokenLifetimes{
+		auth.EntityTypeUser: {
+			AccessTokenExpiryInSecs:  config.Env.UserAccessTokenExpiryInSecs,
+			RefreshTokenExpiryInSecs: config.Env.UserRefreshTokenExpiryInSecs,
+			SessionIdleTimeoutInSecs: config.Env.UserSessionIdleTimeoutInSecs,
+			SessionMaxAgeInSecs:      config.Env.UserSessionMaxAgeInSecs,
+		},
+		auth.EntityTypeAdmin: {
+			AccessTokenExpiryInSecs:  config.Env.AdminAccessTokenExpiryInSecs,
+			RefreshTokenExpiryInSecs: config.Env.AdminRefreshTokenExpiryInSecs,
+			SessionIdleTimeoutInSecs: config.Env.AdminSessionIdleTimeoutInSecs,
+			SessionMaxAgeInSecs:      config.Env.AdminSessionMaxAgeInSecs,
+		},
+		auth.EntityTypeSeller: {
+			AccessTokenExpiryInSecs:  config.Env.SellerAccessTokenExpiryInSecs,
+			RefreshTokenExpiryInSecs: config.Env.SellerRefreshTokenExpiryInSecs,
+			SessionIdleTimeoutInSecs: config.Env.SellerSessionIdleTimeoutInSecs,
+			SessionMaxAgeInSecs:      config.Env.SellerSessionMaxAgeInSecs,
+		},
+	}
+	webAuthnRPID      = config.Env.WebAuthnRPID
+	webAuthnRPName    = config.Env.WebAuthnRPName
+	webAuthnRPOrigins = config.Env.WebAuthnRPOrigins
+	network           = server.NetworkConfig{
+		TrustedProxies:    config.Env.TrustedProxies,
+		AdminAllowedCIDRs: config.Env.AdminAllowedCIDRs,
+		AdminMTLS: server.MTLSConfig{
+			Port:         config.Env.AdminMTLSPort,
+			CertFile:     config.Env.AdminMTLSCertFile,
+			KeyFile:      config.Env.AdminMTLSKeyFile,
+			ClientCAFile: config.Env.AdminMTLSClientCAFile,
+		},
+	}
+	blobStoreDir = config.Env.BlobStoreDir
+	downloads    = server.DownloadConfig{
+		URLSecret:  config.Env.DownloadURLSecret,
+		BaseURL:    config.Env.DownloadBaseURL,
+		LinkInSecs: config.Env.DownloadLinkInSecs,
+	}
 )
 
 func main() {
@@ -27,6 +68,11 @@ func main() {
 		log.Fatal(err)
 	}
 
+	blobStore, err := blobstore.NewLocalStore(blobStoreDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	srv := server.NewServer(
 		srvAddr,
 		db,
@@ -35,7 +81,16 @@ func main() {
 			refreshTokenSecret,
 			accessTokenExpiryInSecs,
 			refreshTokenExpiryInSecs,
+			entityTokenLifetimes,
+		),
+		webauthn.NewRelyingParty(
+			webAuthnRPID,
+			webAuthnRPName,
+			webAuthnRPOrigins,
 		),
+		network,
+		blobStore,
+		downloads,
 	)
 	if err := srv.Start(); err != nil {
 		log.Fatal(fmt.Errorf("failed to start server: %w", err))
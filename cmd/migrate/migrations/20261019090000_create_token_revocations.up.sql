@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    token_id VARCHAR(64) PRIMARY KEY,
+    entity_id UUID NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires_at ON revoked_access_tokens(expires_at);
+
+CREATE TABLE IF NOT EXISTS entity_token_revocations (
+    entity_id UUID NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    revoked_before TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (entity_id, entity_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_token_revocations_expires_at ON entity_token_revocations(expires_at);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    credential_id BYTEA PRIMARY KEY,
+    entity_id UUID NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    name VARCHAR(64) NOT NULL DEFAULT '',
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    aaguid BYTEA NOT NULL,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_entity ON webauthn_credentials(entity_id, entity_type);
+
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+    challenge_id UUID PRIMARY KEY,
+    challenge BYTEA NOT NULL,
+    ceremony VARCHAR(20) NOT NULL,
+    entity_id UUID,
+    entity_type VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_challenges_expires_at ON webauthn_challenges(expires_at);
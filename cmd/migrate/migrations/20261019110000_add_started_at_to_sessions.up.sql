@@ -0,0 +1,4 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- existing sessions are treated as starting at their creation
+UPDATE sessions SET started_at = created_at;
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS auth_methods TEXT[] NOT NULL DEFAULT '{pwd}';
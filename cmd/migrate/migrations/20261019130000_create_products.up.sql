@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS products (
+    product_id UUID PRIMARY KEY,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price BIGINT NOT NULL CHECK (price >= 0),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'active', 'archived')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_products_status_price ON products(status, price);
+CREATE INDEX IF NOT EXISTS idx_products_status_created_at ON products(status, created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS variant_stock;
+DROP TABLE IF EXISTS product_variants;
+DROP TABLE IF EXISTS product_options;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS product_options (
+    option_id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    option_values TEXT[] NOT NULL,
+    position INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    variant_id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    option_values JSONB NOT NULL,
+    -- normalised option values, one variant per combination
+    combination_key TEXT NOT NULL,
+    price_override BIGINT CHECK (price_override >= 0),
+    barcode VARCHAR(14),
+    weight_grams BIGINT CHECK (weight_grams >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, combination_key)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_barcode ON product_variants(barcode) WHERE barcode IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS variant_stock (
+    variant_id UUID PRIMARY KEY REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
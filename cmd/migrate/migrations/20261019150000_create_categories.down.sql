@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_categories;
+DROP TABLE IF EXISTS category_closure;
+DROP TABLE IF EXISTS categories;
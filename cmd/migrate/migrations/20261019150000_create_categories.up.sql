@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS categories (
+    category_id UUID PRIMARY KEY,
+    parent_id UUID REFERENCES categories(category_id) ON DELETE RESTRICT,
+    name VARCHAR(100) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- sibling names are unique, root categories share the nil uuid as parent
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_parent_name ON categories(COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'), LOWER(name));
+
+-- every ancestor/descendant pair including each category with itself at depth 0
+CREATE TABLE IF NOT EXISTS category_closure (
+    ancestor_id UUID NOT NULL REFERENCES categories(category_id) ON DELETE CASCADE,
+    descendant_id UUID NOT NULL REFERENCES categories(category_id) ON DELETE CASCADE,
+    depth INT NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_closure_descendant ON category_closure(descendant_id);
+
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES categories(category_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_categories_category ON product_categories(category_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_products_brand;
+DROP INDEX IF EXISTS idx_products_name_trgm;
+DROP INDEX IF EXISTS idx_products_search_vector;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS search_vector,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS brand;
+
+DROP EXTENSION IF EXISTS pg_trgm;
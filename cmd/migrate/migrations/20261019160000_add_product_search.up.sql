@@ -0,0 +1,18 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS brand VARCHAR(128) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS language REGCONFIG NOT NULL DEFAULT 'english';
+
+-- the name outweighs the brand which outweighs the description, each stemmed
+-- with the product's own language
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector(language, name), 'A') ||
+        setweight(to_tsvector(language, brand), 'B') ||
+        setweight(to_tsvector(language, description), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN(search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN(name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_brand ON products(brand);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS media_thumbnails;
+DROP TABLE IF EXISTS product_media;
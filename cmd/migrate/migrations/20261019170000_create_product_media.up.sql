@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS product_media (
+    media_id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    variant_id UUID REFERENCES product_variants(variant_id) ON DELETE SET NULL,
+    blob_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    width INT NOT NULL CHECK (width > 0),
+    height INT NOT NULL CHECK (height > 0),
+    alt_text VARCHAR(255) NOT NULL DEFAULT '',
+    position INT NOT NULL,
+    thumbnail_status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (thumbnail_status IN ('pending', 'ready', 'failed')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_media_product_id_position ON product_media(product_id, position);
+CREATE INDEX IF NOT EXISTS idx_product_media_pending ON product_media(created_at) WHERE thumbnail_status = 'pending';
+
+CREATE TABLE IF NOT EXISTS media_thumbnails (
+    media_id UUID NOT NULL REFERENCES product_media(media_id) ON DELETE CASCADE,
+    size VARCHAR(20) NOT NULL,
+    blob_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    PRIMARY KEY (media_id, size)
+);
@@ -0,0 +1,9 @@
+-- explicit prices of a product in currencies other than its base currency,
+-- in minor units of that currency
+CREATE TABLE IF NOT EXISTS product_prices (
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, currency)
+);
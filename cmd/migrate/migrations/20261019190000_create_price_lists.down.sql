@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS price_list_entries;
+DROP TABLE IF EXISTS price_lists;
+DROP TABLE IF EXISTS customer_group_members;
+DROP TABLE IF EXISTS customer_groups;
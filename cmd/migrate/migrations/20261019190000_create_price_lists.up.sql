@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS customer_groups (
+    group_id UUID PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_groups_name ON customer_groups(LOWER(name));
+
+CREATE TABLE IF NOT EXISTS customer_group_members (
+    group_id UUID NOT NULL REFERENCES customer_groups(group_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_group_members_user_id ON customer_group_members(user_id);
+
+-- a price list without a group applies to everyone, including guests
+CREATE TABLE IF NOT EXISTS price_lists (
+    price_list_id UUID PRIMARY KEY,
+    name VARCHAR(128) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    group_id UUID REFERENCES customer_groups(group_id) ON DELETE CASCADE,
+    priority INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+-- an entry without a variant applies to every variant of the product, the
+-- min quantity makes it a quantity break tier
+CREATE TABLE IF NOT EXISTS price_list_entries (
+    price_list_id UUID NOT NULL REFERENCES price_lists(price_list_id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    variant_id UUID REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    min_quantity BIGINT NOT NULL DEFAULT 1 CHECK (min_quantity >= 1),
+    amount BIGINT NOT NULL CHECK (amount >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_list_entries_tier ON price_list_entries(
+    price_list_id,
+    product_id,
+    COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'::uuid),
+    min_quantity
+);
+CREATE INDEX IF NOT EXISTS idx_price_list_entries_product_id ON price_list_entries(product_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_rating_summaries;
+DROP TABLE IF EXISTS review_votes;
+DROP TABLE IF EXISTS product_reviews;
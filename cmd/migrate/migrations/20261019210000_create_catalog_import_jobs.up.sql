@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS catalog_import_jobs (
+    job_id UUID PRIMARY KEY,
+    format VARCHAR(8) NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    -- the uploaded file is kept in the blob store until the job is done
+    blob_key VARCHAR(255) NOT NULL,
+    total_rows INT NOT NULL DEFAULT 0,
+    processed_rows INT NOT NULL DEFAULT 0,
+    report JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_import_jobs_status_created_at ON catalog_import_jobs(status, created_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_products_attributes;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS attributes;
+
+DROP TABLE IF EXISTS category_attributes;
@@ -0,0 +1,25 @@
+-- attributes defined on a category apply to its products and to those of
+-- every category below it
+CREATE TABLE IF NOT EXISTS category_attributes (
+    attribute_id UUID PRIMARY KEY,
+    category_id UUID NOT NULL REFERENCES categories(category_id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL CHECK (code ~ '^[a-z][a-z0-9_]*$'),
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('text', 'number', 'enum', 'boolean')),
+    unit VARCHAR(20) NOT NULL DEFAULT '',
+    enum_values TEXT[] NOT NULL DEFAULT '{}',
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    filterable BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (category_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_attributes_code ON category_attributes(code);
+
+-- attribute values keyed by attribute code
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN(attributes);
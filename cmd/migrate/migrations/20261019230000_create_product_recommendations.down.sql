@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recommendation_overrides;
+DROP TABLE IF EXISTS product_co_purchases;
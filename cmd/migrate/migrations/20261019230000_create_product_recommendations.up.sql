@@ -0,0 +1,24 @@
+-- item to item co-purchase scores, recomputed as a whole by the batch job
+CREATE TABLE IF NOT EXISTS product_co_purchases (
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    related_product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    co_purchases INT NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, related_product_id),
+    CHECK (product_id <> related_product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_co_purchases_score ON product_co_purchases(product_id, score DESC);
+
+-- admin overrides, pinned products are recommended first in position order
+-- and excluded ones never
+CREATE TABLE IF NOT EXISTS recommendation_overrides (
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    related_product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('pin', 'exclude')),
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, related_product_id),
+    CHECK (product_id <> related_product_id)
+);
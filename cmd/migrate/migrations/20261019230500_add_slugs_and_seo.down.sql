@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS category_slugs;
+DROP TABLE IF EXISTS product_slugs;
+
+ALTER TABLE categories
+    DROP COLUMN IF EXISTS canonical_url,
+    DROP COLUMN IF EXISTS seo_description,
+    DROP COLUMN IF EXISTS seo_title;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS canonical_url,
+    DROP COLUMN IF EXISTS seo_description,
+    DROP COLUMN IF EXISTS seo_title;
@@ -0,0 +1,70 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS seo_title VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS seo_description VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS canonical_url VARCHAR(2048) NOT NULL DEFAULT '';
+
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS seo_title VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS seo_description VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS canonical_url VARCHAR(2048) NOT NULL DEFAULT '';
+
+-- every slug a product has had per locale, the current one and the previous
+-- ones kept to redirect from, a slug stays with its product for good
+CREATE TABLE IF NOT EXISTS product_slugs (
+    locale VARCHAR(8) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    is_current BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (locale, slug)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_slugs_current ON product_slugs(product_id, locale) WHERE is_current;
+
+CREATE TABLE IF NOT EXISTS category_slugs (
+    locale VARCHAR(8) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    category_id UUID NOT NULL REFERENCES categories(category_id) ON DELETE CASCADE,
+    is_current BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (locale, slug)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_category_slugs_current ON category_slugs(category_id, locale) WHERE is_current;
+
+-- existing products and categories get an english slug from their name, the
+-- ones sharing a name are numbered in creation order and the few a number
+-- collides with take their id instead
+INSERT INTO product_slugs(locale, slug, product_id)
+SELECT 'en', CASE WHEN n = 1 THEN base ELSE base || '-' || n END, product_id
+FROM (
+    SELECT product_id, base, ROW_NUMBER() OVER (PARTITION BY base ORDER BY created_at, product_id) AS n
+    FROM (
+        SELECT product_id, created_at, COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(REGEXP_REPLACE(LOWER(name), '[^a-z0-9]+', '-', 'g'), 200)), ''), 'product') AS base
+        FROM products
+    ) named
+) numbered
+ON CONFLICT DO NOTHING;
+
+INSERT INTO product_slugs(locale, slug, product_id)
+SELECT 'en', COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(REGEXP_REPLACE(LOWER(name), '[^a-z0-9]+', '-', 'g'), 200)), ''), 'product') || '-' || LEFT(product_id::text, 8), product_id
+FROM products
+WHERE NOT EXISTS(SELECT 1 FROM product_slugs s WHERE s.product_id = products.product_id)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO category_slugs(locale, slug, category_id)
+SELECT 'en', CASE WHEN n = 1 THEN base ELSE base || '-' || n END, category_id
+FROM (
+    SELECT category_id, base, ROW_NUMBER() OVER (PARTITION BY base ORDER BY created_at, category_id) AS n
+    FROM (
+        SELECT category_id, created_at, COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(REGEXP_REPLACE(LOWER(name), '[^a-z0-9]+', '-', 'g'), 200)), ''), 'category') AS base
+        FROM categories
+    ) named
+) numbered
+ON CONFLICT DO NOTHING;
+
+INSERT INTO category_slugs(locale, slug, category_id)
+SELECT 'en', COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(REGEXP_REPLACE(LOWER(name), '[^a-z0-9]+', '-', 'g'), 200)), ''), 'category') || '-' || LEFT(category_id::text, 8), category_id
+FROM categories
+WHERE NOT EXISTS(SELECT 1 FROM category_slugs s WHERE s.category_id = categories.category_id)
+ON CONFLICT DO NOTHING;
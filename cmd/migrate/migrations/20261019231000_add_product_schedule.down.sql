@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_products_unpublish_at;
+DROP INDEX IF EXISTS idx_products_publish_at;
+
+UPDATE products SET status = 'draft' WHERE status = 'scheduled';
+
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS products_schedule_check,
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_status_check;
+ALTER TABLE products ADD CONSTRAINT products_status_check CHECK (status IN ('draft', 'active', 'archived'));
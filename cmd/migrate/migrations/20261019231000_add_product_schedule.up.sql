@@ -0,0 +1,12 @@
+-- scheduled products go live at publish_at, active ones are archived at
+-- unpublish_at, the scheduler clears each time once it has acted on it
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_status_check;
+ALTER TABLE products ADD CONSTRAINT products_status_check CHECK (status IN ('draft', 'scheduled', 'active', 'archived'));
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ,
+    ADD CONSTRAINT products_schedule_check CHECK (publish_at IS NULL OR unpublish_at IS NULL OR publish_at < unpublish_at);
+
+CREATE INDEX IF NOT EXISTS idx_products_publish_at ON products(publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_products_unpublish_at ON products(unpublish_at) WHERE status = 'active';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS license_keys;
+DROP TABLE IF EXISTS digital_entitlements;
+DROP TABLE IF EXISTS digital_files;
+
+ALTER TABLE products DROP COLUMN IF EXISTS product_type;
@@ -0,0 +1,47 @@
+-- digital products are downloaded instead of shipped
+ALTER TABLE products ADD COLUMN IF NOT EXISTS product_type VARCHAR(20) NOT NULL DEFAULT 'physical' CHECK (product_type IN ('physical', 'digital'));
+
+CREATE TABLE IF NOT EXISTS digital_files (
+    file_id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    blob_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(127) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_digital_files_product_id ON digital_files(product_id, created_at);
+
+-- an entitlement lets the buyer of a digital product download its files a
+-- limited number of times until it expires, there is one per order line so
+-- fulfilling an order twice is harmless
+CREATE TABLE IF NOT EXISTS digital_entitlements (
+    entitlement_id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    max_downloads INT NOT NULL CHECK (max_downloads > 0),
+    download_count INT NOT NULL DEFAULT 0 CHECK (download_count >= 0),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (order_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_digital_entitlements_user_id ON digital_entitlements(user_id, created_at);
+
+-- license keys are loaded into a pool per product and handed out once, one
+-- per unit bought, a product with keys can not be fulfilled once they run out
+CREATE TABLE IF NOT EXISTS license_keys (
+    key_id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    license_key VARCHAR(255) NOT NULL,
+    entitlement_id UUID REFERENCES digital_entitlements(entitlement_id) ON DELETE SET NULL,
+    allocated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, license_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_license_keys_entitlement_id ON license_keys(entitlement_id);
+CREATE INDEX IF NOT EXISTS idx_license_keys_available ON license_keys(product_id, created_at) WHERE allocated_at IS NULL;
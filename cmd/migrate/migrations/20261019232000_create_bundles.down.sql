@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS bundle_sale_lines;
+DROP TABLE IF EXISTS bundle_sales;
+DROP TABLE IF EXISTS bundle_components;
+DROP TABLE IF EXISTS bundles;
@@ -0,0 +1,48 @@
+-- a bundle is a product sold at its own price that is made of the variants
+-- of other products, a fixed bundle holds every component while a mix and
+-- match bundle lets the customer pick pick_count units among them
+CREATE TABLE IF NOT EXISTS bundles (
+    product_id UUID PRIMARY KEY REFERENCES products(product_id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('fixed', 'mix_and_match')),
+    pick_count INT CHECK (pick_count > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT bundles_pick_count_check CHECK ((kind = 'mix_and_match') = (pick_count IS NOT NULL))
+);
+
+-- a component goes away with its variant, e.g. when the options of its
+-- product change
+CREATE TABLE IF NOT EXISTS bundle_components (
+    product_id UUID NOT NULL REFERENCES bundles(product_id) ON DELETE CASCADE,
+    variant_id UUID NOT NULL REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    position INT NOT NULL,
+    PRIMARY KEY (product_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_components_variant_id ON bundle_components(variant_id);
+
+-- bundle sales keep the revenue of each bundle sold allocated to the
+-- components it took, one sale per bundle and order
+CREATE TABLE IF NOT EXISTS bundle_sales (
+    sale_id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (order_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_sales_product_id ON bundle_sales(product_id, created_at);
+
+-- sale lines keep the sku of their variant so reports outlive it
+CREATE TABLE IF NOT EXISTS bundle_sale_lines (
+    sale_id UUID NOT NULL REFERENCES bundle_sales(sale_id) ON DELETE CASCADE,
+    variant_id UUID NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+    PRIMARY KEY (sale_id, variant_id)
+);
@@ -0,0 +1,23 @@
+-- sellers are marketplace vendors selling their own products, they can only
+-- log in once an admin has approved them
+CREATE TABLE IF NOT EXISTS sellers (
+    seller_id UUID PRIMARY KEY,
+    store_name VARCHAR(100) NOT NULL,
+    first_name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    hashed_password TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'suspended')),
+    status_reason VARCHAR(500) NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sellers_store_name ON sellers(LOWER(store_name));
+CREATE INDEX IF NOT EXISTS idx_sellers_status_created_at ON sellers(status, created_at);
+
+-- products without a seller are sold by the shop itself
+ALTER TABLE products ADD COLUMN IF NOT EXISTS seller_id UUID REFERENCES sellers(seller_id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_products_seller_id ON products(seller_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS stock_reservation_lines;
+DROP TABLE IF EXISTS stock_reservations;
+ALTER TABLE variant_stock DROP CONSTRAINT IF EXISTS variant_stock_reserved_check;
+ALTER TABLE variant_stock DROP COLUMN IF EXISTS reserved;
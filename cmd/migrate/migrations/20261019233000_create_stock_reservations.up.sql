@@ -0,0 +1,26 @@
+-- reserved is the part of the stock held by checkouts in progress, only the
+-- rest can be sold
+ALTER TABLE variant_stock ADD COLUMN IF NOT EXISTS reserved BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE variant_stock ADD CONSTRAINT variant_stock_reserved_check CHECK (reserved >= 0 AND reserved <= quantity);
+
+-- a checkout holds one reservation at a time, it is committed on payment or
+-- released on cancellation or expiry
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    reservation_id UUID PRIMARY KEY,
+    checkout_id UUID NOT NULL,
+    user_id UUID REFERENCES users(user_id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'committed', 'released', 'expired')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_reservations_pending_checkout ON stock_reservations(checkout_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_pending_expires_at ON stock_reservations(expires_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS stock_reservation_lines (
+    reservation_id UUID NOT NULL REFERENCES stock_reservations(reservation_id) ON DELETE CASCADE,
+    variant_id UUID NOT NULL REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (reservation_id, variant_id)
+);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS reservation_allocations;
+ALTER TABLE stock_reservations DROP COLUMN IF EXISTS destination_longitude;
+ALTER TABLE stock_reservations DROP COLUMN IF EXISTS destination_latitude;
+ALTER TABLE stock_reservations DROP COLUMN IF EXISTS allocation_strategy;
+DROP TABLE IF EXISTS stock_transfer_lines;
+DROP TABLE IF EXISTS stock_transfers;
+DROP TABLE IF EXISTS location_stock;
+DROP TABLE IF EXISTS stock_locations;
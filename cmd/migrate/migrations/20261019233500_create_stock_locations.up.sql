@@ -0,0 +1,59 @@
+-- warehouses and retail stores stock is kept at, once a variant has stock at
+-- a location its variant_stock quantity is the sum over the locations
+CREATE TABLE IF NOT EXISTS stock_locations (
+    location_id UUID PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('warehouse', 'store')),
+    latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    -- lower priorities ship first under the priority strategy
+    priority INT NOT NULL DEFAULT 0 CHECK (priority >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS location_stock (
+    location_id UUID NOT NULL REFERENCES stock_locations(location_id),
+    variant_id UUID NOT NULL REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (location_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_stock_variant_id ON location_stock(variant_id);
+
+-- transfers move stock between locations when they are completed
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    transfer_id UUID PRIMARY KEY,
+    from_location_id UUID NOT NULL REFERENCES stock_locations(location_id),
+    to_location_id UUID NOT NULL REFERENCES stock_locations(location_id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'cancelled')),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    CHECK (from_location_id <> to_location_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_status ON stock_transfers(status, created_at);
+
+CREATE TABLE IF NOT EXISTS stock_transfer_lines (
+    transfer_id UUID NOT NULL REFERENCES stock_transfers(transfer_id) ON DELETE CASCADE,
+    variant_id UUID NOT NULL REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (transfer_id, variant_id)
+);
+
+-- how a reservation is allocated to locations when it is committed
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS allocation_strategy VARCHAR(20) NOT NULL DEFAULT 'priority' CHECK (allocation_strategy IN ('closest', 'whole_order', 'priority'));
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS destination_latitude DOUBLE PRECISION;
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS destination_longitude DOUBLE PRECISION;
+
+CREATE TABLE IF NOT EXISTS reservation_allocations (
+    reservation_id UUID NOT NULL REFERENCES stock_reservations(reservation_id) ON DELETE CASCADE,
+    variant_id UUID NOT NULL REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    location_id UUID NOT NULL REFERENCES stock_locations(location_id),
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (reservation_id, variant_id, location_id)
+);
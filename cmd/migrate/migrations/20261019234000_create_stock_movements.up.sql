@@ -0,0 +1,45 @@
+-- append-only ledger of every stock change, variant_stock and location_stock
+-- quantities are the sums of the movements. variant_id has no foreign key so
+-- the history outlives deleted variants.
+CREATE TABLE IF NOT EXISTS stock_movements (
+    movement_id UUID PRIMARY KEY,
+    variant_id UUID NOT NULL,
+    sku VARCHAR(64) NOT NULL DEFAULT '',
+    -- movements without a location only change variant_stock
+    location_id UUID REFERENCES stock_locations(location_id),
+    quantity BIGINT NOT NULL CHECK (quantity <> 0),
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('sale', 'return', 'adjustment', 'transfer', 'receipt')),
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id UUID,
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_variant_id ON stock_movements(variant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_location_id ON stock_movements(location_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_reason ON stock_movements(reason, created_at);
+
+CREATE OR REPLACE FUNCTION stock_movements_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'stock movements are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_movements_append_only
+    BEFORE UPDATE OR DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_append_only();
+
+-- opening balances of the stock kept so far
+INSERT INTO stock_movements(movement_id, variant_id, sku, location_id, quantity, reason, actor_type, reference)
+SELECT md5('opening balance' || ls.location_id || ls.variant_id)::uuid, ls.variant_id, pv.sku, ls.location_id, ls.quantity, 'adjustment', 'system', 'opening balance'
+FROM location_stock ls
+JOIN product_variants pv ON pv.variant_id = ls.variant_id
+WHERE ls.quantity > 0;
+
+INSERT INTO stock_movements(movement_id, variant_id, sku, location_id, quantity, reason, actor_type, reference)
+SELECT md5('opening balance' || vs.variant_id)::uuid, vs.variant_id, pv.sku, NULL, vs.quantity, 'adjustment', 'system', 'opening balance'
+FROM variant_stock vs
+JOIN product_variants pv ON pv.variant_id = vs.variant_id
+WHERE vs.quantity > 0
+AND NOT EXISTS (SELECT 1 FROM location_stock ls WHERE ls.variant_id = vs.variant_id);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS backorders;
+ALTER TABLE stock_reservation_lines DROP CONSTRAINT IF EXISTS stock_reservation_lines_backordered_check;
+ALTER TABLE stock_reservation_lines DROP COLUMN IF EXISTS deposit_percent;
+ALTER TABLE stock_reservation_lines DROP COLUMN IF EXISTS expected_ship_date;
+ALTER TABLE stock_reservation_lines DROP COLUMN IF EXISTS availability;
+ALTER TABLE stock_reservation_lines DROP COLUMN IF EXISTS backordered;
+ALTER TABLE variant_stock DROP COLUMN IF EXISTS backordered;
+DROP TABLE IF EXISTS variant_availability_policies;
@@ -0,0 +1,42 @@
+-- variants sellable beyond the stock on hand, as backorders up to a limit or
+-- as preorders shipping from an expected date
+CREATE TABLE IF NOT EXISTS variant_availability_policies (
+    variant_id UUID PRIMARY KEY REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    mode VARCHAR(20) NOT NULL CHECK (mode IN ('backorder', 'preorder')),
+    -- most units sold beyond the stock at a time, no limit when null
+    backorder_limit BIGINT CHECK (backorder_limit > 0),
+    expected_ship_date DATE,
+    -- part of the price paid up front for preorders
+    deposit_percent INT CHECK (deposit_percent BETWEEN 1 AND 100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (mode <> 'backorder' OR backorder_limit IS NOT NULL),
+    CHECK (mode <> 'preorder' OR expected_ship_date IS NOT NULL)
+);
+
+-- backordered is what pending checkouts and orders waiting for stock hold
+-- beyond the stock on hand, stock received goes to those orders first
+ALTER TABLE variant_stock ADD COLUMN IF NOT EXISTS backordered BIGINT NOT NULL DEFAULT 0 CHECK (backordered >= 0);
+
+-- the part of a line sold beyond the stock and the terms it was sold on
+ALTER TABLE stock_reservation_lines ADD COLUMN IF NOT EXISTS backordered BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE stock_reservation_lines ADD COLUMN IF NOT EXISTS availability VARCHAR(20) NOT NULL DEFAULT 'in_stock' CHECK (availability IN ('in_stock', 'backorder', 'preorder'));
+ALTER TABLE stock_reservation_lines ADD COLUMN IF NOT EXISTS expected_ship_date DATE;
+ALTER TABLE stock_reservation_lines ADD COLUMN IF NOT EXISTS deposit_percent INT;
+ALTER TABLE stock_reservation_lines ADD CONSTRAINT stock_reservation_lines_backordered_check CHECK (backordered >= 0 AND backordered <= quantity);
+
+-- committed lines waiting for stock, allocated in the order they were placed
+CREATE TABLE IF NOT EXISTS backorders (
+    reservation_id UUID NOT NULL REFERENCES stock_reservations(reservation_id) ON DELETE CASCADE,
+    variant_id UUID NOT NULL REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    allocated BIGINT NOT NULL DEFAULT 0 CHECK (allocated >= 0 AND allocated <= quantity),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'fulfilled')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fulfilled_at TIMESTAMPTZ,
+    PRIMARY KEY (reservation_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_backorders_pending ON backorders(created_at, reservation_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_backorders_variant_id ON backorders(variant_id, created_at);
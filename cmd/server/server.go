@@ -1,30 +1,66 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/admin"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/bundle"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/category"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/digital"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/inventory"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/media"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/passkey"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/pricing"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/recommendation"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/review"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/seller"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/session"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/user"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/middleware"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn"
 	"github.com/go-chi/chi"
 	chimiddleware "github.com/go-chi/chi/middleware"
 )
 
+// shutdownTimeout is how long the listeners still running get to finish
+// their requests once another listener stopped.
+const shutdownTimeout = 10 * time.Second
+
 type Server struct {
 	addr         string
 	db           *sql.DB
 	tokenService *auth.TokenService
+	relyingParty *webauthn.RelyingParty
+	network      NetworkConfig
+	blobStore    blobstore.BlobStore
+	downloads    DownloadConfig
+}
+
+// DownloadConfig describes the signed links digital product files are
+// downloaded through, BaseURL is the public url of the api.
+type DownloadConfig struct {
+	URLSecret  string
+	BaseURL    string
+	LinkInSecs int64
 }
 
-func NewServer(addr string, db *sql.DB, tokenService *auth.TokenService) *Server {
+func NewServer(addr string, db *sql.DB, tokenService *auth.TokenService, relyingParty *webauthn.RelyingParty, network NetworkConfig, blobStore blobstore.BlobStore, downloads DownloadConfig) *Server {
 	return &Server{
 		addr:         addr,
 		db:           db,
 		tokenService: tokenService,
+		relyingParty: relyingParty,
+		network:      network,
+		blobStore:    blobStore,
+		downloads:    downloads,
 	}
 }
 
@@ -37,19 +73,110 @@ func (s *Server) Start() error {
 	// to ensure that the url is correctly formatted
 	router.Use(chimiddleware.StripSlashes)
 
-	router.Mount("/api/v1", s.v1Router()) // api version 1 subrouter
+	// resolve the client IP once, behind the trusted proxies only
+	clientIPResolver, err := middleware.NewClientIPResolver(s.network.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	router.Use(clientIPResolver.Middleware)
+
+	adminAccess, err := s.adminAccessMiddlewares()
+	if err != nil {
+		return err
+	}
+
+	router.Mount("/api/v1", s.v1Router(adminAccess)) // api version 1 subrouter
 
-	srv := http.Server{
+	listeners := []*listener{}
+	if s.network.AdminMTLS.enabled() {
+		tlsConfig, err := newMTLSConfig(s.network.AdminMTLS.ClientCAFile)
+		if err != nil {
+			return err
+		}
+
+		mtlsSrv := &http.Server{
+			Addr:      fmt.Sprintf(":%s", s.network.AdminMTLS.Port),
+			Handler:   router,
+			TLSConfig: tlsConfig,
+		}
+
+		listeners = append(listeners, &listener{
+			srv: mtlsSrv,
+			serve: func() error {
+				log.Printf("mTLS server started at port %s\n", s.network.AdminMTLS.Port)
+				return mtlsSrv.ListenAndServeTLS(
+					s.network.AdminMTLS.CertFile,
+					s.network.AdminMTLS.KeyFile,
+				)
+			},
+		})
+	}
+
+	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", s.addr),
 		Handler: router,
 	}
 
-	log.Printf("Server started at port %s\n", s.addr)
+	listeners = append(listeners, &listener{
+		srv: srv,
+		serve: func() error {
+			log.Printf("Server started at port %s\n", s.addr)
+			return srv.ListenAndServe()
+		},
+	})
+
+	return serveAll(listeners...)
+}
+
+// listener is an http server and the call that starts serving it.
+type listener struct {
+	srv   *http.Server
+	serve func() error
+}
+
+// serveAll runs every listener until one of them stops, then shuts the
+// others down so the process never keeps serving on part of its listeners.
+// It returns the error the first listener stopped with.
+func serveAll(listeners ...*listener) error {
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func() {
+			errs <- l.serve()
+		}()
+	}
+
+	err := <-errs
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, l := range listeners {
+		if shutdownErr := l.srv.Shutdown(ctx); shutdownErr != nil {
+			log.Println(shutdownErr)
+		}
+	}
+
+	return err
+}
+
+// adminAccessMiddlewares returns the checks every admin route goes through:
+// the CIDR allowlist and, when the mTLS listener is enabled, a verified client
+// certificate.
+func (s *Server) adminAccessMiddlewares() (chi.Middlewares, error) {
+	allowCIDRs, err := middleware.AllowCIDRs(s.network.AdminAllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	adminAccess := chi.Middlewares{allowCIDRs}
+	if s.network.AdminMTLS.enabled() {
+		adminAccess = append(adminAccess, middleware.RequireClientCert)
+	}
 
-	return srv.ListenAndServe()
+	return adminAccess, nil
 }
 
-func (s *Server) v1Router() *chi.Mux {
+func (s *Server) v1Router(adminAccess chi.Middlewares) *chi.Mux {
 	r := chi.NewRouter()
 
 	// health check
@@ -60,9 +187,21 @@ func (s *Server) v1Router() *chi.Mux {
 	})
 	// session feature
 	sessionStore := session.NewStore(s.db)
+
+	// access token denylist, loaded once and then kept in sync in the
+	// background
+	denylist := session.NewDenylist(sessionStore)
+	if err := denylist.Load(context.Background()); err != nil {
+		log.Println(err)
+	}
+	go denylist.Run(context.Background(), time.Minute)
+
+	authenticator := middleware.NewAuthenticator(s.tokenService, denylist)
+
 	sessionService := session.NewService(
 		sessionStore,
 		s.tokenService,
+		denylist,
 	)
 	sessionHandler := session.NewHandler(sessionService)
 	sessionHandler.RegisterRoutes(r)
@@ -70,7 +209,7 @@ func (s *Server) v1Router() *chi.Mux {
 	// user feature
 	userStore := user.NewStore(s.db)
 	userService := user.NewService(userStore, sessionService)
-	userHandler := user.NewHandler(userService)
+	userHandler := user.NewHandler(userService, authenticator)
 	userHandler.RegisterRoutes(r)
 
 	//admin feature
@@ -79,8 +218,135 @@ func (s *Server) v1Router() *chi.Mux {
 		adminStore,
 		sessionService,
 	)
-	adminHandler := admin.NewHandler(adminService)
-	adminHandler.RegisterRoutes(r)
+	adminHandler := admin.NewHandler(adminService, authenticator)
+
+	// passkey feature
+	passkeyStore := passkey.NewStore(s.db)
+	passkeyService := passkey.NewService(
+		passkeyStore,
+		sessionService,
+		s.relyingParty,
+	)
+	go passkeyService.PurgeExpiredChallenges(context.Background(), 10*time.Minute)
+	passkeyHandler := passkey.NewHandler(passkeyService, authenticator)
+	passkeyHandler.RegisterRoutes(r)
+
+	// product feature
+	productStore := product.NewStore(s.db)
+	productService := product.NewService(productStore)
+	productHandler := product.NewHandler(productService, authenticator)
+	productHandler.RegisterRoutes(r)
+	go productService.RunScheduler(context.Background(), time.Minute)
+
+	// catalog import runs as background jobs, their files wait in the blob
+	// store
+	productImporter := product.NewImporter(productService, productStore, s.blobStore)
+	go productImporter.RunImportWorker(context.Background(), time.Minute)
+	importHandler := product.NewImportHandler(productImporter, authenticator)
+
+	// category feature
+	categoryStore := category.NewStore(s.db)
+	categoryService := category.NewService(categoryStore)
+	categoryHandler := category.NewHandler(categoryService, authenticator)
+	categoryHandler.RegisterRoutes(r)
+
+	// media feature, thumbnails are generated in the background
+	mediaStore := media.NewStore(s.db)
+	mediaService := media.NewService(mediaStore, s.blobStore)
+	go mediaService.RunThumbnailWorker(context.Background(), time.Minute)
+	mediaHandler := media.NewHandler(mediaService, authenticator)
+	mediaHandler.RegisterRoutes(r)
+
+	// digital products feature, files sit in the blob store and are handed
+	// out through signed links once an order is fulfilled
+	digitalStore := digital.NewStore(s.db)
+	digitalService := digital.NewService(
+		digitalStore,
+		s.blobStore,
+		s.downloads.URLSecret,
+		s.downloads.BaseURL,
+		time.Duration(s.downloads.LinkInSecs)*time.Second,
+	)
+	digitalHandler := digital.NewHandler(digitalService, authenticator)
+	digitalHandler.RegisterRoutes(r)
+
+	// bundle feature, bundles hold no stock of their own and draw on the
+	// stock of their component variants
+	bundleStore := bundle.NewStore(s.db)
+	bundleService := bundle.NewService(bundleStore)
+	bundleHandler := bundle.NewHandler(bundleService, authenticator)
+	bundleHandler.RegisterRoutes(r)
+
+	// inventory feature, checkouts hold their stock until they are paid,
+	// cancelled or abandoned
+	inventoryStore := inventory.NewStore(s.db)
+	inventoryService := inventory.NewService(inventoryStore)
+	inventoryHandler := inventory.NewHandler(inventoryService, authenticator)
+	inventoryHandler.RegisterRoutes(r)
+	go inventoryService.RunExpiryJob(context.Background(), time.Minute)
+	go inventoryService.RunBackorderJob(context.Background(), time.Minute)
+
+	// pricing feature, the price resolution the cart and checkout build on
+	pricingStore := pricing.NewStore(s.db)
+	pricingService := pricing.NewService(pricingStore)
+	pricingHandler := pricing.NewHandler(pricingService, authenticator)
+	pricingHandler.RegisterRoutes(r)
+
+	// review feature, reviews of products the author paid for through a
+	// committed checkout are marked as verified purchases
+	reviewStore := review.NewStore(s.db)
+	reviewService := review.NewService(
+		reviewStore,
+		inventoryService,
+		review.NewWordFilter(),
+	)
+	reviewHandler := review.NewHandler(reviewService, authenticator)
+	reviewHandler.RegisterRoutes(r)
+
+	// seller feature, sellers see the committed checkouts holding their
+	// products as orders. Sellers manage their own products through the
+	// product routes of the seller route group
+	sellerStore := seller.NewStore(s.db)
+	sellerService := seller.NewService(
+		sellerStore,
+		sessionService,
+	)
+	sellerHandler := seller.NewHandler(sellerService, authenticator)
+	r.Route("/seller", func(r chi.Router) {
+		sellerHandler.RegisterRoutes(r)
+		productHandler.RegisterSellerRoutes(r)
+	})
+
+	// recommendation feature, co-purchase scores are recomputed daily from
+	// the committed checkouts
+	recommendationStore := recommendation.NewStore(s.db)
+	recommendationService := recommendation.NewService(
+		recommendationStore,
+		inventoryService,
+	)
+	go recommendationService.RunScoreJob(context.Background(), 24*time.Hour)
+	recommendationHandler := recommendation.NewHandler(recommendationService, authenticator)
+	recommendationHandler.RegisterRoutes(r)
+
+	// admin route group, every admin facing route is registered here so
+	// it sits behind the admin access checks
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(adminAccess...)
+
+		adminHandler.RegisterRoutes(r)
+		passkeyHandler.RegisterAdminRoutes(r)
+		productHandler.RegisterAdminRoutes(r)
+		importHandler.RegisterAdminRoutes(r)
+		categoryHandler.RegisterAdminRoutes(r)
+		mediaHandler.RegisterAdminRoutes(r)
+		digitalHandler.RegisterAdminRoutes(r)
+		bundleHandler.RegisterAdminRoutes(r)
+		inventoryHandler.RegisterAdminRoutes(r)
+		pricingHandler.RegisterAdminRoutes(r)
+		reviewHandler.RegisterAdminRoutes(r)
+		recommendationHandler.RegisterAdminRoutes(r)
+		sellerHandler.RegisterAdminRoutes(r)
+	})
 
 	return r
 }
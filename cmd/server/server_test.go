@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServeAllStopsEveryListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	public := &http.Server{Handler: http.NotFoundHandler()}
+	publicErr := make(chan error, 1)
+	errAdmin := errors.New("admin listener failed")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serveAll(
+			&listener{srv: public, serve: func() error {
+				err := public.Serve(ln)
+				publicErr <- err
+				return err
+			}},
+			&listener{srv: &http.Server{}, serve: func() error {
+				return errAdmin
+			}},
+		)
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected serveAll to return once a listener failed")
+	}
+
+	if !errors.Is(err, errAdmin) {
+		t.Errorf("expected the admin listener error, got %v", err)
+	}
+
+	select {
+	case err = <-publicErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("expected the public listener to be shut down, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the public listener to stop")
+	}
+}
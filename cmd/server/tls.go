@@ -0,0 +1,48 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// NetworkConfig controls how clients reach the server and who may use the
+// admin routes.
+type NetworkConfig struct {
+	TrustedProxies    []string
+	AdminAllowedCIDRs []string
+	AdminMTLS         MTLSConfig
+}
+
+// MTLSConfig describes the optional back-office listener that requires client
+// certificates signed by ClientCAFile.
+type MTLSConfig struct {
+	Port         string
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+func (c MTLSConfig) enabled() bool {
+	return c.Port != ""
+}
+
+func newMTLSConfig(clientCAFile string) (*tls.Config, error) {
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client ca file: %w", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("failed to parse client ca file: no certificates found")
+	}
+
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
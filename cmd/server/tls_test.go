@@ -0,0 +1,164 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/middleware"
+)
+
+// newTestCA generates a self signed certificate authority.
+func newTestCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ca key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create ca certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse ca certificate: %v", err)
+	}
+
+	return cert, key
+}
+
+// newTestClientCert generates a client certificate signed by ca.
+func newTestClientCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate client key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "back-office"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create client certificate: %v", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
+
+func TestMTLSListener(t *testing.T) {
+	ca, caKey := newTestCA(t, "back-office ca")
+	rogueCA, rogueCAKey := newTestCA(t, "rogue ca")
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatalf("failed to write ca file: %v", err)
+	}
+
+	tlsConfig, err := newMTLSConfig(caFile)
+	if err != nil {
+		t.Fatalf("failed to create mtls config: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(middleware.RequireClientCert(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		certificates []tls.Certificate
+		expectErr    bool
+	}{
+		{
+			name:         "should accept a client certificate signed by the ca",
+			certificates: []tls.Certificate{newTestClientCert(t, ca, caKey)},
+		},
+		{
+			name:      "should reject a client without a certificate",
+			expectErr: true,
+		},
+		{
+			name:         "should reject a client certificate signed by another ca",
+			certificates: []tls.Certificate{newTestClientCert(t, rogueCA, rogueCAKey)},
+			expectErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := srv.Client()
+			transport := client.Transport.(*http.Transport).Clone()
+			transport.TLSClientConfig.Certificates = tc.certificates
+			client.Transport = transport
+
+			resp, err := client.Get(srv.URL)
+			if tc.expectErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("expected the handshake to fail")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestNewMTLSConfigRejectsEmptyCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("failed to write ca file: %v", err)
+	}
+
+	if _, err := newMTLSConfig(caFile); err == nil {
+		t.Error("expected an error for a ca file without certificates")
+	}
+}
@@ -0,0 +1,268 @@
+// Package attribute holds the structured product attributes categories
+// define, e.g. RAM and CPU for laptops, and checks product values against
+// them.
+package attribute
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/google/uuid"
+)
+
+// Attribute types.
+const (
+	TypeText    = "text"
+	TypeNumber  = "number"
+	TypeEnum    = "enum"
+	TypeBoolean = "boolean"
+)
+
+// maxTextLength bounds text values, longer copy belongs in the description.
+const maxTextLength = 500
+
+// codePattern keeps codes usable as query parameters and JSON keys.
+var codePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// Definition is an attribute defined on a category, it applies to the
+// products of that category and of every category below it.
+type Definition struct {
+	AttributeID uuid.UUID `json:"attribute_id"`
+	CategoryID  uuid.UUID `json:"category_id"`
+	Code        string    `json:"code"` // key of the value in product attributes
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Unit        string    `json:"unit"`   // of numbers e.g. GB or cm
+	Values      []string  `json:"values"` // of enums, in display order
+	Required    bool      `json:"required"`
+	Filterable  bool      `json:"filterable"` // counted in search facets
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Check reports whether the definition is consistent: a lower case code, enum
+// values only on enums and a unit only on numbers.
+func (d *Definition) Check() bool {
+	if !codePattern.MatchString(d.Code) {
+		return false
+	}
+
+	switch d.Type {
+	case TypeEnum:
+		if len(d.Values) == 0 || d.Unit != "" {
+			return false
+		}
+
+		seen := map[string]struct{}{}
+		for _, value := range d.Values {
+			key := strings.ToLower(value)
+			if _, ok := seen[key]; ok || strings.TrimSpace(value) == "" {
+				return false
+			}
+			seen[key] = struct{}{}
+		}
+
+		return true
+	case TypeNumber:
+		return len(d.Values) == 0
+	case TypeText, TypeBoolean:
+		return len(d.Values) == 0 && d.Unit == ""
+	default:
+		return false
+	}
+}
+
+// NormalizeCode turns a code as typed by an admin into its stored form.
+func NormalizeCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// Values are the attribute values of a product keyed by attribute code,
+// stored as a JSON object.
+type Values map[string]any
+
+// Value stores the values as a JSON object, never null.
+func (v Values) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(map[string]any(v))
+}
+
+func (v *Values) Scan(src any) error {
+	var data []byte
+	switch src := src.(type) {
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	case nil:
+		*v = Values{}
+		return nil
+	default:
+		return fmt.Errorf("can not scan %T into attribute values", src)
+	}
+
+	values := Values{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	*v = values
+	return nil
+}
+
+// Validate checks values against the definitions that apply to a product and
+// returns them normalised: codes lower cased, enum values in the case they
+// are defined in and numbers as float64. Every problem is reported in the
+// shape of the request validation errors, under attributes.<code>.
+func Validate(definitions []*Definition, values Values) (Values, error) {
+	byCode := make(map[string]*Definition, len(definitions))
+	for _, definition := range definitions {
+		byCode[definition.Code] = definition
+	}
+
+	normalized := make(Values, len(values))
+	errs := validate.ValidationErrors{}
+
+	for code, value := range values {
+		key := NormalizeCode(code)
+		definition, ok := byCode[key]
+		if !ok {
+			errs = append(errs, fieldError(code, "is not defined for the product categories", "UNKNOWN"))
+			continue
+		}
+
+		if _, ok = normalized[key]; ok {
+			errs = append(errs, fieldError(code, "is set more than once", "UNIQUE"))
+			continue
+		}
+
+		if value == nil {
+			// null clears an attribute, which then counts as missing
+			continue
+		}
+
+		parsed, err := parseValue(definition, value)
+		if err != nil {
+			errs = append(errs, *err)
+			continue
+		}
+
+		normalized[key] = parsed
+	}
+
+	for _, definition := range definitions {
+		if _, ok := normalized[definition.Code]; definition.Required && !ok {
+			errs = append(errs, fieldError(definition.Code, "is required", "REQUIRED"))
+		}
+	}
+
+	if len(errs) > 0 {
+		slices.SortFunc(errs, func(a, b validate.ValidationError) int {
+			return strings.Compare(a.Field, b.Field)
+		})
+
+		return nil, errs
+	}
+
+	return normalized, nil
+}
+
+func parseValue(definition *Definition, value any) (any, *validate.ValidationError) {
+	switch definition.Type {
+	case TypeText:
+		text, ok := value.(string)
+		if !ok {
+			return nil, typeError(definition)
+		}
+
+		text = strings.TrimSpace(text)
+		if utf8.RuneCountInString(text) > maxTextLength {
+			err := fieldError(definition.Code, fmt.Sprintf("must be at most %d characters", maxTextLength), "MAX")
+			return nil, &err
+		}
+
+		return text, nil
+	case TypeNumber:
+		var number float64
+		switch v := value.(type) {
+		case float64:
+			number = v
+		case int:
+			number = float64(v)
+		case int64:
+			number = float64(v)
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				return nil, typeError(definition)
+			}
+			number = f
+		default:
+			return nil, typeError(definition)
+		}
+
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, typeError(definition)
+		}
+
+		return number, nil
+	case TypeEnum:
+		text, ok := value.(string)
+		if !ok {
+			return nil, typeError(definition)
+		}
+
+		for _, allowed := range definition.Values {
+			if strings.EqualFold(allowed, strings.TrimSpace(text)) {
+				return allowed, nil
+			}
+		}
+
+		err := fieldError(
+			definition.Code,
+			fmt.Sprintf("must be one of %s", strings.Join(definition.Values, ", ")),
+			"ONEOF",
+		)
+		return nil, &err
+	case TypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, typeError(definition)
+		}
+
+		return b, nil
+	default:
+		return nil, typeError(definition)
+	}
+}
+
+func typeError(definition *Definition) *validate.ValidationError {
+	msg := fmt.Sprintf("must be a %s", definition.Type)
+	if definition.Type == TypeNumber && definition.Unit != "" {
+		msg = fmt.Sprintf("must be a number in %s", definition.Unit)
+	}
+
+	err := fieldError(definition.Code, msg, "TYPE")
+	return &err
+}
+
+func fieldError(code, msg, tag string) validate.ValidationError {
+	field := "attributes." + code
+	return validate.ValidationError{
+		Field: field,
+		Msg:   fmt.Sprintf("%s %s", field, msg),
+		Code:  "ATTRIBUTES_" + tag,
+	}
+}
@@ -0,0 +1,105 @@
+package attribute
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+)
+
+var laptopAttributes = []*Definition{
+	{Code: "ram", Type: TypeNumber, Unit: "GB", Required: true},
+	{Code: "cpu", Type: TypeText},
+	{Code: "panel", Type: TypeEnum, Values: []string{"IPS", "OLED"}},
+	{Code: "touchscreen", Type: TypeBoolean},
+}
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		definition Definition
+		expected   bool
+	}{
+		{Definition{Code: "ram", Type: TypeNumber, Unit: "GB"}, true},
+		{Definition{Code: "heel_height", Type: TypeNumber}, true},
+		{Definition{Code: "size_system", Type: TypeEnum, Values: []string{"EU", "US", "UK"}}, true},
+		{Definition{Code: "size_system", Type: TypeEnum}, false},
+		{Definition{Code: "size_system", Type: TypeEnum, Values: []string{"EU", "eu"}}, false},
+		{Definition{Code: "waterproof", Type: TypeBoolean, Unit: "m"}, false},
+		{Definition{Code: "cpu", Type: TypeText, Values: []string{"M3"}}, false},
+		{Definition{Code: "Heel Height", Type: TypeNumber}, false},
+		{Definition{Code: "color", Type: "colour"}, false},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.definition.Check(); got != tc.expected {
+			t.Errorf("expected %+v to check %t, got %t", tc.definition, tc.expected, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("should normalise valid values", func(t *testing.T) {
+		values, err := Validate(laptopAttributes, Values{
+			"RAM":         16,
+			"cpu":         "  M3 Pro ",
+			"panel":       "oled",
+			"touchscreen": false,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if values["ram"] != float64(16) || values["cpu"] != "M3 Pro" || values["panel"] != "OLED" || values["touchscreen"] != false {
+			t.Errorf("unexpected normalised values %v", values)
+		}
+	})
+
+	t.Run("should report every invalid value", func(t *testing.T) {
+		_, err := Validate(laptopAttributes, Values{
+			"cpu":    12,
+			"panel":  "TN",
+			"weight": 1.4,
+		})
+
+		var validationErrors validate.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			t.Fatalf("expected validation errors, got %v", err)
+		}
+
+		expected := map[string]string{
+			"attributes.cpu":    "ATTRIBUTES_TYPE",
+			"attributes.panel":  "ATTRIBUTES_ONEOF",
+			"attributes.ram":    "ATTRIBUTES_REQUIRED",
+			"attributes.weight": "ATTRIBUTES_UNKNOWN",
+		}
+		if len(validationErrors) != len(expected) {
+			t.Fatalf("expected %d errors, got %v", len(expected), validationErrors)
+		}
+
+		for _, validationError := range validationErrors {
+			if expected[validationError.Field] != validationError.Code {
+				t.Errorf("expected %s to fail with %s, got %s", validationError.Field, expected[validationError.Field], validationError.Code)
+			}
+		}
+	})
+}
+
+func TestValuesScan(t *testing.T) {
+	var values Values
+	if err := values.Scan([]byte(`{"ram": 16, "panel": "IPS"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if values["ram"] != float64(16) || values["panel"] != "IPS" {
+		t.Errorf("unexpected scanned values %v", values)
+	}
+
+	stored, err := Values(nil).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(stored.([]byte)) != "{}" {
+		t.Errorf("expected nil values to be stored as {}, got %s", stored)
+	}
+}
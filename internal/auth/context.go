@@ -0,0 +1,20 @@
+package auth
+
+import "context"
+
+type contextKey string
+
+const claimsContextKey contextKey = "tokenClaims"
+
+// ContextWithClaims returns a copy of ctx that carries the access token claims
+// of the authenticated entity.
+func ContextWithClaims(ctx context.Context, claims *TokenClaims) context.Context {
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// ClaimsFromContext returns the access token claims stored in ctx by the
+// authentication middleware, if any.
+func ClaimsFromContext(ctx context.Context) (*TokenClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*TokenClaims)
+	return claims, ok && claims != nil
+}
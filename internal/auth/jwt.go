@@ -12,9 +12,27 @@ import (
 type TokenClaims struct {
 	EntityID   string `json:"entityId,omitempty"`
 	EntityType string `json:"entityType,omitempty"`
+	// AuthTime and AuthMethods tell when and how the entity last proved its
+	// identity (OIDC auth_time and amr). Only set on access tokens.
+	AuthTime    *jwt.NumericDate `json:"auth_time,omitempty"`
+	AuthMethods []string         `json:"amr,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Authentication methods reported in the amr claim, see RFC 8176.
+const (
+	AuthMethodPassword    = "pwd"
+	AuthMethodHardwareKey = "hwk"
+	AuthMethodMultiFactor = "mfa"
+)
+
+// Authentication describes when and how an entity authenticated. Time is also
+// the start of the session for refresh tokens.
+type Authentication struct {
+	Time    time.Time
+	Methods []string
+}
+
 type RefreshTokens struct {
 	NewAccessToken        string       `json:"accessToken"`
 	NewRefreshToken       string       `json:"refreshToken"`
@@ -29,37 +47,72 @@ type RefreshTokens struct {
 // 	RefreshTokens(entityID string, entityType string) (*RefreshTokens, error)
 // }
 
+// TokenService issues and validates access and refresh tokens. Token and
+// session lifetimes are configured per entity type, AccessTokenExpiryInSecs and
+// RefreshTokenExpiryInSecs are the fallback for entity types (or fields) that
+// are not configured.
 type TokenService struct {
 	AccessTokenSecret        []byte
 	RefreshTokenSecret       []byte
 	AccessTokenExpiryInSecs  int64
 	RefreshTokenExpiryInSecs int64
+	EntityLifetimes          map[string]TokenLifetimes
 }
 
 func NewTokenService(accessTokenSecret, refreshTokenSecret string,
-	accessTokenExpiryInSecs, refreshTokenExpiryInSecs int64) *TokenService {
+	accessTokenExpiryInSecs, refreshTokenExpiryInSecs int64,
+	entityLifetimes map[string]TokenLifetimes) *TokenService {
 	return &TokenService{
 		AccessTokenSecret:        []byte(accessTokenSecret),
 		RefreshTokenSecret:       []byte(refreshTokenSecret),
 		AccessTokenExpiryInSecs:  accessTokenExpiryInSecs,
 		RefreshTokenExpiryInSecs: refreshTokenExpiryInSecs,
+		EntityLifetimes:          entityLifetimes,
+	}
+}
+
+// LifetimesFor returns the token and session lifetimes of entityType, with
+// unset token expiries filled from the service defaults.
+func (tm *TokenService) LifetimesFor(entityType string) TokenLifetimes {
+	lifetimes := tm.EntityLifetimes[entityType]
+
+	if lifetimes.AccessTokenExpiryInSecs <= 0 {
+		lifetimes.AccessTokenExpiryInSecs = tm.AccessTokenExpiryInSecs
 	}
+
+	if lifetimes.RefreshTokenExpiryInSecs <= 0 {
+		lifetimes.RefreshTokenExpiryInSecs = tm.RefreshTokenExpiryInSecs
+	}
+
+	return lifetimes
 }
 
-func (tm *TokenService) GenerateToken(isRefreshToken bool, entityID string, entityType string) (tokenStr string, claims *TokenClaims, err error) {
+// GenerateToken issues a token for the given authentication. A refresh token
+// never outlives the maximum session age of the entity type counted from
+// authentication.Time, however often it is rotated.
+func (tm *TokenService) GenerateToken(isRefreshToken bool, entityID string, entityType string, authentication Authentication) (tokenStr string, claims *TokenClaims, err error) {
 	var (
 		tokenID string
 		secret  []byte
 		expiry  time.Duration
 	)
 
+	lifetimes := tm.LifetimesFor(entityType)
+
+	// every token gets a unique id (jti). For refresh tokens it doubles as the
+	// session id, for access tokens it is the key used by the revocation
+	// denylist.
+	tokenID = uuid.New().String()
 	secret = tm.AccessTokenSecret
-	expiry = time.Second * time.Duration(tm.AccessTokenExpiryInSecs)
+	expiry = lifetimes.AccessTokenExpiry()
 
 	if isRefreshToken {
-		tokenID = uuid.New().String()
 		secret = tm.RefreshTokenSecret
-		expiry = time.Second * time.Duration(tm.RefreshTokenExpiryInSecs)
+		expiry = lifetimes.RefreshTokenExpiry()
+
+		if maxAge := lifetimes.SessionMaxAge(); maxAge > 0 {
+			expiry = min(expiry, time.Until(authentication.Time.Add(maxAge)))
+		}
 	}
 
 	// claims := jwt.MapClaims{
@@ -88,6 +141,11 @@ func (tm *TokenService) GenerateToken(isRefreshToken bool, entityID string, enti
 		},
 	}
 
+	if !isRefreshToken {
+		claims.AuthTime = jwt.NewNumericDate(authentication.Time)
+		claims.AuthMethods = authentication.Methods
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenStr, err = token.SignedString(secret)
@@ -106,18 +164,20 @@ func (tm *TokenService) ValidateRefreshToken(tokenStr string) (isValid bool, cla
 	return tm.validateToken(tokenStr, tm.RefreshTokenSecret)
 }
 
-func (tm *TokenService) RefreshTokens(entityID string, entityType string) (*RefreshTokens, error) {
+// RefreshTokens issues a new token pair for the rotation of a session. The
+// authentication is the one of the login that started the session.
+func (tm *TokenService) RefreshTokens(entityID string, entityType string, authentication Authentication) (*RefreshTokens, error) {
 	// _, claims, err := tm.validateToken(refreshToken, tm.RefreshTokenSecret)
 	// if err != nil {
 	// 	return "", "", err
 	// }
 
-	newAccessToken, newAccessTokenClaims, err := tm.GenerateToken(false, entityID, entityType)
+	newAccessToken, newAccessTokenClaims, err := tm.GenerateToken(false, entityID, entityType, authentication)
 	if err != nil {
 		return nil, err
 	}
 
-	newRefreshToken, newRefreshTokenClaims, err := tm.GenerateToken(true, entityID, entityType)
+	newRefreshToken, newRefreshTokenClaims, err := tm.GenerateToken(true, entityID, entityType, authentication)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntityTokenLifetimes(t *testing.T) {
+	tokenService := NewTokenService(
+		"access-secret",
+		"refresh-secret",
+		60,
+		24*60*60,
+		map[string]TokenLifetimes{
+			EntityTypeAdmin: {
+				AccessTokenExpiryInSecs:  15 * 60,
+				RefreshTokenExpiryInSecs: 8 * 60 * 60,
+				SessionMaxAgeInSecs:      12 * 60 * 60,
+			},
+		},
+	)
+
+	testCases := []struct {
+		name             string
+		isRefreshToken   bool
+		entityType       string
+		sessionStartedAt time.Time
+		expected         time.Duration
+	}{
+		{
+			name:             "should fall back to the default access token expiry",
+			entityType:       EntityTypeUser,
+			sessionStartedAt: time.Now(),
+			expected:         time.Minute,
+		},
+		{
+			name:             "should use the admin access token expiry",
+			entityType:       EntityTypeAdmin,
+			sessionStartedAt: time.Now(),
+			expected:         15 * time.Minute,
+		},
+		{
+			name:             "should use the admin refresh token expiry",
+			isRefreshToken:   true,
+			entityType:       EntityTypeAdmin,
+			sessionStartedAt: time.Now(),
+			expected:         8 * time.Hour,
+		},
+		{
+			name:             "should cap the admin refresh token at the maximum session age",
+			isRefreshToken:   true,
+			entityType:       EntityTypeAdmin,
+			sessionStartedAt: time.Now().Add(-11 * time.Hour),
+			expected:         time.Hour,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, claims, err := tokenService.GenerateToken(
+				tc.isRefreshToken,
+				"9f1c4a0e-5d7b-4c1e-9a55-3f0f4e0f2b11",
+				tc.entityType,
+				Authentication{Time: tc.sessionStartedAt},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time)
+			if lifetime < tc.expected-2*time.Second || lifetime > tc.expected+2*time.Second {
+				t.Errorf("expected a lifetime of %v, got %v", tc.expected, lifetime)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package auth
+
+import "time"
+
+// Entity types that can hold a session.
+const (
+	EntityTypeUser   = "user"
+	EntityTypeAdmin  = "admin"
+	EntityTypeSeller = "seller"
+)
+
+// TokenLifetimes configures how long the tokens and sessions of one entity
+// type live. A zero SessionIdleTimeoutInSecs or SessionMaxAgeInSecs disables
+// that limit.
+type TokenLifetimes struct {
+	AccessTokenExpiryInSecs  int64
+	RefreshTokenExpiryInSecs int64
+	SessionIdleTimeoutInSecs int64
+	SessionMaxAgeInSecs      int64
+}
+
+func (tl TokenLifetimes) AccessTokenExpiry() time.Duration {
+	return time.Second * time.Duration(tl.AccessTokenExpiryInSecs)
+}
+
+func (tl TokenLifetimes) RefreshTokenExpiry() time.Duration {
+	return time.Second * time.Duration(tl.RefreshTokenExpiryInSecs)
+}
+
+// SessionIdleTimeout is how long a session may go without being renewed
+// before it is considered abandoned.
+func (tl TokenLifetimes) SessionIdleTimeout() time.Duration {
+	return time.Second * time.Duration(tl.SessionIdleTimeoutInSecs)
+}
+
+// SessionMaxAge is how long a session may live from login, across any number
+// of token rotations.
+func (tl TokenLifetimes) SessionMaxAge() time.Duration {
+	return time.Second * time.Duration(tl.SessionMaxAgeInSecs)
+}
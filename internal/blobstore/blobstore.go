@@ -0,0 +1,23 @@
+// Package blobstore keeps uploaded files out of the database behind a small
+// interface so the local filesystem can later be swapped for S3 compatible
+// storage.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores opaque blobs under slash separated keys such as
+// "media/<id>/original".
+type BlobStore interface {
+	// Put writes the blob under key, replacing any existing one.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the blob stored under key, callers must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob, deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
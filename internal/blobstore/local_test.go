@@ -0,0 +1,65 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should read back a stored blob", func(t *testing.T) {
+		if err := store.Put(ctx, "media/1/original", strings.NewReader("first")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.Put(ctx, "media/1/original", strings.NewReader("second")); err != nil {
+			t.Fatal(err)
+		}
+
+		blob, err := store.Get(ctx, "media/1/original")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer blob.Close()
+
+		data, err := io.ReadAll(blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != "second" {
+			t.Errorf("expected the replaced blob, got %q", data)
+		}
+	})
+
+	t.Run("should report a missing blob after delete", func(t *testing.T) {
+		if err := store.Delete(ctx, "media/1/original"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.Delete(ctx, "media/1/original"); err != nil {
+			t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+		}
+
+		if _, err := store.Get(ctx, "media/1/original"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("expected ErrBlobNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should reject keys escaping the root", func(t *testing.T) {
+		for _, key := range []string{"", "/etc/passwd", "../outside", "media/../../outside", "media//1"} {
+			if err := store.Put(ctx, key, strings.NewReader("x")); err == nil {
+				t.Errorf("expected key %q to be rejected", key)
+			}
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -17,6 +18,36 @@ type Config struct {
 	RefreshTokenSecret       string
 	AccessTokenExpiryInSecs  int64
 	RefreshTokenExpiryInSecs int64
+
+	UserAccessTokenExpiryInSecs    int64
+	UserRefreshTokenExpiryInSecs   int64
+	UserSessionIdleTimeoutInSecs   int64
+	UserSessionMaxAgeInSecs        int64
+	AdminAccessTokenExpiryInSecs   int64
+	AdminRefreshTokenExpiryInSecs  int64
+	AdminSessionIdleTimeoutInSecs  int64
+	AdminSessionMaxAgeInSecs       int64
+	SellerAccessTokenExpiryInSecs  int64
+	SellerRefreshTokenExpiryInSecs int64
+	SellerSessionIdleTimeoutInSecs int64
+	SellerSessionMaxAgeInSecs      int64
+
+	WebAuthnRPID      string
+	WebAuthnRPName    string
+	WebAuthnRPOrigins []string
+
+	TrustedProxies        []string
+	AdminAllowedCIDRs     []string
+	AdminMTLSPort         string
+	AdminMTLSCertFile     string
+	AdminMTLSKeyFile      string
+	AdminMTLSClientCAFile string
+
+	BlobStoreDir string
+
+	DownloadURLSecret  string
+	DownloadBaseURL    string
+	DownloadLinkInSecs int64
 }
 
 func initConfig() *Config {
@@ -49,6 +80,115 @@ func initConfig() *Config {
 			"REFRESH_TOKEN_EXPIRY_IN_SECS",
 			720*24*7,
 		),
+		// a zero token expiry falls back to the generic one above, a zero
+		// idle timeout or max age disables that limit
+		UserAccessTokenExpiryInSecs: getEnvAsInt(
+			"USER_ACCESS_TOKEN_EXPIRY_IN_SECS",
+			0,
+		),
+		UserRefreshTokenExpiryInSecs: getEnvAsInt(
+			"USER_REFRESH_TOKEN_EXPIRY_IN_SECS",
+			30*24*60*60, // 30 days
+		),
+		UserSessionIdleTimeoutInSecs: getEnvAsInt(
+			"USER_SESSION_IDLE_TIMEOUT_IN_SECS",
+			14*24*60*60, // 14 days
+		),
+		UserSessionMaxAgeInSecs: getEnvAsInt(
+			"USER_SESSION_MAX_AGE_IN_SECS",
+			90*24*60*60, // 90 days
+		),
+		AdminAccessTokenExpiryInSecs: getEnvAsInt(
+			"ADMIN_ACCESS_TOKEN_EXPIRY_IN_SECS",
+			15*60, // 15 minutes
+		),
+		AdminRefreshTokenExpiryInSecs: getEnvAsInt(
+			"ADMIN_REFRESH_TOKEN_EXPIRY_IN_SECS",
+			8*60*60, // 8 hours
+		),
+		AdminSessionIdleTimeoutInSecs: getEnvAsInt(
+			"ADMIN_SESSION_IDLE_TIMEOUT_IN_SECS",
+			30*60, // 30 minutes
+		),
+		AdminSessionMaxAgeInSecs: getEnvAsInt(
+			"ADMIN_SESSION_MAX_AGE_IN_SECS",
+			12*60*60, // 12 hours
+		),
+		SellerAccessTokenExpiryInSecs: getEnvAsInt(
+			"SELLER_ACCESS_TOKEN_EXPIRY_IN_SECS",
+			15*60, // 15 minutes
+		),
+		SellerRefreshTokenExpiryInSecs: getEnvAsInt(
+			"SELLER_REFRESH_TOKEN_EXPIRY_IN_SECS",
+			7*24*60*60, // 7 days
+		),
+		SellerSessionIdleTimeoutInSecs: getEnvAsInt(
+			"SELLER_SESSION_IDLE_TIMEOUT_IN_SECS",
+			24*60*60, // 1 day
+		),
+		SellerSessionMaxAgeInSecs: getEnvAsInt(
+			"SELLER_SESSION_MAX_AGE_IN_SECS",
+			30*24*60*60, // 30 days
+		),
+		WebAuthnRPID: getEnvAsStr(
+			"WEBAUTHN_RP_ID",
+			"localhost",
+		),
+		WebAuthnRPName: getEnvAsStr(
+			"WEBAUTHN_RP_NAME",
+			"Yellow Pines",
+		),
+		WebAuthnRPOrigins: getEnvAsSlice(
+			"WEBAUTHN_RP_ORIGINS",
+			[]string{"http://localhost:3000"},
+		),
+		// proxies allowed to set X-Forwarded-For, as IPs or CIDRs
+		TrustedProxies: getEnvAsSlice(
+			"TRUSTED_PROXIES",
+			[]string{},
+		),
+		// an empty allowlist leaves the admin routes reachable from anywhere
+		AdminAllowedCIDRs: getEnvAsSlice(
+			"ADMIN_ALLOWED_CIDRS",
+			[]string{},
+		),
+		// the mutual TLS listener is only started when a port is set, the
+		// admin routes then require a client certificate signed by the CA
+		AdminMTLSPort: getEnvAsStr(
+			"ADMIN_MTLS_PORT",
+			"",
+		),
+		AdminMTLSCertFile: getEnvAsStr(
+			"ADMIN_MTLS_CERT_FILE",
+			"",
+		),
+		AdminMTLSKeyFile: getEnvAsStr(
+			"ADMIN_MTLS_KEY_FILE",
+			"",
+		),
+		AdminMTLSClientCAFile: getEnvAsStr(
+			"ADMIN_MTLS_CLIENT_CA_FILE",
+			"",
+		),
+		// uploaded media and their thumbnails are stored below this directory
+		BlobStoreDir: getEnvAsStr(
+			"BLOB_STORE_DIR",
+			"./data/blobs",
+		),
+		// download links of digital products are signed with this secret
+		// and point below the base url, the public url of the api
+		DownloadURLSecret: getEnvAsStr(
+			"DOWNLOAD_URL_SECRET",
+			"secret",
+		),
+		DownloadBaseURL: getEnvAsStr(
+			"DOWNLOAD_BASE_URL",
+			"http://localhost:8080/api/v1",
+		),
+		DownloadLinkInSecs: getEnvAsInt(
+			"DOWNLOAD_LINK_IN_SECS",
+			15*60, // 15 minutes
+		),
 	}
 }
 
@@ -70,3 +210,18 @@ func getEnvAsInt(key string, fallback int64) int64 {
 	}
 	return fallback
 }
+
+// getEnvAsSlice reads a comma separated list, ignoring empty items.
+func getEnvAsSlice(key string, fallback []string) []string {
+	if value, ok := os.LookupEnv(key); ok {
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		return items
+	}
+	return fallback
+}
@@ -11,6 +11,10 @@ type LoginAdminRequest struct {
 	ClientIP  string `json:"clientIP" validate:"required"`
 }
 
+type ReauthenticateRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
 // Responses
 type LoginAdminCookiesResponse struct {
 	AccessToken  interfaces.TokenDetails `json:"accessToken"`
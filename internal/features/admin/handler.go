@@ -6,36 +6,60 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
 	"github.com/go-chi/chi"
+	"github.com/google/uuid"
 )
 
 type servicer interface {
 	// registerAdmin(ctx context.Context, payload *RegisterAdminRequest) error
 	loginAdmin(ctx context.Context, payload *LoginAdminRequest) (*LoginAdminCookiesResponse, error)
-	logoutAdmin(ctx context.Context, refreshToken string) error
+	logoutAdmin(ctx context.Context, refreshToken string, accessToken string) error
+	revokeUserSessions(ctx context.Context, userID uuid.UUID) error
+	reauthenticate(ctx context.Context, adminID uuid.UUID, payload *ReauthenticateRequest) (*interfaces.TokenDetails, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
 }
 
 type handler struct {
-	service servicer
+	service       servicer
+	authenticator authenticator
 }
 
-func NewHandler(service servicer) *handler {
+func NewHandler(service servicer, authenticator authenticator) *handler {
 	return &handler{
-		service: service,
+		service:       service,
+		authenticator: authenticator,
 	}
 }
 
-func (h *handler) RegisterRoutes(router *chi.Mux) {
+// RegisterRoutes registers the admin routes relative to the admin route group,
+// which is mounted at /admin behind the admin access checks.
+func (h *handler) RegisterRoutes(router chi.Router) {
 	router.Post(
-		"/admin/login",
+		"/login",
 		handlerutils.MakeHandler(h.loginUserHandler),
 	)
 	router.Post(
-		"/admin/logout",
+		"/logout",
 		handlerutils.MakeHandler(h.logoutUserHandler),
 	)
+
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Post(
+		"/reauth",
+		handlerutils.MakeHandler(h.reauthenticateHandler),
+	)
+	authenticated.Post(
+		"/users/{userID}/sessions/revoke",
+		handlerutils.MakeHandler(h.revokeUserSessionsHandler),
+	)
 }
 
 func (h *handler) loginUserHandler(w http.ResponseWriter, r *http.Request) error {
@@ -123,12 +147,17 @@ func (h *handler) logoutUserHandler(w http.ResponseWriter, r *http.Request) erro
 		}
 	}
 
+	var accessToken string
+	if cookie, err := r.Cookie("accessToken"); err == nil {
+		accessToken = cookie.Value
+	}
+
 	cookiesNames := []string{
 		"accessToken",
 		"refreshToken",
 	}
 
-	if err = h.service.logoutAdmin(ctx, refreshToken.Value); err != nil {
+	if err = h.service.logoutAdmin(ctx, refreshToken.Value, accessToken); err != nil {
 		handlerutils.ClearCookie(
 			w,
 			&cookiesNames,
@@ -149,3 +178,105 @@ func (h *handler) logoutUserHandler(w http.ResponseWriter, r *http.Request) erro
 		nil,
 	)
 }
+
+func (h *handler) revokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.revokeUserSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"all user sessions and access tokens revoked",
+		nil,
+	)
+}
+
+func (h *handler) reauthenticateHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *ReauthenticateRequest
+	var err error
+	defer r.Body.Close()
+
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return servererrors.New(
+			http.StatusUnauthorized,
+			servererrors.ErrUnauthorized.Error(),
+			nil,
+		)
+	}
+
+	adminID, err := uuid.Parse(claims.EntityID)
+	if err != nil {
+		return err
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	accessToken, err := h.service.reauthenticate(ctx, adminID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrInvalidCredentials):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrInvalidCredentials.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	handlerutils.SetCookies(
+		w,
+		[]handlerutils.Cookie{
+			{
+				Name:    "accessToken",
+				Value:   accessToken.Value,
+				Expires: accessToken.Expires,
+			},
+		},
+	)
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"re-authenticated, elevated access token attached to cookies",
+		nil,
+	)
+}
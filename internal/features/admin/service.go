@@ -3,6 +3,7 @@ package admin
 import (
 	"context"
 
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
 	"github.com/google/uuid"
@@ -15,7 +16,9 @@ type adminStorer interface {
 }
 type sessionServicer interface {
 	LoginEntity(ctx context.Context, payload *interfaces.LoginEntityRequest) (*interfaces.LoginEntityCookiesResponse, error)
-	LogoutEntity(ctx context.Context, refreshToken string) error
+	LogoutEntity(ctx context.Context, refreshToken string, accessToken string) error
+	RevokeAllEntitySessions(ctx context.Context, entityID uuid.UUID, entityType string) error
+	IssueElevatedAccessToken(ctx context.Context, entityID uuid.UUID, entityType string, authMethods []string) (*interfaces.TokenDetails, error)
 }
 
 type service struct {
@@ -45,10 +48,11 @@ func (s *service) loginAdmin(ctx context.Context, payload *LoginAdminRequest) (*
 	resp, err := s.sessionService.LoginEntity(
 		ctx,
 		&interfaces.LoginEntityRequest{
-			EntityID:   admin.AdminID,
-			EntityType: "admin",
-			UserAgent:  payload.UserAgent,
-			ClientIP:   payload.ClientIP,
+			EntityID:    admin.AdminID,
+			EntityType:  auth.EntityTypeAdmin,
+			UserAgent:   payload.UserAgent,
+			ClientIP:    payload.ClientIP,
+			AuthMethods: []string{auth.AuthMethodPassword},
 		},
 	)
 	if err != nil {
@@ -67,6 +71,32 @@ func (s *service) loginAdmin(ctx context.Context, payload *LoginAdminRequest) (*
 	}, nil
 }
 
-func (s *service) logoutAdmin(ctx context.Context, refreshToken string) error {
-	return s.sessionService.LogoutEntity(ctx, refreshToken)
+func (s *service) logoutAdmin(ctx context.Context, refreshToken string, accessToken string) error {
+	return s.sessionService.LogoutEntity(ctx, refreshToken, accessToken)
+}
+
+// revokeUserSessions logs a customer out everywhere, revoking their refresh
+// sessions and every access token issued to them so far.
+func (s *service) revokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	return s.sessionService.RevokeAllEntitySessions(ctx, userID, auth.EntityTypeUser)
+}
+
+// reauthenticate checks the password of an already logged in admin and issues
+// an elevated access token for sensitive operations.
+func (s *service) reauthenticate(ctx context.Context, adminID uuid.UUID, payload *ReauthenticateRequest) (*interfaces.TokenDetails, error) {
+	admin, err := s.adminStore.findByID(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	if admin.AdminID == uuid.Nil || !admin.comparePassword(payload.Password) {
+		return nil, servererrors.ErrInvalidCredentials
+	}
+
+	return s.sessionService.IssueElevatedAccessToken(
+		ctx,
+		admin.AdminID,
+		auth.EntityTypeAdmin,
+		[]string{auth.AuthMethodPassword},
+	)
 }
@@ -0,0 +1,444 @@
+package bundle
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func newTestRouter(t *testing.T) (*chi.Mux, *mockStore) {
+	t.Helper()
+
+	bundleStore := newMockBundleStore()
+	bundleHandler := NewHandler(NewService(bundleStore), nil)
+
+	router := chi.NewRouter()
+	router.Get(
+		"/products/{productID}/bundle",
+		handlerutils.MakeHandler(bundleHandler.getBundleHandler(false)),
+	)
+	router.Get(
+		"/admin/products/{productID}/bundle",
+		handlerutils.MakeHandler(bundleHandler.getBundleHandler(true)),
+	)
+	router.Put(
+		"/admin/products/{productID}/bundle",
+		handlerutils.MakeHandler(bundleHandler.setBundleHandler),
+	)
+	router.Delete(
+		"/admin/products/{productID}/bundle",
+		handlerutils.MakeHandler(bundleHandler.deleteBundleHandler),
+	)
+	router.Post(
+		"/admin/products/{productID}/bundle/sales",
+		handlerutils.MakeHandler(bundleHandler.recordSaleHandler),
+	)
+	router.Get(
+		"/admin/products/{productID}/bundle/revenue",
+		handlerutils.MakeHandler(bundleHandler.revenueReportHandler),
+	)
+
+	return router, bundleStore
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestBundles(t *testing.T) {
+	router, bundleStore := newTestRouter(t)
+
+	kitID := bundleStore.addProduct(product.StatusActive, 5000)
+	boxID := bundleStore.addProduct(product.StatusActive, 3000)
+	draftID := bundleStore.addProduct("draft", 1000)
+	shirtID := bundleStore.addProduct(product.StatusActive, 0)
+	shirt := bundleStore.addVariant(shirtID, "SHIRT-M", 2000, 10)
+	mug := bundleStore.addVariant(bundleStore.addProduct(product.StatusActive, 0), "MUG", 1000, 3)
+	hat := bundleStore.addVariant(bundleStore.addProduct(product.StatusActive, 0), "HAT", 1500, 1)
+	kitVariant := bundleStore.addVariant(kitID, "KIT-XL", 5000, 0)
+
+	kitPath := "/admin/products/" + kitID.String() + "/bundle"
+	boxPath := "/admin/products/" + boxID.String() + "/bundle"
+
+	t.Run("should reject invalid bundles", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			payload SetBundleRequest
+			status  int
+		}{
+			{"no components", SetBundleRequest{Kind: KindFixed}, http.StatusUnprocessableEntity},
+			{"unknown kind", SetBundleRequest{Kind: "pack", Components: []ComponentRequest{{VariantID: mug}}}, http.StatusUnprocessableEntity},
+			{"fixed with a pick count", SetBundleRequest{Kind: KindFixed, PickCount: intPtr(2), Components: []ComponentRequest{{VariantID: mug}}}, http.StatusUnprocessableEntity},
+			{"mix and match without a pick count", SetBundleRequest{Kind: KindMixAndMatch, Components: []ComponentRequest{{VariantID: mug}}}, http.StatusUnprocessableEntity},
+			{"mix and match with quantities", SetBundleRequest{Kind: KindMixAndMatch, PickCount: intPtr(2), Components: []ComponentRequest{{VariantID: mug, Quantity: 2}}}, http.StatusUnprocessableEntity},
+			{"duplicate component", SetBundleRequest{Kind: KindFixed, Components: []ComponentRequest{{VariantID: mug}, {VariantID: mug}}}, http.StatusUnprocessableEntity},
+			{"own variant", SetBundleRequest{Kind: KindFixed, Components: []ComponentRequest{{VariantID: kitVariant}}}, http.StatusUnprocessableEntity},
+			{"unknown variant", SetBundleRequest{Kind: KindFixed, Components: []ComponentRequest{{VariantID: uuid.New()}}}, http.StatusUnprocessableEntity},
+		}
+
+		for _, c := range cases {
+			if code := testutil.Serve(t, router, http.MethodPut, kitPath, nil, c.payload, nil); code != c.status {
+				t.Errorf("%s: expected status code %d, got %d", c.name, c.status, code)
+			}
+		}
+
+		payload := SetBundleRequest{Kind: KindFixed, Components: []ComponentRequest{{VariantID: mug}}}
+		if code := testutil.Serve(t, router, http.MethodPut, "/admin/products/"+uuid.New().String()+"/bundle", nil, payload, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+	})
+
+	t.Run("should derive fixed bundle availability from its scarcest component", func(t *testing.T) {
+		var bundle Bundle
+		payload := SetBundleRequest{Kind: KindFixed, Components: []ComponentRequest{{VariantID: shirt, Quantity: 2}, {VariantID: mug}}}
+		if code := testutil.Serve(t, router, http.MethodPut, kitPath, nil, payload, &bundle); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		// 10 shirts make 5 kits but 3 mugs only 3
+		if bundle.Available != 3 || bundle.Price != 5000 || len(bundle.Components) != 2 {
+			t.Errorf("expected 3 kits at 5000 of 2 components, got %d at %d of %d", bundle.Available, bundle.Price, len(bundle.Components))
+		}
+
+		if code := testutil.Serve(t, router, http.MethodGet, "/products/"+kitID.String()+"/bundle", nil, nil, &bundle); code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, code)
+		}
+	})
+
+	t.Run("should not nest bundles", func(t *testing.T) {
+		payload := SetBundleRequest{Kind: KindFixed, Components: []ComponentRequest{{VariantID: kitVariant}}}
+		if code := testutil.Serve(t, router, http.MethodPut, boxPath, nil, payload, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+
+		payload = SetBundleRequest{Kind: KindFixed, Components: []ComponentRequest{{VariantID: mug}}}
+		if code := testutil.Serve(t, router, http.MethodPut, "/admin/products/"+shirtID.String()+"/bundle", nil, payload, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+	})
+
+	t.Run("should derive mix and match availability from the pooled stock", func(t *testing.T) {
+		var bundle Bundle
+		payload := SetBundleRequest{Kind: KindMixAndMatch, PickCount: intPtr(3), Components: []ComponentRequest{{VariantID: shirt}, {VariantID: mug}, {VariantID: hat}}}
+		if code := testutil.Serve(t, router, http.MethodPut, boxPath, nil, payload, &bundle); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		// 14 units in all make 4 picks of 3
+		if bundle.Available != 4 {
+			t.Errorf("expected 4 boxes available, got %d", bundle.Available)
+		}
+	})
+
+	t.Run("should hide the bundles of products not on sale", func(t *testing.T) {
+		payload := SetBundleRequest{Kind: KindFixed, Components: []ComponentRequest{{VariantID: mug}}}
+		if code := testutil.Serve(t, router, http.MethodPut, "/admin/products/"+draftID.String()+"/bundle", nil, payload, nil); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodGet, "/products/"+draftID.String()+"/bundle", nil, nil, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodGet, "/admin/products/"+draftID.String()+"/bundle", nil, nil, nil); code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, code)
+		}
+	})
+
+	orderID := uuid.New()
+
+	t.Run("should take the components of a sale out of stock and allocate its revenue", func(t *testing.T) {
+		var sale Sale
+		payload := RecordSaleRequest{OrderID: orderID, Quantity: 2}
+		if code := testutil.Serve(t, router, http.MethodPost, kitPath+"/sales", nil, payload, &sale); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if bundleStore.stock[shirt] != 6 || bundleStore.stock[mug] != 1 {
+			t.Errorf("expected 6 shirts and 1 mug left, got %d and %d", bundleStore.stock[shirt], bundleStore.stock[mug])
+		}
+
+		// 4 shirts worth 8000 and 2 mugs worth 2000 share 10000 by 4 to 1
+		if sale.Amount != 10000 || len(sale.Lines) != 2 || sale.Lines[0].Amount != 8000 || sale.Lines[1].Amount != 2000 {
+			t.Errorf("expected 10000 split into 8000 and 2000, got %+v", sale)
+		}
+	})
+
+	t.Run("should record the sale of an order once", func(t *testing.T) {
+		payload := RecordSaleRequest{OrderID: orderID, Quantity: 2}
+		if code := testutil.Serve(t, router, http.MethodPost, kitPath+"/sales", nil, payload, nil); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if bundleStore.stock[shirt] != 6 || len(bundleStore.sales) != 1 {
+			t.Errorf("expected the stock untouched and 1 sale, got %d shirts and %d sales", bundleStore.stock[shirt], len(bundleStore.sales))
+		}
+	})
+
+	t.Run("should refuse sales the stock can not cover", func(t *testing.T) {
+		payload := RecordSaleRequest{OrderID: uuid.New(), Quantity: 2}
+		if code := testutil.Serve(t, router, http.MethodPost, kitPath+"/sales", nil, payload, nil); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		if bundleStore.stock[shirt] != 6 || bundleStore.stock[mug] != 1 {
+			t.Errorf("expected the stock untouched, got %d shirts and %d mugs", bundleStore.stock[shirt], bundleStore.stock[mug])
+		}
+	})
+
+	t.Run("should check the picks of mix and match sales", func(t *testing.T) {
+		cases := []struct {
+			name  string
+			picks []Pick
+		}{
+			{"no picks", nil},
+			{"too few", []Pick{{VariantID: shirt, Quantity: 2}}},
+			{"not a component", []Pick{{VariantID: kitVariant, Quantity: 3}}},
+		}
+
+		for _, c := range cases {
+			payload := RecordSaleRequest{OrderID: uuid.New(), Quantity: 1, Picks: c.picks}
+			if code := testutil.Serve(t, router, http.MethodPost, boxPath+"/sales", nil, payload, nil); code != http.StatusUnprocessableEntity {
+				t.Errorf("%s: expected status code %d, got %d", c.name, http.StatusUnprocessableEntity, code)
+			}
+		}
+
+		payload := RecordSaleRequest{OrderID: uuid.New(), Quantity: 1, Picks: []Pick{{VariantID: mug, Quantity: 1}}}
+		if code := testutil.Serve(t, router, http.MethodPost, kitPath+"/sales", nil, payload, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d for picks in a fixed bundle, got %d", http.StatusUnprocessableEntity, code)
+		}
+
+		var sale Sale
+		payload = RecordSaleRequest{OrderID: uuid.New(), Quantity: 1, Picks: []Pick{{VariantID: shirt, Quantity: 1}, {VariantID: hat, Quantity: 1}, {VariantID: shirt, Quantity: 1}}}
+		if code := testutil.Serve(t, router, http.MethodPost, boxPath+"/sales", nil, payload, &sale); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		// 2 shirts worth 4000 and a hat worth 1500 share 3000
+		var total int64
+		for _, line := range sale.Lines {
+			total += line.Amount
+		}
+		if len(sale.Lines) != 2 || total != 3000 || sale.Lines[0].Amount != 2182 || sale.Lines[1].Amount != 818 {
+			t.Errorf("expected 3000 split into 2182 and 818, got %+v", sale.Lines)
+		}
+
+		if bundleStore.stock[shirt] != 4 || bundleStore.stock[hat] != 0 {
+			t.Errorf("expected 4 shirts and no hat left, got %d and %d", bundleStore.stock[shirt], bundleStore.stock[hat])
+		}
+	})
+
+	t.Run("should report the revenue allocated to components", func(t *testing.T) {
+		var report RevenueReportResponse
+		if code := testutil.Serve(t, router, http.MethodGet, kitPath+"/revenue", nil, nil, &report); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if len(report.Components) != 2 || report.Components[0].Amount != 8000 || report.Components[0].Quantity != 4 {
+			t.Errorf("expected 4 shirts worth 8000 first, got %+v", report.Components)
+		}
+
+		from := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		if code := testutil.Serve(t, router, http.MethodGet, kitPath+"/revenue?from="+from+"&to="+from, nil, nil, nil); code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, code)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodGet, kitPath+"/revenue?from=yesterday", nil, nil, nil); code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, code)
+		}
+	})
+
+	t.Run("should delete bundles", func(t *testing.T) {
+		if code := testutil.Serve(t, router, http.MethodDelete, kitPath, nil, nil, nil); code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodGet, kitPath, nil, nil, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+	})
+}
+
+func TestAllocate(t *testing.T) {
+	cases := []struct {
+		amount  int64
+		weights []int64
+		want    []int64
+	}{
+		{100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{1000, []int64{3, 1}, []int64{750, 250}},
+		{10, []int64{0, 0}, []int64{5, 5}},
+		{7, []int64{2, 3, 5}, []int64{1, 2, 4}},
+	}
+
+	for _, c := range cases {
+		got := allocate(c.amount, c.weights)
+		for i := range c.want {
+			if got[i] != c.want[i] {
+				t.Errorf("allocate(%d): expected %v, got %v", c.amount, c.want, got)
+				break
+			}
+		}
+	}
+}
+
+type mockStore struct {
+	products map[uuid.UUID]*productInfo
+	variants map[uuid.UUID]*Component
+	stock    map[uuid.UUID]int64
+	bundles  map[uuid.UUID]*Bundle
+	sales    []*Sale
+}
+
+func newMockBundleStore() *mockStore {
+	return &mockStore{
+		products: make(map[uuid.UUID]*productInfo),
+		variants: make(map[uuid.UUID]*Component),
+		stock:    make(map[uuid.UUID]int64),
+		bundles:  make(map[uuid.UUID]*Bundle),
+	}
+}
+
+func (m *mockStore) addProduct(status string, price int64) uuid.UUID {
+	productID := uuid.New()
+	m.products[productID] = &productInfo{Status: status, Price: price, Currency: "USD"}
+	return productID
+}
+
+func (m *mockStore) addVariant(productID uuid.UUID, sku string, price int64, stock int64) uuid.UUID {
+	variantID := uuid.New()
+	m.variants[variantID] = &Component{VariantID: variantID, ProductID: productID, SKU: sku, Price: price, Currency: "USD"}
+	m.stock[variantID] = stock
+	return variantID
+}
+
+func (m *mockStore) component(variantID uuid.UUID) *Component {
+	component := *m.variants[variantID]
+	component.StockQuantity = m.stock[variantID]
+	_, component.inBundle = m.bundles[component.ProductID]
+	return &component
+}
+
+func (m *mockStore) findProduct(ctx context.Context, productID uuid.UUID) (*productInfo, error) {
+	if product, ok := m.products[productID]; ok {
+		return product, nil
+	}
+
+	return &productInfo{}, nil
+}
+
+func (m *mockStore) findBundle(ctx context.Context, productID uuid.UUID) (*Bundle, error) {
+	stored, ok := m.bundles[productID]
+	if !ok {
+		return &Bundle{}, nil
+	}
+
+	bundle := *stored
+	bundle.Price = m.products[productID].Price
+	bundle.Currency = m.products[productID].Currency
+	bundle.Components = nil
+	for _, c := range stored.Components {
+		component := m.component(c.VariantID)
+		component.Quantity = c.Quantity
+		bundle.Components = append(bundle.Components, component)
+	}
+
+	return &bundle, nil
+}
+
+func (m *mockStore) findVariants(ctx context.Context, variantIDs []uuid.UUID) ([]*Component, error) {
+	var components []*Component
+	for _, variantID := range variantIDs {
+		if _, ok := m.variants[variantID]; ok {
+			components = append(components, m.component(variantID))
+		}
+	}
+
+	return components, nil
+}
+
+func (m *mockStore) isComponent(ctx context.Context, productID uuid.UUID) (bool, error) {
+	for _, bundle := range m.bundles {
+		for _, component := range bundle.Components {
+			if component.ProductID == productID {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (m *mockStore) saveBundle(ctx context.Context, bundle *Bundle) error {
+	m.bundles[bundle.ProductID] = bundle
+	return nil
+}
+
+func (m *mockStore) deleteBundle(ctx context.Context, productID uuid.UUID) error {
+	delete(m.bundles, productID)
+	return nil
+}
+
+func (m *mockStore) createSale(ctx context.Context, sale *Sale) (bool, error) {
+	for _, existing := range m.sales {
+		if existing.OrderID == sale.OrderID && existing.ProductID == sale.ProductID {
+			return false, nil
+		}
+	}
+
+	for _, line := range sale.Lines {
+		if m.stock[line.VariantID] < line.Quantity {
+			return false, servererrors.ErrInsufficientStock
+		}
+	}
+
+	for _, line := range sale.Lines {
+		m.stock[line.VariantID] -= line.Quantity
+	}
+
+	sale.CreatedAt = time.Now()
+	m.sales = append(m.sales, sale)
+	return true, nil
+}
+
+func (m *mockStore) findSale(ctx context.Context, orderID uuid.UUID, productID uuid.UUID) (*Sale, error) {
+	for _, sale := range m.sales {
+		if sale.OrderID == orderID && sale.ProductID == productID {
+			return sale, nil
+		}
+	}
+
+	return &Sale{}, nil
+}
+
+func (m *mockStore) revenue(ctx context.Context, productID uuid.UUID, from *time.Time, to *time.Time) ([]*ComponentRevenue, error) {
+	var revenue []*ComponentRevenue
+	byVariant := make(map[uuid.UUID]*ComponentRevenue)
+	for _, sale := range m.sales {
+		if sale.ProductID != productID || (from != nil && sale.CreatedAt.Before(*from)) || (to != nil && !sale.CreatedAt.Before(*to)) {
+			continue
+		}
+
+		for _, line := range sale.Lines {
+			r, ok := byVariant[line.VariantID]
+			if !ok {
+				r = &ComponentRevenue{VariantID: line.VariantID, SKU: line.SKU, Currency: sale.Currency}
+				byVariant[line.VariantID] = r
+				revenue = append(revenue, r)
+			}
+
+			r.Quantity += line.Quantity
+			r.Amount += line.Amount
+		}
+	}
+
+	return revenue, nil
+}
@@ -0,0 +1,57 @@
+package bundle
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Requests
+
+// SetBundleRequest turns a product into a bundle of the variants of other
+// products or replaces its components. PickCount is required by mix and
+// match bundles only.
+type SetBundleRequest struct {
+	Kind       string             `json:"kind" validate:"required,oneof=fixed mix_and_match"`
+	PickCount  *int               `json:"pickCount" validate:"omitempty,min=1,max=100"`
+	Components []ComponentRequest `json:"components" validate:"required,min=1,max=50,dive"`
+}
+
+// ComponentRequest is a variant of a bundle, Quantity defaults to 1 and
+// must be 1 in mix and match bundles.
+type ComponentRequest struct {
+	VariantID uuid.UUID `json:"variantId" validate:"required"`
+	Quantity  int       `json:"quantity" validate:"omitempty,min=1,max=100"`
+}
+
+// RecordSaleRequest records the sale of Quantity bundles in an order, taking
+// their components out of stock. Mix and match bundles list the picks of the
+// customer, PickCount units per bundle in all.
+type RecordSaleRequest struct {
+	OrderID  uuid.UUID `json:"orderId" validate:"required"`
+	Quantity int       `json:"quantity" validate:"min=1,max=100"`
+	Picks    []Pick    `json:"picks" validate:"max=100,dive"`
+}
+
+// Pick is a component picked in a mix and match bundle and how many units
+// of it.
+type Pick struct {
+	VariantID uuid.UUID `json:"variantId" validate:"required"`
+	Quantity  int       `json:"quantity" validate:"min=1,max=10000"`
+}
+
+// RevenueReportRequest is read from the query string, e.g.
+// ?from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z, both are optional.
+type RevenueReportRequest struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// Responses
+
+type RevenueReportResponse struct {
+	ProductID  uuid.UUID           `json:"productId"`
+	From       *time.Time          `json:"from"`
+	To         *time.Time          `json:"to"`
+	Components []*ComponentRevenue `json:"components"`
+}
@@ -0,0 +1,86 @@
+package bundle
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bundle kinds. A fixed bundle holds every component, a mix and match bundle
+// lets the customer pick PickCount units among its components.
+const (
+	KindFixed       = "fixed"
+	KindMixAndMatch = "mix_and_match"
+)
+
+// Bundle is a product sold at its own price that is made of the variants of
+// other products, it has no stock of its own.
+type Bundle struct {
+	ProductID  uuid.UUID    `json:"product_id"`
+	Kind       string       `json:"kind"`
+	PickCount  *int         `json:"pick_count"`
+	Price      int64        `json:"price"` // in minor units of currency e.g. cents
+	Currency   string       `json:"currency"`
+	Components []*Component `json:"components"`
+	// Available is how many bundles the stock of the components makes up
+	Available int64     `json:"available"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Component is a variant in a bundle, Quantity units of it go into each
+// fixed bundle while mix and match bundles take single units.
+type Component struct {
+	VariantID     uuid.UUID         `json:"variant_id"`
+	ProductID     uuid.UUID         `json:"product_id"`
+	SKU           string            `json:"sku"`
+	Name          string            `json:"name"`
+	Options       map[string]string `json:"options"`
+	Price         int64             `json:"price"` // standalone price of one unit
+	Currency      string            `json:"currency"`
+	Quantity      int               `json:"quantity"`
+	StockQuantity int64             `json:"-"` // stock not reserved by checkouts
+	Available     bool              `json:"available"`
+	// inBundle is set for the variants of bundle products, which can not be
+	// components themselves
+	inBundle bool
+}
+
+// Sale is a bundle sold in an order along with the components it took and
+// the share of its revenue allocated to each.
+type Sale struct {
+	SaleID    uuid.UUID   `json:"sale_id"`
+	OrderID   uuid.UUID   `json:"order_id"`
+	ProductID uuid.UUID   `json:"product_id"`
+	Quantity  int         `json:"quantity"`
+	Amount    int64       `json:"amount"`
+	Currency  string      `json:"currency"`
+	Lines     []*SaleLine `json:"lines"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// SaleLine is a component taken by a sale, the amounts of the lines add up
+// to the amount of the sale.
+type SaleLine struct {
+	VariantID uuid.UUID `json:"variant_id"`
+	SKU       string    `json:"sku"`
+	Quantity  int64     `json:"quantity"`
+	Amount    int64     `json:"amount"`
+}
+
+// ComponentRevenue is the revenue of a bundle allocated to one of its
+// components over a period.
+type ComponentRevenue struct {
+	VariantID uuid.UUID `json:"variant_id"`
+	SKU       string    `json:"sku"`
+	Quantity  int64     `json:"quantity"`
+	Amount    int64     `json:"amount"`
+	Currency  string    `json:"currency"`
+}
+
+// productInfo is what the bundle feature needs to know of a product.
+type productInfo struct {
+	Status   string
+	Price    int64
+	Currency string
+}
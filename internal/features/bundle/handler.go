@@ -0,0 +1,326 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	getBundle(ctx context.Context, productID uuid.UUID, includeUnpublished bool) (*Bundle, error)
+	setBundle(ctx context.Context, productID uuid.UUID, payload *SetBundleRequest) (*Bundle, error)
+	deleteBundle(ctx context.Context, productID uuid.UUID) error
+	RecordSale(ctx context.Context, orderID uuid.UUID, productID uuid.UUID, quantity int, picks []Pick) (*Sale, error)
+	revenueReport(ctx context.Context, productID uuid.UUID, payload *RevenueReportRequest) (*RevenueReportResponse, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	router.Get(
+		"/products/{productID}/bundle",
+		handlerutils.MakeHandler(h.getBundleHandler(false)),
+	)
+}
+
+// RegisterAdminRoutes registers the bundle management routes relative to
+// the admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/products/{productID}/bundle",
+		handlerutils.MakeHandler(h.getBundleHandler(true)),
+	)
+	authenticated.Put(
+		"/products/{productID}/bundle",
+		handlerutils.MakeHandler(h.setBundleHandler),
+	)
+	authenticated.Delete(
+		"/products/{productID}/bundle",
+		handlerutils.MakeHandler(h.deleteBundleHandler),
+	)
+	authenticated.Post(
+		"/products/{productID}/bundle/sales",
+		handlerutils.MakeHandler(h.recordSaleHandler),
+	)
+	authenticated.Get(
+		"/products/{productID}/bundle/revenue",
+		handlerutils.MakeHandler(h.revenueReportHandler),
+	)
+}
+
+// getBundleHandler serves a bundle with its availability, admins also see
+// the bundles of products that are not on sale.
+func (h *handler) getBundleHandler(includeUnpublished bool) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		bundle, err := h.service.getBundle(ctx, productID, includeUnpublished)
+		if err != nil {
+			return bundleError(err)
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"bundle found",
+			bundle,
+		)
+	}
+}
+
+func (h *handler) setBundleHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	var payload *SetBundleRequest
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	bundle, err := h.service.setBundle(ctx, productID, payload)
+	if err != nil {
+		return bundleError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"bundle saved",
+		bundle,
+	)
+}
+
+func (h *handler) deleteBundleHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteBundle(ctx, productID); err != nil {
+		return bundleError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"bundle deleted",
+		nil,
+	)
+}
+
+// recordSaleHandler records the sale of a bundle in an order until orders
+// record their bundles themselves.
+func (h *handler) recordSaleHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	var payload *RecordSaleRequest
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	sale, err := h.service.RecordSale(ctx, payload.OrderID, productID, payload.Quantity, payload.Picks)
+	if err != nil {
+		return bundleError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"sale recorded",
+		sale,
+	)
+}
+
+func (h *handler) revenueReportHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	payload := &RevenueReportRequest{}
+	if payload.From, err = handlerutils.ParseOptionalQueryTime(r, "from"); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	if payload.To, err = handlerutils.ParseOptionalQueryTime(r, "to"); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	report, err := h.service.revenueReport(ctx, productID, payload)
+	if err != nil {
+		return bundleError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"revenue report",
+		report,
+	)
+}
+
+func bundleError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrProductNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrProductNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrBundleNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrBundleNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrVariantNotFound):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrVariantNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidBundle):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidBundle.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidBundleSelection):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidBundleSelection.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInsufficientStock):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrInsufficientStock.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidQueryParams):
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,377 @@
+package bundle
+
+import (
+	"context"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type bundleStorer interface {
+	findProduct(ctx context.Context, productID uuid.UUID) (*productInfo, error)
+	findBundle(ctx context.Context, productID uuid.UUID) (*Bundle, error)
+	findVariants(ctx context.Context, variantIDs []uuid.UUID) ([]*Component, error)
+	isComponent(ctx context.Context, productID uuid.UUID) (bool, error)
+	saveBundle(ctx context.Context, bundle *Bundle) error
+	deleteBundle(ctx context.Context, productID uuid.UUID) error
+	createSale(ctx context.Context, sale *Sale) (bool, error)
+	findSale(ctx context.Context, orderID uuid.UUID, productID uuid.UUID) (*Sale, error)
+	revenue(ctx context.Context, productID uuid.UUID, from *time.Time, to *time.Time) ([]*ComponentRevenue, error)
+}
+
+type service struct {
+	bundleStore bundleStorer
+}
+
+func NewService(bundleStore bundleStorer) *service {
+	return &service{
+		bundleStore: bundleStore,
+	}
+}
+
+// getBundle returns a bundle along with how many of it the component stock
+// makes up, bundles of products not on sale are only returned when
+// includeUnpublished is set.
+func (s *service) getBundle(ctx context.Context, productID uuid.UUID, includeUnpublished bool) (*Bundle, error) {
+	info, err := s.bundleStore.findProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Status == "" || (info.Status != product.StatusActive && !includeUnpublished) {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	bundle, err := s.bundleStore.findBundle(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.ProductID == uuid.Nil {
+		return nil, servererrors.ErrBundleNotFound
+	}
+
+	setAvailability(bundle)
+
+	return bundle, nil
+}
+
+// setBundle turns a product into a bundle of the variants of other products,
+// or replaces the components of a bundle. Bundles do not nest, a bundle can
+// not be a component and a component can not become a bundle.
+func (s *service) setBundle(ctx context.Context, productID uuid.UUID, payload *SetBundleRequest) (*Bundle, error) {
+	product, err := s.bundleStore.findProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Status == "" {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	if (payload.Kind == KindMixAndMatch) != (payload.PickCount != nil) {
+		return nil, servererrors.ErrInvalidBundle
+	}
+
+	isComponent, err := s.bundleStore.isComponent(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isComponent {
+		return nil, servererrors.ErrInvalidBundle
+	}
+
+	variantIDs := make([]uuid.UUID, 0, len(payload.Components))
+	quantities := make(map[uuid.UUID]int, len(payload.Components))
+	for _, component := range payload.Components {
+		if _, ok := quantities[component.VariantID]; ok {
+			return nil, servererrors.ErrInvalidBundle
+		}
+
+		quantity := component.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+
+		if payload.Kind == KindMixAndMatch && quantity != 1 {
+			return nil, servererrors.ErrInvalidBundle
+		}
+
+		variantIDs = append(variantIDs, component.VariantID)
+		quantities[component.VariantID] = quantity
+	}
+
+	variants, err := s.bundleStore.findVariants(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*Component, len(variants))
+	for _, variant := range variants {
+		byID[variant.VariantID] = variant
+	}
+
+	bundle := &Bundle{
+		ProductID:  productID,
+		Kind:       payload.Kind,
+		PickCount:  payload.PickCount,
+		Components: make([]*Component, 0, len(variantIDs)),
+	}
+	for _, variantID := range variantIDs {
+		variant, ok := byID[variantID]
+		if !ok {
+			return nil, servererrors.ErrVariantNotFound
+		}
+
+		if variant.ProductID == productID || variant.inBundle {
+			return nil, servererrors.ErrInvalidBundle
+		}
+
+		variant.Quantity = quantities[variantID]
+		bundle.Components = append(bundle.Components, variant)
+	}
+
+	if err = s.bundleStore.saveBundle(ctx, bundle); err != nil {
+		return nil, err
+	}
+
+	return s.getBundle(ctx, productID, true)
+}
+
+// deleteBundle turns a bundle back into a plain product.
+func (s *service) deleteBundle(ctx context.Context, productID uuid.UUID) error {
+	bundle, err := s.bundleStore.findBundle(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if bundle.ProductID == uuid.Nil {
+		return servererrors.ErrBundleNotFound
+	}
+
+	return s.bundleStore.deleteBundle(ctx, productID)
+}
+
+// RecordSale records the sale of quantity bundles in an order, taking their
+// components out of stock and allocating the bundle revenue to them. It is
+// meant to be called as the order is placed, picks list the components the
+// customer chose in a mix and match bundle. Recording the sale of an order
+// again returns the sale already recorded.
+func (s *service) RecordSale(ctx context.Context, orderID uuid.UUID, productID uuid.UUID, quantity int, picks []Pick) (*Sale, error) {
+	bundle, err := s.bundleStore.findBundle(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.ProductID == uuid.Nil {
+		return nil, servererrors.ErrBundleNotFound
+	}
+
+	existing, err := s.bundleStore.findSale(ctx, orderID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.SaleID != uuid.Nil {
+		return existing, nil
+	}
+
+	units, err := componentUnits(bundle, quantity, picks)
+	if err != nil {
+		return nil, err
+	}
+
+	sale := &Sale{
+		SaleID:    uuid.New(),
+		OrderID:   orderID,
+		ProductID: productID,
+		Quantity:  quantity,
+		Amount:    bundle.Price * int64(quantity),
+		Currency:  bundle.Currency,
+	}
+
+	weights := make([]int64, 0, len(bundle.Components))
+	for _, component := range bundle.Components {
+		if units[component.VariantID] == 0 {
+			continue
+		}
+
+		sale.Lines = append(sale.Lines, &SaleLine{
+			VariantID: component.VariantID,
+			SKU:       component.SKU,
+			Quantity:  units[component.VariantID],
+		})
+		weights = append(weights, revenueWeight(bundle, component, units[component.VariantID]))
+	}
+
+	for i, amount := range allocate(sale.Amount, weights) {
+		sale.Lines[i].Amount = amount
+	}
+
+	if _, err = s.bundleStore.createSale(ctx, sale); err != nil {
+		return nil, err
+	}
+
+	return s.bundleStore.findSale(ctx, orderID, productID)
+}
+
+// revenueReport sums the revenue of a bundle allocated to each of its
+// components over a period.
+func (s *service) revenueReport(ctx context.Context, productID uuid.UUID, payload *RevenueReportRequest) (*RevenueReportResponse, error) {
+	if payload.From != nil && payload.To != nil && !payload.To.After(*payload.From) {
+		return nil, servererrors.ErrInvalidQueryParams
+	}
+
+	product, err := s.bundleStore.findProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Status == "" {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	revenue, err := s.bundleStore.revenue(ctx, productID, payload.From, payload.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RevenueReportResponse{
+		ProductID:  productID,
+		From:       payload.From,
+		To:         payload.To,
+		Components: revenue,
+	}, nil
+}
+
+// componentUnits returns how many units of each component quantity bundles
+// take, a fixed bundle takes its components while a mix and match one takes
+// the picks, which must add up to its pick count per bundle.
+func componentUnits(bundle *Bundle, quantity int, picks []Pick) (map[uuid.UUID]int64, error) {
+	units := make(map[uuid.UUID]int64, len(bundle.Components))
+
+	if bundle.Kind == KindFixed {
+		if len(picks) > 0 {
+			return nil, servererrors.ErrInvalidBundleSelection
+		}
+
+		for _, component := range bundle.Components {
+			units[component.VariantID] = int64(component.Quantity) * int64(quantity)
+		}
+
+		return units, nil
+	}
+
+	eligible := make(map[uuid.UUID]bool, len(bundle.Components))
+	for _, component := range bundle.Components {
+		eligible[component.VariantID] = true
+	}
+
+	var picked int64
+	for _, pick := range picks {
+		if !eligible[pick.VariantID] {
+			return nil, servererrors.ErrInvalidBundleSelection
+		}
+
+		units[pick.VariantID] += int64(pick.Quantity)
+		picked += int64(pick.Quantity)
+	}
+
+	if picked != int64(*bundle.PickCount)*int64(quantity) {
+		return nil, servererrors.ErrInvalidBundleSelection
+	}
+
+	return units, nil
+}
+
+// setAvailability works out how many bundles the component stock makes up
+// and which components are in stock. A fixed bundle is limited by its
+// scarcest component, a mix and match bundle by the stock of all its
+// components together.
+func setAvailability(bundle *Bundle) {
+	bundle.Available = 0
+	if len(bundle.Components) == 0 {
+		return
+	}
+
+	if bundle.Kind == KindMixAndMatch {
+		var stock int64
+		for _, component := range bundle.Components {
+			component.Available = component.StockQuantity > 0
+			stock += component.StockQuantity
+		}
+
+		bundle.Available = stock / int64(*bundle.PickCount)
+		return
+	}
+
+	for i, component := range bundle.Components {
+		component.Available = component.StockQuantity >= int64(component.Quantity)
+
+		available := component.StockQuantity / int64(component.Quantity)
+		if i == 0 || available < bundle.Available {
+			bundle.Available = available
+		}
+	}
+}
+
+// revenueWeight is the share of the bundle revenue a component earns, its
+// standalone price for the units taken. Components priced in another
+// currency than the bundle can not be compared so every unit weighs the
+// same then.
+func revenueWeight(bundle *Bundle, component *Component, units int64) int64 {
+	for _, c := range bundle.Components {
+		if c.Currency != bundle.Currency {
+			return units
+		}
+	}
+
+	return component.Price * units
+}
+
+// allocate splits amount in proportion to weights, rounding down and handing
+// the remainder out one unit at a time to the largest fractions so the parts
+// add up to amount. Equal parts are used when every weight is zero.
+func allocate(amount int64, weights []int64) []int64 {
+	parts := make([]int64, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+
+	var total int64
+	for _, weight := range weights {
+		total += weight
+	}
+
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = int64(len(weights))
+	}
+
+	remainders := make([]int64, len(weights))
+	allocated := int64(0)
+	for i, weight := range weights {
+		parts[i] = amount * weight / total
+		remainders[i] = amount * weight % total
+		allocated += parts[i]
+	}
+
+	for ; allocated < amount; allocated++ {
+		largest := 0
+		for i := range remainders {
+			if remainders[i] > remainders[largest] {
+				largest = i
+			}
+		}
+
+		parts[largest]++
+		remainders[largest] = -1
+	}
+
+	return parts
+}
@@ -0,0 +1,523 @@
+package bundle
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	componentFields = `v.variant_id, v.product_id, v.sku, p.name, v.option_values, COALESCE(v.price_override, p.price), p.currency, COALESCE(vs.quantity - vs.reserved, 0),
+		EXISTS(SELECT 1 FROM bundles ob WHERE ob.product_id = v.product_id)`
+	componentJoins = "product_variants v JOIN products p ON p.product_id = v.product_id LEFT JOIN variant_stock vs ON vs.variant_id = v.variant_id"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+// findProduct returns the status and price of a product, a zero productInfo
+// when there is none.
+func (s *store) findProduct(ctx context.Context, productID uuid.UUID) (*productInfo, error) {
+	product := new(productInfo)
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT status, price, currency FROM products WHERE product_id = $1",
+		productID,
+	).Scan(&product.Status, &product.Price, &product.Currency)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"failed to find product in bundle store: %w",
+			err,
+		)
+	}
+
+	return product, nil
+}
+
+// findBundle returns a bundle with its components in order, a zero Bundle
+// when the product is not one.
+func (s *store) findBundle(ctx context.Context, productID uuid.UUID) (*Bundle, error) {
+	bundle := &Bundle{Components: []*Component{}}
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT b.product_id, b.kind, b.pick_count, p.price, p.currency, b.created_at, b.updated_at FROM bundles b JOIN products p ON p.product_id = b.product_id WHERE b.product_id = $1",
+		productID,
+	).Scan(
+		&bundle.ProductID,
+		&bundle.Kind,
+		&bundle.PickCount,
+		&bundle.Price,
+		&bundle.Currency,
+		&bundle.CreatedAt,
+		&bundle.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return new(Bundle), nil
+		}
+
+		return nil, fmt.Errorf(
+			"failed to find bundle in bundle store: %w",
+			err,
+		)
+	}
+
+	bundle.Components, err = s.getComponentsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s, bc.quantity FROM bundle_components bc JOIN %s ON v.variant_id = bc.variant_id WHERE bc.product_id = $1 ORDER BY bc.position ASC", componentFields, componentJoins),
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find bundle components in bundle store: %w",
+			err,
+		)
+	}
+
+	return bundle, nil
+}
+
+// findVariants returns the variants found among variantIDs as components of
+// quantity 0.
+func (s *store) findVariants(ctx context.Context, variantIDs []uuid.UUID) ([]*Component, error) {
+	components, err := s.getComponentsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s, 0 FROM %s WHERE v.variant_id = ANY($1)", componentFields, componentJoins),
+		pq.Array(variantIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find variants in bundle store: %w",
+			err,
+		)
+	}
+
+	return components, nil
+}
+
+// isComponent tells whether a variant of the product is in some bundle.
+func (s *store) isComponent(ctx context.Context, productID uuid.UUID) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM bundle_components bc JOIN product_variants v ON v.variant_id = bc.variant_id WHERE v.product_id = $1)",
+		productID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to find bundle components in bundle store: %w",
+			err,
+		)
+	}
+
+	return exists, nil
+}
+
+// saveBundle creates or replaces a bundle and its components atomically.
+func (s *store) saveBundle(ctx context.Context, bundle *Bundle) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO bundles(product_id, kind, pick_count) VALUES($1, $2, $3)
+			ON CONFLICT (product_id) DO UPDATE SET kind = EXCLUDED.kind, pick_count = EXCLUDED.pick_count, updated_at = NOW()`,
+			bundle.ProductID,
+			bundle.Kind,
+			bundle.PickCount,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save bundle in bundle store: %w", err)
+		}
+
+		if _, err = tx.ExecContext(ctx, "DELETE FROM bundle_components WHERE product_id = $1", bundle.ProductID); err != nil {
+			return fmt.Errorf("failed to delete bundle components in bundle store: %w", err)
+		}
+
+		for position, component := range bundle.Components {
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO bundle_components(product_id, variant_id, quantity, position) VALUES($1, $2, $3, $4)",
+				bundle.ProductID,
+				component.VariantID,
+				component.Quantity,
+				position,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert bundle component in bundle store: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// deleteBundle turns a bundle back into a plain product, keeping its sales.
+func (s *store) deleteBundle(ctx context.Context, productID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM bundles WHERE product_id = $1",
+		productID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to delete bundle in bundle store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// createSale records a sale and takes its components out of stock, all or
+// nothing. It returns servererrors.ErrInsufficientStock when a component is
+// short and false when the order already has a sale of the bundle.
+func (s *store) createSale(ctx context.Context, sale *Sale) (bool, error) {
+	created := false
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO bundle_sales(sale_id, order_id, product_id, quantity, amount, currency) VALUES($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (order_id, product_id) DO NOTHING`,
+			sale.SaleID,
+			sale.OrderID,
+			sale.ProductID,
+			sale.Quantity,
+			sale.Amount,
+			sale.Currency,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert bundle sale in bundle store: %w", err)
+		}
+
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count inserted bundle sales in bundle store: %w", err)
+		}
+
+		if inserted == 0 {
+			return nil
+		}
+
+		// variants are updated in id order so concurrent sales of
+		// overlapping bundles lock them in the same order, stock owed to
+		// backorders is not for sale
+		lines := slices.Clone(sale.Lines)
+		slices.SortFunc(lines, func(a, b *SaleLine) int {
+			return slices.Compare(a.VariantID[:], b.VariantID[:])
+		})
+
+		for _, line := range lines {
+			result, err = tx.ExecContext(
+				ctx,
+				"UPDATE variant_stock SET quantity = quantity - $1, updated_at = NOW() WHERE variant_id = $2 AND quantity - reserved - backordered >= $1",
+				line.Quantity,
+				line.VariantID,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update variant stock in bundle store: %w", err)
+			}
+
+			updated, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to count updated variant stock in bundle store: %w", err)
+			}
+
+			if updated == 0 {
+				return servererrors.ErrInsufficientStock
+			}
+
+			// variants kept at locations ship from them in priority order
+			rows, err := tx.QueryContext(
+				ctx,
+				`UPDATE location_stock ls SET quantity = ls.quantity - d.take, updated_at = NOW()
+				FROM (
+					SELECT s.location_id, LEAST(s.quantity, GREATEST($1 - (SUM(s.quantity) OVER w - s.quantity), 0)) AS take
+					FROM location_stock s JOIN stock_locations l ON l.location_id = s.location_id
+					WHERE s.variant_id = $2
+					WINDOW w AS (ORDER BY l.priority ASC, l.code ASC ROWS UNBOUNDED PRECEDING)
+				) d
+				WHERE ls.location_id = d.location_id AND ls.variant_id = $2 AND d.take > 0
+				RETURNING ls.location_id, d.take`,
+				line.Quantity,
+				line.VariantID,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update location stock in bundle store: %w", err)
+			}
+
+			movements, err := saleMovements(rows, sale, line)
+			if err != nil {
+				return err
+			}
+
+			if err = stockledger.Record(ctx, tx, movements...); err != nil {
+				return fmt.Errorf("failed to record bundle sale in bundle store: %w", err)
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO bundle_sale_lines(sale_id, variant_id, sku, quantity, amount) VALUES($1, $2, $3, $4, $5)",
+				sale.SaleID,
+				line.VariantID,
+				line.SKU,
+				line.Quantity,
+				line.Amount,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert bundle sale line in bundle store: %w", err)
+			}
+		}
+
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// saleMovements scans the stock taken from each location for a line of a
+// sale into movements, a variant not kept at locations moves as a whole.
+func saleMovements(rows *sql.Rows, sale *Sale, line *SaleLine) ([]*stockledger.Movement, error) {
+	defer rows.Close()
+
+	var movements []*stockledger.Movement
+	for rows.Next() {
+		var locationID uuid.UUID
+		var taken int64
+		if err := rows.Scan(&locationID, &taken); err != nil {
+			return nil, fmt.Errorf("failed to scan location stock in bundle store: %w", err)
+		}
+
+		movements = append(movements, &stockledger.Movement{
+			VariantID:  line.VariantID,
+			LocationID: &locationID,
+			Quantity:   -taken,
+			Reason:     stockledger.ReasonSale,
+			Reference:  sale.OrderID.String(),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate location stock in bundle store: %w", err)
+	}
+
+	if len(movements) == 0 {
+		movements = append(movements, &stockledger.Movement{
+			VariantID: line.VariantID,
+			Quantity:  -line.Quantity,
+			Reason:    stockledger.ReasonSale,
+			Reference: sale.OrderID.String(),
+		})
+	}
+
+	return movements, nil
+}
+
+// findSale returns the sale of a bundle in an order, a zero Sale when there
+// is none.
+func (s *store) findSale(ctx context.Context, orderID uuid.UUID, productID uuid.UUID) (*Sale, error) {
+	sale := &Sale{Lines: []*SaleLine{}}
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT sale_id, order_id, product_id, quantity, amount, currency, created_at FROM bundle_sales WHERE order_id = $1 AND product_id = $2",
+		orderID,
+		productID,
+	).Scan(
+		&sale.SaleID,
+		&sale.OrderID,
+		&sale.ProductID,
+		&sale.Quantity,
+		&sale.Amount,
+		&sale.Currency,
+		&sale.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return new(Sale), nil
+		}
+
+		return nil, fmt.Errorf(
+			"failed to find bundle sale in bundle store: %w",
+			err,
+		)
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT variant_id, sku, quantity, amount FROM bundle_sale_lines WHERE sale_id = $1 ORDER BY sku ASC",
+		sale.SaleID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find bundle sale lines in bundle store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line := new(SaleLine)
+		if err = rows.Scan(&line.VariantID, &line.SKU, &line.Quantity, &line.Amount); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into bundle sale line in bundle store: %w",
+				err,
+			)
+		}
+
+		sale.Lines = append(sale.Lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in bundle store: %w",
+			err,
+		)
+	}
+
+	return sale, nil
+}
+
+// revenue sums the revenue of a bundle allocated to each component over the
+// sales made from from, inclusive, to to, exclusive. Either bound may be nil.
+func (s *store) revenue(ctx context.Context, productID uuid.UUID, from *time.Time, to *time.Time) ([]*ComponentRevenue, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT l.variant_id, MAX(l.sku), SUM(l.quantity), SUM(l.amount), bs.currency
+		FROM bundle_sale_lines l JOIN bundle_sales bs ON bs.sale_id = l.sale_id
+		WHERE bs.product_id = $1 AND ($2::timestamptz IS NULL OR bs.created_at >= $2) AND ($3::timestamptz IS NULL OR bs.created_at < $3)
+		GROUP BY l.variant_id, bs.currency
+		ORDER BY SUM(l.amount) DESC, MAX(l.sku) ASC`,
+		productID,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to sum bundle revenue in bundle store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	revenue := []*ComponentRevenue{}
+	for rows.Next() {
+		component := new(ComponentRevenue)
+		err = rows.Scan(
+			&component.VariantID,
+			&component.SKU,
+			&component.Quantity,
+			&component.Amount,
+			&component.Currency,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into component revenue in bundle store: %w",
+				err,
+			)
+		}
+
+		revenue = append(revenue, component)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in bundle store: %w",
+			err,
+		)
+	}
+
+	return revenue, nil
+}
+
+func (s *store) getComponentsWithContext(ctx context.Context, query string, args ...any) ([]*Component, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in bundle store getComponentsWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	components := []*Component{}
+	for rows.Next() {
+		component := new(Component)
+		var options []byte
+		err = rows.Scan(
+			&component.VariantID,
+			&component.ProductID,
+			&component.SKU,
+			&component.Name,
+			&options,
+			&component.Price,
+			&component.Currency,
+			&component.StockQuantity,
+			&component.inBundle,
+			&component.Quantity,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into component in bundle store: %w",
+				err,
+			)
+		}
+
+		if err = json.Unmarshal(options, &component.Options); err != nil {
+			return nil, fmt.Errorf(
+				"failed to decode component options in bundle store: %w",
+				err,
+			)
+		}
+
+		components = append(components, component)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in bundle store: %w",
+			err,
+		)
+	}
+
+	return components, nil
+}
+
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in bundle store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in bundle store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func (h *handler) createAttributeHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *CreateAttributeRequest
+	var err error
+	defer r.Body.Close()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	definition, err := h.service.createAttribute(ctx, categoryID, payload)
+	if err != nil {
+		return attributeError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"attribute created",
+		definition,
+	)
+}
+
+func (h *handler) updateAttributeHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *UpdateAttributeRequest
+	var err error
+	defer r.Body.Close()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	attributeID, err := uuid.Parse(chi.URLParam(r, "attributeID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	definition, err := h.service.updateAttribute(ctx, categoryID, attributeID, payload)
+	if err != nil {
+		return attributeError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"attribute updated",
+		definition,
+	)
+}
+
+func (h *handler) deleteAttributeHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	attributeID, err := uuid.Parse(chi.URLParam(r, "attributeID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteAttribute(ctx, categoryID, attributeID); err != nil {
+		return attributeError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"attribute deleted",
+		nil,
+	)
+}
+
+// listAttributesHandler returns the attributes the products of a category
+// have, including those inherited from its ancestors.
+func (h *handler) listAttributesHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	definitions, err := h.service.listAttributes(ctx, categoryID)
+	if err != nil {
+		return attributeError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"attributes found",
+		definitions,
+	)
+}
+
+// attributeError maps the errors shared by the attribute handlers to
+// responses.
+func attributeError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrCategoryNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrCategoryNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrAttributeNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrAttributeNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrAttributeAlreadyExists):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrAttributeAlreadyExists.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidAttribute):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidAttribute.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
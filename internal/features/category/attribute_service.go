@@ -0,0 +1,118 @@
+package category
+
+import (
+	"context"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+func (s *service) createAttribute(ctx context.Context, categoryID uuid.UUID, payload *CreateAttributeRequest) (*attribute.Definition, error) {
+	if _, err := s.findCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+
+	definition := &attribute.Definition{
+		AttributeID: uuid.New(),
+		CategoryID:  categoryID,
+		Code:        attribute.NormalizeCode(payload.Code),
+		Name:        strings.TrimSpace(payload.Name),
+		Type:        payload.Type,
+		Unit:        strings.TrimSpace(payload.Unit),
+		Values:      trimValues(payload.Values),
+		Required:    payload.Required,
+		Filterable:  payload.Filterable,
+	}
+
+	if !definition.Check() {
+		return nil, servererrors.ErrInvalidAttribute
+	}
+
+	if err := s.categoryStore.createAttribute(ctx, definition); err != nil {
+		return nil, err
+	}
+
+	return s.categoryStore.findAttribute(ctx, definition.AttributeID)
+}
+
+func (s *service) updateAttribute(ctx context.Context, categoryID uuid.UUID, attributeID uuid.UUID, payload *UpdateAttributeRequest) (*attribute.Definition, error) {
+	definition, err := s.findAttribute(ctx, categoryID, attributeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Name != nil {
+		definition.Name = strings.TrimSpace(*payload.Name)
+	}
+
+	if payload.Unit != nil {
+		definition.Unit = strings.TrimSpace(*payload.Unit)
+	}
+
+	if payload.Values != nil {
+		definition.Values = trimValues(payload.Values)
+	}
+
+	if payload.Required != nil {
+		definition.Required = *payload.Required
+	}
+
+	if payload.Filterable != nil {
+		definition.Filterable = *payload.Filterable
+	}
+
+	if !definition.Check() {
+		return nil, servererrors.ErrInvalidAttribute
+	}
+
+	if err = s.categoryStore.updateAttribute(ctx, definition); err != nil {
+		return nil, err
+	}
+
+	return s.categoryStore.findAttribute(ctx, attributeID)
+}
+
+func (s *service) deleteAttribute(ctx context.Context, categoryID uuid.UUID, attributeID uuid.UUID) error {
+	definition, err := s.findAttribute(ctx, categoryID, attributeID)
+	if err != nil {
+		return err
+	}
+
+	return s.categoryStore.deleteAttribute(ctx, definition)
+}
+
+// listAttributes returns the attribute schema of the products of a category,
+// inherited attributes included.
+func (s *service) listAttributes(ctx context.Context, categoryID uuid.UUID) ([]*attribute.Definition, error) {
+	if _, err := s.findCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+
+	return s.categoryStore.findAttributes(ctx, categoryID)
+}
+
+// findAttribute returns an attribute defined on categoryID itself, inherited
+// attributes are managed on the category that defines them.
+func (s *service) findAttribute(ctx context.Context, categoryID uuid.UUID, attributeID uuid.UUID) (*attribute.Definition, error) {
+	definition, err := s.categoryStore.findAttribute(ctx, attributeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if definition.AttributeID == uuid.Nil || definition.CategoryID != categoryID {
+		return nil, servererrors.ErrAttributeNotFound
+	}
+
+	return definition, nil
+}
+
+func trimValues(values []string) []string {
+	trimmed := make([]string, 0, len(values))
+	for _, value := range values {
+		trimmed = append(trimmed, strings.TrimSpace(value))
+	}
+
+	return trimmed
+}
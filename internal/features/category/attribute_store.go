@@ -0,0 +1,209 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const attributeFields = "a.attribute_id, a.category_id, a.code, a.name, a.type, a.unit, a.enum_values, a.required, a.filterable, a.position, a.created_at, a.updated_at"
+
+// createAttribute adds an attribute as the last of its category. Its code
+// must not be used by the category's ancestors, which it would inherit, nor
+// by its descendants, which would inherit it. The tree lock keeps a
+// concurrent move from changing either side while this is checked.
+func (s *store) createAttribute(ctx context.Context, definition *attribute.Definition) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, lockTree); err != nil {
+			return fmt.Errorf("failed to lock category tree in category store: %w", err)
+		}
+
+		var taken bool
+		err := tx.QueryRowContext(
+			ctx,
+			"SELECT EXISTS(SELECT 1 FROM category_attributes a JOIN category_closure cc ON (cc.ancestor_id = a.category_id AND cc.descendant_id = $1) OR (cc.descendant_id = a.category_id AND cc.ancestor_id = $1) WHERE a.code = $2)",
+			definition.CategoryID,
+			definition.Code,
+		).Scan(&taken)
+		if err != nil {
+			return fmt.Errorf("failed to check attribute code in category store: %w", err)
+		}
+
+		if taken {
+			return servererrors.ErrAttributeAlreadyExists
+		}
+
+		err = tx.QueryRowContext(
+			ctx,
+			`INSERT INTO category_attributes(attribute_id, category_id, code, name, type, unit, enum_values, required, filterable, position)
+			SELECT $1, $2::uuid, $3, $4, $5, $6, $7, $8, $9, COALESCE(MAX(position) + 1, 0) FROM category_attributes WHERE category_id = $2::uuid
+			RETURNING position`,
+			definition.AttributeID,
+			definition.CategoryID,
+			definition.Code,
+			definition.Name,
+			definition.Type,
+			definition.Unit,
+			pq.Array(definition.Values),
+			definition.Required,
+			definition.Filterable,
+		).Scan(&definition.Position)
+		if err != nil {
+			switch {
+			case isPQError(err, uniqueViolation):
+				return servererrors.ErrAttributeAlreadyExists
+			case isPQError(err, foreignKeyViolation):
+				return servererrors.ErrCategoryNotFound
+			default:
+				return fmt.Errorf("failed to insert new attribute in category store: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *store) findAttribute(ctx context.Context, attributeID uuid.UUID) (*attribute.Definition, error) {
+	definitions, err := s.getAttributesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM category_attributes a WHERE a.attribute_id = $1", attributeFields),
+		attributeID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find attribute by id in category store: %w",
+			err,
+		)
+	}
+
+	if len(definitions) == 0 {
+		return new(attribute.Definition), nil
+	}
+
+	return definitions[0], nil
+}
+
+// findAttributes returns the attributes that apply to the products of
+// categoryID, its own and those inherited from its ancestors, root first.
+// Should a move have brought the same code in twice, the definition nearest
+// to the category wins.
+func (s *store) findAttributes(ctx context.Context, categoryID uuid.UUID) ([]*attribute.Definition, error) {
+	definitions, err := s.getAttributesWithContext(
+		ctx,
+		fmt.Sprintf(
+			`SELECT %s FROM (
+				SELECT DISTINCT ON (a.code) a.*, cc.depth FROM category_attributes a
+				JOIN category_closure cc ON cc.ancestor_id = a.category_id
+				WHERE cc.descendant_id = $1
+				ORDER BY a.code, cc.depth
+			) a ORDER BY a.depth DESC, a.position, a.code`,
+			attributeFields,
+		),
+		categoryID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find category attributes in category store: %w",
+			err,
+		)
+	}
+
+	return definitions, nil
+}
+
+func (s *store) updateAttribute(ctx context.Context, definition *attribute.Definition) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE category_attributes SET name = $1, unit = $2, enum_values = $3, required = $4, filterable = $5, updated_at = NOW() WHERE attribute_id = $6",
+		definition.Name,
+		definition.Unit,
+		pq.Array(definition.Values),
+		definition.Required,
+		definition.Filterable,
+		definition.AttributeID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update attribute in category store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// deleteAttribute removes an attribute definition, the values products hold
+// for it are left alone and dropped the next time their attributes are set.
+func (s *store) deleteAttribute(ctx context.Context, definition *attribute.Definition) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM category_attributes WHERE attribute_id = $1",
+			definition.AttributeID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete attribute in category store: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE category_attributes SET position = position - 1 WHERE category_id = $1 AND position > $2",
+			definition.CategoryID,
+			definition.Position,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to shift attributes in category store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *store) getAttributesWithContext(ctx context.Context, query string, args ...any) ([]*attribute.Definition, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in category store getAttributesWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	definitions := []*attribute.Definition{}
+	for rows.Next() {
+		definition := new(attribute.Definition)
+		err = rows.Scan(
+			&definition.AttributeID,
+			&definition.CategoryID,
+			&definition.Code,
+			&definition.Name,
+			&definition.Type,
+			&definition.Unit,
+			pq.Array(&definition.Values),
+			&definition.Required,
+			&definition.Filterable,
+			&definition.Position,
+			&definition.CreatedAt,
+			&definition.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into attribute in category store: %w",
+				err,
+			)
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, rows.Err()
+}
@@ -0,0 +1,211 @@
+package category
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestCategoryAttributes(t *testing.T) {
+	router, _ := newTestRouter()
+
+	create := func(name string, parentID *uuid.UUID) uuid.UUID {
+		t.Helper()
+
+		category := new(Category)
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories", nil, CreateCategoryRequest{Name: name, ParentID: parentID}, category)
+		if code != http.StatusCreated {
+			t.Fatalf("expected status code %d creating %s, got %d", http.StatusCreated, name, code)
+		}
+
+		return category.CategoryID
+	}
+
+	electronics := create("Electronics", nil)
+	laptops := create("Laptops", &electronics)
+	phones := create("Phones", &electronics)
+
+	define := func(categoryID uuid.UUID, payload CreateAttributeRequest) (*attribute.Definition, int) {
+		t.Helper()
+
+		definition := new(attribute.Definition)
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories/"+categoryID.String()+"/attributes", nil, payload, definition)
+		return definition, code
+	}
+
+	warranty, code := define(electronics, CreateAttributeRequest{Code: "Warranty", Name: "Warranty", Type: attribute.TypeNumber, Unit: "months"})
+	if code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+	}
+
+	if warranty.Code != "warranty" {
+		t.Errorf("expected the code to be lower cased, got %s", warranty.Code)
+	}
+
+	if _, code = define(laptops, CreateAttributeRequest{Code: "ram", Name: "RAM", Type: attribute.TypeNumber, Unit: "GB", Required: true, Filterable: true}); code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+	}
+
+	panel, code := define(laptops, CreateAttributeRequest{Code: "panel", Name: "Panel", Type: attribute.TypeEnum, Values: []string{"IPS", "OLED"}})
+	if code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+	}
+
+	t.Run("should inherit the attributes of ancestors", func(t *testing.T) {
+		definitions := []*attribute.Definition{}
+		code := testutil.Serve(t, router, http.MethodGet, "/categories/"+laptops.String()+"/attributes", nil, nil, &definitions)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if codes := attributeCodes(definitions); !slices.Equal(codes, []string{"warranty", "ram", "panel"}) {
+			t.Errorf("expected warranty, ram and panel, got %v", codes)
+		}
+
+		testutil.Serve(t, router, http.MethodGet, "/categories/"+phones.String()+"/attributes", nil, nil, &definitions)
+		if codes := attributeCodes(definitions); !slices.Equal(codes, []string{"warranty"}) {
+			t.Errorf("expected only warranty, got %v", codes)
+		}
+	})
+
+	t.Run("should reject codes used along the same branch", func(t *testing.T) {
+		testCases := []struct {
+			categoryID uuid.UUID
+			code       string
+			expected   int
+		}{
+			{laptops, "warranty", http.StatusConflict}, // defined by an ancestor
+			{electronics, "ram", http.StatusConflict},  // defined by a descendant
+			{phones, "ram", http.StatusCreated},        // a sibling branch
+		}
+
+		for _, tc := range testCases {
+			_, code := define(tc.categoryID, CreateAttributeRequest{Code: tc.code, Name: tc.code, Type: attribute.TypeText})
+			if code != tc.expected {
+				t.Errorf("expected status code %d defining %s, got %d", tc.expected, tc.code, code)
+			}
+		}
+	})
+
+	t.Run("should reject inconsistent definitions", func(t *testing.T) {
+		payloads := []CreateAttributeRequest{
+			{Code: "size_system", Name: "Size system", Type: attribute.TypeEnum},
+			{Code: "touchscreen", Name: "Touchscreen", Type: attribute.TypeBoolean, Unit: "inch"},
+			{Code: "heel height", Name: "Heel height", Type: attribute.TypeNumber},
+		}
+
+		for _, payload := range payloads {
+			if _, code := define(laptops, payload); code != http.StatusUnprocessableEntity {
+				t.Errorf("expected status code %d for %+v, got %d", http.StatusUnprocessableEntity, payload, code)
+			}
+		}
+	})
+
+	t.Run("should update an attribute on its own category only", func(t *testing.T) {
+		path := "/admin/categories/" + laptops.String() + "/attributes/" + panel.AttributeID.String()
+
+		updated := new(attribute.Definition)
+		code := testutil.Serve(t, router, http.MethodPatch, path, nil, UpdateAttributeRequest{Values: []string{"IPS", "OLED", "Mini LED"}}, updated)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if len(updated.Values) != 3 {
+			t.Errorf("expected 3 panel values, got %v", updated.Values)
+		}
+
+		path = "/admin/categories/" + electronics.String() + "/attributes/" + panel.AttributeID.String()
+		if code = testutil.Serve(t, router, http.MethodDelete, path, nil, nil, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+	})
+}
+
+func attributeCodes(definitions []*attribute.Definition) []string {
+	codes := []string{}
+	for _, definition := range definitions {
+		codes = append(codes, definition.Code)
+	}
+
+	return codes
+}
+
+func (m *mockStore) createAttribute(ctx context.Context, definition *attribute.Definition) error {
+	for _, existing := range m.Attributes {
+		if existing.Code != definition.Code {
+			continue
+		}
+
+		if m.isAncestor(existing.CategoryID, definition.CategoryID) || m.isAncestor(definition.CategoryID, existing.CategoryID) {
+			return servererrors.ErrAttributeAlreadyExists
+		}
+	}
+
+	for _, existing := range m.Attributes {
+		if existing.CategoryID == definition.CategoryID {
+			definition.Position++
+		}
+	}
+
+	m.Attributes[definition.AttributeID] = definition
+	return nil
+}
+
+func (m *mockStore) findAttribute(ctx context.Context, attributeID uuid.UUID) (*attribute.Definition, error) {
+	definition, exists := m.Attributes[attributeID]
+	if !exists {
+		return new(attribute.Definition), nil
+	}
+
+	d := *definition
+	return &d, nil
+}
+
+func (m *mockStore) findAttributes(ctx context.Context, categoryID uuid.UUID) ([]*attribute.Definition, error) {
+	ancestors, _ := m.findAncestors(ctx, categoryID)
+
+	definitions := []*attribute.Definition{}
+	for _, ancestor := range ancestors {
+		own := []*attribute.Definition{}
+		for _, definition := range m.Attributes {
+			if definition.CategoryID == ancestor.CategoryID {
+				own = append(own, definition)
+			}
+		}
+
+		slices.SortFunc(own, func(a, b *attribute.Definition) int {
+			return a.Position - b.Position
+		})
+		definitions = append(definitions, own...)
+	}
+
+	return definitions, nil
+}
+
+func (m *mockStore) updateAttribute(ctx context.Context, definition *attribute.Definition) error {
+	m.Attributes[definition.AttributeID] = definition
+	return nil
+}
+
+func (m *mockStore) deleteAttribute(ctx context.Context, definition *attribute.Definition) error {
+	delete(m.Attributes, definition.AttributeID)
+	return nil
+}
+
+// isAncestor reports whether ancestorID is categoryID or above it.
+func (m *mockStore) isAncestor(ancestorID, categoryID uuid.UUID) bool {
+	ancestors, _ := m.findAncestors(context.Background(), categoryID)
+	for _, ancestor := range ancestors {
+		if ancestor.CategoryID == ancestorID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,357 @@
+package category
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func newTestRouter() (*chi.Mux, *mockStore) {
+	categoryStore := newMockCategoryStore()
+	categoryHandler := NewHandler(NewService(categoryStore), nil)
+
+	router := chi.NewRouter()
+	router.Get(
+		"/categories",
+		handlerutils.MakeHandler(categoryHandler.getTreeHandler),
+	)
+	router.Get(
+		"/categories/{categoryID}/breadcrumb",
+		handlerutils.MakeHandler(categoryHandler.getBreadcrumbHandler),
+	)
+	router.Post(
+		"/admin/categories",
+		handlerutils.MakeHandler(categoryHandler.createCategoryHandler),
+	)
+	router.Post(
+		"/admin/categories/reorder",
+		handlerutils.MakeHandler(categoryHandler.reorderCategoriesHandler),
+	)
+	router.Post(
+		"/admin/categories/{categoryID}/move",
+		handlerutils.MakeHandler(categoryHandler.moveCategoryHandler),
+	)
+	router.Delete(
+		"/admin/categories/{categoryID}",
+		handlerutils.MakeHandler(categoryHandler.deleteCategoryHandler),
+	)
+	router.Get(
+		"/categories/{categoryID}/attributes",
+		handlerutils.MakeHandler(categoryHandler.listAttributesHandler),
+	)
+	router.Post(
+		"/admin/categories/{categoryID}/attributes",
+		handlerutils.MakeHandler(categoryHandler.createAttributeHandler),
+	)
+	router.Patch(
+		"/admin/categories/{categoryID}/attributes/{attributeID}",
+		handlerutils.MakeHandler(categoryHandler.updateAttributeHandler),
+	)
+	router.Delete(
+		"/admin/categories/{categoryID}/attributes/{attributeID}",
+		handlerutils.MakeHandler(categoryHandler.deleteAttributeHandler),
+	)
+	router.Get(
+		"/categories/by-slug/{slug}",
+		handlerutils.MakeHandler(categoryHandler.getCategoryBySlugHandler),
+	)
+	router.Put(
+		"/admin/categories/{categoryID}/seo",
+		handlerutils.MakeHandler(categoryHandler.setSEOHandler),
+	)
+	router.Put(
+		"/admin/categories/{categoryID}/slugs/{locale}",
+		handlerutils.MakeHandler(seo.SetSlugHandler("categoryID", categoryHandler.service.setSlug, slugError)),
+	)
+
+	return router, categoryStore
+}
+
+func TestCategoryTree(t *testing.T) {
+	router, _ := newTestRouter()
+
+	create := func(name string, parentID *uuid.UUID) uuid.UUID {
+		t.Helper()
+
+		category := new(Category)
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories", nil, CreateCategoryRequest{Name: name, ParentID: parentID}, category)
+		if code != http.StatusCreated {
+			t.Fatalf("expected status code %d creating %s, got %d", http.StatusCreated, name, code)
+		}
+
+		return category.CategoryID
+	}
+
+	men := create("Men", nil)
+	women := create("Women", nil)
+	shoes := create("Shoes", &men)
+	running := create("Running", &shoes)
+	boots := create("Boots", &shoes)
+
+	t.Run("should reject a duplicate sibling name", func(t *testing.T) {
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories", nil, CreateCategoryRequest{Name: "shoes", ParentID: &men}, nil)
+		if code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+	})
+
+	t.Run("should return the breadcrumb from the root", func(t *testing.T) {
+		breadcrumb := []*Category{}
+		code := testutil.Serve(t, router, http.MethodGet, "/categories/"+running.String()+"/breadcrumb", nil, nil, &breadcrumb)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if names := categoryNames(breadcrumb); !slices.Equal(names, []string{"Men", "Shoes", "Running"}) {
+			t.Errorf("expected breadcrumb Men > Shoes > Running, got %v", names)
+		}
+	})
+
+	t.Run("should not move a category under its own subtree", func(t *testing.T) {
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories/"+men.String()+"/move", nil, MoveCategoryRequest{ParentID: &running}, nil)
+		if code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+	})
+
+	t.Run("should move a subtree", func(t *testing.T) {
+		position := 0
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories/"+shoes.String()+"/move", nil, MoveCategoryRequest{ParentID: &women, Position: &position}, nil)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		breadcrumb := []*Category{}
+		testutil.Serve(t, router, http.MethodGet, "/categories/"+boots.String()+"/breadcrumb", nil, nil, &breadcrumb)
+		if names := categoryNames(breadcrumb); !slices.Equal(names, []string{"Women", "Shoes", "Boots"}) {
+			t.Errorf("expected breadcrumb Women > Shoes > Boots, got %v", names)
+		}
+	})
+
+	t.Run("should reorder siblings", func(t *testing.T) {
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories/reorder", nil, ReorderCategoriesRequest{ParentID: &shoes, CategoryIDs: []uuid.UUID{boots, running}}, nil)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		tree := []*Node{}
+		testutil.Serve(t, router, http.MethodGet, "/categories", nil, nil, &tree)
+
+		if names := nodeNames(tree); !slices.Equal(names, []string{"Men", "Women"}) {
+			t.Fatalf("expected roots Men, Women, got %v", names)
+		}
+
+		if names := nodeNames(tree[1].Children[0].Children); !slices.Equal(names, []string{"Boots", "Running"}) {
+			t.Errorf("expected Boots before Running, got %v", names)
+		}
+	})
+
+	t.Run("should reject a partial sibling order", func(t *testing.T) {
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories/reorder", nil, ReorderCategoriesRequest{ParentID: &shoes, CategoryIDs: []uuid.UUID{boots}}, nil)
+		if code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+	})
+
+	t.Run("should not delete a category with subcategories", func(t *testing.T) {
+		code := testutil.Serve(t, router, http.MethodDelete, "/admin/categories/"+shoes.String(), nil, nil, nil)
+		if code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+	})
+}
+
+func categoryNames(categories []*Category) []string {
+	names := []string{}
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+
+	return names
+}
+
+func nodeNames(nodes []*Node) []string {
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	return names
+}
+
+// mockStore keeps the tree as parent links and derives what the closure
+// table would hold by walking them.
+type mockStore struct {
+	*testutil.SlugStore
+	Categories map[uuid.UUID]*Category
+	Attributes map[uuid.UUID]*attribute.Definition
+}
+
+func newMockCategoryStore() *mockStore {
+	return &mockStore{
+		SlugStore:  testutil.NewSlugStore(),
+		Categories: make(map[uuid.UUID]*Category),
+		Attributes: make(map[uuid.UUID]*attribute.Definition),
+	}
+}
+
+func (m *mockStore) create(ctx context.Context, category *Category) error {
+	for _, sibling := range m.siblings(category.ParentID) {
+		if equalFoldName(sibling.Name, category.Name) {
+			return servererrors.ErrCategoryAlreadyExists
+		}
+	}
+
+	category.Position = len(m.siblings(category.ParentID))
+	m.Categories[category.CategoryID] = category
+	return nil
+}
+
+func (m *mockStore) findByID(ctx context.Context, categoryID uuid.UUID) (*Category, error) {
+	category, exists := m.Categories[categoryID]
+	if !exists {
+		return new(Category), nil
+	}
+
+	category.Slugs = m.Current(categoryID)
+
+	return category, nil
+}
+
+func (m *mockStore) findAll(ctx context.Context) ([]*Category, error) {
+	categories := []*Category{}
+	for _, category := range m.Categories {
+		categories = append(categories, category)
+	}
+	sortByPosition(categories)
+
+	return categories, nil
+}
+
+func (m *mockStore) findChildren(ctx context.Context, parentID *uuid.UUID) ([]*Category, error) {
+	return m.siblings(parentID), nil
+}
+
+func (m *mockStore) findAncestors(ctx context.Context, categoryID uuid.UUID) ([]*Category, error) {
+	ancestors := []*Category{}
+	for category, ok := m.Categories[categoryID]; ok; {
+		ancestors = append([]*Category{category}, ancestors...)
+		if category.ParentID == nil {
+			break
+		}
+		category, ok = m.Categories[*category.ParentID]
+	}
+
+	return ancestors, nil
+}
+
+func (m *mockStore) updateName(ctx context.Context, categoryID uuid.UUID, name string) error {
+	m.Categories[categoryID].Name = name
+	return nil
+}
+
+func (m *mockStore) move(ctx context.Context, categoryID uuid.UUID, parentID *uuid.UUID, position *int) error {
+	category, ok := m.Categories[categoryID]
+	if !ok {
+		return servererrors.ErrCategoryNotFound
+	}
+
+	if parentID != nil {
+		ancestors, _ := m.findAncestors(ctx, *parentID)
+		if len(ancestors) == 0 {
+			return servererrors.ErrCategoryNotFound
+		}
+
+		for _, ancestor := range ancestors {
+			if ancestor.CategoryID == categoryID {
+				return servererrors.ErrInvalidCategoryMove
+			}
+		}
+	}
+
+	for _, sibling := range m.siblings(category.ParentID) {
+		if sibling.Position > category.Position {
+			sibling.Position--
+		}
+	}
+
+	siblings := m.siblings(parentID)
+	newPosition := len(siblings)
+	if position != nil {
+		newPosition = min(*position, len(siblings))
+	}
+
+	for _, sibling := range siblings {
+		if sibling.Position >= newPosition {
+			sibling.Position++
+		}
+	}
+
+	category.ParentID = parentID
+	category.Position = newPosition
+	return nil
+}
+
+func (m *mockStore) reorder(ctx context.Context, parentID *uuid.UUID, categoryIDs []uuid.UUID) error {
+	siblings := m.siblings(parentID)
+	if len(siblings) != len(categoryIDs) {
+		return servererrors.ErrInvalidCategoryOrder
+	}
+
+	for i, categoryID := range categoryIDs {
+		category, ok := m.Categories[categoryID]
+		if !ok || !slices.Contains(siblings, category) {
+			return servererrors.ErrInvalidCategoryOrder
+		}
+		category.Position = i
+	}
+
+	return nil
+}
+
+func (m *mockStore) delete(ctx context.Context, category *Category) error {
+	delete(m.Categories, category.CategoryID)
+	return nil
+}
+
+func (m *mockStore) assignProducts(ctx context.Context, categoryID uuid.UUID, productIDs []uuid.UUID) error {
+	return nil
+}
+
+func (m *mockStore) unassignProduct(ctx context.Context, categoryID uuid.UUID, productID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockStore) siblings(parentID *uuid.UUID) []*Category {
+	siblings := []*Category{}
+	for _, category := range m.Categories {
+		if (category.ParentID == nil && parentID == nil) ||
+			(category.ParentID != nil && parentID != nil && *category.ParentID == *parentID) {
+			siblings = append(siblings, category)
+		}
+	}
+	sortByPosition(siblings)
+
+	return siblings
+}
+
+func sortByPosition(categories []*Category) {
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Position < categories[j].Position
+	})
+}
+
+func equalFoldName(a, b string) bool {
+	return bytes.EqualFold([]byte(a), []byte(b))
+}
@@ -0,0 +1,79 @@
+package category
+
+import "github.com/google/uuid"
+
+// Requests
+
+type CreateCategoryRequest struct {
+	Name     string     `json:"name" validate:"required,min=1,max=100"`
+	ParentID *uuid.UUID `json:"parentId"`
+}
+
+type RenameCategoryRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+// MoveCategoryRequest moves a category with its subtree under ParentID, or to
+// the root when it is null. Position defaults to the end of the new siblings.
+type MoveCategoryRequest struct {
+	ParentID *uuid.UUID `json:"parentId"`
+	Position *int       `json:"position" validate:"omitempty,gte=0"`
+}
+
+// ReorderCategoriesRequest lists every child of ParentID, or every root
+// category when it is null, in the new order.
+type ReorderCategoriesRequest struct {
+	ParentID    *uuid.UUID  `json:"parentId"`
+	CategoryIDs []uuid.UUID `json:"categoryIds" validate:"required,min=1,unique"`
+}
+
+// SetSEORequest replaces what search engines are shown about a category
+// page, blank fields fall back to the category's own.
+type SetSEORequest struct {
+	Title        string `json:"title" validate:"max=255"`
+	Description  string `json:"description" validate:"max=500"`
+	CanonicalURL string `json:"canonicalUrl" validate:"omitempty,http_url,max=2048"`
+}
+
+type AssignProductsRequest struct {
+	ProductIDs []uuid.UUID `json:"productIds" validate:"required,min=1,max=500,unique"`
+}
+
+// Responses
+
+type CategoryResponse struct {
+	Category   *Category   `json:"category"`
+	Breadcrumb []*Category `json:"breadcrumb"`
+	Children   []*Category `json:"children"`
+}
+
+// SlugRedirectResponse is the body of the permanent redirect from a previous
+// slug, Slug is the category's current slug in Locale.
+type SlugRedirectResponse struct {
+	CategoryID uuid.UUID `json:"categoryId"`
+	Locale     string    `json:"locale"`
+	Slug       string    `json:"slug"`
+}
+
+// CreateAttributeRequest defines an attribute for the products of a category
+// and of every category below it. Code is the key of the value in product
+// attributes, e.g. "ram". Enums list their Values, numbers may have a Unit.
+type CreateAttributeRequest struct {
+	Code       string   `json:"code" validate:"required,max=64"`
+	Name       string   `json:"name" validate:"required,min=1,max=100"`
+	Type       string   `json:"type" validate:"required,oneof=text number enum boolean"`
+	Unit       string   `json:"unit" validate:"max=20"`
+	Values     []string `json:"values" validate:"max=200,dive,required,max=100"`
+	Required   bool     `json:"required"`
+	Filterable bool     `json:"filterable"`
+}
+
+// UpdateAttributeRequest only changes the fields that are set, the code and
+// type of an attribute are fixed once products may hold values for it.
+type UpdateAttributeRequest struct {
+	Name       *string  `json:"name" validate:"omitempty,min=1,max=100"`
+	Unit       *string  `json:"unit" validate:"omitempty,max=20"`
+	Values     []string `json:"values" validate:"omitempty,max=200,dive,required,max=100"`
+	Required   *bool    `json:"required"`
+	Filterable *bool    `json:"filterable"`
+}
@@ -0,0 +1,26 @@
+package category
+
+import (
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/google/uuid"
+)
+
+type Category struct {
+	CategoryID uuid.UUID    `json:"category_id"`
+	ParentID   *uuid.UUID   `json:"parent_id"`
+	Name       string       `json:"name"`
+	Position   int          `json:"position"`
+	SEO        seo.Metadata `json:"seo"`
+	// Slugs are the current slugs of the category keyed by locale
+	Slugs     map[string]string `json:"slugs"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Node is a category with its subcategories, used to render the tree.
+type Node struct {
+	*Category
+	Children []*Node `json:"children"`
+}
@@ -0,0 +1,687 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	createCategory(ctx context.Context, payload *CreateCategoryRequest) (*Category, error)
+	renameCategory(ctx context.Context, categoryID uuid.UUID, payload *RenameCategoryRequest) (*Category, error)
+	moveCategory(ctx context.Context, categoryID uuid.UUID, payload *MoveCategoryRequest) (*Category, error)
+	reorderCategories(ctx context.Context, payload *ReorderCategoriesRequest) ([]*Category, error)
+	deleteCategory(ctx context.Context, categoryID uuid.UUID) error
+	getTree(ctx context.Context) ([]*Node, error)
+	getCategory(ctx context.Context, categoryID uuid.UUID) (*CategoryResponse, error)
+	getBreadcrumb(ctx context.Context, categoryID uuid.UUID) ([]*Category, error)
+	assignProducts(ctx context.Context, categoryID uuid.UUID, payload *AssignProductsRequest) error
+	unassignProduct(ctx context.Context, categoryID uuid.UUID, productID uuid.UUID) error
+	createAttribute(ctx context.Context, categoryID uuid.UUID, payload *CreateAttributeRequest) (*attribute.Definition, error)
+	updateAttribute(ctx context.Context, categoryID uuid.UUID, attributeID uuid.UUID, payload *UpdateAttributeRequest) (*attribute.Definition, error)
+	deleteAttribute(ctx context.Context, categoryID uuid.UUID, attributeID uuid.UUID) error
+	listAttributes(ctx context.Context, categoryID uuid.UUID) ([]*attribute.Definition, error)
+	setSEO(ctx context.Context, categoryID uuid.UUID, payload *SetSEORequest) (*Category, error)
+	getCategoryBySlug(ctx context.Context, locale string, slug string) (*CategoryResponse, string, error)
+	listSlugs(ctx context.Context, categoryID uuid.UUID) ([]*seo.Slug, error)
+	setSlug(ctx context.Context, categoryID uuid.UUID, locale string, payload *seo.SetSlugRequest) (*seo.Slug, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+// RegisterRoutes registers the storefront category routes. The products of a
+// category are listed by the product feature under
+// /categories/{categoryID}/products.
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	router.Get(
+		"/categories",
+		handlerutils.MakeHandler(h.getTreeHandler),
+	)
+	router.Get(
+		"/categories/by-slug/{slug}",
+		handlerutils.MakeHandler(h.getCategoryBySlugHandler),
+	)
+	router.Get(
+		"/categories/{categoryID}",
+		handlerutils.MakeHandler(h.getCategoryHandler),
+	)
+	router.Get(
+		"/categories/{categoryID}/breadcrumb",
+		handlerutils.MakeHandler(h.getBreadcrumbHandler),
+	)
+	router.Get(
+		"/categories/{categoryID}/attributes",
+		handlerutils.MakeHandler(h.listAttributesHandler),
+	)
+}
+
+// RegisterAdminRoutes registers the category management routes relative to
+// the admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Post(
+		"/categories",
+		handlerutils.MakeHandler(h.createCategoryHandler),
+	)
+	authenticated.Post(
+		"/categories/reorder",
+		handlerutils.MakeHandler(h.reorderCategoriesHandler),
+	)
+	authenticated.Patch(
+		"/categories/{categoryID}",
+		handlerutils.MakeHandler(h.renameCategoryHandler),
+	)
+	authenticated.Put(
+		"/categories/{categoryID}/seo",
+		handlerutils.MakeHandler(h.setSEOHandler),
+	)
+	authenticated.Get(
+		"/categories/{categoryID}/slugs",
+		handlerutils.MakeHandler(seo.ListSlugsHandler("categoryID", h.service.listSlugs, slugError)),
+	)
+	authenticated.Put(
+		"/categories/{categoryID}/slugs/{locale}",
+		handlerutils.MakeHandler(seo.SetSlugHandler("categoryID", h.service.setSlug, slugError)),
+	)
+	authenticated.Post(
+		"/categories/{categoryID}/move",
+		handlerutils.MakeHandler(h.moveCategoryHandler),
+	)
+	authenticated.Delete(
+		"/categories/{categoryID}",
+		handlerutils.MakeHandler(h.deleteCategoryHandler),
+	)
+	authenticated.Post(
+		"/categories/{categoryID}/products",
+		handlerutils.MakeHandler(h.assignProductsHandler),
+	)
+	authenticated.Delete(
+		"/categories/{categoryID}/products/{productID}",
+		handlerutils.MakeHandler(h.unassignProductHandler),
+	)
+	authenticated.Post(
+		"/categories/{categoryID}/attributes",
+		handlerutils.MakeHandler(h.createAttributeHandler),
+	)
+	authenticated.Patch(
+		"/categories/{categoryID}/attributes/{attributeID}",
+		handlerutils.MakeHandler(h.updateAttributeHandler),
+	)
+	authenticated.Delete(
+		"/categories/{categoryID}/attributes/{attributeID}",
+		handlerutils.MakeHandler(h.deleteAttributeHandler),
+	)
+}
+
+func (h *handler) createCategoryHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *CreateCategoryRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	category, err := h.service.createCategory(ctx, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrCategoryAlreadyExists):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrCategoryAlreadyExists.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"category created",
+		category,
+	)
+}
+
+func (h *handler) renameCategoryHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *RenameCategoryRequest
+	var err error
+	defer r.Body.Close()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	category, err := h.service.renameCategory(ctx, categoryID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrCategoryAlreadyExists):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrCategoryAlreadyExists.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"category renamed",
+		category,
+	)
+}
+
+func (h *handler) setSEOHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *SetSEORequest
+	defer r.Body.Close()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	category, err := h.service.setSEO(ctx, categoryID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"category seo updated",
+		category,
+	)
+}
+
+func (h *handler) moveCategoryHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *MoveCategoryRequest
+	var err error
+	defer r.Body.Close()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	category, err := h.service.moveCategory(ctx, categoryID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrInvalidCategoryMove):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrInvalidCategoryMove.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrCategoryAlreadyExists):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrCategoryAlreadyExists.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"category moved",
+		category,
+	)
+}
+
+func (h *handler) reorderCategoriesHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *ReorderCategoriesRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	categories, err := h.service.reorderCategories(ctx, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrInvalidCategoryOrder):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrInvalidCategoryOrder.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"categories reordered",
+		categories,
+	)
+}
+
+func (h *handler) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteCategory(ctx, categoryID); err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrCategoryHasChildren):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrCategoryHasChildren.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"category deleted",
+		nil,
+	)
+}
+
+func (h *handler) assignProductsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *AssignProductsRequest
+	var err error
+	defer r.Body.Close()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	if err = h.service.assignProducts(ctx, categoryID, payload); err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"products assigned",
+		nil,
+	)
+}
+
+func (h *handler) unassignProductHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.unassignProduct(ctx, categoryID, productID); err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"product unassigned",
+		nil,
+	)
+}
+
+func (h *handler) getTreeHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	tree, err := h.service.getTree(ctx)
+	if err != nil {
+		return err
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"categories found",
+		tree,
+	)
+}
+
+func (h *handler) getCategoryHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	resp, err := h.service.getCategory(ctx, categoryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"category found",
+		resp,
+	)
+}
+
+func (h *handler) getBreadcrumbHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	categoryID, err := uuid.Parse(chi.URLParam(r, "categoryID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	breadcrumb, err := h.service.getBreadcrumb(ctx, categoryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"breadcrumb found",
+		breadcrumb,
+	)
+}
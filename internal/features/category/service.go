@@ -0,0 +1,242 @@
+package category
+
+import (
+	"context"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type categoryStorer interface {
+	create(ctx context.Context, category *Category) error
+	findByID(ctx context.Context, categoryID uuid.UUID) (*Category, error)
+	findAll(ctx context.Context) ([]*Category, error)
+	findChildren(ctx context.Context, parentID *uuid.UUID) ([]*Category, error)
+	findAncestors(ctx context.Context, categoryID uuid.UUID) ([]*Category, error)
+	updateName(ctx context.Context, categoryID uuid.UUID, name string) error
+	move(ctx context.Context, categoryID uuid.UUID, parentID *uuid.UUID, position *int) error
+	reorder(ctx context.Context, parentID *uuid.UUID, categoryIDs []uuid.UUID) error
+	delete(ctx context.Context, category *Category) error
+	assignProducts(ctx context.Context, categoryID uuid.UUID, productIDs []uuid.UUID) error
+	unassignProduct(ctx context.Context, categoryID uuid.UUID, productID uuid.UUID) error
+	createAttribute(ctx context.Context, definition *attribute.Definition) error
+	findAttribute(ctx context.Context, attributeID uuid.UUID) (*attribute.Definition, error)
+	findAttributes(ctx context.Context, categoryID uuid.UUID) ([]*attribute.Definition, error)
+	updateAttribute(ctx context.Context, definition *attribute.Definition) error
+	deleteAttribute(ctx context.Context, definition *attribute.Definition) error
+	updateSEO(ctx context.Context, categoryID uuid.UUID, metadata *seo.Metadata) error
+	seo.SlugStorer
+}
+
+type service struct {
+	categoryStore categoryStorer
+	slugs         *seo.SlugService
+}
+
+func NewService(categoryStore categoryStorer) *service {
+	return &service{
+		categoryStore: categoryStore,
+		slugs:         seo.NewSlugService(categoryStore, "category"),
+	}
+}
+
+func (s *service) createCategory(ctx context.Context, payload *CreateCategoryRequest) (*Category, error) {
+	if payload.ParentID != nil {
+		if _, err := s.findCategory(ctx, *payload.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	category := &Category{
+		CategoryID: uuid.New(),
+		ParentID:   payload.ParentID,
+		Name:       strings.TrimSpace(payload.Name),
+	}
+
+	if err := s.categoryStore.create(ctx, category); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.slugs.Assign(ctx, category.CategoryID, seo.DefaultLocale, category.Name); err != nil {
+		return nil, err
+	}
+
+	return s.categoryStore.findByID(ctx, category.CategoryID)
+}
+
+func (s *service) renameCategory(ctx context.Context, categoryID uuid.UUID, payload *RenameCategoryRequest) (*Category, error) {
+	if _, err := s.findCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryStore.updateName(ctx, categoryID, strings.TrimSpace(payload.Name)); err != nil {
+		return nil, err
+	}
+
+	return s.categoryStore.findByID(ctx, categoryID)
+}
+
+// setSEO replaces what search engines are shown about a category page.
+func (s *service) setSEO(ctx context.Context, categoryID uuid.UUID, payload *SetSEORequest) (*Category, error) {
+	if _, err := s.findCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+
+	metadata := &seo.Metadata{
+		Title:        strings.TrimSpace(payload.Title),
+		Description:  strings.TrimSpace(payload.Description),
+		CanonicalURL: strings.TrimSpace(payload.CanonicalURL),
+	}
+
+	if err := s.categoryStore.updateSEO(ctx, categoryID, metadata); err != nil {
+		return nil, err
+	}
+
+	return s.categoryStore.findByID(ctx, categoryID)
+}
+
+// moveCategory moves a category and everything below it. Moving a category
+// under itself or one of its descendants is rejected by the store while it
+// holds the tree lock.
+func (s *service) moveCategory(ctx context.Context, categoryID uuid.UUID, payload *MoveCategoryRequest) (*Category, error) {
+	if payload.ParentID != nil && *payload.ParentID == categoryID {
+		return nil, servererrors.ErrInvalidCategoryMove
+	}
+
+	if err := s.categoryStore.move(ctx, categoryID, payload.ParentID, payload.Position); err != nil {
+		return nil, err
+	}
+
+	return s.categoryStore.findByID(ctx, categoryID)
+}
+
+func (s *service) reorderCategories(ctx context.Context, payload *ReorderCategoriesRequest) ([]*Category, error) {
+	if payload.ParentID != nil {
+		if _, err := s.findCategory(ctx, *payload.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.categoryStore.reorder(ctx, payload.ParentID, payload.CategoryIDs); err != nil {
+		return nil, err
+	}
+
+	return s.categoryStore.findChildren(ctx, payload.ParentID)
+}
+
+// deleteCategory removes a category without subcategories, its products are
+// only unassigned.
+func (s *service) deleteCategory(ctx context.Context, categoryID uuid.UUID) error {
+	category, err := s.findCategory(ctx, categoryID)
+	if err != nil {
+		return err
+	}
+
+	children, err := s.categoryStore.findChildren(ctx, &categoryID)
+	if err != nil {
+		return err
+	}
+
+	if len(children) > 0 {
+		return servererrors.ErrCategoryHasChildren
+	}
+
+	return s.categoryStore.delete(ctx, category)
+}
+
+// getTree returns the whole category tree, siblings in position order.
+func (s *service) getTree(ctx context.Context) ([]*Node, error) {
+	categories, err := s.categoryStore.findAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uuid.UUID]*Node, len(categories))
+	for _, category := range categories {
+		nodes[category.CategoryID] = &Node{Category: category, Children: []*Node{}}
+	}
+
+	roots := []*Node{}
+	for _, category := range categories {
+		node := nodes[category.CategoryID]
+		if category.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+
+		if parent, ok := nodes[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return roots, nil
+}
+
+func (s *service) getCategory(ctx context.Context, categoryID uuid.UUID) (*CategoryResponse, error) {
+	category, err := s.findCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	breadcrumb, err := s.categoryStore.findAncestors(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := s.categoryStore.findChildren(ctx, &categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CategoryResponse{
+		Category:   category,
+		Breadcrumb: breadcrumb,
+		Children:   children,
+	}, nil
+}
+
+// getBreadcrumb returns the categories from the root down to categoryID.
+func (s *service) getBreadcrumb(ctx context.Context, categoryID uuid.UUID) ([]*Category, error) {
+	breadcrumb, err := s.categoryStore.findAncestors(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(breadcrumb) == 0 {
+		return nil, servererrors.ErrCategoryNotFound
+	}
+
+	return breadcrumb, nil
+}
+
+func (s *service) assignProducts(ctx context.Context, categoryID uuid.UUID, payload *AssignProductsRequest) error {
+	if _, err := s.findCategory(ctx, categoryID); err != nil {
+		return err
+	}
+
+	return s.categoryStore.assignProducts(ctx, categoryID, payload.ProductIDs)
+}
+
+func (s *service) unassignProduct(ctx context.Context, categoryID uuid.UUID, productID uuid.UUID) error {
+	if _, err := s.findCategory(ctx, categoryID); err != nil {
+		return err
+	}
+
+	return s.categoryStore.unassignProduct(ctx, categoryID, productID)
+}
+
+func (s *service) findCategory(ctx context.Context, categoryID uuid.UUID) (*Category, error) {
+	category, err := s.categoryStore.findByID(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if category.CategoryID == uuid.Nil {
+		return nil, servererrors.ErrCategoryNotFound
+	}
+
+	return category, nil
+}
@@ -0,0 +1,82 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/go-chi/chi"
+)
+
+// getCategoryBySlugHandler returns the category a slug names, e.g.
+// ?locale=fr. A previous slug answers with a permanent redirect to the
+// current one.
+func (h *handler) getCategoryBySlugHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	slug, err := url.PathUnescape(chi.URLParam(r, "slug"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = seo.DefaultLocale
+	}
+
+	response, current, err := h.service.getCategoryBySlug(ctx, locale, slug)
+	if err != nil {
+		return slugError(err)
+	}
+
+	if current != "" && current != slug {
+		// relative to the requested path, so it holds whatever the api is
+		// mounted under
+		query := url.Values{"locale": {locale}}
+		w.Header().Set("Location", url.PathEscape(current)+"?"+query.Encode())
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusMovedPermanently,
+			"category moved",
+			&SlugRedirectResponse{
+				CategoryID: response.Category.CategoryID,
+				Locale:     locale,
+				Slug:       current,
+			},
+		)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"category found",
+		response,
+	)
+}
+
+// slugError maps the errors of the slug services to responses.
+func slugError(err error) error {
+	if errors.Is(err, servererrors.ErrCategoryNotFound) {
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrCategoryNotFound.Error(),
+			nil,
+		)
+	}
+
+	return seo.SlugError(err)
+}
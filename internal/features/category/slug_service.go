@@ -0,0 +1,51 @@
+package category
+
+import (
+	"context"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+// getCategoryBySlug returns the category a slug names in a locale and its
+// current slug there, which differs from slug when the category has been
+// given another one since.
+func (s *service) getCategoryBySlug(ctx context.Context, locale string, slug string) (*CategoryResponse, string, error) {
+	found, locale, err := s.slugs.Resolve(ctx, locale, slug)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if found.OwnerID == uuid.Nil {
+		return nil, "", servererrors.ErrCategoryNotFound
+	}
+
+	response, err := s.getCategory(ctx, found.OwnerID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return response, response.Category.Slugs[locale], nil
+}
+
+// listSlugs returns the current and previous slugs of a category in every
+// locale.
+func (s *service) listSlugs(ctx context.Context, categoryID uuid.UUID) ([]*seo.Slug, error) {
+	if _, err := s.findCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+
+	return s.slugs.List(ctx, categoryID)
+}
+
+// setSlug changes the current slug of a category in a locale, the previous
+// one keeps redirecting to it.
+func (s *service) setSlug(ctx context.Context, categoryID uuid.UUID, locale string, payload *seo.SetSlugRequest) (*seo.Slug, error) {
+	category, err := s.findCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.slugs.Set(ctx, categoryID, category.Name, locale, payload)
+}
@@ -0,0 +1,29 @@
+package category
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// attachSlugs loads the current slugs of categories in one query.
+func (s *store) attachSlugs(ctx context.Context, categories []*Category) error {
+	categoryIDs := make([]uuid.UUID, 0, len(categories))
+	for _, category := range categories {
+		categoryIDs = append(categoryIDs, category.CategoryID)
+	}
+
+	slugs, err := s.CurrentSlugs(ctx, categoryIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, category := range categories {
+		category.Slugs = slugs[category.CategoryID]
+		if category.Slugs == nil {
+			category.Slugs = map[string]string{}
+		}
+	}
+
+	return nil
+}
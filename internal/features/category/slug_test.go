@@ -0,0 +1,98 @@
+package category
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func (m *mockStore) updateSEO(ctx context.Context, categoryID uuid.UUID, metadata *seo.Metadata) error {
+	m.Categories[categoryID].SEO = *metadata
+	return nil
+}
+
+func TestCategorySlugs(t *testing.T) {
+	router, _ := newTestRouter()
+
+	create := func(name string, parentID *uuid.UUID) *Category {
+		t.Helper()
+
+		category := new(Category)
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/categories", nil, CreateCategoryRequest{Name: name, ParentID: parentID}, category)
+		if code != http.StatusCreated {
+			t.Fatalf("expected status code %d creating %s, got %d", http.StatusCreated, name, code)
+		}
+
+		return category
+	}
+
+	men := create("Men", nil)
+	women := create("Women", nil)
+	menShoes := create("Shoes", &men.CategoryID)
+	womenShoes := create("Shoes", &women.CategoryID)
+
+	t.Run("should number slugs of categories sharing a name", func(t *testing.T) {
+		if menShoes.Slugs["en"] != "shoes" || womenShoes.Slugs["en"] != "shoes-2" {
+			t.Errorf("expected shoes and shoes-2, got %v and %v", menShoes.Slugs, womenShoes.Slugs)
+		}
+	})
+
+	womenShoesSlugs := "/admin/categories/" + womenShoes.CategoryID.String() + "/slugs/"
+
+	testCases := []struct {
+		name     string
+		path     string
+		payload  seo.SetSlugRequest
+		expected int
+	}{
+		{"should reject a slug in use by another category", womenShoesSlugs + "en", seo.SetSlugRequest{Slug: "Shoes"}, http.StatusConflict},
+		{"should reject an invalid locale", womenShoesSlugs + "en-", seo.SetSlugRequest{Slug: "womens-shoes"}, http.StatusUnprocessableEntity},
+		{"should reject an unknown category", "/admin/categories/" + uuid.NewString() + "/slugs/en", seo.SetSlugRequest{}, http.StatusNotFound},
+		{"should generate a slug from a title", womenShoesSlugs + "en", seo.SetSlugRequest{Title: "Women's Shoes"}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := testutil.Serve(t, router, http.MethodPut, tc.path, nil, tc.payload, nil); code != tc.expected {
+				t.Errorf("expected status code %d, got %d", tc.expected, code)
+			}
+		})
+	}
+
+	t.Run("should redirect a previous slug to the current one", func(t *testing.T) {
+		if code := testutil.Serve(t, router, http.MethodGet, "/categories/by-slug/shoes-2", nil, nil, nil); code != http.StatusMovedPermanently {
+			t.Errorf("expected status code %d, got %d", http.StatusMovedPermanently, code)
+		}
+
+		response := new(CategoryResponse)
+		if code := testutil.Serve(t, router, http.MethodGet, "/categories/by-slug/women-s-shoes", nil, nil, response); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if response.Category.CategoryID != womenShoes.CategoryID || len(response.Breadcrumb) != 2 {
+			t.Errorf("expected the women's shoes category with its breadcrumb, got %+v", response)
+		}
+	})
+
+	t.Run("should set the seo metadata", func(t *testing.T) {
+		path := "/admin/categories/" + men.CategoryID.String() + "/seo"
+
+		if code := testutil.Serve(t, router, http.MethodPut, path, nil, SetSEORequest{CanonicalURL: "shop/men"}, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d for a relative canonical url, got %d", http.StatusUnprocessableEntity, code)
+		}
+
+		category := new(Category)
+		code := testutil.Serve(t, router, http.MethodPut, path, nil, SetSEORequest{Title: " Men's clothing ", CanonicalURL: "https://shop.example.com/men"}, category)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if category.SEO.Title != "Men's clothing" || category.SEO.CanonicalURL != "https://shop.example.com/men" {
+			t.Errorf("expected the seo metadata to be set, got %+v", category.SEO)
+		}
+	})
+}
@@ -0,0 +1,537 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	categoryFields = "c.category_id, c.parent_id, c.name, c.position, c.seo_title, c.seo_description, c.canonical_url, c.created_at, c.updated_at"
+
+	// postgres error codes
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+
+	// lockTree serialises every change to the tree shape so concurrent moves
+	// can not create cycles or duplicate positions
+	lockTree = "SELECT pg_advisory_xact_lock(hashtext('category_tree'))"
+)
+
+type store struct {
+	*seo.SlugStore
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		SlugStore: seo.NewSlugStore(db, "category_slugs", "category_id"),
+		db:        db,
+	}
+}
+
+// create inserts category as the last child of its parent along with its
+// closure rows.
+func (s *store) create(ctx context.Context, category *Category) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, lockTree); err != nil {
+			return fmt.Errorf("failed to lock category tree in category store: %w", err)
+		}
+
+		err := tx.QueryRowContext(
+			ctx,
+			"INSERT INTO categories(category_id, parent_id, name, position) SELECT $1::uuid, $2::uuid, $3, COALESCE(MAX(position) + 1, 0) FROM categories WHERE parent_id IS NOT DISTINCT FROM $2::uuid RETURNING position",
+			category.CategoryID,
+			category.ParentID,
+			category.Name,
+		).Scan(&category.Position)
+		if err != nil {
+			switch {
+			case isPQError(err, uniqueViolation):
+				return servererrors.ErrCategoryAlreadyExists
+			case isPQError(err, foreignKeyViolation):
+				return servererrors.ErrCategoryNotFound
+			default:
+				return fmt.Errorf("failed to insert new category in category store: %w", err)
+			}
+		}
+
+		// the category itself plus one row per ancestor of its parent
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO category_closure(ancestor_id, descendant_id, depth) SELECT $1::uuid, $1::uuid, 0 UNION ALL SELECT ancestor_id, $1::uuid, depth + 1 FROM category_closure WHERE descendant_id = $2::uuid",
+			category.CategoryID,
+			category.ParentID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert category closure in category store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *store) findByID(ctx context.Context, categoryID uuid.UUID) (*Category, error) {
+	categories, err := s.getCategoriesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM categories c WHERE c.category_id = $1", categoryFields),
+		categoryID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find category by id in category store: %w",
+			err,
+		)
+	}
+
+	if len(categories) == 0 {
+		return new(Category), nil
+	}
+
+	return categories[0], nil
+}
+
+// findAll returns every category, siblings in position order.
+func (s *store) findAll(ctx context.Context) ([]*Category, error) {
+	categories, err := s.getCategoriesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM categories c ORDER BY c.position, c.name", categoryFields),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find categories in category store: %w",
+			err,
+		)
+	}
+
+	return categories, nil
+}
+
+func (s *store) findChildren(ctx context.Context, parentID *uuid.UUID) ([]*Category, error) {
+	categories, err := s.getCategoriesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM categories c WHERE c.parent_id IS NOT DISTINCT FROM $1 ORDER BY c.position, c.name", categoryFields),
+		parentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find category children in category store: %w",
+			err,
+		)
+	}
+
+	return categories, nil
+}
+
+// findAncestors returns the path from the root down to categoryID, both
+// included.
+func (s *store) findAncestors(ctx context.Context, categoryID uuid.UUID) ([]*Category, error) {
+	categories, err := s.getCategoriesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM categories c JOIN category_closure cc ON cc.ancestor_id = c.category_id WHERE cc.descendant_id = $1 ORDER BY cc.depth DESC", categoryFields),
+		categoryID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find category ancestors in category store: %w",
+			err,
+		)
+	}
+
+	return categories, nil
+}
+
+func (s *store) updateName(ctx context.Context, categoryID uuid.UUID, name string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE categories SET name = $1, updated_at = NOW() WHERE category_id = $2",
+		name,
+		categoryID,
+	)
+	if err != nil {
+		if isPQError(err, uniqueViolation) {
+			return servererrors.ErrCategoryAlreadyExists
+		}
+
+		return fmt.Errorf(
+			"failed to update category name in category store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) updateSEO(ctx context.Context, categoryID uuid.UUID, metadata *seo.Metadata) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE categories SET seo_title = $1, seo_description = $2, canonical_url = $3, updated_at = NOW() WHERE category_id = $4",
+		metadata.Title,
+		metadata.Description,
+		metadata.CanonicalURL,
+		categoryID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update category seo in category store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// move re-parents a category and its subtree, then places it at position
+// among its new siblings. The closure rows linking the subtree to its old
+// ancestors are swapped for rows linking it to the new ones, the links
+// inside the subtree stay as they are.
+func (s *store) move(ctx context.Context, categoryID uuid.UUID, parentID *uuid.UUID, position *int) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, lockTree); err != nil {
+			return fmt.Errorf("failed to lock category tree in category store: %w", err)
+		}
+
+		var oldParentID *uuid.UUID
+		var oldPosition int
+		err := tx.QueryRowContext(
+			ctx,
+			"SELECT parent_id, position FROM categories WHERE category_id = $1",
+			categoryID,
+		).Scan(&oldParentID, &oldPosition)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return servererrors.ErrCategoryNotFound
+			}
+
+			return fmt.Errorf("failed to find category to move in category store: %w", err)
+		}
+
+		if parentID != nil {
+			// the new parent must exist outside the moved subtree, checked
+			// under the lock so a concurrent move can not sneak a cycle in
+			var exists, inSubtree bool
+			err = tx.QueryRowContext(
+				ctx,
+				"SELECT EXISTS(SELECT 1 FROM categories WHERE category_id = $2), EXISTS(SELECT 1 FROM category_closure WHERE ancestor_id = $1 AND descendant_id = $2)",
+				categoryID,
+				*parentID,
+			).Scan(&exists, &inSubtree)
+			if err != nil {
+				return fmt.Errorf("failed to check category move in category store: %w", err)
+			}
+
+			if !exists {
+				return servererrors.ErrCategoryNotFound
+			}
+
+			if inSubtree {
+				return servererrors.ErrInvalidCategoryMove
+			}
+		}
+
+		// close the gap left among the old siblings
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE categories SET position = position - 1 WHERE parent_id IS NOT DISTINCT FROM $1 AND position > $2",
+			oldParentID,
+			oldPosition,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to shift old siblings in category store: %w", err)
+		}
+
+		var siblingCount int
+		err = tx.QueryRowContext(
+			ctx,
+			"SELECT COUNT(*) FROM categories WHERE parent_id IS NOT DISTINCT FROM $1 AND category_id <> $2",
+			parentID,
+			categoryID,
+		).Scan(&siblingCount)
+		if err != nil {
+			return fmt.Errorf("failed to count new siblings in category store: %w", err)
+		}
+
+		newPosition := siblingCount
+		if position != nil {
+			newPosition = min(*position, siblingCount)
+		}
+
+		// make room among the new siblings
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE categories SET position = position + 1 WHERE parent_id IS NOT DISTINCT FROM $1 AND category_id <> $2 AND position >= $3",
+			parentID,
+			categoryID,
+			newPosition,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to shift new siblings in category store: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE categories SET parent_id = $1, position = $2, updated_at = NOW() WHERE category_id = $3",
+			parentID,
+			newPosition,
+			categoryID,
+		)
+		if err != nil {
+			if isPQError(err, uniqueViolation) {
+				return servererrors.ErrCategoryAlreadyExists
+			}
+
+			return fmt.Errorf("failed to move category in category store: %w", err)
+		}
+
+		// detach the subtree from its old ancestors
+		_, err = tx.ExecContext(
+			ctx,
+			"DELETE FROM category_closure WHERE descendant_id IN (SELECT descendant_id FROM category_closure WHERE ancestor_id = $1) AND ancestor_id NOT IN (SELECT descendant_id FROM category_closure WHERE ancestor_id = $1)",
+			categoryID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to detach category subtree in category store: %w", err)
+		}
+
+		if parentID == nil {
+			return nil
+		}
+
+		// attach it below every ancestor of the new parent
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO category_closure(ancestor_id, descendant_id, depth) SELECT above.ancestor_id, below.descendant_id, above.depth + below.depth + 1 FROM category_closure above CROSS JOIN category_closure below WHERE above.descendant_id = $1 AND below.ancestor_id = $2",
+			*parentID,
+			categoryID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to attach category subtree in category store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// reorder sets the positions of the children of parentID to the order of
+// categoryIDs, which must list each of them exactly once.
+func (s *store) reorder(ctx context.Context, parentID *uuid.UUID, categoryIDs []uuid.UUID) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, lockTree); err != nil {
+			return fmt.Errorf("failed to lock category tree in category store: %w", err)
+		}
+
+		rows, err := tx.QueryContext(
+			ctx,
+			"SELECT category_id FROM categories WHERE parent_id IS NOT DISTINCT FROM $1",
+			parentID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to find siblings in category store: %w", err)
+		}
+
+		siblings := []uuid.UUID{}
+		for rows.Next() {
+			var siblingID uuid.UUID
+			if err = rows.Scan(&siblingID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan sibling in category store: %w", err)
+			}
+			siblings = append(siblings, siblingID)
+		}
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate siblings in category store: %w", err)
+		}
+
+		if len(siblings) != len(categoryIDs) {
+			return servererrors.ErrInvalidCategoryOrder
+		}
+
+		for _, categoryID := range categoryIDs {
+			if !slices.Contains(siblings, categoryID) {
+				return servererrors.ErrInvalidCategoryOrder
+			}
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE categories c SET position = o.position - 1, updated_at = NOW() FROM UNNEST($1::uuid[]) WITH ORDINALITY AS o(category_id, position) WHERE c.category_id = o.category_id",
+			pq.Array(categoryIDs),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to reorder categories in category store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// delete removes a leaf category, closing the gap among its siblings.
+func (s *store) delete(ctx context.Context, category *Category) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, lockTree); err != nil {
+			return fmt.Errorf("failed to lock category tree in category store: %w", err)
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM categories WHERE category_id = $1",
+			category.CategoryID,
+		)
+		if err != nil {
+			if isPQError(err, foreignKeyViolation) {
+				return servererrors.ErrCategoryHasChildren
+			}
+
+			return fmt.Errorf("failed to delete category in category store: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE categories SET position = position - 1 WHERE parent_id IS NOT DISTINCT FROM $1 AND position > $2",
+			category.ParentID,
+			category.Position,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to shift siblings in category store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *store) assignProducts(ctx context.Context, categoryID uuid.UUID, productIDs []uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO product_categories(product_id, category_id) SELECT UNNEST($1::uuid[]), $2::uuid ON CONFLICT DO NOTHING",
+		pq.Array(productIDs),
+		categoryID,
+	)
+	if err != nil {
+		if isPQError(err, foreignKeyViolation) {
+			return servererrors.ErrProductNotFound
+		}
+
+		return fmt.Errorf(
+			"failed to assign products in category store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) unassignProduct(ctx context.Context, categoryID uuid.UUID, productID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM product_categories WHERE category_id = $1 AND product_id = $2",
+		categoryID,
+		productID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to unassign product in category store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) getCategoriesWithContext(ctx context.Context, query string, args ...any) ([]*Category, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in category store getCategoriesWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+	for rows.Next() {
+		category := new(Category)
+		if err = scanRowsIntoCategory(rows, category); err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in category store: %w",
+			err,
+		)
+	}
+
+	if err = s.attachSlugs(ctx, categories); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func scanRowsIntoCategory(rows *sql.Rows, category *Category) error {
+	if category == nil {
+		return errors.New(
+			"scanRowsIntoCategory err in category store",
+		)
+	}
+
+	err := rows.Scan(
+		&category.CategoryID,
+		&category.ParentID,
+		&category.Name,
+		&category.Position,
+		&category.SEO.Title,
+		&category.SEO.Description,
+		&category.SEO.CanonicalURL,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to scan row into category in category store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// withTx runs fn in a transaction, committing when it returns nil.
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in category store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in category store: %w", err)
+	}
+
+	return nil
+}
+
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
@@ -0,0 +1,482 @@
+package digital
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const testBaseURL = "https://shop.test/api/v1"
+
+func newTestRouter(t *testing.T) (*chi.Mux, *mockStore, *service) {
+	t.Helper()
+
+	blobStore, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digitalStore := newMockDigitalStore()
+	digitalService := NewService(digitalStore, blobStore, "test-secret", testBaseURL+"/", time.Minute)
+	digitalHandler := NewHandler(digitalService, nil)
+
+	router := chi.NewRouter()
+	router.Get(
+		"/downloads/{entitlementID}/files/{fileID}",
+		handlerutils.MakeHandler(digitalHandler.downloadFileHandler),
+	)
+	router.Get(
+		"/shipping/requirements",
+		handlerutils.MakeHandler(digitalHandler.shippingRequirementsHandler),
+	)
+	router.Get(
+		"/admin/products/{productID}/files",
+		handlerutils.MakeHandler(digitalHandler.listFilesHandler),
+	)
+	router.Post(
+		"/admin/products/{productID}/files",
+		handlerutils.MakeHandler(digitalHandler.uploadFileHandler),
+	)
+	router.Post(
+		"/admin/products/{productID}/license-keys",
+		handlerutils.MakeHandler(digitalHandler.addLicenseKeysHandler),
+	)
+	router.Post(
+		"/admin/digital/fulfillments",
+		handlerutils.MakeHandler(digitalHandler.fulfillOrderHandler),
+	)
+
+	authenticated := router.With(testutil.AuthenticateFromHeader)
+	authenticated.Get(
+		"/downloads",
+		handlerutils.MakeHandler(digitalHandler.listDownloadsHandler),
+	)
+
+	return router, digitalStore, digitalService
+}
+
+func upload(t *testing.T, router http.Handler, productID uuid.UUID, filename string, data []byte) (int, *File) {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(data)
+	form.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, "/admin/products/"+productID.String()+"/files", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	resp := struct {
+		Data *File `json:"data"`
+	}{}
+	if rr.Code == http.StatusCreated {
+		if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rr.Code, resp.Data
+}
+
+// download follows a signed link, relative to the router.
+func download(t *testing.T, router http.Handler, link string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, strings.TrimPrefix(link, testBaseURL), nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestDigitalProducts(t *testing.T) {
+	router, digitalStore, _ := newTestRouter(t)
+
+	ebookID := uuid.New()
+	licenseID := uuid.New()
+	mugID := uuid.New()
+	digitalStore.Types[ebookID] = product.TypeDigital
+	digitalStore.Types[licenseID] = product.TypeDigital
+	digitalStore.Types[mugID] = "physical"
+
+	t.Run("should only take files for digital products", func(t *testing.T) {
+		if code, _ := upload(t, router, mugID, "mug.pdf", []byte("%PDF")); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+
+		if code, _ := upload(t, router, uuid.New(), "book.pdf", []byte("%PDF")); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+	})
+
+	code, book := upload(t, router, ebookID, "book.pdf", []byte("%PDF-1.7 the book"))
+	if code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+	}
+
+	if book.Name != "book.pdf" || book.SizeBytes != 17 {
+		t.Errorf("expected book.pdf of 17 bytes, got %q of %d", book.Name, book.SizeBytes)
+	}
+
+	t.Run("should add license keys once each", func(t *testing.T) {
+		var resp AddLicenseKeysResponse
+		payload := AddLicenseKeysRequest{Keys: []string{"KEY-1", " KEY-2 ", "KEY-1", "KEY-3"}}
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/products/"+licenseID.String()+"/license-keys", nil, payload, &resp)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if resp.Added != 3 || resp.Stock.Available != 3 {
+			t.Errorf("expected 3 keys added and available, got %d and %d", resp.Added, resp.Stock.Available)
+		}
+
+		payload = AddLicenseKeysRequest{Keys: []string{"KEY-3", "KEY-4"}}
+		testutil.Serve(t, router, http.MethodPost, "/admin/products/"+licenseID.String()+"/license-keys", nil, payload, &resp)
+		if resp.Added != 1 || resp.Stock.Total != 4 {
+			t.Errorf("expected 1 key added out of 4, got %d out of %d", resp.Added, resp.Stock.Total)
+		}
+	})
+
+	t.Run("should tell whether products need shipping", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			query    string
+			expected bool
+		}{
+			{"digital only", "?productId=" + ebookID.String() + "&productId=" + licenseID.String(), false},
+			{"mixed", "?productId=" + ebookID.String() + "&productId=" + mugID.String(), true},
+		}
+
+		for _, tc := range testCases {
+			var resp ShippingResponse
+			code := testutil.Serve(t, router, http.MethodGet, "/shipping/requirements"+tc.query, nil, nil, &resp)
+			if code != http.StatusOK || resp.RequiresShipping != tc.expected {
+				t.Errorf("%s: expected requires shipping %v, got %v with status code %d", tc.name, tc.expected, resp.RequiresShipping, code)
+			}
+		}
+	})
+
+	userID := uuid.New()
+	orderID := uuid.New()
+	order := FulfillOrderRequest{
+		OrderID: orderID,
+		UserID:  userID,
+		Lines: []OrderLine{
+			{ProductID: ebookID, Quantity: 1},
+			{ProductID: licenseID, Quantity: 2},
+			{ProductID: mugID, Quantity: 1},
+		},
+	}
+
+	t.Run("should fulfill the digital products of an order once", func(t *testing.T) {
+		var entitlements []*Entitlement
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/digital/fulfillments", nil, order, &entitlements)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if len(entitlements) != 2 {
+			t.Fatalf("expected 2 entitlements, got %d", len(entitlements))
+		}
+
+		keys := map[uuid.UUID][]string{}
+		for _, entitlement := range entitlements {
+			keys[entitlement.ProductID] = entitlement.LicenseKeys
+		}
+
+		if len(keys[ebookID]) != 0 || !slices.Equal(keys[licenseID], []string{"KEY-1", "KEY-2"}) {
+			t.Errorf("expected the first two keys for the license only, got %v", keys)
+		}
+
+		code = testutil.Serve(t, router, http.MethodPost, "/admin/digital/fulfillments", nil, order, &entitlements)
+		if code != http.StatusOK || len(entitlements) != 2 || len(digitalStore.Entitlements) != 2 {
+			t.Errorf("expected fulfilling again to return the same 2 entitlements, got %d with status code %d", len(digitalStore.Entitlements), code)
+		}
+	})
+
+	t.Run("should reject an order once license keys run out", func(t *testing.T) {
+		payload := FulfillOrderRequest{
+			OrderID: uuid.New(),
+			UserID:  userID,
+			Lines:   []OrderLine{{ProductID: ebookID, Quantity: 1}, {ProductID: licenseID, Quantity: 3}},
+		}
+
+		code := testutil.Serve(t, router, http.MethodPost, "/admin/digital/fulfillments", nil, payload, nil)
+		if code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		if len(digitalStore.Entitlements) != 2 {
+			t.Errorf("expected no entitlement for the rejected order, got %d in all", len(digitalStore.Entitlements))
+		}
+	})
+
+	var downloads []*DownloadResponse
+	if code := testutil.Serve(t, router, http.MethodGet, "/downloads", &userID, nil, &downloads); code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+	}
+
+	var link *DownloadLink
+	for _, download := range downloads {
+		if download.Entitlement.ProductID == ebookID && len(download.Files) == 1 {
+			link = download.Files[0]
+		}
+	}
+
+	if link == nil || !strings.HasPrefix(link.URL, testBaseURL+"/downloads/") {
+		t.Fatalf("expected a signed link to the book, got %+v", downloads)
+	}
+
+	t.Run("should reject tampered or expired links", func(t *testing.T) {
+		parsed, _ := url.Parse(link.URL)
+		query := parsed.Query()
+
+		tampered := *parsed
+		expires := query.Get("expires")
+		query.Set("expires", expires+"0")
+		tampered.RawQuery = query.Encode()
+
+		if rr := download(t, router, tampered.String()); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+
+		_, _, service := newTestRouter(t)
+		expired := service.downloadURL(uuid.New(), book.FileID, time.Now().Add(-time.Second))
+		if rr := download(t, router, expired); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should count downloads up to the limit", func(t *testing.T) {
+		for i := 0; i < maxDownloads; i++ {
+			rr := download(t, router, link.URL)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			if rr.Body.String() != "%PDF-1.7 the book" || !strings.Contains(rr.Header().Get("Content-Disposition"), "book.pdf") {
+				t.Fatalf("expected the book as an attachment, got %q", rr.Body.String())
+			}
+		}
+
+		if rr := download(t, router, link.URL); rr.Code != http.StatusGone {
+			t.Errorf("expected status code %d, got %d", http.StatusGone, rr.Code)
+		}
+
+		testutil.Serve(t, router, http.MethodGet, "/downloads", &userID, nil, &downloads)
+		for _, download := range downloads {
+			if download.Entitlement.ProductID == ebookID && len(download.Files) != 0 {
+				t.Errorf("expected no links once the downloads are used up, got %d", len(download.Files))
+			}
+		}
+	})
+}
+
+type mockStore struct {
+	Types        map[uuid.UUID]string
+	Files        map[uuid.UUID]*File
+	Keys         []*mockLicenseKey
+	Entitlements []*Entitlement
+}
+
+type mockLicenseKey struct {
+	ProductID     uuid.UUID
+	Key           string
+	EntitlementID uuid.UUID
+}
+
+func newMockDigitalStore() *mockStore {
+	return &mockStore{
+		Types: map[uuid.UUID]string{},
+		Files: map[uuid.UUID]*File{},
+	}
+}
+
+func (m *mockStore) productTypes(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	types := map[uuid.UUID]string{}
+	for _, productID := range productIDs {
+		if productType, ok := m.Types[productID]; ok {
+			types[productID] = productType
+		}
+	}
+
+	return types, nil
+}
+
+func (m *mockStore) createFile(ctx context.Context, file *File) error {
+	file.CreatedAt = time.Now()
+	m.Files[file.FileID] = file
+	return nil
+}
+
+func (m *mockStore) findFile(ctx context.Context, fileID uuid.UUID) (*File, error) {
+	if file, ok := m.Files[fileID]; ok {
+		return file, nil
+	}
+
+	return new(File), nil
+}
+
+func (m *mockStore) findFiles(ctx context.Context, productID uuid.UUID) ([]*File, error) {
+	files := []*File{}
+	for _, file := range m.Files {
+		if file.ProductID == productID {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+func (m *mockStore) deleteFile(ctx context.Context, fileID uuid.UUID) error {
+	delete(m.Files, fileID)
+	return nil
+}
+
+func (m *mockStore) addLicenseKeys(ctx context.Context, productID uuid.UUID, keys []string) (int64, error) {
+	var added int64
+	for _, key := range keys {
+		if !slices.ContainsFunc(m.Keys, func(k *mockLicenseKey) bool { return k.ProductID == productID && k.Key == key }) {
+			m.Keys = append(m.Keys, &mockLicenseKey{ProductID: productID, Key: key})
+			added++
+		}
+	}
+
+	return added, nil
+}
+
+func (m *mockStore) licenseKeyStock(ctx context.Context, productID uuid.UUID) (*LicenseKeyStock, error) {
+	stock := &LicenseKeyStock{ProductID: productID}
+	for _, key := range m.Keys {
+		if key.ProductID == productID {
+			stock.Total++
+			if key.EntitlementID == uuid.Nil {
+				stock.Available++
+			}
+		}
+	}
+
+	return stock, nil
+}
+
+func (m *mockStore) createEntitlements(ctx context.Context, entitlements []*Entitlement) error {
+	created := []*Entitlement{}
+	allocated := []*mockLicenseKey{}
+	rollback := func() {
+		for _, key := range allocated {
+			key.EntitlementID = uuid.Nil
+		}
+	}
+
+	for _, entitlement := range entitlements {
+		if slices.ContainsFunc(m.Entitlements, func(e *Entitlement) bool {
+			return e.OrderID == entitlement.OrderID && e.ProductID == entitlement.ProductID
+		}) {
+			continue
+		}
+
+		hasKeys := false
+		count := 0
+		for _, key := range m.Keys {
+			if key.ProductID != entitlement.ProductID {
+				continue
+			}
+
+			hasKeys = true
+			if key.EntitlementID == uuid.Nil && count < entitlement.Quantity {
+				key.EntitlementID = entitlement.EntitlementID
+				allocated = append(allocated, key)
+				count++
+			}
+		}
+
+		if hasKeys && count < entitlement.Quantity {
+			rollback()
+			return servererrors.ErrLicenseKeysExhausted
+		}
+
+		entitlement.CreatedAt = time.Now()
+		created = append(created, entitlement)
+	}
+
+	m.Entitlements = append(m.Entitlements, created...)
+	return nil
+}
+
+func (m *mockStore) findEntitlement(ctx context.Context, entitlementID uuid.UUID) (*Entitlement, error) {
+	for _, entitlement := range m.Entitlements {
+		if entitlement.EntitlementID == entitlementID {
+			return m.withKeys(entitlement), nil
+		}
+	}
+
+	return new(Entitlement), nil
+}
+
+func (m *mockStore) findEntitlementsByOrder(ctx context.Context, orderID uuid.UUID) ([]*Entitlement, error) {
+	entitlements := []*Entitlement{}
+	for _, entitlement := range m.Entitlements {
+		if entitlement.OrderID == orderID {
+			entitlements = append(entitlements, m.withKeys(entitlement))
+		}
+	}
+
+	return entitlements, nil
+}
+
+func (m *mockStore) findEntitlementsByUser(ctx context.Context, userID uuid.UUID) ([]*Entitlement, error) {
+	entitlements := []*Entitlement{}
+	for _, entitlement := range m.Entitlements {
+		if entitlement.UserID == userID {
+			entitlements = append(entitlements, m.withKeys(entitlement))
+		}
+	}
+
+	return entitlements, nil
+}
+
+func (m *mockStore) recordDownload(ctx context.Context, entitlementID uuid.UUID, now time.Time) (bool, error) {
+	for _, entitlement := range m.Entitlements {
+		if entitlement.EntitlementID == entitlementID && entitlement.DownloadCount < entitlement.MaxDownloads && entitlement.ExpiresAt.After(now) {
+			entitlement.DownloadCount++
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *mockStore) withKeys(entitlement *Entitlement) *Entitlement {
+	copied := *entitlement
+	copied.LicenseKeys = []string{}
+	for _, key := range m.Keys {
+		if key.EntitlementID == entitlement.EntitlementID {
+			copied.LicenseKeys = append(copied.LicenseKeys, key.Key)
+		}
+	}
+
+	return &copied
+}
@@ -0,0 +1,73 @@
+package digital
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Requests
+
+// UploadFileRequest holds the form fields sent alongside the file, Name
+// defaults to the name of the uploaded file.
+type UploadFileRequest struct {
+	Name        string `validate:"required,max=255"`
+	ContentType string `validate:"required,max=127"`
+}
+
+// AddLicenseKeysRequest adds keys to the pool of a product, keys already in
+// the pool are skipped.
+type AddLicenseKeysRequest struct {
+	Keys []string `json:"keys" validate:"required,min=1,max=10000,dive,required,max=255"`
+}
+
+// FulfillOrderRequest grants the digital products of a paid order to its
+// buyer, physical products among the lines are skipped.
+type FulfillOrderRequest struct {
+	OrderID uuid.UUID   `json:"orderId" validate:"required"`
+	UserID  uuid.UUID   `json:"userId" validate:"required"`
+	Lines   []OrderLine `json:"lines" validate:"required,min=1,max=100,dive"`
+}
+
+// OrderLine is a product of an order and how many units of it were bought.
+type OrderLine struct {
+	ProductID uuid.UUID `json:"productId" validate:"required"`
+	Quantity  int       `json:"quantity" validate:"min=1,max=100"`
+}
+
+// ShippingRequest lists the products of a cart or order, read from the
+// productId query parameters.
+type ShippingRequest struct {
+	ProductIDs []uuid.UUID `validate:"required,min=1,max=100,unique"`
+}
+
+// Responses
+
+type AddLicenseKeysResponse struct {
+	Added int64            `json:"added"`
+	Stock *LicenseKeyStock `json:"stock"`
+}
+
+// DownloadResponse is an entitlement of the user along with signed links to
+// its files, there are no links once the entitlement is used up or expired.
+type DownloadResponse struct {
+	Entitlement *Entitlement    `json:"entitlement"`
+	Files       []*DownloadLink `json:"files"`
+}
+
+// DownloadLink is a signed link to a file, valid until ExpiresAt.
+type DownloadLink struct {
+	FileID      uuid.UUID `json:"fileId"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	URL         string    `json:"url"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// ShippingResponse tells whether a set of products needs shipping, only
+// orders of digital products alone skip it.
+type ShippingResponse struct {
+	RequiresShipping  bool        `json:"requiresShipping"`
+	ShippedProductIDs []uuid.UUID `json:"shippedProductIds"`
+}
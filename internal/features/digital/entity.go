@@ -0,0 +1,43 @@
+package digital
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// File is a downloadable file of a digital product, such as an e-book or an
+// installer.
+type File struct {
+	FileID      uuid.UUID `json:"file_id"`
+	ProductID   uuid.UUID `json:"product_id"`
+	Name        string    `json:"name"`
+	BlobKey     string    `json:"-"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Entitlement lets the buyer of a digital product download its files up to
+// MaxDownloads times until it expires. It holds a license key per unit
+// bought when the product comes with license keys.
+type Entitlement struct {
+	EntitlementID uuid.UUID `json:"entitlement_id"`
+	OrderID       uuid.UUID `json:"order_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	ProductID     uuid.UUID `json:"product_id"`
+	Quantity      int       `json:"quantity"`
+	LicenseKeys   []string  `json:"license_keys"`
+	MaxDownloads  int       `json:"max_downloads"`
+	DownloadCount int       `json:"download_count"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LicenseKeyStock counts the license keys of a product and how many of them
+// are still to be handed out.
+type LicenseKeyStock struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Total     int64     `json:"total"`
+	Available int64     `json:"available"`
+}
@@ -0,0 +1,562 @@
+package digital
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	uploadFile(ctx context.Context, productID uuid.UUID, payload *UploadFileRequest, r io.Reader, size int64) (*File, error)
+	listFiles(ctx context.Context, productID uuid.UUID) ([]*File, error)
+	deleteFile(ctx context.Context, productID uuid.UUID, fileID uuid.UUID) error
+	addLicenseKeys(ctx context.Context, productID uuid.UUID, payload *AddLicenseKeysRequest) (*AddLicenseKeysResponse, error)
+	licenseKeyStock(ctx context.Context, productID uuid.UUID) (*LicenseKeyStock, error)
+	Fulfill(ctx context.Context, orderID uuid.UUID, userID uuid.UUID, lines []OrderLine) ([]*Entitlement, error)
+	shippedProducts(ctx context.Context, productIDs []uuid.UUID) ([]uuid.UUID, error)
+	listDownloads(ctx context.Context, userID uuid.UUID) ([]*DownloadResponse, error)
+	openDownload(ctx context.Context, entitlementID uuid.UUID, fileID uuid.UUID, expires string, signature string) (io.ReadCloser, *File, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+const (
+	// multipartOverhead leaves room for the form fields and boundaries on
+	// top of the file itself
+	multipartOverhead = 1 << 20
+	// multipartMemory is how much of a form is buffered in memory before it
+	// spills into temporary files
+	multipartMemory = 1 << 20
+	// transferTimeout replaces the usual request timeout for the uploads
+	// and downloads of files that can be large
+	transferTimeout = 30 * time.Minute
+)
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	// download links carry their own signature, they need no session
+	router.Get(
+		"/downloads/{entitlementID}/files/{fileID}",
+		handlerutils.MakeHandler(h.downloadFileHandler),
+	)
+	router.Get(
+		"/shipping/requirements",
+		handlerutils.MakeHandler(h.shippingRequirementsHandler),
+	)
+
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeUser))
+	authenticated.Get(
+		"/downloads",
+		handlerutils.MakeHandler(h.listDownloadsHandler),
+	)
+}
+
+// RegisterAdminRoutes registers the digital product management routes
+// relative to the admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/products/{productID}/files",
+		handlerutils.MakeHandler(h.listFilesHandler),
+	)
+	authenticated.Post(
+		"/products/{productID}/files",
+		handlerutils.MakeHandler(h.uploadFileHandler),
+	)
+	authenticated.Delete(
+		"/products/{productID}/files/{fileID}",
+		handlerutils.MakeHandler(h.deleteFileHandler),
+	)
+	authenticated.Get(
+		"/products/{productID}/license-keys",
+		handlerutils.MakeHandler(h.licenseKeyStockHandler),
+	)
+	authenticated.Post(
+		"/products/{productID}/license-keys",
+		handlerutils.MakeHandler(h.addLicenseKeysHandler),
+	)
+	authenticated.Post(
+		"/digital/fulfillments",
+		handlerutils.MakeHandler(h.fulfillOrderHandler),
+	)
+}
+
+// uploadFileHandler accepts a multipart form with the file in "file" and an
+// optional "name" field.
+func (h *handler) uploadFileHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		transferTimeout,
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFileBytes+multipartOverhead)
+	defer r.Body.Close()
+
+	if err = r.ParseMultipartForm(multipartMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return servererrors.New(
+				http.StatusRequestEntityTooLarge,
+				servererrors.ErrFileTooLarge.Error(),
+				nil,
+			)
+		}
+
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil || header.Size == 0 {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+	defer file.Close()
+
+	payload := &UploadFileRequest{
+		Name:        r.FormValue("name"),
+		ContentType: header.Header.Get("Content-Type"),
+	}
+	if payload.Name == "" {
+		payload.Name = path.Base(header.Filename)
+	}
+	if payload.ContentType == "" {
+		payload.ContentType = defaultContentType
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	uploaded, err := h.service.uploadFile(ctx, productID, payload, file, header.Size)
+	if err != nil {
+		return digitalError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"file uploaded",
+		uploaded,
+	)
+}
+
+func (h *handler) listFilesHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	files, err := h.service.listFiles(ctx, productID)
+	if err != nil {
+		return digitalError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"files found",
+		files,
+	)
+}
+
+func (h *handler) deleteFileHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	fileID, err := uuid.Parse(chi.URLParam(r, "fileID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteFile(ctx, productID, fileID); err != nil {
+		return digitalError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"file deleted",
+		nil,
+	)
+}
+
+func (h *handler) addLicenseKeysHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *AddLicenseKeysRequest
+	defer r.Body.Close()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	resp, err := h.service.addLicenseKeys(ctx, productID, payload)
+	if err != nil {
+		return digitalError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"license keys added",
+		resp,
+	)
+}
+
+func (h *handler) licenseKeyStockHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	stock, err := h.service.licenseKeyStock(ctx, productID)
+	if err != nil {
+		return digitalError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"license key stock found",
+		stock,
+	)
+}
+
+// fulfillOrderHandler grants the digital products of a paid order by hand,
+// e.g. for orders paid outside the store.
+func (h *handler) fulfillOrderHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *FulfillOrderRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	entitlements, err := h.service.Fulfill(ctx, payload.OrderID, payload.UserID, payload.Lines)
+	if err != nil {
+		return digitalError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"order fulfilled",
+		entitlements,
+	)
+}
+
+// shippingRequirementsHandler tells whether the products of a cart need
+// shipping, e.g. ?productId=...&productId=...
+func (h *handler) shippingRequirementsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	payload := &ShippingRequest{ProductIDs: []uuid.UUID{}}
+	for _, value := range r.URL.Query()["productId"] {
+		productID, err := uuid.Parse(value)
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		payload.ProductIDs = append(payload.ProductIDs, productID)
+	}
+
+	if err := validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	shipped, err := h.service.shippedProducts(ctx, payload.ProductIDs)
+	if err != nil {
+		return digitalError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"shipping requirements found",
+		&ShippingResponse{
+			RequiresShipping:  len(shipped) > 0,
+			ShippedProductIDs: shipped,
+		},
+	)
+}
+
+func (h *handler) listDownloadsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	downloads, err := h.service.listDownloads(ctx, userID)
+	if err != nil {
+		return digitalError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"downloads found",
+		downloads,
+	)
+}
+
+// downloadFileHandler streams a file to the holder of a signed download
+// link, each request counts as a download.
+func (h *handler) downloadFileHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		transferTimeout,
+	)
+	defer cancel()
+
+	entitlementID, err := uuid.Parse(chi.URLParam(r, "entitlementID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	fileID, err := uuid.Parse(chi.URLParam(r, "fileID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	query := r.URL.Query()
+	blob, file, err := h.service.openDownload(ctx, entitlementID, fileID, query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		return digitalError(err)
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(file.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = io.Copy(w, blob); err != nil {
+		// the status is already written, all that is left is to log it
+		log.Println(err)
+	}
+
+	return nil
+}
+
+// userIDFromContext returns the id of the user authenticated by the auth
+// middleware.
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, servererrors.New(
+			http.StatusUnauthorized,
+			servererrors.ErrUnauthorized.Error(),
+			nil,
+		)
+	}
+
+	return uuid.Parse(claims.EntityID)
+}
+
+// digitalError maps the errors of the digital product service to responses.
+func digitalError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrProductNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrProductNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrDigitalFileNotFound),
+		errors.Is(err, blobstore.ErrBlobNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrDigitalFileNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrNotDigitalProduct):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrNotDigitalProduct.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrFileTooLarge):
+		return servererrors.New(
+			http.StatusRequestEntityTooLarge,
+			servererrors.ErrFileTooLarge.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrLicenseKeysExhausted):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrLicenseKeysExhausted.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidDownloadLink):
+		return servererrors.New(
+			http.StatusForbidden,
+			servererrors.ErrInvalidDownloadLink.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrDownloadUnavailable):
+		return servererrors.New(
+			http.StatusGone,
+			servererrors.ErrDownloadUnavailable.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
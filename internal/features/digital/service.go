@@ -0,0 +1,388 @@
+package digital
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type digitalStorer interface {
+	productTypes(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]string, error)
+	createFile(ctx context.Context, file *File) error
+	findFile(ctx context.Context, fileID uuid.UUID) (*File, error)
+	findFiles(ctx context.Context, productID uuid.UUID) ([]*File, error)
+	deleteFile(ctx context.Context, fileID uuid.UUID) error
+	addLicenseKeys(ctx context.Context, productID uuid.UUID, keys []string) (int64, error)
+	licenseKeyStock(ctx context.Context, productID uuid.UUID) (*LicenseKeyStock, error)
+	createEntitlements(ctx context.Context, entitlements []*Entitlement) error
+	findEntitlement(ctx context.Context, entitlementID uuid.UUID) (*Entitlement, error)
+	findEntitlementsByOrder(ctx context.Context, orderID uuid.UUID) ([]*Entitlement, error)
+	findEntitlementsByUser(ctx context.Context, userID uuid.UUID) ([]*Entitlement, error)
+	recordDownload(ctx context.Context, entitlementID uuid.UUID, now time.Time) (bool, error)
+}
+
+const (
+	// maxDownloads and accessPeriod bound how often and how long a buyer
+	// can download the files of a digital product
+	maxDownloads = 5
+	accessPeriod = 30 * 24 * time.Hour
+	// maxFileBytes bounds a single digital file
+	maxFileBytes = 2 << 30 // 2GB
+	// defaultContentType is served for files uploaded without one
+	defaultContentType = "application/octet-stream"
+)
+
+type service struct {
+	digitalStore digitalStorer
+	blobStore    blobstore.BlobStore
+	// download links are signed with secret, point below baseURL and stay
+	// valid for linkTTL
+	secret  []byte
+	baseURL string
+	linkTTL time.Duration
+}
+
+func NewService(digitalStore digitalStorer, blobStore blobstore.BlobStore, secret string, baseURL string, linkTTL time.Duration) *service {
+	return &service{
+		digitalStore: digitalStore,
+		blobStore:    blobStore,
+		secret:       []byte(secret),
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		linkTTL:      linkTTL,
+	}
+}
+
+// uploadFile streams a new file of a digital product into the blob store.
+func (s *service) uploadFile(ctx context.Context, productID uuid.UUID, payload *UploadFileRequest, r io.Reader, size int64) (*File, error) {
+	if err := s.checkDigitalProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	if size > maxFileBytes {
+		return nil, servererrors.ErrFileTooLarge
+	}
+
+	fileID := uuid.New()
+	file := &File{
+		FileID:      fileID,
+		ProductID:   productID,
+		Name:        strings.TrimSpace(payload.Name),
+		BlobKey:     fmt.Sprintf("digital/%s/%s", productID, fileID),
+		ContentType: payload.ContentType,
+		SizeBytes:   size,
+	}
+
+	if err := s.blobStore.Put(ctx, file.BlobKey, io.LimitReader(r, size)); err != nil {
+		return nil, err
+	}
+
+	if err := s.digitalStore.createFile(ctx, file); err != nil {
+		if deleteErr := s.blobStore.Delete(context.Background(), file.BlobKey); deleteErr != nil {
+			log.Println(deleteErr)
+		}
+
+		return nil, err
+	}
+
+	return s.digitalStore.findFile(ctx, fileID)
+}
+
+func (s *service) listFiles(ctx context.Context, productID uuid.UUID) ([]*File, error) {
+	if err := s.checkDigitalProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	return s.digitalStore.findFiles(ctx, productID)
+}
+
+// deleteFile removes a file, buyers can no longer download it.
+func (s *service) deleteFile(ctx context.Context, productID uuid.UUID, fileID uuid.UUID) error {
+	file, err := s.digitalStore.findFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	if file.FileID == uuid.Nil || file.ProductID != productID {
+		return servererrors.ErrDigitalFileNotFound
+	}
+
+	if err = s.digitalStore.deleteFile(ctx, fileID); err != nil {
+		return err
+	}
+
+	// the row is gone so a blob left behind is only wasted space
+	if err = s.blobStore.Delete(ctx, file.BlobKey); err != nil {
+		log.Println(err)
+	}
+
+	return nil
+}
+
+// addLicenseKeys adds keys to the pool a product hands its buyers keys from.
+func (s *service) addLicenseKeys(ctx context.Context, productID uuid.UUID, payload *AddLicenseKeysRequest) (*AddLicenseKeysResponse, error) {
+	if err := s.checkDigitalProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(payload.Keys))
+	seen := map[string]bool{}
+	for _, key := range payload.Keys {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	added, err := s.digitalStore.addLicenseKeys(ctx, productID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	stock, err := s.digitalStore.licenseKeyStock(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AddLicenseKeysResponse{
+		Added: added,
+		Stock: stock,
+	}, nil
+}
+
+func (s *service) licenseKeyStock(ctx context.Context, productID uuid.UUID) (*LicenseKeyStock, error) {
+	if err := s.checkDigitalProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	return s.digitalStore.licenseKeyStock(ctx, productID)
+}
+
+// Fulfill grants the buyer of an order the digital products among its lines
+// and hands out their license keys, it is meant to be called once the order
+// is paid. Physical products are skipped and fulfilling an order again
+// returns the entitlements it already has.
+func (s *service) Fulfill(ctx context.Context, orderID uuid.UUID, userID uuid.UUID, lines []OrderLine) ([]*Entitlement, error) {
+	quantities := map[uuid.UUID]int{}
+	productIDs := []uuid.UUID{}
+	for _, line := range lines {
+		if _, ok := quantities[line.ProductID]; !ok {
+			productIDs = append(productIDs, line.ProductID)
+		}
+		quantities[line.ProductID] += line.Quantity
+	}
+
+	types, err := s.digitalStore.productTypes(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entitlements := []*Entitlement{}
+	for _, productID := range productIDs {
+		productType, ok := types[productID]
+		if !ok {
+			return nil, servererrors.ErrProductNotFound
+		}
+
+		if productType != product.TypeDigital {
+			continue
+		}
+
+		entitlements = append(entitlements, &Entitlement{
+			EntitlementID: uuid.New(),
+			OrderID:       orderID,
+			UserID:        userID,
+			ProductID:     productID,
+			Quantity:      quantities[productID],
+			MaxDownloads:  maxDownloads,
+			ExpiresAt:     now.Add(accessPeriod),
+		})
+	}
+
+	if len(entitlements) == 0 {
+		return entitlements, nil
+	}
+
+	if err = s.digitalStore.createEntitlements(ctx, entitlements); err != nil {
+		return nil, err
+	}
+
+	return s.digitalStore.findEntitlementsByOrder(ctx, orderID)
+}
+
+// RequiresShipping tells whether an order of the products has anything to
+// ship, orders of digital products alone skip shipping entirely.
+func (s *service) RequiresShipping(ctx context.Context, productIDs []uuid.UUID) (bool, error) {
+	shipped, err := s.shippedProducts(ctx, productIDs)
+	if err != nil {
+		return false, err
+	}
+
+	return len(shipped) > 0, nil
+}
+
+// shippedProducts returns the products that are not digital, in the order
+// given.
+func (s *service) shippedProducts(ctx context.Context, productIDs []uuid.UUID) ([]uuid.UUID, error) {
+	types, err := s.digitalStore.productTypes(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	shipped := []uuid.UUID{}
+	for _, productID := range productIDs {
+		productType, ok := types[productID]
+		if !ok {
+			return nil, servererrors.ErrProductNotFound
+		}
+
+		if productType != product.TypeDigital {
+			shipped = append(shipped, productID)
+		}
+	}
+
+	return shipped, nil
+}
+
+// listDownloads returns the entitlements of a user, newest first, with
+// freshly signed links to the files of those still usable.
+func (s *service) listDownloads(ctx context.Context, userID uuid.UUID) ([]*DownloadResponse, error) {
+	entitlements, err := s.digitalStore.findEntitlementsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	downloads := make([]*DownloadResponse, 0, len(entitlements))
+	for _, entitlement := range entitlements {
+		download := &DownloadResponse{
+			Entitlement: entitlement,
+			Files:       []*DownloadLink{},
+		}
+		downloads = append(downloads, download)
+
+		if entitlement.DownloadCount >= entitlement.MaxDownloads || !entitlement.ExpiresAt.After(now) {
+			continue
+		}
+
+		files, err := s.digitalStore.findFiles(ctx, entitlement.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		// a link never outlives the entitlement it was signed for
+		expiresAt := now.Add(s.linkTTL)
+		if entitlement.ExpiresAt.Before(expiresAt) {
+			expiresAt = entitlement.ExpiresAt
+		}
+
+		for _, file := range files {
+			download.Files = append(download.Files, &DownloadLink{
+				FileID:      file.FileID,
+				Name:        file.Name,
+				ContentType: file.ContentType,
+				SizeBytes:   file.SizeBytes,
+				URL:         s.downloadURL(entitlement.EntitlementID, file.FileID, expiresAt),
+				ExpiresAt:   expiresAt,
+			})
+		}
+	}
+
+	return downloads, nil
+}
+
+// openDownload checks a signed download link and counts the download
+// against its entitlement before opening the file, callers must close it.
+func (s *service) openDownload(ctx context.Context, entitlementID uuid.UUID, fileID uuid.UUID, expires string, signature string) (io.ReadCloser, *File, error) {
+	now := time.Now()
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() >= expiresAt ||
+		!hmac.Equal([]byte(signature), []byte(s.sign(entitlementID, fileID, expiresAt))) {
+		return nil, nil, servererrors.ErrInvalidDownloadLink
+	}
+
+	entitlement, err := s.digitalStore.findEntitlement(ctx, entitlementID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := s.digitalStore.findFile(ctx, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if entitlement.EntitlementID == uuid.Nil || file.FileID == uuid.Nil || file.ProductID != entitlement.ProductID {
+		return nil, nil, servererrors.ErrDigitalFileNotFound
+	}
+
+	blob, err := s.blobStore.Get(ctx, file.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recorded, err := s.digitalStore.recordDownload(ctx, entitlementID, now)
+	if err != nil || !recorded {
+		blob.Close()
+		if err == nil {
+			err = servererrors.ErrDownloadUnavailable
+		}
+
+		return nil, nil, err
+	}
+
+	return blob, file, nil
+}
+
+// downloadURL returns the signed link to a file of an entitlement.
+func (s *service) downloadURL(entitlementID uuid.UUID, fileID uuid.UUID, expiresAt time.Time) string {
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+		"signature": {s.sign(entitlementID, fileID, expiresAt.Unix())},
+	}
+
+	return fmt.Sprintf("%s/downloads/%s/files/%s?%s", s.baseURL, entitlementID, fileID, query.Encode())
+}
+
+// sign returns the signature of a download link, it covers the entitlement,
+// the file and when the link expires so none of them can be changed.
+func (s *service) sign(entitlementID uuid.UUID, fileID uuid.UUID, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s:%s:%d", entitlementID, fileID, expires)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *service) checkDigitalProduct(ctx context.Context, productID uuid.UUID) error {
+	types, err := s.digitalStore.productTypes(ctx, []uuid.UUID{productID})
+	if err != nil {
+		return err
+	}
+
+	productType, ok := types[productID]
+	if !ok {
+		return servererrors.ErrProductNotFound
+	}
+
+	if productType != product.TypeDigital {
+		return servererrors.ErrNotDigitalProduct
+	}
+
+	return nil
+}
@@ -0,0 +1,500 @@
+package digital
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	fileFields        = "file_id, product_id, name, blob_key, content_type, size_bytes, created_at"
+	entitlementFields = "entitlement_id, order_id, user_id, product_id, quantity, max_downloads, download_count, expires_at, created_at"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+// productTypes returns the type of each product found, keyed by product id.
+func (s *store) productTypes(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT product_id, product_type FROM products WHERE product_id = ANY($1)",
+		pq.Array(productIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find product types in digital store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	types := map[uuid.UUID]string{}
+	for rows.Next() {
+		var productID uuid.UUID
+		var productType string
+		if err = rows.Scan(&productID, &productType); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan product type in digital store: %w",
+				err,
+			)
+		}
+
+		types[productID] = productType
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in digital store: %w",
+			err,
+		)
+	}
+
+	return types, nil
+}
+
+func (s *store) createFile(ctx context.Context, file *File) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO digital_files(file_id, product_id, name, blob_key, content_type, size_bytes) VALUES($1, $2, $3, $4, $5, $6)",
+		file.FileID,
+		file.ProductID,
+		file.Name,
+		file.BlobKey,
+		file.ContentType,
+		file.SizeBytes,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to insert new file in digital store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findFile(ctx context.Context, fileID uuid.UUID) (*File, error) {
+	files, err := s.getFilesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM digital_files WHERE file_id = $1", fileFields),
+		fileID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find file by id in digital store: %w",
+			err,
+		)
+	}
+
+	if len(files) == 0 {
+		return new(File), nil
+	}
+
+	return files[0], nil
+}
+
+func (s *store) findFiles(ctx context.Context, productID uuid.UUID) ([]*File, error) {
+	files, err := s.getFilesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM digital_files WHERE product_id = $1 ORDER BY created_at ASC, file_id ASC", fileFields),
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find files by product in digital store: %w",
+			err,
+		)
+	}
+
+	return files, nil
+}
+
+func (s *store) deleteFile(ctx context.Context, fileID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM digital_files WHERE file_id = $1",
+		fileID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to delete file in digital store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// addLicenseKeys adds keys to the pool of a product, skipping those already
+// in it, and returns how many were added.
+func (s *store) addLicenseKeys(ctx context.Context, productID uuid.UUID, keys []string) (int64, error) {
+	keyIDs := make([]uuid.UUID, len(keys))
+	for i := range keys {
+		keyIDs[i] = uuid.New()
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO license_keys(key_id, product_id, license_key)
+		SELECT key_id, $1, license_key FROM UNNEST($2::uuid[], $3::text[]) AS k(key_id, license_key)
+		ON CONFLICT (product_id, license_key) DO NOTHING`,
+		productID,
+		pq.Array(keyIDs),
+		pq.Array(keys),
+	)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"failed to insert license keys in digital store: %w",
+			err,
+		)
+	}
+
+	added, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf(
+			"failed to count inserted license keys in digital store: %w",
+			err,
+		)
+	}
+
+	return added, nil
+}
+
+func (s *store) licenseKeyStock(ctx context.Context, productID uuid.UUID) (*LicenseKeyStock, error) {
+	stock := &LicenseKeyStock{ProductID: productID}
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE allocated_at IS NULL) FROM license_keys WHERE product_id = $1",
+		productID,
+	).Scan(&stock.Total, &stock.Available)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to count license keys in digital store: %w",
+			err,
+		)
+	}
+
+	return stock, nil
+}
+
+// createEntitlements saves the entitlements of an order and allocates a
+// license key per unit to those of products that have keys, all or nothing.
+// Entitlements the order already has for a product are left as they are.
+func (s *store) createEntitlements(ctx context.Context, entitlements []*Entitlement) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, entitlement := range entitlements {
+			result, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO digital_entitlements(entitlement_id, order_id, user_id, product_id, quantity, max_downloads, expires_at)
+				VALUES($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (order_id, product_id) DO NOTHING`,
+				entitlement.EntitlementID,
+				entitlement.OrderID,
+				entitlement.UserID,
+				entitlement.ProductID,
+				entitlement.Quantity,
+				entitlement.MaxDownloads,
+				entitlement.ExpiresAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert entitlement in digital store: %w", err)
+			}
+
+			inserted, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to count inserted entitlements in digital store: %w", err)
+			}
+
+			if inserted == 0 {
+				continue
+			}
+
+			// SKIP LOCKED lets concurrent orders take different keys
+			// instead of waiting on each other
+			result, err = tx.ExecContext(
+				ctx,
+				`UPDATE license_keys SET entitlement_id = $1, allocated_at = NOW() WHERE key_id IN (
+					SELECT key_id FROM license_keys WHERE product_id = $2 AND allocated_at IS NULL
+					ORDER BY created_at ASC, key_id ASC LIMIT $3 FOR UPDATE SKIP LOCKED
+				)`,
+				entitlement.EntitlementID,
+				entitlement.ProductID,
+				entitlement.Quantity,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to allocate license keys in digital store: %w", err)
+			}
+
+			allocated, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to count allocated license keys in digital store: %w", err)
+			}
+
+			if allocated == int64(entitlement.Quantity) {
+				continue
+			}
+
+			var hasKeys bool
+			err = tx.QueryRowContext(
+				ctx,
+				"SELECT EXISTS(SELECT 1 FROM license_keys WHERE product_id = $1)",
+				entitlement.ProductID,
+			).Scan(&hasKeys)
+			if err != nil {
+				return fmt.Errorf("failed to find license keys in digital store: %w", err)
+			}
+
+			if hasKeys {
+				return servererrors.ErrLicenseKeysExhausted
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *store) findEntitlement(ctx context.Context, entitlementID uuid.UUID) (*Entitlement, error) {
+	entitlements, err := s.getEntitlementsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM digital_entitlements WHERE entitlement_id = $1", entitlementFields),
+		entitlementID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find entitlement by id in digital store: %w",
+			err,
+		)
+	}
+
+	if len(entitlements) == 0 {
+		return new(Entitlement), nil
+	}
+
+	return entitlements[0], nil
+}
+
+func (s *store) findEntitlementsByOrder(ctx context.Context, orderID uuid.UUID) ([]*Entitlement, error) {
+	entitlements, err := s.getEntitlementsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM digital_entitlements WHERE order_id = $1 ORDER BY created_at ASC, entitlement_id ASC", entitlementFields),
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find entitlements by order in digital store: %w",
+			err,
+		)
+	}
+
+	return entitlements, nil
+}
+
+func (s *store) findEntitlementsByUser(ctx context.Context, userID uuid.UUID) ([]*Entitlement, error) {
+	entitlements, err := s.getEntitlementsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM digital_entitlements WHERE user_id = $1 ORDER BY created_at DESC, entitlement_id ASC", entitlementFields),
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find entitlements by user in digital store: %w",
+			err,
+		)
+	}
+
+	return entitlements, nil
+}
+
+// recordDownload counts a download against an entitlement, it reports false
+// without counting it once the entitlement is used up or expired.
+func (s *store) recordDownload(ctx context.Context, entitlementID uuid.UUID, now time.Time) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		"UPDATE digital_entitlements SET download_count = download_count + 1 WHERE entitlement_id = $1 AND download_count < max_downloads AND expires_at > $2",
+		entitlementID,
+		now,
+	)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to record download in digital store: %w",
+			err,
+		)
+	}
+
+	recorded, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to count recorded downloads in digital store: %w",
+			err,
+		)
+	}
+
+	return recorded == 1, nil
+}
+
+func (s *store) getFilesWithContext(ctx context.Context, query string, args ...any) ([]*File, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in digital store getFilesWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	files := []*File{}
+	for rows.Next() {
+		file := new(File)
+		err = rows.Scan(
+			&file.FileID,
+			&file.ProductID,
+			&file.Name,
+			&file.BlobKey,
+			&file.ContentType,
+			&file.SizeBytes,
+			&file.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into file in digital store: %w",
+				err,
+			)
+		}
+
+		files = append(files, file)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in digital store: %w",
+			err,
+		)
+	}
+
+	return files, nil
+}
+
+// getEntitlementsWithContext runs a query selecting entitlementFields and
+// attaches the license keys allocated to each entitlement.
+func (s *store) getEntitlementsWithContext(ctx context.Context, query string, args ...any) ([]*Entitlement, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in digital store getEntitlementsWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	entitlements := []*Entitlement{}
+	byID := map[uuid.UUID]*Entitlement{}
+	entitlementIDs := []uuid.UUID{}
+	for rows.Next() {
+		entitlement := &Entitlement{LicenseKeys: []string{}}
+		err = rows.Scan(
+			&entitlement.EntitlementID,
+			&entitlement.OrderID,
+			&entitlement.UserID,
+			&entitlement.ProductID,
+			&entitlement.Quantity,
+			&entitlement.MaxDownloads,
+			&entitlement.DownloadCount,
+			&entitlement.ExpiresAt,
+			&entitlement.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into entitlement in digital store: %w",
+				err,
+			)
+		}
+
+		entitlements = append(entitlements, entitlement)
+		byID[entitlement.EntitlementID] = entitlement
+		entitlementIDs = append(entitlementIDs, entitlement.EntitlementID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in digital store: %w",
+			err,
+		)
+	}
+
+	if len(entitlements) == 0 {
+		return entitlements, nil
+	}
+
+	keyRows, err := s.db.QueryContext(
+		ctx,
+		"SELECT entitlement_id, license_key FROM license_keys WHERE entitlement_id = ANY($1) ORDER BY allocated_at ASC, key_id ASC",
+		pq.Array(entitlementIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query license keys in digital store: %w",
+			err,
+		)
+	}
+	defer keyRows.Close()
+
+	for keyRows.Next() {
+		var entitlementID uuid.UUID
+		var key string
+		if err = keyRows.Scan(&entitlementID, &key); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan license key in digital store: %w",
+				err,
+			)
+		}
+
+		byID[entitlementID].LicenseKeys = append(byID[entitlementID].LicenseKeys, key)
+	}
+
+	if err = keyRows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate license keys in digital store: %w",
+			err,
+		)
+	}
+
+	return entitlements, nil
+}
+
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in digital store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in digital store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package inventory
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+// earthRadiusKm is the mean radius of the earth used for distances.
+const earthRadiusKm = 6371.0
+
+// stockAt is the stock of the variants of an order at a location.
+type stockAt struct {
+	location   *Location
+	quantities map[uuid.UUID]int64
+}
+
+// allocate picks the locations each line ships from, splitting a line over
+// locations when none has all of it. Lines of variants that are not kept at
+// any location get no allocation. It returns
+// servererrors.ErrInsufficientStock when the locations do not have enough of
+// a line between them.
+func allocate(lines []*ReservationLine, stock []*stockAt, strategy string, destination *Coordinates) ([]*Allocation, error) {
+	tracked := map[uuid.UUID]bool{}
+	for _, at := range stock {
+		for variantID := range at.quantities {
+			tracked[variantID] = true
+		}
+	}
+
+	remaining := map[uuid.UUID]int64{}
+	for _, line := range lines {
+		if tracked[line.VariantID] {
+			remaining[line.VariantID] += line.Quantity
+		}
+	}
+
+	ranked := rankLocations(stock, strategy, destination)
+	if strategy == AllocationStrategyWholeOrder {
+		ranked = coverWholeOrder(ranked, remaining)
+	}
+
+	available := make(map[*stockAt]map[uuid.UUID]int64, len(ranked))
+	for _, at := range ranked {
+		available[at] = make(map[uuid.UUID]int64, len(at.quantities))
+		for variantID, quantity := range at.quantities {
+			available[at][variantID] = quantity
+		}
+	}
+
+	allocations := []*Allocation{}
+	for _, line := range lines {
+		if !tracked[line.VariantID] {
+			continue
+		}
+
+		need := line.Quantity
+		for _, at := range ranked {
+			take := min(need, available[at][line.VariantID])
+			if take <= 0 {
+				continue
+			}
+
+			allocations = append(allocations, &Allocation{
+				VariantID:    line.VariantID,
+				LocationID:   at.location.LocationID,
+				LocationCode: at.location.Code,
+				Quantity:     take,
+			})
+			available[at][line.VariantID] -= take
+			need -= take
+
+			if need == 0 {
+				break
+			}
+		}
+
+		if need > 0 {
+			return nil, servererrors.ErrInsufficientStock
+		}
+	}
+
+	return allocations, nil
+}
+
+// rankLocations orders locations by distance to the destination under the
+// closest strategy, or when the whole order strategy has a destination to
+// break ties with, and by priority otherwise.
+func rankLocations(stock []*stockAt, strategy string, destination *Coordinates) []*stockAt {
+	ranked := slices.Clone(stock)
+
+	byDistance := destination != nil && strategy != AllocationStrategyPriority
+	slices.SortStableFunc(ranked, func(x, y *stockAt) int {
+		a, b := x.location, y.location
+		if byDistance {
+			if c := cmp.Compare(distanceKm(destination, a), distanceKm(destination, b)); c != 0 {
+				return c
+			}
+		}
+
+		if c := cmp.Compare(a.Priority, b.Priority); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Code, b.Code)
+	})
+
+	return ranked
+}
+
+// coverWholeOrder moves the best ranked location that has every line to the
+// front. When there is none the order is split, taking first the locations
+// that cover the most of what is left so it ships from as few as possible.
+func coverWholeOrder(ranked []*stockAt, remaining map[uuid.UUID]int64) []*stockAt {
+	for i, at := range ranked {
+		if covered(at, remaining) == total(remaining) {
+			return append([]*stockAt{at}, slices.Delete(slices.Clone(ranked), i, i+1)...)
+		}
+	}
+
+	left := make(map[uuid.UUID]int64, len(remaining))
+	for variantID, quantity := range remaining {
+		left[variantID] = quantity
+	}
+
+	pool := slices.Clone(ranked)
+	ordered := make([]*stockAt, 0, len(ranked))
+	for len(pool) > 0 && total(left) > 0 {
+		best, bestCovered := 0, int64(0)
+		for i, at := range pool {
+			if c := covered(at, left); c > bestCovered {
+				best, bestCovered = i, c
+			}
+		}
+
+		if bestCovered == 0 {
+			break
+		}
+
+		at := pool[best]
+		for variantID, quantity := range left {
+			left[variantID] = quantity - min(quantity, at.quantities[variantID])
+		}
+
+		ordered = append(ordered, at)
+		pool = slices.Delete(pool, best, best+1)
+	}
+
+	return append(ordered, pool...)
+}
+
+// covered is how many of the remaining units a location has.
+func covered(at *stockAt, remaining map[uuid.UUID]int64) int64 {
+	var units int64
+	for variantID, quantity := range remaining {
+		units += min(quantity, at.quantities[variantID])
+	}
+
+	return units
+}
+
+func total(remaining map[uuid.UUID]int64) int64 {
+	var units int64
+	for _, quantity := range remaining {
+		units += quantity
+	}
+
+	return units
+}
+
+// distanceKm is the great circle distance from a point to a location.
+func distanceKm(from *Coordinates, to *Location) float64 {
+	lat1, lat2 := from.Latitude*math.Pi/180, to.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (to.Longitude - from.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
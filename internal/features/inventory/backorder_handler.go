@@ -0,0 +1,192 @@
+package inventory
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// cartAvailabilityHandler tells how each line of a cart would be sold, from
+// stock, as a backorder or preorder, or not at all.
+func (h *handler) cartAvailabilityHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *CartAvailabilityRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	lines, err := h.service.cartAvailability(ctx, payload)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"cart availability",
+		lines,
+	)
+}
+
+func (h *handler) setAvailabilityPolicyHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	variantID, err := uuid.Parse(chi.URLParam(r, "variantID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	var payload *SetAvailabilityPolicyRequest
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	policy, err := h.service.setAvailabilityPolicy(ctx, variantID, payload)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"availability policy saved",
+		policy,
+	)
+}
+
+func (h *handler) deleteAvailabilityPolicyHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	variantID, err := uuid.Parse(chi.URLParam(r, "variantID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteAvailabilityPolicy(ctx, variantID); err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"availability policy deleted",
+		nil,
+	)
+}
+
+// listBackordersHandler lists backorders in the order they are allocated,
+// e.g. ?status=pending&variantId=...
+func (h *handler) listBackordersHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	page, err := handlerutils.ParseQueryInt(r, "page", 1)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	pageSize, err := handlerutils.ParseQueryInt(r, "pageSize", defaultPageSize)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	payload := &ListBackordersRequest{
+		Status:   r.URL.Query().Get("status"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if payload.VariantID, err = handlerutils.ParseOptionalQueryUUID(r, "variantId"); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	backorders, err := h.service.listBackorders(ctx, payload)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"backorders found",
+		backorders,
+	)
+}
@@ -0,0 +1,233 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+// backorderBatchSize is how many backorders AllocateBackorders reads per
+// query.
+const backorderBatchSize = 100
+
+func (s *service) setAvailabilityPolicy(ctx context.Context, variantID uuid.UUID, payload *SetAvailabilityPolicyRequest) (*AvailabilityPolicy, error) {
+	policy := &AvailabilityPolicy{
+		VariantID:      variantID,
+		Mode:           payload.Mode,
+		Limit:          payload.Limit,
+		DepositPercent: payload.DepositPercent,
+	}
+
+	if payload.ExpectedShipDate != nil {
+		shipDate := payload.ExpectedShipDate.UTC().Truncate(24 * time.Hour)
+		policy.ExpectedShipDate = &shipDate
+	}
+
+	if err := s.inventoryStore.setAvailabilityPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	return s.inventoryStore.findAvailabilityPolicy(ctx, variantID)
+}
+
+func (s *service) deleteAvailabilityPolicy(ctx context.Context, variantID uuid.UUID) error {
+	deleted, err := s.inventoryStore.deleteAvailabilityPolicy(ctx, variantID)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return servererrors.ErrAvailabilityNotFound
+	}
+
+	return nil
+}
+
+// cartAvailability tells how each line of a cart would be sold if it was
+// checked out now, from stock, as a backorder or preorder, or not at all.
+func (s *service) cartAvailability(ctx context.Context, payload *CartAvailabilityRequest) ([]*LineAvailability, error) {
+	lines := mergeLines(payload.Lines)
+	variantIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+
+	availabilities, err := s.inventoryStore.variantAvailability(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byVariant := make(map[uuid.UUID]*variantAvailability, len(availabilities))
+	for _, availability := range availabilities {
+		byVariant[availability.variantID] = availability
+	}
+
+	cart := make([]*LineAvailability, 0, len(lines))
+	for _, line := range lines {
+		availability, ok := byVariant[line.VariantID]
+		if !ok {
+			return nil, servererrors.ErrVariantNotFound
+		}
+
+		lineAvailability := &LineAvailability{
+			VariantID: line.VariantID,
+			SKU:       availability.sku,
+			Quantity:  line.Quantity,
+		}
+
+		if err = splitLine(line, availability); err != nil {
+			lineAvailability.InStock = freeStock(availability)
+			lineAvailability.Availability = AvailabilityUnavailable
+		} else {
+			lineAvailability.InStock = line.inStock()
+			lineAvailability.Backordered = line.Backordered
+			lineAvailability.Availability = line.Availability
+			lineAvailability.ExpectedShipDate = line.ExpectedShipDate
+			lineAvailability.DepositPercent = line.DepositPercent
+		}
+
+		cart = append(cart, lineAvailability)
+	}
+
+	return cart, nil
+}
+
+func (s *service) listBackorders(ctx context.Context, payload *ListBackordersRequest) (*ListBackordersResponse, error) {
+	backorders, totalCount, err := s.inventoryStore.listBackorders(ctx, &backorderFilter{
+		Status:    payload.Status,
+		VariantID: payload.VariantID,
+		Limit:     payload.PageSize,
+		Offset:    (payload.Page - 1) * payload.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListBackordersResponse{
+		Backorders: backorders,
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// AllocateBackorders gives received stock to the backorders waiting for it,
+// oldest first, and returns how many units it allocated. A backorder only
+// gets stock once every older backorder of its variant has all it needs.
+func (s *service) AllocateBackorders(ctx context.Context) (int64, error) {
+	var allocated int64
+	reservations := map[uuid.UUID]*Reservation{}
+	for {
+		backorders, err := s.inventoryStore.pendingBackorders(ctx, backorderBatchSize)
+		if err != nil {
+			return allocated, err
+		}
+
+		// the stock of a variant left for younger backorders
+		available := map[uuid.UUID]int64{}
+		progressed := false
+		for _, backorder := range backorders {
+			left, ok := available[backorder.VariantID]
+			if !ok {
+				left = backorder.available
+			}
+
+			quantity := min(backorder.Quantity-backorder.Allocated, left)
+			if quantity <= 0 {
+				continue
+			}
+
+			reservation, ok := reservations[backorder.ReservationID]
+			if !ok {
+				if reservation, err = s.inventoryStore.findReservation(ctx, backorder.ReservationID); err != nil {
+					return allocated, err
+				}
+
+				reservations[backorder.ReservationID] = reservation
+			}
+
+			lines := []*ReservationLine{{VariantID: backorder.VariantID, Quantity: quantity}}
+			allocations, err := s.Allocate(ctx, lines, reservation.Strategy, reservation.Destination)
+			if err == nil {
+				ok, err = s.inventoryStore.allocateBackorder(ctx, backorder, quantity, allocations)
+			}
+
+			if err != nil {
+				if !errors.Is(err, servererrors.ErrInsufficientStock) {
+					return allocated, err
+				}
+
+				// the stock moved meanwhile, younger backorders wait for the
+				// next run rather than jump the queue
+				available[backorder.VariantID] = 0
+				continue
+			}
+
+			available[backorder.VariantID] = left - quantity
+			if ok {
+				allocated += quantity
+				progressed = true
+			}
+		}
+
+		if len(backorders) < backorderBatchSize || !progressed {
+			return allocated, nil
+		}
+	}
+}
+
+// RunBackorderJob allocates received stock to backorders every interval. It
+// blocks until ctx is done.
+func (s *service) RunBackorderJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.AllocateBackorders(ctx); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// splitLine splits a line into the part held from the stock on hand and the
+// part backordered beyond it, filling in the terms the line is sold on. It
+// returns servererrors.ErrInsufficientStock when the variant can not be sold
+// beyond its stock and servererrors.ErrBackorderLimitReached when its limit
+// does not leave enough.
+func splitLine(line *ReservationLine, availability *variantAvailability) error {
+	line.Backordered = max(line.Quantity-freeStock(availability), 0)
+	line.Availability = AvailabilityInStock
+	line.ExpectedShipDate, line.DepositPercent = nil, nil
+	if line.Backordered == 0 {
+		return nil
+	}
+
+	policy := availability.policy
+	if policy == nil {
+		return servererrors.ErrInsufficientStock
+	}
+
+	if policy.Limit != nil && availability.backordered+line.Backordered > *policy.Limit {
+		return servererrors.ErrBackorderLimitReached
+	}
+
+	line.Availability = policy.Mode
+	line.ExpectedShipDate = policy.ExpectedShipDate
+	line.DepositPercent = policy.DepositPercent
+	return nil
+}
+
+// freeStock is the stock of a variant new lines can be held from, stock owed
+// to backorders goes to them first.
+func freeStock(availability *variantAvailability) int64 {
+	return max(availability.onHand-availability.reserved-availability.backordered, 0)
+}
@@ -0,0 +1,428 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	policyFields       = "p.variant_id, p.mode, p.backorder_limit, p.expected_ship_date, p.deposit_percent, p.created_at, p.updated_at"
+	availabilityFields = "vs.variant_id, v.sku, vs.quantity, vs.reserved, vs.backordered, p.variant_id, p.mode, p.backorder_limit, p.expected_ship_date, p.deposit_percent, p.created_at, p.updated_at"
+	availabilityJoins  = "variant_stock vs JOIN product_variants v ON v.variant_id = vs.variant_id LEFT JOIN variant_availability_policies p ON p.variant_id = vs.variant_id"
+	backorderFields    = "b.reservation_id, b.variant_id, v.sku, b.quantity, b.allocated, b.status, b.created_at, b.updated_at, b.fulfilled_at"
+	backorderJoins     = "backorders b JOIN product_variants v ON v.variant_id = b.variant_id"
+)
+
+// setAvailabilityPolicy creates or replaces the policy of a variant, it
+// returns servererrors.ErrVariantNotFound for unknown variants.
+func (s *store) setAvailabilityPolicy(ctx context.Context, policy *AvailabilityPolicy) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO variant_availability_policies(variant_id, mode, backorder_limit, expected_ship_date, deposit_percent) VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (variant_id) DO UPDATE SET mode = EXCLUDED.mode, backorder_limit = EXCLUDED.backorder_limit,
+			expected_ship_date = EXCLUDED.expected_ship_date, deposit_percent = EXCLUDED.deposit_percent, updated_at = NOW()`,
+		policy.VariantID,
+		policy.Mode,
+		policy.Limit,
+		policy.ExpectedShipDate,
+		policy.DepositPercent,
+	)
+	if err != nil {
+		if isPQError(err, foreignKeyViolation) {
+			return servererrors.ErrVariantNotFound
+		}
+
+		return fmt.Errorf("failed to set availability policy in inventory store: %w", err)
+	}
+
+	return nil
+}
+
+// findAvailabilityPolicy returns the policy of a variant, a zero
+// AvailabilityPolicy when it has none.
+func (s *store) findAvailabilityPolicy(ctx context.Context, variantID uuid.UUID) (*AvailabilityPolicy, error) {
+	policies, err := s.getPoliciesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM variant_availability_policies p WHERE p.variant_id = $1", policyFields),
+		variantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(policies) == 0 {
+		return new(AvailabilityPolicy), nil
+	}
+
+	return policies[0], nil
+}
+
+// deleteAvailabilityPolicy stops a variant from being sold beyond its stock,
+// it returns false when the variant had no policy. Lines already backordered
+// are still allocated.
+func (s *store) deleteAvailabilityPolicy(ctx context.Context, variantID uuid.UUID) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM variant_availability_policies WHERE variant_id = $1",
+		variantID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete availability policy in inventory store: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count deleted availability policies in inventory store: %w", err)
+	}
+
+	return deleted > 0, nil
+}
+
+// variantAvailability returns the stock and policy of each of the variants
+// that exists.
+func (s *store) variantAvailability(ctx context.Context, variantIDs []uuid.UUID) ([]*variantAvailability, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE vs.variant_id = ANY($1)", availabilityFields, availabilityJoins),
+		pq.Array(variantIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find variant availability in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	availabilities := []*variantAvailability{}
+	for rows.Next() {
+		availability, err := scanAvailability(rows)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into variant availability in inventory store: %w",
+				err,
+			)
+		}
+
+		availabilities = append(availabilities, availability)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return availabilities, nil
+}
+
+// pendingBackorders returns up to limit pending backorders of variants with
+// stock that is not reserved by checkouts, oldest first.
+func (s *store) pendingBackorders(ctx context.Context, limit int) ([]*Backorder, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			`SELECT %s, vs.quantity - vs.reserved FROM %s JOIN variant_stock vs ON vs.variant_id = b.variant_id
+			WHERE b.status = 'pending' AND vs.quantity > vs.reserved
+			ORDER BY b.created_at ASC, b.reservation_id ASC
+			LIMIT $1`,
+			backorderFields,
+			backorderJoins,
+		),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find pending backorders in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	backorders := []*Backorder{}
+	for rows.Next() {
+		backorder := new(Backorder)
+		err = rows.Scan(
+			&backorder.ReservationID,
+			&backorder.VariantID,
+			&backorder.SKU,
+			&backorder.Quantity,
+			&backorder.Allocated,
+			&backorder.Status,
+			&backorder.CreatedAt,
+			&backorder.UpdatedAt,
+			&backorder.FulfilledAt,
+			&backorder.available,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into backorder in inventory store: %w",
+				err,
+			)
+		}
+
+		backorders = append(backorders, backorder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return backorders, nil
+}
+
+// allocateBackorder takes quantity units of received stock for a backorder,
+// out of the locations they are allocated to, and records the sale. It
+// returns false when the backorder no longer needs them and
+// servererrors.ErrInsufficientStock when the stock or a location no longer
+// has them.
+func (s *store) allocateBackorder(ctx context.Context, backorder *Backorder, quantity int64, allocations []*Allocation) (bool, error) {
+	allocated := false
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE backorders SET allocated = allocated + $3,
+				status = CASE WHEN allocated + $3 = quantity THEN 'fulfilled' ELSE 'pending' END,
+				fulfilled_at = CASE WHEN allocated + $3 = quantity THEN NOW() END,
+				updated_at = NOW()
+			WHERE reservation_id = $1 AND variant_id = $2 AND status = 'pending' AND allocated + $3 <= quantity`,
+			backorder.ReservationID,
+			backorder.VariantID,
+			quantity,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update backorder in inventory store: %w", err)
+		}
+
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count updated backorders in inventory store: %w", err)
+		}
+
+		if updated == 0 {
+			return nil
+		}
+
+		result, err = tx.ExecContext(
+			ctx,
+			"UPDATE variant_stock SET quantity = quantity - $1, backordered = backordered - $1, updated_at = NOW() WHERE variant_id = $2 AND quantity - reserved >= $1",
+			quantity,
+			backorder.VariantID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update variant stock in inventory store: %w", err)
+		}
+
+		updated, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count updated variant stock in inventory store: %w", err)
+		}
+
+		if updated == 0 {
+			return servererrors.ErrInsufficientStock
+		}
+
+		if err = applyAllocations(ctx, tx, backorder.ReservationID, allocations); err != nil {
+			return err
+		}
+
+		lines := []*ReservationLine{{VariantID: backorder.VariantID, Quantity: quantity}}
+		err = stockledger.Record(ctx, tx, commitMovements(backorder.ReservationID, lines, allocations)...)
+		if err != nil {
+			return fmt.Errorf("failed to record backorder in inventory store: %w", err)
+		}
+
+		allocated = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return allocated, nil
+}
+
+// listBackorders returns a page of the backorders matching filter, oldest
+// first as they are allocated, and how many match in total.
+func (s *store) listBackorders(ctx context.Context, filter *backorderFilter) ([]*Backorder, int64, error) {
+	conditions := []string{"TRUE"}
+	args := []any{}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("b.status = $%d", len(args)))
+	}
+
+	if filter.VariantID != nil {
+		args = append(args, *filter.VariantID)
+		conditions = append(conditions, fmt.Sprintf("b.variant_id = $%d", len(args)))
+	}
+
+	where := strings.Join(conditions, " AND ")
+
+	var totalCount int64
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM backorders b WHERE "+where,
+		args...,
+	).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to count backorders in inventory store: %w",
+			err,
+		)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT %s FROM %s WHERE %s ORDER BY b.created_at ASC, b.reservation_id ASC, v.sku ASC LIMIT $%d OFFSET $%d",
+			backorderFields,
+			backorderJoins,
+			where,
+			len(args)-1,
+			len(args),
+		),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to find backorders in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	backorders := []*Backorder{}
+	for rows.Next() {
+		backorder := new(Backorder)
+		err = rows.Scan(
+			&backorder.ReservationID,
+			&backorder.VariantID,
+			&backorder.SKU,
+			&backorder.Quantity,
+			&backorder.Allocated,
+			&backorder.Status,
+			&backorder.CreatedAt,
+			&backorder.UpdatedAt,
+			&backorder.FulfilledAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf(
+				"failed to scan row into backorder in inventory store: %w",
+				err,
+			)
+		}
+
+		backorders = append(backorders, backorder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return backorders, totalCount, nil
+}
+
+func (s *store) getPoliciesWithContext(ctx context.Context, query string, args ...any) ([]*AvailabilityPolicy, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find availability policies in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	policies := []*AvailabilityPolicy{}
+	for rows.Next() {
+		policy := new(AvailabilityPolicy)
+		err = rows.Scan(
+			&policy.VariantID,
+			&policy.Mode,
+			&policy.Limit,
+			&policy.ExpectedShipDate,
+			&policy.DepositPercent,
+			&policy.CreatedAt,
+			&policy.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into availability policy in inventory store: %w",
+				err,
+			)
+		}
+
+		policies = append(policies, policy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return policies, nil
+}
+
+// scanner is a *sql.Row or *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAvailability scans a row of availabilityFields, the policy is nil for
+// variants without one.
+func scanAvailability(row scanner) (*variantAvailability, error) {
+	availability := new(variantAvailability)
+	var policyVariantID *uuid.UUID
+	var mode sql.NullString
+	var createdAt, updatedAt sql.NullTime
+	policy := new(AvailabilityPolicy)
+	err := row.Scan(
+		&availability.variantID,
+		&availability.sku,
+		&availability.onHand,
+		&availability.reserved,
+		&availability.backordered,
+		&policyVariantID,
+		&mode,
+		&policy.Limit,
+		&policy.ExpectedShipDate,
+		&policy.DepositPercent,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if policyVariantID != nil {
+		policy.VariantID = *policyVariantID
+		policy.Mode = mode.String
+		policy.CreatedAt = createdAt.Time
+		policy.UpdatedAt = updatedAt.Time
+		availability.policy = policy
+	}
+
+	return availability, nil
+}
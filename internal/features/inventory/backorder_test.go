@@ -0,0 +1,285 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestSplitLine(t *testing.T) {
+	shipDate := time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC)
+	backorder := &AvailabilityPolicy{Mode: AvailabilityBackorder, Limit: int64Ptr(5)}
+	preorder := &AvailabilityPolicy{Mode: AvailabilityPreorder, ExpectedShipDate: &shipDate, DepositPercent: intPtr(20)}
+
+	cases := []struct {
+		name         string
+		quantity     int64
+		availability variantAvailability
+		backordered  int64
+		mode         string
+		err          error
+	}{
+		{"in stock", 3, variantAvailability{onHand: 5, reserved: 2}, 0, AvailabilityInStock, nil},
+		{"in stock without a policy", 3, variantAvailability{onHand: 3}, 0, AvailabilityInStock, nil},
+		{"beyond stock without a policy", 4, variantAvailability{onHand: 3}, 0, "", servererrors.ErrInsufficientStock},
+		{"owed to backorders", 2, variantAvailability{onHand: 3, backordered: 2}, 0, "", servererrors.ErrInsufficientStock},
+		{"backordered", 4, variantAvailability{onHand: 3, policy: backorder}, 1, AvailabilityBackorder, nil},
+		{"up to the limit", 3, variantAvailability{onHand: 3, reserved: 1, backordered: 2, policy: backorder}, 3, AvailabilityBackorder, nil},
+		{"beyond the limit", 4, variantAvailability{onHand: 3, reserved: 1, backordered: 2, policy: backorder}, 0, "", servererrors.ErrBackorderLimitReached},
+		{"preordered", 10, variantAvailability{policy: preorder}, 10, AvailabilityPreorder, nil},
+	}
+
+	for _, c := range cases {
+		line := &ReservationLine{Quantity: c.quantity}
+		err := splitLine(line, &c.availability)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: expected error %v, got %v", c.name, c.err, err)
+			continue
+		}
+
+		if err != nil || c.mode == "" {
+			continue
+		}
+
+		if line.Backordered != c.backordered || line.Availability != c.mode {
+			t.Errorf("%s: expected %d %s, got %d %s", c.name, c.backordered, c.mode, line.Backordered, line.Availability)
+		}
+	}
+
+	line := &ReservationLine{Quantity: 2}
+	if err := splitLine(line, &variantAvailability{policy: preorder}); err != nil {
+		t.Fatal(err)
+	}
+
+	if line.ExpectedShipDate == nil || !line.ExpectedShipDate.Equal(shipDate) || line.DepositPercent == nil || *line.DepositPercent != 20 {
+		t.Errorf("expected the preorder terms on the line, got %+v", line)
+	}
+}
+
+func TestBackorders(t *testing.T) {
+	router, inventoryStore, _ := newTestRouter(t)
+
+	productID := uuid.New()
+	shirt := inventoryStore.addVariant(productID, "SHIRT-M", 2)
+	mug := inventoryStore.addVariant(productID, "MUG", 0)
+	lamp := inventoryStore.addVariant(productID, "LAMP", 0)
+	alice := uuid.New()
+	bob := uuid.New()
+
+	policyPath := func(variantID uuid.UUID) string {
+		return "/admin/inventory/variants/" + variantID.String() + "/availability"
+	}
+
+	reserve := func(t *testing.T, userID uuid.UUID, variantID uuid.UUID, quantity int64) (*Reservation, int) {
+		t.Helper()
+
+		payload := ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: variantID, Quantity: quantity}}}
+		reservation := new(Reservation)
+		code := testutil.Serve(t, router, http.MethodPost, "/checkout/reservations", &userID, payload, reservation)
+		return reservation, code
+	}
+
+	commit := func(t *testing.T, reservationID uuid.UUID) *Reservation {
+		t.Helper()
+
+		committed := new(Reservation)
+		path := "/admin/inventory/reservations/" + reservationID.String() + "/commit"
+		if code := testutil.Serve(t, router, http.MethodPost, path, nil, nil, committed); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		return committed
+	}
+
+	receive := func(t *testing.T, variantID uuid.UUID, quantity int64) {
+		t.Helper()
+
+		receipt := RecordMovementRequest{VariantID: variantID, Quantity: quantity, Reason: stockledger.ReasonReceipt}
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/movements", nil, receipt, nil); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+	}
+
+	t.Run("should reject invalid policies", func(t *testing.T) {
+		shipDate := time.Now().AddDate(0, 2, 0)
+		cases := []struct {
+			name      string
+			variantID uuid.UUID
+			payload   SetAvailabilityPolicyRequest
+			status    int
+		}{
+			{"no mode", shirt, SetAvailabilityPolicyRequest{Limit: int64Ptr(3)}, http.StatusUnprocessableEntity},
+			{"backorder without a limit", shirt, SetAvailabilityPolicyRequest{Mode: AvailabilityBackorder}, http.StatusUnprocessableEntity},
+			{"backorder with a deposit", shirt, SetAvailabilityPolicyRequest{Mode: AvailabilityBackorder, Limit: int64Ptr(3), DepositPercent: intPtr(10)}, http.StatusUnprocessableEntity},
+			{"preorder without a ship date", mug, SetAvailabilityPolicyRequest{Mode: AvailabilityPreorder}, http.StatusUnprocessableEntity},
+			{"deposit over the price", mug, SetAvailabilityPolicyRequest{Mode: AvailabilityPreorder, ExpectedShipDate: &shipDate, DepositPercent: intPtr(101)}, http.StatusUnprocessableEntity},
+			{"unknown variant", uuid.New(), SetAvailabilityPolicyRequest{Mode: AvailabilityBackorder, Limit: int64Ptr(3)}, http.StatusUnprocessableEntity},
+		}
+
+		for _, c := range cases {
+			if code := testutil.Serve(t, router, http.MethodPut, policyPath(c.variantID), nil, c.payload, nil); code != c.status {
+				t.Errorf("%s: expected status code %d, got %d", c.name, c.status, code)
+			}
+		}
+
+		if code := testutil.Serve(t, router, http.MethodDelete, policyPath(lamp), nil, nil, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+	})
+
+	t.Run("should tell how a cart would be sold", func(t *testing.T) {
+		backorder := SetAvailabilityPolicyRequest{Mode: AvailabilityBackorder, Limit: int64Ptr(3)}
+		if code := testutil.Serve(t, router, http.MethodPut, policyPath(shirt), nil, backorder, nil); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		shipDate := time.Now().AddDate(0, 2, 0)
+		preorder := SetAvailabilityPolicyRequest{Mode: AvailabilityPreorder, ExpectedShipDate: &shipDate, DepositPercent: intPtr(20)}
+		policy := new(AvailabilityPolicy)
+		if code := testutil.Serve(t, router, http.MethodPut, policyPath(mug), nil, preorder, policy); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if policy.ExpectedShipDate == nil || policy.ExpectedShipDate.Hour() != 0 {
+			t.Errorf("expected the ship date truncated to the day, got %v", policy.ExpectedShipDate)
+		}
+
+		payload := CartAvailabilityRequest{Lines: []ReservationLineRequest{
+			{VariantID: shirt, Quantity: 4},
+			{VariantID: mug, Quantity: 1},
+			{VariantID: lamp, Quantity: 1},
+		}}
+		var lines []*LineAvailability
+		if code := testutil.Serve(t, router, http.MethodPost, "/cart/availability", nil, payload, &lines); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if len(lines) != 3 {
+			t.Fatalf("expected 3 lines, got %d", len(lines))
+		}
+
+		if shirts := lines[0]; shirts.Availability != AvailabilityBackorder || shirts.InStock != 2 || shirts.Backordered != 2 {
+			t.Errorf("expected 2 shirts in stock and 2 backordered, got %+v", shirts)
+		}
+
+		if mugs := lines[1]; mugs.Availability != AvailabilityPreorder || mugs.ExpectedShipDate == nil || mugs.DepositPercent == nil || *mugs.DepositPercent != 20 {
+			t.Errorf("expected the mug preordered with a 20%% deposit, got %+v", mugs)
+		}
+
+		if lamps := lines[2]; lamps.Availability != AvailabilityUnavailable || lamps.InStock != 0 {
+			t.Errorf("expected the lamp unavailable, got %+v", lamps)
+		}
+	})
+
+	var first, second *Reservation
+	t.Run("should sell beyond the stock up to the limit", func(t *testing.T) {
+		var code int
+		if first, code = reserve(t, alice, shirt, 4); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		if line := first.Lines[0]; line.Backordered != 2 || line.Availability != AvailabilityBackorder {
+			t.Errorf("expected 2 shirts backordered, got %+v", line)
+		}
+
+		// 2 shirts are backordered already and the limit is 3
+		if _, code = reserve(t, bob, shirt, 2); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		if second, code = reserve(t, bob, shirt, 1); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		if _, code = reserve(t, bob, lamp, 1); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		if stock := inventoryStore.stock[shirt]; stock.reserved != 2 || stock.backordered != 3 {
+			t.Errorf("expected 2 shirts reserved and 3 backordered, got %d and %d", stock.reserved, stock.backordered)
+		}
+	})
+
+	t.Run("should wait for stock once committed", func(t *testing.T) {
+		for _, reservation := range []*Reservation{first, second} {
+			if committed := commit(t, reservation.ReservationID); committed.Fulfillment != FulfillmentStatusPartial {
+				t.Errorf("expected %s, got %s", FulfillmentStatusPartial, committed.Fulfillment)
+			}
+		}
+
+		if stock := inventoryStore.stock[shirt]; stock.quantity != 0 || stock.reserved != 0 || stock.backordered != 3 {
+			t.Errorf("expected no shirts on hand or reserved and 3 backordered, got %+v", stock)
+		}
+
+		var backorders ListBackordersResponse
+		if code := testutil.Serve(t, router, http.MethodGet, "/admin/inventory/backorders?status=pending", nil, nil, &backorders); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if backorders.TotalCount != 2 {
+			t.Errorf("expected 2 pending backorders, got %d", backorders.TotalCount)
+		}
+	})
+
+	t.Run("should allocate received stock to the oldest backorders first", func(t *testing.T) {
+		receive(t, shirt, 2)
+
+		reservation := new(Reservation)
+		path := "/checkout/reservations/" + first.ReservationID.String()
+		if code := testutil.Serve(t, router, http.MethodGet, path, &alice, nil, reservation); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if reservation.Fulfillment != FulfillmentStatusFulfilled || reservation.Lines[0].Allocated != 2 {
+			t.Errorf("expected the first order fulfilled, got %s with %d allocated", reservation.Fulfillment, reservation.Lines[0].Allocated)
+		}
+
+		path = "/checkout/reservations/" + second.ReservationID.String()
+		if code := testutil.Serve(t, router, http.MethodGet, path, &bob, nil, reservation); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if reservation.Fulfillment != FulfillmentStatusPartial {
+			t.Errorf("expected the second order still waiting, got %s", reservation.Fulfillment)
+		}
+
+		// stock owed to the second order is not for sale
+		receive(t, shirt, 2)
+		if stock := inventoryStore.stock[shirt]; stock.quantity != 1 || stock.backordered != 0 {
+			t.Errorf("expected 1 shirt on hand and none backordered, got %d and %d", stock.quantity, stock.backordered)
+		}
+
+		var backorders ListBackordersResponse
+		if code := testutil.Serve(t, router, http.MethodGet, "/admin/inventory/backorders?status=fulfilled", nil, nil, &backorders); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if backorders.TotalCount != 2 {
+			t.Errorf("expected 2 fulfilled backorders, got %d", backorders.TotalCount)
+		}
+
+		reconciliation, err := inventoryStore.reconcile(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(reconciliation.Drift) != 0 {
+			t.Errorf("expected no drift, got %d", len(reconciliation.Drift))
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+)
+
+// Requests
+
+// ReserveStockRequest holds stock for the lines of a checkout. Reserving for
+// a checkout again replaces its reservation, e.g. after the cart changed.
+type ReserveStockRequest struct {
+	CheckoutID uuid.UUID                `json:"checkoutId" validate:"required"`
+	Lines      []ReservationLineRequest `json:"lines" validate:"required,min=1,max=100,dive"`
+	// Strategy defaults to priority, the closest strategy needs the
+	// Destination the order ships to
+	Strategy    string              `json:"allocationStrategy" validate:"omitempty,oneof=closest whole_order priority"`
+	Destination *CoordinatesRequest `json:"destination" validate:"required_if=Strategy closest,omitempty"`
+}
+
+type ReservationLineRequest struct {
+	VariantID uuid.UUID `json:"variantId" validate:"required"`
+	Quantity  int64     `json:"quantity" validate:"min=1,max=10000"`
+}
+
+type CoordinatesRequest struct {
+	Latitude  *float64 `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+}
+
+type CreateLocationRequest struct {
+	Code     string              `json:"code" validate:"required,min=2,max=32,printascii,excludes= "`
+	Name     string              `json:"name" validate:"required,min=2,max=255"`
+	Kind     string              `json:"kind" validate:"required,oneof=warehouse store"`
+	Position *CoordinatesRequest `json:"position" validate:"required"`
+	Priority int                 `json:"priority" validate:"min=0,max=1000"`
+}
+
+// UpdateLocationRequest only changes the fields that are set.
+type UpdateLocationRequest struct {
+	Name     *string             `json:"name" validate:"omitempty,min=2,max=255"`
+	Kind     *string             `json:"kind" validate:"omitempty,oneof=warehouse store"`
+	Position *CoordinatesRequest `json:"position" validate:"omitempty"`
+	Priority *int                `json:"priority" validate:"omitempty,min=0,max=1000"`
+}
+
+// SetLocationStockRequest sets the quantities of variants at a location, the
+// variants not listed keep theirs. The changes are recorded as adjustments
+// unless Reason says otherwise, e.g. a receipt of goods counted in.
+type SetLocationStockRequest struct {
+	Lines     []LocationStockLineRequest `json:"lines" validate:"required,min=1,max=500,dive"`
+	Reason    string                     `json:"reason" validate:"omitempty,oneof=adjustment receipt return"`
+	Reference string                     `json:"reference" validate:"max=255"`
+	Note      string                     `json:"note" validate:"max=1000"`
+}
+
+type LocationStockLineRequest struct {
+	VariantID uuid.UUID `json:"variantId" validate:"required"`
+	Quantity  int64     `json:"quantity" validate:"min=0,max=1000000"`
+}
+
+// AllocateRequest previews the locations the lines of an order would ship
+// from.
+type AllocateRequest struct {
+	Lines       []ReservationLineRequest `json:"lines" validate:"required,min=1,max=100,dive"`
+	Strategy    string                   `json:"allocationStrategy" validate:"required,oneof=closest whole_order priority"`
+	Destination *CoordinatesRequest      `json:"destination" validate:"required_if=Strategy closest,omitempty"`
+}
+
+type CreateTransferRequest struct {
+	FromLocationID uuid.UUID                `json:"fromLocationId" validate:"required"`
+	ToLocationID   uuid.UUID                `json:"toLocationId" validate:"required"`
+	Lines          []ReservationLineRequest `json:"lines" validate:"required,min=1,max=500,dive"`
+	Note           string                   `json:"note" validate:"max=1000"`
+}
+
+type ListTransfersRequest struct {
+	Status   string `validate:"omitempty,oneof=pending completed cancelled"`
+	Page     int64  `validate:"min=1"`
+	PageSize int64  `validate:"min=1,max=100"`
+}
+
+// RecordMovementRequest adds Quantity, or takes it away when negative, from
+// the stock of a variant, at a location for variants kept at locations.
+type RecordMovementRequest struct {
+	VariantID  uuid.UUID  `json:"variantId" validate:"required"`
+	LocationID *uuid.UUID `json:"locationId"`
+	Quantity   int64      `json:"quantity" validate:"required,min=-1000000,max=1000000"`
+	Reason     string     `json:"reason" validate:"required,oneof=receipt return adjustment"`
+	Reference  string     `json:"reference" validate:"max=255"`
+	Note       string     `json:"note" validate:"max=1000"`
+}
+
+// ListMovementsRequest is read from the query string, all filters are
+// optional.
+type ListMovementsRequest struct {
+	VariantID  *uuid.UUID
+	LocationID *uuid.UUID
+	Reason     string `validate:"omitempty,oneof=sale return adjustment transfer receipt"`
+	From       *time.Time
+	To         *time.Time
+	Page       int64 `validate:"min=1"`
+	PageSize   int64 `validate:"min=1,max=100"`
+}
+
+// SetAvailabilityPolicyRequest lets a variant be sold beyond its stock.
+// Backorders need a Limit, preorders an ExpectedShipDate and may take a
+// deposit.
+type SetAvailabilityPolicyRequest struct {
+	Mode             string     `json:"mode" validate:"required,oneof=backorder preorder"`
+	Limit            *int64     `json:"limit" validate:"required_if=Mode backorder,omitempty,min=1,max=1000000"`
+	ExpectedShipDate *time.Time `json:"expectedShipDate" validate:"required_if=Mode preorder"`
+	DepositPercent   *int       `json:"depositPercent" validate:"excluded_if=Mode backorder,omitempty,min=1,max=100"`
+}
+
+// CartAvailabilityRequest asks how the lines of a cart would be sold.
+type CartAvailabilityRequest struct {
+	Lines []ReservationLineRequest `json:"lines" validate:"required,min=1,max=100,dive"`
+}
+
+// ListBackordersRequest is read from the query string, e.g.
+// ?status=pending&variantId=...
+type ListBackordersRequest struct {
+	Status    string `validate:"omitempty,oneof=pending fulfilled"`
+	VariantID *uuid.UUID
+	Page      int64 `validate:"min=1"`
+	PageSize  int64 `validate:"min=1,max=100"`
+}
+
+// Responses
+
+type ListTransfersResponse struct {
+	Transfers  []*Transfer `json:"transfers"`
+	Page       int64       `json:"page"`
+	PageSize   int64       `json:"pageSize"`
+	TotalCount int64       `json:"totalCount"`
+}
+
+type ListMovementsResponse struct {
+	Movements  []*stockledger.Movement `json:"movements"`
+	Page       int64                   `json:"page"`
+	PageSize   int64                   `json:"pageSize"`
+	TotalCount int64                   `json:"totalCount"`
+}
+
+type ListBackordersResponse struct {
+	Backorders []*Backorder `json:"backorders"`
+	Page       int64        `json:"page"`
+	PageSize   int64        `json:"pageSize"`
+	TotalCount int64        `json:"totalCount"`
+}
@@ -0,0 +1,266 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A reservation is pending from the start of a checkout until it is
+// committed on payment, released on cancellation or expired on timeout.
+const (
+	ReservationStatusPending   = "pending"
+	ReservationStatusCommitted = "committed"
+	ReservationStatusReleased  = "released"
+	ReservationStatusExpired   = "expired"
+)
+
+// Reservation holds stock for a checkout so it can not be sold to anyone
+// else while the customer pays.
+type Reservation struct {
+	ReservationID uuid.UUID          `json:"reservation_id"`
+	CheckoutID    uuid.UUID          `json:"checkout_id"`
+	UserID        *uuid.UUID         `json:"user_id"`
+	Status        string             `json:"status"`
+	Lines         []*ReservationLine `json:"lines"`
+	// Strategy and Destination decide the locations the reservation ships
+	// from, Allocations are those locations once it is committed
+	Strategy    string        `json:"allocation_strategy"`
+	Destination *Coordinates  `json:"destination"`
+	Allocations []*Allocation `json:"allocations"`
+	// Fulfillment tells whether a committed reservation still waits for
+	// stock of backordered or preordered lines
+	Fulfillment string    `json:"fulfillment"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// A committed reservation is partially fulfilled until the stock of its
+// backordered and preordered lines is allocated.
+const (
+	FulfillmentStatusFulfilled = "fulfilled"
+	FulfillmentStatusPartial   = "partially_fulfilled"
+)
+
+type ReservationLine struct {
+	VariantID uuid.UUID `json:"variant_id"`
+	SKU       string    `json:"sku"`
+	Quantity  int64     `json:"quantity"`
+	// Backordered is the part of Quantity sold beyond the stock on hand, on
+	// the terms of Availability, and Allocated the part of it allocated
+	// since
+	Backordered      int64      `json:"backordered"`
+	Allocated        int64      `json:"backorder_allocated"`
+	Availability     string     `json:"availability"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date"`
+	DepositPercent   *int       `json:"deposit_percent"`
+}
+
+// inStock is the part of a line held from the stock on hand.
+func (l *ReservationLine) inStock() int64 {
+	return l.Quantity - l.Backordered
+}
+
+// StockLevel is the stock of a variant, Available is what is on hand and
+// neither reserved by checkouts nor owed to backorders. Locations break
+// OnHand down by location for variants kept at locations.
+type StockLevel struct {
+	VariantID   uuid.UUID           `json:"variant_id"`
+	SKU         string              `json:"sku"`
+	OnHand      int64               `json:"on_hand"`
+	Reserved    int64               `json:"reserved"`
+	Backordered int64               `json:"backordered"`
+	Available   int64               `json:"available"`
+	Policy      *AvailabilityPolicy `json:"availability_policy"`
+	Locations   []*LocationStock    `json:"locations"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// A line is sold from the stock on hand, or beyond it as a backorder or
+// preorder when the policy of its variant allows, or not at all.
+const (
+	AvailabilityInStock     = "in_stock"
+	AvailabilityBackorder   = "backorder"
+	AvailabilityPreorder    = "preorder"
+	AvailabilityUnavailable = "unavailable"
+)
+
+// AvailabilityPolicy lets a variant be sold beyond its stock on hand, as a
+// backorder or as a preorder by Mode.
+type AvailabilityPolicy struct {
+	VariantID uuid.UUID `json:"variant_id"`
+	Mode      string    `json:"mode"`
+	// Limit is the most units sold beyond the stock at a time, nil for no
+	// limit
+	Limit            *int64     `json:"limit"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date"`
+	// DepositPercent is the part of the price paid up front for preorders
+	DepositPercent *int      `json:"deposit_percent"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// variantAvailability is the stock of a variant a line is held from.
+type variantAvailability struct {
+	variantID   uuid.UUID
+	sku         string
+	onHand      int64
+	reserved    int64
+	backordered int64
+	policy      *AvailabilityPolicy
+}
+
+// LineAvailability tells how a cart line would be sold, InStock units from
+// the stock on hand and Backordered ones beyond it.
+type LineAvailability struct {
+	VariantID        uuid.UUID  `json:"variant_id"`
+	SKU              string     `json:"sku"`
+	Quantity         int64      `json:"quantity"`
+	InStock          int64      `json:"in_stock"`
+	Backordered      int64      `json:"backordered"`
+	Availability     string     `json:"availability"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date"`
+	DepositPercent   *int       `json:"deposit_percent"`
+}
+
+// A backorder is pending until all its units are allocated.
+const (
+	BackorderStatusPending   = "pending"
+	BackorderStatusFulfilled = "fulfilled"
+)
+
+// Backorder is the part of a committed line sold beyond the stock, waiting
+// for stock to be received. Backorders are allocated in the order they were
+// placed.
+type Backorder struct {
+	ReservationID uuid.UUID  `json:"reservation_id"`
+	VariantID     uuid.UUID  `json:"variant_id"`
+	SKU           string     `json:"sku"`
+	Quantity      int64      `json:"quantity"`
+	Allocated     int64      `json:"allocated"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	FulfilledAt   *time.Time `json:"fulfilled_at"`
+	// available is the stock on hand not reserved by checkouts when the
+	// backorder was read
+	available int64
+}
+
+const (
+	LocationKindWarehouse = "warehouse"
+	LocationKindStore     = "store"
+)
+
+// Location is a warehouse or retail store stock is kept at and shipped from.
+type Location struct {
+	LocationID uuid.UUID `json:"location_id"`
+	Code       string    `json:"code"`
+	Name       string    `json:"name"`
+	Kind       string    `json:"kind"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	// Priority orders locations under the priority strategy, lowest first
+	Priority  int       `json:"priority"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LocationStock is the quantity of a variant at a location.
+type LocationStock struct {
+	LocationID   uuid.UUID `json:"location_id"`
+	LocationCode string    `json:"location_code"`
+	VariantID    uuid.UUID `json:"variant_id"`
+	SKU          string    `json:"sku"`
+	Quantity     int64     `json:"quantity"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Allocation strategies pick the locations order lines ship from. Closest
+// prefers the locations nearest the destination, whole order a single
+// location that has every line and priority the locations in priority order.
+const (
+	AllocationStrategyClosest    = "closest"
+	AllocationStrategyWholeOrder = "whole_order"
+	AllocationStrategyPriority   = "priority"
+)
+
+// Allocation is the part of an order line shipped from a location, a line
+// split over locations has one allocation per location.
+type Allocation struct {
+	VariantID    uuid.UUID `json:"variant_id"`
+	LocationID   uuid.UUID `json:"location_id"`
+	LocationCode string    `json:"location_code"`
+	Quantity     int64     `json:"quantity"`
+}
+
+// A transfer is pending until stock is received at the destination and it
+// is completed, or until it is cancelled.
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusCompleted = "completed"
+	TransferStatusCancelled = "cancelled"
+)
+
+// Transfer moves stock from one location to another.
+type Transfer struct {
+	TransferID     uuid.UUID       `json:"transfer_id"`
+	FromLocationID uuid.UUID       `json:"from_location_id"`
+	ToLocationID   uuid.UUID       `json:"to_location_id"`
+	Status         string          `json:"status"`
+	Note           string          `json:"note"`
+	Lines          []*TransferLine `json:"lines"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	CompletedAt    *time.Time      `json:"completed_at"`
+}
+
+type TransferLine struct {
+	VariantID uuid.UUID `json:"variant_id"`
+	SKU       string    `json:"sku"`
+	Quantity  int64     `json:"quantity"`
+}
+
+// Reconciliation compares the cached stock with the stock the ledger of
+// movements adds up to.
+type Reconciliation struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// Variants is how many variants were checked
+	Variants int64    `json:"variants"`
+	Drift    []*Drift `json:"drift"`
+}
+
+// Drift is stock whose cached quantity is not what its movements add up to,
+// at a location or, without LocationID, over all of them.
+type Drift struct {
+	VariantID    uuid.UUID  `json:"variant_id"`
+	SKU          string     `json:"sku"`
+	LocationID   *uuid.UUID `json:"location_id"`
+	LocationCode string     `json:"location_code"`
+	Cached       int64      `json:"cached"`
+	Ledger       int64      `json:"ledger"`
+	Difference   int64      `json:"difference"`
+}
+
+type movementFilter struct {
+	VariantID  *uuid.UUID
+	LocationID *uuid.UUID
+	Reason     string
+	From       *time.Time
+	To         *time.Time
+	Limit      int64
+	Offset     int64
+}
+
+type backorderFilter struct {
+	Status    string
+	VariantID *uuid.UUID
+	Limit     int64
+	Offset    int64
+}
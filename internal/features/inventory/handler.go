@@ -0,0 +1,484 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	Reserve(ctx context.Context, userID *uuid.UUID, payload *ReserveStockRequest) (*Reservation, error)
+	getReservation(ctx context.Context, reservationID uuid.UUID, userID *uuid.UUID) (*Reservation, error)
+	Release(ctx context.Context, reservationID uuid.UUID, userID *uuid.UUID) (*Reservation, error)
+	Commit(ctx context.Context, reservationID uuid.UUID) (*Reservation, error)
+	stockLevels(ctx context.Context, productID uuid.UUID) ([]*StockLevel, error)
+	createLocation(ctx context.Context, payload *CreateLocationRequest) (*Location, error)
+	listLocations(ctx context.Context) ([]*Location, error)
+	getLocation(ctx context.Context, locationID uuid.UUID) (*Location, error)
+	updateLocation(ctx context.Context, locationID uuid.UUID, payload *UpdateLocationRequest) (*Location, error)
+	getLocationStock(ctx context.Context, locationID uuid.UUID) ([]*LocationStock, error)
+	setLocationStock(ctx context.Context, locationID uuid.UUID, payload *SetLocationStockRequest) ([]*LocationStock, error)
+	previewAllocation(ctx context.Context, payload *AllocateRequest) ([]*Allocation, error)
+	createTransfer(ctx context.Context, payload *CreateTransferRequest) (*Transfer, error)
+	listTransfers(ctx context.Context, payload *ListTransfersRequest) (*ListTransfersResponse, error)
+	getTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error)
+	completeTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error)
+	cancelTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error)
+	recordMovement(ctx context.Context, payload *RecordMovementRequest) (*stockledger.Movement, error)
+	listMovements(ctx context.Context, payload *ListMovementsRequest) (*ListMovementsResponse, error)
+	Reconcile(ctx context.Context) (*Reconciliation, error)
+	setAvailabilityPolicy(ctx context.Context, variantID uuid.UUID, payload *SetAvailabilityPolicyRequest) (*AvailabilityPolicy, error)
+	deleteAvailabilityPolicy(ctx context.Context, variantID uuid.UUID) error
+	cartAvailability(ctx context.Context, payload *CartAvailabilityRequest) ([]*LineAvailability, error)
+	listBackorders(ctx context.Context, payload *ListBackordersRequest) (*ListBackordersResponse, error)
+}
+
+const defaultPageSize = 20
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	router.Post(
+		"/cart/availability",
+		handlerutils.MakeHandler(h.cartAvailabilityHandler),
+	)
+
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeUser))
+	authenticated.Post(
+		"/checkout/reservations",
+		handlerutils.MakeHandler(h.reserveStockHandler),
+	)
+	authenticated.Get(
+		"/checkout/reservations/{reservationID}",
+		handlerutils.MakeHandler(h.getReservationHandler),
+	)
+	authenticated.Delete(
+		"/checkout/reservations/{reservationID}",
+		handlerutils.MakeHandler(h.releaseReservationHandler(false)),
+	)
+}
+
+// RegisterAdminRoutes registers the inventory management routes relative to
+// the admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/products/{productID}/inventory",
+		handlerutils.MakeHandler(h.stockLevelsHandler),
+	)
+	authenticated.Post(
+		"/inventory/reservations/{reservationID}/commit",
+		handlerutils.MakeHandler(h.commitReservationHandler),
+	)
+	authenticated.Post(
+		"/inventory/reservations/{reservationID}/release",
+		handlerutils.MakeHandler(h.releaseReservationHandler(true)),
+	)
+	authenticated.Get(
+		"/inventory/locations",
+		handlerutils.MakeHandler(h.listLocationsHandler),
+	)
+	authenticated.Post(
+		"/inventory/locations",
+		handlerutils.MakeHandler(h.createLocationHandler),
+	)
+	authenticated.Get(
+		"/inventory/locations/{locationID}",
+		handlerutils.MakeHandler(h.getLocationHandler),
+	)
+	authenticated.Patch(
+		"/inventory/locations/{locationID}",
+		handlerutils.MakeHandler(h.updateLocationHandler),
+	)
+	authenticated.Get(
+		"/inventory/locations/{locationID}/stock",
+		handlerutils.MakeHandler(h.getLocationStockHandler),
+	)
+	authenticated.Put(
+		"/inventory/locations/{locationID}/stock",
+		handlerutils.MakeHandler(h.setLocationStockHandler),
+	)
+	authenticated.Post(
+		"/inventory/allocations",
+		handlerutils.MakeHandler(h.previewAllocationHandler),
+	)
+	authenticated.Get(
+		"/inventory/transfers",
+		handlerutils.MakeHandler(h.listTransfersHandler),
+	)
+	authenticated.Post(
+		"/inventory/transfers",
+		handlerutils.MakeHandler(h.createTransferHandler),
+	)
+	authenticated.Get(
+		"/inventory/transfers/{transferID}",
+		handlerutils.MakeHandler(h.getTransferHandler),
+	)
+	authenticated.Post(
+		"/inventory/transfers/{transferID}/complete",
+		handlerutils.MakeHandler(h.completeTransferHandler),
+	)
+	authenticated.Post(
+		"/inventory/transfers/{transferID}/cancel",
+		handlerutils.MakeHandler(h.cancelTransferHandler),
+	)
+	authenticated.Get(
+		"/inventory/movements",
+		handlerutils.MakeHandler(h.listMovementsHandler),
+	)
+	authenticated.Post(
+		"/inventory/movements",
+		handlerutils.MakeHandler(h.recordMovementHandler),
+	)
+	authenticated.Get(
+		"/inventory/reconciliation",
+		handlerutils.MakeHandler(h.reconciliationHandler),
+	)
+	authenticated.Put(
+		"/inventory/variants/{variantID}/availability",
+		handlerutils.MakeHandler(h.setAvailabilityPolicyHandler),
+	)
+	authenticated.Delete(
+		"/inventory/variants/{variantID}/availability",
+		handlerutils.MakeHandler(h.deleteAvailabilityPolicyHandler),
+	)
+	authenticated.Get(
+		"/inventory/backorders",
+		handlerutils.MakeHandler(h.listBackordersHandler),
+	)
+}
+
+func (h *handler) reserveStockHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var payload *ReserveStockRequest
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	reservation, err := h.service.Reserve(ctx, &userID, payload)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"stock reserved",
+		reservation,
+	)
+}
+
+func (h *handler) getReservationHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	reservationID, err := uuid.Parse(chi.URLParam(r, "reservationID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	reservation, err := h.service.getReservation(ctx, reservationID, &userID)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"reservation found",
+		reservation,
+	)
+}
+
+// releaseReservationHandler gives the stock of a reservation back, customers
+// release their own cancelled checkouts while admins may release any.
+func (h *handler) releaseReservationHandler(asAdmin bool) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		var owner *uuid.UUID
+		if !asAdmin {
+			userID, err := userIDFromContext(ctx)
+			if err != nil {
+				return err
+			}
+
+			owner = &userID
+		}
+
+		reservationID, err := uuid.Parse(chi.URLParam(r, "reservationID"))
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		reservation, err := h.service.Release(ctx, reservationID, owner)
+		if err != nil {
+			return inventoryError(err)
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"reservation released",
+			reservation,
+		)
+	}
+}
+
+// commitReservationHandler commits the reservation of a paid checkout until
+// payments commit their reservations themselves.
+func (h *handler) commitReservationHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	reservationID, err := uuid.Parse(chi.URLParam(r, "reservationID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	reservation, err := h.service.Commit(ctx, reservationID)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"reservation committed",
+		reservation,
+	)
+}
+
+func (h *handler) stockLevelsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	levels, err := h.service.stockLevels(ctx, productID)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"stock levels",
+		levels,
+	)
+}
+
+// userIDFromContext returns the id of the user authenticated by the auth
+// middleware.
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, servererrors.New(
+			http.StatusUnauthorized,
+			servererrors.ErrUnauthorized.Error(),
+			nil,
+		)
+	}
+
+	return uuid.Parse(claims.EntityID)
+}
+
+// inventoryError maps the errors of the inventory service to responses.
+func inventoryError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrProductNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrProductNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrReservationNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrReservationNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrVariantNotFound):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrVariantNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInsufficientStock):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrInsufficientStock.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrReservationCommitted):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrReservationCommitted.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrReservationExpired):
+		return servererrors.New(
+			http.StatusGone,
+			servererrors.ErrReservationExpired.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrStockBelowReserved):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrStockBelowReserved.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrLocationNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrLocationNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrLocationAlreadyExists):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrLocationAlreadyExists.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrTransferNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrTransferNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrTransferNotPending):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrTransferNotPending.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidTransfer):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidTransfer.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrBackorderLimitReached):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrBackorderLimitReached.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrReservationLimitReached):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrReservationLimitReached.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrReservedQuantityLimit):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrReservedQuantityLimit.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrAvailabilityNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrAvailabilityNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrStockManagedByLocations):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrStockManagedByLocations.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidRequestPayload):
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
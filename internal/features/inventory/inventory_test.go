@@ -0,0 +1,1088 @@
+package inventory
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func newTestRouter(t *testing.T) (*chi.Mux, *mockStore, *service) {
+	t.Helper()
+
+	inventoryStore := newMockInventoryStore()
+	inventoryService := NewService(inventoryStore)
+	inventoryHandler := NewHandler(inventoryService, nil)
+
+	router := chi.NewRouter()
+	authenticated := router.With(testutil.AuthenticateFromHeader)
+	authenticated.Post(
+		"/checkout/reservations",
+		handlerutils.MakeHandler(inventoryHandler.reserveStockHandler),
+	)
+	authenticated.Get(
+		"/checkout/reservations/{reservationID}",
+		handlerutils.MakeHandler(inventoryHandler.getReservationHandler),
+	)
+	authenticated.Delete(
+		"/checkout/reservations/{reservationID}",
+		handlerutils.MakeHandler(inventoryHandler.releaseReservationHandler(false)),
+	)
+	router.Get(
+		"/admin/products/{productID}/inventory",
+		handlerutils.MakeHandler(inventoryHandler.stockLevelsHandler),
+	)
+	router.Post(
+		"/admin/inventory/reservations/{reservationID}/commit",
+		handlerutils.MakeHandler(inventoryHandler.commitReservationHandler),
+	)
+	router.Post(
+		"/admin/inventory/reservations/{reservationID}/release",
+		handlerutils.MakeHandler(inventoryHandler.releaseReservationHandler(true)),
+	)
+	router.Post(
+		"/admin/inventory/locations",
+		handlerutils.MakeHandler(inventoryHandler.createLocationHandler),
+	)
+	router.Patch(
+		"/admin/inventory/locations/{locationID}",
+		handlerutils.MakeHandler(inventoryHandler.updateLocationHandler),
+	)
+	router.Put(
+		"/admin/inventory/locations/{locationID}/stock",
+		handlerutils.MakeHandler(inventoryHandler.setLocationStockHandler),
+	)
+	router.Post(
+		"/admin/inventory/allocations",
+		handlerutils.MakeHandler(inventoryHandler.previewAllocationHandler),
+	)
+	router.Post(
+		"/admin/inventory/transfers",
+		handlerutils.MakeHandler(inventoryHandler.createTransferHandler),
+	)
+	router.Post(
+		"/admin/inventory/transfers/{transferID}/complete",
+		handlerutils.MakeHandler(inventoryHandler.completeTransferHandler),
+	)
+	router.Post(
+		"/admin/inventory/transfers/{transferID}/cancel",
+		handlerutils.MakeHandler(inventoryHandler.cancelTransferHandler),
+	)
+	router.Get(
+		"/admin/inventory/movements",
+		handlerutils.MakeHandler(inventoryHandler.listMovementsHandler),
+	)
+	router.Post(
+		"/admin/inventory/movements",
+		handlerutils.MakeHandler(inventoryHandler.recordMovementHandler),
+	)
+	router.Get(
+		"/admin/inventory/reconciliation",
+		handlerutils.MakeHandler(inventoryHandler.reconciliationHandler),
+	)
+	router.Post(
+		"/cart/availability",
+		handlerutils.MakeHandler(inventoryHandler.cartAvailabilityHandler),
+	)
+	router.Put(
+		"/admin/inventory/variants/{variantID}/availability",
+		handlerutils.MakeHandler(inventoryHandler.setAvailabilityPolicyHandler),
+	)
+	router.Delete(
+		"/admin/inventory/variants/{variantID}/availability",
+		handlerutils.MakeHandler(inventoryHandler.deleteAvailabilityPolicyHandler),
+	)
+	router.Get(
+		"/admin/inventory/backorders",
+		handlerutils.MakeHandler(inventoryHandler.listBackordersHandler),
+	)
+
+	return router, inventoryStore, inventoryService
+}
+
+func TestReservations(t *testing.T) {
+	router, inventoryStore, inventoryService := newTestRouter(t)
+
+	productID := uuid.New()
+	shirt := inventoryStore.addVariant(productID, "SHIRT-M", 5)
+	mug := inventoryStore.addVariant(productID, "MUG", 2)
+	alice := uuid.New()
+	bob := uuid.New()
+
+	reserve := func(t *testing.T, userID uuid.UUID, payload ReserveStockRequest) (*Reservation, int) {
+		t.Helper()
+
+		reservation := new(Reservation)
+		code := testutil.Serve(t, router, http.MethodPost, "/checkout/reservations", &userID, payload, reservation)
+		return reservation, code
+	}
+
+	t.Run("should reject invalid reservations", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			payload ReserveStockRequest
+			status  int
+		}{
+			{"no checkout", ReserveStockRequest{Lines: []ReservationLineRequest{{VariantID: shirt, Quantity: 1}}}, http.StatusUnprocessableEntity},
+			{"no lines", ReserveStockRequest{CheckoutID: uuid.New()}, http.StatusUnprocessableEntity},
+			{"zero quantity", ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: shirt}}}, http.StatusUnprocessableEntity},
+			{"unknown variant", ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: uuid.New(), Quantity: 1}}}, http.StatusUnprocessableEntity},
+			{"more than in stock", ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: mug, Quantity: 3}}}, http.StatusConflict},
+		}
+
+		for _, c := range cases {
+			if _, code := reserve(t, alice, c.payload); code != c.status {
+				t.Errorf("%s: expected status code %d, got %d", c.name, c.status, code)
+			}
+		}
+
+		if shirtStock, mugStock := inventoryStore.stock[shirt], inventoryStore.stock[mug]; shirtStock.reserved != 0 || mugStock.reserved != 0 {
+			t.Errorf("expected nothing reserved, got %d shirts and %d mugs", shirtStock.reserved, mugStock.reserved)
+		}
+	})
+
+	t.Run("should hold stock so other checkouts can not take it", func(t *testing.T) {
+		checkoutID := uuid.New()
+		payload := ReserveStockRequest{CheckoutID: checkoutID, Lines: []ReservationLineRequest{{VariantID: mug, Quantity: 1}, {VariantID: mug, Quantity: 1}}}
+		reservation, code := reserve(t, alice, payload)
+		if code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		if reservation.Status != ReservationStatusPending || len(reservation.Lines) != 1 || reservation.Lines[0].Quantity != 2 {
+			t.Errorf("expected a pending reservation of 2 mugs, got %+v", reservation)
+		}
+
+		other := ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: mug, Quantity: 1}}}
+		if _, code = reserve(t, bob, other); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		// reserving the same cart again is a no-op
+		again, code := reserve(t, alice, payload)
+		if code != http.StatusCreated || again.ReservationID != reservation.ReservationID {
+			t.Errorf("expected reservation %s again, got %s with status code %d", reservation.ReservationID, again.ReservationID, code)
+		}
+
+		// a changed cart replaces the reservation
+		changed := ReserveStockRequest{CheckoutID: checkoutID, Lines: []ReservationLineRequest{{VariantID: mug, Quantity: 1}}}
+		replaced, code := reserve(t, alice, changed)
+		if code != http.StatusCreated || replaced.ReservationID == reservation.ReservationID {
+			t.Fatalf("expected a new reservation, got %s with status code %d", replaced.ReservationID, code)
+		}
+
+		if reserved := inventoryStore.stock[mug].reserved; reserved != 1 {
+			t.Errorf("expected 1 mug reserved, got %d", reserved)
+		}
+
+		var levels []*StockLevel
+		if code = testutil.Serve(t, router, http.MethodGet, "/admin/products/"+productID.String()+"/inventory", nil, nil, &levels); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		for _, level := range levels {
+			if level.SKU == "MUG" && (level.OnHand != 2 || level.Reserved != 1 || level.Available != 1) {
+				t.Errorf("expected 2 mugs on hand, 1 reserved and 1 available, got %+v", level)
+			}
+		}
+
+		path := "/checkout/reservations/" + replaced.ReservationID.String()
+		if code = testutil.Serve(t, router, http.MethodGet, path, &bob, nil, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+
+		if code = testutil.Serve(t, router, http.MethodDelete, path, &bob, nil, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+
+		var released Reservation
+		if code = testutil.Serve(t, router, http.MethodDelete, path, &alice, nil, &released); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if released.Status != ReservationStatusReleased || inventoryStore.stock[mug].reserved != 0 {
+			t.Errorf("expected the reservation released and no mug reserved, got %s and %d", released.Status, inventoryStore.stock[mug].reserved)
+		}
+
+		commitPath := "/admin/inventory/reservations/" + replaced.ReservationID.String() + "/commit"
+		if code = testutil.Serve(t, router, http.MethodPost, commitPath, nil, nil, nil); code != http.StatusGone {
+			t.Errorf("expected status code %d, got %d", http.StatusGone, code)
+		}
+	})
+
+	t.Run("should take committed stock off hand", func(t *testing.T) {
+		payload := ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: shirt, Quantity: 3}}}
+		reservation, code := reserve(t, bob, payload)
+		if code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		commitPath := "/admin/inventory/reservations/" + reservation.ReservationID.String() + "/commit"
+		for range 2 {
+			var committed Reservation
+			if code = testutil.Serve(t, router, http.MethodPost, commitPath, nil, nil, &committed); code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+			}
+
+			if committed.Status != ReservationStatusCommitted {
+				t.Errorf("expected status %s, got %s", ReservationStatusCommitted, committed.Status)
+			}
+		}
+
+		if stock := inventoryStore.stock[shirt]; stock.quantity != 2 || stock.reserved != 0 {
+			t.Errorf("expected 2 shirts on hand and none reserved, got %d and %d", stock.quantity, stock.reserved)
+		}
+
+		for userID, expected := range map[uuid.UUID]bool{bob: true, alice: false} {
+			purchased, err := inventoryService.HasPurchased(context.Background(), userID, productID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if purchased != expected {
+				t.Errorf("expected purchased to be %t, got %t", expected, purchased)
+			}
+		}
+
+		var baskets [][]uuid.UUID
+		err := inventoryService.Baskets(context.Background(), time.Now().Add(-time.Hour), func(productIDs []uuid.UUID) error {
+			baskets = append(baskets, productIDs)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(baskets) != 1 || len(baskets[0]) != 1 || baskets[0][0] != productID {
+			t.Errorf("expected one basket of the product, got %v", baskets)
+		}
+
+		path := "/checkout/reservations/" + reservation.ReservationID.String()
+		if code = testutil.Serve(t, router, http.MethodDelete, path, &bob, nil, nil); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+	})
+
+	t.Run("should limit what a user holds across checkouts", func(t *testing.T) {
+		poster := inventoryStore.addVariant(productID, "POSTER", 500)
+		carol := uuid.New()
+
+		for range maxPendingReservationsPerUser {
+			payload := ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: poster, Quantity: 10}}}
+			if _, code := reserve(t, carol, payload); code != http.StatusCreated {
+				t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+			}
+		}
+
+		one := ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: poster, Quantity: 1}}}
+		if _, code := reserve(t, carol, one); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		dave := uuid.New()
+		many := ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: poster, Quantity: maxReservedUnitsPerUser + 1}}}
+		if _, code := reserve(t, dave, many); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		if reserved := inventoryStore.stock[poster].reserved; reserved != 10*maxPendingReservationsPerUser {
+			t.Errorf("expected %d posters reserved, got %d", 10*maxPendingReservationsPerUser, reserved)
+		}
+
+		// replacing a checkout does not count it against the limits
+		replaced := ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: poster, Quantity: maxReservedUnitsPerUser}}}
+		if _, code := reserve(t, dave, replaced); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		replaced.Lines[0].Quantity = maxReservedUnitsPerUser - 1
+		if _, code := reserve(t, dave, replaced); code != http.StatusCreated {
+			t.Errorf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+	})
+
+	t.Run("should give back the stock of expired reservations", func(t *testing.T) {
+		payload := ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: shirt, Quantity: 2}}}
+		abandoned, code := reserve(t, alice, payload)
+		if code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		payload = ReserveStockRequest{CheckoutID: uuid.New(), Lines: []ReservationLineRequest{{VariantID: mug, Quantity: 2}}}
+		late, code := reserve(t, bob, payload)
+		if code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		inventoryStore.reservations[abandoned.ReservationID].ExpiresAt = time.Now().Add(-time.Minute)
+		inventoryStore.reservations[late.ReservationID].ExpiresAt = time.Now().Add(-time.Minute)
+
+		// paying after expiry fails even before the job runs
+		commitPath := "/admin/inventory/reservations/" + late.ReservationID.String() + "/commit"
+		if code = testutil.Serve(t, router, http.MethodPost, commitPath, nil, nil, nil); code != http.StatusGone {
+			t.Errorf("expected status code %d, got %d", http.StatusGone, code)
+		}
+
+		expired, err := inventoryService.ExpireReservations(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expired != 1 {
+			t.Errorf("expected 1 reservation expired, got %d", expired)
+		}
+
+		if shirts, mugs := inventoryStore.stock[shirt], inventoryStore.stock[mug]; shirts.reserved != 0 || mugs.reserved != 0 {
+			t.Errorf("expected nothing reserved, got %d shirts and %d mugs", shirts.reserved, mugs.reserved)
+		}
+
+		if status := inventoryStore.reservations[abandoned.ReservationID].Status; status != ReservationStatusExpired {
+			t.Errorf("expected status %s, got %s", ReservationStatusExpired, status)
+		}
+	})
+
+	t.Run("should return not found for unknown products and reservations", func(t *testing.T) {
+		if code := testutil.Serve(t, router, http.MethodGet, "/admin/products/"+uuid.New().String()+"/inventory", nil, nil, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/reservations/"+uuid.New().String()+"/release", nil, nil, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+	})
+}
+
+type mockStock struct {
+	productID   uuid.UUID
+	sku         string
+	quantity    int64
+	reserved    int64
+	backordered int64
+}
+
+type mockStore struct {
+	stock        map[uuid.UUID]*mockStock
+	reservations map[uuid.UUID]*Reservation
+	locations    map[uuid.UUID]*Location
+	// atLocation holds the quantity of each variant by location
+	atLocation map[uuid.UUID]map[uuid.UUID]int64
+	transfers  map[uuid.UUID]*Transfer
+	movements  []*stockledger.Movement
+	policies   map[uuid.UUID]*AvailabilityPolicy
+	// backorders are kept in the order they were placed
+	backorders []*Backorder
+}
+
+func newMockInventoryStore() *mockStore {
+	return &mockStore{
+		stock:        map[uuid.UUID]*mockStock{},
+		reservations: map[uuid.UUID]*Reservation{},
+		locations:    map[uuid.UUID]*Location{},
+		atLocation:   map[uuid.UUID]map[uuid.UUID]int64{},
+		transfers:    map[uuid.UUID]*Transfer{},
+		policies:     map[uuid.UUID]*AvailabilityPolicy{},
+	}
+}
+
+func (m *mockStore) addVariant(productID uuid.UUID, sku string, quantity int64) uuid.UUID {
+	variantID := uuid.New()
+	m.stock[variantID] = &mockStock{productID: productID, sku: sku, quantity: quantity}
+	m.record(&stockledger.Movement{VariantID: variantID, Quantity: quantity, Reason: stockledger.ReasonAdjustment})
+	return variantID
+}
+
+// record appends movements to the ledger as stockledger.Record does.
+func (m *mockStore) record(movements ...*stockledger.Movement) {
+	for _, movement := range movements {
+		if movement.Quantity == 0 {
+			continue
+		}
+
+		if movement.MovementID == uuid.Nil {
+			movement.MovementID = uuid.New()
+		}
+
+		movement.SKU = m.stock[movement.VariantID].sku
+		movement.ActorType = stockledger.ActorSystem
+		movement.CreatedAt = time.Now()
+		m.movements = append(m.movements, movement)
+	}
+}
+
+func (m *mockStore) createReservation(ctx context.Context, reservation *Reservation) (bool, error) {
+	for _, r := range m.reservations {
+		if r.CheckoutID == reservation.CheckoutID && r.Status == ReservationStatusPending {
+			return false, nil
+		}
+	}
+
+	if reservation.UserID != nil {
+		var pending, units int64
+		for _, r := range m.reservations {
+			if r.UserID == nil || *r.UserID != *reservation.UserID || r.Status != ReservationStatusPending || !r.ExpiresAt.After(time.Now()) {
+				continue
+			}
+
+			pending++
+			for _, line := range r.Lines {
+				units += line.Quantity
+			}
+		}
+
+		if err := checkUserLimits(pending, units, reservation.Lines); err != nil {
+			return false, err
+		}
+	}
+
+	for _, line := range reservation.Lines {
+		if _, ok := m.stock[line.VariantID]; !ok {
+			return false, servererrors.ErrVariantNotFound
+		}
+
+		if err := splitLine(line, m.availability(line.VariantID)); err != nil {
+			return false, err
+		}
+	}
+
+	saved := *reservation
+	saved.Lines = []*ReservationLine{}
+	for _, line := range reservation.Lines {
+		stock := m.stock[line.VariantID]
+		stock.reserved += line.inStock()
+		stock.backordered += line.Backordered
+
+		copied := *line
+		copied.SKU = stock.sku
+		saved.Lines = append(saved.Lines, &copied)
+	}
+
+	saved.CreatedAt = time.Now()
+	saved.UpdatedAt = saved.CreatedAt
+	m.reservations[saved.ReservationID] = &saved
+	return true, nil
+}
+
+func (m *mockStore) findReservation(ctx context.Context, reservationID uuid.UUID) (*Reservation, error) {
+	if reservation, ok := m.reservations[reservationID]; ok {
+		copied := *reservation
+		copied.Lines = []*ReservationLine{}
+		for _, line := range reservation.Lines {
+			copiedLine := *line
+			for _, backorder := range m.backorders {
+				if backorder.ReservationID == reservationID && backorder.VariantID == line.VariantID {
+					copiedLine.Allocated = backorder.Allocated
+				}
+			}
+
+			copied.Lines = append(copied.Lines, &copiedLine)
+		}
+
+		if copied.Status == ReservationStatusCommitted {
+			copied.Fulfillment = FulfillmentStatusFulfilled
+			for _, line := range copied.Lines {
+				if line.Allocated < line.Backordered {
+					copied.Fulfillment = FulfillmentStatusPartial
+				}
+			}
+		}
+
+		return &copied, nil
+	}
+
+	return new(Reservation), nil
+}
+
+func (m *mockStore) findPendingReservation(ctx context.Context, checkoutID uuid.UUID) (*Reservation, error) {
+	for _, reservation := range m.reservations {
+		if reservation.CheckoutID == checkoutID && reservation.Status == ReservationStatusPending {
+			copied := *reservation
+			return &copied, nil
+		}
+	}
+
+	return new(Reservation), nil
+}
+
+func (m *mockStore) closeReservation(ctx context.Context, reservationID uuid.UUID, status string, allocations []*Allocation) (bool, error) {
+	reservation, ok := m.reservations[reservationID]
+	if !ok || reservation.Status != ReservationStatusPending {
+		return false, nil
+	}
+
+	if status == ReservationStatusCommitted && !reservation.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+
+	for _, line := range reservation.Lines {
+		stock := m.stock[line.VariantID]
+		stock.reserved -= line.inStock()
+		if status != ReservationStatusCommitted {
+			stock.backordered -= line.Backordered
+			continue
+		}
+
+		stock.quantity -= line.inStock()
+		if line.Backordered > 0 {
+			m.backorders = append(m.backorders, &Backorder{
+				ReservationID: reservationID,
+				VariantID:     line.VariantID,
+				SKU:           stock.sku,
+				Quantity:      line.Backordered,
+				Status:        BackorderStatusPending,
+				CreatedAt:     time.Now(),
+			})
+		}
+	}
+
+	if status == ReservationStatusCommitted {
+		for _, allocation := range allocations {
+			if m.atLocation[allocation.LocationID][allocation.VariantID] < allocation.Quantity {
+				return false, servererrors.ErrInsufficientStock
+			}
+
+			m.atLocation[allocation.LocationID][allocation.VariantID] -= allocation.Quantity
+		}
+
+		reservation.Allocations = allocations
+		m.record(commitMovements(reservationID, inStockLines(reservation.Lines), allocations)...)
+	}
+
+	reservation.Status = status
+	reservation.UpdatedAt = time.Now()
+	return true, nil
+}
+
+func (m *mockStore) expiredReservations(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	reservationIDs := []uuid.UUID{}
+	for _, reservation := range m.reservations {
+		if reservation.Status == ReservationStatusPending && !reservation.ExpiresAt.After(time.Now()) && len(reservationIDs) < limit {
+			reservationIDs = append(reservationIDs, reservation.ReservationID)
+		}
+	}
+
+	return reservationIDs, nil
+}
+
+func (m *mockStore) productExists(ctx context.Context, productID uuid.UUID) (bool, error) {
+	for _, stock := range m.stock {
+		if stock.productID == productID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *mockStore) stockLevels(ctx context.Context, productID uuid.UUID) ([]*StockLevel, error) {
+	levels := []*StockLevel{}
+	for variantID, stock := range m.stock {
+		if stock.productID == productID {
+			levels = append(levels, &StockLevel{
+				VariantID:   variantID,
+				SKU:         stock.sku,
+				OnHand:      stock.quantity,
+				Reserved:    stock.reserved,
+				Backordered: stock.backordered,
+				Available:   max(stock.quantity-stock.reserved-stock.backordered, 0),
+				Policy:      m.policies[variantID],
+			})
+		}
+	}
+
+	return levels, nil
+}
+
+func (m *mockStore) createLocation(ctx context.Context, location *Location) error {
+	for _, l := range m.locations {
+		if l.Code == location.Code {
+			return servererrors.ErrLocationAlreadyExists
+		}
+	}
+
+	saved := *location
+	m.locations[saved.LocationID] = &saved
+	return nil
+}
+
+func (m *mockStore) findLocation(ctx context.Context, locationID uuid.UUID) (*Location, error) {
+	if location, ok := m.locations[locationID]; ok {
+		copied := *location
+		return &copied, nil
+	}
+
+	return new(Location), nil
+}
+
+func (m *mockStore) listLocations(ctx context.Context) ([]*Location, error) {
+	locations := []*Location{}
+	for _, location := range m.locations {
+		copied := *location
+		locations = append(locations, &copied)
+	}
+
+	return locations, nil
+}
+
+func (m *mockStore) updateLocation(ctx context.Context, location *Location) error {
+	saved := *location
+	m.locations[saved.LocationID] = &saved
+	return nil
+}
+
+func (m *mockStore) locationStock(ctx context.Context, locationID uuid.UUID) ([]*LocationStock, error) {
+	stock := []*LocationStock{}
+	for variantID, quantity := range m.atLocation[locationID] {
+		stock = append(stock, &LocationStock{
+			LocationID:   locationID,
+			LocationCode: m.locations[locationID].Code,
+			VariantID:    variantID,
+			SKU:          m.stock[variantID].sku,
+			Quantity:     quantity,
+		})
+	}
+
+	return stock, nil
+}
+
+func (m *mockStore) setLocationStock(ctx context.Context, locationID uuid.UUID, lines []*LocationStock, change *stockledger.Movement) error {
+	for _, line := range lines {
+		stock, ok := m.stock[line.VariantID]
+		if !ok {
+			return servererrors.ErrVariantNotFound
+		}
+
+		total := line.Quantity
+		for otherID, quantities := range m.atLocation {
+			if otherID != locationID {
+				total += quantities[line.VariantID]
+			}
+		}
+
+		if total < stock.reserved {
+			return servererrors.ErrStockBelowReserved
+		}
+	}
+
+	for _, line := range lines {
+		if m.atLocation[locationID] == nil {
+			m.atLocation[locationID] = map[uuid.UUID]int64{}
+		}
+
+		before, previousQuantity := m.stock[line.VariantID].quantity, m.atLocation[locationID][line.VariantID]
+		m.atLocation[locationID][line.VariantID] = line.Quantity
+		m.syncStock(line.VariantID)
+		m.recordAtLocation(&stockledger.Movement{
+			VariantID:  line.VariantID,
+			LocationID: &locationID,
+			Quantity:   line.Quantity - previousQuantity,
+			Reason:     change.Reason,
+			Reference:  change.Reference,
+			Note:       change.Note,
+		}, before)
+	}
+
+	return nil
+}
+
+// recordAtLocation records a movement at a location and the stock moved to
+// locations as the store does.
+func (m *mockStore) recordAtLocation(movement *stockledger.Movement, before int64) {
+	m.record(movement)
+	if moved := m.stock[movement.VariantID].quantity - before - movement.Quantity; moved != 0 {
+		m.record(&stockledger.Movement{VariantID: movement.VariantID, Quantity: moved, Reason: stockledger.ReasonAdjustment})
+	}
+}
+
+// syncStock sets the stock of a variant to its quantity over all locations
+// as the store does.
+func (m *mockStore) syncStock(variantID uuid.UUID) {
+	var total int64
+	for _, quantities := range m.atLocation {
+		total += quantities[variantID]
+	}
+
+	m.stock[variantID].quantity = total
+}
+
+func (m *mockStore) stockAtLocations(ctx context.Context, variantIDs []uuid.UUID) ([]*stockAt, error) {
+	stock := []*stockAt{}
+	for locationID, quantities := range m.atLocation {
+		at := &stockAt{location: m.locations[locationID], quantities: map[uuid.UUID]int64{}}
+		for _, variantID := range variantIDs {
+			if quantity, ok := quantities[variantID]; ok {
+				at.quantities[variantID] = quantity
+			}
+		}
+
+		if len(at.quantities) > 0 {
+			stock = append(stock, at)
+		}
+	}
+
+	return stock, nil
+}
+
+func (m *mockStore) createTransfer(ctx context.Context, transfer *Transfer) error {
+	for _, line := range transfer.Lines {
+		if _, ok := m.stock[line.VariantID]; !ok {
+			return servererrors.ErrVariantNotFound
+		}
+	}
+
+	saved := *transfer
+	saved.CreatedAt = time.Now()
+	saved.UpdatedAt = saved.CreatedAt
+	m.transfers[saved.TransferID] = &saved
+	return nil
+}
+
+func (m *mockStore) findTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error) {
+	if transfer, ok := m.transfers[transferID]; ok {
+		copied := *transfer
+		return &copied, nil
+	}
+
+	return new(Transfer), nil
+}
+
+func (m *mockStore) listTransfers(ctx context.Context, status string, limit int64, offset int64) ([]*Transfer, int64, error) {
+	transfers := []*Transfer{}
+	for _, transfer := range m.transfers {
+		if status == "" || transfer.Status == status {
+			transfers = append(transfers, transfer)
+		}
+	}
+
+	return transfers, int64(len(transfers)), nil
+}
+
+func (m *mockStore) completeTransfer(ctx context.Context, transfer *Transfer) (bool, error) {
+	saved := m.transfers[transfer.TransferID]
+	if saved.Status != TransferStatusPending {
+		return false, nil
+	}
+
+	for _, line := range saved.Lines {
+		if m.atLocation[saved.FromLocationID][line.VariantID] < line.Quantity {
+			return false, servererrors.ErrInsufficientStock
+		}
+	}
+
+	if m.atLocation[saved.ToLocationID] == nil {
+		m.atLocation[saved.ToLocationID] = map[uuid.UUID]int64{}
+	}
+
+	for _, line := range saved.Lines {
+		m.atLocation[saved.FromLocationID][line.VariantID] -= line.Quantity
+		m.atLocation[saved.ToLocationID][line.VariantID] += line.Quantity
+		m.record(
+			&stockledger.Movement{VariantID: line.VariantID, LocationID: &saved.FromLocationID, Quantity: -line.Quantity, Reason: stockledger.ReasonTransfer},
+			&stockledger.Movement{VariantID: line.VariantID, LocationID: &saved.ToLocationID, Quantity: line.Quantity, Reason: stockledger.ReasonTransfer},
+		)
+	}
+
+	now := time.Now()
+	saved.Status = TransferStatusCompleted
+	saved.CompletedAt = &now
+	return true, nil
+}
+
+func (m *mockStore) cancelTransfer(ctx context.Context, transferID uuid.UUID) (bool, error) {
+	saved := m.transfers[transferID]
+	if saved.Status != TransferStatusPending {
+		return false, nil
+	}
+
+	saved.Status = TransferStatusCancelled
+	return true, nil
+}
+
+func (m *mockStore) recordMovement(ctx context.Context, movement *stockledger.Movement) error {
+	stock, ok := m.stock[movement.VariantID]
+	if !ok {
+		return servererrors.ErrVariantNotFound
+	}
+
+	before := stock.quantity
+	if movement.LocationID != nil {
+		if m.atLocation[*movement.LocationID][movement.VariantID]+movement.Quantity < 0 {
+			return servererrors.ErrInsufficientStock
+		}
+
+		total := movement.Quantity
+		for _, quantities := range m.atLocation {
+			total += quantities[movement.VariantID]
+		}
+
+		if total < stock.reserved {
+			return servererrors.ErrStockBelowReserved
+		}
+
+		if m.atLocation[*movement.LocationID] == nil {
+			m.atLocation[*movement.LocationID] = map[uuid.UUID]int64{}
+		}
+
+		m.atLocation[*movement.LocationID][movement.VariantID] += movement.Quantity
+		m.syncStock(movement.VariantID)
+		m.recordAtLocation(movement, before)
+		return nil
+	}
+
+	for _, quantities := range m.atLocation {
+		if _, ok := quantities[movement.VariantID]; ok {
+			return servererrors.ErrStockManagedByLocations
+		}
+	}
+
+	if stock.quantity+movement.Quantity < 0 {
+		return servererrors.ErrInsufficientStock
+	}
+
+	if stock.quantity+movement.Quantity < stock.reserved {
+		return servererrors.ErrStockBelowReserved
+	}
+
+	stock.quantity += movement.Quantity
+	m.record(movement)
+	return nil
+}
+
+func (m *mockStore) listMovements(ctx context.Context, filter *movementFilter) ([]*stockledger.Movement, int64, error) {
+	movements := []*stockledger.Movement{}
+	for i := len(m.movements) - 1; i >= 0; i-- {
+		movement := m.movements[i]
+		if filter.VariantID != nil && movement.VariantID != *filter.VariantID {
+			continue
+		}
+
+		if filter.LocationID != nil && (movement.LocationID == nil || *movement.LocationID != *filter.LocationID) {
+			continue
+		}
+
+		if filter.Reason != "" && movement.Reason != filter.Reason {
+			continue
+		}
+
+		movements = append(movements, movement)
+	}
+
+	return movements, int64(len(movements)), nil
+}
+
+// reconcile compares the cached stock with the sums of the movements as the
+// store does.
+func (m *mockStore) reconcile(ctx context.Context) (*Reconciliation, error) {
+	byVariant := map[uuid.UUID]int64{}
+	byLocation := map[uuid.UUID]map[uuid.UUID]int64{}
+	for _, movement := range m.movements {
+		byVariant[movement.VariantID] += movement.Quantity
+		if movement.LocationID != nil {
+			if byLocation[*movement.LocationID] == nil {
+				byLocation[*movement.LocationID] = map[uuid.UUID]int64{}
+			}
+
+			byLocation[*movement.LocationID][movement.VariantID] += movement.Quantity
+		}
+	}
+
+	reconciliation := &Reconciliation{CheckedAt: time.Now(), Variants: int64(len(m.stock)), Drift: []*Drift{}}
+	for variantID, stock := range m.stock {
+		if ledger := byVariant[variantID]; ledger != stock.quantity {
+			reconciliation.Drift = append(reconciliation.Drift, &Drift{VariantID: variantID, SKU: stock.sku, Cached: stock.quantity, Ledger: ledger, Difference: stock.quantity - ledger})
+		}
+	}
+
+	for locationID, quantities := range m.atLocation {
+		for variantID, cached := range quantities {
+			if ledger := byLocation[locationID][variantID]; ledger != cached {
+				reconciliation.Drift = append(reconciliation.Drift, &Drift{
+					VariantID:    variantID,
+					SKU:          m.stock[variantID].sku,
+					LocationID:   &locationID,
+					LocationCode: m.locations[locationID].Code,
+					Cached:       cached,
+					Ledger:       ledger,
+					Difference:   cached - ledger,
+				})
+			}
+		}
+	}
+
+	return reconciliation, nil
+}
+
+func (m *mockStore) setAvailabilityPolicy(ctx context.Context, policy *AvailabilityPolicy) error {
+	if _, ok := m.stock[policy.VariantID]; !ok {
+		return servererrors.ErrVariantNotFound
+	}
+
+	saved := *policy
+	saved.CreatedAt = time.Now()
+	saved.UpdatedAt = saved.CreatedAt
+	m.policies[saved.VariantID] = &saved
+	return nil
+}
+
+func (m *mockStore) findAvailabilityPolicy(ctx context.Context, variantID uuid.UUID) (*AvailabilityPolicy, error) {
+	if policy, ok := m.policies[variantID]; ok {
+		copied := *policy
+		return &copied, nil
+	}
+
+	return new(AvailabilityPolicy), nil
+}
+
+func (m *mockStore) deleteAvailabilityPolicy(ctx context.Context, variantID uuid.UUID) (bool, error) {
+	if _, ok := m.policies[variantID]; !ok {
+		return false, nil
+	}
+
+	delete(m.policies, variantID)
+	return true, nil
+}
+
+// availability returns the stock a line of a variant is held from.
+func (m *mockStore) availability(variantID uuid.UUID) *variantAvailability {
+	stock := m.stock[variantID]
+	return &variantAvailability{
+		variantID:   variantID,
+		sku:         stock.sku,
+		onHand:      stock.quantity,
+		reserved:    stock.reserved,
+		backordered: stock.backordered,
+		policy:      m.policies[variantID],
+	}
+}
+
+func (m *mockStore) variantAvailability(ctx context.Context, variantIDs []uuid.UUID) ([]*variantAvailability, error) {
+	availabilities := []*variantAvailability{}
+	for _, variantID := range variantIDs {
+		if _, ok := m.stock[variantID]; ok {
+			availabilities = append(availabilities, m.availability(variantID))
+		}
+	}
+
+	return availabilities, nil
+}
+
+func (m *mockStore) pendingBackorders(ctx context.Context, limit int) ([]*Backorder, error) {
+	backorders := []*Backorder{}
+	for _, backorder := range m.backorders {
+		stock := m.stock[backorder.VariantID]
+		if backorder.Status != BackorderStatusPending || stock.quantity <= stock.reserved || len(backorders) == limit {
+			continue
+		}
+
+		copied := *backorder
+		copied.available = stock.quantity - stock.reserved
+		backorders = append(backorders, &copied)
+	}
+
+	return backorders, nil
+}
+
+func (m *mockStore) allocateBackorder(ctx context.Context, backorder *Backorder, quantity int64, allocations []*Allocation) (bool, error) {
+	var saved *Backorder
+	for _, b := range m.backorders {
+		if b.ReservationID == backorder.ReservationID && b.VariantID == backorder.VariantID {
+			saved = b
+		}
+	}
+
+	if saved == nil || saved.Status != BackorderStatusPending || saved.Allocated+quantity > saved.Quantity {
+		return false, nil
+	}
+
+	stock := m.stock[backorder.VariantID]
+	if stock.quantity-stock.reserved < quantity {
+		return false, servererrors.ErrInsufficientStock
+	}
+
+	for _, allocation := range allocations {
+		if m.atLocation[allocation.LocationID][allocation.VariantID] < allocation.Quantity {
+			return false, servererrors.ErrInsufficientStock
+		}
+	}
+
+	reservation := m.reservations[backorder.ReservationID]
+	for _, allocation := range allocations {
+		m.atLocation[allocation.LocationID][allocation.VariantID] -= allocation.Quantity
+		reservation.Allocations = append(reservation.Allocations, allocation)
+	}
+
+	stock.quantity -= quantity
+	stock.backordered -= quantity
+	saved.Allocated += quantity
+	saved.UpdatedAt = time.Now()
+	if saved.Allocated == saved.Quantity {
+		saved.Status = BackorderStatusFulfilled
+		saved.FulfilledAt = &saved.UpdatedAt
+	}
+
+	lines := []*ReservationLine{{VariantID: backorder.VariantID, Quantity: quantity}}
+	m.record(commitMovements(backorder.ReservationID, lines, allocations)...)
+	return true, nil
+}
+
+func (m *mockStore) listBackorders(ctx context.Context, filter *backorderFilter) ([]*Backorder, int64, error) {
+	backorders := []*Backorder{}
+	for _, backorder := range m.backorders {
+		if filter.Status != "" && backorder.Status != filter.Status {
+			continue
+		}
+
+		if filter.VariantID != nil && backorder.VariantID != *filter.VariantID {
+			continue
+		}
+
+		copied := *backorder
+		backorders = append(backorders, &copied)
+	}
+
+	return backorders, int64(len(backorders)), nil
+}
+
+func (m *mockStore) hasCommittedProduct(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (bool, error) {
+	for _, reservation := range m.reservations {
+		if reservation.Status != ReservationStatusCommitted || reservation.UserID == nil || *reservation.UserID != userID {
+			continue
+		}
+
+		for _, line := range reservation.Lines {
+			if m.stock[line.VariantID].productID == productID {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (m *mockStore) committedBaskets(ctx context.Context, since time.Time, fn func(productIDs []uuid.UUID) error) error {
+	for _, reservation := range m.reservations {
+		if reservation.Status != ReservationStatusCommitted || reservation.UpdatedAt.Before(since) {
+			continue
+		}
+
+		productIDs := []uuid.UUID{}
+		for _, line := range reservation.Lines {
+			if productID := m.stock[line.VariantID].productID; !slices.Contains(productIDs, productID) {
+				productIDs = append(productIDs, productID)
+			}
+		}
+
+		if err := fn(productIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package inventory
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+)
+
+func (s *service) createLocation(ctx context.Context, payload *CreateLocationRequest) (*Location, error) {
+	location := &Location{
+		LocationID: uuid.New(),
+		Code:       strings.ToUpper(strings.TrimSpace(payload.Code)),
+		Name:       strings.TrimSpace(payload.Name),
+		Kind:       payload.Kind,
+		Latitude:   *payload.Position.Latitude,
+		Longitude:  *payload.Position.Longitude,
+		Priority:   payload.Priority,
+	}
+
+	if err := s.inventoryStore.createLocation(ctx, location); err != nil {
+		return nil, err
+	}
+
+	return s.inventoryStore.findLocation(ctx, location.LocationID)
+}
+
+func (s *service) listLocations(ctx context.Context) ([]*Location, error) {
+	return s.inventoryStore.listLocations(ctx)
+}
+
+func (s *service) getLocation(ctx context.Context, locationID uuid.UUID) (*Location, error) {
+	location, err := s.inventoryStore.findLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if location.LocationID == uuid.Nil {
+		return nil, servererrors.ErrLocationNotFound
+	}
+
+	return location, nil
+}
+
+func (s *service) updateLocation(ctx context.Context, locationID uuid.UUID, payload *UpdateLocationRequest) (*Location, error) {
+	location, err := s.getLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Name != nil {
+		location.Name = strings.TrimSpace(*payload.Name)
+	}
+
+	if payload.Kind != nil {
+		location.Kind = *payload.Kind
+	}
+
+	if payload.Position != nil {
+		location.Latitude = *payload.Position.Latitude
+		location.Longitude = *payload.Position.Longitude
+	}
+
+	if payload.Priority != nil {
+		location.Priority = *payload.Priority
+	}
+
+	if err = s.inventoryStore.updateLocation(ctx, location); err != nil {
+		return nil, err
+	}
+
+	return s.inventoryStore.findLocation(ctx, locationID)
+}
+
+func (s *service) getLocationStock(ctx context.Context, locationID uuid.UUID) ([]*LocationStock, error) {
+	if _, err := s.getLocation(ctx, locationID); err != nil {
+		return nil, err
+	}
+
+	return s.inventoryStore.locationStock(ctx, locationID)
+}
+
+// setLocationStock sets the quantities of variants at a location. From then
+// on the stock of those variants is their quantity over all locations.
+func (s *service) setLocationStock(ctx context.Context, locationID uuid.UUID, payload *SetLocationStockRequest) ([]*LocationStock, error) {
+	if _, err := s.getLocation(ctx, locationID); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]bool, len(payload.Lines))
+	lines := make([]*LocationStock, 0, len(payload.Lines))
+	for _, line := range payload.Lines {
+		if seen[line.VariantID] {
+			return nil, servererrors.ErrInvalidRequestPayload
+		}
+
+		seen[line.VariantID] = true
+		lines = append(lines, &LocationStock{LocationID: locationID, VariantID: line.VariantID, Quantity: line.Quantity})
+	}
+
+	change := &stockledger.Movement{
+		Reason:    payload.Reason,
+		Reference: strings.TrimSpace(payload.Reference),
+		Note:      strings.TrimSpace(payload.Note),
+	}
+	if change.Reason == "" {
+		change.Reason = stockledger.ReasonAdjustment
+	}
+
+	if err := s.inventoryStore.setLocationStock(ctx, locationID, lines, change); err != nil {
+		return nil, err
+	}
+
+	// stock counted in goes to the backorders waiting for it first
+	if _, err := s.AllocateBackorders(ctx); err != nil {
+		log.Println(err)
+	}
+
+	return s.inventoryStore.locationStock(ctx, locationID)
+}
+
+// Allocate picks the locations the lines of an order ship from under
+// strategy, splitting lines over locations when need be. Lines of variants
+// not kept at any location get no allocation.
+func (s *service) Allocate(ctx context.Context, lines []*ReservationLine, strategy string, destination *Coordinates) ([]*Allocation, error) {
+	variantIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+
+	stock, err := s.inventoryStore.stockAtLocations(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return allocate(lines, stock, strategy, destination)
+}
+
+// previewAllocation shows the locations an order would ship from without
+// taking any stock.
+func (s *service) previewAllocation(ctx context.Context, payload *AllocateRequest) ([]*Allocation, error) {
+	return s.Allocate(ctx, mergeLines(payload.Lines), payload.Strategy, newCoordinates(payload.Destination))
+}
+
+// createTransfer records a transfer of stock between two locations, the
+// stock moves when the transfer is completed.
+func (s *service) createTransfer(ctx context.Context, payload *CreateTransferRequest) (*Transfer, error) {
+	if payload.FromLocationID == payload.ToLocationID {
+		return nil, servererrors.ErrInvalidTransfer
+	}
+
+	for _, locationID := range []uuid.UUID{payload.FromLocationID, payload.ToLocationID} {
+		if _, err := s.getLocation(ctx, locationID); err != nil {
+			return nil, err
+		}
+	}
+
+	transfer := &Transfer{
+		TransferID:     uuid.New(),
+		FromLocationID: payload.FromLocationID,
+		ToLocationID:   payload.ToLocationID,
+		Status:         TransferStatusPending,
+		Note:           strings.TrimSpace(payload.Note),
+	}
+	for _, line := range mergeLines(payload.Lines) {
+		transfer.Lines = append(transfer.Lines, &TransferLine{VariantID: line.VariantID, Quantity: line.Quantity})
+	}
+
+	if err := s.inventoryStore.createTransfer(ctx, transfer); err != nil {
+		return nil, err
+	}
+
+	return s.inventoryStore.findTransfer(ctx, transfer.TransferID)
+}
+
+func (s *service) listTransfers(ctx context.Context, payload *ListTransfersRequest) (*ListTransfersResponse, error) {
+	transfers, totalCount, err := s.inventoryStore.listTransfers(
+		ctx,
+		payload.Status,
+		payload.PageSize,
+		(payload.Page-1)*payload.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListTransfersResponse{
+		Transfers:  transfers,
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
+
+func (s *service) getTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error) {
+	transfer, err := s.inventoryStore.findTransfer(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	if transfer.TransferID == uuid.Nil {
+		return nil, servererrors.ErrTransferNotFound
+	}
+
+	return transfer, nil
+}
+
+// completeTransfer moves the stock of a pending transfer once it arrived.
+func (s *service) completeTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error) {
+	transfer, err := s.getTransfer(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	completed, err := s.inventoryStore.completeTransfer(ctx, transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	if !completed {
+		return nil, servererrors.ErrTransferNotPending
+	}
+
+	return s.inventoryStore.findTransfer(ctx, transferID)
+}
+
+func (s *service) cancelTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error) {
+	if _, err := s.getTransfer(ctx, transferID); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.inventoryStore.cancelTransfer(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cancelled {
+		return nil, servererrors.ErrTransferNotPending
+	}
+
+	return s.inventoryStore.findTransfer(ctx, transferID)
+}
+
+func newCoordinates(coordinates *CoordinatesRequest) *Coordinates {
+	if coordinates == nil {
+		return nil
+	}
+
+	return &Coordinates{Latitude: *coordinates.Latitude, Longitude: *coordinates.Longitude}
+}
@@ -0,0 +1,700 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	locationFields      = "location_id, code, name, kind, latitude, longitude, priority, created_at, updated_at"
+	locationStockFields = "ls.location_id, l.code, ls.variant_id, v.sku, ls.quantity, ls.updated_at"
+	locationStockJoins  = "location_stock ls JOIN stock_locations l ON l.location_id = ls.location_id JOIN product_variants v ON v.variant_id = ls.variant_id"
+	transferFields      = "transfer_id, from_location_id, to_location_id, status, note, created_at, updated_at, completed_at"
+)
+
+func (s *store) createLocation(ctx context.Context, location *Location) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO stock_locations(location_id, code, name, kind, latitude, longitude, priority) VALUES($1, $2, $3, $4, $5, $6, $7)",
+		location.LocationID,
+		location.Code,
+		location.Name,
+		location.Kind,
+		location.Latitude,
+		location.Longitude,
+		location.Priority,
+	)
+	if err != nil {
+		if isPQError(err, uniqueViolation) {
+			return servererrors.ErrLocationAlreadyExists
+		}
+
+		return fmt.Errorf(
+			"failed to insert location in inventory store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// findLocation returns a location, a zero Location when there is none.
+func (s *store) findLocation(ctx context.Context, locationID uuid.UUID) (*Location, error) {
+	location := new(Location)
+	err := s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM stock_locations WHERE location_id = $1", locationFields),
+		locationID,
+	).Scan(
+		&location.LocationID,
+		&location.Code,
+		&location.Name,
+		&location.Kind,
+		&location.Latitude,
+		&location.Longitude,
+		&location.Priority,
+		&location.CreatedAt,
+		&location.UpdatedAt,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"failed to find location in inventory store: %w",
+			err,
+		)
+	}
+
+	return location, nil
+}
+
+// listLocations returns every location in priority order.
+func (s *store) listLocations(ctx context.Context) ([]*Location, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM stock_locations ORDER BY priority ASC, code ASC", locationFields),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to list locations in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	locations := []*Location{}
+	for rows.Next() {
+		location := new(Location)
+		err = rows.Scan(
+			&location.LocationID,
+			&location.Code,
+			&location.Name,
+			&location.Kind,
+			&location.Latitude,
+			&location.Longitude,
+			&location.Priority,
+			&location.CreatedAt,
+			&location.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into location in inventory store: %w",
+				err,
+			)
+		}
+
+		locations = append(locations, location)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return locations, nil
+}
+
+func (s *store) updateLocation(ctx context.Context, location *Location) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE stock_locations SET name = $1, kind = $2, latitude = $3, longitude = $4, priority = $5, updated_at = NOW() WHERE location_id = $6",
+		location.Name,
+		location.Kind,
+		location.Latitude,
+		location.Longitude,
+		location.Priority,
+		location.LocationID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update location in inventory store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// locationStock returns the stock kept at a location by sku.
+func (s *store) locationStock(ctx context.Context, locationID uuid.UUID) ([]*LocationStock, error) {
+	return s.getLocationStockWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE ls.location_id = $1 ORDER BY v.sku ASC", locationStockFields, locationStockJoins),
+		locationID,
+	)
+}
+
+// setLocationStock sets the quantities of variants at a location and the
+// stock of each variant to its quantity over all locations, recording the
+// changes with the reason, reference and note of change. It returns
+// servererrors.ErrVariantNotFound for unknown variants and
+// servererrors.ErrStockBelowReserved when a variant would have less stock
+// than checkouts reserved.
+func (s *store) setLocationStock(ctx context.Context, locationID uuid.UUID, lines []*LocationStock, change *stockledger.Movement) error {
+	sorted := slices.Clone(lines)
+	slices.SortFunc(sorted, func(a, b *LocationStock) int {
+		return slices.Compare(a.VariantID[:], b.VariantID[:])
+	})
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, line := range sorted {
+			before, err := lockVariantStock(ctx, tx, line.VariantID)
+			if err != nil {
+				return err
+			}
+
+			var previousQuantity int64
+			err = tx.QueryRowContext(
+				ctx,
+				"SELECT quantity FROM location_stock WHERE location_id = $1 AND variant_id = $2",
+				locationID,
+				line.VariantID,
+			).Scan(&previousQuantity)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to find location stock in inventory store: %w", err)
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				`INSERT INTO location_stock(location_id, variant_id, quantity) VALUES($1, $2, $3)
+				ON CONFLICT (location_id, variant_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()`,
+				locationID,
+				line.VariantID,
+				line.Quantity,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to set location stock in inventory store: %w", err)
+			}
+
+			after, err := syncVariantStock(ctx, tx, line.VariantID)
+			if err != nil {
+				return err
+			}
+
+			movement := &stockledger.Movement{
+				VariantID:  line.VariantID,
+				LocationID: &locationID,
+				Quantity:   line.Quantity - previousQuantity,
+				Reason:     change.Reason,
+				Reference:  change.Reference,
+				Note:       change.Note,
+			}
+			if err = recordAtLocation(ctx, tx, movement, before, after); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// stockAtLocations returns the stock of the variants at each location that
+// keeps any of them, quantities of 0 included.
+func (s *store) stockAtLocations(ctx context.Context, variantIDs []uuid.UUID) ([]*stockAt, error) {
+	locations, err := s.listLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT location_id, variant_id, quantity FROM location_stock WHERE variant_id = ANY($1)",
+		pq.Array(variantIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find location stock in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	byLocation := map[uuid.UUID]*stockAt{}
+	for rows.Next() {
+		var locationID, variantID uuid.UUID
+		var quantity int64
+		if err = rows.Scan(&locationID, &variantID, &quantity); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into location stock in inventory store: %w",
+				err,
+			)
+		}
+
+		at, ok := byLocation[locationID]
+		if !ok {
+			at = &stockAt{quantities: map[uuid.UUID]int64{}}
+			byLocation[locationID] = at
+		}
+
+		at.quantities[variantID] = quantity
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	stock := []*stockAt{}
+	for _, location := range locations {
+		if at, ok := byLocation[location.LocationID]; ok {
+			at.location = location
+			stock = append(stock, at)
+		}
+	}
+
+	return stock, nil
+}
+
+// createTransfer records a pending transfer. It returns
+// servererrors.ErrVariantNotFound when a line is not a variant.
+func (s *store) createTransfer(ctx context.Context, transfer *Transfer) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO stock_transfers(transfer_id, from_location_id, to_location_id, status, note) VALUES($1, $2, $3, $4, $5)",
+			transfer.TransferID,
+			transfer.FromLocationID,
+			transfer.ToLocationID,
+			transfer.Status,
+			transfer.Note,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert transfer in inventory store: %w", err)
+		}
+
+		for _, line := range transfer.Lines {
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO stock_transfer_lines(transfer_id, variant_id, quantity) VALUES($1, $2, $3)",
+				transfer.TransferID,
+				line.VariantID,
+				line.Quantity,
+			)
+			if err != nil {
+				if isPQError(err, foreignKeyViolation) {
+					return servererrors.ErrVariantNotFound
+				}
+
+				return fmt.Errorf("failed to insert transfer line in inventory store: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// findTransfer returns a transfer with its lines, a zero Transfer when there
+// is none.
+func (s *store) findTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error) {
+	transfers, err := s.getTransfersWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM stock_transfers WHERE transfer_id = $1", transferFields),
+		transferID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transfers) == 0 {
+		return new(Transfer), nil
+	}
+
+	return transfers[0], nil
+}
+
+// listTransfers returns a page of transfers, newest first, optionally of one
+// status only, and how many there are in all.
+func (s *store) listTransfers(ctx context.Context, status string, limit int64, offset int64) ([]*Transfer, int64, error) {
+	var totalCount int64
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM stock_transfers WHERE $1 = '' OR status = $1",
+		status,
+	).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to count transfers in inventory store: %w",
+			err,
+		)
+	}
+
+	transfers, err := s.getTransfersWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM stock_transfers WHERE $1 = '' OR status = $1 ORDER BY created_at DESC, transfer_id ASC LIMIT $2 OFFSET $3", transferFields),
+		status,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transfers, totalCount, nil
+}
+
+// completeTransfer moves the stock of a pending transfer from its source to
+// its destination, all or nothing. It returns false when the transfer is not
+// pending and servererrors.ErrInsufficientStock when the source is short.
+func (s *store) completeTransfer(ctx context.Context, transfer *Transfer) (bool, error) {
+	completed := false
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		updated, err := setTransferStatus(ctx, tx, transfer.TransferID, TransferStatusCompleted)
+		if err != nil || !updated {
+			return err
+		}
+
+		lines := slices.Clone(transfer.Lines)
+		slices.SortFunc(lines, func(a, b *TransferLine) int {
+			return slices.Compare(a.VariantID[:], b.VariantID[:])
+		})
+
+		for _, line := range lines {
+			if _, err = lockVariantStock(ctx, tx, line.VariantID); err != nil {
+				return err
+			}
+
+			result, err := tx.ExecContext(
+				ctx,
+				"UPDATE location_stock SET quantity = quantity - $1, updated_at = NOW() WHERE location_id = $2 AND variant_id = $3 AND quantity >= $1",
+				line.Quantity,
+				transfer.FromLocationID,
+				line.VariantID,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update location stock in inventory store: %w", err)
+			}
+
+			moved, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to count updated location stock in inventory store: %w", err)
+			}
+
+			if moved == 0 {
+				return servererrors.ErrInsufficientStock
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				`INSERT INTO location_stock(location_id, variant_id, quantity) VALUES($1, $2, $3)
+				ON CONFLICT (location_id, variant_id) DO UPDATE SET quantity = location_stock.quantity + EXCLUDED.quantity, updated_at = NOW()`,
+				transfer.ToLocationID,
+				line.VariantID,
+				line.Quantity,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update location stock in inventory store: %w", err)
+			}
+
+			err = stockledger.Record(
+				ctx,
+				tx,
+				&stockledger.Movement{
+					VariantID:  line.VariantID,
+					LocationID: &transfer.FromLocationID,
+					Quantity:   -line.Quantity,
+					Reason:     stockledger.ReasonTransfer,
+					Reference:  transfer.TransferID.String(),
+				},
+				&stockledger.Movement{
+					VariantID:  line.VariantID,
+					LocationID: &transfer.ToLocationID,
+					Quantity:   line.Quantity,
+					Reason:     stockledger.ReasonTransfer,
+					Reference:  transfer.TransferID.String(),
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("failed to record transfer in inventory store: %w", err)
+			}
+		}
+
+		completed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return completed, nil
+}
+
+// cancelTransfer cancels a pending transfer, it returns false when the
+// transfer is not pending.
+func (s *store) cancelTransfer(ctx context.Context, transferID uuid.UUID) (bool, error) {
+	cancelled := false
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		cancelled, err = setTransferStatus(ctx, tx, transferID, TransferStatusCancelled)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return cancelled, nil
+}
+
+func (s *store) getLocationStockWithContext(ctx context.Context, query string, args ...any) ([]*LocationStock, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in inventory store getLocationStockWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	stock := []*LocationStock{}
+	for rows.Next() {
+		line := new(LocationStock)
+		err = rows.Scan(
+			&line.LocationID,
+			&line.LocationCode,
+			&line.VariantID,
+			&line.SKU,
+			&line.Quantity,
+			&line.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into location stock in inventory store: %w",
+				err,
+			)
+		}
+
+		stock = append(stock, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return stock, nil
+}
+
+func (s *store) getTransfersWithContext(ctx context.Context, query string, args ...any) ([]*Transfer, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in inventory store getTransfersWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	transfers := []*Transfer{}
+	for rows.Next() {
+		transfer := &Transfer{Lines: []*TransferLine{}}
+		err = rows.Scan(
+			&transfer.TransferID,
+			&transfer.FromLocationID,
+			&transfer.ToLocationID,
+			&transfer.Status,
+			&transfer.Note,
+			&transfer.CreatedAt,
+			&transfer.UpdatedAt,
+			&transfer.CompletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into transfer in inventory store: %w",
+				err,
+			)
+		}
+
+		transfers = append(transfers, transfer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	for _, transfer := range transfers {
+		transfer.Lines, err = s.getTransferLinesWithContext(ctx, transfer.TransferID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return transfers, nil
+}
+
+func (s *store) getTransferLinesWithContext(ctx context.Context, transferID uuid.UUID) ([]*TransferLine, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT l.variant_id, v.sku, l.quantity
+		FROM stock_transfer_lines l JOIN product_variants v ON v.variant_id = l.variant_id
+		WHERE l.transfer_id = $1
+		ORDER BY v.sku ASC`,
+		transferID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find transfer lines in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	lines := []*TransferLine{}
+	for rows.Next() {
+		line := new(TransferLine)
+		if err = rows.Scan(&line.VariantID, &line.SKU, &line.Quantity); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into transfer line in inventory store: %w",
+				err,
+			)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return lines, nil
+}
+
+// setTransferStatus moves a pending transfer to status, it returns false when
+// the transfer is not pending.
+func setTransferStatus(ctx context.Context, tx *sql.Tx, transferID uuid.UUID, status string) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE stock_transfers SET status = $1, updated_at = NOW(), completed_at = CASE WHEN $1 = 'completed' THEN NOW() END
+		WHERE transfer_id = $2 AND status = 'pending'`,
+		status,
+		transferID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update transfer in inventory store: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count updated transfers in inventory store: %w", err)
+	}
+
+	return updated > 0, nil
+}
+
+// lockVariantStock creates the stock row of a variant if need be and locks
+// it. Every change to the stock of a variant at its locations locks the
+// variant first, so they are serialized with checkouts reserving it.
+func lockVariantStock(ctx context.Context, tx *sql.Tx, variantID uuid.UUID) (int64, error) {
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO variant_stock(variant_id) VALUES($1) ON CONFLICT (variant_id) DO NOTHING",
+		variantID,
+	)
+	if err != nil {
+		if isPQError(err, foreignKeyViolation) {
+			return 0, servererrors.ErrVariantNotFound
+		}
+
+		return 0, fmt.Errorf("failed to create variant stock in inventory store: %w", err)
+	}
+
+	var quantity int64
+	err = tx.QueryRowContext(
+		ctx,
+		"SELECT quantity FROM variant_stock WHERE variant_id = $1 FOR UPDATE",
+		variantID,
+	).Scan(&quantity)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock variant stock in inventory store: %w", err)
+	}
+
+	return quantity, nil
+}
+
+// syncVariantStock sets the stock of a variant to its quantity over all
+// locations and returns it.
+func syncVariantStock(ctx context.Context, tx *sql.Tx, variantID uuid.UUID) (int64, error) {
+	var quantity int64
+	err := tx.QueryRowContext(
+		ctx,
+		"UPDATE variant_stock SET quantity = (SELECT COALESCE(SUM(quantity), 0) FROM location_stock WHERE variant_id = $1), updated_at = NOW() WHERE variant_id = $1 RETURNING quantity",
+		variantID,
+	).Scan(&quantity)
+	if err != nil {
+		// the stock can not drop below what checkouts reserved
+		if isPQError(err, checkViolation) {
+			return 0, servererrors.ErrStockBelowReserved
+		}
+
+		return 0, fmt.Errorf("failed to update variant stock in inventory store: %w", err)
+	}
+
+	return quantity, nil
+}
+
+// recordAtLocation records a movement at a location that changed the stock
+// of its variant from before to after. When the variant was not kept at
+// locations until now the stock it had moves to its locations, which is
+// recorded as an adjustment at no location.
+func recordAtLocation(ctx context.Context, tx *sql.Tx, movement *stockledger.Movement, before int64, after int64) error {
+	movements := []*stockledger.Movement{movement}
+	if moved := after - before - movement.Quantity; moved != 0 {
+		movements = append(movements, &stockledger.Movement{
+			VariantID: movement.VariantID,
+			Quantity:  moved,
+			Reason:    stockledger.ReasonAdjustment,
+			Reference: movement.Reference,
+			Note:      "stock moved to locations",
+		})
+	}
+
+	if err := stockledger.Record(ctx, tx, movements...); err != nil {
+		return fmt.Errorf("failed to record location stock in inventory store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package inventory
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func TestAllocate(t *testing.T) {
+	shirt, mug := uuid.New(), uuid.New()
+
+	// central ships first by priority, west is nearest san francisco
+	central := &Location{LocationID: uuid.New(), Code: "WH-CENTRAL", Latitude: 41.88, Longitude: -87.63, Priority: 0}
+	east := &Location{LocationID: uuid.New(), Code: "WH-EAST", Latitude: 40.71, Longitude: -74.01, Priority: 1}
+	west := &Location{LocationID: uuid.New(), Code: "WH-WEST", Latitude: 34.05, Longitude: -118.24, Priority: 2}
+	sanFrancisco := &Coordinates{Latitude: 37.77, Longitude: -122.42}
+
+	stock := []*stockAt{
+		{location: central, quantities: map[uuid.UUID]int64{shirt: 2}},
+		{location: east, quantities: map[uuid.UUID]int64{shirt: 5, mug: 5}},
+		{location: west, quantities: map[uuid.UUID]int64{shirt: 3, mug: 1}},
+	}
+
+	cases := []struct {
+		name        string
+		lines       []*ReservationLine
+		strategy    string
+		destination *Coordinates
+		want        map[string]int64
+	}{
+		{
+			name:     "priority splits a line over locations in order",
+			lines:    []*ReservationLine{{VariantID: shirt, Quantity: 4}},
+			strategy: AllocationStrategyPriority,
+			want:     map[string]int64{"WH-CENTRAL": 2, "WH-EAST": 2},
+		},
+		{
+			name:        "closest takes the nearest locations first",
+			lines:       []*ReservationLine{{VariantID: shirt, Quantity: 4}},
+			strategy:    AllocationStrategyClosest,
+			destination: sanFrancisco,
+			want:        map[string]int64{"WH-WEST": 3, "WH-CENTRAL": 1},
+		},
+		{
+			name:     "whole order prefers a location with every line",
+			lines:    []*ReservationLine{{VariantID: shirt, Quantity: 2}, {VariantID: mug, Quantity: 2}},
+			strategy: AllocationStrategyWholeOrder,
+			want:     map[string]int64{"WH-EAST": 4},
+		},
+		{
+			name:     "whole order splits over the fewest locations when none has every line",
+			lines:    []*ReservationLine{{VariantID: shirt, Quantity: 8}, {VariantID: mug, Quantity: 1}},
+			strategy: AllocationStrategyWholeOrder,
+			want:     map[string]int64{"WH-EAST": 6, "WH-WEST": 3},
+		},
+		{
+			name:     "lines of variants not kept at locations get no allocation",
+			lines:    []*ReservationLine{{VariantID: uuid.New(), Quantity: 1}},
+			strategy: AllocationStrategyPriority,
+			want:     map[string]int64{},
+		},
+	}
+
+	for _, c := range cases {
+		allocations, err := allocate(c.lines, stock, c.strategy, c.destination)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		got := map[string]int64{}
+		for _, allocation := range allocations {
+			got[allocation.LocationCode] += allocation.Quantity
+		}
+
+		if len(got) != len(c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+			continue
+		}
+
+		for code, quantity := range c.want {
+			if got[code] != quantity {
+				t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+				break
+			}
+		}
+	}
+
+	if _, err := allocate([]*ReservationLine{{VariantID: mug, Quantity: 7}}, stock, AllocationStrategyPriority, nil); err == nil {
+		t.Error("expected an error allocating more than the locations have")
+	}
+}
+
+func TestLocations(t *testing.T) {
+	router, inventoryStore, _ := newTestRouter(t)
+
+	productID := uuid.New()
+	shirt := inventoryStore.addVariant(productID, "SHIRT-M", 0)
+	alice := uuid.New()
+
+	createLocation := func(t *testing.T, code string, latitude, longitude float64, priority int) *Location {
+		t.Helper()
+
+		location := new(Location)
+		payload := CreateLocationRequest{
+			Code:     code,
+			Name:     code,
+			Kind:     LocationKindWarehouse,
+			Position: &CoordinatesRequest{Latitude: float64Ptr(latitude), Longitude: float64Ptr(longitude)},
+			Priority: priority,
+		}
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/locations", nil, payload, location); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		return location
+	}
+
+	east := createLocation(t, "wh-east", 40.71, -74.01, 0)
+	west := createLocation(t, "WH-WEST", 34.05, -118.24, 1)
+
+	t.Run("should reject invalid locations", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			payload CreateLocationRequest
+			status  int
+		}{
+			{"no position", CreateLocationRequest{Code: "WH-1", Name: "North", Kind: LocationKindWarehouse}, http.StatusUnprocessableEntity},
+			{"unknown kind", CreateLocationRequest{Code: "WH-1", Name: "North", Kind: "depot", Position: &CoordinatesRequest{Latitude: float64Ptr(0), Longitude: float64Ptr(0)}}, http.StatusUnprocessableEntity},
+			{"latitude out of range", CreateLocationRequest{Code: "WH-1", Name: "North", Kind: LocationKindStore, Position: &CoordinatesRequest{Latitude: float64Ptr(91), Longitude: float64Ptr(0)}}, http.StatusUnprocessableEntity},
+			{"duplicate code", CreateLocationRequest{Code: "WH-EAST", Name: "East", Kind: LocationKindStore, Position: &CoordinatesRequest{Latitude: float64Ptr(0), Longitude: float64Ptr(0)}}, http.StatusConflict},
+		}
+
+		for _, c := range cases {
+			if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/locations", nil, c.payload, nil); code != c.status {
+				t.Errorf("%s: expected status code %d, got %d", c.name, c.status, code)
+			}
+		}
+
+		if east.Code != "WH-EAST" {
+			t.Errorf("expected code WH-EAST, got %s", east.Code)
+		}
+	})
+
+	t.Run("should keep the variant stock at the sum over locations", func(t *testing.T) {
+		for _, location := range []*Location{east, west} {
+			payload := SetLocationStockRequest{Lines: []LocationStockLineRequest{{VariantID: shirt, Quantity: 4}}}
+			if code := testutil.Serve(t, router, http.MethodPut, "/admin/inventory/locations/"+location.LocationID.String()+"/stock", nil, payload, nil); code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+			}
+		}
+
+		if quantity := inventoryStore.stock[shirt].quantity; quantity != 8 {
+			t.Errorf("expected 8 shirts on hand, got %d", quantity)
+		}
+
+		payload := SetLocationStockRequest{Lines: []LocationStockLineRequest{{VariantID: uuid.New(), Quantity: 1}}}
+		if code := testutil.Serve(t, router, http.MethodPut, "/admin/inventory/locations/"+east.LocationID.String()+"/stock", nil, payload, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodPut, "/admin/inventory/locations/"+uuid.New().String()+"/stock", nil, payload, nil); code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+	})
+
+	t.Run("should move stock when a transfer completes", func(t *testing.T) {
+		payload := CreateTransferRequest{
+			FromLocationID: east.LocationID,
+			ToLocationID:   west.LocationID,
+			Lines:          []ReservationLineRequest{{VariantID: shirt, Quantity: 3}},
+		}
+		transfer := new(Transfer)
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/transfers", nil, payload, transfer); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		if got := inventoryStore.atLocation[west.LocationID][shirt]; got != 4 {
+			t.Errorf("expected pending transfers to move nothing, got %d shirts at WH-WEST", got)
+		}
+
+		completePath := "/admin/inventory/transfers/" + transfer.TransferID.String() + "/complete"
+		if code := testutil.Serve(t, router, http.MethodPost, completePath, nil, nil, transfer); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		eastShirts, westShirts := inventoryStore.atLocation[east.LocationID][shirt], inventoryStore.atLocation[west.LocationID][shirt]
+		if transfer.Status != TransferStatusCompleted || eastShirts != 1 || westShirts != 7 {
+			t.Errorf("expected a completed transfer leaving 1 and 7 shirts, got %s with %d and %d", transfer.Status, eastShirts, westShirts)
+		}
+
+		if quantity := inventoryStore.stock[shirt].quantity; quantity != 8 {
+			t.Errorf("expected transfers to keep 8 shirts on hand, got %d", quantity)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodPost, completePath, nil, nil, nil); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		short := new(Transfer)
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/transfers", nil, payload, short); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/transfers/"+short.TransferID.String()+"/complete", nil, nil, nil); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/transfers/"+short.TransferID.String()+"/cancel", nil, nil, nil); code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		same := CreateTransferRequest{FromLocationID: east.LocationID, ToLocationID: east.LocationID, Lines: payload.Lines}
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/transfers", nil, same, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+	})
+
+	t.Run("should preview allocations", func(t *testing.T) {
+		var allocations []*Allocation
+		payload := AllocateRequest{Lines: []ReservationLineRequest{{VariantID: shirt, Quantity: 2}}, Strategy: AllocationStrategyPriority}
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/allocations", nil, payload, &allocations); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		// east ships first but only has 1 shirt left
+		if len(allocations) != 2 || allocations[0].LocationCode != "WH-EAST" || allocations[0].Quantity != 1 {
+			t.Errorf("expected 1 shirt from WH-EAST and 1 from WH-WEST, got %+v", allocations)
+		}
+
+		payload.Strategy = AllocationStrategyClosest
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/allocations", nil, payload, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+	})
+
+	t.Run("should take committed stock from the allocated locations", func(t *testing.T) {
+		payload := ReserveStockRequest{
+			CheckoutID:  uuid.New(),
+			Lines:       []ReservationLineRequest{{VariantID: shirt, Quantity: 2}},
+			Strategy:    AllocationStrategyClosest,
+			Destination: &CoordinatesRequest{Latitude: float64Ptr(37.77), Longitude: float64Ptr(-122.42)},
+		}
+		reservation := new(Reservation)
+		if code := testutil.Serve(t, router, http.MethodPost, "/checkout/reservations", &alice, payload, reservation); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		// the reserved shirts can not be set away from the locations
+		low := SetLocationStockRequest{Lines: []LocationStockLineRequest{{VariantID: shirt, Quantity: 0}}}
+		if code := testutil.Serve(t, router, http.MethodPut, "/admin/inventory/locations/"+west.LocationID.String()+"/stock", nil, low, nil); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+
+		committed := new(Reservation)
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/reservations/"+reservation.ReservationID.String()+"/commit", nil, nil, committed); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if len(committed.Allocations) != 1 || committed.Allocations[0].LocationID != west.LocationID || committed.Allocations[0].Quantity != 2 {
+			t.Errorf("expected 2 shirts from WH-WEST, got %+v", committed.Allocations)
+		}
+
+		if westShirts, onHand := inventoryStore.atLocation[west.LocationID][shirt], inventoryStore.stock[shirt].quantity; westShirts != 5 || onHand != 6 {
+			t.Errorf("expected 5 shirts at WH-WEST and 6 on hand, got %d and %d", westShirts, onHand)
+		}
+	})
+}
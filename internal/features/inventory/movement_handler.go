@@ -0,0 +1,161 @@
+package inventory
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+)
+
+// recordMovementHandler changes the stock of a variant by a quantity, e.g.
+// for goods received or written off, and records why.
+func (h *handler) recordMovementHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *RecordMovementRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	movement, err := h.service.recordMovement(ctx, payload)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"stock movement recorded",
+		movement,
+	)
+}
+
+// listMovementsHandler lists the stock movements newest first, filtered by
+// variant, location, reason and time, e.g.
+// ?variantId=...&reason=adjustment&from=2026-01-01T00:00:00Z.
+func (h *handler) listMovementsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	page, err := handlerutils.ParseQueryInt(r, "page", 1)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	pageSize, err := handlerutils.ParseQueryInt(r, "pageSize", defaultPageSize)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	payload := &ListMovementsRequest{
+		Reason:   r.URL.Query().Get("reason"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if payload.VariantID, err = handlerutils.ParseOptionalQueryUUID(r, "variantId"); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	if payload.LocationID, err = handlerutils.ParseOptionalQueryUUID(r, "locationId"); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	if payload.From, err = handlerutils.ParseOptionalQueryTime(r, "from"); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	if payload.To, err = handlerutils.ParseOptionalQueryTime(r, "to"); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	movements, err := h.service.listMovements(ctx, payload)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"stock movements found",
+		movements,
+	)
+}
+
+// reconciliationHandler reports the stock whose cached quantity drifted from
+// the ledger of movements.
+func (h *handler) reconciliationHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	reconciliation, err := h.service.Reconcile(ctx)
+	if err != nil {
+		return inventoryError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"stock reconciled",
+		reconciliation,
+	)
+}
@@ -0,0 +1,71 @@
+package inventory
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+)
+
+// recordMovement changes the stock of a variant outside of sales and
+// transfers, e.g. goods received, returned or written off, and records why.
+func (s *service) recordMovement(ctx context.Context, payload *RecordMovementRequest) (*stockledger.Movement, error) {
+	if payload.LocationID != nil {
+		if _, err := s.getLocation(ctx, *payload.LocationID); err != nil {
+			return nil, err
+		}
+	}
+
+	movement := &stockledger.Movement{
+		MovementID: uuid.New(),
+		VariantID:  payload.VariantID,
+		LocationID: payload.LocationID,
+		Quantity:   payload.Quantity,
+		Reason:     payload.Reason,
+		Reference:  strings.TrimSpace(payload.Reference),
+		Note:       strings.TrimSpace(payload.Note),
+	}
+
+	if err := s.inventoryStore.recordMovement(ctx, movement); err != nil {
+		return nil, err
+	}
+
+	// stock received goes to the backorders waiting for it first
+	if movement.Quantity > 0 {
+		if _, err := s.AllocateBackorders(ctx); err != nil {
+			log.Println(err)
+		}
+	}
+
+	return movement, nil
+}
+
+func (s *service) listMovements(ctx context.Context, payload *ListMovementsRequest) (*ListMovementsResponse, error) {
+	movements, totalCount, err := s.inventoryStore.listMovements(ctx, &movementFilter{
+		VariantID:  payload.VariantID,
+		LocationID: payload.LocationID,
+		Reason:     payload.Reason,
+		From:       payload.From,
+		To:         payload.To,
+		Limit:      payload.PageSize,
+		Offset:     (payload.Page - 1) * payload.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListMovementsResponse{
+		Movements:  movements,
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// Reconcile reports the stock whose cached quantity drifted from what its
+// movements add up to.
+func (s *service) Reconcile(ctx context.Context) (*Reconciliation, error) {
+	return s.inventoryStore.reconcile(ctx)
+}
@@ -0,0 +1,289 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+)
+
+const movementFields = "movement_id, variant_id, sku, location_id, quantity, reason, actor_type, actor_id, reference, note, created_at"
+
+// recordMovement changes the stock of a variant by the quantity of movement
+// and records it. Variants kept at locations change at the location of the
+// movement, the others change as a whole and a movement for them has no
+// location. It returns servererrors.ErrStockManagedByLocations for a
+// movement without a location of a variant kept at locations,
+// servererrors.ErrInsufficientStock when the stock would go below 0 and
+// servererrors.ErrStockBelowReserved when it would go below what checkouts
+// reserved.
+func (s *store) recordMovement(ctx context.Context, movement *stockledger.Movement) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		before, err := lockVariantStock(ctx, tx, movement.VariantID)
+		if err != nil {
+			return err
+		}
+
+		if movement.LocationID != nil {
+			_, err = tx.ExecContext(
+				ctx,
+				`INSERT INTO location_stock(location_id, variant_id, quantity) VALUES($1, $2, $3)
+				ON CONFLICT (location_id, variant_id) DO UPDATE SET quantity = location_stock.quantity + EXCLUDED.quantity, updated_at = NOW()`,
+				movement.LocationID,
+				movement.VariantID,
+				movement.Quantity,
+			)
+			if err != nil {
+				switch {
+				case isPQError(err, checkViolation):
+					return servererrors.ErrInsufficientStock
+				case isPQError(err, foreignKeyViolation):
+					return servererrors.ErrLocationNotFound
+				}
+
+				return fmt.Errorf("failed to update location stock in inventory store: %w", err)
+			}
+
+			after, err := syncVariantStock(ctx, tx, movement.VariantID)
+			if err != nil {
+				return err
+			}
+
+			return recordAtLocation(ctx, tx, movement, before, after)
+		}
+
+		var byLocation bool
+		err = tx.QueryRowContext(
+			ctx,
+			"SELECT EXISTS(SELECT 1 FROM location_stock WHERE variant_id = $1)",
+			movement.VariantID,
+		).Scan(&byLocation)
+		if err != nil {
+			return fmt.Errorf("failed to find location stock in inventory store: %w", err)
+		}
+
+		if byLocation {
+			return servererrors.ErrStockManagedByLocations
+		}
+
+		if before+movement.Quantity < 0 {
+			return servererrors.ErrInsufficientStock
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE variant_stock SET quantity = quantity + $1, updated_at = NOW() WHERE variant_id = $2",
+			movement.Quantity,
+			movement.VariantID,
+		)
+		if err != nil {
+			// the stock can not drop below what checkouts reserved
+			if isPQError(err, checkViolation) {
+				return servererrors.ErrStockBelowReserved
+			}
+
+			return fmt.Errorf("failed to update variant stock in inventory store: %w", err)
+		}
+
+		if err = stockledger.Record(ctx, tx, movement); err != nil {
+			return fmt.Errorf("failed to record stock movement in inventory store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// listMovements returns a page of the movements matching filter, newest
+// first, and how many match in total.
+func (s *store) listMovements(ctx context.Context, filter *movementFilter) ([]*stockledger.Movement, int64, error) {
+	conditions := []string{"TRUE"}
+	args := []any{}
+
+	if filter.VariantID != nil {
+		args = append(args, *filter.VariantID)
+		conditions = append(conditions, fmt.Sprintf("variant_id = $%d", len(args)))
+	}
+
+	if filter.LocationID != nil {
+		args = append(args, *filter.LocationID)
+		conditions = append(conditions, fmt.Sprintf("location_id = $%d", len(args)))
+	}
+
+	if filter.Reason != "" {
+		args = append(args, filter.Reason)
+		conditions = append(conditions, fmt.Sprintf("reason = $%d", len(args)))
+	}
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	where := strings.Join(conditions, " AND ")
+
+	var totalCount int64
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM stock_movements WHERE "+where,
+		args...,
+	).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to count stock movements in inventory store: %w",
+			err,
+		)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT %s FROM stock_movements WHERE %s ORDER BY created_at DESC, movement_id ASC LIMIT $%d OFFSET $%d",
+			movementFields,
+			where,
+			len(args)-1,
+			len(args),
+		),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to find stock movements in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	movements := []*stockledger.Movement{}
+	for rows.Next() {
+		movement := new(stockledger.Movement)
+		err = rows.Scan(
+			&movement.MovementID,
+			&movement.VariantID,
+			&movement.SKU,
+			&movement.LocationID,
+			&movement.Quantity,
+			&movement.Reason,
+			&movement.ActorType,
+			&movement.ActorID,
+			&movement.Reference,
+			&movement.Note,
+			&movement.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf(
+				"failed to scan row into stock movement in inventory store: %w",
+				err,
+			)
+		}
+
+		movements = append(movements, movement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return movements, totalCount, nil
+}
+
+// reconcile compares the cached stock of every variant, and of every variant
+// at each location, with the sum of its movements. It reads from a single
+// snapshot so stock changing meanwhile is not reported as drift.
+func (s *store) reconcile(ctx context.Context) (*Reconciliation, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction in inventory store: %w", err)
+	}
+	defer tx.Rollback()
+
+	reconciliation := &Reconciliation{Drift: []*Drift{}}
+	err = tx.QueryRowContext(
+		ctx,
+		"SELECT NOW(), COUNT(*) FROM variant_stock",
+	).Scan(&reconciliation.CheckedAt, &reconciliation.Variants)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count variant stock in inventory store: %w", err)
+	}
+	reconciliation.CheckedAt = reconciliation.CheckedAt.UTC()
+
+	queries := []string{
+		`SELECT vs.variant_id, v.sku, NULL::uuid, '', vs.quantity, COALESCE(m.quantity, 0)
+		FROM variant_stock vs
+		JOIN product_variants v ON v.variant_id = vs.variant_id
+		LEFT JOIN (SELECT variant_id, SUM(quantity) AS quantity FROM stock_movements GROUP BY variant_id) m ON m.variant_id = vs.variant_id
+		WHERE vs.quantity <> COALESCE(m.quantity, 0)
+		ORDER BY v.sku ASC`,
+		`SELECT d.variant_id, v.sku, d.location_id, l.code, d.cached, d.ledger
+		FROM (
+			SELECT COALESCE(ls.variant_id, m.variant_id) AS variant_id, COALESCE(ls.location_id, m.location_id) AS location_id,
+				COALESCE(ls.quantity, 0) AS cached, COALESCE(m.quantity, 0) AS ledger
+			FROM location_stock ls
+			FULL JOIN (
+				SELECT variant_id, location_id, SUM(quantity) AS quantity FROM stock_movements
+				WHERE location_id IS NOT NULL GROUP BY variant_id, location_id
+			) m ON m.variant_id = ls.variant_id AND m.location_id = ls.location_id
+		) d
+		JOIN product_variants v ON v.variant_id = d.variant_id
+		JOIN stock_locations l ON l.location_id = d.location_id
+		WHERE d.cached <> d.ledger
+		ORDER BY v.sku ASC, l.code ASC`,
+	}
+
+	for _, query := range queries {
+		drift, err := getDriftWithContext(ctx, tx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		reconciliation.Drift = append(reconciliation.Drift, drift...)
+	}
+
+	return reconciliation, nil
+}
+
+func getDriftWithContext(ctx context.Context, tx *sql.Tx, query string) ([]*Drift, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to reconcile stock in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	drift := []*Drift{}
+	for rows.Next() {
+		d := new(Drift)
+		err = rows.Scan(&d.VariantID, &d.SKU, &d.LocationID, &d.LocationCode, &d.Cached, &d.Ledger)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into drift in inventory store: %w",
+				err,
+			)
+		}
+
+		d.Difference = d.Cached - d.Ledger
+		drift = append(drift, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return drift, nil
+}
@@ -0,0 +1,175 @@
+package inventory
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestCommitMovements(t *testing.T) {
+	reservationID := uuid.New()
+	shirt, mug := uuid.New(), uuid.New()
+	east, west := uuid.New(), uuid.New()
+
+	lines := []*ReservationLine{{VariantID: shirt, Quantity: 5}, {VariantID: mug, Quantity: 2}}
+	allocations := []*Allocation{
+		{VariantID: shirt, LocationID: east, Quantity: 3},
+		{VariantID: shirt, LocationID: west, Quantity: 2},
+	}
+
+	movements := commitMovements(reservationID, lines, allocations)
+
+	sold := map[uuid.UUID]int64{}
+	atLocations := 0
+	for _, movement := range movements {
+		if movement.Reason != stockledger.ReasonSale || movement.Reference != reservationID.String() {
+			t.Errorf("expected a sale of reservation %s, got %s of %s", reservationID, movement.Reason, movement.Reference)
+		}
+
+		sold[movement.VariantID] -= movement.Quantity
+		if movement.LocationID != nil {
+			atLocations++
+		}
+	}
+
+	if sold[shirt] != 5 || sold[mug] != 2 {
+		t.Errorf("expected 5 shirts and 2 mugs sold, got %d and %d", sold[shirt], sold[mug])
+	}
+
+	// the shirts ship from two locations, the mugs are not kept at any
+	if len(movements) != 3 || atLocations != 2 {
+		t.Errorf("expected 3 movements, 2 at locations, got %d and %d", len(movements), atLocations)
+	}
+}
+
+func TestMovements(t *testing.T) {
+	router, inventoryStore, _ := newTestRouter(t)
+
+	productID := uuid.New()
+	shirt := inventoryStore.addVariant(productID, "SHIRT-M", 0)
+	mug := inventoryStore.addVariant(productID, "MUG", 10)
+
+	east := new(Location)
+	payload := CreateLocationRequest{
+		Code:     "WH-EAST",
+		Name:     "East",
+		Kind:     LocationKindWarehouse,
+		Position: &CoordinatesRequest{Latitude: float64Ptr(40.71), Longitude: float64Ptr(-74.01)},
+	}
+	if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/locations", nil, payload, east); code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+	}
+
+	reconcile := func(t *testing.T) *Reconciliation {
+		t.Helper()
+
+		reconciliation := new(Reconciliation)
+		if code := testutil.Serve(t, router, http.MethodGet, "/admin/inventory/reconciliation", nil, nil, reconciliation); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		return reconciliation
+	}
+
+	t.Run("should record receipts and write-offs", func(t *testing.T) {
+		receipt := RecordMovementRequest{VariantID: shirt, LocationID: &east.LocationID, Quantity: 12, Reason: stockledger.ReasonReceipt, Reference: "PO-1001"}
+		movement := new(stockledger.Movement)
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/movements", nil, receipt, movement); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		if movement.SKU != "SHIRT-M" || movement.Quantity != 12 || movement.Reference != "PO-1001" {
+			t.Errorf("expected 12 SHIRT-M received for PO-1001, got %d %s for %s", movement.Quantity, movement.SKU, movement.Reference)
+		}
+
+		writeOff := RecordMovementRequest{VariantID: mug, Quantity: -3, Reason: stockledger.ReasonAdjustment, Note: "broken in storage"}
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/movements", nil, writeOff, nil); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		if shirts, mugs := inventoryStore.stock[shirt].quantity, inventoryStore.stock[mug].quantity; shirts != 12 || mugs != 7 {
+			t.Errorf("expected 12 shirts and 7 mugs, got %d and %d", shirts, mugs)
+		}
+	})
+
+	t.Run("should reject invalid movements", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			payload RecordMovementRequest
+			status  int
+		}{
+			{"no quantity", RecordMovementRequest{VariantID: mug, Reason: stockledger.ReasonAdjustment}, http.StatusUnprocessableEntity},
+			{"sales are recorded by orders", RecordMovementRequest{VariantID: mug, Quantity: -1, Reason: stockledger.ReasonSale}, http.StatusUnprocessableEntity},
+			{"more than on hand", RecordMovementRequest{VariantID: mug, Quantity: -8, Reason: stockledger.ReasonAdjustment}, http.StatusConflict},
+			{"variant kept at locations", RecordMovementRequest{VariantID: shirt, Quantity: 1, Reason: stockledger.ReasonReturn}, http.StatusConflict},
+			{"unknown location", RecordMovementRequest{VariantID: shirt, LocationID: &productID, Quantity: 1, Reason: stockledger.ReasonReturn}, http.StatusNotFound},
+			{"unknown variant", RecordMovementRequest{VariantID: uuid.New(), Quantity: 1, Reason: stockledger.ReasonReceipt}, http.StatusUnprocessableEntity},
+		}
+
+		for _, c := range cases {
+			if code := testutil.Serve(t, router, http.MethodPost, "/admin/inventory/movements", nil, c.payload, nil); code != c.status {
+				t.Errorf("%s: expected status code %d, got %d", c.name, c.status, code)
+			}
+		}
+	})
+
+	t.Run("should record counted location stock with its reason", func(t *testing.T) {
+		counted := SetLocationStockRequest{Lines: []LocationStockLineRequest{{VariantID: shirt, Quantity: 10}, {VariantID: mug, Quantity: 5}}, Reference: "COUNT-7"}
+		if code := testutil.Serve(t, router, http.MethodPut, "/admin/inventory/locations/"+east.LocationID.String()+"/stock", nil, counted, nil); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		var adjustments ListMovementsResponse
+		path := "/admin/inventory/movements?reason=adjustment&locationId=" + east.LocationID.String()
+		if code := testutil.Serve(t, router, http.MethodGet, path, nil, nil, &adjustments); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		// the mugs moved to the location from 7 to 5, the shirts shrank by 2
+		byVariant := map[uuid.UUID]int64{}
+		for _, movement := range adjustments.Movements {
+			byVariant[movement.VariantID] += movement.Quantity
+			if movement.Reference != "COUNT-7" {
+				t.Errorf("expected reference COUNT-7, got %s", movement.Reference)
+			}
+		}
+
+		if byVariant[shirt] != -2 || byVariant[mug] != 5 {
+			t.Errorf("expected shirts adjusted by -2 and mugs by 5 at the location, got %d and %d", byVariant[shirt], byVariant[mug])
+		}
+	})
+
+	t.Run("should find no drift when every change is recorded", func(t *testing.T) {
+		if reconciliation := reconcile(t); len(reconciliation.Drift) != 0 || reconciliation.Variants != 2 {
+			t.Errorf("expected 2 variants without drift, got %d variants and %d drifted", reconciliation.Variants, len(reconciliation.Drift))
+		}
+	})
+
+	t.Run("should report drift of cached stock", func(t *testing.T) {
+		inventoryStore.atLocation[east.LocationID][shirt] = 9
+		inventoryStore.syncStock(shirt)
+
+		reconciliation := reconcile(t)
+		if len(reconciliation.Drift) != 2 {
+			t.Fatalf("expected the shirts to drift overall and at the location, got %d drifted", len(reconciliation.Drift))
+		}
+
+		for _, drift := range reconciliation.Drift {
+			if drift.VariantID != shirt || drift.Cached != 9 || drift.Ledger != 10 || drift.Difference != -1 {
+				t.Errorf("expected 9 shirts cached and 10 in the ledger, got %d and %d", drift.Cached, drift.Ledger)
+			}
+		}
+	})
+
+	t.Run("should reject invalid filters", func(t *testing.T) {
+		for _, query := range []string{"?variantId=shirt", "?reason=theft", "?from=yesterday", "?pageSize=1000"} {
+			code := testutil.Serve(t, router, http.MethodGet, "/admin/inventory/movements"+query, nil, nil, nil)
+			if code != http.StatusBadRequest && code != http.StatusUnprocessableEntity {
+				t.Errorf("%s: expected a client error, got %d", query, code)
+			}
+		}
+	})
+}
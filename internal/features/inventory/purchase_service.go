@@ -0,0 +1,20 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HasPurchased tells whether the user paid for the product, i.e. committed a
+// reservation holding any of its variants.
+func (s *service) HasPurchased(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (bool, error) {
+	return s.inventoryStore.hasCommittedProduct(ctx, userID, productID)
+}
+
+// Baskets passes the products of every reservation committed since a time,
+// each paid checkout being one order, to fn.
+func (s *service) Baskets(ctx context.Context, since time.Time, fn func(productIDs []uuid.UUID) error) error {
+	return s.inventoryStore.committedBaskets(ctx, since, fn)
+}
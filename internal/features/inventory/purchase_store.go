@@ -0,0 +1,81 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// hasCommittedProduct tells whether a committed reservation of userID holds
+// a variant of productID.
+func (s *store) hasCommittedProduct(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (bool, error) {
+	var exists bool
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS(
+			SELECT 1 FROM stock_reservations r
+			JOIN stock_reservation_lines l ON l.reservation_id = r.reservation_id
+			JOIN product_variants v ON v.variant_id = l.variant_id
+			WHERE r.user_id = $1 AND r.status = 'committed' AND v.product_id = $2
+		)`,
+		userID,
+		productID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to check purchase in inventory store: %w",
+			err,
+		)
+	}
+
+	return exists, nil
+}
+
+// committedBaskets passes the distinct products of each reservation
+// committed since a time to fn, stopping at the first error fn returns.
+func (s *store) committedBaskets(ctx context.Context, since time.Time, fn func(productIDs []uuid.UUID) error) error {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT ARRAY_AGG(DISTINCT v.product_id)
+		FROM stock_reservations r
+		JOIN stock_reservation_lines l ON l.reservation_id = r.reservation_id
+		JOIN product_variants v ON v.variant_id = l.variant_id
+		WHERE r.status = 'committed' AND r.updated_at >= $1
+		GROUP BY r.reservation_id`,
+		since,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to find committed baskets in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productIDs []uuid.UUID
+		if err = rows.Scan(pq.Array(&productIDs)); err != nil {
+			return fmt.Errorf(
+				"failed to scan row into basket in inventory store: %w",
+				err,
+			)
+		}
+
+		if err = fn(productIDs); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
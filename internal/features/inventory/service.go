@@ -0,0 +1,373 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+)
+
+type inventoryStorer interface {
+	createReservation(ctx context.Context, reservation *Reservation) (bool, error)
+	findReservation(ctx context.Context, reservationID uuid.UUID) (*Reservation, error)
+	findPendingReservation(ctx context.Context, checkoutID uuid.UUID) (*Reservation, error)
+	closeReservation(ctx context.Context, reservationID uuid.UUID, status string, allocations []*Allocation) (bool, error)
+	expiredReservations(ctx context.Context, limit int) ([]uuid.UUID, error)
+	productExists(ctx context.Context, productID uuid.UUID) (bool, error)
+	stockLevels(ctx context.Context, productID uuid.UUID) ([]*StockLevel, error)
+	createLocation(ctx context.Context, location *Location) error
+	findLocation(ctx context.Context, locationID uuid.UUID) (*Location, error)
+	listLocations(ctx context.Context) ([]*Location, error)
+	updateLocation(ctx context.Context, location *Location) error
+	locationStock(ctx context.Context, locationID uuid.UUID) ([]*LocationStock, error)
+	setLocationStock(ctx context.Context, locationID uuid.UUID, lines []*LocationStock, change *stockledger.Movement) error
+	stockAtLocations(ctx context.Context, variantIDs []uuid.UUID) ([]*stockAt, error)
+	createTransfer(ctx context.Context, transfer *Transfer) error
+	findTransfer(ctx context.Context, transferID uuid.UUID) (*Transfer, error)
+	listTransfers(ctx context.Context, status string, limit int64, offset int64) ([]*Transfer, int64, error)
+	completeTransfer(ctx context.Context, transfer *Transfer) (bool, error)
+	cancelTransfer(ctx context.Context, transferID uuid.UUID) (bool, error)
+	recordMovement(ctx context.Context, movement *stockledger.Movement) error
+	listMovements(ctx context.Context, filter *movementFilter) ([]*stockledger.Movement, int64, error)
+	reconcile(ctx context.Context) (*Reconciliation, error)
+	setAvailabilityPolicy(ctx context.Context, policy *AvailabilityPolicy) error
+	findAvailabilityPolicy(ctx context.Context, variantID uuid.UUID) (*AvailabilityPolicy, error)
+	deleteAvailabilityPolicy(ctx context.Context, variantID uuid.UUID) (bool, error)
+	variantAvailability(ctx context.Context, variantIDs []uuid.UUID) ([]*variantAvailability, error)
+	pendingBackorders(ctx context.Context, limit int) ([]*Backorder, error)
+	allocateBackorder(ctx context.Context, backorder *Backorder, quantity int64, allocations []*Allocation) (bool, error)
+	listBackorders(ctx context.Context, filter *backorderFilter) ([]*Backorder, int64, error)
+	hasCommittedProduct(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (bool, error)
+	committedBaskets(ctx context.Context, since time.Time, fn func(productIDs []uuid.UUID) error) error
+}
+
+const (
+	// reservationTTL is how long a checkout holds its stock before it goes
+	// back on sale.
+	reservationTTL = 15 * time.Minute
+
+	// expiryBatchSize is how many reservations the expiry job closes per
+	// query.
+	expiryBatchSize = 100
+
+	// commitAttempts is how many times a commit allocates again when stock
+	// moved between locations while it was being allocated.
+	commitAttempts = 3
+
+	// maxPendingReservationsPerUser and maxReservedUnitsPerUser bound what a
+	// user holds across the checkouts they have in progress, so one account
+	// can not take scarce stock off sale.
+	maxPendingReservationsPerUser = 3
+	maxReservedUnitsPerUser       = 100
+)
+
+// checkUserLimits tells whether a user already holding units across
+// pending reservations may hold lines in one more.
+func checkUserLimits(pending int64, units int64, lines []*ReservationLine) error {
+	if pending+1 > maxPendingReservationsPerUser {
+		return servererrors.ErrReservationLimitReached
+	}
+
+	for _, line := range lines {
+		units += line.Quantity
+	}
+
+	if units > maxReservedUnitsPerUser {
+		return servererrors.ErrReservedQuantityLimit
+	}
+
+	return nil
+}
+
+type service struct {
+	inventoryStore inventoryStorer
+}
+
+func NewService(inventoryStore inventoryStorer) *service {
+	return &service{
+		inventoryStore: inventoryStore,
+	}
+}
+
+// Reserve holds the stock of a checkout for reservationTTL. Reserving the
+// same lines again returns the pending reservation, reserving other lines or
+// for another destination releases it and holds the new ones instead. Lines
+// beyond the stock are backordered or preordered when their variants allow.
+// Guest checkouts have no userID, a user holds at most
+// maxPendingReservationsPerUser reservations of maxReservedUnitsPerUser units
+// in total.
+func (s *service) Reserve(ctx context.Context, userID *uuid.UUID, payload *ReserveStockRequest) (*Reservation, error) {
+	lines := mergeLines(payload.Lines)
+	destination := newCoordinates(payload.Destination)
+	strategy := payload.Strategy
+	if strategy == "" {
+		strategy = AllocationStrategyPriority
+	}
+
+	pending, err := s.inventoryStore.findPendingReservation(ctx, payload.CheckoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending.ReservationID != uuid.Nil {
+		if !ownedBy(pending, userID) {
+			return nil, servererrors.ErrReservationNotFound
+		}
+
+		unchanged := sameLines(pending.Lines, lines) && pending.Strategy == strategy && sameDestination(pending.Destination, destination)
+		if pending.ExpiresAt.After(time.Now()) && unchanged {
+			return pending, nil
+		}
+
+		status := ReservationStatusReleased
+		if !pending.ExpiresAt.After(time.Now()) {
+			status = ReservationStatusExpired
+		}
+
+		if _, err = s.inventoryStore.closeReservation(ctx, pending.ReservationID, status, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	reservation := &Reservation{
+		ReservationID: uuid.New(),
+		CheckoutID:    payload.CheckoutID,
+		UserID:        userID,
+		Status:        ReservationStatusPending,
+		Lines:         lines,
+		Strategy:      strategy,
+		Destination:   destination,
+		ExpiresAt:     time.Now().Add(reservationTTL),
+	}
+
+	created, err := s.inventoryStore.createReservation(ctx, reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		// a concurrent request for the checkout got there first
+		pending, err = s.inventoryStore.findPendingReservation(ctx, payload.CheckoutID)
+		if err != nil {
+			return nil, err
+		}
+
+		if pending.ReservationID == uuid.Nil || !ownedBy(pending, userID) {
+			return nil, servererrors.ErrReservationNotFound
+		}
+
+		return pending, nil
+	}
+
+	return s.inventoryStore.findReservation(ctx, reservation.ReservationID)
+}
+
+// getReservation returns a reservation, only to its owner when userID is set.
+func (s *service) getReservation(ctx context.Context, reservationID uuid.UUID, userID *uuid.UUID) (*Reservation, error) {
+	reservation, err := s.inventoryStore.findReservation(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.ReservationID == uuid.Nil || (userID != nil && !ownedBy(reservation, userID)) {
+		return nil, servererrors.ErrReservationNotFound
+	}
+
+	return reservation, nil
+}
+
+// Release gives the stock of a pending reservation back when its checkout is
+// cancelled, only its owner may release it when userID is set. Releasing a
+// reservation that is no longer pending is a no-op unless it was committed.
+func (s *service) Release(ctx context.Context, reservationID uuid.UUID, userID *uuid.UUID) (*Reservation, error) {
+	reservation, err := s.getReservation(ctx, reservationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.Status == ReservationStatusPending {
+		if _, err = s.inventoryStore.closeReservation(ctx, reservationID, ReservationStatusReleased, nil); err != nil {
+			return nil, err
+		}
+
+		if reservation, err = s.inventoryStore.findReservation(ctx, reservationID); err != nil {
+			return nil, err
+		}
+	}
+
+	if reservation.Status == ReservationStatusCommitted {
+		return nil, servererrors.ErrReservationCommitted
+	}
+
+	return reservation, nil
+}
+
+// Commit turns a pending reservation into a sale once its checkout is paid,
+// taking its stock off hand at the locations its allocation strategy picks.
+// Backordered lines wait for stock to be received and the reservation is
+// partially fulfilled until then. Committing again is a no-op, committing a
+// reservation that expired or was released fails with
+// servererrors.ErrReservationExpired.
+func (s *service) Commit(ctx context.Context, reservationID uuid.UUID) (*Reservation, error) {
+	reservation, err := s.getReservation(ctx, reservationID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.Status == ReservationStatusPending {
+		for attempt := 1; ; attempt++ {
+			allocations, err := s.Allocate(ctx, inStockLines(reservation.Lines), reservation.Strategy, reservation.Destination)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = s.inventoryStore.closeReservation(ctx, reservationID, ReservationStatusCommitted, allocations)
+			if err == nil {
+				break
+			}
+
+			if !errors.Is(err, servererrors.ErrInsufficientStock) || attempt == commitAttempts {
+				return nil, err
+			}
+		}
+
+		if reservation, err = s.inventoryStore.findReservation(ctx, reservationID); err != nil {
+			return nil, err
+		}
+	}
+
+	switch reservation.Status {
+	case ReservationStatusCommitted:
+		return reservation, nil
+	case ReservationStatusPending:
+		// still pending after the commit means it had expired, the expiry
+		// job just has not got to it yet
+		if _, err = s.inventoryStore.closeReservation(ctx, reservationID, ReservationStatusExpired, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, servererrors.ErrReservationExpired
+}
+
+// ExpireReservations gives back the stock of every pending reservation past
+// its expiry and returns how many it closed.
+func (s *service) ExpireReservations(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		reservationIDs, err := s.inventoryStore.expiredReservations(ctx, expiryBatchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		for _, reservationID := range reservationIDs {
+			closed, err := s.inventoryStore.closeReservation(ctx, reservationID, ReservationStatusExpired, nil)
+			if err != nil {
+				return expired, err
+			}
+
+			if closed {
+				expired++
+			}
+		}
+
+		if len(reservationIDs) < expiryBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunExpiryJob expires the reservations of abandoned checkouts every
+// interval. It blocks until ctx is done.
+func (s *service) RunExpiryJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ExpireReservations(ctx); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// stockLevels returns the stock on hand, reserved and available of each
+// variant of a product.
+func (s *service) stockLevels(ctx context.Context, productID uuid.UUID) ([]*StockLevel, error) {
+	exists, err := s.inventoryStore.productExists(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	return s.inventoryStore.stockLevels(ctx, productID)
+}
+
+// ownedBy tells whether a reservation belongs to userID, guest reservations
+// belong to guests only.
+func ownedBy(reservation *Reservation, userID *uuid.UUID) bool {
+	if reservation.UserID == nil || userID == nil {
+		return reservation.UserID == nil && userID == nil
+	}
+
+	return *reservation.UserID == *userID
+}
+
+// mergeLines sums the quantities of lines of the same variant.
+func mergeLines(requested []ReservationLineRequest) []*ReservationLine {
+	quantities := make(map[uuid.UUID]int64, len(requested))
+	lines := make([]*ReservationLine, 0, len(requested))
+	for _, line := range requested {
+		if _, ok := quantities[line.VariantID]; !ok {
+			lines = append(lines, &ReservationLine{VariantID: line.VariantID})
+		}
+
+		quantities[line.VariantID] += line.Quantity
+	}
+
+	for _, line := range lines {
+		line.Quantity = quantities[line.VariantID]
+	}
+
+	return lines
+}
+
+// sameLines tells whether two sets of lines hold the same quantities of the
+// same variants.
+func sameLines(a []*ReservationLine, b []*ReservationLine) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	quantities := make(map[uuid.UUID]int64, len(a))
+	for _, line := range a {
+		quantities[line.VariantID] = line.Quantity
+	}
+
+	for _, line := range b {
+		if quantity, ok := quantities[line.VariantID]; !ok || quantity != line.Quantity {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameDestination(a *Coordinates, b *Coordinates) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return *a == *b
+}
@@ -0,0 +1,696 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	reservationFields = "reservation_id, checkout_id, user_id, status, allocation_strategy, destination_latitude, destination_longitude, expires_at, created_at, updated_at"
+
+	// uniqueViolation, foreignKeyViolation and checkViolation are the
+	// postgres error codes raised by unique, foreign key and check
+	// constraints
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	checkViolation      = "23514"
+
+	// lockUserReservations serializes the reservations of a user so their
+	// limits are checked against what they already hold
+	lockUserReservations = "SELECT pg_advisory_xact_lock(hashtext('stock_reservations'), hashtext($1::text))"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+// createReservation records a pending reservation and holds its lines out of
+// the available stock, all or nothing. The part of a line beyond the stock is
+// backordered when the policy of its variant allows, filling in the terms it
+// is sold on. It returns servererrors.ErrVariantNotFound,
+// servererrors.ErrInsufficientStock or servererrors.ErrBackorderLimitReached
+// when a line can not be held, servererrors.ErrReservationLimitReached or
+// servererrors.ErrReservedQuantityLimit when the user would hold more than
+// their limits across unexpired pending reservations, and false when the
+// checkout already has a pending reservation.
+func (s *store) createReservation(ctx context.Context, reservation *Reservation) (bool, error) {
+	created := false
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if reservation.UserID != nil {
+			if _, err := tx.ExecContext(ctx, lockUserReservations, *reservation.UserID); err != nil {
+				return fmt.Errorf("failed to lock user reservations in inventory store: %w", err)
+			}
+		}
+
+		var latitude, longitude *float64
+		if reservation.Destination != nil {
+			latitude, longitude = &reservation.Destination.Latitude, &reservation.Destination.Longitude
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO stock_reservations(reservation_id, checkout_id, user_id, status, allocation_strategy, destination_latitude, destination_longitude, expires_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (checkout_id) WHERE status = 'pending' DO NOTHING`,
+			reservation.ReservationID,
+			reservation.CheckoutID,
+			reservation.UserID,
+			ReservationStatusPending,
+			reservation.Strategy,
+			latitude,
+			longitude,
+			reservation.ExpiresAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert stock reservation in inventory store: %w", err)
+		}
+
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count inserted stock reservations in inventory store: %w", err)
+		}
+
+		if inserted == 0 {
+			return nil
+		}
+
+		if reservation.UserID != nil {
+			var pending, units int64
+			err = tx.QueryRowContext(
+				ctx,
+				`SELECT COUNT(DISTINCT r.reservation_id), COALESCE(SUM(l.quantity), 0)
+				FROM stock_reservations r
+				LEFT JOIN stock_reservation_lines l ON l.reservation_id = r.reservation_id
+				WHERE r.user_id = $1 AND r.status = 'pending' AND r.expires_at > NOW() AND r.reservation_id <> $2`,
+				*reservation.UserID,
+				reservation.ReservationID,
+			).Scan(&pending, &units)
+			if err != nil {
+				return fmt.Errorf("failed to count user reservations in inventory store: %w", err)
+			}
+
+			if err = checkUserLimits(pending, units, reservation.Lines); err != nil {
+				return err
+			}
+		}
+
+		// variants are updated in id order so concurrent checkouts of
+		// overlapping carts lock them in the same order
+		for _, line := range sortedLines(reservation.Lines) {
+			availability, err := scanAvailability(tx.QueryRowContext(
+				ctx,
+				fmt.Sprintf("SELECT %s FROM %s WHERE vs.variant_id = $1 FOR UPDATE OF vs", availabilityFields, availabilityJoins),
+				line.VariantID,
+			))
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return servererrors.ErrVariantNotFound
+				}
+
+				return fmt.Errorf("failed to lock variant stock in inventory store: %w", err)
+			}
+
+			if err = splitLine(line, availability); err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				"UPDATE variant_stock SET reserved = reserved + $1, backordered = backordered + $2, updated_at = NOW() WHERE variant_id = $3",
+				line.inStock(),
+				line.Backordered,
+				line.VariantID,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update variant stock in inventory store: %w", err)
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				`INSERT INTO stock_reservation_lines(reservation_id, variant_id, quantity, backordered, availability, expected_ship_date, deposit_percent)
+				VALUES($1, $2, $3, $4, $5, $6, $7)`,
+				reservation.ReservationID,
+				line.VariantID,
+				line.Quantity,
+				line.Backordered,
+				line.Availability,
+				line.ExpectedShipDate,
+				line.DepositPercent,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert stock reservation line in inventory store: %w", err)
+			}
+		}
+
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// findReservation returns a reservation with its lines, a zero Reservation
+// when there is none.
+func (s *store) findReservation(ctx context.Context, reservationID uuid.UUID) (*Reservation, error) {
+	return s.getReservationWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM stock_reservations WHERE reservation_id = $1", reservationFields),
+		reservationID,
+	)
+}
+
+// findPendingReservation returns the pending reservation of a checkout, a
+// zero Reservation when there is none.
+func (s *store) findPendingReservation(ctx context.Context, checkoutID uuid.UUID) (*Reservation, error) {
+	return s.getReservationWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM stock_reservations WHERE checkout_id = $1 AND status = 'pending'", reservationFields),
+		checkoutID,
+	)
+}
+
+// closeReservation moves a pending reservation to status and gives its lines
+// back to the available stock. Committing takes the lines out of the stock on
+// hand instead, and out of the locations they are allocated to, and only
+// succeeds before the reservation expires. The backordered part of committed
+// lines waits for stock as backorders. It returns false when the
+// reservation was not pending or, on commit, had expired, and
+// servererrors.ErrInsufficientStock when a location no longer has its
+// allocation.
+func (s *store) closeReservation(ctx context.Context, reservationID uuid.UUID, status string, allocations []*Allocation) (bool, error) {
+	closed := false
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE stock_reservations SET status = $2, updated_at = NOW()
+			WHERE reservation_id = $1 AND status = 'pending' AND ($2 <> 'committed' OR expires_at > NOW())`,
+			reservationID,
+			status,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update stock reservation in inventory store: %w", err)
+		}
+
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count updated stock reservations in inventory store: %w", err)
+		}
+
+		if updated == 0 {
+			return nil
+		}
+
+		lines, err := s.getLinesWithContext(ctx, tx, reservationID)
+		if err != nil {
+			return err
+		}
+
+		sold := 0
+		if status == ReservationStatusCommitted {
+			sold = 1
+		}
+
+		// committed backorders keep their claim on the stock to come
+		for _, line := range sortedLines(lines) {
+			_, err = tx.ExecContext(
+				ctx,
+				"UPDATE variant_stock SET reserved = reserved - $1, quantity = quantity - $1 * $3, backordered = backordered - $4 * (1 - $3), updated_at = NOW() WHERE variant_id = $2",
+				line.inStock(),
+				line.VariantID,
+				sold,
+				line.Backordered,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update variant stock in inventory store: %w", err)
+			}
+
+			if status != ReservationStatusCommitted || line.Backordered == 0 {
+				continue
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO backorders(reservation_id, variant_id, quantity) VALUES($1, $2, $3)",
+				reservationID,
+				line.VariantID,
+				line.Backordered,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert backorder in inventory store: %w", err)
+			}
+		}
+
+		if status == ReservationStatusCommitted {
+			if err = applyAllocations(ctx, tx, reservationID, allocations); err != nil {
+				return err
+			}
+
+			err = stockledger.Record(ctx, tx, commitMovements(reservationID, inStockLines(lines), allocations)...)
+			if err != nil {
+				return fmt.Errorf("failed to record committed reservation in inventory store: %w", err)
+			}
+		}
+
+		closed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return closed, nil
+}
+
+// expiredReservations returns the ids of up to limit pending reservations
+// past their expiry, oldest first.
+func (s *store) expiredReservations(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT reservation_id FROM stock_reservations WHERE status = 'pending' AND expires_at <= NOW() ORDER BY expires_at ASC LIMIT $1",
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find expired stock reservations in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	reservationIDs := []uuid.UUID{}
+	for rows.Next() {
+		var reservationID uuid.UUID
+		if err = rows.Scan(&reservationID); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into reservation id in inventory store: %w",
+				err,
+			)
+		}
+
+		reservationIDs = append(reservationIDs, reservationID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return reservationIDs, nil
+}
+
+// productExists tells whether there is a product with the id.
+func (s *store) productExists(ctx context.Context, productID uuid.UUID) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM products WHERE product_id = $1)",
+		productID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to find product in inventory store: %w",
+			err,
+		)
+	}
+
+	return exists, nil
+}
+
+// stockLevels returns the stock of each variant of a product by sku.
+func (s *store) stockLevels(ctx context.Context, productID uuid.UUID) ([]*StockLevel, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT v.variant_id, v.sku, COALESCE(vs.quantity, 0), COALESCE(vs.reserved, 0), COALESCE(vs.backordered, 0), COALESCE(vs.updated_at, v.updated_at)
+		FROM product_variants v LEFT JOIN variant_stock vs ON vs.variant_id = v.variant_id
+		WHERE v.product_id = $1
+		ORDER BY v.sku ASC`,
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find stock levels in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	levels := []*StockLevel{}
+	for rows.Next() {
+		level := new(StockLevel)
+		err = rows.Scan(
+			&level.VariantID,
+			&level.SKU,
+			&level.OnHand,
+			&level.Reserved,
+			&level.Backordered,
+			&level.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into stock level in inventory store: %w",
+				err,
+			)
+		}
+
+		level.Available = max(level.OnHand-level.Reserved-level.Backordered, 0)
+		level.Locations = []*LocationStock{}
+		levels = append(levels, level)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	locationStock, err := s.getLocationStockWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE v.product_id = $1 ORDER BY l.priority ASC, l.code ASC", locationStockFields, locationStockJoins),
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stock := range locationStock {
+		for _, level := range levels {
+			if level.VariantID == stock.VariantID {
+				level.Locations = append(level.Locations, stock)
+			}
+		}
+	}
+
+	policies, err := s.getPoliciesWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM variant_availability_policies p JOIN product_variants v ON v.variant_id = p.variant_id WHERE v.product_id = $1", policyFields),
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		for _, level := range levels {
+			if level.VariantID == policy.VariantID {
+				level.Policy = policy
+			}
+		}
+	}
+
+	return levels, nil
+}
+
+func (s *store) getReservationWithContext(ctx context.Context, query string, args ...any) (*Reservation, error) {
+	reservation := &Reservation{Lines: []*ReservationLine{}, Allocations: []*Allocation{}}
+	var latitude, longitude sql.NullFloat64
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	).Scan(
+		&reservation.ReservationID,
+		&reservation.CheckoutID,
+		&reservation.UserID,
+		&reservation.Status,
+		&reservation.Strategy,
+		&latitude,
+		&longitude,
+		&reservation.ExpiresAt,
+		&reservation.CreatedAt,
+		&reservation.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return new(Reservation), nil
+		}
+
+		return nil, fmt.Errorf(
+			"failed to find stock reservation in inventory store: %w",
+			err,
+		)
+	}
+
+	if latitude.Valid && longitude.Valid {
+		reservation.Destination = &Coordinates{Latitude: latitude.Float64, Longitude: longitude.Float64}
+	}
+
+	reservation.Lines, err = s.getLinesWithContext(ctx, s.db, reservation.ReservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.Status == ReservationStatusCommitted {
+		reservation.Fulfillment = FulfillmentStatusFulfilled
+		for _, line := range reservation.Lines {
+			if line.Allocated < line.Backordered {
+				reservation.Fulfillment = FulfillmentStatusPartial
+			}
+		}
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT a.variant_id, a.location_id, l.code, a.quantity
+		FROM reservation_allocations a JOIN stock_locations l ON l.location_id = a.location_id
+		WHERE a.reservation_id = $1
+		ORDER BY a.variant_id ASC, l.code ASC`,
+		reservation.ReservationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find reservation allocations in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		allocation := new(Allocation)
+		err = rows.Scan(&allocation.VariantID, &allocation.LocationID, &allocation.LocationCode, &allocation.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into reservation allocation in inventory store: %w",
+				err,
+			)
+		}
+
+		reservation.Allocations = append(reservation.Allocations, allocation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return reservation, nil
+}
+
+// querier is what getLinesWithContext needs of a *sql.DB or *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (s *store) getLinesWithContext(ctx context.Context, q querier, reservationID uuid.UUID) ([]*ReservationLine, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT l.variant_id, v.sku, l.quantity, l.backordered, COALESCE(b.allocated, 0), l.availability, l.expected_ship_date, l.deposit_percent
+		FROM stock_reservation_lines l
+		JOIN product_variants v ON v.variant_id = l.variant_id
+		LEFT JOIN backorders b ON b.reservation_id = l.reservation_id AND b.variant_id = l.variant_id
+		WHERE l.reservation_id = $1
+		ORDER BY v.sku ASC`,
+		reservationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find stock reservation lines in inventory store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	lines := []*ReservationLine{}
+	for rows.Next() {
+		line := new(ReservationLine)
+		err = rows.Scan(
+			&line.VariantID,
+			&line.SKU,
+			&line.Quantity,
+			&line.Backordered,
+			&line.Allocated,
+			&line.Availability,
+			&line.ExpectedShipDate,
+			&line.DepositPercent,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into stock reservation line in inventory store: %w",
+				err,
+			)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in inventory store: %w",
+			err,
+		)
+	}
+
+	return lines, nil
+}
+
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in inventory store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in inventory store: %w", err)
+	}
+
+	return nil
+}
+
+// applyAllocations takes committed stock out of the locations it ships from
+// and records where it went, adding to what backorders of the reservation
+// already took from a location.
+func applyAllocations(ctx context.Context, tx *sql.Tx, reservationID uuid.UUID, allocations []*Allocation) error {
+	sorted := slices.Clone(allocations)
+	slices.SortFunc(sorted, func(a, b *Allocation) int {
+		if c := slices.Compare(a.VariantID[:], b.VariantID[:]); c != 0 {
+			return c
+		}
+
+		return slices.Compare(a.LocationID[:], b.LocationID[:])
+	})
+
+	for _, allocation := range sorted {
+		result, err := tx.ExecContext(
+			ctx,
+			"UPDATE location_stock SET quantity = quantity - $1, updated_at = NOW() WHERE location_id = $2 AND variant_id = $3 AND quantity >= $1",
+			allocation.Quantity,
+			allocation.LocationID,
+			allocation.VariantID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update location stock in inventory store: %w", err)
+		}
+
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count updated location stock in inventory store: %w", err)
+		}
+
+		if updated == 0 {
+			return servererrors.ErrInsufficientStock
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO reservation_allocations(reservation_id, variant_id, location_id, quantity) VALUES($1, $2, $3, $4)
+			ON CONFLICT (reservation_id, variant_id, location_id) DO UPDATE SET quantity = reservation_allocations.quantity + EXCLUDED.quantity`,
+			reservationID,
+			allocation.VariantID,
+			allocation.LocationID,
+			allocation.Quantity,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert reservation allocation in inventory store: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// commitMovements returns the sales a committed reservation records, one per
+// location a line ships from and one for the part of a line of a variant not
+// kept at locations.
+func commitMovements(reservationID uuid.UUID, lines []*ReservationLine, allocations []*Allocation) []*stockledger.Movement {
+	movements := make([]*stockledger.Movement, 0, len(lines)+len(allocations))
+	for _, line := range sortedLines(lines) {
+		unallocated := line.Quantity
+		for _, allocation := range allocations {
+			if allocation.VariantID != line.VariantID {
+				continue
+			}
+
+			locationID := allocation.LocationID
+			movements = append(movements, &stockledger.Movement{
+				VariantID:  line.VariantID,
+				LocationID: &locationID,
+				Quantity:   -allocation.Quantity,
+				Reason:     stockledger.ReasonSale,
+				Reference:  reservationID.String(),
+			})
+			unallocated -= allocation.Quantity
+		}
+
+		if unallocated > 0 {
+			movements = append(movements, &stockledger.Movement{
+				VariantID: line.VariantID,
+				Quantity:  -unallocated,
+				Reason:    stockledger.ReasonSale,
+				Reference: reservationID.String(),
+			})
+		}
+	}
+
+	return movements
+}
+
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
+// inStockLines returns the part of each line held from the stock on hand.
+func inStockLines(lines []*ReservationLine) []*ReservationLine {
+	inStock := make([]*ReservationLine, 0, len(lines))
+	for _, line := range lines {
+		if line.inStock() > 0 {
+			inStock = append(inStock, &ReservationLine{VariantID: line.VariantID, SKU: line.SKU, Quantity: line.inStock()})
+		}
+	}
+
+	return inStock
+}
+
+// sortedLines returns the lines in variant id order.
+func sortedLines(lines []*ReservationLine) []*ReservationLine {
+	sorted := slices.Clone(lines)
+	slices.SortFunc(sorted, func(a, b *ReservationLine) int {
+		return slices.Compare(a.VariantID[:], b.VariantID[:])
+	})
+
+	return sorted
+}
@@ -0,0 +1,23 @@
+package media
+
+import "github.com/google/uuid"
+
+// Requests
+
+// UploadMediaRequest holds the form fields sent alongside the file.
+type UploadMediaRequest struct {
+	AltText   string `validate:"max=255"`
+	VariantID *uuid.UUID
+}
+
+// UpdateMediaRequest only changes the fields that are set, RemoveVariant
+// makes the image apply to the whole product again.
+type UpdateMediaRequest struct {
+	AltText       *string    `json:"altText" validate:"omitempty,max=255"`
+	VariantID     *uuid.UUID `json:"variantId"`
+	RemoveVariant bool       `json:"removeVariant"`
+}
+
+type ReorderMediaRequest struct {
+	MediaIDs []uuid.UUID `json:"mediaIds" validate:"required,min=1,unique"`
+}
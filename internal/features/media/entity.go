@@ -0,0 +1,41 @@
+package media
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ThumbnailStatusPending = "pending"
+	ThumbnailStatusReady   = "ready"
+	ThumbnailStatusFailed  = "failed"
+)
+
+// Media is an image of a product, optionally shown for a single variant.
+type Media struct {
+	MediaID         uuid.UUID    `json:"media_id"`
+	ProductID       uuid.UUID    `json:"product_id"`
+	VariantID       *uuid.UUID   `json:"variant_id"`
+	BlobKey         string       `json:"-"`
+	ContentType     string       `json:"content_type"`
+	SizeBytes       int64        `json:"size_bytes"`
+	Width           int          `json:"width"`
+	Height          int          `json:"height"`
+	AltText         string       `json:"alt_text"`
+	Position        int          `json:"position"`
+	ThumbnailStatus string       `json:"thumbnail_status"`
+	Thumbnails      []*Thumbnail `json:"thumbnails"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// Thumbnail is a resized copy of a media image fitting within its size box.
+type Thumbnail struct {
+	MediaID     uuid.UUID `json:"-"`
+	Size        string    `json:"size"`
+	BlobKey     string    `json:"-"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+}
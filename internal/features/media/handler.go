@@ -0,0 +1,447 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	uploadMedia(ctx context.Context, productID uuid.UUID, payload *UploadMediaRequest, data []byte) (*Media, error)
+	updateMedia(ctx context.Context, productID uuid.UUID, mediaID uuid.UUID, payload *UpdateMediaRequest) (*Media, error)
+	reorderMedia(ctx context.Context, productID uuid.UUID, payload *ReorderMediaRequest) ([]*Media, error)
+	deleteMedia(ctx context.Context, productID uuid.UUID, mediaID uuid.UUID) error
+	listMedia(ctx context.Context, productID uuid.UUID, includeUnpublished bool) ([]*Media, error)
+	openMedia(ctx context.Context, mediaID uuid.UUID, size string) (io.ReadCloser, string, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+const (
+	// multipartOverhead leaves room for the form fields and boundaries on
+	// top of the file itself
+	multipartOverhead = 1 << 20
+	// multipartMemory is how much of a form is buffered in memory before it
+	// spills into temporary files
+	multipartMemory = 1 << 20
+)
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	router.Get(
+		"/products/{productID}/media",
+		handlerutils.MakeHandler(h.listMediaHandler(false)),
+	)
+	router.Get(
+		"/media/{mediaID}",
+		handlerutils.MakeHandler(h.serveMediaHandler),
+	)
+	router.Get(
+		"/media/{mediaID}/thumbnails/{size}",
+		handlerutils.MakeHandler(h.serveMediaHandler),
+	)
+}
+
+// RegisterAdminRoutes registers the media management routes relative to the
+// admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/products/{productID}/media",
+		handlerutils.MakeHandler(h.listMediaHandler(true)),
+	)
+	authenticated.Post(
+		"/products/{productID}/media",
+		handlerutils.MakeHandler(h.uploadMediaHandler),
+	)
+	authenticated.Post(
+		"/products/{productID}/media/reorder",
+		handlerutils.MakeHandler(h.reorderMediaHandler),
+	)
+	authenticated.Patch(
+		"/products/{productID}/media/{mediaID}",
+		handlerutils.MakeHandler(h.updateMediaHandler),
+	)
+	authenticated.Delete(
+		"/products/{productID}/media/{mediaID}",
+		handlerutils.MakeHandler(h.deleteMediaHandler),
+	)
+}
+
+// uploadMediaHandler accepts a multipart form with the image in "file" and
+// the optional "altText" and "variantId" fields.
+func (h *handler) uploadMediaHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+multipartOverhead)
+	defer r.Body.Close()
+
+	if err = r.ParseMultipartForm(multipartMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return servererrors.New(
+				http.StatusRequestEntityTooLarge,
+				servererrors.ErrFileTooLarge.Error(),
+				nil,
+			)
+		}
+
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes+1))
+	if err != nil {
+		return err
+	}
+
+	payload := &UploadMediaRequest{
+		AltText: r.FormValue("altText"),
+	}
+
+	if value := r.FormValue("variantId"); value != "" {
+		variantID, err := uuid.Parse(value)
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+		payload.VariantID = &variantID
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	media, err := h.service.uploadMedia(ctx, productID, payload, data)
+	if err != nil {
+		return mediaError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"media uploaded",
+		media,
+	)
+}
+
+func (h *handler) updateMediaHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *UpdateMediaRequest
+	var err error
+	defer r.Body.Close()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	mediaID, err := uuid.Parse(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	media, err := h.service.updateMedia(ctx, productID, mediaID, payload)
+	if err != nil {
+		return mediaError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"media updated",
+		media,
+	)
+}
+
+func (h *handler) reorderMediaHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *ReorderMediaRequest
+	var err error
+	defer r.Body.Close()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	media, err := h.service.reorderMedia(ctx, productID, payload)
+	if err != nil {
+		return mediaError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"media reordered",
+		media,
+	)
+}
+
+func (h *handler) deleteMediaHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	mediaID, err := uuid.Parse(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteMedia(ctx, productID, mediaID); err != nil {
+		return mediaError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"media deleted",
+		nil,
+	)
+}
+
+func (h *handler) listMediaHandler(includeUnpublished bool) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		media, err := h.service.listMedia(ctx, productID, includeUnpublished)
+		if err != nil {
+			return mediaError(err)
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"media found",
+			media,
+		)
+	}
+}
+
+// serveMediaHandler streams the original image, or one of its thumbnails
+// when the route names a size.
+func (h *handler) serveMediaHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	mediaID, err := uuid.Parse(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	blob, contentType, err := h.service.openMedia(ctx, mediaID, chi.URLParam(r, "size"))
+	if err != nil {
+		return mediaError(err)
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = io.Copy(w, blob); err != nil {
+		// the status is already written, all that is left is to log it
+		log.Println(err)
+	}
+
+	return nil
+}
+
+// mediaError maps the errors shared by the media handlers to responses.
+func mediaError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrProductNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrProductNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrMediaNotFound),
+		errors.Is(err, blobstore.ErrBlobNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrMediaNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrVariantNotForProduct):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrVariantNotForProduct.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidMediaOrder):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidMediaOrder.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidImage):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidImage.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrFileTooLarge):
+		return servererrors.New(
+			http.StatusRequestEntityTooLarge,
+			servererrors.ErrFileTooLarge.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrUnsupportedMediaType):
+		return servererrors.New(
+			http.StatusUnsupportedMediaType,
+			servererrors.ErrUnsupportedMediaType.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
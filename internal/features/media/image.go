@@ -0,0 +1,113 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/image/draw"
+
+	// registers the webp decoder with image.Decode
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	maxUploadBytes = 10 << 20 // 10MB
+	// maxDimension bounds either side of an image and maxPixels their
+	// product, both are checked from the header before anything is decoded
+	maxDimension = 8000
+	maxPixels    = 40_000_000
+	minDimension = 16
+
+	jpegQuality = 85
+)
+
+// allowedContentTypes are the sniffed types accepted for upload.
+var allowedContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+type thumbnailSize struct {
+	Name string
+	Box  int // the thumbnail fits within a Box x Box square
+}
+
+var thumbnailSizes = []thumbnailSize{
+	{Name: "small", Box: 160},
+	{Name: "medium", Box: 480},
+	{Name: "large", Box: 1024},
+}
+
+// inspectImage sniffs the content type from the file itself, ignoring the
+// name and headers sent by the client, and reads the dimensions.
+func inspectImage(data []byte) (string, int, int, error) {
+	contentType := mimetype.Detect(data).String()
+	if !allowedContentTypes[contentType] {
+		return "", 0, 0, servererrors.ErrUnsupportedMediaType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", 0, 0, servererrors.ErrInvalidImage
+	}
+
+	if config.Width < minDimension || config.Height < minDimension ||
+		config.Width > maxDimension || config.Height > maxDimension ||
+		config.Width*config.Height > maxPixels {
+		return "", 0, 0, servererrors.ErrInvalidImage
+	}
+
+	return contentType, config.Width, config.Height, nil
+}
+
+// fitWithin scales width x height down to fit a box x box square keeping the
+// aspect ratio, images are never scaled up.
+func fitWithin(width, height, box int) (int, int) {
+	if width <= box && height <= box {
+		return width, height
+	}
+
+	if width >= height {
+		return box, max(1, height*box/width)
+	}
+
+	return max(1, width*box/height), box
+}
+
+// resize encodes a thumbnail of src fitting within box. JPEG sources stay
+// JPEG, PNG and WebP sources become PNG to keep their transparency.
+func resize(src image.Image, sourceType string, box int) ([]byte, string, int, int, error) {
+	width, height := fitWithin(src.Bounds().Dx(), src.Bounds().Dy(), box)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	contentType := "image/png"
+	var err error
+	if sourceType == "image/jpeg" {
+		contentType = "image/jpeg"
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, "", 0, 0, fmt.Errorf("failed to encode %s thumbnail: %w", contentType, err)
+	}
+
+	return buf.Bytes(), contentType, width, height, nil
+}
+
+func extension(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "jpg"
+	}
+
+	return "png"
+}
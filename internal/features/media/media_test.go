@@ -0,0 +1,374 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func newTestRouter(t *testing.T) (*chi.Mux, *mockStore, *service) {
+	t.Helper()
+
+	blobStore, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaStore := newMockMediaStore()
+	mediaService := NewService(mediaStore, blobStore)
+	mediaHandler := NewHandler(mediaService, nil)
+
+	router := chi.NewRouter()
+	router.Get(
+		"/products/{productID}/media",
+		handlerutils.MakeHandler(mediaHandler.listMediaHandler(false)),
+	)
+	router.Get(
+		"/media/{mediaID}/thumbnails/{size}",
+		handlerutils.MakeHandler(mediaHandler.serveMediaHandler),
+	)
+	router.Post(
+		"/admin/products/{productID}/media",
+		handlerutils.MakeHandler(mediaHandler.uploadMediaHandler),
+	)
+	router.Post(
+		"/admin/products/{productID}/media/reorder",
+		handlerutils.MakeHandler(mediaHandler.reorderMediaHandler),
+	)
+
+	return router, mediaStore, mediaService
+}
+
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, height/2, color.RGBA{R: 255, A: 255})
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func upload(t *testing.T, router http.Handler, productID uuid.UUID, data []byte, altText string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	// the client's name and content type are deliberately misleading, only
+	// the sniffed type counts
+	file, err := form.CreateFormFile("file", "photo.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(data)
+	form.WriteField("altText", altText)
+	form.Close()
+
+	req, err := http.NewRequest(http.MethodPost, "/admin/products/"+productID.String()+"/media", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestUploadMedia(t *testing.T) {
+	router, mediaStore, mediaService := newTestRouter(t)
+
+	productID := uuid.New()
+	mediaStore.Products[productID] = "active"
+
+	rr := upload(t, router, productID, pngImage(t, 2000, 1000), "Front view")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		Data Media `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Data.ContentType != "image/png" || resp.Data.Width != 2000 || resp.Data.Height != 1000 {
+		t.Errorf("expected a 2000x1000 image/png, got %dx%d %s", resp.Data.Width, resp.Data.Height, resp.Data.ContentType)
+	}
+
+	if resp.Data.ThumbnailStatus != ThumbnailStatusPending {
+		t.Errorf("expected thumbnails to be pending, got %s", resp.Data.ThumbnailStatus)
+	}
+
+	t.Run("should generate thumbnails keeping the aspect ratio", func(t *testing.T) {
+		mediaService.generateThumbnails(context.Background(), mediaStore.Media[resp.Data.MediaID])
+
+		media := mediaStore.Media[resp.Data.MediaID]
+		if media.ThumbnailStatus != ThumbnailStatusReady {
+			t.Fatalf("expected thumbnails to be ready, got %s", media.ThumbnailStatus)
+		}
+
+		sizes := []string{}
+		for _, thumbnail := range media.Thumbnails {
+			sizes = append(sizes, thumbnail.Size)
+
+			if thumbnail.Width != 2*thumbnail.Height {
+				t.Errorf("expected %s thumbnail to keep a 2:1 ratio, got %dx%d", thumbnail.Size, thumbnail.Width, thumbnail.Height)
+			}
+		}
+
+		if !slices.Equal(sizes, []string{"small", "medium", "large"}) {
+			t.Errorf("expected small, medium and large thumbnails, got %v", sizes)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, "/media/"+media.MediaID.String()+"/thumbnails/small", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		config, _, err := image.DecodeConfig(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if config.Width != 160 || config.Height != 80 {
+			t.Errorf("expected a 160x80 thumbnail, got %dx%d", config.Width, config.Height)
+		}
+	})
+
+	t.Run("should reject a file that is not an image", func(t *testing.T) {
+		rr := upload(t, router, productID, []byte("<html><body>not an image</body></html>"), "")
+		if rr.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("expected status code %d, got %d", http.StatusUnsupportedMediaType, rr.Code)
+		}
+	})
+
+	t.Run("should reject images over the dimension limits", func(t *testing.T) {
+		rr := upload(t, router, productID, pngImage(t, maxDimension+1, 16), "")
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+	})
+
+	t.Run("should reject files over the size limit", func(t *testing.T) {
+		rr := upload(t, router, productID, make([]byte, maxUploadBytes+multipartOverhead), "")
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status code %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+		}
+	})
+
+	t.Run("should fail for an unknown product", func(t *testing.T) {
+		rr := upload(t, router, uuid.New(), pngImage(t, 32, 32), "")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+func TestReorderMedia(t *testing.T) {
+	router, mediaStore, _ := newTestRouter(t)
+
+	productID := uuid.New()
+	mediaStore.Products[productID] = "active"
+
+	mediaIDs := []uuid.UUID{}
+	for range 3 {
+		rr := upload(t, router, productID, pngImage(t, 32, 32), "")
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+
+		var resp struct {
+			Data Media `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		mediaIDs = append(mediaIDs, resp.Data.MediaID)
+	}
+
+	reorder := func(ids []uuid.UUID) int {
+		body, _ := json.Marshal(ReorderMediaRequest{MediaIDs: ids})
+		req, _ := http.NewRequest(http.MethodPost, "/admin/products/"+productID.String()+"/media/reorder", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	t.Run("should list media in the new order", func(t *testing.T) {
+		expected := []uuid.UUID{mediaIDs[2], mediaIDs[0], mediaIDs[1]}
+		if code := reorder(expected); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, "/products/"+productID.String()+"/media", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var resp struct {
+			Data []*Media `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		got := []uuid.UUID{}
+		for _, media := range resp.Data {
+			got = append(got, media.MediaID)
+		}
+
+		if !slices.Equal(got, expected) {
+			t.Errorf("expected order %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("should reject an order missing an image", func(t *testing.T) {
+		if code := reorder(mediaIDs[:2]); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+	})
+
+	t.Run("should hide media of draft products", func(t *testing.T) {
+		mediaStore.Products[productID] = "draft"
+
+		req, _ := http.NewRequest(http.MethodGet, "/products/"+productID.String()+"/media", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+type mockStore struct {
+	Products map[uuid.UUID]string // product status by id
+	Media    map[uuid.UUID]*Media
+}
+
+func newMockMediaStore() *mockStore {
+	return &mockStore{
+		Products: make(map[uuid.UUID]string),
+		Media:    make(map[uuid.UUID]*Media),
+	}
+}
+
+func (m *mockStore) create(ctx context.Context, media *Media) error {
+	media.Position = len(m.productMedia(media.ProductID))
+	media.ThumbnailStatus = ThumbnailStatusPending
+	media.Thumbnails = []*Thumbnail{}
+	m.Media[media.MediaID] = media
+	return nil
+}
+
+func (m *mockStore) findByID(ctx context.Context, mediaID uuid.UUID) (*Media, error) {
+	media, exists := m.Media[mediaID]
+	if !exists {
+		return new(Media), nil
+	}
+
+	return media, nil
+}
+
+func (m *mockStore) findByProduct(ctx context.Context, productID uuid.UUID) ([]*Media, error) {
+	return m.productMedia(productID), nil
+}
+
+func (m *mockStore) findPendingThumbnails(ctx context.Context, limit int) ([]*Media, error) {
+	pending := []*Media{}
+	for _, media := range m.Media {
+		if media.ThumbnailStatus == ThumbnailStatusPending && len(pending) < limit {
+			pending = append(pending, media)
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *mockStore) productStatus(ctx context.Context, productID uuid.UUID) (string, error) {
+	return m.Products[productID], nil
+}
+
+func (m *mockStore) variantBelongsTo(ctx context.Context, variantID uuid.UUID, productID uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (m *mockStore) update(ctx context.Context, media *Media) error {
+	m.Media[media.MediaID] = media
+	return nil
+}
+
+func (m *mockStore) reorder(ctx context.Context, productID uuid.UUID, mediaIDs []uuid.UUID) error {
+	existing := m.productMedia(productID)
+	if len(existing) != len(mediaIDs) {
+		return servererrors.ErrInvalidMediaOrder
+	}
+
+	for i, mediaID := range mediaIDs {
+		media, ok := m.Media[mediaID]
+		if !ok || media.ProductID != productID {
+			return servererrors.ErrInvalidMediaOrder
+		}
+		media.Position = i
+	}
+
+	return nil
+}
+
+func (m *mockStore) delete(ctx context.Context, mediaID uuid.UUID) error {
+	delete(m.Media, mediaID)
+	return nil
+}
+
+func (m *mockStore) saveThumbnails(ctx context.Context, mediaID uuid.UUID, thumbnails []*Thumbnail) error {
+	m.Media[mediaID].Thumbnails = thumbnails
+	m.Media[mediaID].ThumbnailStatus = ThumbnailStatusReady
+	return nil
+}
+
+func (m *mockStore) updateThumbnailStatus(ctx context.Context, mediaID uuid.UUID, status string) error {
+	m.Media[mediaID].ThumbnailStatus = status
+	return nil
+}
+
+func (m *mockStore) productMedia(productID uuid.UUID) []*Media {
+	media := []*Media{}
+	for _, m := range m.Media {
+		if m.ProductID == productID {
+			media = append(media, m)
+		}
+	}
+
+	sort.Slice(media, func(i, j int) bool {
+		return media[i].Position < media[j].Position
+	})
+
+	return media
+}
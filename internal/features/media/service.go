@@ -0,0 +1,353 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type mediaStorer interface {
+	create(ctx context.Context, media *Media) error
+	findByID(ctx context.Context, mediaID uuid.UUID) (*Media, error)
+	findByProduct(ctx context.Context, productID uuid.UUID) ([]*Media, error)
+	findPendingThumbnails(ctx context.Context, limit int) ([]*Media, error)
+	productStatus(ctx context.Context, productID uuid.UUID) (string, error)
+	variantBelongsTo(ctx context.Context, variantID uuid.UUID, productID uuid.UUID) (bool, error)
+	update(ctx context.Context, media *Media) error
+	reorder(ctx context.Context, productID uuid.UUID, mediaIDs []uuid.UUID) error
+	delete(ctx context.Context, mediaID uuid.UUID) error
+	saveThumbnails(ctx context.Context, mediaID uuid.UUID, thumbnails []*Thumbnail) error
+	updateThumbnailStatus(ctx context.Context, mediaID uuid.UUID, status string) error
+}
+
+const (
+	// thumbnailQueueSize bounds the uploads waiting for the worker, uploads
+	// that do not fit are picked up by its periodic sweep instead
+	thumbnailQueueSize = 64
+	pendingBatchSize   = 20
+)
+
+type service struct {
+	mediaStore mediaStorer
+	blobStore  blobstore.BlobStore
+	thumbnails chan uuid.UUID
+}
+
+func NewService(mediaStore mediaStorer, blobStore blobstore.BlobStore) *service {
+	return &service{
+		mediaStore: mediaStore,
+		blobStore:  blobStore,
+		thumbnails: make(chan uuid.UUID, thumbnailQueueSize),
+	}
+}
+
+// uploadMedia stores a new product image and queues its thumbnails.
+func (s *service) uploadMedia(ctx context.Context, productID uuid.UUID, payload *UploadMediaRequest, data []byte) (*Media, error) {
+	if err := s.checkProduct(ctx, productID, true); err != nil {
+		return nil, err
+	}
+
+	if payload.VariantID != nil {
+		if err := s.checkVariant(ctx, *payload.VariantID, productID); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) > maxUploadBytes {
+		return nil, servererrors.ErrFileTooLarge
+	}
+
+	contentType, width, height, err := inspectImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaID := uuid.New()
+	media := &Media{
+		MediaID:     mediaID,
+		ProductID:   productID,
+		VariantID:   payload.VariantID,
+		BlobKey:     fmt.Sprintf("media/%s/%s/original", productID, mediaID),
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		Width:       width,
+		Height:      height,
+		AltText:     strings.TrimSpace(payload.AltText),
+	}
+
+	if err = s.blobStore.Put(ctx, media.BlobKey, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	if err = s.mediaStore.create(ctx, media); err != nil {
+		if deleteErr := s.blobStore.Delete(context.Background(), media.BlobKey); deleteErr != nil {
+			log.Println(deleteErr)
+		}
+
+		return nil, err
+	}
+
+	select {
+	case s.thumbnails <- mediaID:
+	default:
+	}
+
+	return s.mediaStore.findByID(ctx, mediaID)
+}
+
+func (s *service) updateMedia(ctx context.Context, productID uuid.UUID, mediaID uuid.UUID, payload *UpdateMediaRequest) (*Media, error) {
+	media, err := s.findMedia(ctx, productID, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.AltText != nil {
+		media.AltText = strings.TrimSpace(*payload.AltText)
+	}
+
+	switch {
+	case payload.RemoveVariant:
+		media.VariantID = nil
+	case payload.VariantID != nil:
+		if err = s.checkVariant(ctx, *payload.VariantID, productID); err != nil {
+			return nil, err
+		}
+		media.VariantID = payload.VariantID
+	}
+
+	if err = s.mediaStore.update(ctx, media); err != nil {
+		return nil, err
+	}
+
+	return s.mediaStore.findByID(ctx, mediaID)
+}
+
+func (s *service) reorderMedia(ctx context.Context, productID uuid.UUID, payload *ReorderMediaRequest) ([]*Media, error) {
+	if err := s.checkProduct(ctx, productID, true); err != nil {
+		return nil, err
+	}
+
+	if err := s.mediaStore.reorder(ctx, productID, payload.MediaIDs); err != nil {
+		return nil, err
+	}
+
+	return s.mediaStore.findByProduct(ctx, productID)
+}
+
+// deleteMedia removes the media first so it stops being served, failing to
+// clean up its blobs afterwards only leaves orphaned files behind.
+func (s *service) deleteMedia(ctx context.Context, productID uuid.UUID, mediaID uuid.UUID) error {
+	media, err := s.findMedia(ctx, productID, mediaID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.mediaStore.delete(ctx, mediaID); err != nil {
+		return err
+	}
+
+	keys := []string{media.BlobKey}
+	for _, thumbnail := range media.Thumbnails {
+		keys = append(keys, thumbnail.BlobKey)
+	}
+
+	for _, key := range keys {
+		if err = s.blobStore.Delete(ctx, key); err != nil {
+			log.Println(err)
+		}
+	}
+
+	return nil
+}
+
+// listMedia returns the images of a product in display order, images of
+// products not on sale are only returned when includeUnpublished is set.
+func (s *service) listMedia(ctx context.Context, productID uuid.UUID, includeUnpublished bool) ([]*Media, error) {
+	if err := s.checkProduct(ctx, productID, includeUnpublished); err != nil {
+		return nil, err
+	}
+
+	return s.mediaStore.findByProduct(ctx, productID)
+}
+
+// openMedia opens the original file of a media, or the thumbnail named size
+// when it is set.
+func (s *service) openMedia(ctx context.Context, mediaID uuid.UUID, size string) (io.ReadCloser, string, error) {
+	media, err := s.mediaStore.findByID(ctx, mediaID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if media.MediaID == uuid.Nil {
+		return nil, "", servererrors.ErrMediaNotFound
+	}
+
+	status, err := s.mediaStore.productStatus(ctx, media.ProductID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if status != product.StatusActive {
+		return nil, "", servererrors.ErrMediaNotFound
+	}
+
+	key, contentType := media.BlobKey, media.ContentType
+	if size != "" {
+		key = ""
+		for _, thumbnail := range media.Thumbnails {
+			if thumbnail.Size == size {
+				key, contentType = thumbnail.BlobKey, thumbnail.ContentType
+			}
+		}
+
+		if key == "" {
+			return nil, "", servererrors.ErrMediaNotFound
+		}
+	}
+
+	blob, err := s.blobStore.Get(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return blob, contentType, nil
+}
+
+// RunThumbnailWorker generates thumbnails for new uploads as they are queued
+// and, every interval, for any media still pending e.g. after a restart. It
+// blocks until ctx is done.
+func (s *service) RunThumbnailWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case mediaID := <-s.thumbnails:
+			media, err := s.mediaStore.findByID(ctx, mediaID)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			if media.MediaID != uuid.Nil && media.ThumbnailStatus == ThumbnailStatusPending {
+				s.generateThumbnails(ctx, media)
+			}
+		case <-ticker.C:
+			pending, err := s.mediaStore.findPendingThumbnails(ctx, pendingBatchSize)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			for _, media := range pending {
+				s.generateThumbnails(ctx, media)
+			}
+		}
+	}
+}
+
+// generateThumbnails resizes the original into every thumbnailSizes entry, a
+// media that can not be processed is marked failed rather than retried.
+func (s *service) generateThumbnails(ctx context.Context, media *Media) {
+	thumbnails, err := s.makeThumbnails(ctx, media)
+	if err == nil {
+		err = s.mediaStore.saveThumbnails(ctx, media.MediaID, thumbnails)
+		if err == nil {
+			return
+		}
+	}
+
+	log.Printf("failed to generate thumbnails for media %s: %v\n", media.MediaID, err)
+	if err = s.mediaStore.updateThumbnailStatus(ctx, media.MediaID, ThumbnailStatusFailed); err != nil {
+		log.Println(err)
+	}
+}
+
+func (s *service) makeThumbnails(ctx context.Context, media *Media) ([]*Thumbnail, error) {
+	blob, err := s.blobStore.Get(ctx, media.BlobKey)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+
+	src, _, err := image.Decode(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode media: %w", err)
+	}
+
+	thumbnails := []*Thumbnail{}
+	for _, size := range thumbnailSizes {
+		data, contentType, width, height, err := resize(src, media.ContentType, size.Box)
+		if err != nil {
+			return nil, err
+		}
+
+		thumbnail := &Thumbnail{
+			MediaID:     media.MediaID,
+			Size:        size.Name,
+			BlobKey:     fmt.Sprintf("media/%s/%s/%s.%s", media.ProductID, media.MediaID, size.Name, extension(contentType)),
+			ContentType: contentType,
+			Width:       width,
+			Height:      height,
+		}
+
+		if err = s.blobStore.Put(ctx, thumbnail.BlobKey, bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	return thumbnails, nil
+}
+
+func (s *service) checkProduct(ctx context.Context, productID uuid.UUID, includeUnpublished bool) error {
+	status, err := s.mediaStore.productStatus(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if status == "" || (status != product.StatusActive && !includeUnpublished) {
+		return servererrors.ErrProductNotFound
+	}
+
+	return nil
+}
+
+func (s *service) checkVariant(ctx context.Context, variantID uuid.UUID, productID uuid.UUID) error {
+	ok, err := s.mediaStore.variantBelongsTo(ctx, variantID, productID)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return servererrors.ErrVariantNotForProduct
+	}
+
+	return nil
+}
+
+func (s *service) findMedia(ctx context.Context, productID uuid.UUID, mediaID uuid.UUID) (*Media, error) {
+	media, err := s.mediaStore.findByID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	if media.MediaID == uuid.Nil || media.ProductID != productID {
+		return nil, servererrors.ErrMediaNotFound
+	}
+
+	return media, nil
+}
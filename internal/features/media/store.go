@@ -0,0 +1,420 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const mediaFields = "media_id, product_id, variant_id, blob_key, content_type, size_bytes, width, height, alt_text, position, thumbnail_status, created_at, updated_at"
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+// create appends the media after the existing images of its product.
+func (s *store) create(ctx context.Context, media *Media) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO product_media(media_id, product_id, variant_id, blob_key, content_type, size_bytes, width, height, alt_text, position)
+		SELECT $1, $2::uuid, $3, $4, $5, $6, $7, $8, $9, COALESCE(MAX(position) + 1, 0) FROM product_media WHERE product_id = $2::uuid`,
+		media.MediaID,
+		media.ProductID,
+		media.VariantID,
+		media.BlobKey,
+		media.ContentType,
+		media.SizeBytes,
+		media.Width,
+		media.Height,
+		media.AltText,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to insert new media in media store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findByID(ctx context.Context, mediaID uuid.UUID) (*Media, error) {
+	media, err := s.getMediaWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM product_media WHERE media_id = $1", mediaFields),
+		mediaID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find media by id in media store: %w",
+			err,
+		)
+	}
+
+	if len(media) == 0 {
+		return new(Media), nil
+	}
+
+	return media[0], nil
+}
+
+func (s *store) findByProduct(ctx context.Context, productID uuid.UUID) ([]*Media, error) {
+	media, err := s.getMediaWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM product_media WHERE product_id = $1 ORDER BY position ASC, created_at ASC", mediaFields),
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find media by product in media store: %w",
+			err,
+		)
+	}
+
+	return media, nil
+}
+
+// findPendingThumbnails returns the oldest media still waiting for their
+// thumbnails.
+func (s *store) findPendingThumbnails(ctx context.Context, limit int) ([]*Media, error) {
+	media, err := s.getMediaWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM product_media WHERE thumbnail_status = $1 ORDER BY created_at ASC LIMIT $2", mediaFields),
+		ThumbnailStatusPending,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find pending thumbnails in media store: %w",
+			err,
+		)
+	}
+
+	return media, nil
+}
+
+// productStatus returns the status of a product, or an empty string when it
+// does not exist.
+func (s *store) productStatus(ctx context.Context, productID uuid.UUID) (string, error) {
+	var status string
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT status FROM products WHERE product_id = $1",
+		productID,
+	).Scan(&status)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf(
+			"failed to find product status in media store: %w",
+			err,
+		)
+	}
+
+	return status, nil
+}
+
+func (s *store) variantBelongsTo(ctx context.Context, variantID uuid.UUID, productID uuid.UUID) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM product_variants WHERE variant_id = $1 AND product_id = $2)",
+		variantID,
+		productID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to check variant in media store: %w",
+			err,
+		)
+	}
+
+	return exists, nil
+}
+
+func (s *store) update(ctx context.Context, media *Media) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE product_media SET alt_text = $1, variant_id = $2, updated_at = NOW() WHERE media_id = $3",
+		media.AltText,
+		media.VariantID,
+		media.MediaID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update media in media store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// reorder sets the positions of a product's images to the order of mediaIDs,
+// which must list each of them exactly once.
+func (s *store) reorder(ctx context.Context, productID uuid.UUID, mediaIDs []uuid.UUID) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(
+			ctx,
+			"SELECT media_id FROM product_media WHERE product_id = $1 FOR UPDATE",
+			productID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to find product media in media store: %w", err)
+		}
+
+		existing := []uuid.UUID{}
+		for rows.Next() {
+			var mediaID uuid.UUID
+			if err = rows.Scan(&mediaID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan media in media store: %w", err)
+			}
+			existing = append(existing, mediaID)
+		}
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate media in media store: %w", err)
+		}
+
+		if len(existing) != len(mediaIDs) {
+			return servererrors.ErrInvalidMediaOrder
+		}
+
+		for _, mediaID := range mediaIDs {
+			if !slices.Contains(existing, mediaID) {
+				return servererrors.ErrInvalidMediaOrder
+			}
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE product_media m SET position = o.position - 1, updated_at = NOW() FROM UNNEST($1::uuid[]) WITH ORDINALITY AS o(media_id, position) WHERE m.media_id = o.media_id",
+			pq.Array(mediaIDs),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to reorder media in media store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// delete removes the media and its thumbnail rows, the blobs are left to the
+// caller.
+func (s *store) delete(ctx context.Context, mediaID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM product_media WHERE media_id = $1",
+		mediaID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to delete media in media store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// saveThumbnails replaces the thumbnails of a media and marks them ready.
+func (s *store) saveThumbnails(ctx context.Context, mediaID uuid.UUID, thumbnails []*Thumbnail) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM media_thumbnails WHERE media_id = $1", mediaID); err != nil {
+			return fmt.Errorf("failed to delete thumbnails in media store: %w", err)
+		}
+
+		for _, thumbnail := range thumbnails {
+			_, err := tx.ExecContext(
+				ctx,
+				"INSERT INTO media_thumbnails(media_id, size, blob_key, content_type, width, height) VALUES($1, $2, $3, $4, $5, $6)",
+				mediaID,
+				thumbnail.Size,
+				thumbnail.BlobKey,
+				thumbnail.ContentType,
+				thumbnail.Width,
+				thumbnail.Height,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert thumbnail in media store: %w", err)
+			}
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			"UPDATE product_media SET thumbnail_status = $1, updated_at = NOW() WHERE media_id = $2",
+			ThumbnailStatusReady,
+			mediaID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update thumbnail status in media store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *store) updateThumbnailStatus(ctx context.Context, mediaID uuid.UUID, status string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE product_media SET thumbnail_status = $1, updated_at = NOW() WHERE media_id = $2",
+		status,
+		mediaID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update thumbnail status in media store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// getMediaWithContext runs a query selecting mediaFields and loads the
+// thumbnails of every returned media.
+func (s *store) getMediaWithContext(ctx context.Context, query string, args ...any) ([]*Media, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in media store getMediaWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	media := []*Media{}
+	byID := map[uuid.UUID]*Media{}
+	mediaIDs := []uuid.UUID{}
+	for rows.Next() {
+		m := &Media{Thumbnails: []*Thumbnail{}}
+		if err = scanRowsIntoMedia(rows, m); err != nil {
+			return nil, err
+		}
+
+		media = append(media, m)
+		byID[m.MediaID] = m
+		mediaIDs = append(mediaIDs, m.MediaID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in media store: %w",
+			err,
+		)
+	}
+
+	if len(media) == 0 {
+		return media, nil
+	}
+
+	thumbnailRows, err := s.db.QueryContext(
+		ctx,
+		"SELECT media_id, size, blob_key, content_type, width, height FROM media_thumbnails WHERE media_id = ANY($1) ORDER BY width ASC",
+		pq.Array(mediaIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query thumbnails in media store: %w",
+			err,
+		)
+	}
+	defer thumbnailRows.Close()
+
+	for thumbnailRows.Next() {
+		thumbnail := new(Thumbnail)
+		err = thumbnailRows.Scan(
+			&thumbnail.MediaID,
+			&thumbnail.Size,
+			&thumbnail.BlobKey,
+			&thumbnail.ContentType,
+			&thumbnail.Width,
+			&thumbnail.Height,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into thumbnail in media store: %w",
+				err,
+			)
+		}
+
+		if m, ok := byID[thumbnail.MediaID]; ok {
+			m.Thumbnails = append(m.Thumbnails, thumbnail)
+		}
+	}
+
+	if err = thumbnailRows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate thumbnail rows in media store: %w",
+			err,
+		)
+	}
+
+	return media, nil
+}
+
+func scanRowsIntoMedia(rows *sql.Rows, media *Media) error {
+	if media == nil {
+		return errors.New(
+			"scanRowsIntoMedia err in media store",
+		)
+	}
+
+	err := rows.Scan(
+		&media.MediaID,
+		&media.ProductID,
+		&media.VariantID,
+		&media.BlobKey,
+		&media.ContentType,
+		&media.SizeBytes,
+		&media.Width,
+		&media.Height,
+		&media.AltText,
+		&media.Position,
+		&media.ThumbnailStatus,
+		&media.CreatedAt,
+		&media.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to scan row into media in media store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in media store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in media store: %w", err)
+	}
+
+	return nil
+}
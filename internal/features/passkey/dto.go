@@ -0,0 +1,55 @@
+package passkey
+
+import (
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn"
+	"github.com/google/uuid"
+)
+
+// Requests
+
+type FinishRegistrationRequest struct {
+	ChallengeID uuid.UUID                        `json:"challengeId" validate:"required"`
+	Name        string                           `json:"name" validate:"max=64"`
+	Credential  *webauthn.RegistrationCredential `json:"credential" validate:"required"`
+}
+
+type FinishLoginRequest struct {
+	ChallengeID uuid.UUID                     `json:"challengeId" validate:"required"`
+	Credential  *webauthn.AssertionCredential `json:"credential" validate:"required"`
+	UserAgent   string                        `json:"userAgent" validate:"required"`
+	ClientIP    string                        `json:"clientIP" validate:"required"`
+}
+
+type FinishReauthenticationRequest struct {
+	ChallengeID uuid.UUID                     `json:"challengeId" validate:"required"`
+	Credential  *webauthn.AssertionCredential `json:"credential" validate:"required"`
+}
+
+// Responses
+
+type BeginRegistrationResponse struct {
+	ChallengeID uuid.UUID                 `json:"challengeId"`
+	Options     *webauthn.CreationOptions `json:"publicKey"`
+}
+
+type BeginLoginResponse struct {
+	ChallengeID uuid.UUID                `json:"challengeId"`
+	Options     *webauthn.RequestOptions `json:"publicKey"`
+}
+
+type CredentialResponse struct {
+	ID         webauthn.URLEncodedBase64 `json:"id"`
+	Name       string                    `json:"name"`
+	Transports []string                  `json:"transports"`
+	Synced     bool                      `json:"synced"`
+	LastUsedAt *time.Time                `json:"lastUsedAt"`
+	CreatedAt  time.Time                 `json:"createdAt"`
+}
+
+type LoginPasskeyCookiesResponse struct {
+	AccessToken  interfaces.TokenDetails `json:"accessToken"`
+	RefreshToken interfaces.TokenDetails `json:"refreshToken"`
+}
@@ -0,0 +1,45 @@
+package passkey
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Credential is a WebAuthn public key credential registered by a user or an
+// admin.
+type Credential struct {
+	CredentialID   []byte       `json:"credential_id"`
+	EntityID       uuid.UUID    `json:"entity_id"`
+	EntityType     string       `json:"entity_type"`
+	Name           string       `json:"name"`
+	PublicKey      []byte       `json:"-"`
+	SignCount      int64        `json:"-"`
+	AAGUID         []byte       `json:"aaguid"`
+	Transports     []string     `json:"transports"`
+	BackupEligible bool         `json:"backup_eligible"`
+	BackupState    bool         `json:"backup_state"`
+	LastUsedAt     sql.NullTime `json:"last_used_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// Challenge is the server side state of a registration or login ceremony. It
+// is consumed by the finishing request.
+type Challenge struct {
+	ChallengeID uuid.UUID     `json:"challenge_id"`
+	Challenge   []byte        `json:"-"`
+	Ceremony    string        `json:"ceremony"`
+	EntityID    uuid.NullUUID `json:"entity_id"` // unknown until a login ceremony finishes
+	EntityType  string        `json:"entity_type"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// EntityProfile holds the user or admin details shown by authenticators when
+// picking a passkey.
+type EntityProfile struct {
+	Email       string
+	DisplayName string
+}
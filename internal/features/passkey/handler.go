@@ -0,0 +1,485 @@
+package passkey
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	beginRegistration(ctx context.Context, entityID uuid.UUID, entityType string) (*BeginRegistrationResponse, error)
+	finishRegistration(ctx context.Context, entityID uuid.UUID, entityType string, payload *FinishRegistrationRequest) error
+	beginLogin(ctx context.Context, entityType string) (*BeginLoginResponse, error)
+	finishLogin(ctx context.Context, entityType string, payload *FinishLoginRequest) (*LoginPasskeyCookiesResponse, error)
+	listCredentials(ctx context.Context, entityID uuid.UUID, entityType string) ([]*CredentialResponse, error)
+	deleteCredential(ctx context.Context, entityID uuid.UUID, entityType string, credentialID []byte) error
+	beginReauthentication(ctx context.Context, entityID uuid.UUID, entityType string) (*BeginLoginResponse, error)
+	finishReauthentication(ctx context.Context, entityID uuid.UUID, entityType string, payload *FinishReauthenticationRequest) (*interfaces.TokenDetails, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	h.registerEntityRoutes(router, "/passkeys", auth.EntityTypeUser)
+}
+
+// RegisterAdminRoutes registers the admin passkey routes relative to the
+// admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	h.registerEntityRoutes(router, "/passkeys", auth.EntityTypeAdmin)
+}
+
+// registerEntityRoutes registers the passkey ceremonies of one entity type
+// under prefix. Keeping the entity type in the route means an admin passkey
+// can never be used on the customer login and the other way around.
+func (h *handler) registerEntityRoutes(router chi.Router, prefix string, entityType string) {
+	router.Post(
+		prefix+"/login/begin",
+		handlerutils.MakeHandler(h.beginLoginHandler(entityType)),
+	)
+	router.Post(
+		prefix+"/login/finish",
+		handlerutils.MakeHandler(h.finishLoginHandler(entityType)),
+	)
+
+	authenticated := router.With(h.authenticator.Authenticate(entityType))
+	authenticated.Post(
+		prefix+"/register/begin",
+		handlerutils.MakeHandler(h.beginRegistrationHandler),
+	)
+	authenticated.Post(
+		prefix+"/register/finish",
+		handlerutils.MakeHandler(h.finishRegistrationHandler),
+	)
+	authenticated.Post(
+		prefix+"/reauth/begin",
+		handlerutils.MakeHandler(h.beginReauthenticationHandler),
+	)
+	authenticated.Post(
+		prefix+"/reauth/finish",
+		handlerutils.MakeHandler(h.finishReauthenticationHandler),
+	)
+	authenticated.Get(
+		prefix,
+		handlerutils.MakeHandler(h.listCredentialsHandler),
+	)
+	authenticated.Delete(
+		prefix+"/{credentialID}",
+		handlerutils.MakeHandler(h.deleteCredentialHandler),
+	)
+}
+
+func (h *handler) beginRegistrationHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	entityID, entityType, err := entityFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.service.beginRegistration(ctx, entityID, entityType)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrUnauthorized):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrUnauthorized.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"passkey registration started",
+		resp,
+	)
+}
+
+func (h *handler) finishRegistrationHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *FinishRegistrationRequest
+	var err error
+	defer r.Body.Close()
+
+	entityID, entityType, err := entityFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	if err = h.service.finishRegistration(ctx, entityID, entityType, payload); err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrPasskeyChallengeNotFound):
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrPasskeyChallengeNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrInvalidPasskey):
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidPasskey.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrPasskeyAlreadyRegistered):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrPasskeyAlreadyRegistered.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"passkey registered",
+		nil,
+	)
+}
+
+func (h *handler) beginLoginHandler(entityType string) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		resp, err := h.service.beginLogin(ctx, entityType)
+		if err != nil {
+			return err
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"passkey login started",
+			resp,
+		)
+	}
+}
+
+func (h *handler) finishLoginHandler(entityType string) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		var payload *FinishLoginRequest
+		var err error
+		defer r.Body.Close()
+
+		if err = handlerutils.ParseJSON(r, &payload); err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		payload.ClientIP = handlerutils.GetClientIP(r)
+		payload.UserAgent = r.UserAgent()
+
+		if err = validate.StructFields(payload); err != nil {
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrValidationFailed.Error(),
+				err,
+			)
+		}
+
+		loginResponse, err := h.service.finishLogin(ctx, entityType, payload)
+		if err != nil {
+			switch {
+			case errors.Is(err, servererrors.ErrPasskeyChallengeNotFound):
+				return servererrors.New(
+					http.StatusUnauthorized,
+					servererrors.ErrPasskeyChallengeNotFound.Error(),
+					nil,
+				)
+			case errors.Is(err, servererrors.ErrInvalidPasskey):
+				return servererrors.New(
+					http.StatusUnauthorized,
+					servererrors.ErrInvalidPasskey.Error(),
+					nil,
+				)
+			default:
+				return err
+			}
+		}
+
+		cookies := []handlerutils.Cookie{
+			{
+				Name:    "accessToken",
+				Value:   loginResponse.AccessToken.Value,
+				Expires: loginResponse.AccessToken.Expires,
+			},
+			{
+				Name:    "refreshToken",
+				Value:   loginResponse.RefreshToken.Value,
+				Expires: loginResponse.RefreshToken.Expires,
+			},
+		}
+		handlerutils.SetCookies(
+			w,
+			cookies,
+		)
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusCreated,
+			"access and refresh tokens attached to cookies",
+			nil,
+		)
+	}
+}
+
+func (h *handler) listCredentialsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	entityID, entityType, err := entityFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := h.service.listCredentials(ctx, entityID, entityType)
+	if err != nil {
+		return err
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"passkeys retrieved",
+		credentials,
+	)
+}
+
+func (h *handler) deleteCredentialHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	entityID, entityType, err := entityFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(chi.URLParam(r, "credentialID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteCredential(ctx, entityID, entityType, credentialID); err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrPasskeyNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrPasskeyNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"passkey deleted",
+		nil,
+	)
+}
+
+func (h *handler) beginReauthenticationHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	entityID, entityType, err := entityFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.service.beginReauthentication(ctx, entityID, entityType)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrPasskeyNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrPasskeyNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"passkey re-authentication started",
+		resp,
+	)
+}
+
+func (h *handler) finishReauthenticationHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *FinishReauthenticationRequest
+	var err error
+	defer r.Body.Close()
+
+	entityID, entityType, err := entityFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	accessToken, err := h.service.finishReauthentication(ctx, entityID, entityType, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrPasskeyChallengeNotFound):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrPasskeyChallengeNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrInvalidPasskey):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrInvalidPasskey.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	handlerutils.SetCookies(
+		w,
+		[]handlerutils.Cookie{
+			{
+				Name:    "accessToken",
+				Value:   accessToken.Value,
+				Expires: accessToken.Expires,
+			},
+		},
+	)
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"re-authenticated, elevated access token attached to cookies",
+		nil,
+	)
+}
+
+// entityFromContext returns the authenticated entity set by the auth
+// middleware.
+func entityFromContext(ctx context.Context) (uuid.UUID, string, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, "", servererrors.New(
+			http.StatusUnauthorized,
+			servererrors.ErrUnauthorized.Error(),
+			nil,
+		)
+	}
+
+	entityID, err := uuid.Parse(claims.EntityID)
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("invalid entity id in access token: %w", err)
+	}
+
+	return entityID, claims.EntityType, nil
+}
@@ -0,0 +1,216 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn/webauthntest"
+	"github.com/google/uuid"
+)
+
+const origin = "http://localhost:3000"
+
+func TestPasskeyCeremonies(t *testing.T) {
+	ctx := context.Background()
+	store := newMockPasskeyStore()
+	sessions := &mockSessionService{}
+	passkeyService := NewService(
+		store,
+		sessions,
+		webauthn.NewRelyingParty("localhost", "Yellow Pines", []string{origin}),
+	)
+
+	adminID := uuid.New()
+	store.profiles[adminID] = &EntityProfile{Email: "admin@yellowpines.com", DisplayName: "Ada Admin"}
+	authenticator := webauthntest.New(origin)
+
+	registration, err := passkeyService.beginRegistration(ctx, adminID, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if registration.Options.AuthenticatorSelection.UserVerification != webauthn.VerificationRequired {
+		t.Errorf("expected admins to require user verification")
+	}
+
+	err = passkeyService.finishRegistration(ctx, adminID, "admin", &FinishRegistrationRequest{
+		ChallengeID: registration.ChallengeID,
+		Credential:  authenticator.Register(registration.Options),
+	})
+	if err != nil {
+		t.Fatalf("expected registration to succeed, got %v", err)
+	}
+
+	t.Run("should log the admin in with the passkey", func(t *testing.T) {
+		login, err := passkeyService.beginLogin(ctx, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = passkeyService.finishLogin(ctx, "admin", &FinishLoginRequest{
+			ChallengeID: login.ChallengeID,
+			Credential:  authenticator.Login(login.Options),
+		})
+		if err != nil {
+			t.Fatalf("expected login to succeed, got %v", err)
+		}
+
+		if sessions.lastLogin == nil || sessions.lastLogin.EntityID != adminID || sessions.lastLogin.EntityType != "admin" {
+			t.Errorf("expected an admin session to be created for the passkey owner")
+		}
+	})
+
+	t.Run("should not accept an admin passkey on the customer login", func(t *testing.T) {
+		login, err := passkeyService.beginLogin(ctx, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = passkeyService.finishLogin(ctx, "user", &FinishLoginRequest{
+			ChallengeID: login.ChallengeID,
+			Credential:  authenticator.Login(login.Options),
+		})
+		if !errors.Is(err, servererrors.ErrInvalidPasskey) {
+			t.Errorf("expected %v, got %v", servererrors.ErrInvalidPasskey, err)
+		}
+	})
+
+	t.Run("should reject a replayed challenge", func(t *testing.T) {
+		login, err := passkeyService.beginLogin(ctx, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		payload := &FinishLoginRequest{
+			ChallengeID: login.ChallengeID,
+			Credential:  authenticator.Login(login.Options),
+		}
+
+		if _, err := passkeyService.finishLogin(ctx, "admin", payload); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = passkeyService.finishLogin(ctx, "admin", payload)
+		if !errors.Is(err, servererrors.ErrPasskeyChallengeNotFound) {
+			t.Errorf("expected %v, got %v", servererrors.ErrPasskeyChallengeNotFound, err)
+		}
+	})
+
+	t.Run("should reject a cloned authenticator", func(t *testing.T) {
+		login, err := passkeyService.beginLogin(ctx, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		authenticator.SignCount = 0 // the clone lags behind the original
+
+		_, err = passkeyService.finishLogin(ctx, "admin", &FinishLoginRequest{
+			ChallengeID: login.ChallengeID,
+			Credential:  authenticator.Login(login.Options),
+		})
+		if !errors.Is(err, servererrors.ErrInvalidPasskey) {
+			t.Errorf("expected %v, got %v", servererrors.ErrInvalidPasskey, err)
+		}
+	})
+}
+
+type mockSessionService struct {
+	lastLogin *interfaces.LoginEntityRequest
+}
+
+func (m *mockSessionService) LoginEntity(ctx context.Context, payload *interfaces.LoginEntityRequest) (*interfaces.LoginEntityCookiesResponse, error) {
+	m.lastLogin = payload
+	return &interfaces.LoginEntityCookiesResponse{}, nil
+}
+
+func (m *mockSessionService) IssueElevatedAccessToken(ctx context.Context, entityID uuid.UUID, entityType string, authMethods []string) (*interfaces.TokenDetails, error) {
+	return &interfaces.TokenDetails{}, nil
+}
+
+type mockPasskeyStore struct {
+	credentials map[string]*Credential
+	challenges  map[uuid.UUID]*Challenge
+	profiles    map[uuid.UUID]*EntityProfile
+}
+
+func newMockPasskeyStore() *mockPasskeyStore {
+	return &mockPasskeyStore{
+		credentials: make(map[string]*Credential),
+		challenges:  make(map[uuid.UUID]*Challenge),
+		profiles:    make(map[uuid.UUID]*EntityProfile),
+	}
+}
+
+func (m *mockPasskeyStore) createCredential(ctx context.Context, credential *Credential) error {
+	credential.CreatedAt = time.Now()
+	m.credentials[string(credential.CredentialID)] = credential
+	return nil
+}
+
+func (m *mockPasskeyStore) findCredentialByID(ctx context.Context, credentialID []byte) (*Credential, error) {
+	if credential, ok := m.credentials[string(credentialID)]; ok {
+		copied := *credential
+		return &copied, nil
+	}
+	return new(Credential), nil
+}
+
+func (m *mockPasskeyStore) findCredentialsByEntity(ctx context.Context, entityID uuid.UUID, entityType string) ([]*Credential, error) {
+	credentials := []*Credential{}
+	for _, credential := range m.credentials {
+		if credential.EntityID == entityID && credential.EntityType == entityType {
+			credentials = append(credentials, credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (m *mockPasskeyStore) updateCredentialUsage(ctx context.Context, credentialID []byte, previousSignCount int64, credential *Credential) (bool, error) {
+	stored, ok := m.credentials[string(credentialID)]
+	if !ok || stored.SignCount != previousSignCount {
+		return false, nil
+	}
+	stored.SignCount = credential.SignCount
+	return true, nil
+}
+
+func (m *mockPasskeyStore) deleteCredential(ctx context.Context, credentialID []byte, entityID uuid.UUID, entityType string) (bool, error) {
+	for key, credential := range m.credentials {
+		if bytes.Equal(credential.CredentialID, credentialID) && credential.EntityID == entityID {
+			delete(m.credentials, key)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockPasskeyStore) createChallenge(ctx context.Context, challenge *Challenge) error {
+	m.challenges[challenge.ChallengeID] = challenge
+	return nil
+}
+
+func (m *mockPasskeyStore) consumeChallenge(ctx context.Context, challengeID uuid.UUID, ceremony string) (*Challenge, error) {
+	challenge, ok := m.challenges[challengeID]
+	if !ok || challenge.Ceremony != ceremony {
+		return new(Challenge), nil
+	}
+	delete(m.challenges, challengeID)
+	return challenge, nil
+}
+
+func (m *mockPasskeyStore) deleteExpiredChallenges(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPasskeyStore) findEntityProfile(ctx context.Context, entityID uuid.UUID, entityType string) (*EntityProfile, error) {
+	if profile, ok := m.profiles[entityID]; ok {
+		return profile, nil
+	}
+	return new(EntityProfile), nil
+}
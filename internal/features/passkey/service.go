@@ -0,0 +1,439 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	ceremonyRegistration     = "registration"
+	ceremonyLogin            = "login"
+	ceremonyReauthentication = "reauthentication"
+
+	defaultCredentialName = "Passkey"
+)
+
+type passkeyStorer interface {
+	createCredential(ctx context.Context, credential *Credential) error
+	findCredentialByID(ctx context.Context, credentialID []byte) (*Credential, error)
+	findCredentialsByEntity(ctx context.Context, entityID uuid.UUID, entityType string) ([]*Credential, error)
+	updateCredentialUsage(ctx context.Context, credentialID []byte, previousSignCount int64, credential *Credential) (bool, error)
+	deleteCredential(ctx context.Context, credentialID []byte, entityID uuid.UUID, entityType string) (bool, error)
+	createChallenge(ctx context.Context, challenge *Challenge) error
+	consumeChallenge(ctx context.Context, challengeID uuid.UUID, ceremony string) (*Challenge, error)
+	deleteExpiredChallenges(ctx context.Context) error
+	findEntityProfile(ctx context.Context, entityID uuid.UUID, entityType string) (*EntityProfile, error)
+}
+
+type sessionServicer interface {
+	LoginEntity(ctx context.Context, payload *interfaces.LoginEntityRequest) (*interfaces.LoginEntityCookiesResponse, error)
+	IssueElevatedAccessToken(ctx context.Context, entityID uuid.UUID, entityType string, authMethods []string) (*interfaces.TokenDetails, error)
+}
+
+type service struct {
+	store          passkeyStorer
+	sessionService sessionServicer
+	relyingParty   *webauthn.RelyingParty
+}
+
+func NewService(store passkeyStorer, sessionService sessionServicer, relyingParty *webauthn.RelyingParty) *service {
+	return &service{
+		store:          store,
+		sessionService: sessionService,
+		relyingParty:   relyingParty,
+	}
+}
+
+// PurgeExpiredChallenges deletes abandoned ceremony challenges every interval
+// until ctx is done.
+func (s *service) PurgeExpiredChallenges(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.store.deleteExpiredChallenges(ctx); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+func (s *service) beginRegistration(ctx context.Context, entityID uuid.UUID, entityType string) (*BeginRegistrationResponse, error) {
+	profile, err := s.store.findEntityProfile(ctx, entityID, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.Email == "" {
+		return nil, servererrors.ErrUnauthorized
+	}
+
+	existing, err := s.store.findCredentialsByEntity(ctx, entityID, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	excludeIDs := make([][]byte, 0, len(existing))
+	for _, credential := range existing {
+		excludeIDs = append(excludeIDs, credential.CredentialID)
+	}
+
+	challenge, err := s.newChallenge(
+		ctx,
+		ceremonyRegistration,
+		uuid.NullUUID{UUID: entityID, Valid: true},
+		entityType,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BeginRegistrationResponse{
+		ChallengeID: challenge.ChallengeID,
+		Options: s.relyingParty.NewCreationOptions(
+			challenge.Challenge,
+			webauthn.UserEntity{
+				ID:          entityID[:],
+				Name:        profile.Email,
+				DisplayName: profile.DisplayName,
+			},
+			excludeIDs,
+			userVerificationFor(entityType),
+		),
+	}, nil
+}
+
+func (s *service) finishRegistration(ctx context.Context, entityID uuid.UUID, entityType string, payload *FinishRegistrationRequest) error {
+	challenge, err := s.consumeChallenge(ctx, payload.ChallengeID, ceremonyRegistration, entityType)
+	if err != nil {
+		return err
+	}
+
+	if challenge.EntityID.UUID != entityID {
+		return servererrors.ErrPasskeyChallengeNotFound
+	}
+
+	verified, err := s.relyingParty.VerifyRegistration(
+		payload.Credential,
+		challenge.Challenge,
+		userVerificationFor(entityType) == webauthn.VerificationRequired,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", servererrors.ErrInvalidPasskey, err)
+	}
+
+	existing, err := s.store.findCredentialByID(ctx, verified.ID)
+	if err != nil {
+		return err
+	}
+
+	if existing.CredentialID != nil {
+		return servererrors.ErrPasskeyAlreadyRegistered
+	}
+
+	name := payload.Name
+	if name == "" {
+		name = defaultCredentialName
+	}
+
+	return s.store.createCredential(ctx, &Credential{
+		CredentialID:   verified.ID,
+		EntityID:       entityID,
+		EntityType:     entityType,
+		Name:           name,
+		PublicKey:      verified.PublicKey,
+		SignCount:      int64(verified.SignCount),
+		AAGUID:         verified.AAGUID,
+		Transports:     verified.Transports,
+		BackupEligible: verified.BackupEligible,
+		BackupState:    verified.BackupState,
+	})
+}
+
+func (s *service) beginLogin(ctx context.Context, entityType string) (*BeginLoginResponse, error) {
+	challenge, err := s.newChallenge(
+		ctx,
+		ceremonyLogin,
+		uuid.NullUUID{},
+		entityType,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BeginLoginResponse{
+		ChallengeID: challenge.ChallengeID,
+		Options: s.relyingParty.NewRequestOptions(
+			challenge.Challenge,
+			nil, // discoverable credentials, the authenticator picks the account
+			userVerificationFor(entityType),
+		),
+	}, nil
+}
+
+func (s *service) finishLogin(ctx context.Context, entityType string, payload *FinishLoginRequest) (*LoginPasskeyCookiesResponse, error) {
+	challenge, err := s.consumeChallenge(ctx, payload.ChallengeID, ceremonyLogin, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, verified, err := s.verifyAssertion(ctx, challenge, payload.Credential)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.sessionService.LoginEntity(
+		ctx,
+		&interfaces.LoginEntityRequest{
+			EntityID:    credential.EntityID,
+			EntityType:  credential.EntityType,
+			UserAgent:   payload.UserAgent,
+			ClientIP:    payload.ClientIP,
+			AuthMethods: authMethodsFor(verified),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginPasskeyCookiesResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+	}, nil
+}
+
+// beginReauthentication starts an assertion ceremony restricted to the
+// passkeys of an already logged in entity.
+func (s *service) beginReauthentication(ctx context.Context, entityID uuid.UUID, entityType string) (*BeginLoginResponse, error) {
+	credentials, err := s.store.findCredentialsByEntity(ctx, entityID, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(credentials) == 0 {
+		return nil, servererrors.ErrPasskeyNotFound
+	}
+
+	allowIDs := make([][]byte, 0, len(credentials))
+	for _, credential := range credentials {
+		allowIDs = append(allowIDs, credential.CredentialID)
+	}
+
+	challenge, err := s.newChallenge(
+		ctx,
+		ceremonyReauthentication,
+		uuid.NullUUID{UUID: entityID, Valid: true},
+		entityType,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BeginLoginResponse{
+		ChallengeID: challenge.ChallengeID,
+		Options: s.relyingParty.NewRequestOptions(
+			challenge.Challenge,
+			allowIDs,
+			userVerificationFor(entityType),
+		),
+	}, nil
+}
+
+// finishReauthentication verifies the assertion and issues an elevated access
+// token for sensitive operations.
+func (s *service) finishReauthentication(ctx context.Context, entityID uuid.UUID, entityType string, payload *FinishReauthenticationRequest) (*interfaces.TokenDetails, error) {
+	challenge, err := s.consumeChallenge(ctx, payload.ChallengeID, ceremonyReauthentication, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge.EntityID.UUID != entityID {
+		return nil, servererrors.ErrPasskeyChallengeNotFound
+	}
+
+	credential, verified, err := s.verifyAssertion(ctx, challenge, payload.Credential)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.EntityID != entityID {
+		return nil, servererrors.ErrInvalidPasskey
+	}
+
+	return s.sessionService.IssueElevatedAccessToken(
+		ctx,
+		entityID,
+		entityType,
+		authMethodsFor(verified),
+	)
+}
+
+// verifyAssertion checks an assertion against the stored credential it claims
+// to come from and records the new signature counter.
+func (s *service) verifyAssertion(ctx context.Context, challenge *Challenge, assertion *webauthn.AssertionCredential) (*Credential, *webauthn.VerifiedAssertion, error) {
+	credential, err := s.store.findCredentialByID(ctx, assertion.RawID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// an admin passkey must not open a customer session and vice versa
+	if credential.CredentialID == nil || credential.EntityType != challenge.EntityType {
+		return nil, nil, servererrors.ErrInvalidPasskey
+	}
+
+	userHandle := assertion.Response.UserHandle
+	if len(userHandle) != 0 && !bytes.Equal(userHandle, credential.EntityID[:]) {
+		return nil, nil, servererrors.ErrInvalidPasskey
+	}
+
+	verified, err := s.relyingParty.VerifyAssertion(
+		assertion,
+		challenge.Challenge,
+		credential.PublicKey,
+		uint32(credential.SignCount),
+		userVerificationFor(credential.EntityType) == webauthn.VerificationRequired,
+	)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCountRegression) {
+			log.Printf(
+				"possible cloned passkey for %s %s: stored sign count %d",
+				credential.EntityType,
+				credential.EntityID,
+				credential.SignCount,
+			)
+		}
+
+		return nil, nil, fmt.Errorf("%w: %v", servererrors.ErrInvalidPasskey, err)
+	}
+
+	updated, err := s.store.updateCredentialUsage(
+		ctx,
+		credential.CredentialID,
+		credential.SignCount,
+		&Credential{
+			SignCount:   int64(verified.SignCount),
+			BackupState: verified.BackupState,
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !updated {
+		// another ceremony raced this one with the same counter value
+		return nil, nil, servererrors.ErrInvalidPasskey
+	}
+
+	return credential, verified, nil
+}
+
+func (s *service) listCredentials(ctx context.Context, entityID uuid.UUID, entityType string) ([]*CredentialResponse, error) {
+	credentials, err := s.store.findCredentialsByEntity(ctx, entityID, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*CredentialResponse, 0, len(credentials))
+	for _, credential := range credentials {
+		var lastUsedAt *time.Time
+		if credential.LastUsedAt.Valid {
+			lastUsedAt = &credential.LastUsedAt.Time
+		}
+
+		resp = append(resp, &CredentialResponse{
+			ID:         credential.CredentialID,
+			Name:       credential.Name,
+			Transports: credential.Transports,
+			Synced:     credential.BackupState,
+			LastUsedAt: lastUsedAt,
+			CreatedAt:  credential.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *service) deleteCredential(ctx context.Context, entityID uuid.UUID, entityType string, credentialID []byte) error {
+	deleted, err := s.store.deleteCredential(ctx, credentialID, entityID, entityType)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return servererrors.ErrPasskeyNotFound
+	}
+
+	return nil
+}
+
+func (s *service) newChallenge(ctx context.Context, ceremony string, entityID uuid.NullUUID, entityType string) (*Challenge, error) {
+	raw, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &Challenge{
+		ChallengeID: uuid.New(),
+		Challenge:   raw,
+		Ceremony:    ceremony,
+		EntityID:    entityID,
+		EntityType:  entityType,
+		ExpiresAt:   time.Now().Add(s.relyingParty.Timeout),
+	}
+
+	if err := s.store.createChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (s *service) consumeChallenge(ctx context.Context, challengeID uuid.UUID, ceremony string, entityType string) (*Challenge, error) {
+	challenge, err := s.store.consumeChallenge(ctx, challengeID, ceremony)
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge.ChallengeID == uuid.Nil ||
+		challenge.EntityType != entityType ||
+		challenge.ExpiresAt.Before(time.Now()) {
+		return nil, servererrors.ErrPasskeyChallengeNotFound
+	}
+
+	return challenge, nil
+}
+
+// userVerificationFor returns the user verification requirement of an entity
+// type. Admins must always verify (PIN or biometrics) with their passkey.
+func userVerificationFor(entityType string) string {
+	if entityType == auth.EntityTypeAdmin {
+		return webauthn.VerificationRequired
+	}
+
+	return webauthn.VerificationPreferred
+}
+
+// authMethodsFor returns the amr values of a passkey assertion. A passkey
+// with user verification is something you have plus something you know or
+// are.
+func authMethodsFor(verified *webauthn.VerifiedAssertion) []string {
+	if verified.UserVerified {
+		return []string{auth.AuthMethodHardwareKey, auth.AuthMethodMultiFactor}
+	}
+
+	return []string{auth.AuthMethodHardwareKey}
+}
@@ -0,0 +1,297 @@
+package passkey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	credentialFields = "credential_id, entity_id, entity_type, name, public_key, sign_count, aaguid, transports, backup_eligible, backup_state, last_used_at, created_at, updated_at"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+func (s *store) createCredential(ctx context.Context, credential *Credential) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO webauthn_credentials(credential_id, entity_id, entity_type, name, public_key, sign_count, aaguid, transports, backup_eligible, backup_state) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		credential.CredentialID,
+		credential.EntityID,
+		credential.EntityType,
+		credential.Name,
+		credential.PublicKey,
+		credential.SignCount,
+		credential.AAGUID,
+		pq.Array(credential.Transports),
+		credential.BackupEligible,
+		credential.BackupState,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to insert new credential in passkey store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findCredentialByID(ctx context.Context, credentialID []byte) (*Credential, error) {
+	credentials, err := s.getCredentialsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM webauthn_credentials WHERE credential_id = $1", credentialFields),
+		credentialID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find credential by id in passkey store: %w",
+			err,
+		)
+	}
+
+	if len(credentials) == 0 {
+		return new(Credential), nil
+	}
+
+	return credentials[0], nil
+}
+
+func (s *store) findCredentialsByEntity(ctx context.Context, entityID uuid.UUID, entityType string) ([]*Credential, error) {
+	credentials, err := s.getCredentialsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM webauthn_credentials WHERE entity_id = $1 AND entity_type = $2 ORDER BY created_at", credentialFields),
+		entityID,
+		entityType,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find credentials by entity in passkey store: %w",
+			err,
+		)
+	}
+
+	return credentials, nil
+}
+
+// updateCredentialUsage stores the new signature counter. The update only
+// applies while the stored counter is still lower, so two concurrent logins
+// with the same assertion cannot both succeed.
+func (s *store) updateCredentialUsage(ctx context.Context, credentialID []byte, previousSignCount int64, credential *Credential) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		"UPDATE webauthn_credentials SET sign_count = $1, backup_state = $2, last_used_at = NOW(), updated_at = NOW() WHERE credential_id = $3 AND sign_count = $4",
+		credential.SignCount,
+		credential.BackupState,
+		credentialID,
+		previousSignCount,
+	)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to update credential usage in passkey store: %w",
+			err,
+		)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (s *store) deleteCredential(ctx context.Context, credentialID []byte, entityID uuid.UUID, entityType string) (bool, error) {
+	result, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM webauthn_credentials WHERE credential_id = $1 AND entity_id = $2 AND entity_type = $3",
+		credentialID,
+		entityID,
+		entityType,
+	)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to delete credential in passkey store: %w",
+			err,
+		)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (s *store) createChallenge(ctx context.Context, challenge *Challenge) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO webauthn_challenges(challenge_id, challenge, ceremony, entity_id, entity_type, expires_at) VALUES($1, $2, $3, $4, $5, $6)",
+		challenge.ChallengeID,
+		challenge.Challenge,
+		challenge.Ceremony,
+		challenge.EntityID,
+		challenge.EntityType,
+		challenge.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to insert new challenge in passkey store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// consumeChallenge deletes and returns the challenge so that it can only be
+// answered once. A zero valued challenge is returned if none matches.
+func (s *store) consumeChallenge(ctx context.Context, challengeID uuid.UUID, ceremony string) (*Challenge, error) {
+	challenge := new(Challenge)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		"DELETE FROM webauthn_challenges WHERE challenge_id = $1 AND ceremony = $2 RETURNING challenge_id, challenge, ceremony, entity_id, entity_type, expires_at, created_at",
+		challengeID,
+		ceremony,
+	).Scan(
+		&challenge.ChallengeID,
+		&challenge.Challenge,
+		&challenge.Ceremony,
+		&challenge.EntityID,
+		&challenge.EntityType,
+		&challenge.ExpiresAt,
+		&challenge.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return new(Challenge), nil
+		default:
+			return nil, fmt.Errorf(
+				"failed to consume challenge in passkey store: %w",
+				err,
+			)
+		}
+	}
+
+	return challenge, nil
+}
+
+func (s *store) deleteExpiredChallenges(ctx context.Context) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM webauthn_challenges WHERE expires_at <= NOW()",
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to delete expired challenges in passkey store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findEntityProfile(ctx context.Context, entityID uuid.UUID, entityType string) (*EntityProfile, error) {
+	var query string
+	switch entityType {
+	case auth.EntityTypeUser:
+		query = "SELECT email, first_name || ' ' || last_name FROM users WHERE user_id = $1"
+	case auth.EntityTypeAdmin:
+		query = "SELECT email, first_name || ' ' || last_name FROM admins WHERE admin_id = $1"
+	default:
+		return nil, fmt.Errorf("unknown entity type %q in passkey store", entityType)
+	}
+
+	profile := new(EntityProfile)
+	err := s.db.QueryRowContext(ctx, query, entityID).Scan(
+		&profile.Email,
+		&profile.DisplayName,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return new(EntityProfile), nil
+		default:
+			return nil, fmt.Errorf(
+				"failed to find entity profile in passkey store: %w",
+				err,
+			)
+		}
+	}
+
+	return profile, nil
+}
+
+func (s *store) getCredentialsWithContext(ctx context.Context, query string, args ...any) ([]*Credential, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in passkey store getCredentialsWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	credentials := []*Credential{}
+	for rows.Next() {
+		credential := new(Credential)
+		if err := scanRowsIntoCredential(rows, credential); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+func scanRowsIntoCredential(rows *sql.Rows, credential *Credential) error {
+	if credential == nil {
+		return errors.New(
+			"scanRowsIntoCredential err in passkey store",
+		)
+	}
+
+	err := rows.Scan(
+		&credential.CredentialID,
+		&credential.EntityID,
+		&credential.EntityType,
+		&credential.Name,
+		&credential.PublicKey,
+		&credential.SignCount,
+		&credential.AAGUID,
+		pq.Array(&credential.Transports),
+		&credential.BackupEligible,
+		&credential.BackupState,
+		&credential.LastUsedAt,
+		&credential.CreatedAt,
+		&credential.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to scan row into credential in passkey store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
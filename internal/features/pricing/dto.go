@@ -0,0 +1,81 @@
+package pricing
+
+import (
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/google/uuid"
+)
+
+// PriceQuery asks for the effective price of Quantity units of a product, or
+// of one of its variants, for a user. UserID is nil for guests and Currency
+// defaults to the product's own currency when empty.
+type PriceQuery struct {
+	UserID    *uuid.UUID
+	ProductID uuid.UUID
+	VariantID *uuid.UUID
+	Quantity  int64
+	Currency  string
+}
+
+// ResolvedPrice is the outcome of a PriceQuery. PriceListID and MinQuantity
+// are only set when a price list entry won over the base price.
+type ResolvedPrice struct {
+	ProductID   uuid.UUID   `json:"productId"`
+	VariantID   *uuid.UUID  `json:"variantId"`
+	Quantity    int64       `json:"quantity"`
+	UnitPrice   money.Money `json:"unitPrice"`
+	Total       money.Money `json:"total"`
+	Source      string      `json:"source"`
+	PriceListID *uuid.UUID  `json:"priceListId,omitempty"`
+	MinQuantity int64       `json:"minQuantity,omitempty"`
+}
+
+// Requests
+
+type CreateCustomerGroupRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=64"`
+}
+
+type AddGroupMembersRequest struct {
+	UserIDs []uuid.UUID `json:"userIds" validate:"required,min=1,max=500,unique"`
+}
+
+// CreatePriceListRequest creates a price list for the members of GroupID, or
+// for everyone when it is null. StartsAt and EndsAt are optional and leave the
+// window open on that side.
+type CreatePriceListRequest struct {
+	Name     string     `json:"name" validate:"required,min=1,max=128"`
+	Currency string     `json:"currency" validate:"required,len=3"`
+	GroupID  *uuid.UUID `json:"groupId"`
+	Priority int        `json:"priority" validate:"gte=0,lte=1000"`
+	StartsAt *time.Time `json:"startsAt"`
+	EndsAt   *time.Time `json:"endsAt"`
+}
+
+// UpdatePriceListRequest only changes the fields that are set, the Remove
+// flags clear the group or either side of the window. The currency of a list
+// is fixed since its entries are amounts in it.
+type UpdatePriceListRequest struct {
+	Name           *string    `json:"name" validate:"omitempty,min=1,max=128"`
+	GroupID        *uuid.UUID `json:"groupId"`
+	RemoveGroup    bool       `json:"removeGroup"`
+	Priority       *int       `json:"priority" validate:"omitempty,gte=0,lte=1000"`
+	StartsAt       *time.Time `json:"startsAt"`
+	RemoveStartsAt bool       `json:"removeStartsAt"`
+	EndsAt         *time.Time `json:"endsAt"`
+	RemoveEndsAt   bool       `json:"removeEndsAt"`
+}
+
+type PriceListEntryRequest struct {
+	ProductID   uuid.UUID  `json:"productId" validate:"required"`
+	VariantID   *uuid.UUID `json:"variantId"`
+	MinQuantity int64      `json:"minQuantity" validate:"gte=1"`
+	Amount      int64      `json:"amount" validate:"gte=0"`
+}
+
+// SetPriceListEntriesRequest replaces every entry of a price list, an empty
+// list clears it.
+type SetPriceListEntriesRequest struct {
+	Entries []*PriceListEntryRequest `json:"entries" validate:"max=5000,dive"`
+}
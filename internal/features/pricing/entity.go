@@ -0,0 +1,68 @@
+package pricing
+
+import (
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/google/uuid"
+)
+
+// Price sources, telling where a resolved price comes from.
+const (
+	SourceBase      = "base"
+	SourcePriceList = "price_list"
+)
+
+type CustomerGroup struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	Name      string    `json:"name"`
+	Members   int       `json:"members"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PriceList overrides the base prices in one currency for the members of a
+// group, or for everyone when GroupID is nil, while it is active. When several
+// lists match, the highest priority wins.
+type PriceList struct {
+	PriceListID uuid.UUID         `json:"price_list_id"`
+	Name        string            `json:"name"`
+	Currency    money.Currency    `json:"currency"`
+	GroupID     *uuid.UUID        `json:"group_id"`
+	Priority    int               `json:"priority"`
+	StartsAt    *time.Time        `json:"starts_at"`
+	EndsAt      *time.Time        `json:"ends_at"`
+	Entries     []*PriceListEntry `json:"entries,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// PriceListEntry is the unit price of a product, or of one of its variants
+// when VariantID is set, for quantities of at least MinQuantity. Entries of
+// the same product with increasing min quantities form quantity break tiers.
+type PriceListEntry struct {
+	PriceListID uuid.UUID  `json:"price_list_id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	VariantID   *uuid.UUID `json:"variant_id"`
+	MinQuantity int64      `json:"min_quantity"`
+	Amount      int64      `json:"amount"`
+}
+
+// basePrice holds what the catalog itself knows about the price of a product
+// or variant. Status is empty when the product does not exist.
+type basePrice struct {
+	Status        string
+	Currency      string
+	Price         int64
+	VariantFound  bool
+	PriceOverride *int64
+	// CurrencyPrice is the explicit product price in the requested
+	// currency, if any
+	CurrencyPrice *int64
+}
+
+// listPrice is the entry of the winning price list.
+type listPrice struct {
+	PriceListID uuid.UUID
+	MinQuantity int64
+	Amount      int64
+}
@@ -0,0 +1,629 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	ResolvePrice(ctx context.Context, query *PriceQuery) (*ResolvedPrice, error)
+	createGroup(ctx context.Context, payload *CreateCustomerGroupRequest) (*CustomerGroup, error)
+	listGroups(ctx context.Context) ([]*CustomerGroup, error)
+	deleteGroup(ctx context.Context, groupID uuid.UUID) error
+	addMembers(ctx context.Context, groupID uuid.UUID, payload *AddGroupMembersRequest) error
+	removeMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error
+	createPriceList(ctx context.Context, payload *CreatePriceListRequest) (*PriceList, error)
+	updatePriceList(ctx context.Context, priceListID uuid.UUID, payload *UpdatePriceListRequest) (*PriceList, error)
+	deletePriceList(ctx context.Context, priceListID uuid.UUID) error
+	listPriceLists(ctx context.Context) ([]*PriceList, error)
+	getPriceList(ctx context.Context, priceListID uuid.UUID) (*PriceList, error)
+	setEntries(ctx context.Context, priceListID uuid.UUID, payload *SetPriceListEntriesRequest) (*PriceList, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+	Identify(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	router.With(h.authenticator.Identify(auth.EntityTypeUser)).Get(
+		"/products/{productID}/price",
+		handlerutils.MakeHandler(h.getPriceHandler),
+	)
+}
+
+// RegisterAdminRoutes registers the customer group and price list routes
+// relative to the admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/customer-groups",
+		handlerutils.MakeHandler(h.listGroupsHandler),
+	)
+	authenticated.Post(
+		"/customer-groups",
+		handlerutils.MakeHandler(h.createGroupHandler),
+	)
+	authenticated.Delete(
+		"/customer-groups/{groupID}",
+		handlerutils.MakeHandler(h.deleteGroupHandler),
+	)
+	authenticated.Post(
+		"/customer-groups/{groupID}/members",
+		handlerutils.MakeHandler(h.addMembersHandler),
+	)
+	authenticated.Delete(
+		"/customer-groups/{groupID}/members/{userID}",
+		handlerutils.MakeHandler(h.removeMemberHandler),
+	)
+	authenticated.Get(
+		"/price-lists",
+		handlerutils.MakeHandler(h.listPriceListsHandler),
+	)
+	authenticated.Post(
+		"/price-lists",
+		handlerutils.MakeHandler(h.createPriceListHandler),
+	)
+	authenticated.Get(
+		"/price-lists/{priceListID}",
+		handlerutils.MakeHandler(h.getPriceListHandler),
+	)
+	authenticated.Patch(
+		"/price-lists/{priceListID}",
+		handlerutils.MakeHandler(h.updatePriceListHandler),
+	)
+	authenticated.Delete(
+		"/price-lists/{priceListID}",
+		handlerutils.MakeHandler(h.deletePriceListHandler),
+	)
+	authenticated.Put(
+		"/price-lists/{priceListID}/entries",
+		handlerutils.MakeHandler(h.setEntriesHandler),
+	)
+}
+
+// getPriceHandler resolves the price of the optional "variantId" for the
+// optional "quantity" and "currency" query parameters. Signed in users get
+// the prices of their customer groups.
+func (h *handler) getPriceHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	quantity, err := handlerutils.ParseQueryInt(r, "quantity", 1)
+	if err != nil || quantity < 1 {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	query := &PriceQuery{
+		ProductID: productID,
+		Quantity:  quantity,
+		Currency:  r.URL.Query().Get("currency"),
+	}
+
+	if value := r.URL.Query().Get("variantId"); value != "" {
+		variantID, err := uuid.Parse(value)
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+		query.VariantID = &variantID
+	}
+
+	if claims, ok := auth.ClaimsFromContext(ctx); ok {
+		userID, err := uuid.Parse(claims.EntityID)
+		if err == nil {
+			query.UserID = &userID
+		}
+	}
+
+	price, err := h.service.ResolvePrice(ctx, query)
+	if err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"price resolved",
+		price,
+	)
+}
+
+func (h *handler) listGroupsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	groups, err := h.service.listGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"customer groups found",
+		groups,
+	)
+}
+
+func (h *handler) createGroupHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *CreateCustomerGroupRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	group, err := h.service.createGroup(ctx, payload)
+	if err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"customer group created",
+		group,
+	)
+}
+
+func (h *handler) deleteGroupHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	groupID, err := uuid.Parse(chi.URLParam(r, "groupID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteGroup(ctx, groupID); err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"customer group deleted",
+		nil,
+	)
+}
+
+func (h *handler) addMembersHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *AddGroupMembersRequest
+	defer r.Body.Close()
+
+	groupID, err := uuid.Parse(chi.URLParam(r, "groupID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	if err = h.service.addMembers(ctx, groupID, payload); err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"customer group members added",
+		nil,
+	)
+}
+
+func (h *handler) removeMemberHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	groupID, err := uuid.Parse(chi.URLParam(r, "groupID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.removeMember(ctx, groupID, userID); err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"customer group member removed",
+		nil,
+	)
+}
+
+func (h *handler) listPriceListsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	lists, err := h.service.listPriceLists(ctx)
+	if err != nil {
+		return err
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"price lists found",
+		lists,
+	)
+}
+
+func (h *handler) createPriceListHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *CreatePriceListRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	list, err := h.service.createPriceList(ctx, payload)
+	if err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"price list created",
+		list,
+	)
+}
+
+func (h *handler) getPriceListHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	priceListID, err := uuid.Parse(chi.URLParam(r, "priceListID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	list, err := h.service.getPriceList(ctx, priceListID)
+	if err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"price list found",
+		list,
+	)
+}
+
+func (h *handler) updatePriceListHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *UpdatePriceListRequest
+	defer r.Body.Close()
+
+	priceListID, err := uuid.Parse(chi.URLParam(r, "priceListID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	list, err := h.service.updatePriceList(ctx, priceListID, payload)
+	if err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"price list updated",
+		list,
+	)
+}
+
+func (h *handler) deletePriceListHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	priceListID, err := uuid.Parse(chi.URLParam(r, "priceListID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deletePriceList(ctx, priceListID); err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"price list deleted",
+		nil,
+	)
+}
+
+func (h *handler) setEntriesHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *SetPriceListEntriesRequest
+	defer r.Body.Close()
+
+	priceListID, err := uuid.Parse(chi.URLParam(r, "priceListID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	list, err := h.service.setEntries(ctx, priceListID, payload)
+	if err != nil {
+		return pricingError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"price list entries updated",
+		list,
+	)
+}
+
+// pricingError maps the errors of the pricing service to responses.
+func pricingError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrProductNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrProductNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrVariantNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrVariantNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrCustomerGroupNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrCustomerGroupNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrPriceListNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrPriceListNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrUserNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrUserNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrCustomerGroupAlreadyExists):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrCustomerGroupAlreadyExists.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrNoPriceForCurrency):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrNoPriceForCurrency.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrUnsupportedCurrency):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrUnsupportedCurrency.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidPriceList):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidPriceList.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidPriceListEntries):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidPriceListEntries.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrVariantNotForProduct):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrVariantNotForProduct.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
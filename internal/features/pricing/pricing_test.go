@@ -0,0 +1,555 @@
+package pricing
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func newTestRouter() (*chi.Mux, *mockStore) {
+	pricingStore := newMockPricingStore()
+	pricingHandler := NewHandler(NewService(pricingStore), nil)
+
+	router := chi.NewRouter()
+	router.With(testutil.AuthenticateFromHeader).Get(
+		"/products/{productID}/price",
+		handlerutils.MakeHandler(pricingHandler.getPriceHandler),
+	)
+	router.Post(
+		"/admin/customer-groups",
+		handlerutils.MakeHandler(pricingHandler.createGroupHandler),
+	)
+	router.Post(
+		"/admin/customer-groups/{groupID}/members",
+		handlerutils.MakeHandler(pricingHandler.addMembersHandler),
+	)
+	router.Post(
+		"/admin/price-lists",
+		handlerutils.MakeHandler(pricingHandler.createPriceListHandler),
+	)
+	router.Patch(
+		"/admin/price-lists/{priceListID}",
+		handlerutils.MakeHandler(pricingHandler.updatePriceListHandler),
+	)
+	router.Put(
+		"/admin/price-lists/{priceListID}/entries",
+		handlerutils.MakeHandler(pricingHandler.setEntriesHandler),
+	)
+
+	return router, pricingStore
+}
+
+func TestPriceResolution(t *testing.T) {
+	router, pricingStore := newTestRouter()
+
+	productID := uuid.New()
+	variantID := uuid.New()
+	override := int64(1800)
+	pricingStore.Products[productID] = &mockProduct{
+		Status:   "active",
+		Currency: "USD",
+		Price:    2000,
+		Variants: map[uuid.UUID]*int64{variantID: &override, uuid.New(): nil},
+		Prices:   map[string]int64{"EUR": 1900},
+	}
+
+	wholesaler := uuid.New()
+	pricingStore.Users = append(pricingStore.Users, wholesaler)
+
+	group := new(CustomerGroup)
+	testutil.Serve(t, router, http.MethodPost, "/admin/customer-groups", nil, CreateCustomerGroupRequest{Name: "Wholesale"}, group)
+	code := testutil.Serve(t, router, http.MethodPost, "/admin/customer-groups/"+group.GroupID.String()+"/members", nil, AddGroupMembersRequest{UserIDs: []uuid.UUID{wholesaler}}, nil)
+	if code != http.StatusOK {
+		t.Fatalf("expected status code %d adding members, got %d", http.StatusOK, code)
+	}
+
+	createList := func(payload CreatePriceListRequest, entries []*PriceListEntryRequest) uuid.UUID {
+		t.Helper()
+
+		list := new(PriceList)
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/price-lists", nil, payload, list); code != http.StatusCreated {
+			t.Fatalf("expected status code %d creating %s, got %d", http.StatusCreated, payload.Name, code)
+		}
+
+		code := testutil.Serve(t, router, http.MethodPut, "/admin/price-lists/"+list.PriceListID.String()+"/entries", nil, SetPriceListEntriesRequest{Entries: entries}, nil)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d setting entries of %s, got %d", http.StatusOK, payload.Name, code)
+		}
+
+		return list.PriceListID
+	}
+
+	wholesale := createList(
+		CreatePriceListRequest{Name: "Wholesale", Currency: "USD", GroupID: &group.GroupID, Priority: 10},
+		[]*PriceListEntryRequest{
+			{ProductID: productID, MinQuantity: 1, Amount: 1500},
+			{ProductID: productID, MinQuantity: 10, Amount: 1200},
+			{ProductID: productID, MinQuantity: 50, Amount: 1000},
+		},
+	)
+	past := time.Now().Add(-48 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	createList(
+		CreatePriceListRequest{Name: "Expired sale", Currency: "USD", Priority: 100, StartsAt: &past, EndsAt: &yesterday},
+		[]*PriceListEntryRequest{{ProductID: productID, MinQuantity: 1, Amount: 100}},
+	)
+
+	price := func(userID *uuid.UUID, query string) *ResolvedPrice {
+		t.Helper()
+
+		resolved := new(ResolvedPrice)
+		code := testutil.Serve(t, router, http.MethodGet, "/products/"+productID.String()+"/price"+query, userID, nil, resolved)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d for %s, got %d", http.StatusOK, query, code)
+		}
+
+		return resolved
+	}
+
+	t.Run("should return the base price to guests", func(t *testing.T) {
+		resolved := price(nil, "?quantity=3")
+		if resolved.Source != SourceBase || resolved.UnitPrice != money.MustNew(2000, "USD") || resolved.Total != money.MustNew(6000, "USD") {
+			t.Errorf("expected 3 x 20.00 USD from the base price, got %s x %s from %s", resolved.UnitPrice, resolved.Total, resolved.Source)
+		}
+	})
+
+	t.Run("should use the variant price override", func(t *testing.T) {
+		resolved := price(nil, "?variantId="+variantID.String())
+		if resolved.UnitPrice != money.MustNew(1800, "USD") {
+			t.Errorf("expected 18.00 USD, got %s", resolved.UnitPrice)
+		}
+	})
+
+	t.Run("should use the explicit price of another currency", func(t *testing.T) {
+		resolved := price(nil, "?currency=eur")
+		if resolved.UnitPrice != money.MustNew(1900, "EUR") {
+			t.Errorf("expected 19.00 EUR, got %s", resolved.UnitPrice)
+		}
+	})
+
+	t.Run("should apply the quantity break reached by group members", func(t *testing.T) {
+		for query, amount := range map[string]int64{"?quantity=1": 1500, "?quantity=10": 1200, "?quantity=49": 1200, "?quantity=50": 1000} {
+			resolved := price(&wholesaler, query)
+			if resolved.Source != SourcePriceList || resolved.PriceListID == nil || *resolved.PriceListID != wholesale {
+				t.Fatalf("expected the wholesale list for %s, got %s", query, resolved.Source)
+			}
+
+			if resolved.UnitPrice.Amount() != amount {
+				t.Errorf("expected %d for %s, got %d", amount, query, resolved.UnitPrice.Amount())
+			}
+		}
+	})
+
+	t.Run("should prefer an entry for the variant", func(t *testing.T) {
+		createList(
+			CreatePriceListRequest{Name: "Wholesale variants", Currency: "USD", GroupID: &group.GroupID, Priority: 10},
+			[]*PriceListEntryRequest{{ProductID: productID, VariantID: &variantID, MinQuantity: 1, Amount: 1400}},
+		)
+
+		if resolved := price(&wholesaler, "?variantId="+variantID.String()); resolved.UnitPrice.Amount() != 1400 {
+			t.Errorf("expected the variant entry of 1400, got %d", resolved.UnitPrice.Amount())
+		}
+	})
+
+	t.Run("should let a higher priority list win", func(t *testing.T) {
+		createList(
+			CreatePriceListRequest{Name: "Clearance", Currency: "USD", Priority: 20},
+			[]*PriceListEntryRequest{{ProductID: productID, MinQuantity: 1, Amount: 1600}},
+		)
+
+		if resolved := price(&wholesaler, "?quantity=50"); resolved.UnitPrice.Amount() != 1600 {
+			t.Errorf("expected the clearance price of 1600, got %d", resolved.UnitPrice.Amount())
+		}
+
+		if resolved := price(nil, ""); resolved.UnitPrice.Amount() != 1600 {
+			t.Errorf("expected guests to get the clearance price of 1600, got %d", resolved.UnitPrice.Amount())
+		}
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{
+			name:     "should not price a currency without a price",
+			path:     "/products/" + productID.String() + "/price?currency=GBP",
+			expected: http.StatusNotFound,
+		},
+		{
+			name:     "should reject an unknown currency",
+			path:     "/products/" + productID.String() + "/price?currency=XYZ",
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject a variant of another product",
+			path:     "/products/" + productID.String() + "/price?variantId=" + uuid.NewString(),
+			expected: http.StatusNotFound,
+		},
+		{
+			name:     "should reject a zero quantity",
+			path:     "/products/" + productID.String() + "/price?quantity=0",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "should not price an unknown product",
+			path:     "/products/" + uuid.NewString() + "/price",
+			expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := testutil.Serve(t, router, http.MethodGet, tc.path, nil, nil, nil); code != tc.expected {
+				t.Errorf("expected status code %d, got %d", tc.expected, code)
+			}
+		})
+	}
+}
+
+func TestPriceListRoutes(t *testing.T) {
+	router, pricingStore := newTestRouter()
+
+	productID := uuid.New()
+	pricingStore.Products[productID] = &mockProduct{Status: "active", Currency: "USD", Price: 1000}
+
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	list := new(PriceList)
+	testutil.Serve(t, router, http.MethodPost, "/admin/price-lists", nil, CreatePriceListRequest{Name: "Staff", Currency: "USD"}, list)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		payload  any
+		expected int
+	}{
+		{
+			name:     "should reject a window ending before it starts",
+			method:   http.MethodPost,
+			path:     "/admin/price-lists",
+			payload:  CreatePriceListRequest{Name: "Backwards", Currency: "USD", StartsAt: &later, EndsAt: &now},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject an unknown currency",
+			method:   http.MethodPost,
+			path:     "/admin/price-lists",
+			payload:  CreatePriceListRequest{Name: "Unknown", Currency: "XYZ"},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject an unknown group",
+			method:   http.MethodPost,
+			path:     "/admin/price-lists",
+			payload:  map[string]any{"name": "Nobody", "currency": "USD", "groupId": uuid.New()},
+			expected: http.StatusNotFound,
+		},
+		{
+			name:     "should reject an update ending the window before it starts",
+			method:   http.MethodPatch,
+			path:     "/admin/price-lists/" + list.PriceListID.String(),
+			payload:  UpdatePriceListRequest{StartsAt: &later, EndsAt: &now},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "should reject two amounts for the same tier",
+			method: http.MethodPut,
+			path:   "/admin/price-lists/" + list.PriceListID.String() + "/entries",
+			payload: SetPriceListEntriesRequest{Entries: []*PriceListEntryRequest{
+				{ProductID: productID, MinQuantity: 5, Amount: 900},
+				{ProductID: productID, MinQuantity: 5, Amount: 800},
+			}},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "should reject a tier below one",
+			method: http.MethodPut,
+			path:   "/admin/price-lists/" + list.PriceListID.String() + "/entries",
+			payload: SetPriceListEntriesRequest{Entries: []*PriceListEntryRequest{
+				{ProductID: productID, MinQuantity: 0, Amount: 900},
+			}},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "should reject a variant of another product",
+			method: http.MethodPut,
+			path:   "/admin/price-lists/" + list.PriceListID.String() + "/entries",
+			payload: SetPriceListEntriesRequest{Entries: []*PriceListEntryRequest{
+				{ProductID: productID, VariantID: ptr(uuid.New()), MinQuantity: 1, Amount: 900},
+			}},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should not update an unknown price list",
+			method:   http.MethodPatch,
+			path:     "/admin/price-lists/" + uuid.NewString(),
+			payload:  UpdatePriceListRequest{},
+			expected: http.StatusNotFound,
+		},
+		{
+			name:     "should not add unknown users to a group",
+			method:   http.MethodPost,
+			path:     "/admin/customer-groups/" + uuid.NewString() + "/members",
+			payload:  AddGroupMembersRequest{UserIDs: []uuid.UUID{uuid.New()}},
+			expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := testutil.Serve(t, router, tc.method, tc.path, nil, tc.payload, nil); code != tc.expected {
+				t.Errorf("expected status code %d, got %d", tc.expected, code)
+			}
+		})
+	}
+
+	t.Run("should reject a duplicate group name", func(t *testing.T) {
+		testutil.Serve(t, router, http.MethodPost, "/admin/customer-groups", nil, CreateCustomerGroupRequest{Name: "Retail"}, nil)
+		if code := testutil.Serve(t, router, http.MethodPost, "/admin/customer-groups", nil, CreateCustomerGroupRequest{Name: "retail"}, nil); code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+type mockProduct struct {
+	Status   string
+	Currency string
+	Price    int64
+	// Variants maps the variants of the product to their price override
+	Variants map[uuid.UUID]*int64
+	Prices   map[string]int64
+}
+
+// mockStore ranks price list entries the same way findListPrice orders them.
+type mockStore struct {
+	Groups     map[uuid.UUID]*CustomerGroup
+	Members    map[uuid.UUID][]uuid.UUID
+	Users      []uuid.UUID
+	PriceLists map[uuid.UUID]*PriceList
+	Products   map[uuid.UUID]*mockProduct
+}
+
+func newMockPricingStore() *mockStore {
+	return &mockStore{
+		Groups:     map[uuid.UUID]*CustomerGroup{},
+		Members:    map[uuid.UUID][]uuid.UUID{},
+		PriceLists: map[uuid.UUID]*PriceList{},
+		Products:   map[uuid.UUID]*mockProduct{},
+	}
+}
+
+func (m *mockStore) createGroup(ctx context.Context, group *CustomerGroup) error {
+	for _, existing := range m.Groups {
+		if strings.EqualFold(existing.Name, group.Name) {
+			return servererrors.ErrCustomerGroupAlreadyExists
+		}
+	}
+
+	group.CreatedAt = time.Now()
+	m.Groups[group.GroupID] = group
+	return nil
+}
+
+func (m *mockStore) findGroups(ctx context.Context) ([]*CustomerGroup, error) {
+	groups := []*CustomerGroup{}
+	for _, group := range m.Groups {
+		group.Members = len(m.Members[group.GroupID])
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func (m *mockStore) groupExists(ctx context.Context, groupID uuid.UUID) (bool, error) {
+	_, ok := m.Groups[groupID]
+	return ok, nil
+}
+
+func (m *mockStore) deleteGroup(ctx context.Context, groupID uuid.UUID) error {
+	delete(m.Groups, groupID)
+	delete(m.Members, groupID)
+	return nil
+}
+
+func (m *mockStore) addMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		if !slices.Contains(m.Users, userID) {
+			return servererrors.ErrUserNotFound
+		}
+	}
+
+	for _, userID := range userIDs {
+		if !slices.Contains(m.Members[groupID], userID) {
+			m.Members[groupID] = append(m.Members[groupID], userID)
+		}
+	}
+
+	return nil
+}
+
+func (m *mockStore) removeMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error {
+	m.Members[groupID] = slices.DeleteFunc(m.Members[groupID], func(id uuid.UUID) bool {
+		return id == userID
+	})
+	return nil
+}
+
+func (m *mockStore) createPriceList(ctx context.Context, list *PriceList) error {
+	if list.GroupID != nil && m.Groups[*list.GroupID] == nil {
+		return servererrors.ErrCustomerGroupNotFound
+	}
+
+	list.CreatedAt, list.UpdatedAt = time.Now(), time.Now()
+	m.PriceLists[list.PriceListID] = list
+	return nil
+}
+
+func (m *mockStore) updatePriceList(ctx context.Context, list *PriceList) error {
+	if list.GroupID != nil && m.Groups[*list.GroupID] == nil {
+		return servererrors.ErrCustomerGroupNotFound
+	}
+
+	list.UpdatedAt = time.Now()
+	m.PriceLists[list.PriceListID] = list
+	return nil
+}
+
+func (m *mockStore) deletePriceList(ctx context.Context, priceListID uuid.UUID) error {
+	delete(m.PriceLists, priceListID)
+	return nil
+}
+
+func (m *mockStore) findPriceLists(ctx context.Context) ([]*PriceList, error) {
+	lists := []*PriceList{}
+	for _, list := range m.PriceLists {
+		lists = append(lists, list)
+	}
+
+	return lists, nil
+}
+
+func (m *mockStore) findPriceListByID(ctx context.Context, priceListID uuid.UUID) (*PriceList, error) {
+	list, ok := m.PriceLists[priceListID]
+	if !ok {
+		return new(PriceList), nil
+	}
+
+	copied := *list
+	return &copied, nil
+}
+
+func (m *mockStore) replaceEntries(ctx context.Context, priceListID uuid.UUID, entries []*PriceListEntry) error {
+	for _, entry := range entries {
+		product, ok := m.Products[entry.ProductID]
+		if !ok {
+			return servererrors.ErrProductNotFound
+		}
+
+		if entry.VariantID != nil {
+			if _, ok := product.Variants[*entry.VariantID]; !ok {
+				return servererrors.ErrVariantNotForProduct
+			}
+		}
+	}
+
+	m.PriceLists[priceListID].Entries = entries
+	return nil
+}
+
+func (m *mockStore) findBasePrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string) (*basePrice, error) {
+	product, ok := m.Products[productID]
+	if !ok {
+		return new(basePrice), nil
+	}
+
+	base := &basePrice{
+		Status:   product.Status,
+		Currency: product.Currency,
+		Price:    product.Price,
+	}
+
+	if variantID != nil {
+		base.PriceOverride, base.VariantFound = product.Variants[*variantID]
+	}
+
+	if amount, ok := product.Prices[currency]; ok {
+		base.CurrencyPrice = &amount
+	}
+
+	return base, nil
+}
+
+func (m *mockStore) findListPrice(ctx context.Context, query *PriceQuery, currency string, at time.Time) (*listPrice, error) {
+	type candidate struct {
+		list  *PriceList
+		entry *PriceListEntry
+	}
+
+	var best *candidate
+	better := func(c *candidate) bool {
+		switch {
+		case best == nil:
+			return true
+		case c.list.Priority != best.list.Priority:
+			return c.list.Priority > best.list.Priority
+		case (c.list.GroupID != nil) != (best.list.GroupID != nil):
+			return c.list.GroupID != nil
+		case (c.entry.VariantID != nil) != (best.entry.VariantID != nil):
+			return c.entry.VariantID != nil
+		case c.entry.MinQuantity != best.entry.MinQuantity:
+			return c.entry.MinQuantity > best.entry.MinQuantity
+		default:
+			return c.list.PriceListID.String() < best.list.PriceListID.String()
+		}
+	}
+
+	for _, list := range m.PriceLists {
+		if list.Currency.Code() != currency ||
+			(list.StartsAt != nil && at.Before(*list.StartsAt)) ||
+			(list.EndsAt != nil && !at.Before(*list.EndsAt)) {
+			continue
+		}
+
+		if list.GroupID != nil && (query.UserID == nil || !slices.Contains(m.Members[*list.GroupID], *query.UserID)) {
+			continue
+		}
+
+		for _, entry := range list.Entries {
+			if entry.ProductID != query.ProductID || entry.MinQuantity > query.Quantity ||
+				(entry.VariantID != nil && (query.VariantID == nil || *entry.VariantID != *query.VariantID)) {
+				continue
+			}
+
+			if c := (&candidate{list: list, entry: entry}); better(c) {
+				best = c
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, nil
+	}
+
+	return &listPrice{
+		PriceListID: best.list.PriceListID,
+		MinQuantity: best.entry.MinQuantity,
+		Amount:      best.entry.Amount,
+	}, nil
+}
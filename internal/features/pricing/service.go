@@ -0,0 +1,332 @@
+package pricing
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type pricingStorer interface {
+	createGroup(ctx context.Context, group *CustomerGroup) error
+	findGroups(ctx context.Context) ([]*CustomerGroup, error)
+	groupExists(ctx context.Context, groupID uuid.UUID) (bool, error)
+	deleteGroup(ctx context.Context, groupID uuid.UUID) error
+	addMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error
+	removeMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error
+	createPriceList(ctx context.Context, list *PriceList) error
+	updatePriceList(ctx context.Context, list *PriceList) error
+	deletePriceList(ctx context.Context, priceListID uuid.UUID) error
+	findPriceLists(ctx context.Context) ([]*PriceList, error)
+	findPriceListByID(ctx context.Context, priceListID uuid.UUID) (*PriceList, error)
+	replaceEntries(ctx context.Context, priceListID uuid.UUID, entries []*PriceListEntry) error
+	findBasePrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string) (*basePrice, error)
+	findListPrice(ctx context.Context, query *PriceQuery, currency string, at time.Time) (*listPrice, error)
+}
+
+type service struct {
+	pricingStore pricingStorer
+	now          func() time.Time
+}
+
+func NewService(pricingStore pricingStorer) *service {
+	return &service{
+		pricingStore: pricingStore,
+		now:          time.Now,
+	}
+}
+
+// ResolvePrice returns the effective price of query. It is the single place
+// prices are worked out so the catalog, cart and checkout always agree.
+//
+// The base price is the variant's price override or the product price in the
+// product's own currency, or the product's explicit price in any other
+// currency. The best matching price list entry, if any, replaces it even when
+// it is higher, the lists are the merchant's explicit decision.
+func (s *service) ResolvePrice(ctx context.Context, query *PriceQuery) (*ResolvedPrice, error) {
+	q := *query
+	query = &q
+
+	if query.Quantity < 1 {
+		query.Quantity = 1
+	}
+
+	if query.Currency != "" {
+		currency, err := money.ParseCurrency(query.Currency)
+		if err != nil {
+			return nil, servererrors.ErrUnsupportedCurrency
+		}
+		query.Currency = currency.Code()
+	}
+
+	base, err := s.pricingStore.findBasePrice(ctx, query.ProductID, query.VariantID, query.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if base.Status != product.StatusActive {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	if query.VariantID != nil && !base.VariantFound {
+		return nil, servererrors.ErrVariantNotFound
+	}
+
+	currency := query.Currency
+	if currency == "" {
+		currency = base.Currency
+	}
+
+	resolved := &ResolvedPrice{
+		ProductID: query.ProductID,
+		VariantID: query.VariantID,
+		Quantity:  query.Quantity,
+	}
+
+	var amount *int64
+	switch {
+	case currency != base.Currency:
+		amount = base.CurrencyPrice
+	case base.PriceOverride != nil:
+		amount = base.PriceOverride
+	default:
+		amount = &base.Price
+	}
+	resolved.Source = SourceBase
+
+	listPrice, err := s.pricingStore.findListPrice(ctx, query, currency, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	if listPrice != nil {
+		amount = &listPrice.Amount
+		resolved.Source = SourcePriceList
+		resolved.PriceListID = &listPrice.PriceListID
+		resolved.MinQuantity = listPrice.MinQuantity
+	}
+
+	if amount == nil {
+		return nil, servererrors.ErrNoPriceForCurrency
+	}
+
+	if resolved.UnitPrice, err = money.New(*amount, currency); err != nil {
+		return nil, err
+	}
+
+	if resolved.Total, err = resolved.UnitPrice.Multiply(query.Quantity); err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+func (s *service) createGroup(ctx context.Context, payload *CreateCustomerGroupRequest) (*CustomerGroup, error) {
+	group := &CustomerGroup{
+		GroupID: uuid.New(),
+		Name:    strings.TrimSpace(payload.Name),
+	}
+
+	if err := s.pricingStore.createGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (s *service) listGroups(ctx context.Context) ([]*CustomerGroup, error) {
+	return s.pricingStore.findGroups(ctx)
+}
+
+func (s *service) deleteGroup(ctx context.Context, groupID uuid.UUID) error {
+	if err := s.checkGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	return s.pricingStore.deleteGroup(ctx, groupID)
+}
+
+func (s *service) addMembers(ctx context.Context, groupID uuid.UUID, payload *AddGroupMembersRequest) error {
+	if err := s.checkGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	return s.pricingStore.addMembers(ctx, groupID, payload.UserIDs)
+}
+
+func (s *service) removeMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error {
+	if err := s.checkGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	return s.pricingStore.removeMember(ctx, groupID, userID)
+}
+
+func (s *service) createPriceList(ctx context.Context, payload *CreatePriceListRequest) (*PriceList, error) {
+	currency, err := money.ParseCurrency(payload.Currency)
+	if err != nil {
+		return nil, servererrors.ErrUnsupportedCurrency
+	}
+
+	list := &PriceList{
+		PriceListID: uuid.New(),
+		Name:        strings.TrimSpace(payload.Name),
+		Currency:    currency,
+		GroupID:     payload.GroupID,
+		Priority:    payload.Priority,
+		StartsAt:    payload.StartsAt,
+		EndsAt:      payload.EndsAt,
+	}
+
+	if err = checkWindow(list); err != nil {
+		return nil, err
+	}
+
+	if err = s.pricingStore.createPriceList(ctx, list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *service) updatePriceList(ctx context.Context, priceListID uuid.UUID, payload *UpdatePriceListRequest) (*PriceList, error) {
+	list, err := s.findPriceList(ctx, priceListID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Name != nil {
+		list.Name = strings.TrimSpace(*payload.Name)
+	}
+
+	if payload.Priority != nil {
+		list.Priority = *payload.Priority
+	}
+
+	switch {
+	case payload.RemoveGroup:
+		list.GroupID = nil
+	case payload.GroupID != nil:
+		list.GroupID = payload.GroupID
+	}
+
+	switch {
+	case payload.RemoveStartsAt:
+		list.StartsAt = nil
+	case payload.StartsAt != nil:
+		list.StartsAt = payload.StartsAt
+	}
+
+	switch {
+	case payload.RemoveEndsAt:
+		list.EndsAt = nil
+	case payload.EndsAt != nil:
+		list.EndsAt = payload.EndsAt
+	}
+
+	if err = checkWindow(list); err != nil {
+		return nil, err
+	}
+
+	if err = s.pricingStore.updatePriceList(ctx, list); err != nil {
+		return nil, err
+	}
+
+	return s.pricingStore.findPriceListByID(ctx, priceListID)
+}
+
+func (s *service) deletePriceList(ctx context.Context, priceListID uuid.UUID) error {
+	if _, err := s.findPriceList(ctx, priceListID); err != nil {
+		return err
+	}
+
+	return s.pricingStore.deletePriceList(ctx, priceListID)
+}
+
+func (s *service) listPriceLists(ctx context.Context) ([]*PriceList, error) {
+	return s.pricingStore.findPriceLists(ctx)
+}
+
+func (s *service) getPriceList(ctx context.Context, priceListID uuid.UUID) (*PriceList, error) {
+	return s.findPriceList(ctx, priceListID)
+}
+
+// setEntries replaces the entries of a price list, each product or variant
+// may only have one amount per min quantity.
+func (s *service) setEntries(ctx context.Context, priceListID uuid.UUID, payload *SetPriceListEntriesRequest) (*PriceList, error) {
+	if _, err := s.findPriceList(ctx, priceListID); err != nil {
+		return nil, err
+	}
+
+	type tier struct {
+		productID   uuid.UUID
+		variantID   uuid.UUID
+		minQuantity int64
+	}
+
+	seen := map[tier]bool{}
+	entries := make([]*PriceListEntry, 0, len(payload.Entries))
+	for _, e := range payload.Entries {
+		key := tier{productID: e.ProductID, minQuantity: e.MinQuantity}
+		if e.VariantID != nil {
+			key.variantID = *e.VariantID
+		}
+
+		if seen[key] {
+			return nil, servererrors.ErrInvalidPriceListEntries
+		}
+		seen[key] = true
+
+		entries = append(entries, &PriceListEntry{
+			PriceListID: priceListID,
+			ProductID:   e.ProductID,
+			VariantID:   e.VariantID,
+			MinQuantity: e.MinQuantity,
+			Amount:      e.Amount,
+		})
+	}
+
+	if err := s.pricingStore.replaceEntries(ctx, priceListID, entries); err != nil {
+		return nil, err
+	}
+
+	return s.pricingStore.findPriceListByID(ctx, priceListID)
+}
+
+func (s *service) checkGroup(ctx context.Context, groupID uuid.UUID) error {
+	exists, err := s.pricingStore.groupExists(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return servererrors.ErrCustomerGroupNotFound
+	}
+
+	return nil
+}
+
+func (s *service) findPriceList(ctx context.Context, priceListID uuid.UUID) (*PriceList, error) {
+	list, err := s.pricingStore.findPriceListByID(ctx, priceListID)
+	if err != nil {
+		return nil, err
+	}
+
+	if list.PriceListID == uuid.Nil {
+		return nil, servererrors.ErrPriceListNotFound
+	}
+
+	return list, nil
+}
+
+func checkWindow(list *PriceList) error {
+	if list.StartsAt != nil && list.EndsAt != nil && !list.EndsAt.After(*list.StartsAt) {
+		return servererrors.ErrInvalidPriceList
+	}
+
+	return nil
+}
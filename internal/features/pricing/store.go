@@ -0,0 +1,522 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	priceListFields = "price_list_id, name, currency, group_id, priority, starts_at, ends_at, created_at, updated_at"
+
+	// postgres error codes
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+func (s *store) createGroup(ctx context.Context, group *CustomerGroup) error {
+	err := s.db.QueryRowContext(
+		ctx,
+		"INSERT INTO customer_groups(group_id, name) VALUES($1, $2) RETURNING created_at",
+		group.GroupID,
+		group.Name,
+	).Scan(&group.CreatedAt)
+	if err != nil {
+		if isPQError(err, uniqueViolation) {
+			return servererrors.ErrCustomerGroupAlreadyExists
+		}
+
+		return fmt.Errorf(
+			"failed to insert new customer group in pricing store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findGroups(ctx context.Context) ([]*CustomerGroup, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT g.group_id, g.name, COUNT(m.user_id), g.created_at
+		FROM customer_groups g LEFT JOIN customer_group_members m ON m.group_id = g.group_id
+		GROUP BY g.group_id ORDER BY g.name ASC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find customer groups in pricing store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	groups := []*CustomerGroup{}
+	for rows.Next() {
+		group := new(CustomerGroup)
+		err = rows.Scan(
+			&group.GroupID,
+			&group.Name,
+			&group.Members,
+			&group.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into customer group in pricing store: %w",
+				err,
+			)
+		}
+
+		groups = append(groups, group)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in pricing store: %w",
+			err,
+		)
+	}
+
+	return groups, nil
+}
+
+func (s *store) groupExists(ctx context.Context, groupID uuid.UUID) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM customer_groups WHERE group_id = $1)",
+		groupID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to check customer group in pricing store: %w",
+			err,
+		)
+	}
+
+	return exists, nil
+}
+
+// deleteGroup removes a group along with its memberships and price lists.
+func (s *store) deleteGroup(ctx context.Context, groupID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM customer_groups WHERE group_id = $1",
+		groupID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to delete customer group in pricing store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// addMembers adds users to a group, users already in it are left as they are.
+func (s *store) addMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO customer_group_members(group_id, user_id)
+		SELECT $1, UNNEST($2::uuid[]) ON CONFLICT DO NOTHING`,
+		groupID,
+		pq.Array(userIDs),
+	)
+	if err != nil {
+		if isPQError(err, foreignKeyViolation) {
+			return servererrors.ErrUserNotFound
+		}
+
+		return fmt.Errorf(
+			"failed to add customer group members in pricing store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) removeMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM customer_group_members WHERE group_id = $1 AND user_id = $2",
+		groupID,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to remove customer group member in pricing store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) createPriceList(ctx context.Context, list *PriceList) error {
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO price_lists(price_list_id, name, currency, group_id, priority, starts_at, ends_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING created_at, updated_at`,
+		list.PriceListID,
+		list.Name,
+		list.Currency,
+		list.GroupID,
+		list.Priority,
+		list.StartsAt,
+		list.EndsAt,
+	).Scan(&list.CreatedAt, &list.UpdatedAt)
+	if err != nil {
+		if isPQError(err, foreignKeyViolation) {
+			return servererrors.ErrCustomerGroupNotFound
+		}
+
+		return fmt.Errorf(
+			"failed to insert new price list in pricing store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) updatePriceList(ctx context.Context, list *PriceList) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE price_lists SET name = $1, group_id = $2, priority = $3, starts_at = $4, ends_at = $5, updated_at = NOW()
+		WHERE price_list_id = $6`,
+		list.Name,
+		list.GroupID,
+		list.Priority,
+		list.StartsAt,
+		list.EndsAt,
+		list.PriceListID,
+	)
+	if err != nil {
+		if isPQError(err, foreignKeyViolation) {
+			return servererrors.ErrCustomerGroupNotFound
+		}
+
+		return fmt.Errorf(
+			"failed to update price list in pricing store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) deletePriceList(ctx context.Context, priceListID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM price_lists WHERE price_list_id = $1",
+		priceListID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to delete price list in pricing store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findPriceLists(ctx context.Context) ([]*PriceList, error) {
+	lists, err := s.getPriceListsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM price_lists ORDER BY priority DESC, name ASC", priceListFields),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find price lists in pricing store: %w",
+			err,
+		)
+	}
+
+	return lists, nil
+}
+
+// findPriceListByID returns the list with its entries.
+func (s *store) findPriceListByID(ctx context.Context, priceListID uuid.UUID) (*PriceList, error) {
+	lists, err := s.getPriceListsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM price_lists WHERE price_list_id = $1", priceListFields),
+		priceListID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find price list by id in pricing store: %w",
+			err,
+		)
+	}
+
+	if len(lists) == 0 {
+		return new(PriceList), nil
+	}
+
+	list := lists[0]
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT price_list_id, product_id, variant_id, min_quantity, amount FROM price_list_entries
+		WHERE price_list_id = $1 ORDER BY product_id, variant_id NULLS FIRST, min_quantity ASC`,
+		priceListID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find price list entries in pricing store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	list.Entries = []*PriceListEntry{}
+	for rows.Next() {
+		entry := new(PriceListEntry)
+		err = rows.Scan(
+			&entry.PriceListID,
+			&entry.ProductID,
+			&entry.VariantID,
+			&entry.MinQuantity,
+			&entry.Amount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into price list entry in pricing store: %w",
+				err,
+			)
+		}
+
+		list.Entries = append(list.Entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in pricing store: %w",
+			err,
+		)
+	}
+
+	return list, nil
+}
+
+// replaceEntries swaps every entry of a price list for entries. An entry
+// whose variant is not one of its product's fails with
+// ErrVariantNotForProduct.
+func (s *store) replaceEntries(ctx context.Context, priceListID uuid.UUID, entries []*PriceListEntry) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM price_list_entries WHERE price_list_id = $1", priceListID); err != nil {
+			return fmt.Errorf("failed to delete price list entries in pricing store: %w", err)
+		}
+
+		for _, entry := range entries {
+			result, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO price_list_entries(price_list_id, product_id, variant_id, min_quantity, amount)
+				SELECT $1, $2::uuid, $3::uuid, $4, $5
+				WHERE $3::uuid IS NULL OR EXISTS(SELECT 1 FROM product_variants WHERE variant_id = $3::uuid AND product_id = $2::uuid)`,
+				priceListID,
+				entry.ProductID,
+				entry.VariantID,
+				entry.MinQuantity,
+				entry.Amount,
+			)
+			if err != nil {
+				switch {
+				case isPQError(err, foreignKeyViolation):
+					return servererrors.ErrProductNotFound
+				case isPQError(err, uniqueViolation):
+					return servererrors.ErrInvalidPriceListEntries
+				default:
+					return fmt.Errorf("failed to insert price list entry in pricing store: %w", err)
+				}
+			}
+
+			inserted, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to insert price list entry in pricing store: %w", err)
+			}
+
+			if inserted == 0 {
+				return servererrors.ErrVariantNotForProduct
+			}
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			"UPDATE price_lists SET updated_at = NOW() WHERE price_list_id = $1",
+			priceListID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update price list in pricing store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// findBasePrice looks up the catalog price of a product, its variant when
+// variantID is set, and its explicit price in currency.
+func (s *store) findBasePrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string) (*basePrice, error) {
+	base := new(basePrice)
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT p.status, p.currency, p.price, v.variant_id IS NOT NULL, v.price_override, pp.amount
+		FROM products p
+		LEFT JOIN product_variants v ON v.product_id = p.product_id AND v.variant_id = $2
+		LEFT JOIN product_prices pp ON pp.product_id = p.product_id AND pp.currency = $3
+		WHERE p.product_id = $1`,
+		productID,
+		variantID,
+		currency,
+	).Scan(
+		&base.Status,
+		&base.Currency,
+		&base.Price,
+		&base.VariantFound,
+		&base.PriceOverride,
+		&base.CurrencyPrice,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"failed to find base price in pricing store: %w",
+			err,
+		)
+	}
+
+	return base, nil
+}
+
+// findListPrice returns the winning entry among the price lists in currency
+// that are active at and apply to the user, or nil when none does. Lists are
+// ranked by priority, then group lists before lists for everyone. Among
+// those, an entry for the variant beats one for the whole product and the
+// highest tier reached by quantity applies.
+func (s *store) findListPrice(ctx context.Context, query *PriceQuery, currency string, at time.Time) (*listPrice, error) {
+	price := new(listPrice)
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT pl.price_list_id, e.min_quantity, e.amount
+		FROM price_list_entries e
+		JOIN price_lists pl ON pl.price_list_id = e.price_list_id
+		WHERE e.product_id = $1
+			AND (e.variant_id IS NULL OR e.variant_id = $2)
+			AND e.min_quantity <= $3
+			AND pl.currency = $4
+			AND (pl.starts_at IS NULL OR pl.starts_at <= $5)
+			AND (pl.ends_at IS NULL OR pl.ends_at > $5)
+			AND (pl.group_id IS NULL OR pl.group_id IN (SELECT group_id FROM customer_group_members WHERE user_id = $6))
+		ORDER BY pl.priority DESC, (pl.group_id IS NOT NULL) DESC,
+			(e.variant_id IS NOT NULL) DESC, e.min_quantity DESC, pl.price_list_id
+		LIMIT 1`,
+		query.ProductID,
+		query.VariantID,
+		query.Quantity,
+		currency,
+		at,
+		query.UserID,
+	).Scan(
+		&price.PriceListID,
+		&price.MinQuantity,
+		&price.Amount,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf(
+			"failed to find list price in pricing store: %w",
+			err,
+		)
+	}
+
+	return price, nil
+}
+
+func (s *store) getPriceListsWithContext(ctx context.Context, query string, args ...any) ([]*PriceList, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in pricing store getPriceListsWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	lists := []*PriceList{}
+	for rows.Next() {
+		list := new(PriceList)
+		err = rows.Scan(
+			&list.PriceListID,
+			&list.Name,
+			&list.Currency,
+			&list.GroupID,
+			&list.Priority,
+			&list.StartsAt,
+			&list.EndsAt,
+			&list.CreatedAt,
+			&list.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into price list in pricing store: %w",
+				err,
+			)
+		}
+
+		lists = append(lists, list)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in pricing store: %w",
+			err,
+		)
+	}
+
+	return lists, nil
+}
+
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in pricing store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in pricing store: %w", err)
+	}
+
+	return nil
+}
+
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
@@ -0,0 +1,83 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestProductAttributes(t *testing.T) {
+	router, productStore := newTestRouter(t)
+
+	laptop := &Product{ProductID: uuid.New(), SKU: "LAPTOP-1", Name: "Pines Book", Price: 99900, Currency: "USD", Status: StatusActive}
+	tablet := &Product{ProductID: uuid.New(), SKU: "TABLET-1", Name: "Pines Book Tab", Price: 49900, Currency: "USD", Status: StatusActive}
+	productStore.Products[laptop.ProductID] = laptop
+	productStore.Products[tablet.ProductID] = tablet
+	productStore.Schemas[laptop.ProductID] = []*attribute.Definition{
+		{Code: "ram", Type: attribute.TypeNumber, Unit: "GB", Required: true, Filterable: true},
+		{Code: "panel", Type: attribute.TypeEnum, Values: []string{"IPS", "OLED"}},
+		{Code: "touchscreen", Type: attribute.TypeBoolean},
+	}
+
+	laptopPath := "/admin/products/" + laptop.ProductID.String()
+
+	testCases := []struct {
+		name       string
+		path       string
+		attributes attribute.Values
+		expected   int
+	}{
+		{"should reject a missing required attribute", laptopPath, attribute.Values{"panel": "IPS"}, http.StatusUnprocessableEntity},
+		{"should reject a value of the wrong type", laptopPath, attribute.Values{"ram": "16GB"}, http.StatusUnprocessableEntity},
+		{"should reject an enum value not defined", laptopPath, attribute.Values{"ram": 16, "panel": "TN"}, http.StatusUnprocessableEntity},
+		{"should reject attributes no category defines", "/admin/products/" + tablet.ProductID.String(), attribute.Values{"ram": 8}, http.StatusUnprocessableEntity},
+		{"should accept attributes matching the schema", laptopPath, attribute.Values{"RAM": 16, "panel": "oled", "touchscreen": true}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := testutil.Send(t, router, http.MethodPatch, tc.path, UpdateProductRequest{Attributes: tc.attributes}, nil)
+			if rr.Code != tc.expected {
+				t.Errorf("expected status code %d, got %d: %s", tc.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	if laptop.Attributes["ram"] != float64(16) || laptop.Attributes["panel"] != "OLED" {
+		t.Fatalf("expected normalised attributes, got %v", laptop.Attributes)
+	}
+
+	t.Run("should keep attributes when the payload leaves them out", func(t *testing.T) {
+		name := "Pines Book Pro"
+		rr := testutil.Send(t, router, http.MethodPatch, laptopPath, UpdateProductRequest{Name: &name}, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if len(laptop.Attributes) != 3 {
+			t.Errorf("expected 3 attributes, got %v", laptop.Attributes)
+		}
+	})
+
+	t.Run("should filter search results by attribute", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodGet, "/products/search?q=pines&currency=USD&attr=ram:16", nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var body struct {
+			Data SearchProductsResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Data.TotalCount != 1 || body.Data.Results[0].Product.ProductID != laptop.ProductID {
+			t.Errorf("expected only the laptop to match, got %d results", body.Data.TotalCount)
+		}
+	})
+}
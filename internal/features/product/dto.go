@@ -0,0 +1,225 @@
+package product
+
+import (
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/google/uuid"
+)
+
+// Requests
+
+type CreateProductRequest struct {
+	SKU         string `json:"sku" validate:"required,min=3,max=64"`
+	Type        string `json:"type" validate:"omitempty,oneof=physical digital"`
+	Name        string `json:"name" validate:"required,min=2,max=255"`
+	Description string `json:"description" validate:"max=5000"`
+	Brand       string `json:"brand" validate:"max=128"`
+	Language    string `json:"language" validate:"omitempty,oneof=simple english french german spanish italian portuguese dutch"`
+	Price       int64  `json:"price" validate:"gte=0"`
+	Currency    string `json:"currency" validate:"required,len=3,alpha"`
+	Status      string `json:"status" validate:"omitempty,oneof=draft active"`
+	// SellerID is set from the session of the seller creating the product
+	SellerID *uuid.UUID `json:"-"`
+}
+
+// UpdateProductRequest only changes the fields that are set.
+type UpdateProductRequest struct {
+	SKU         *string `json:"sku" validate:"omitempty,min=3,max=64"`
+	Type        *string `json:"type" validate:"omitempty,oneof=physical digital"`
+	Name        *string `json:"name" validate:"omitempty,min=2,max=255"`
+	Description *string `json:"description" validate:"omitempty,max=5000"`
+	Brand       *string `json:"brand" validate:"omitempty,max=128"`
+	Language    *string `json:"language" validate:"omitempty,oneof=simple english french german spanish italian portuguese dutch"`
+	Price       *int64  `json:"price" validate:"omitempty,gte=0"`
+	Currency    *string `json:"currency" validate:"omitempty,len=3,alpha"`
+	Status      *string `json:"status" validate:"omitempty,oneof=draft active"`
+	// Attributes replace the attribute values of the product, they are
+	// checked against the attributes its categories define
+	Attributes attribute.Values `json:"attributes" validate:"omitempty,max=100"`
+	// SEOTitle, SEODescription and CanonicalURL are cleared with an empty
+	// string
+	SEOTitle       *string `json:"seoTitle" validate:"omitempty,max=255"`
+	SEODescription *string `json:"seoDescription" validate:"omitempty,max=500"`
+	CanonicalURL   *string `json:"canonicalUrl" validate:"omitempty,max=2048,len=0|http_url"`
+}
+
+type ListProductsRequest struct {
+	Page               int64  `validate:"min=1"`
+	PageSize           int64  `validate:"min=1,max=100"`
+	Status             string `validate:"omitempty,oneof=draft scheduled active archived"`
+	MinPrice           *int64 `validate:"omitempty,gte=0"`
+	MaxPrice           *int64 `validate:"omitempty,gte=0"`
+	CategoryID         *uuid.UUID
+	IncludeDescendants bool
+	// SellerID is set from the session of a seller listing its own products
+	SellerID *uuid.UUID
+	Sort     string `validate:"omitempty,oneof=name -name price -price created_at -created_at"`
+}
+
+// SearchProductsRequest is read from the query string, e.g.
+// ?q=running+shoe&currency=USD&brand=Pines&attr=Color:Red&minPrice=1000&page=2.
+// Prices are in minor units of Currency. Attributes match option values by
+// option name and category attributes by code, e.g. attr=ram:16.
+type SearchProductsRequest struct {
+	Query      string `validate:"required,max=200"`
+	Currency   string `validate:"required,len=3,alpha"`
+	Language   string `validate:"omitempty,oneof=simple english french german spanish italian portuguese dutch"`
+	CategoryID *uuid.UUID
+	Brands     []string          `validate:"max=20,dive,required,max=128"`
+	MinPrice   *int64            `validate:"omitempty,gte=0"`
+	MaxPrice   *int64            `validate:"omitempty,gte=0"`
+	Attributes map[string]string `validate:"max=10"`
+	Page       int64             `validate:"min=1"`
+	PageSize   int64             `validate:"min=1,max=100"`
+}
+
+// SetPricesRequest replaces the explicit prices of a product, e.g.
+// [{"amount": 1999, "currency": "EUR"}]. An empty list leaves only the base
+// price.
+type SetPricesRequest struct {
+	Prices []money.Money `json:"prices" validate:"max=50"`
+}
+
+type OptionRequest struct {
+	Name   string   `json:"name" validate:"required,min=1,max=64"`
+	Values []string `json:"values" validate:"required,min=1,max=50,dive,required,max=64"`
+}
+
+// SetOptionsRequest replaces the product options, an empty list removes all
+// options and variants.
+type SetOptionsRequest struct {
+	Options []OptionRequest `json:"options" validate:"max=3,dive"`
+}
+
+// VariantUpdate only changes the fields that are set. RemovePriceOverride and
+// RemoveBarcode clear those fields back to null.
+type VariantUpdate struct {
+	VariantID           uuid.UUID `json:"variantId" validate:"required"`
+	SKU                 *string   `json:"sku" validate:"omitempty,min=3,max=64"`
+	PriceOverride       *int64    `json:"priceOverride" validate:"omitempty,gte=0"`
+	RemovePriceOverride bool      `json:"removePriceOverride"`
+	Barcode             *string   `json:"barcode" validate:"omitempty,numeric,min=8,max=14"`
+	RemoveBarcode       bool      `json:"removeBarcode"`
+	WeightGrams         *int64    `json:"weightGrams" validate:"omitempty,gte=0"`
+	StockQuantity       *int64    `json:"stockQuantity" validate:"omitempty,gte=0"`
+}
+
+type BulkUpdateVariantsRequest struct {
+	Variants []VariantUpdate `json:"variants" validate:"required,min=1,max=250,dive"`
+}
+
+// ScheduleProductRequest sets when a product goes live, when it is archived,
+// or both. A draft or archived product given a PublishAt becomes scheduled,
+// an active product can only be given an UnpublishAt.
+type ScheduleProductRequest struct {
+	PublishAt   *time.Time `json:"publishAt" validate:"required_without=UnpublishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
+}
+
+// Responses
+
+type ListProductsResponse struct {
+	Products   []*Product `json:"products"`
+	Page       int64      `json:"page"`
+	PageSize   int64      `json:"pageSize"`
+	TotalCount int64      `json:"totalCount"`
+}
+
+// PreviewProductResponse is a product as the storefront would show it,
+// whatever its status.
+type PreviewProductResponse struct {
+	Product  *Product             `json:"product"`
+	Variants []*StorefrontVariant `json:"variants"`
+}
+
+// SlugRedirectResponse is the body of the permanent redirect from a previous
+// slug, Slug is the product's current slug in Locale.
+type SlugRedirectResponse struct {
+	ProductID uuid.UUID `json:"productId"`
+	Locale    string    `json:"locale"`
+	Slug      string    `json:"slug"`
+}
+
+type SetOptionsResponse struct {
+	Options  []*Option  `json:"options"`
+	Variants []*Variant `json:"variants"`
+}
+
+// StorefrontVariant is the customer facing view of a variant, stock levels are
+// reduced to whether it can be bought.
+type StorefrontVariant struct {
+	VariantID uuid.UUID         `json:"variantId"`
+	SKU       string            `json:"sku"`
+	Options   map[string]string `json:"options"`
+	Price     int64             `json:"price"`
+	Currency  string            `json:"currency"`
+	Available bool              `json:"available"`
+}
+
+type OptionValueAvailability struct {
+	Value     string `json:"value"`
+	Selected  bool   `json:"selected"`
+	Available bool   `json:"available"`
+}
+
+type OptionAvailability struct {
+	Name   string                    `json:"name"`
+	Values []OptionValueAvailability `json:"values"`
+}
+
+// ResolveVariantResponse holds the variant matching a full selection, if any,
+// and for every option value whether picking it keeps the rest of the
+// selection purchasable.
+type ResolveVariantResponse struct {
+	Variant *StorefrontVariant   `json:"variant"`
+	Options []OptionAvailability `json:"options"`
+}
+
+// SearchHighlights hold the matched terms wrapped in <b></b>, the description
+// is cut down to the fragments around the matches.
+type SearchHighlights struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type SearchResult struct {
+	Product    *Product         `json:"product"`
+	Highlights SearchHighlights `json:"highlights"`
+	Score      float64          `json:"score"`
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// PriceRangeFacet counts products priced from Min up to but excluding Max, an
+// open end is null.
+type PriceRangeFacet struct {
+	Min   *int64 `json:"min"`
+	Max   *int64 `json:"max"`
+	Count int64  `json:"count"`
+}
+
+type AttributeFacet struct {
+	Name   string       `json:"name"`
+	Values []FacetValue `json:"values"`
+}
+
+type SearchFacets struct {
+	Categories  []FacetValue      `json:"categories"`
+	Brands      []FacetValue      `json:"brands"`
+	PriceRanges []PriceRangeFacet `json:"priceRanges"`
+	Attributes  []AttributeFacet  `json:"attributes"`
+}
+
+type SearchProductsResponse struct {
+	Results    []*SearchResult `json:"results"`
+	Facets     *SearchFacets   `json:"facets"`
+	Page       int64           `json:"page"`
+	PageSize   int64           `json:"pageSize"`
+	TotalCount int64           `json:"totalCount"`
+}
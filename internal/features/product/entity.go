@@ -0,0 +1,180 @@
+package product
+
+import (
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/google/uuid"
+)
+
+// A product moves from draft to scheduled to active to archived, only active
+// products are shown to customers.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusActive    = "active"
+	StatusArchived  = "archived"
+)
+
+// Physical products are shipped, digital ones are downloaded.
+const (
+	TypePhysical = "physical"
+	TypeDigital  = "digital"
+)
+
+type Product struct {
+	ProductID uuid.UUID `json:"product_id"`
+	SKU       string    `json:"sku"`
+	Type      string    `json:"type"`
+	// SellerID is the marketplace seller selling the product, nil for the
+	// products sold by the shop itself
+	SellerID    *uuid.UUID `json:"seller_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Brand       string     `json:"brand"`
+	Language    string     `json:"language"` // text search configuration used to stem name and description
+	Price       int64      `json:"price"`    // in minor units of currency e.g. cents
+	Currency    string     `json:"currency"`
+	// Prices are explicit prices in currencies other than Currency
+	Prices []money.Money `json:"prices"`
+	Status string        `json:"status"`
+	// PublishAt is when a scheduled product goes live and UnpublishAt when an
+	// active one is archived, each is cleared once the scheduler acts on it
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+	// Attributes hold the values of the attributes the product categories
+	// define, keyed by attribute code
+	Attributes attribute.Values `json:"attributes"`
+	SEO        seo.Metadata     `json:"seo"`
+	// Slugs are the current slugs of the product keyed by locale
+	Slugs     map[string]string `json:"slugs"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Option is a product dimension such as size or color along with the values
+// it can take, in display order.
+type Option struct {
+	OptionID  uuid.UUID `json:"option_id"`
+	ProductID uuid.UUID `json:"product_id"`
+	Name      string    `json:"name"`
+	Values    []string  `json:"values"`
+	Position  int       `json:"position"`
+}
+
+// Variant is one purchasable combination of a product's option values.
+type Variant struct {
+	VariantID     uuid.UUID         `json:"variant_id"`
+	ProductID     uuid.UUID         `json:"product_id"`
+	SKU           string            `json:"sku"`
+	Options       map[string]string `json:"options"`
+	PriceOverride *int64            `json:"price_override"`
+	Price         int64             `json:"price"` // price override or the product price
+	Barcode       *string           `json:"barcode"`
+	WeightGrams   *int64            `json:"weight_grams"`
+	StockQuantity int64             `json:"stock_quantity"`
+	// ReservedQuantity is the part of the stock held by checkouts in
+	// progress, it can not be sold to anyone else
+	ReservedQuantity int64     `json:"reserved_quantity"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// inStock tells whether some of the stock of the variant is not reserved.
+func (v *Variant) inStock() bool {
+	return v.StockQuantity > v.ReservedQuantity
+}
+
+// Import job statuses.
+const (
+	ImportStatusQueued    = "queued"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// ImportJob is a catalog import running in the background, ProcessedRows out
+// of TotalRows tells its progress and Report is set once it completes.
+type ImportJob struct {
+	JobID         uuid.UUID     `json:"job_id"`
+	Format        string        `json:"format"`
+	DryRun        bool          `json:"dry_run"`
+	Status        string        `json:"status"`
+	BlobKey       string        `json:"-"`
+	TotalRows     int           `json:"total_rows"`
+	ProcessedRows int           `json:"processed_rows"`
+	Report        *ImportReport `json:"report"`
+	Error         string        `json:"error,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	StartedAt     *time.Time    `json:"started_at"`
+	FinishedAt    *time.Time    `json:"finished_at"`
+}
+
+// ImportReport sums up an import, a dry run reports what would have been
+// created and updated.
+type ImportReport struct {
+	TotalRows int         `json:"total_rows"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Failed    int         `json:"failed"`
+	Errors    []*RowError `json:"errors"`
+	// ErrorsTruncated is set when more rows failed than Errors lists
+	ErrorsTruncated bool `json:"errors_truncated"`
+}
+
+// RowError lists why one row of an import failed, Line is its line in the
+// file.
+type RowError struct {
+	Line       int                       `json:"line"`
+	SKU        string                    `json:"sku"`
+	VariantSKU string                    `json:"variant_sku,omitempty"`
+	Errors     validate.ValidationErrors `json:"errors"`
+}
+
+// listFilter is what the store needs to page through products.
+type listFilter struct {
+	Statuses []string
+	MinPrice *int64
+	MaxPrice *int64
+	// CategoryID limits the products to a category, or to its whole subtree
+	// with IncludeDescendants
+	CategoryID         *uuid.UUID
+	IncludeDescendants bool
+	// SellerID limits the products to those of a seller
+	SellerID *uuid.UUID
+	Sort     string
+	Limit    int64
+	Offset   int64
+}
+
+// searchFilter is what the store needs to run a full text search.
+type searchFilter struct {
+	Query    string
+	Language string
+	// Currency limits the search to products priced in it, so prices and their
+	// bounds compare in the same minor unit
+	Currency string
+	Statuses []string
+	// CategoryID limits the search to a category and its whole subtree
+	CategoryID *uuid.UUID
+	Brands     []string
+	MinPrice   *int64
+	MaxPrice   *int64
+	// Attributes maps option names to the value products must offer
+	Attributes map[string]string
+	Limit      int64
+	Offset     int64
+}
+
+// facetCount is one row of the facet query, Key identifies the bucket within
+// its Facet and Label is what to show for it.
+type facetCount struct {
+	Facet string
+	Key   string
+	Label string
+	Count int64
+}
@@ -0,0 +1,573 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	createProduct(ctx context.Context, payload *CreateProductRequest) (*Product, error)
+	updateProduct(ctx context.Context, productID uuid.UUID, payload *UpdateProductRequest) (*Product, error)
+	archiveProduct(ctx context.Context, productID uuid.UUID) error
+	setPrices(ctx context.Context, productID uuid.UUID, payload *SetPricesRequest) (*Product, error)
+	getProduct(ctx context.Context, productID uuid.UUID, includeUnpublished bool) (*Product, error)
+	listProducts(ctx context.Context, payload *ListProductsRequest, includeUnpublished bool) (*ListProductsResponse, error)
+	searchProducts(ctx context.Context, payload *SearchProductsRequest) (*SearchProductsResponse, error)
+	setOptions(ctx context.Context, productID uuid.UUID, payload *SetOptionsRequest) (*SetOptionsResponse, error)
+	bulkUpdateVariants(ctx context.Context, productID uuid.UUID, payload *BulkUpdateVariantsRequest) ([]*Variant, error)
+	listVariants(ctx context.Context, productID uuid.UUID, includeUnpublished bool) ([]*Variant, error)
+	listStorefrontVariants(ctx context.Context, productID uuid.UUID) ([]*StorefrontVariant, error)
+	resolveVariant(ctx context.Context, productID uuid.UUID, selection map[string]string) (*ResolveVariantResponse, error)
+	getProductBySlug(ctx context.Context, locale string, slug string) (*Product, string, error)
+	listSlugs(ctx context.Context, productID uuid.UUID) ([]*seo.Slug, error)
+	setSlug(ctx context.Context, productID uuid.UUID, locale string, payload *seo.SetSlugRequest) (*seo.Slug, error)
+	scheduleProduct(ctx context.Context, productID uuid.UUID, payload *ScheduleProductRequest) (*Product, error)
+	unscheduleProduct(ctx context.Context, productID uuid.UUID) (*Product, error)
+	previewProduct(ctx context.Context, productID uuid.UUID) (*PreviewProductResponse, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+const defaultPageSize = 20
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	router.Get(
+		"/products",
+		handlerutils.MakeHandler(h.listProductsHandler(false)),
+	)
+	router.Get(
+		"/products/search",
+		handlerutils.MakeHandler(h.searchProductsHandler),
+	)
+	router.Get(
+		"/products/by-slug/{slug}",
+		handlerutils.MakeHandler(h.getProductBySlugHandler),
+	)
+	router.Get(
+		"/products/{productID}",
+		handlerutils.MakeHandler(h.getProductHandler(false)),
+	)
+	router.Get(
+		"/products/{productID}/variants",
+		handlerutils.MakeHandler(h.listStorefrontVariantsHandler),
+	)
+	router.Get(
+		"/products/{productID}/variants/resolve",
+		handlerutils.MakeHandler(h.resolveVariantHandler),
+	)
+	router.Get(
+		"/categories/{categoryID}/products",
+		handlerutils.MakeHandler(h.listProductsHandler(false)),
+	)
+}
+
+// RegisterAdminRoutes registers the catalog management routes relative to the
+// admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/products",
+		handlerutils.MakeHandler(h.listProductsHandler(true)),
+	)
+	authenticated.Get(
+		"/products/{productID}",
+		handlerutils.MakeHandler(h.getProductHandler(true)),
+	)
+	authenticated.Post(
+		"/products",
+		handlerutils.MakeHandler(h.createProductHandler),
+	)
+	authenticated.Patch(
+		"/products/{productID}",
+		handlerutils.MakeHandler(h.updateProductHandler),
+	)
+	authenticated.Post(
+		"/products/{productID}/archive",
+		handlerutils.MakeHandler(h.archiveProductHandler),
+	)
+	authenticated.Put(
+		"/products/{productID}/prices",
+		handlerutils.MakeHandler(h.setPricesHandler),
+	)
+	authenticated.Put(
+		"/products/{productID}/options",
+		handlerutils.MakeHandler(h.setOptionsHandler),
+	)
+	authenticated.Get(
+		"/products/{productID}/variants",
+		handlerutils.MakeHandler(h.listVariantsHandler),
+	)
+	authenticated.Patch(
+		"/products/{productID}/variants",
+		handlerutils.MakeHandler(h.bulkUpdateVariantsHandler),
+	)
+	authenticated.Get(
+		"/products/{productID}/slugs",
+		handlerutils.MakeHandler(seo.ListSlugsHandler("productID", h.service.listSlugs, slugError)),
+	)
+	authenticated.Put(
+		"/products/{productID}/slugs/{locale}",
+		handlerutils.MakeHandler(seo.SetSlugHandler("productID", h.service.setSlug, slugError)),
+	)
+	authenticated.Get(
+		"/products/{productID}/preview",
+		handlerutils.MakeHandler(h.previewProductHandler),
+	)
+	authenticated.Put(
+		"/products/{productID}/schedule",
+		handlerutils.MakeHandler(h.scheduleProductHandler),
+	)
+	authenticated.Delete(
+		"/products/{productID}/schedule",
+		handlerutils.MakeHandler(h.unscheduleProductHandler),
+	)
+}
+
+func (h *handler) createProductHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *CreateProductRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	// products created by a seller are sold by that seller
+	if sellerID, ok := sellerIDFromContext(ctx); ok {
+		payload.SellerID = &sellerID
+	}
+
+	product, err := h.service.createProduct(ctx, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrProductAlreadyExists):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrProductAlreadyExists.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrUnsupportedCurrency):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrUnsupportedCurrency.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"product created",
+		product,
+	)
+}
+
+func (h *handler) updateProductHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *UpdateProductRequest
+	var err error
+	defer r.Body.Close()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	product, err := h.service.updateProduct(ctx, productID, payload)
+	if err != nil {
+		var attributeErrors validate.ValidationErrors
+		switch {
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrProductAlreadyExists):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrProductAlreadyExists.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrUnsupportedCurrency):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrUnsupportedCurrency.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrInvalidPrices):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrInvalidPrices.Error(),
+				nil,
+			)
+		case errors.As(err, &attributeErrors):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrValidationFailed.Error(),
+				attributeErrors,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"product updated",
+		product,
+	)
+}
+
+func (h *handler) setPricesHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *SetPricesRequest
+	var err error
+	defer r.Body.Close()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		if errors.Is(err, money.ErrUnknownCurrency) {
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrUnsupportedCurrency.Error(),
+				nil,
+			)
+		}
+
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	product, err := h.service.setPrices(ctx, productID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrInvalidPrices):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrInvalidPrices.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"product prices updated",
+		product,
+	)
+}
+
+func (h *handler) archiveProductHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.archiveProduct(ctx, productID); err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"product archived",
+		nil,
+	)
+}
+
+func (h *handler) getProductHandler(includeUnpublished bool) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		product, err := h.service.getProduct(ctx, productID, includeUnpublished)
+		if err != nil {
+			switch {
+			case errors.Is(err, servererrors.ErrProductNotFound):
+				return servererrors.New(
+					http.StatusNotFound,
+					servererrors.ErrProductNotFound.Error(),
+					nil,
+				)
+			default:
+				return err
+			}
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"product found",
+			product,
+		)
+	}
+}
+
+func (h *handler) listProductsHandler(includeUnpublished bool) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		payload, err := parseListProductsRequest(r)
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidQueryParams.Error(),
+				nil,
+			)
+		}
+
+		if err = validate.StructFields(payload); err != nil {
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrValidationFailed.Error(),
+				err,
+			)
+		}
+
+		// sellers only ever list their own products
+		if sellerID, ok := sellerIDFromContext(ctx); ok {
+			payload.SellerID = &sellerID
+		}
+
+		resp, err := h.service.listProducts(ctx, payload, includeUnpublished)
+		if err != nil {
+			switch {
+			case errors.Is(err, servererrors.ErrInvalidQueryParams):
+				return servererrors.New(
+					http.StatusBadRequest,
+					servererrors.ErrInvalidQueryParams.Error(),
+					nil,
+				)
+			case errors.Is(err, servererrors.ErrCategoryNotFound):
+				return servererrors.New(
+					http.StatusNotFound,
+					servererrors.ErrCategoryNotFound.Error(),
+					nil,
+				)
+			default:
+				return err
+			}
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"products found",
+			resp,
+		)
+	}
+}
+
+// parseListProductsRequest reads the list query parameters, e.g.
+// ?page=2&pageSize=20&status=active&minPrice=100&maxPrice=5000&sort=-price
+// The category comes from the route when listing /categories/{categoryID}/products
+// and from ?categoryId otherwise, ?includeDescendants=true widens it to the
+// whole subtree. ?sellerId limits the products to those of a seller.
+func parseListProductsRequest(r *http.Request) (*ListProductsRequest, error) {
+	page, err := handlerutils.ParseQueryInt(r, "page", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize, err := handlerutils.ParseQueryInt(r, "pageSize", defaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	minPrice, err := handlerutils.ParseOptionalQueryInt(r, "minPrice")
+	if err != nil {
+		return nil, err
+	}
+
+	maxPrice, err := handlerutils.ParseOptionalQueryInt(r, "maxPrice")
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.URL.Query()
+
+	var categoryID *uuid.UUID
+	categoryIDStr := chi.URLParam(r, "categoryID")
+	if categoryIDStr == "" {
+		categoryIDStr = query.Get("categoryId")
+	}
+
+	if categoryIDStr != "" {
+		id, err := uuid.Parse(categoryIDStr)
+		if err != nil {
+			return nil, err
+		}
+		categoryID = &id
+	}
+
+	var sellerID *uuid.UUID
+	if value := query.Get("sellerId"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		sellerID = &id
+	}
+
+	includeDescendants := false
+	if value := query.Get("includeDescendants"); value != "" {
+		includeDescendants, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ListProductsRequest{
+		Page:               page,
+		PageSize:           pageSize,
+		Status:             query.Get("status"),
+		MinPrice:           minPrice,
+		MaxPrice:           maxPrice,
+		CategoryID:         categoryID,
+		IncludeDescendants: includeDescendants,
+		SellerID:           sellerID,
+		Sort:               query.Get("sort"),
+	}, nil
+}
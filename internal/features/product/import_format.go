@@ -0,0 +1,396 @@
+package product
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+)
+
+// Catalog file formats, both hold the same rows.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// maxImportRows bounds a single import, larger catalogs are split over
+// several files.
+const maxImportRows = 50_000
+
+// importColumns are the CSV header names, in export order. They match the
+// JSON names of ImportRow.
+var importColumns = []string{
+	"sku", "type", "name", "description", "brand", "language", "status", "currency", "price", "prices", "options",
+	"variantSku", "variantOptions", "priceOverride", "barcode", "weightGrams", "stock",
+}
+
+// ImportRow is one line of a catalog file. A row without a variant sku
+// upserts the product with sku, setting the fields present. A row with one
+// updates a variant of that product, found by variant sku or else by its
+// option values, whose sku then becomes the variant sku. Variants themselves
+// come from the product options.
+//
+// In CSV files prices are written as EUR:1999;GBP:1799, options as
+// Size=S|M|L;Color=Red|Blue and variant options as Size=M;Color=Red.
+type ImportRow struct {
+	SKU            string            `json:"sku"`
+	Type           *string           `json:"type,omitempty"`
+	Name           *string           `json:"name,omitempty"`
+	Description    *string           `json:"description,omitempty"`
+	Brand          *string           `json:"brand,omitempty"`
+	Language       *string           `json:"language,omitempty"`
+	Status         *string           `json:"status,omitempty"`
+	Currency       *string           `json:"currency,omitempty"`
+	Price          *int64            `json:"price,omitempty"`
+	Prices         []money.Money     `json:"prices,omitempty"`
+	Options        []OptionRequest   `json:"options,omitempty"`
+	VariantSKU     string            `json:"variantSku,omitempty"`
+	VariantOptions map[string]string `json:"variantOptions,omitempty"`
+	PriceOverride  *int64            `json:"priceOverride,omitempty"`
+	Barcode        *string           `json:"barcode,omitempty"`
+	WeightGrams    *int64            `json:"weightGrams,omitempty"`
+	Stock          *int64            `json:"stock,omitempty"`
+}
+
+// importLine is a parsed row along with its 1-based line in the file, rows
+// that could not be read carry their errors instead.
+type importLine struct {
+	Line   int
+	Row    *ImportRow
+	Errors validate.ValidationErrors
+}
+
+// parseImport reads every row of a catalog file. Problems with a single row
+// are reported on its line, only an unreadable file fails as a whole.
+func parseImport(data []byte, format string) ([]*importLine, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(data)
+	case FormatJSONL:
+		return parseJSONL(data)
+	default:
+		return nil, servererrors.ErrUnsupportedImportFormat
+	}
+}
+
+func parseJSONL(data []byte) ([]*importLine, error) {
+	lines := []*importLine{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	for n := 1; scanner.Scan(); n++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		if len(lines) == maxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", servererrors.ErrInvalidImportFile, maxImportRows)
+		}
+
+		line := &importLine{Line: n, Row: new(ImportRow)}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(line.Row); err != nil {
+			line.Row = nil
+			line.Errors = validate.ValidationErrors{rowError("", "invalid json: "+err.Error(), "ROW_INVALID")}
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", servererrors.ErrInvalidImportFile, err)
+	}
+
+	return lines, nil
+}
+
+func parseCSV(data []byte) ([]*importLine, error) {
+	// spreadsheets often save a byte order mark in front of the header
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", servererrors.ErrInvalidImportFile)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(importColumns, name) {
+			return nil, fmt.Errorf("%w: unknown column %q", servererrors.ErrInvalidImportFile, name)
+		}
+		columns[name] = i
+	}
+
+	if _, ok := columns["sku"]; !ok {
+		return nil, fmt.Errorf("%w: missing sku column", servererrors.ErrInvalidImportFile)
+	}
+
+	lines := []*importLine{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("%w: %v", servererrors.ErrInvalidImportFile, err)
+			}
+
+			return nil, fmt.Errorf("%w: line %d: %v", servererrors.ErrInvalidImportFile, parseErr.StartLine, parseErr.Err)
+		}
+
+		if len(lines) == maxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", servererrors.ErrInvalidImportFile, maxImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		lines = append(lines, csvLine(line, record, columns))
+	}
+
+	return lines, nil
+}
+
+// csvLine converts a record into a row, empty cells leave the field unset.
+func csvLine(line int, record []string, columns map[string]int) *importLine {
+	result := &importLine{Line: line, Row: new(ImportRow)}
+	row := result.Row
+
+	cell := func(name string) *string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) || strings.TrimSpace(record[i]) == "" {
+			return nil
+		}
+
+		value := strings.TrimSpace(record[i])
+		return &value
+	}
+
+	integer := func(name string) *int64 {
+		value := cell(name)
+		if value == nil {
+			return nil
+		}
+
+		n, err := strconv.ParseInt(*value, 10, 64)
+		if err != nil {
+			result.Errors = append(result.Errors, rowError(name, name+" must be a whole number", strings.ToUpper(name)+"_INVALID"))
+			return nil
+		}
+
+		return &n
+	}
+
+	if value := cell("sku"); value != nil {
+		row.SKU = *value
+	}
+	row.Type = cell("type")
+	row.Name = cell("name")
+	row.Description = cell("description")
+	row.Brand = cell("brand")
+	row.Language = cell("language")
+	row.Status = cell("status")
+	row.Currency = cell("currency")
+	row.Price = integer("price")
+	if value := cell("variantSku"); value != nil {
+		row.VariantSKU = *value
+	}
+	row.PriceOverride = integer("priceOverride")
+	row.Barcode = cell("barcode")
+	row.WeightGrams = integer("weightGrams")
+	row.Stock = integer("stock")
+
+	if value := cell("prices"); value != nil {
+		prices, err := parsePricesCell(*value)
+		if err != nil {
+			result.Errors = append(result.Errors, rowError("prices", err.Error(), "PRICES_INVALID"))
+		}
+		row.Prices = prices
+	}
+
+	if value := cell("options"); value != nil {
+		row.Options = parseOptionsCell(*value)
+	}
+
+	if value := cell("variantOptions"); value != nil {
+		selection, err := parseSelectionCell(*value)
+		if err != nil {
+			result.Errors = append(result.Errors, rowError("variantOptions", err.Error(), "VARIANTOPTIONS_INVALID"))
+		}
+		row.VariantOptions = selection
+	}
+
+	return result
+}
+
+// parsePricesCell reads EUR:1999;GBP:1799.
+func parsePricesCell(value string) ([]money.Money, error) {
+	prices := []money.Money{}
+	for _, part := range strings.Split(value, ";") {
+		code, amount, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("prices must look like EUR:1999;GBP:1799")
+		}
+
+		n, err := strconv.ParseInt(strings.TrimSpace(amount), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("prices must be whole numbers of minor units")
+		}
+
+		price, err := money.New(n, strings.TrimSpace(code))
+		if err != nil {
+			return nil, fmt.Errorf("prices has an unsupported currency %s", code)
+		}
+
+		prices = append(prices, price)
+	}
+
+	return prices, nil
+}
+
+// parseOptionsCell reads Size=S|M|L;Color=Red|Blue, leaving the validation
+// to the options rules.
+func parseOptionsCell(value string) []OptionRequest {
+	options := []OptionRequest{}
+	for _, part := range strings.Split(value, ";") {
+		name, values, _ := strings.Cut(part, "=")
+		option := OptionRequest{Name: strings.TrimSpace(name), Values: []string{}}
+		for _, v := range strings.Split(values, "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				option.Values = append(option.Values, v)
+			}
+		}
+
+		options = append(options, option)
+	}
+
+	return options
+}
+
+// parseSelectionCell reads Size=M;Color=Red.
+func parseSelectionCell(value string) (map[string]string, error) {
+	selection := map[string]string{}
+	for _, part := range strings.Split(value, ";") {
+		name, v, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("variantOptions must look like Size=M;Color=Red")
+		}
+
+		selection[strings.TrimSpace(name)] = strings.TrimSpace(v)
+	}
+
+	return selection, nil
+}
+
+// rowWriter writes rows in one of the catalog formats.
+type rowWriter interface {
+	Write(row *ImportRow) error
+	Flush() error
+}
+
+func newRowWriter(w io.Writer, format string) (rowWriter, error) {
+	switch format {
+	case FormatCSV:
+		cw := &csvRowWriter{w: csv.NewWriter(w)}
+		return cw, cw.w.Write(importColumns)
+	case FormatJSONL:
+		return &jsonlRowWriter{encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, servererrors.ErrUnsupportedImportFormat
+	}
+}
+
+type jsonlRowWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlRowWriter) Write(row *ImportRow) error {
+	return j.encoder.Encode(row)
+}
+
+func (j *jsonlRowWriter) Flush() error {
+	return nil
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) Write(row *ImportRow) error {
+	text := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+
+	integer := func(value *int64) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.FormatInt(*value, 10)
+	}
+
+	prices := make([]string, 0, len(row.Prices))
+	for _, price := range row.Prices {
+		prices = append(prices, fmt.Sprintf("%s:%d", price.Currency().Code(), price.Amount()))
+	}
+
+	options := make([]string, 0, len(row.Options))
+	for _, option := range row.Options {
+		options = append(options, option.Name+"="+strings.Join(option.Values, "|"))
+	}
+
+	selection := make([]string, 0, len(row.VariantOptions))
+	for name, value := range row.VariantOptions {
+		selection = append(selection, name+"="+value)
+	}
+	slices.Sort(selection)
+
+	return c.w.Write([]string{
+		row.SKU,
+		text(row.Type),
+		text(row.Name),
+		text(row.Description),
+		text(row.Brand),
+		text(row.Language),
+		text(row.Status),
+		text(row.Currency),
+		integer(row.Price),
+		strings.Join(prices, ";"),
+		strings.Join(options, ";"),
+		row.VariantSKU,
+		strings.Join(selection, ";"),
+		integer(row.PriceOverride),
+		text(row.Barcode),
+		integer(row.WeightGrams),
+		integer(row.Stock),
+	})
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func rowError(field, msg, code string) validate.ValidationError {
+	return validate.ValidationError{
+		Field: field,
+		Msg:   msg,
+		Code:  code,
+	}
+}
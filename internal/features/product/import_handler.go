@@ -0,0 +1,238 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type importServicer interface {
+	startImport(ctx context.Context, data []byte, format string, dryRun bool) (*ImportJob, error)
+	getImportJob(ctx context.Context, jobID uuid.UUID) (*ImportJob, error)
+	Export(ctx context.Context, w io.Writer, format string) error
+}
+
+// formatContentTypes are the content types of the catalog file formats.
+var formatContentTypes = map[string]string{
+	FormatCSV:   "text/csv",
+	FormatJSONL: "application/jsonl",
+}
+
+type importHandler struct {
+	service       importServicer
+	authenticator authenticator
+}
+
+func NewImportHandler(service importServicer, authenticator authenticator) *importHandler {
+	return &importHandler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+// RegisterAdminRoutes registers the catalog import and export routes relative
+// to the admin route group.
+func (h *importHandler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Post(
+		"/products/import",
+		handlerutils.MakeHandler(h.startImportHandler),
+	)
+	authenticated.Get(
+		"/products/import/{jobID}",
+		handlerutils.MakeHandler(h.getImportJobHandler),
+	)
+	authenticated.Get(
+		"/products/export",
+		handlerutils.MakeHandler(h.exportHandler),
+	)
+}
+
+// startImportHandler queues the catalog file in the request body, e.g.
+// ?format=csv&dryRun=true. Without a format the content type decides.
+func (h *importHandler) startImportHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	dryRun, err := parseQueryBool(r, "dryRun")
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	defer r.Body.Close()
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return servererrors.New(
+				http.StatusRequestEntityTooLarge,
+				servererrors.ErrFileTooLarge.Error(),
+				map[string]int{"maxBytes": maxImportBytes},
+			)
+		}
+
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	job, err := h.service.startImport(ctx, data, requestFormat(r), dryRun)
+	if err != nil {
+		return importError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusAccepted,
+		"import queued",
+		job,
+	)
+}
+
+func (h *importHandler) getImportJobHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	job, err := h.service.getImportJob(ctx, jobID)
+	if err != nil {
+		return importError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"import job found",
+		job,
+	)
+}
+
+// exportHandler streams the whole catalog in the format of ?format=, csv by
+// default, ready to be edited and imported back.
+func (h *importHandler) exportHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(5 * time.Minute),
+	)
+	defer cancel()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatCSV
+	}
+
+	contentType, ok := formatContentTypes[format]
+	if !ok {
+		return importError(servererrors.ErrUnsupportedImportFormat)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", "catalog."+format),
+	)
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.service.Export(ctx, w, format); err != nil {
+		// the status is already written, all that is left is to log it
+		log.Println(err)
+	}
+
+	return nil
+}
+
+// requestFormat is the ?format= of the request, or else the format its
+// content type names.
+func requestFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	for format, contentType := range formatContentTypes {
+		if mediaType == contentType {
+			return format
+		}
+	}
+
+	return ""
+}
+
+func parseQueryBool(r *http.Request, key string) (bool, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// importError maps the errors shared by the import handlers to responses.
+func importError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrImportJobNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrImportJobNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrUnsupportedImportFormat):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrUnsupportedImportFormat.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidImportFile):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidImportFile.Error(),
+			map[string]string{"reason": err.Error()},
+		)
+	case errors.Is(err, servererrors.ErrFileTooLarge):
+		return servererrors.New(
+			http.StatusRequestEntityTooLarge,
+			servererrors.ErrFileTooLarge.Error(),
+			map[string]int{"maxBytes": maxImportBytes},
+		)
+	default:
+		return err
+	}
+}
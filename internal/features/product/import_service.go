@@ -0,0 +1,672 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/google/uuid"
+)
+
+type importJobStorer interface {
+	createImportJob(ctx context.Context, job *ImportJob) error
+	findImportJob(ctx context.Context, jobID uuid.UUID) (*ImportJob, error)
+	claimImportJob(ctx context.Context, staleAfter time.Duration) (*ImportJob, error)
+	updateImportProgress(ctx context.Context, jobID uuid.UUID, processedRows int) error
+	finishImportJob(ctx context.Context, job *ImportJob) error
+}
+
+const (
+	maxImportBytes = 20 << 20 // 20MB
+	// progressEvery is how many rows are imported between progress updates
+	progressEvery = 100
+	// maxReportedErrors bounds the row errors kept in a report
+	maxReportedErrors = 1000
+	// staleImportAfter is how long a running job may go without progress
+	// before another worker takes it over
+	staleImportAfter = 10 * time.Minute
+	exportPageSize   = 500
+)
+
+// Row outcomes counted in the import report.
+const (
+	rowCreated = "created"
+	rowUpdated = "updated"
+)
+
+type importer struct {
+	service   *service
+	jobStore  importJobStorer
+	blobStore blobstore.BlobStore
+	wake      chan struct{}
+}
+
+// NewImporter returns the catalog importer and exporter built on the product
+// service, so imported rows go through the same rules as the admin routes.
+// blobStore holds uploaded files until their job runs, it is only needed by
+// background jobs.
+func NewImporter(service *service, jobStore importJobStorer, blobStore blobstore.BlobStore) *importer {
+	return &importer{
+		service:   service,
+		jobStore:  jobStore,
+		blobStore: blobStore,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// startImport checks that data is a readable catalog file and queues it as
+// a background job.
+func (i *importer) startImport(ctx context.Context, data []byte, format string, dryRun bool) (*ImportJob, error) {
+	if len(data) > maxImportBytes {
+		return nil, servererrors.ErrFileTooLarge
+	}
+
+	lines, err := parseImport(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &ImportJob{
+		JobID:     uuid.New(),
+		Format:    format,
+		DryRun:    dryRun,
+		Status:    ImportStatusQueued,
+		TotalRows: len(lines),
+	}
+	job.BlobKey = fmt.Sprintf("imports/%s.%s", job.JobID, format)
+
+	if err = i.blobStore.Put(ctx, job.BlobKey, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	if err = i.jobStore.createImportJob(ctx, job); err != nil {
+		if deleteErr := i.blobStore.Delete(context.Background(), job.BlobKey); deleteErr != nil {
+			log.Println(deleteErr)
+		}
+
+		return nil, err
+	}
+
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+
+	return i.jobStore.findImportJob(ctx, job.JobID)
+}
+
+func (i *importer) getImportJob(ctx context.Context, jobID uuid.UUID) (*ImportJob, error) {
+	job, err := i.jobStore.findImportJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.JobID == uuid.Nil {
+		return nil, servererrors.ErrImportJobNotFound
+	}
+
+	return job, nil
+}
+
+// RunImportWorker runs queued import jobs one at a time, as soon as they are
+// started and every interval for jobs queued by other instances. It blocks
+// until ctx is done.
+func (i *importer) RunImportWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.wake:
+		case <-ticker.C:
+		}
+
+		for ctx.Err() == nil {
+			job, err := i.jobStore.claimImportJob(ctx, staleImportAfter)
+			if err != nil {
+				log.Println(err)
+				break
+			}
+
+			if job.JobID == uuid.Nil {
+				break
+			}
+
+			i.runJob(ctx, job)
+		}
+	}
+}
+
+func (i *importer) runJob(ctx context.Context, job *ImportJob) {
+	report, err := i.importBlob(ctx, job)
+	if err != nil {
+		log.Printf("failed to run import job %s: %v\n", job.JobID, err)
+		job.Status = ImportStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = ImportStatusCompleted
+		job.Report = report
+		job.ProcessedRows = report.TotalRows
+	}
+
+	if err = i.jobStore.finishImportJob(ctx, job); err != nil {
+		log.Println(err)
+		return
+	}
+
+	if err = i.blobStore.Delete(ctx, job.BlobKey); err != nil {
+		log.Println(err)
+	}
+}
+
+func (i *importer) importBlob(ctx context.Context, job *ImportJob) (*ImportReport, error) {
+	blob, err := i.blobStore.Get(ctx, job.BlobKey)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := parseImport(data, job.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.importLines(ctx, lines, job.DryRun, func(processed int) {
+		if err := i.jobStore.updateImportProgress(ctx, job.JobID, processed); err != nil {
+			log.Println(err)
+		}
+	})
+}
+
+// Import reads a whole catalog file from r and imports it right away, as the
+// command line does. A dry run only validates the rows.
+func (i *importer) Import(ctx context.Context, r io.Reader, format string, dryRun bool) (*ImportReport, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxImportBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxImportBytes {
+		return nil, servererrors.ErrFileTooLarge
+	}
+
+	lines, err := parseImport(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.importLines(ctx, lines, dryRun, nil)
+}
+
+// importRun is the state of one import. Dry runs write nothing, so planned
+// keeps the options and variants the products they touch would have, for the
+// rows further down.
+type importRun struct {
+	dryRun  bool
+	planned map[string]*plannedProduct
+}
+
+type plannedProduct struct {
+	options  []*Option
+	variants []*Variant
+}
+
+// importLines imports rows in file order, a failing row is reported and the
+// import goes on. Only unexpected errors such as a lost database connection
+// stop it.
+func (i *importer) importLines(ctx context.Context, lines []*importLine, dryRun bool, progress func(processed int)) (*ImportReport, error) {
+	run := &importRun{dryRun: dryRun, planned: map[string]*plannedProduct{}}
+	report := &ImportReport{TotalRows: len(lines), Errors: []*RowError{}}
+
+	for n, line := range lines {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		errs := line.Errors
+		outcome := ""
+		if len(errs) == 0 {
+			var err error
+			outcome, errs, err = i.importRow(ctx, run, line.Row)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.Line, err)
+			}
+		}
+
+		switch {
+		case len(errs) > 0:
+			report.Failed++
+			if len(report.Errors) == maxReportedErrors {
+				report.ErrorsTruncated = true
+				break
+			}
+
+			rowErr := &RowError{Line: line.Line, Errors: errs}
+			if line.Row != nil {
+				rowErr.SKU, rowErr.VariantSKU = line.Row.SKU, line.Row.VariantSKU
+			}
+			report.Errors = append(report.Errors, rowErr)
+		case outcome == rowCreated:
+			report.Created++
+		default:
+			report.Updated++
+		}
+
+		if progress != nil && (n+1)%progressEvery == 0 {
+			progress(n + 1)
+		}
+	}
+
+	return report, nil
+}
+
+// importRow imports one row, the returned validation errors explain why it
+// was rejected.
+func (i *importer) importRow(ctx context.Context, run *importRun, row *ImportRow) (string, validate.ValidationErrors, error) {
+	if normalizeSKU(row.SKU) == "" {
+		return "", validate.ValidationErrors{rowError("sku", "sku is required", "SKU_REQUIRED")}, nil
+	}
+
+	var outcome string
+	var err error
+	if row.VariantSKU == "" {
+		outcome, err = i.importProduct(ctx, run, row)
+	} else {
+		outcome, err = i.importVariant(ctx, run, row)
+	}
+	if err != nil {
+		errs, err := importRowErrors(err)
+		return "", errs, err
+	}
+
+	return outcome, nil, nil
+}
+
+func (i *importer) importProduct(ctx context.Context, run *importRun, row *ImportRow) (string, error) {
+	sku := normalizeSKU(row.SKU)
+	product, err := i.service.productStore.findBySKU(ctx, sku)
+	if err != nil {
+		return "", err
+	}
+
+	_, planned := run.planned[sku]
+	if product.ProductID == uuid.Nil && !planned {
+		return rowCreated, i.createProduct(ctx, run, row)
+	}
+
+	update := &UpdateProductRequest{
+		Type:        row.Type,
+		Name:        row.Name,
+		Description: row.Description,
+		Brand:       row.Brand,
+		Language:    row.Language,
+		Price:       row.Price,
+		Currency:    row.Currency,
+		Status:      row.Status,
+	}
+	if err = validate.StructFields(update); err != nil {
+		return "", err
+	}
+
+	if run.dryRun {
+		currency := product.Currency
+		if row.Currency != nil {
+			parsed, err := money.ParseCurrency(*row.Currency)
+			if err != nil {
+				return "", servererrors.ErrUnsupportedCurrency
+			}
+			currency = parsed.Code()
+		}
+
+		return rowUpdated, i.plan(ctx, run, product, currency, row)
+	}
+
+	if _, err = i.service.updateProduct(ctx, product.ProductID, update); err != nil {
+		return "", err
+	}
+
+	return rowUpdated, i.setPricesAndOptions(ctx, product.ProductID, row)
+}
+
+func (i *importer) createProduct(ctx context.Context, run *importRun, row *ImportRow) error {
+	create := &CreateProductRequest{
+		SKU:         row.SKU,
+		Type:        deref(row.Type),
+		Name:        deref(row.Name),
+		Description: deref(row.Description),
+		Brand:       deref(row.Brand),
+		Language:    deref(row.Language),
+		Price:       deref(row.Price),
+		Currency:    deref(row.Currency),
+		Status:      deref(row.Status),
+	}
+	if err := validate.StructFields(create); err != nil {
+		return err
+	}
+
+	currency, err := money.ParseCurrency(create.Currency)
+	if err != nil {
+		return servererrors.ErrUnsupportedCurrency
+	}
+
+	if run.dryRun {
+		return i.plan(ctx, run, new(Product), currency.Code(), row)
+	}
+
+	product, err := i.service.createProduct(ctx, create)
+	if err != nil {
+		return err
+	}
+
+	return i.setPricesAndOptions(ctx, product.ProductID, row)
+}
+
+// plan checks the prices and options of a product row without saving them,
+// working out the variants the options would give.
+func (i *importer) plan(ctx context.Context, run *importRun, product *Product, currency string, row *ImportRow) error {
+	if row.Prices != nil {
+		if err := checkPrices(currency, row.Prices); err != nil {
+			return err
+		}
+	}
+
+	planned, err := i.planned(ctx, run, product, normalizeSKU(row.SKU))
+	if err != nil {
+		return err
+	}
+
+	if row.Options == nil {
+		return nil
+	}
+
+	payload := &SetOptionsRequest{Options: row.Options}
+	if err = validate.StructFields(payload); err != nil {
+		return err
+	}
+
+	options, err := normalizeOptions(uuid.Nil, row.Options)
+	if err != nil {
+		return err
+	}
+
+	existingByKey := make(map[string]*Variant, len(planned.variants))
+	for _, variant := range planned.variants {
+		existingByKey[combinationKey(variant.Options)] = variant
+	}
+
+	variants := []*Variant{}
+	for _, combination := range combinations(options) {
+		variant, ok := existingByKey[combinationKey(combination)]
+		if !ok {
+			variant = &Variant{VariantID: uuid.New()}
+			variant.SKU = variantSKU(normalizeSKU(row.SKU), options, combination, variant.VariantID)
+		}
+		variant.Options = combination
+
+		variants = append(variants, variant)
+	}
+
+	planned.options, planned.variants = options, variants
+	return nil
+}
+
+// planned returns the plan of the product with sku, starting from what is
+// saved the first time a dry run touches it.
+func (i *importer) planned(ctx context.Context, run *importRun, product *Product, sku string) (*plannedProduct, error) {
+	if planned, ok := run.planned[sku]; ok {
+		return planned, nil
+	}
+
+	planned := &plannedProduct{}
+	if product.ProductID != uuid.Nil {
+		var err error
+		if planned.options, err = i.service.productStore.findOptions(ctx, product.ProductID); err != nil {
+			return nil, err
+		}
+
+		if planned.variants, err = i.service.productStore.findVariants(ctx, product.ProductID); err != nil {
+			return nil, err
+		}
+	}
+	run.planned[sku] = planned
+
+	return planned, nil
+}
+
+func (i *importer) setPricesAndOptions(ctx context.Context, productID uuid.UUID, row *ImportRow) error {
+	if row.Prices != nil {
+		if _, err := i.service.setPrices(ctx, productID, &SetPricesRequest{Prices: row.Prices}); err != nil {
+			return err
+		}
+	}
+
+	if row.Options != nil {
+		payload := &SetOptionsRequest{Options: row.Options}
+		if err := validate.StructFields(payload); err != nil {
+			return err
+		}
+
+		if _, err := i.service.setOptions(ctx, productID, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importVariant updates the variant with the row's variant sku, or else the
+// one with its variant options which then takes the variant sku.
+func (i *importer) importVariant(ctx context.Context, run *importRun, row *ImportRow) (string, error) {
+	sku := normalizeSKU(row.SKU)
+	variantSKU := normalizeSKU(row.VariantSKU)
+
+	update := &VariantUpdate{
+		VariantID:     uuid.New(), // replaced once the variant is found
+		SKU:           &variantSKU,
+		PriceOverride: row.PriceOverride,
+		Barcode:       row.Barcode,
+		WeightGrams:   row.WeightGrams,
+		StockQuantity: row.Stock,
+	}
+	if err := validate.StructFields(update); err != nil {
+		return "", err
+	}
+
+	product, err := i.service.productStore.findBySKU(ctx, sku)
+	if err != nil {
+		return "", err
+	}
+
+	if _, planned := run.planned[sku]; product.ProductID == uuid.Nil && !planned {
+		return "", servererrors.ErrProductNotFound
+	}
+
+	var variants []*Variant
+	if run.dryRun {
+		planned, err := i.planned(ctx, run, product, sku)
+		if err != nil {
+			return "", err
+		}
+		variants = planned.variants
+	} else if variants, err = i.service.productStore.findVariants(ctx, product.ProductID); err != nil {
+		return "", err
+	}
+
+	var variant *Variant
+	for _, v := range variants {
+		if v.SKU == variantSKU {
+			variant = v
+			break
+		}
+	}
+
+	if variant == nil && row.VariantOptions != nil {
+		key := combinationKey(row.VariantOptions)
+		for _, v := range variants {
+			if combinationKey(v.Options) == key {
+				variant = v
+				break
+			}
+		}
+	}
+
+	if variant == nil {
+		return "", servererrors.ErrVariantNotFound
+	}
+	update.VariantID = variant.VariantID
+
+	if run.dryRun {
+		// later rows find the variant by its new sku
+		variant.SKU = variantSKU
+		return rowUpdated, nil
+	}
+
+	_, err = i.service.bulkUpdateVariants(ctx, product.ProductID, &BulkUpdateVariantsRequest{
+		Variants: []VariantUpdate{*update},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return rowUpdated, nil
+}
+
+// Export writes every product, drafts included, followed by its variants in
+// the given format. The output imports back unchanged.
+func (i *importer) Export(ctx context.Context, w io.Writer, format string) error {
+	writer, err := newRowWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	for offset := int64(0); ; offset += exportPageSize {
+		products, _, err := i.service.productStore.list(ctx, &listFilter{
+			Sort:   "created_at",
+			Limit:  exportPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, product := range products {
+			if err = i.exportProduct(ctx, writer, product); err != nil {
+				return err
+			}
+		}
+
+		if len(products) < exportPageSize {
+			break
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (i *importer) exportProduct(ctx context.Context, writer rowWriter, product *Product) error {
+	options, err := i.service.productStore.findOptions(ctx, product.ProductID)
+	if err != nil {
+		return err
+	}
+
+	variants, err := i.service.productStore.findVariants(ctx, product.ProductID)
+	if err != nil {
+		return err
+	}
+
+	row := &ImportRow{
+		SKU:         product.SKU,
+		Type:        &product.Type,
+		Name:        &product.Name,
+		Description: &product.Description,
+		Brand:       &product.Brand,
+		Language:    &product.Language,
+		Status:      &product.Status,
+		Currency:    &product.Currency,
+		Price:       &product.Price,
+		Prices:      product.Prices,
+		Options:     make([]OptionRequest, 0, len(options)),
+	}
+
+	for _, option := range options {
+		row.Options = append(row.Options, OptionRequest{Name: option.Name, Values: option.Values})
+	}
+
+	if err = writer.Write(row); err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		err = writer.Write(&ImportRow{
+			SKU:            product.SKU,
+			VariantSKU:     variant.SKU,
+			VariantOptions: variant.Options,
+			PriceOverride:  variant.PriceOverride,
+			Barcode:        variant.Barcode,
+			WeightGrams:    variant.WeightGrams,
+			Stock:          &variant.StockQuantity,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importRowErrors turns the errors of the product service into the errors of
+// a row, anything it does not recognise is returned as is.
+func importRowErrors(err error) (validate.ValidationErrors, error) {
+	// validate.StructFields returns a pointer to its errors
+	var validationErrors *validate.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return *validationErrors, nil
+	}
+
+	rowErrors := []struct {
+		err   error
+		field string
+		code  string
+	}{
+		{servererrors.ErrUnsupportedCurrency, "currency", "CURRENCY_UNSUPPORTED"},
+		{servererrors.ErrInvalidPrices, "prices", "PRICES_INVALID"},
+		{servererrors.ErrProductAlreadyExists, "sku", "SKU_TAKEN"},
+		{servererrors.ErrProductNotFound, "sku", "SKU_NOT_FOUND"},
+		{servererrors.ErrInvalidProductOptions, "options", "OPTIONS_INVALID"},
+		{servererrors.ErrTooManyVariants, "options", "OPTIONS_TOO_MANY_VARIANTS"},
+		{servererrors.ErrVariantAlreadyExists, "variantSku", "VARIANTSKU_TAKEN"},
+		{servererrors.ErrVariantNotFound, "variantSku", "VARIANTSKU_NOT_FOUND"},
+	}
+
+	for _, rowErr := range rowErrors {
+		if errors.Is(err, rowErr.err) {
+			return validate.ValidationErrors{rowError(rowErr.field, rowErr.err.Error(), rowErr.code)}, nil
+		}
+	}
+
+	return nil, err
+}
+
+func deref[T any](value *T) T {
+	var zero T
+	if value == nil {
+		return zero
+	}
+
+	return *value
+}
@@ -0,0 +1,166 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const importJobFields = "job_id, format, dry_run, status, blob_key, total_rows, processed_rows, report, error, created_at, updated_at, started_at, finished_at"
+
+func (s *store) createImportJob(ctx context.Context, job *ImportJob) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO catalog_import_jobs(job_id, format, dry_run, status, blob_key, total_rows) VALUES($1, $2, $3, $4, $5, $6)",
+		job.JobID,
+		job.Format,
+		job.DryRun,
+		job.Status,
+		job.BlobKey,
+		job.TotalRows,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to insert new import job in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findImportJob(ctx context.Context, jobID uuid.UUID) (*ImportJob, error) {
+	job, err := scanImportJob(s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM catalog_import_jobs WHERE job_id = $1", importJobFields),
+		jobID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find import job in product store: %w",
+			err,
+		)
+	}
+
+	return job, nil
+}
+
+// claimImportJob marks the oldest queued job running and returns it, or a
+// zero job when there is none. A running job that made no progress for
+// staleAfter is assumed abandoned by a crashed worker and claimed again.
+func (s *store) claimImportJob(ctx context.Context, staleAfter time.Duration) (*ImportJob, error) {
+	job, err := scanImportJob(s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			`UPDATE catalog_import_jobs SET status = $1, processed_rows = 0, started_at = NOW(), updated_at = NOW()
+			WHERE job_id = (
+				SELECT job_id FROM catalog_import_jobs
+				WHERE status = $2 OR (status = $1 AND updated_at < NOW() - $3 * INTERVAL '1 second')
+				ORDER BY created_at ASC LIMIT 1 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING %s`,
+			importJobFields,
+		),
+		ImportStatusRunning,
+		ImportStatusQueued,
+		staleAfter.Seconds(),
+	))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to claim import job in product store: %w",
+			err,
+		)
+	}
+
+	return job, nil
+}
+
+func (s *store) updateImportProgress(ctx context.Context, jobID uuid.UUID, processedRows int) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE catalog_import_jobs SET processed_rows = $1, updated_at = NOW() WHERE job_id = $2",
+		processedRows,
+		jobID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update import progress in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// finishImportJob saves the final status, report and error of a job.
+func (s *store) finishImportJob(ctx context.Context, job *ImportJob) error {
+	var report []byte
+	if job.Report != nil {
+		var err error
+		if report, err = json.Marshal(job.Report); err != nil {
+			return fmt.Errorf("failed to marshal import report in product store: %w", err)
+		}
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE catalog_import_jobs SET status = $1, processed_rows = $2, report = $3, error = $4, updated_at = NOW(), finished_at = NOW()
+		WHERE job_id = $5`,
+		job.Status,
+		job.ProcessedRows,
+		report,
+		job.Error,
+		job.JobID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to finish import job in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// scanImportJob scans a row of importJobFields, returning a zero job when
+// there is no row.
+func scanImportJob(row *sql.Row) (*ImportJob, error) {
+	job := new(ImportJob)
+	var report []byte
+	err := row.Scan(
+		&job.JobID,
+		&job.Format,
+		&job.DryRun,
+		&job.Status,
+		&job.BlobKey,
+		&job.TotalRows,
+		&job.ProcessedRows,
+		&report,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return new(ImportJob), nil
+		}
+
+		return nil, err
+	}
+
+	if report != nil {
+		job.Report = new(ImportReport)
+		if err = json.Unmarshal(report, job.Report); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal import report: %w", err)
+		}
+	}
+
+	return job, nil
+}
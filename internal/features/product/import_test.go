@@ -0,0 +1,401 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/blobstore"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const catalogCSV = `sku,name,price,currency,prices,options,status,variantSku,variantOptions,stock
+tee,Tee,2000,USD,EUR:1800,Size=S|M;Color=Red,active,,,
+TEE,,,,,,,TEE-S,Size=S;Color=Red,5
+TEE,,,,,,,TEE-M-RED,,7
+mug,Mug,abc,USD,,,,,,
+cap,Cap,1500,XXX,,,,,,
+sock,,900,USD,,,,,,
+TEE,,,,,,,TEE-XL,Size=XL;Color=Red,1
+`
+
+func newTestImporter(t *testing.T) (*importer, *mockStore) {
+	t.Helper()
+
+	productStore := newMockProductStore()
+	blobStore, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewImporter(NewService(productStore), newMockImportJobStore(), blobStore), productStore
+}
+
+func TestImport(t *testing.T) {
+	t.Run("should only report row errors on a dry run", func(t *testing.T) {
+		importer, productStore := newTestImporter(t)
+
+		report, err := importer.Import(context.Background(), strings.NewReader(catalogCSV), FormatCSV, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(productStore.Products) != 0 {
+			t.Errorf("expected a dry run to create no products, got %d", len(productStore.Products))
+		}
+
+		assertReport(t, report, 1, 2, 4)
+		assertRowErrors(t, report, map[int]string{
+			5: "PRICE_INVALID",
+			6: "CURRENCY_UNSUPPORTED",
+			7: "NAME_REQUIRED",
+			8: "VARIANTSKU_NOT_FOUND",
+		})
+	})
+
+	t.Run("should upsert products and update variants by sku", func(t *testing.T) {
+		importer, productStore := newTestImporter(t)
+
+		report, err := importer.Import(context.Background(), strings.NewReader(catalogCSV), FormatCSV, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertReport(t, report, 1, 2, 4)
+
+		tee, _ := productStore.findBySKU(context.Background(), "TEE")
+		if tee.ProductID == uuid.Nil {
+			t.Fatal("expected the TEE product to be created")
+		}
+
+		if len(tee.Prices) != 1 || tee.Prices[0].Currency().Code() != "EUR" {
+			t.Errorf("expected a EUR price, got %v", tee.Prices)
+		}
+
+		stock := map[string]int64{}
+		variants, _ := productStore.findVariants(context.Background(), tee.ProductID)
+		for _, variant := range variants {
+			stock[variant.SKU] = variant.StockQuantity
+		}
+
+		if stock["TEE-S"] != 5 || stock["TEE-M-RED"] != 7 || len(stock) != 2 {
+			t.Errorf("expected TEE-S with 5 and TEE-M-RED with 7 in stock, got %v", stock)
+		}
+
+		update := `{"sku":"TEE","name":"Organic Tee","price":2200}` + "\n" +
+			`{"sku":"TEE","variantSku":"TEE-S","stock":3}` + "\n"
+		report, err = importer.Import(context.Background(), strings.NewReader(update), FormatJSONL, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertReport(t, report, 0, 2, 0)
+
+		if tee.Name != "Organic Tee" || tee.Price != 2200 {
+			t.Errorf("expected the TEE product to be updated, got %q at %d", tee.Name, tee.Price)
+		}
+
+		if len(productStore.Products) != 1 {
+			t.Errorf("expected the import to update TEE in place, got %d products", len(productStore.Products))
+		}
+	})
+
+	t.Run("should reject unreadable files", func(t *testing.T) {
+		importer, _ := newTestImporter(t)
+
+		files := []struct {
+			format string
+			data   string
+		}{
+			{FormatCSV, "name,price\nTee,2000\n"},
+			{FormatCSV, "sku,colour\nTEE,Red\n"},
+			{"xlsx", "sku\nTEE\n"},
+		}
+
+		for _, file := range files {
+			if _, err := importer.Import(context.Background(), strings.NewReader(file.data), file.format, true); err == nil {
+				t.Errorf("expected %q as %s to be rejected", file.data, file.format)
+			}
+		}
+	})
+
+	t.Run("should export a catalog that imports back unchanged", func(t *testing.T) {
+		for _, format := range []string{FormatCSV, FormatJSONL} {
+			importer, productStore := newTestImporter(t)
+
+			if _, err := importer.Import(context.Background(), strings.NewReader(catalogCSV), FormatCSV, false); err != nil {
+				t.Fatal(err)
+			}
+
+			var exported bytes.Buffer
+			if err := importer.Export(context.Background(), &exported, format); err != nil {
+				t.Fatal(err)
+			}
+
+			reimporter, reimported := newTestImporter(t)
+			report, err := reimporter.Import(context.Background(), bytes.NewReader(exported.Bytes()), format, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Failed != 0 {
+				t.Fatalf("expected the %s export to import back, got %+v", format, report.Errors)
+			}
+
+			for _, product := range productStore.Products {
+				copied, _ := reimported.findBySKU(context.Background(), product.SKU)
+				if copied.Name != product.Name || copied.Price != product.Price || len(copied.Prices) != len(product.Prices) {
+					t.Errorf("expected %s to survive a %s round trip, got %+v", product.SKU, format, copied)
+				}
+
+				variants, _ := productStore.findVariants(context.Background(), product.ProductID)
+				copiedVariants, _ := reimported.findVariants(context.Background(), copied.ProductID)
+				if len(copiedVariants) != len(variants) {
+					t.Fatalf("expected %d variants, got %d", len(variants), len(copiedVariants))
+				}
+
+				for n, variant := range variants {
+					if copiedVariants[n].SKU != variant.SKU || copiedVariants[n].StockQuantity != variant.StockQuantity {
+						t.Errorf("expected variant %s to survive a %s round trip, got %+v", variant.SKU, format, copiedVariants[n])
+					}
+				}
+			}
+		}
+	})
+}
+
+func TestImportJobs(t *testing.T) {
+	importer, productStore := newTestImporter(t)
+	jobStore := importer.jobStore.(*mockImportJobStore)
+	importHandler := NewImportHandler(importer, nil)
+
+	router := chi.NewRouter()
+	router.Post(
+		"/admin/products/import",
+		handlerutils.MakeHandler(importHandler.startImportHandler),
+	)
+	router.Get(
+		"/admin/products/import/{jobID}",
+		handlerutils.MakeHandler(importHandler.getImportJobHandler),
+	)
+	router.Get(
+		"/admin/products/export",
+		handlerutils.MakeHandler(importHandler.exportHandler),
+	)
+
+	testCases := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		expected    int
+	}{
+		{"should queue a csv import", "/admin/products/import", "text/csv", catalogCSV, http.StatusAccepted},
+		{"should take the format from the query", "/admin/products/import?format=jsonl&dryRun=true", "", `{"sku":"MUG"}`, http.StatusAccepted},
+		{"should reject an unknown format", "/admin/products/import", "application/pdf", catalogCSV, http.StatusUnprocessableEntity},
+		{"should reject a file without a sku column", "/admin/products/import?format=csv", "", "name\nTee\n", http.StatusUnprocessableEntity},
+		{"should reject an invalid dry run flag", "/admin/products/import?format=csv&dryRun=maybe", "", catalogCSV, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expected {
+				t.Errorf("expected status code %d, got %d: %s", tc.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	if len(jobStore.Jobs) != 2 {
+		t.Fatalf("expected 2 queued jobs, got %d", len(jobStore.Jobs))
+	}
+
+	t.Run("should run queued jobs in the background", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			importer.RunImportWorker(ctx, time.Hour)
+			close(done)
+		}()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for jobStore.pending() > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		<-done
+
+		for _, job := range jobStore.Jobs {
+			if job.Status != ImportStatusCompleted {
+				t.Fatalf("expected job %s to complete, got %s: %s", job.JobID, job.Status, job.Error)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/products/import/"+job.JobID.String(), nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var resp struct {
+				Data ImportJob `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.Data.Report == nil || resp.Data.ProcessedRows != resp.Data.TotalRows {
+				t.Errorf("expected job %s to report every row, got %+v", job.JobID, resp.Data)
+			}
+		}
+
+		// the jsonl job was a dry run, MUG must not exist
+		if len(productStore.Products) != 1 {
+			t.Errorf("expected 1 imported product, got %d", len(productStore.Products))
+		}
+	})
+
+	t.Run("should return 404 for an unknown job", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/products/import/"+uuid.NewString(), nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should export as jsonl", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/products/export?format=jsonl", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if contentType := rr.Header().Get("Content-Type"); contentType != "application/jsonl" {
+			t.Errorf("expected content type application/jsonl, got %s", contentType)
+		}
+
+		if lines := strings.Count(rr.Body.String(), "\n"); lines != 3 {
+			t.Errorf("expected 1 product and 2 variant lines, got %d", lines)
+		}
+	})
+}
+
+func assertReport(t *testing.T, report *ImportReport, created, updated, failed int) {
+	t.Helper()
+
+	if report.Created != created || report.Updated != updated || report.Failed != failed {
+		t.Errorf(
+			"expected %d created, %d updated and %d failed, got %d, %d and %d: %+v",
+			created, updated, failed, report.Created, report.Updated, report.Failed, report.Errors,
+		)
+	}
+}
+
+// assertRowErrors checks the first error code reported for each line.
+func assertRowErrors(t *testing.T, report *ImportReport, expected map[int]string) {
+	t.Helper()
+
+	codes := map[int]string{}
+	for _, rowErr := range report.Errors {
+		codes[rowErr.Line] = rowErr.Errors[0].Code
+	}
+
+	for line, code := range expected {
+		if codes[line] != code {
+			t.Errorf("expected line %d to fail with %s, got %q", line, code, codes[line])
+		}
+	}
+}
+
+type mockImportJobStore struct {
+	mu   sync.Mutex
+	Jobs map[uuid.UUID]*ImportJob
+}
+
+func newMockImportJobStore() *mockImportJobStore {
+	return &mockImportJobStore{
+		Jobs: make(map[uuid.UUID]*ImportJob),
+	}
+}
+
+func (m *mockImportJobStore) createImportJob(ctx context.Context, job *ImportJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job.CreatedAt = time.Now()
+	m.Jobs[job.JobID] = job
+	return nil
+}
+
+func (m *mockImportJobStore) findImportJob(ctx context.Context, jobID uuid.UUID) (*ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.Jobs[jobID]
+	if !exists {
+		return new(ImportJob), nil
+	}
+
+	j := *job
+	return &j, nil
+}
+
+func (m *mockImportJobStore) claimImportJob(ctx context.Context, staleAfter time.Duration) (*ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, job := range m.Jobs {
+		if job.Status == ImportStatusQueued {
+			job.Status = ImportStatusRunning
+			j := *job
+			return &j, nil
+		}
+	}
+
+	return new(ImportJob), nil
+}
+
+func (m *mockImportJobStore) updateImportProgress(ctx context.Context, jobID uuid.UUID, processedRows int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Jobs[jobID].ProcessedRows = processedRows
+	return nil
+}
+
+func (m *mockImportJobStore) finishImportJob(ctx context.Context, job *ImportJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j := *job
+	m.Jobs[job.JobID] = &j
+	return nil
+}
+
+func (m *mockImportJobStore) pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := 0
+	for _, job := range m.Jobs {
+		if job.Status == ImportStatusQueued || job.Status == ImportStatusRunning {
+			pending++
+		}
+	}
+
+	return pending
+}
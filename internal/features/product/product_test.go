@@ -0,0 +1,668 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type testCase struct {
+	name     string
+	method   string
+	path     string
+	payload  any
+	expected int
+}
+
+func newTestRouter(t *testing.T) (*chi.Mux, *mockStore) {
+	t.Helper()
+
+	productStore := newMockProductStore()
+	productHandler := NewHandler(NewService(productStore), nil)
+
+	router := chi.NewRouter()
+	router.Get(
+		"/products",
+		handlerutils.MakeHandler(productHandler.listProductsHandler(false)),
+	)
+	router.Get(
+		"/products/search",
+		handlerutils.MakeHandler(productHandler.searchProductsHandler),
+	)
+	router.Get(
+		"/products/{productID}",
+		handlerutils.MakeHandler(productHandler.getProductHandler(false)),
+	)
+	router.Get(
+		"/admin/products",
+		handlerutils.MakeHandler(productHandler.listProductsHandler(true)),
+	)
+	router.Post(
+		"/admin/products",
+		handlerutils.MakeHandler(productHandler.createProductHandler),
+	)
+	router.Patch(
+		"/admin/products/{productID}",
+		handlerutils.MakeHandler(productHandler.updateProductHandler),
+	)
+	router.Put(
+		"/admin/products/{productID}/prices",
+		handlerutils.MakeHandler(productHandler.setPricesHandler),
+	)
+	router.Post(
+		"/admin/products/{productID}/archive",
+		handlerutils.MakeHandler(productHandler.archiveProductHandler),
+	)
+	router.Put(
+		"/admin/products/{productID}/options",
+		handlerutils.MakeHandler(productHandler.setOptionsHandler),
+	)
+	router.Patch(
+		"/admin/products/{productID}/variants",
+		handlerutils.MakeHandler(productHandler.bulkUpdateVariantsHandler),
+	)
+	router.Get(
+		"/products/{productID}/variants/resolve",
+		handlerutils.MakeHandler(productHandler.resolveVariantHandler),
+	)
+	router.Get(
+		"/products/by-slug/{slug}",
+		handlerutils.MakeHandler(productHandler.getProductBySlugHandler),
+	)
+	router.Get(
+		"/admin/products/{productID}/slugs",
+		handlerutils.MakeHandler(seo.ListSlugsHandler("productID", productHandler.service.listSlugs, slugError)),
+	)
+	router.Put(
+		"/admin/products/{productID}/slugs/{locale}",
+		handlerutils.MakeHandler(seo.SetSlugHandler("productID", productHandler.service.setSlug, slugError)),
+	)
+	router.Get(
+		"/admin/products/{productID}/preview",
+		handlerutils.MakeHandler(productHandler.previewProductHandler),
+	)
+	router.Put(
+		"/admin/products/{productID}/schedule",
+		handlerutils.MakeHandler(productHandler.scheduleProductHandler),
+	)
+	router.Delete(
+		"/admin/products/{productID}/schedule",
+		handlerutils.MakeHandler(productHandler.unscheduleProductHandler),
+	)
+
+	return router, productStore
+}
+
+func TestProductRoutes(t *testing.T) {
+	router, productStore := newTestRouter(t)
+
+	draft := &Product{ProductID: uuid.New(), SKU: "DRAFT-1", Name: "Draft", Price: 100, Currency: "USD", Status: StatusDraft}
+	active := &Product{ProductID: uuid.New(), SKU: "ACTIVE-1", Name: "Active", Price: 100, Currency: "USD", Status: StatusActive}
+	productStore.Products[draft.ProductID] = draft
+	productStore.Products[active.ProductID] = active
+
+	testCases := []testCase{
+		{
+			name:   "should create a product",
+			method: http.MethodPost,
+			path:   "/admin/products",
+			payload: CreateProductRequest{
+				SKU:      "tee-001",
+				Name:     "Pine Tee",
+				Price:    2500,
+				Currency: "usd",
+			},
+			expected: http.StatusCreated,
+		},
+		{
+			name:   "should fail to create a product with a taken sku",
+			method: http.MethodPost,
+			path:   "/admin/products",
+			payload: CreateProductRequest{
+				SKU:      " TEE-001 ",
+				Name:     "Another Tee",
+				Price:    2500,
+				Currency: "USD",
+			},
+			expected: http.StatusConflict,
+		},
+		{
+			name:   "should fail to create a product with an invalid payload",
+			method: http.MethodPost,
+			path:   "/admin/products",
+			payload: CreateProductRequest{
+				SKU:      "x",
+				Price:    -1,
+				Currency: "dollars",
+			},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "should fail to create a product in an unknown currency",
+			method: http.MethodPost,
+			path:   "/admin/products",
+			payload: CreateProductRequest{
+				SKU:      "tee-002",
+				Name:     "Pine Tee",
+				Price:    2500,
+				Currency: "XYZ",
+			},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should set explicit prices",
+			method:   http.MethodPut,
+			path:     "/admin/products/" + active.ProductID.String() + "/prices",
+			payload:  map[string]any{"prices": []map[string]any{{"amount": 95, "currency": "eur"}, {"amount": 15000, "currency": "JPY"}}},
+			expected: http.StatusOK,
+		},
+		{
+			name:     "should reject a price in the base currency",
+			method:   http.MethodPut,
+			path:     "/admin/products/" + draft.ProductID.String() + "/prices",
+			payload:  map[string]any{"prices": []map[string]any{{"amount": 95, "currency": "USD"}}},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject duplicate price currencies",
+			method:   http.MethodPut,
+			path:     "/admin/products/" + draft.ProductID.String() + "/prices",
+			payload:  map[string]any{"prices": []map[string]any{{"amount": 95, "currency": "EUR"}, {"amount": 96, "currency": "eur"}}},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject a price in an unknown currency",
+			method:   http.MethodPut,
+			path:     "/admin/products/" + draft.ProductID.String() + "/prices",
+			payload:  map[string]any{"prices": []map[string]any{{"amount": 95, "currency": "ABC"}}},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should fail to change the currency to one with an explicit price",
+			method:   http.MethodPatch,
+			path:     "/admin/products/" + active.ProductID.String(),
+			payload:  map[string]any{"currency": "EUR"},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should fail to update a product to a taken sku",
+			method:   http.MethodPatch,
+			path:     "/admin/products/" + active.ProductID.String(),
+			payload:  map[string]any{"sku": "draft-1"},
+			expected: http.StatusConflict,
+		},
+		{
+			name:     "should update a product",
+			method:   http.MethodPatch,
+			path:     "/admin/products/" + active.ProductID.String(),
+			payload:  map[string]any{"price": 150},
+			expected: http.StatusOK,
+		},
+		{
+			name:     "should fail to update a missing product",
+			method:   http.MethodPatch,
+			path:     "/admin/products/" + uuid.NewString(),
+			payload:  map[string]any{"price": 150},
+			expected: http.StatusNotFound,
+		},
+		{
+			name:     "should not show drafts publicly",
+			method:   http.MethodGet,
+			path:     "/products/" + draft.ProductID.String(),
+			expected: http.StatusNotFound,
+		},
+		{
+			name:     "should show active products publicly",
+			method:   http.MethodGet,
+			path:     "/products/" + active.ProductID.String(),
+			expected: http.StatusOK,
+		},
+		{
+			name:     "should archive a product",
+			method:   http.MethodPost,
+			path:     "/admin/products/" + draft.ProductID.String() + "/archive",
+			expected: http.StatusOK,
+		},
+		{
+			name:     "should reject a malformed product id",
+			method:   http.MethodGet,
+			path:     "/products/not-a-uuid",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "should reject a malformed page",
+			method:   http.MethodGet,
+			path:     "/products?page=abc",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "should reject an oversized page",
+			method:   http.MethodGet,
+			path:     "/products?pageSize=1000",
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject an unknown sort",
+			method:   http.MethodGet,
+			path:     "/products?sort=sku",
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject a min price above the max price",
+			method:   http.MethodGet,
+			path:     "/products?minPrice=500&maxPrice=100",
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := testutil.Send(t, router, tc.method, tc.path, tc.payload, nil)
+			if rr.Code != tc.expected {
+				t.Errorf(
+					"expected status code %d, got %d: %s",
+					tc.expected, rr.Code, rr.Body.String(),
+				)
+			}
+		})
+	}
+
+	if active.Price != 150 {
+		t.Errorf("expected the price to be updated to 150, got %d", active.Price)
+	}
+
+	if len(active.Prices) != 2 || active.Prices[0] != money.MustNew(95, "EUR") {
+		t.Errorf("expected the explicit prices to be stored, got %v", active.Prices)
+	}
+
+	if draft.Status != StatusArchived {
+		t.Errorf("expected the product to be archived, got %s", draft.Status)
+	}
+}
+
+func TestListProducts(t *testing.T) {
+	router, productStore := newTestRouter(t)
+
+	for _, p := range []*Product{
+		{SKU: "A", Name: "Acorn", Price: 300, Status: StatusActive},
+		{SKU: "B", Name: "Birch", Price: 100, Status: StatusActive},
+		{SKU: "C", Name: "Cedar", Price: 200, Status: StatusDraft},
+		{SKU: "D", Name: "Douglas", Price: 400, Status: StatusArchived},
+	} {
+		p.ProductID = uuid.New()
+		productStore.Products[p.ProductID] = p
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected []string
+		total    int64
+	}{
+		{
+			name:     "should only list active products publicly",
+			path:     "/products?sort=name",
+			expected: []string{"A", "B"},
+			total:    2,
+		},
+		{
+			name:     "should show drafts to admins",
+			path:     "/admin/products?sort=name",
+			expected: []string{"A", "B", "C", "D"},
+			total:    4,
+		},
+		{
+			name:     "should filter by status",
+			path:     "/products?status=active&sort=-price",
+			expected: []string{"A", "B"},
+			total:    2,
+		},
+		{
+			name:     "should not leak drafts through the status filter",
+			path:     "/products?status=draft",
+			expected: []string{},
+		},
+		{
+			name:     "should filter by price",
+			path:     "/admin/products?minPrice=150&maxPrice=350&sort=price",
+			expected: []string{"C", "A"},
+			total:    2,
+		},
+		{
+			name:     "should paginate",
+			path:     "/admin/products?sort=name&page=2&pageSize=3",
+			expected: []string{"D"},
+			total:    4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := testutil.Send(t, router, http.MethodGet, tc.path, nil, nil)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var body struct {
+				Data ListProductsResponse `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			skus := []string{}
+			for _, p := range body.Data.Products {
+				skus = append(skus, p.SKU)
+			}
+
+			if !slices.Equal(skus, tc.expected) {
+				t.Errorf("expected products %v, got %v", tc.expected, skus)
+			}
+
+			if body.Data.TotalCount != tc.total {
+				t.Errorf("expected total count %d, got %d", tc.total, body.Data.TotalCount)
+			}
+		})
+	}
+}
+
+type mockStore struct {
+	*testutil.SlugStore
+	Products map[uuid.UUID]*Product
+	Options  map[uuid.UUID][]*Option
+	Variants map[uuid.UUID]*Variant
+	// Schemas are the attributes the categories of each product define
+	Schemas map[uuid.UUID][]*attribute.Definition
+}
+
+func newMockProductStore() *mockStore {
+	return &mockStore{
+		SlugStore: testutil.NewSlugStore(),
+		Products:  make(map[uuid.UUID]*Product),
+		Options:   make(map[uuid.UUID][]*Option),
+		Variants:  make(map[uuid.UUID]*Variant),
+		Schemas:   make(map[uuid.UUID][]*attribute.Definition),
+	}
+}
+
+func (m *mockStore) create(ctx context.Context, product *Product) error {
+	m.Products[product.ProductID] = product
+	return nil
+}
+
+func (m *mockStore) findByID(ctx context.Context, productID uuid.UUID) (*Product, error) {
+	product, exists := m.Products[productID]
+	if !exists {
+		return new(Product), nil
+	}
+
+	product.Slugs = m.Current(productID)
+
+	return product, nil
+}
+
+func (m *mockStore) findBySKU(ctx context.Context, sku string) (*Product, error) {
+	for _, product := range m.Products {
+		if product.SKU == sku {
+			return product, nil
+		}
+	}
+
+	return new(Product), nil
+}
+
+func (m *mockStore) update(ctx context.Context, product *Product) error {
+	m.Products[product.ProductID] = product
+	return nil
+}
+
+func (m *mockStore) updateStatus(ctx context.Context, productID uuid.UUID, status string) error {
+	m.Products[productID].Status = status
+	m.Products[productID].PublishAt = nil
+	m.Products[productID].UnpublishAt = nil
+	return nil
+}
+
+func (m *mockStore) list(ctx context.Context, filter *listFilter) ([]*Product, int64, error) {
+	matches := []*Product{}
+	for _, product := range m.Products {
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, product.Status) {
+			continue
+		}
+		if filter.MinPrice != nil && product.Price < *filter.MinPrice {
+			continue
+		}
+		if filter.MaxPrice != nil && product.Price > *filter.MaxPrice {
+			continue
+		}
+		if filter.SellerID != nil && (product.SellerID == nil || *product.SellerID != *filter.SellerID) {
+			continue
+		}
+
+		matches = append(matches, product)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		switch filter.Sort {
+		case "price":
+			return matches[i].Price < matches[j].Price
+		case "-price":
+			return matches[i].Price > matches[j].Price
+		default:
+			return matches[i].Name < matches[j].Name
+		}
+	})
+
+	totalCount := int64(len(matches))
+	start := min(filter.Offset, totalCount)
+	end := min(filter.Offset+filter.Limit, totalCount)
+
+	return matches[start:end], totalCount, nil
+}
+
+func (m *mockStore) replacePrices(ctx context.Context, productID uuid.UUID, prices []money.Money) error {
+	m.Products[productID].Prices = prices
+	return nil
+}
+
+func (m *mockStore) categoryExists(ctx context.Context, categoryID uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+// search matches the query as a case insensitive substring of the name, the
+// ranking and typo tolerance are left to postgres.
+func (m *mockStore) search(ctx context.Context, filter *searchFilter) ([]*SearchResult, int64, error) {
+	results := []*SearchResult{}
+	for _, product := range m.searchMatches(filter) {
+		results = append(results, &SearchResult{
+			Product:    product,
+			Highlights: SearchHighlights{Name: product.Name, Description: product.Description},
+		})
+	}
+
+	totalCount := int64(len(results))
+	start := min(filter.Offset, totalCount)
+	end := min(filter.Offset+filter.Limit, totalCount)
+
+	return results[start:end], totalCount, nil
+}
+
+func (m *mockStore) searchFacets(ctx context.Context, filter *searchFilter, priceBounds []int64) ([]*facetCount, error) {
+	brands := map[string]int64{}
+	buckets := map[int]int64{}
+	for _, product := range m.searchMatches(filter) {
+		if product.Brand != "" {
+			brands[product.Brand]++
+		}
+
+		bucket := 0
+		for bucket < len(priceBounds) && product.Price >= priceBounds[bucket] {
+			bucket++
+		}
+		buckets[bucket]++
+	}
+
+	counts := []*facetCount{}
+	for brand, count := range brands {
+		counts = append(counts, &facetCount{Facet: "brand", Key: brand, Label: brand, Count: count})
+	}
+	for bucket, count := range buckets {
+		counts = append(counts, &facetCount{Facet: "price", Key: strconv.Itoa(bucket), Count: count})
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Facet != counts[j].Facet {
+			return counts[i].Facet < counts[j].Facet
+		}
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Label < counts[j].Label
+	})
+
+	return counts, nil
+}
+
+func (m *mockStore) searchMatches(filter *searchFilter) []*Product {
+	matches := []*Product{}
+	for _, product := range m.Products {
+		if !strings.Contains(strings.ToLower(product.Name), strings.ToLower(filter.Query)) {
+			continue
+		}
+		if product.Currency != filter.Currency {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, product.Status) {
+			continue
+		}
+		if len(filter.Brands) > 0 && !slices.Contains(filter.Brands, product.Brand) {
+			continue
+		}
+		if !matchesAttributes(product, filter.Attributes) {
+			continue
+		}
+
+		matches = append(matches, product)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Name < matches[j].Name
+	})
+
+	return matches
+}
+
+func (m *mockStore) findOptions(ctx context.Context, productID uuid.UUID) ([]*Option, error) {
+	return m.Options[productID], nil
+}
+
+func (m *mockStore) findVariants(ctx context.Context, productID uuid.UUID) ([]*Variant, error) {
+	variants := []*Variant{}
+	for _, variant := range m.Variants {
+		if variant.ProductID != productID {
+			continue
+		}
+
+		// copy like a fresh read from the database would
+		v := *variant
+		v.Price = m.Products[productID].Price
+		if v.PriceOverride != nil {
+			v.Price = *v.PriceOverride
+		}
+
+		variants = append(variants, &v)
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].SKU < variants[j].SKU
+	})
+
+	return variants, nil
+}
+
+func (m *mockStore) replaceOptions(ctx context.Context, productID uuid.UUID, options []*Option, variants []*Variant) error {
+	m.Options[productID] = options
+
+	keep := map[uuid.UUID]struct{}{}
+	for _, variant := range variants {
+		keep[variant.VariantID] = struct{}{}
+
+		if existing, ok := m.Variants[variant.VariantID]; ok {
+			existing.Options = variant.Options
+			continue
+		}
+
+		variant.ProductID = productID
+		m.Variants[variant.VariantID] = variant
+	}
+
+	for variantID, variant := range m.Variants {
+		if _, ok := keep[variantID]; !ok && variant.ProductID == productID {
+			delete(m.Variants, variantID)
+		}
+	}
+
+	return nil
+}
+
+func (m *mockStore) updateVariants(ctx context.Context, variants []*Variant) error {
+	for _, variant := range variants {
+		v := *variant
+		m.Variants[variant.VariantID] = &v
+	}
+
+	return nil
+}
+
+func (m *mockStore) findAttributeSchema(ctx context.Context, productID uuid.UUID) ([]*attribute.Definition, error) {
+	return m.Schemas[productID], nil
+}
+
+func (m *mockStore) applySchedules(ctx context.Context, now time.Time) (int64, int64, error) {
+	var published, unpublished int64
+	for _, product := range m.Products {
+		if product.Status == StatusScheduled && product.PublishAt != nil && !product.PublishAt.After(now) {
+			product.Status = StatusActive
+			product.PublishAt = nil
+			published++
+		}
+	}
+
+	for _, product := range m.Products {
+		if product.Status == StatusActive && product.UnpublishAt != nil && !product.UnpublishAt.After(now) {
+			product.Status = StatusArchived
+			product.UnpublishAt = nil
+			unpublished++
+		}
+	}
+
+	return published, unpublished, nil
+}
+
+// matchesAttributes compares product attribute values as text the way the
+// jsonb ->> operator does, options are not matched.
+func matchesAttributes(product *Product, attributes map[string]string) bool {
+	for code, value := range attributes {
+		actual, ok := product.Attributes[strings.ToLower(code)]
+		if !ok || !strings.EqualFold(fmt.Sprint(actual), value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,136 @@
+package product
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+// scheduleProduct sets when a product is published, unpublished or both. A
+// draft or archived product given a publish time becomes scheduled and stays
+// hidden until then, an unpublish time archives an active or scheduled
+// product once it has come.
+func (s *service) scheduleProduct(ctx context.Context, productID uuid.UUID, payload *ScheduleProductRequest) (*Product, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	now := time.Now()
+
+	if payload.PublishAt != nil {
+		if product.Status == StatusActive {
+			return nil, servererrors.ErrInvalidStatusChange
+		}
+
+		if !payload.PublishAt.After(now) {
+			return nil, servererrors.ErrInvalidSchedule
+		}
+
+		publishAt := payload.PublishAt.UTC()
+		product.Status = StatusScheduled
+		product.PublishAt = &publishAt
+	}
+
+	if payload.UnpublishAt != nil {
+		if product.Status != StatusActive && product.Status != StatusScheduled {
+			return nil, servererrors.ErrInvalidStatusChange
+		}
+
+		unpublishAt := payload.UnpublishAt.UTC()
+		product.UnpublishAt = &unpublishAt
+	}
+
+	if product.UnpublishAt != nil {
+		if !product.UnpublishAt.After(now) ||
+			(product.PublishAt != nil && !product.UnpublishAt.After(*product.PublishAt)) {
+			return nil, servererrors.ErrInvalidSchedule
+		}
+	}
+
+	if err = s.productStore.update(ctx, product); err != nil {
+		return nil, err
+	}
+
+	return s.productStore.findByID(ctx, productID)
+}
+
+// unscheduleProduct cancels the pending publication and unpublication of a
+// product, a scheduled product goes back to draft.
+func (s *service) unscheduleProduct(ctx context.Context, productID uuid.UUID) (*Product, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	if product.PublishAt == nil && product.UnpublishAt == nil {
+		return product, nil
+	}
+
+	if product.Status == StatusScheduled {
+		product.Status = StatusDraft
+	}
+
+	product.PublishAt = nil
+	product.UnpublishAt = nil
+
+	if err = s.productStore.update(ctx, product); err != nil {
+		return nil, err
+	}
+
+	return s.productStore.findByID(ctx, productID)
+}
+
+// previewProduct returns a product and its variants the way the storefront
+// shows them, whatever the product status, so admins can review drafts before
+// they are published.
+func (s *service) previewProduct(ctx context.Context, productID uuid.UUID) (*PreviewProductResponse, error) {
+	product, err := s.getProduct(ctx, productID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.productStore.findVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &PreviewProductResponse{
+		Product:  product,
+		Variants: make([]*StorefrontVariant, 0, len(variants)),
+	}
+	for _, variant := range variants {
+		resp.Variants = append(resp.Variants, newStorefrontVariant(product, variant))
+	}
+
+	return resp, nil
+}
+
+// RunScheduler publishes and unpublishes the products whose scheduled time
+// has come, every interval. It blocks until ctx is done.
+func (s *service) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, _, err := s.productStore.applySchedules(ctx, time.Now()); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
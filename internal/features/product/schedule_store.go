@@ -0,0 +1,49 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// applySchedules publishes the scheduled products whose publish time has come
+// and then archives the active ones whose unpublish time has, in one
+// transaction. It returns how many products it published and archived.
+func (s *store) applySchedules(ctx context.Context, now time.Time) (int64, int64, error) {
+	var published, unpublished int64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			"UPDATE products SET status = 'active', publish_at = NULL, updated_at = NOW() WHERE status = 'scheduled' AND publish_at <= $1",
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to publish scheduled products in product store: %w", err)
+		}
+
+		if published, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to count published products in product store: %w", err)
+		}
+
+		result, err = tx.ExecContext(
+			ctx,
+			"UPDATE products SET status = 'archived', unpublish_at = NULL, updated_at = NOW() WHERE status = 'active' AND unpublish_at <= $1",
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to unpublish products in product store: %w", err)
+		}
+
+		if unpublished, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to count unpublished products in product store: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return published, unpublished, nil
+}
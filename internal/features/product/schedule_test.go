@@ -0,0 +1,128 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestProductSchedule(t *testing.T) {
+	router, productStore := newTestRouter(t)
+
+	draft := createProduct(t, router, CreateProductRequest{SKU: "LAMP-1", Name: "Desk Lamp", Price: 4900, Currency: "USD"})
+	active := createProduct(t, router, CreateProductRequest{SKU: "LAMP-2", Name: "Floor Lamp", Price: 8900, Currency: "USD", Status: StatusActive})
+
+	publishAt := time.Now().Add(time.Hour)
+	unpublishAt := time.Now().Add(2 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	draftSchedule := "/admin/products/" + draft.ProductID.String() + "/schedule"
+	activeSchedule := "/admin/products/" + active.ProductID.String() + "/schedule"
+
+	testCases := []struct {
+		name     string
+		path     string
+		payload  ScheduleProductRequest
+		expected int
+	}{
+		{"should require a publish or unpublish time", draftSchedule, ScheduleProductRequest{}, http.StatusUnprocessableEntity},
+		{"should reject a publish time in the past", draftSchedule, ScheduleProductRequest{PublishAt: &past}, http.StatusUnprocessableEntity},
+		{"should reject publishing an active product", activeSchedule, ScheduleProductRequest{PublishAt: &publishAt}, http.StatusConflict},
+		{"should reject unpublishing a draft", draftSchedule, ScheduleProductRequest{UnpublishAt: &unpublishAt}, http.StatusConflict},
+		{"should reject unpublishing before publishing", draftSchedule, ScheduleProductRequest{PublishAt: &unpublishAt, UnpublishAt: &publishAt}, http.StatusUnprocessableEntity},
+		{"should reject an unknown product", "/admin/products/" + uuid.NewString() + "/schedule", ScheduleProductRequest{PublishAt: &publishAt}, http.StatusNotFound},
+		{"should schedule the publication of a draft", draftSchedule, ScheduleProductRequest{PublishAt: &publishAt, UnpublishAt: &unpublishAt}, http.StatusOK},
+		{"should schedule the unpublication of an active product", activeSchedule, ScheduleProductRequest{UnpublishAt: &publishAt}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := testutil.Send(t, router, http.MethodPut, tc.path, tc.payload, nil)
+			if rr.Code != tc.expected {
+				t.Errorf("expected status code %d, got %d: %s", tc.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("should keep scheduled products hidden but previewable", func(t *testing.T) {
+		if status := productStore.Products[draft.ProductID].Status; status != StatusScheduled {
+			t.Fatalf("expected status %q, got %q", StatusScheduled, status)
+		}
+
+		rr := testutil.Send(t, router, http.MethodGet, "/products/"+draft.ProductID.String(), nil, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+
+		rr = testutil.Send(t, router, http.MethodGet, "/admin/products/"+draft.ProductID.String()+"/preview", nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var resp struct {
+			Data *PreviewProductResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Data.Product.ProductID != draft.ProductID || resp.Data.Variants == nil {
+			t.Errorf("expected a preview of the draft, got %+v", resp.Data)
+		}
+	})
+
+	t.Run("should publish and unpublish products when their time comes", func(t *testing.T) {
+		published, unpublished, _ := productStore.applySchedules(context.Background(), publishAt)
+		if published != 1 || unpublished != 1 {
+			t.Fatalf("expected 1 product published and 1 unpublished, got %d and %d", published, unpublished)
+		}
+
+		rr := testutil.Send(t, router, http.MethodGet, "/products/"+draft.ProductID.String(), nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		rr = testutil.Send(t, router, http.MethodGet, "/products/"+active.ProductID.String(), nil, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should cancel a schedule and return scheduled products to draft", func(t *testing.T) {
+		later := time.Now().Add(3 * time.Hour)
+		rr := testutil.Send(t, router, http.MethodPut, activeSchedule, ScheduleProductRequest{PublishAt: &later}, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		rr = testutil.Send(t, router, http.MethodDelete, activeSchedule, nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		product := productStore.Products[active.ProductID]
+		if product.Status != StatusDraft || product.PublishAt != nil || product.UnpublishAt != nil {
+			t.Errorf("expected an unscheduled draft, got status %q publish at %v unpublish at %v", product.Status, product.PublishAt, product.UnpublishAt)
+		}
+	})
+
+	t.Run("should cancel a pending publication when the status is set by hand", func(t *testing.T) {
+		later := time.Now().Add(3 * time.Hour)
+		testutil.Send(t, router, http.MethodPut, activeSchedule, ScheduleProductRequest{PublishAt: &later}, nil)
+
+		status := StatusActive
+		rr := testutil.Send(t, router, http.MethodPatch, "/admin/products/"+active.ProductID.String(), UpdateProductRequest{Status: &status}, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		if product := productStore.Products[active.ProductID]; product.PublishAt != nil {
+			t.Errorf("expected the publication to be cancelled, got %v", product.PublishAt)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/google/uuid"
+)
+
+func (h *handler) searchProductsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	payload, err := parseSearchProductsRequest(r)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	resp, err := h.service.searchProducts(ctx, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrInvalidQueryParams):
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidQueryParams.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrUnsupportedCurrency):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrUnsupportedCurrency.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrCategoryNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrCategoryNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"products found",
+		resp,
+	)
+}
+
+// parseSearchProductsRequest reads the search query parameters, e.g.
+// ?q=wool+socks&currency=USD&lang=english&categoryId=...&brand=Pines&brand=Acme
+// &attr=Color:Red&attr=Size:M&minPrice=500&maxPrice=2500&page=1&pageSize=20
+func parseSearchProductsRequest(r *http.Request) (*SearchProductsRequest, error) {
+	page, err := handlerutils.ParseQueryInt(r, "page", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize, err := handlerutils.ParseQueryInt(r, "pageSize", defaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	minPrice, err := handlerutils.ParseOptionalQueryInt(r, "minPrice")
+	if err != nil {
+		return nil, err
+	}
+
+	maxPrice, err := handlerutils.ParseOptionalQueryInt(r, "maxPrice")
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.URL.Query()
+
+	var categoryID *uuid.UUID
+	if value := query.Get("categoryId"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		categoryID = &id
+	}
+
+	attributes := map[string]string{}
+	for _, attr := range query["attr"] {
+		name, value, ok := strings.Cut(attr, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, servererrors.ErrInvalidQueryParams
+		}
+		attributes[name] = value
+	}
+
+	return &SearchProductsRequest{
+		Query:      query.Get("q"),
+		Currency:   query.Get("currency"),
+		Language:   query.Get("lang"),
+		CategoryID: categoryID,
+		Brands:     query["brand"],
+		MinPrice:   minPrice,
+		MaxPrice:   maxPrice,
+		Attributes: attributes,
+		Page:       page,
+		PageSize:   pageSize,
+	}, nil
+}
@@ -0,0 +1,152 @@
+package product
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+)
+
+// priceBounds split the price facet into ranges, in minor units of a currency
+// with two decimal digits, e.g. 10.00 to 250.00 USD.
+var priceBounds = []int64{1000, 2500, 5000, 10000, 25000}
+
+// priceBoundsFor scales priceBounds to the minor unit of currency, e.g. 10 to
+// 250 JPY or 10.000 to 250.000 BHD.
+func priceBoundsFor(currency money.Currency) []int64 {
+	bounds := make([]int64, len(priceBounds))
+	for i, bound := range priceBounds {
+		for range currency.Digits() {
+			bound *= 10
+		}
+		for range 2 {
+			bound /= 10
+		}
+		bounds[i] = bound
+	}
+
+	return bounds
+}
+
+// searchProducts runs a full text search over active products and counts the
+// facets of every match, not only of the returned page.
+func (s *service) searchProducts(ctx context.Context, payload *SearchProductsRequest) (*SearchProductsResponse, error) {
+	if payload.MinPrice != nil && payload.MaxPrice != nil && *payload.MinPrice > *payload.MaxPrice {
+		return nil, servererrors.ErrInvalidQueryParams
+	}
+
+	query := strings.TrimSpace(payload.Query)
+	if query == "" {
+		return nil, servererrors.ErrInvalidQueryParams
+	}
+
+	currency, err := money.ParseCurrency(payload.Currency)
+	if err != nil {
+		return nil, servererrors.ErrUnsupportedCurrency
+	}
+
+	if payload.CategoryID != nil {
+		exists, err := s.productStore.categoryExists(ctx, *payload.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, servererrors.ErrCategoryNotFound
+		}
+	}
+
+	filter := &searchFilter{
+		Query:      query,
+		Language:   payload.Language,
+		Currency:   currency.Code(),
+		Statuses:   []string{StatusActive},
+		CategoryID: payload.CategoryID,
+		Brands:     payload.Brands,
+		MinPrice:   payload.MinPrice,
+		MaxPrice:   payload.MaxPrice,
+		Attributes: payload.Attributes,
+		Limit:      payload.PageSize,
+		Offset:     (payload.Page - 1) * payload.PageSize,
+	}
+
+	results, totalCount, err := s.productStore.search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := priceBoundsFor(currency)
+	counts, err := s.productStore.searchFacets(ctx, filter, bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchProductsResponse{
+		Results:    results,
+		Facets:     buildFacets(counts, bounds),
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// buildFacets groups the facet rows, which come ordered by facet and count,
+// into their response shape. Price buckets index into priceBounds.
+func buildFacets(counts []*facetCount, priceBounds []int64) *SearchFacets {
+	facets := &SearchFacets{
+		Categories:  []FacetValue{},
+		Brands:      []FacetValue{},
+		PriceRanges: []PriceRangeFacet{},
+		Attributes:  []AttributeFacet{},
+	}
+
+	attributes := map[string]int{}
+	for _, count := range counts {
+		value := FacetValue{Value: count.Key, Label: count.Label, Count: count.Count}
+
+		switch count.Facet {
+		case "category":
+			facets.Categories = append(facets.Categories, value)
+		case "brand":
+			facets.Brands = append(facets.Brands, value)
+		case "price":
+			bucket, err := strconv.Atoi(count.Key)
+			if err != nil || bucket < 0 || bucket > len(priceBounds) {
+				continue
+			}
+
+			priceRange := PriceRangeFacet{Count: count.Count}
+			if bucket > 0 {
+				priceRange.Min = &priceBounds[bucket-1]
+			}
+			if bucket < len(priceBounds) {
+				priceRange.Max = &priceBounds[bucket]
+			}
+			facets.PriceRanges = append(facets.PriceRanges, priceRange)
+		case "attribute":
+			i, ok := attributes[count.Key]
+			if !ok {
+				i = len(facets.Attributes)
+				attributes[count.Key] = i
+				facets.Attributes = append(facets.Attributes, AttributeFacet{Name: count.Key, Values: []FacetValue{}})
+			}
+
+			facets.Attributes[i].Values = append(facets.Attributes[i].Values, FacetValue{
+				Value: count.Label,
+				Label: count.Label,
+				Count: count.Count,
+			})
+		}
+	}
+
+	// price ranges read best in ascending order rather than by count
+	sort.Slice(facets.PriceRanges, func(i, j int) bool {
+		return facets.PriceRanges[j].Min != nil &&
+			(facets.PriceRanges[i].Min == nil || *facets.PriceRanges[i].Min < *facets.PriceRanges[j].Min)
+	})
+
+	return facets
+}
@@ -0,0 +1,220 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// tsQuery parses the search text with each product's own language so words
+// are stemmed the same way as the indexed vector.
+const tsQuery = "websearch_to_tsquery(language, $1)"
+
+// search returns one page of products matching filter, best matches first,
+// along with the number of matches across all pages. Products match on the
+// stemmed text or, to tolerate typos, on trigram word similarity with the name.
+func (s *store) search(ctx context.Context, filter *searchFilter) ([]*SearchResult, int64, error) {
+	where, args := searchConditions(filter)
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(
+		`SELECT %s,
+			ts_headline(language, name, %s, 'HighlightAll=true'),
+			ts_headline(language, description, %s, 'MaxFragments=2, MinWords=5, MaxWords=20'),
+			ts_rank(search_vector, %s) + word_similarity($1, name) AS score,
+			COUNT(*) OVER()
+		FROM products %s ORDER BY score DESC, product_id ASC LIMIT $%d OFFSET $%d`,
+		productFields,
+		tsQuery,
+		tsQuery,
+		tsQuery,
+		where,
+		len(args)-1,
+		len(args),
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to search products in product store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	var totalCount int64
+	results := []*SearchResult{}
+	for rows.Next() {
+		result := &SearchResult{Product: new(Product)}
+		dest := append(
+			productColumns(result.Product),
+			&result.Highlights.Name,
+			&result.Highlights.Description,
+			&result.Score,
+			&totalCount,
+		)
+
+		if err = rows.Scan(dest...); err != nil {
+			return nil, 0, fmt.Errorf(
+				"failed to scan row into search result in product store: %w",
+				err,
+			)
+		}
+
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to iterate rows in product store: %w",
+			err,
+		)
+	}
+
+	products := make([]*Product, 0, len(results))
+	for _, result := range results {
+		products = append(products, result.Product)
+	}
+
+	if err = s.attachPrices(ctx, products); err != nil {
+		return nil, 0, err
+	}
+
+	if err = s.attachSlugs(ctx, products); err != nil {
+		return nil, 0, err
+	}
+
+	return results, totalCount, nil
+}
+
+// searchFacets counts the products matching filter per category, brand, price
+// bucket, option value and filterable attribute value. Price buckets are keyed
+// by their index into priceBounds, in minor units of filter.Currency, as
+// returned by width_bucket.
+func (s *store) searchFacets(ctx context.Context, filter *searchFilter, priceBounds []int64) ([]*facetCount, error) {
+	where, args := searchConditions(filter)
+
+	args = append(args, pq.Array(priceBounds))
+	query := fmt.Sprintf(
+		`WITH matches AS (SELECT product_id, brand, price, attributes FROM products %s)
+		SELECT 'category', c.category_id::text, c.name, COUNT(*)
+			FROM matches m
+			JOIN product_categories pc ON pc.product_id = m.product_id
+			JOIN categories c ON c.category_id = pc.category_id
+			GROUP BY c.category_id, c.name
+		UNION ALL
+		SELECT 'brand', brand, brand, COUNT(*) FROM matches WHERE brand <> '' GROUP BY brand
+		UNION ALL
+		SELECT 'price', width_bucket(price, $%d::bigint[])::text, '', COUNT(*) FROM matches GROUP BY 2
+		UNION ALL
+		SELECT 'attribute', o.name, v.value, COUNT(DISTINCT m.product_id)
+			FROM matches m
+			JOIN product_options o ON o.product_id = m.product_id
+			CROSS JOIN LATERAL unnest(o.option_values) AS v(value)
+			GROUP BY o.name, v.value
+		UNION ALL
+		SELECT 'attribute', kv.key, kv.value, COUNT(*)
+			FROM matches m
+			CROSS JOIN LATERAL jsonb_each_text(m.attributes) AS kv(key, value)
+			WHERE kv.key IN (SELECT code FROM category_attributes WHERE filterable)
+			GROUP BY kv.key, kv.value
+		ORDER BY 1, 4 DESC, 3`,
+		where,
+		len(args),
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to count search facets in product store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	counts := []*facetCount{}
+	for rows.Next() {
+		count := new(facetCount)
+		if err = rows.Scan(&count.Facet, &count.Key, &count.Label, &count.Count); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into facet count in product store: %w",
+				err,
+			)
+		}
+
+		counts = append(counts, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in product store: %w",
+			err,
+		)
+	}
+
+	return counts, nil
+}
+
+// searchConditions builds the WHERE clause shared by the search and facet
+// queries, the search text is always $1.
+func searchConditions(filter *searchFilter) (string, []any) {
+	args := []any{filter.Query}
+	conditions := []string{
+		fmt.Sprintf("(search_vector @@ %s OR $1 <%% name)", tsQuery),
+	}
+
+	if filter.Language != "" {
+		args = append(args, filter.Language)
+		conditions = append(conditions, fmt.Sprintf("language = $%d::regconfig", len(args)))
+	}
+
+	args = append(args, filter.Currency)
+	conditions = append(conditions, fmt.Sprintf("currency = $%d", len(args)))
+
+	if len(filter.Statuses) > 0 {
+		args = append(args, pq.Array(filter.Statuses))
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", len(args)))
+	}
+
+	if filter.CategoryID != nil {
+		args = append(args, *filter.CategoryID)
+		conditions = append(conditions, fmt.Sprintf("product_id IN (SELECT pc.product_id FROM product_categories pc JOIN category_closure cc ON cc.descendant_id = pc.category_id WHERE cc.ancestor_id = $%d)", len(args)))
+	}
+
+	if len(filter.Brands) > 0 {
+		args = append(args, pq.Array(filter.Brands))
+		conditions = append(conditions, fmt.Sprintf("brand = ANY($%d)", len(args)))
+	}
+
+	if filter.MinPrice != nil {
+		args = append(args, *filter.MinPrice)
+		conditions = append(conditions, fmt.Sprintf("price >= $%d", len(args)))
+	}
+
+	if filter.MaxPrice != nil {
+		args = append(args, *filter.MaxPrice)
+		conditions = append(conditions, fmt.Sprintf("price <= $%d", len(args)))
+	}
+
+	names := make([]string, 0, len(filter.Attributes))
+	for name := range filter.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		args = append(args, name, filter.Attributes[name])
+		conditions = append(conditions, fmt.Sprintf(
+			"(EXISTS (SELECT 1 FROM product_options o WHERE o.product_id = products.product_id AND LOWER(o.name) = LOWER($%d) AND $%d = ANY(o.option_values)) OR LOWER(attributes ->> LOWER($%d)) = LOWER($%d))",
+			len(args)-1,
+			len(args),
+			len(args)-1,
+			len(args),
+		))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
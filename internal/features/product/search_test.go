@@ -0,0 +1,148 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestSearchProducts(t *testing.T) {
+	router, productStore := newTestRouter(t)
+
+	for _, product := range []*Product{
+		{ProductID: uuid.New(), SKU: "SOCK-1", Name: "Wool Socks", Brand: "Pines", Price: 900, Currency: "USD", Status: StatusActive},
+		{ProductID: uuid.New(), SKU: "SOCK-2", Name: "Running Socks", Brand: "Acme", Price: 1500, Currency: "USD", Status: StatusActive},
+		{ProductID: uuid.New(), SKU: "SOCK-3", Name: "Hiking Socks", Brand: "Pines", Price: 30000, Currency: "USD", Status: StatusActive},
+		{ProductID: uuid.New(), SKU: "SOCK-4", Name: "Draft Socks", Brand: "Pines", Price: 900, Currency: "USD", Status: StatusDraft},
+		{ProductID: uuid.New(), SKU: "SOCK-5", Name: "Old Socks", Brand: "Pines", Price: 900, Currency: "USD", Status: StatusArchived},
+		{ProductID: uuid.New(), SKU: "SOCK-6", Name: "Silk Socks", Brand: "Pines", Price: 30, Currency: "JPY", Status: StatusActive},
+	} {
+		productStore.Products[product.ProductID] = product
+	}
+
+	t.Run("should return active matches with facets", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodGet, "/products/search?q=socks&currency=USD", nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var body struct {
+			Data SearchProductsResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Data.TotalCount != 3 {
+			t.Errorf("expected 3 matches, got %d", body.Data.TotalCount)
+		}
+
+		brands := []FacetValue{{Value: "Pines", Label: "Pines", Count: 2}, {Value: "Acme", Label: "Acme", Count: 1}}
+		if !slices.Equal(body.Data.Facets.Brands, brands) {
+			t.Errorf("expected brand facets %v, got %v", brands, body.Data.Facets.Brands)
+		}
+
+		ranges := body.Data.Facets.PriceRanges
+		if len(ranges) != 3 {
+			t.Fatalf("expected 3 price ranges, got %d", len(ranges))
+		}
+
+		if ranges[0].Min != nil || *ranges[0].Max != 1000 {
+			t.Errorf("expected the first range to be under 1000, got %v-%v", ranges[0].Min, ranges[0].Max)
+		}
+
+		if *ranges[2].Min != 25000 || ranges[2].Max != nil {
+			t.Errorf("expected the last range to be open ended from 25000, got %v-%v", ranges[2].Min, ranges[2].Max)
+		}
+	})
+
+	t.Run("should bucket prices in the minor unit of the currency", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodGet, "/products/search?q=socks&currency=JPY", nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var body struct {
+			Data SearchProductsResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Data.TotalCount != 1 || body.Data.Results[0].Product.SKU != "SOCK-6" {
+			t.Fatalf("expected only SOCK-6, got %d results", body.Data.TotalCount)
+		}
+
+		ranges := body.Data.Facets.PriceRanges
+		if len(ranges) != 1 || *ranges[0].Min != 25 || *ranges[0].Max != 50 {
+			t.Errorf("expected a single 25-50 range, got %v", ranges)
+		}
+	})
+
+	t.Run("should filter by brand", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodGet, "/products/search?q=socks&currency=usd&brand=Acme", nil, nil)
+
+		var body struct {
+			Data SearchProductsResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Data.Results) != 1 || body.Data.Results[0].Product.SKU != "SOCK-2" {
+			t.Errorf("expected only SOCK-2, got %d results", len(body.Data.Results))
+		}
+	})
+
+	testCases := []testCase{
+		{
+			name:     "should require a search query",
+			method:   http.MethodGet,
+			path:     "/products/search?currency=USD",
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should require a currency",
+			method:   http.MethodGet,
+			path:     "/products/search?q=socks",
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject an unknown currency",
+			method:   http.MethodGet,
+			path:     "/products/search?q=socks&currency=XYZ",
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should reject a malformed attribute filter",
+			method:   http.MethodGet,
+			path:     "/products/search?q=socks&currency=USD&attr=Color",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "should reject an unknown language",
+			method:   http.MethodGet,
+			path:     "/products/search?q=socks&currency=USD&lang=klingon",
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should fail for an unknown category",
+			method:   http.MethodGet,
+			path:     "/products/search?q=socks&currency=USD&categoryId=" + uuid.NewString(),
+			expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := testutil.Send(t, router, tc.method, tc.path, tc.payload, nil)
+			if rr.Code != tc.expected {
+				t.Errorf("expected status code %d, got %d", tc.expected, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// RegisterSellerRoutes registers the routes sellers manage their own products
+// with relative to the seller route group. They share the admin handlers,
+// which scope creating and listing to the seller in session, while the
+// routes of a single product are only let through for the seller selling it.
+func (h *handler) RegisterSellerRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeSeller))
+	authenticated.Get(
+		"/products",
+		handlerutils.MakeHandler(h.listProductsHandler(true)),
+	)
+	authenticated.Post(
+		"/products",
+		handlerutils.MakeHandler(h.createProductHandler),
+	)
+
+	owned := authenticated.With(h.requireSellerOwnership)
+	owned.Get(
+		"/products/{productID}",
+		handlerutils.MakeHandler(h.getProductHandler(true)),
+	)
+	owned.Patch(
+		"/products/{productID}",
+		handlerutils.MakeHandler(h.updateProductHandler),
+	)
+	owned.Post(
+		"/products/{productID}/archive",
+		handlerutils.MakeHandler(h.archiveProductHandler),
+	)
+	owned.Put(
+		"/products/{productID}/prices",
+		handlerutils.MakeHandler(h.setPricesHandler),
+	)
+	owned.Put(
+		"/products/{productID}/options",
+		handlerutils.MakeHandler(h.setOptionsHandler),
+	)
+	owned.Get(
+		"/products/{productID}/variants",
+		handlerutils.MakeHandler(h.listVariantsHandler),
+	)
+	owned.Patch(
+		"/products/{productID}/variants",
+		handlerutils.MakeHandler(h.bulkUpdateVariantsHandler),
+	)
+	owned.Get(
+		"/products/{productID}/slugs",
+		handlerutils.MakeHandler(seo.ListSlugsHandler("productID", h.service.listSlugs, slugError)),
+	)
+	owned.Put(
+		"/products/{productID}/slugs/{locale}",
+		handlerutils.MakeHandler(seo.SetSlugHandler("productID", h.service.setSlug, slugError)),
+	)
+	owned.Get(
+		"/products/{productID}/preview",
+		handlerutils.MakeHandler(h.previewProductHandler),
+	)
+	owned.Put(
+		"/products/{productID}/schedule",
+		handlerutils.MakeHandler(h.scheduleProductHandler),
+	)
+	owned.Delete(
+		"/products/{productID}/schedule",
+		handlerutils.MakeHandler(h.unscheduleProductHandler),
+	)
+}
+
+// requireSellerOwnership only lets requests for a product through when the
+// seller in session sells it. The products of others are reported missing
+// so sellers can not tell which products exist.
+func (h *handler) requireSellerOwnership(next http.Handler) http.Handler {
+	return handlerutils.MakeHandler(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		sellerID, ok := sellerIDFromContext(ctx)
+		if !ok {
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrUnauthorized.Error(),
+				nil,
+			)
+		}
+
+		product, err := h.service.getProduct(ctx, productID, true)
+		if err != nil && !errors.Is(err, servererrors.ErrProductNotFound) {
+			return err
+		}
+
+		if err != nil || product.SellerID == nil || *product.SellerID != sellerID {
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		}
+
+		next.ServeHTTP(w, r)
+		return nil
+	})
+}
+
+// sellerIDFromContext returns the id of the seller in session, ok is false
+// for any other entity type.
+func sellerIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok || claims.EntityType != auth.EntityTypeSeller {
+		return uuid.Nil, false
+	}
+
+	sellerID, err := uuid.Parse(claims.EntityID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return sellerID, true
+}
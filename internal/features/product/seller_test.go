@@ -0,0 +1,79 @@
+package product
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func TestSellerProducts(t *testing.T) {
+	productStore := newMockProductStore()
+	productHandler := NewHandler(NewService(productStore), testutil.HeaderAuthenticator{})
+
+	router := chi.NewRouter()
+	router.Route("/seller", productHandler.RegisterSellerRoutes)
+
+	sellerA := uuid.New()
+	sellerB := uuid.New()
+	house := &Product{ProductID: uuid.New(), SKU: "HOUSE-1", Name: "House", Price: 100, Currency: "USD", Status: StatusActive}
+	productStore.Products[house.ProductID] = house
+
+	var lamp Product
+	payload := CreateProductRequest{SKU: "LAMP-1", Name: "Desk Lamp", Price: 4900, Currency: "USD"}
+	if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/products", auth.EntityTypeSeller, sellerA, payload, &lamp); code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+	}
+
+	t.Run("should sell created products as the seller in session", func(t *testing.T) {
+		if lamp.SellerID == nil || *lamp.SellerID != sellerA {
+			t.Errorf("expected the lamp to be sold by %s, got %v", sellerA, lamp.SellerID)
+		}
+	})
+
+	t.Run("should only list the products of the seller", func(t *testing.T) {
+		var resp ListProductsResponse
+		if code := testutil.ServeAs(t, router, http.MethodGet, "/seller/products?sellerId="+sellerB.String(), auth.EntityTypeSeller, sellerA, nil, &resp); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if resp.TotalCount != 1 || resp.Products[0].ProductID != lamp.ProductID {
+			t.Errorf("expected only the lamp, got %d products", resp.TotalCount)
+		}
+
+		if code := testutil.ServeAs(t, router, http.MethodGet, "/seller/products", auth.EntityTypeSeller, sellerB, nil, &resp); code != http.StatusOK || resp.TotalCount != 0 {
+			t.Errorf("expected no products for another seller, got %d with status code %d", resp.TotalCount, code)
+		}
+	})
+
+	t.Run("should let sellers manage their own products only", func(t *testing.T) {
+		name := "Brass Desk Lamp"
+		path := "/seller/products/" + lamp.ProductID.String()
+		if code := testutil.ServeAs(t, router, http.MethodPatch, path, auth.EntityTypeSeller, sellerA, UpdateProductRequest{Name: &name}, nil); code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if productStore.Products[lamp.ProductID].Name != name {
+			t.Errorf("expected the lamp renamed to %q, got %q", name, productStore.Products[lamp.ProductID].Name)
+		}
+
+		for _, route := range []struct{ method, path string }{
+			{http.MethodGet, path},
+			{http.MethodPatch, path},
+			{http.MethodPost, path + "/archive"},
+			{http.MethodGet, "/seller/products/" + house.ProductID.String()},
+			{http.MethodGet, "/seller/products/" + uuid.NewString()},
+		} {
+			if code := testutil.ServeAs(t, router, route.method, route.path, auth.EntityTypeSeller, sellerB, UpdateProductRequest{Name: &name}, nil); code != http.StatusNotFound {
+				t.Errorf("%s %s: expected status code %d, got %d", route.method, route.path, http.StatusNotFound, code)
+			}
+		}
+
+		if productStore.Products[lamp.ProductID].Status == StatusArchived {
+			t.Error("expected the lamp not archived by another seller")
+		}
+	})
+}
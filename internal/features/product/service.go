@@ -0,0 +1,342 @@
+package product
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type productStorer interface {
+	create(ctx context.Context, product *Product) error
+	findByID(ctx context.Context, productID uuid.UUID) (*Product, error)
+	findBySKU(ctx context.Context, sku string) (*Product, error)
+	update(ctx context.Context, product *Product) error
+	updateStatus(ctx context.Context, productID uuid.UUID, status string) error
+	list(ctx context.Context, filter *listFilter) ([]*Product, int64, error)
+	categoryExists(ctx context.Context, categoryID uuid.UUID) (bool, error)
+	replacePrices(ctx context.Context, productID uuid.UUID, prices []money.Money) error
+	search(ctx context.Context, filter *searchFilter) ([]*SearchResult, int64, error)
+	searchFacets(ctx context.Context, filter *searchFilter, priceBounds []int64) ([]*facetCount, error)
+	findOptions(ctx context.Context, productID uuid.UUID) ([]*Option, error)
+	findVariants(ctx context.Context, productID uuid.UUID) ([]*Variant, error)
+	replaceOptions(ctx context.Context, productID uuid.UUID, options []*Option, variants []*Variant) error
+	updateVariants(ctx context.Context, variants []*Variant) error
+	findAttributeSchema(ctx context.Context, productID uuid.UUID) ([]*attribute.Definition, error)
+	seo.SlugStorer
+	applySchedules(ctx context.Context, now time.Time) (int64, int64, error)
+}
+
+// defaultLanguage is the text search configuration of products created
+// without one.
+const defaultLanguage = "english"
+
+type service struct {
+	productStore productStorer
+	slugs        *seo.SlugService
+}
+
+func NewService(productStore productStorer) *service {
+	return &service{
+		productStore: productStore,
+		slugs:        seo.NewSlugService(productStore, "product"),
+	}
+}
+
+func (s *service) createProduct(ctx context.Context, payload *CreateProductRequest) (*Product, error) {
+	currency, err := money.ParseCurrency(payload.Currency)
+	if err != nil {
+		return nil, servererrors.ErrUnsupportedCurrency
+	}
+
+	sku := normalizeSKU(payload.SKU)
+
+	existing, err := s.productStore.findBySKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.ProductID != uuid.Nil {
+		return nil, servererrors.ErrProductAlreadyExists
+	}
+
+	status := payload.Status
+	if status == "" {
+		status = StatusDraft
+	}
+
+	productType := payload.Type
+	if productType == "" {
+		productType = TypePhysical
+	}
+
+	language := payload.Language
+	if language == "" {
+		language = defaultLanguage
+	}
+
+	product := &Product{
+		ProductID:   uuid.New(),
+		SKU:         sku,
+		Type:        productType,
+		SellerID:    payload.SellerID,
+		Name:        strings.TrimSpace(payload.Name),
+		Description: strings.TrimSpace(payload.Description),
+		Brand:       strings.TrimSpace(payload.Brand),
+		Language:    language,
+		Price:       payload.Price,
+		Currency:    currency.Code(),
+		Status:      status,
+	}
+
+	if err = s.productStore.create(ctx, product); err != nil {
+		return nil, err
+	}
+
+	if _, err = s.slugs.Assign(ctx, product.ProductID, seo.DefaultLocale, product.Name); err != nil {
+		return nil, err
+	}
+
+	return s.productStore.findByID(ctx, product.ProductID)
+}
+
+func (s *service) updateProduct(ctx context.Context, productID uuid.UUID, payload *UpdateProductRequest) (*Product, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	if payload.SKU != nil {
+		sku := normalizeSKU(*payload.SKU)
+		if sku != product.SKU {
+			existing, err := s.productStore.findBySKU(ctx, sku)
+			if err != nil {
+				return nil, err
+			}
+
+			if existing.ProductID != uuid.Nil {
+				return nil, servererrors.ErrProductAlreadyExists
+			}
+		}
+
+		product.SKU = sku
+	}
+
+	if payload.Type != nil {
+		product.Type = *payload.Type
+	}
+
+	if payload.Name != nil {
+		product.Name = strings.TrimSpace(*payload.Name)
+	}
+
+	if payload.Description != nil {
+		product.Description = strings.TrimSpace(*payload.Description)
+	}
+
+	if payload.Brand != nil {
+		product.Brand = strings.TrimSpace(*payload.Brand)
+	}
+
+	if payload.Language != nil {
+		product.Language = *payload.Language
+	}
+
+	if payload.Price != nil {
+		product.Price = *payload.Price
+	}
+
+	if payload.Currency != nil {
+		currency, err := money.ParseCurrency(*payload.Currency)
+		if err != nil {
+			return nil, servererrors.ErrUnsupportedCurrency
+		}
+
+		for _, price := range product.Prices {
+			if price.Currency() == currency {
+				return nil, servererrors.ErrInvalidPrices
+			}
+		}
+
+		product.Currency = currency.Code()
+	}
+
+	if payload.Status != nil && *payload.Status != product.Status {
+		// Setting the status by hand overrides a pending publication, and
+		// a product taken back to draft has nothing left to unpublish.
+		product.Status = *payload.Status
+		product.PublishAt = nil
+		if product.Status == StatusDraft {
+			product.UnpublishAt = nil
+		}
+	}
+
+	if payload.SEOTitle != nil {
+		product.SEO.Title = strings.TrimSpace(*payload.SEOTitle)
+	}
+
+	if payload.SEODescription != nil {
+		product.SEO.Description = strings.TrimSpace(*payload.SEODescription)
+	}
+
+	if payload.CanonicalURL != nil {
+		product.SEO.CanonicalURL = strings.TrimSpace(*payload.CanonicalURL)
+	}
+
+	if payload.Attributes != nil {
+		schema, err := s.productStore.findAttributeSchema(ctx, productID)
+		if err != nil {
+			return nil, err
+		}
+
+		if product.Attributes, err = attribute.Validate(schema, payload.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = s.productStore.update(ctx, product); err != nil {
+		return nil, err
+	}
+
+	return s.productStore.findByID(ctx, productID)
+}
+
+// setPrices replaces the explicit prices of a product, the base price stays
+// the only price in the product currency.
+func (s *service) setPrices(ctx context.Context, productID uuid.UUID, payload *SetPricesRequest) (*Product, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	if err = checkPrices(product.Currency, payload.Prices); err != nil {
+		return nil, err
+	}
+
+	if err = s.productStore.replacePrices(ctx, productID, payload.Prices); err != nil {
+		return nil, err
+	}
+
+	return s.productStore.findByID(ctx, productID)
+}
+
+// archiveProduct hides a product from sale while keeping it around for
+// existing references.
+func (s *service) archiveProduct(ctx context.Context, productID uuid.UUID) error {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return servererrors.ErrProductNotFound
+	}
+
+	if product.Status == StatusArchived {
+		return nil
+	}
+
+	return s.productStore.updateStatus(ctx, productID, StatusArchived)
+}
+
+// getProduct returns a product, customers only see active products and the
+// others are only returned when includeUnpublished is set.
+func (s *service) getProduct(ctx context.Context, productID uuid.UUID, includeUnpublished bool) (*Product, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil || (product.Status != StatusActive && !includeUnpublished) {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	return product, nil
+}
+
+// listProducts returns a page of products, only active ones unless
+// includeUnpublished is set.
+func (s *service) listProducts(ctx context.Context, payload *ListProductsRequest, includeUnpublished bool) (*ListProductsResponse, error) {
+	if payload.MinPrice != nil && payload.MaxPrice != nil && *payload.MinPrice > *payload.MaxPrice {
+		return nil, servererrors.ErrInvalidQueryParams
+	}
+
+	if payload.CategoryID != nil {
+		exists, err := s.productStore.categoryExists(ctx, *payload.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, servererrors.ErrCategoryNotFound
+		}
+	}
+
+	var statuses []string
+	switch {
+	case payload.Status != "" && payload.Status != StatusActive && !includeUnpublished:
+		return &ListProductsResponse{
+			Products: []*Product{},
+			Page:     payload.Page,
+			PageSize: payload.PageSize,
+		}, nil
+	case payload.Status != "":
+		statuses = []string{payload.Status}
+	case !includeUnpublished:
+		statuses = []string{StatusActive}
+	}
+
+	products, totalCount, err := s.productStore.list(ctx, &listFilter{
+		Statuses:           statuses,
+		MinPrice:           payload.MinPrice,
+		MaxPrice:           payload.MaxPrice,
+		CategoryID:         payload.CategoryID,
+		IncludeDescendants: payload.IncludeDescendants,
+		SellerID:           payload.SellerID,
+		Sort:               payload.Sort,
+		Limit:              payload.PageSize,
+		Offset:             (payload.Page - 1) * payload.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListProductsResponse{
+		Products:   products,
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// checkPrices rejects negative prices and more than one price per currency,
+// the base currency included.
+func checkPrices(baseCurrency string, prices []money.Money) error {
+	seen := map[string]bool{baseCurrency: true}
+	for _, price := range prices {
+		code := price.Currency().Code()
+		if price.IsNegative() || seen[code] {
+			return servererrors.ErrInvalidPrices
+		}
+		seen[code] = true
+	}
+
+	return nil
+}
+
+func normalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
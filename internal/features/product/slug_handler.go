@@ -0,0 +1,82 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/go-chi/chi"
+)
+
+// getProductBySlugHandler returns the product a slug names, e.g.
+// ?locale=fr. A previous slug answers with a permanent redirect to the
+// current one.
+func (h *handler) getProductBySlugHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	slug, err := url.PathUnescape(chi.URLParam(r, "slug"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = seo.DefaultLocale
+	}
+
+	product, current, err := h.service.getProductBySlug(ctx, locale, slug)
+	if err != nil {
+		return slugError(err)
+	}
+
+	if current != "" && current != slug {
+		// relative to the requested path, so it holds whatever the api is
+		// mounted under
+		query := url.Values{"locale": {locale}}
+		w.Header().Set("Location", url.PathEscape(current)+"?"+query.Encode())
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusMovedPermanently,
+			"product moved",
+			&SlugRedirectResponse{
+				ProductID: product.ProductID,
+				Locale:    locale,
+				Slug:      current,
+			},
+		)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"product found",
+		product,
+	)
+}
+
+// slugError maps the errors of the slug services to responses.
+func slugError(err error) error {
+	if errors.Is(err, servererrors.ErrProductNotFound) {
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrProductNotFound.Error(),
+			nil,
+		)
+	}
+
+	return seo.SlugError(err)
+}
@@ -0,0 +1,60 @@
+package product
+
+import (
+	"context"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+// getProductBySlug returns the public product a slug names in a locale and
+// its current slug there, which differs from slug when the product has been
+// given another one since.
+func (s *service) getProductBySlug(ctx context.Context, locale string, slug string) (*Product, string, error) {
+	found, locale, err := s.slugs.Resolve(ctx, locale, slug)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if found.OwnerID == uuid.Nil {
+		return nil, "", servererrors.ErrProductNotFound
+	}
+
+	product, err := s.getProduct(ctx, found.OwnerID, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return product, product.Slugs[locale], nil
+}
+
+// listSlugs returns the current and previous slugs of a product in every
+// locale.
+func (s *service) listSlugs(ctx context.Context, productID uuid.UUID) ([]*seo.Slug, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	return s.slugs.List(ctx, productID)
+}
+
+// setSlug changes the current slug of a product in a locale, the previous
+// one keeps redirecting to it.
+func (s *service) setSlug(ctx context.Context, productID uuid.UUID, locale string, payload *seo.SetSlugRequest) (*seo.Slug, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	return s.slugs.Set(ctx, productID, product.Name, locale, payload)
+}
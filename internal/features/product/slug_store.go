@@ -0,0 +1,29 @@
+package product
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// attachSlugs loads the current slugs of products in one query.
+func (s *store) attachSlugs(ctx context.Context, products []*Product) error {
+	productIDs := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ProductID)
+	}
+
+	slugs, err := s.CurrentSlugs(ctx, productIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		product.Slugs = slugs[product.ProductID]
+		if product.Slugs == nil {
+			product.Slugs = map[string]string{}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func createProduct(t *testing.T, router http.Handler, payload CreateProductRequest) *Product {
+	t.Helper()
+
+	rr := testutil.Send(t, router, http.MethodPost, "/admin/products", payload, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		Data *Product `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.Data
+}
+
+func TestProductSlugs(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	desk := createProduct(t, router, CreateProductRequest{SKU: "DESK-1", Name: "Oak Desk", Price: 19900, Currency: "USD", Status: StatusActive})
+	otherDesk := createProduct(t, router, CreateProductRequest{SKU: "DESK-2", Name: "Oak  desk!", Price: 17900, Currency: "USD", Status: StatusActive})
+	draft := createProduct(t, router, CreateProductRequest{SKU: "CHAIR-1", Name: "Crème Chair", Price: 9900, Currency: "USD"})
+
+	t.Run("should generate slugs from names on create", func(t *testing.T) {
+		expected := map[*Product]string{desk: "oak-desk", otherDesk: "oak-desk-2", draft: "creme-chair"}
+		for product, slug := range expected {
+			if product.Slugs["en"] != slug {
+				t.Errorf("expected %q to get slug %q, got %v", product.Name, slug, product.Slugs)
+			}
+		}
+	})
+
+	deskSlugs := "/admin/products/" + desk.ProductID.String() + "/slugs/"
+
+	testCases := []struct {
+		name     string
+		path     string
+		payload  seo.SetSlugRequest
+		expected int
+	}{
+		{"should reject a slug in use by another product", deskSlugs + "en", seo.SetSlugRequest{Slug: "oak-desk-2"}, http.StatusConflict},
+		{"should reject a slug without letters or digits", deskSlugs + "en", seo.SetSlugRequest{Slug: "--"}, http.StatusUnprocessableEntity},
+		{"should reject an invalid locale", deskSlugs + "english", seo.SetSlugRequest{Slug: "desk"}, http.StatusUnprocessableEntity},
+		{"should reject an unknown product", "/admin/products/" + uuid.NewString() + "/slugs/en", seo.SetSlugRequest{Slug: "desk"}, http.StatusNotFound},
+		{"should generate a slug per locale from a title", deskSlugs + "fr_fr", seo.SetSlugRequest{Title: "Bureau en chêne"}, http.StatusOK},
+		{"should normalise a given slug", deskSlugs + "en", seo.SetSlugRequest{Slug: "Solid Oak Desk"}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := testutil.Send(t, router, http.MethodPut, tc.path, tc.payload, nil)
+			if rr.Code != tc.expected {
+				t.Errorf("expected status code %d, got %d: %s", tc.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("should redirect a previous slug to the current one", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodGet, "/products/by-slug/oak-desk", nil, nil)
+		if rr.Code != http.StatusMovedPermanently {
+			t.Fatalf("expected status code %d, got %d", http.StatusMovedPermanently, rr.Code)
+		}
+
+		if location := rr.Header().Get("Location"); location != "solid-oak-desk?locale=en" {
+			t.Errorf("expected a redirect to solid-oak-desk, got %q", location)
+		}
+
+		rr = testutil.Send(t, router, http.MethodGet, "/products/by-slug/solid-oak-desk", nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should resolve slugs per locale", func(t *testing.T) {
+		if rr := testutil.Send(t, router, http.MethodGet, "/products/by-slug/bureau-en-chene?locale=fr-FR", nil, nil); rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		if rr := testutil.Send(t, router, http.MethodGet, "/products/by-slug/bureau-en-chene", nil, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d in the default locale, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should keep previous slugs from being taken", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodPut, "/admin/products/"+otherDesk.ProductID.String()+"/slugs/en", seo.SetSlugRequest{Slug: "oak-desk"}, nil)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should hide drafts", func(t *testing.T) {
+		if rr := testutil.Send(t, router, http.MethodGet, "/products/by-slug/creme-chair", nil, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should list current and previous slugs", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodGet, "/admin/products/"+desk.ProductID.String()+"/slugs", nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var resp struct {
+			Data []*seo.Slug `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		current := 0
+		for _, slug := range resp.Data {
+			if slug.Current {
+				current++
+			}
+		}
+
+		if len(resp.Data) != 3 || current != 2 {
+			t.Errorf("expected 3 slugs, one current per locale, got %d with %d current", len(resp.Data), current)
+		}
+	})
+}
+
+func TestProductSEO(t *testing.T) {
+	router, productStore := newTestRouter(t)
+
+	desk := &Product{ProductID: uuid.New(), SKU: "DESK-1", Name: "Oak Desk", Price: 19900, Currency: "USD", Status: StatusActive}
+	productStore.Products[desk.ProductID] = desk
+	path := "/admin/products/" + desk.ProductID.String()
+
+	title, canonical := " Solid oak desk | Yellow Pines ", "https://shop.example.com/desks/oak"
+	rr := testutil.Send(t, router, http.MethodPatch, path, UpdateProductRequest{SEOTitle: &title, CanonicalURL: &canonical}, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	if desk.SEO.Title != "Solid oak desk | Yellow Pines" || desk.SEO.CanonicalURL != canonical {
+		t.Errorf("expected the seo fields to be set, got %+v", desk.SEO)
+	}
+
+	invalid := "not a url"
+	if rr = testutil.Send(t, router, http.MethodPatch, path, UpdateProductRequest{CanonicalURL: &invalid}, nil); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+	}
+
+	cleared := ""
+	if rr = testutil.Send(t, router, http.MethodPatch, path, UpdateProductRequest{CanonicalURL: &cleared}, nil); rr.Code != http.StatusOK || desk.SEO.CanonicalURL != "" {
+		t.Errorf("expected the canonical url to be cleared, got %d %q", rr.Code, desk.SEO.CanonicalURL)
+	}
+}
@@ -0,0 +1,497 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/attribute"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/money"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	productFields = "product_id, sku, product_type, seller_id, name, description, brand, language::text, price, currency, status, publish_at, unpublish_at, attributes, seo_title, seo_description, canonical_url, created_at, updated_at"
+
+	// uniqueViolation and checkViolation are the postgres error codes raised
+	// by unique and check constraints
+	uniqueViolation = "23505"
+	checkViolation  = "23514"
+)
+
+// sortColumns maps the accepted sort parameters to their ORDER BY clause, the
+// product id keeps the order stable between pages.
+var sortColumns = map[string]string{
+	"name":        "name ASC, product_id ASC",
+	"-name":       "name DESC, product_id DESC",
+	"price":       "price ASC, product_id ASC",
+	"-price":      "price DESC, product_id DESC",
+	"created_at":  "created_at ASC, product_id ASC",
+	"-created_at": "created_at DESC, product_id DESC",
+}
+
+const defaultSort = "-created_at"
+
+type store struct {
+	*seo.SlugStore
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		SlugStore: seo.NewSlugStore(db, "product_slugs", "product_id"),
+		db:        db,
+	}
+}
+
+func (s *store) create(ctx context.Context, product *Product) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO products(product_id, sku, product_type, seller_id, name, description, brand, language, price, currency, status, attributes, seo_title, seo_description, canonical_url) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)",
+		product.ProductID,
+		product.SKU,
+		product.Type,
+		product.SellerID,
+		product.Name,
+		product.Description,
+		product.Brand,
+		product.Language,
+		product.Price,
+		product.Currency,
+		product.Status,
+		product.Attributes,
+		product.SEO.Title,
+		product.SEO.Description,
+		product.SEO.CanonicalURL,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return servererrors.ErrProductAlreadyExists
+		}
+
+		return fmt.Errorf(
+			"failed to insert new product in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findByID(ctx context.Context, productID uuid.UUID) (*Product, error) {
+	products, _, err := s.getProductsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s, 0 FROM products WHERE product_id = $1", productFields),
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find product by id in product store: %w",
+			err,
+		)
+	}
+
+	if len(products) == 0 {
+		return new(Product), nil
+	}
+
+	return products[0], nil
+}
+
+func (s *store) findBySKU(ctx context.Context, sku string) (*Product, error) {
+	products, _, err := s.getProductsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s, 0 FROM products WHERE sku = $1", productFields),
+		sku,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find product by sku in product store: %w",
+			err,
+		)
+	}
+
+	if len(products) == 0 {
+		return new(Product), nil
+	}
+
+	return products[0], nil
+}
+
+func (s *store) update(ctx context.Context, product *Product) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE products SET sku = $1, product_type = $2, name = $3, description = $4, brand = $5, language = $6, price = $7, currency = $8, status = $9, publish_at = $10, unpublish_at = $11, attributes = $12, seo_title = $13, seo_description = $14, canonical_url = $15, updated_at = NOW() WHERE product_id = $16",
+		product.SKU,
+		product.Type,
+		product.Name,
+		product.Description,
+		product.Brand,
+		product.Language,
+		product.Price,
+		product.Currency,
+		product.Status,
+		product.PublishAt,
+		product.UnpublishAt,
+		product.Attributes,
+		product.SEO.Title,
+		product.SEO.Description,
+		product.SEO.CanonicalURL,
+		product.ProductID,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return servererrors.ErrProductAlreadyExists
+		}
+
+		return fmt.Errorf(
+			"failed to update product in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// updateStatus sets the status of a product by hand, which cancels any
+// scheduled publish or unpublish.
+func (s *store) updateStatus(ctx context.Context, productID uuid.UUID, status string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE products SET status = $1, publish_at = NULL, unpublish_at = NULL, updated_at = NOW() WHERE product_id = $2",
+		status,
+		productID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update product status in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// list returns one page of products matching filter along with the number of
+// products matching it across all pages.
+func (s *store) list(ctx context.Context, filter *listFilter) ([]*Product, int64, error) {
+	conditions := []string{}
+	args := []any{}
+
+	if len(filter.Statuses) > 0 {
+		args = append(args, pq.Array(filter.Statuses))
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", len(args)))
+	}
+
+	if filter.MinPrice != nil {
+		args = append(args, *filter.MinPrice)
+		conditions = append(conditions, fmt.Sprintf("price >= $%d", len(args)))
+	}
+
+	if filter.MaxPrice != nil {
+		args = append(args, *filter.MaxPrice)
+		conditions = append(conditions, fmt.Sprintf("price <= $%d", len(args)))
+	}
+
+	if filter.CategoryID != nil {
+		args = append(args, *filter.CategoryID)
+		if filter.IncludeDescendants {
+			conditions = append(conditions, fmt.Sprintf("product_id IN (SELECT pc.product_id FROM product_categories pc JOIN category_closure cc ON cc.descendant_id = pc.category_id WHERE cc.ancestor_id = $%d)", len(args)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("product_id IN (SELECT product_id FROM product_categories WHERE category_id = $%d)", len(args)))
+		}
+	}
+
+	if filter.SellerID != nil {
+		args = append(args, *filter.SellerID)
+		conditions = append(conditions, fmt.Sprintf("seller_id = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	orderBy, ok := sortColumns[filter.Sort]
+	if !ok {
+		orderBy = sortColumns[defaultSort]
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(
+		"SELECT %s, COUNT(*) OVER() FROM products %s ORDER BY %s LIMIT $%d OFFSET $%d",
+		productFields,
+		where,
+		orderBy,
+		len(args)-1,
+		len(args),
+	)
+
+	products, totalCount, err := s.getProductsWithContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to list products in product store: %w",
+			err,
+		)
+	}
+
+	return products, totalCount, nil
+}
+
+func (s *store) categoryExists(ctx context.Context, categoryID uuid.UUID) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM categories WHERE category_id = $1)",
+		categoryID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to check category in product store: %w",
+			err,
+		)
+	}
+
+	return exists, nil
+}
+
+// findAttributeSchema returns the attributes defined for the products of the
+// categories productID is in, including those the categories inherit. A code
+// defined along several branches counts once, by its nearest definition.
+func (s *store) findAttributeSchema(ctx context.Context, productID uuid.UUID) ([]*attribute.Definition, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT DISTINCT ON (a.code) a.attribute_id, a.category_id, a.code, a.name, a.type, a.unit, a.enum_values, a.required, a.filterable, a.position
+		FROM product_categories pc
+		JOIN category_closure cc ON cc.descendant_id = pc.category_id
+		JOIN category_attributes a ON a.category_id = cc.ancestor_id
+		WHERE pc.product_id = $1
+		ORDER BY a.code, cc.depth, a.required DESC`,
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find attribute schema in product store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	definitions := []*attribute.Definition{}
+	for rows.Next() {
+		definition := new(attribute.Definition)
+		err = rows.Scan(
+			&definition.AttributeID,
+			&definition.CategoryID,
+			&definition.Code,
+			&definition.Name,
+			&definition.Type,
+			&definition.Unit,
+			pq.Array(&definition.Values),
+			&definition.Required,
+			&definition.Filterable,
+			&definition.Position,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into attribute in product store: %w",
+				err,
+			)
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in product store: %w",
+			err,
+		)
+	}
+
+	return definitions, nil
+}
+
+// getProductsWithContext runs a query selecting productFields followed by a
+// count column and returns the products with the count of the last row.
+func (s *store) getProductsWithContext(ctx context.Context, query string, args ...any) ([]*Product, int64, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to query db in product store getProductsWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	var totalCount int64
+	products := []*Product{}
+	for rows.Next() {
+		product := new(Product)
+		if err = scanRowsIntoProduct(rows, product, &totalCount); err != nil {
+			return nil, 0, err
+		}
+
+		products = append(products, product)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to iterate rows in product store: %w",
+			err,
+		)
+	}
+
+	if err = s.attachPrices(ctx, products); err != nil {
+		return nil, 0, err
+	}
+
+	if err = s.attachSlugs(ctx, products); err != nil {
+		return nil, 0, err
+	}
+
+	return products, totalCount, nil
+}
+
+// replacePrices replaces the explicit per currency prices of a product.
+func (s *store) replacePrices(ctx context.Context, productID uuid.UUID, prices []money.Money) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM product_prices WHERE product_id = $1", productID); err != nil {
+			return fmt.Errorf("failed to delete product prices in product store: %w", err)
+		}
+
+		for _, price := range prices {
+			_, err := tx.ExecContext(
+				ctx,
+				"INSERT INTO product_prices(product_id, currency, amount) VALUES($1, $2, $3)",
+				productID,
+				price.Currency(),
+				price.Amount(),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert product price in product store: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// attachPrices loads the explicit prices of products in one query.
+func (s *store) attachPrices(ctx context.Context, products []*Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*Product, len(products))
+	productIDs := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		product.Prices = []money.Money{}
+		byID[product.ProductID] = product
+		productIDs = append(productIDs, product.ProductID)
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT product_id, currency, amount FROM product_prices WHERE product_id = ANY($1) ORDER BY currency ASC",
+		pq.Array(productIDs),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to query product prices in product store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID uuid.UUID
+		var currency money.Currency
+		var amount int64
+		if err = rows.Scan(&productID, &currency, &amount); err != nil {
+			return fmt.Errorf(
+				"failed to scan row into product price in product store: %w",
+				err,
+			)
+		}
+
+		price, err := money.New(amount, currency.Code())
+		if err != nil {
+			return err
+		}
+
+		if product, ok := byID[productID]; ok {
+			product.Prices = append(product.Prices, price)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf(
+			"failed to iterate product price rows in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func scanRowsIntoProduct(rows *sql.Rows, product *Product, totalCount *int64) error {
+	if product == nil {
+		return errors.New(
+			"scanRowsIntoProduct err in product store",
+		)
+	}
+
+	err := rows.Scan(append(productColumns(product), totalCount)...)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to scan row into product in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// productColumns returns the scan destinations matching productFields.
+func productColumns(product *Product) []any {
+	return []any{
+		&product.ProductID,
+		&product.SKU,
+		&product.Type,
+		&product.SellerID,
+		&product.Name,
+		&product.Description,
+		&product.Brand,
+		&product.Language,
+		&product.Price,
+		&product.Currency,
+		&product.Status,
+		&product.PublishAt,
+		&product.UnpublishAt,
+		&product.Attributes,
+		&product.SEO.Title,
+		&product.SEO.Description,
+		&product.SEO.CanonicalURL,
+		&product.CreatedAt,
+		&product.UpdatedAt,
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	return isPQError(err, uniqueViolation)
+}
+
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
@@ -0,0 +1,307 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func (h *handler) setOptionsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *SetOptionsRequest
+	var err error
+	defer r.Body.Close()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	resp, err := h.service.setOptions(ctx, productID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrInvalidProductOptions):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrInvalidProductOptions.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrTooManyVariants):
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrTooManyVariants.Error(),
+				map[string]int{"maxVariants": maxVariants},
+			)
+		case errors.Is(err, servererrors.ErrVariantAlreadyExists):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrVariantAlreadyExists.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"product options updated",
+		resp,
+	)
+}
+
+func (h *handler) bulkUpdateVariantsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *BulkUpdateVariantsRequest
+	var err error
+	defer r.Body.Close()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	variants, err := h.service.bulkUpdateVariants(ctx, productID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrVariantNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrVariantNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrVariantAlreadyExists):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrVariantAlreadyExists.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrStockBelowReserved):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrStockBelowReserved.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrStockManagedByLocations):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrStockManagedByLocations.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"variants updated",
+		variants,
+	)
+}
+
+func (h *handler) listVariantsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	variants, err := h.service.listVariants(ctx, productID, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"variants found",
+		variants,
+	)
+}
+
+func (h *handler) listStorefrontVariantsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	variants, err := h.service.listStorefrontVariants(ctx, productID)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"variants found",
+		variants,
+	)
+}
+
+// resolveVariantHandler takes the selected option values as query parameters
+// named after the options, e.g. ?size=M&color=red
+func (h *handler) resolveVariantHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	selection := map[string]string{}
+	for name, values := range r.URL.Query() {
+		if len(values) != 1 {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidQueryParams.Error(),
+				nil,
+			)
+		}
+
+		selection[name] = values[0]
+	}
+
+	resp, err := h.service.resolveVariant(ctx, productID, selection)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrProductNotFound):
+			return servererrors.New(
+				http.StatusNotFound,
+				servererrors.ErrProductNotFound.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrInvalidQueryParams):
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidQueryParams.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"variant resolved",
+		resp,
+	)
+}
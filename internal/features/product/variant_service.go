@@ -0,0 +1,424 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+// maxVariants caps the option matrix so a careless options update can not
+// create thousands of variants.
+const maxVariants = 250
+
+// maxSKULength matches the sku column of products and variants.
+const maxSKULength = 64
+
+// setOptions replaces the options of a product and regenerates its variants,
+// one per combination of option values. Variants whose combination survives
+// keep their id, sku, prices and stock.
+func (s *service) setOptions(ctx context.Context, productID uuid.UUID, payload *SetOptionsRequest) (*SetOptionsResponse, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	options, err := normalizeOptions(productID, payload.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.productStore.findVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	existingByKey := make(map[string]*Variant, len(existing))
+	for _, variant := range existing {
+		existingByKey[combinationKey(variant.Options)] = variant
+	}
+
+	variants := []*Variant{}
+	for _, combination := range combinations(options) {
+		variant, ok := existingByKey[combinationKey(combination)]
+		if !ok {
+			variant = &Variant{VariantID: uuid.New()}
+			variant.SKU = variantSKU(product.SKU, options, combination, variant.VariantID)
+		}
+		variant.Options = combination
+
+		variants = append(variants, variant)
+	}
+
+	if err = s.productStore.replaceOptions(ctx, productID, options, variants); err != nil {
+		return nil, err
+	}
+
+	variants, err = s.productStore.findVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetOptionsResponse{
+		Options:  options,
+		Variants: variants,
+	}, nil
+}
+
+// bulkUpdateVariants applies several variant edits of one product at once,
+// either all of them are saved or none.
+func (s *service) bulkUpdateVariants(ctx context.Context, productID uuid.UUID, payload *BulkUpdateVariantsRequest) ([]*Variant, error) {
+	product, err := s.productStore.findByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ProductID == uuid.Nil {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	variants, err := s.productStore.findVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	variantsByID := make(map[uuid.UUID]*Variant, len(variants))
+	for _, variant := range variants {
+		variantsByID[variant.VariantID] = variant
+	}
+
+	updated := []*Variant{}
+	for _, update := range payload.Variants {
+		variant, ok := variantsByID[update.VariantID]
+		if !ok {
+			return nil, servererrors.ErrVariantNotFound
+		}
+
+		applyVariantUpdate(variant, &update)
+		updated = append(updated, variant)
+	}
+
+	skus := make(map[string]struct{}, len(variants))
+	barcodes := make(map[string]struct{}, len(variants))
+	for _, variant := range variants {
+		if _, taken := skus[variant.SKU]; taken {
+			return nil, servererrors.ErrVariantAlreadyExists
+		}
+		skus[variant.SKU] = struct{}{}
+
+		if variant.Barcode == nil {
+			continue
+		}
+		if _, taken := barcodes[*variant.Barcode]; taken {
+			return nil, servererrors.ErrVariantAlreadyExists
+		}
+		barcodes[*variant.Barcode] = struct{}{}
+	}
+
+	if err = s.productStore.updateVariants(ctx, updated); err != nil {
+		return nil, err
+	}
+
+	return s.productStore.findVariants(ctx, productID)
+}
+
+// listVariants returns the variants of a product, those of products not on
+// sale are only returned when includeUnpublished is set.
+func (s *service) listVariants(ctx context.Context, productID uuid.UUID, includeUnpublished bool) ([]*Variant, error) {
+	if _, err := s.getProduct(ctx, productID, includeUnpublished); err != nil {
+		return nil, err
+	}
+
+	return s.productStore.findVariants(ctx, productID)
+}
+
+func (s *service) listStorefrontVariants(ctx context.Context, productID uuid.UUID) ([]*StorefrontVariant, error) {
+	product, err := s.getProduct(ctx, productID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.productStore.findVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	storefrontVariants := make([]*StorefrontVariant, 0, len(variants))
+	for _, variant := range variants {
+		storefrontVariants = append(storefrontVariants, newStorefrontVariant(product, variant))
+	}
+
+	return storefrontVariants, nil
+}
+
+// resolveVariant takes a possibly partial selection of option values and
+// returns the matching variant once every option is selected, along with the
+// availability of each option value given the other selected values.
+func (s *service) resolveVariant(ctx context.Context, productID uuid.UUID, selection map[string]string) (*ResolveVariantResponse, error) {
+	product, err := s.getProduct(ctx, productID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := s.productStore.findOptions(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := canonicalSelection(options, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.productStore.findVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ResolveVariantResponse{
+		Options: make([]OptionAvailability, 0, len(options)),
+	}
+
+	for _, option := range options {
+		availability := OptionAvailability{
+			Name:   option.Name,
+			Values: make([]OptionValueAvailability, 0, len(option.Values)),
+		}
+
+		for _, value := range option.Values {
+			availability.Values = append(availability.Values, OptionValueAvailability{
+				Value:    value,
+				Selected: selected[option.Name] == value,
+				Available: anyVariant(variants, func(variant *Variant) bool {
+					return variant.inStock() &&
+						variant.Options[option.Name] == value &&
+						matchesSelection(variant, selected, option.Name)
+				}),
+			})
+		}
+
+		resp.Options = append(resp.Options, availability)
+	}
+
+	if len(options) > 0 && len(selected) == len(options) {
+		for _, variant := range variants {
+			if matchesSelection(variant, selected, "") {
+				resp.Variant = newStorefrontVariant(product, variant)
+				break
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+func applyVariantUpdate(variant *Variant, update *VariantUpdate) {
+	if update.SKU != nil {
+		variant.SKU = normalizeSKU(*update.SKU)
+	}
+
+	if update.RemovePriceOverride {
+		variant.PriceOverride = nil
+	} else if update.PriceOverride != nil {
+		variant.PriceOverride = update.PriceOverride
+	}
+
+	if update.RemoveBarcode {
+		variant.Barcode = nil
+	} else if update.Barcode != nil {
+		barcode := strings.TrimSpace(*update.Barcode)
+		variant.Barcode = &barcode
+	}
+
+	if update.WeightGrams != nil {
+		variant.WeightGrams = update.WeightGrams
+	}
+
+	if update.StockQuantity != nil {
+		variant.StockQuantity = *update.StockQuantity
+	}
+}
+
+// normalizeOptions trims option names and values and rejects duplicates,
+// compared without letter case.
+func normalizeOptions(productID uuid.UUID, requested []OptionRequest) ([]*Option, error) {
+	options := make([]*Option, 0, len(requested))
+	names := map[string]struct{}{}
+	variantCount := 1
+
+	for i, req := range requested {
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			return nil, servererrors.ErrInvalidProductOptions
+		}
+
+		if _, taken := names[strings.ToLower(name)]; taken {
+			return nil, servererrors.ErrInvalidProductOptions
+		}
+		names[strings.ToLower(name)] = struct{}{}
+
+		values := make([]string, 0, len(req.Values))
+		seen := map[string]struct{}{}
+		for _, value := range req.Values {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return nil, servererrors.ErrInvalidProductOptions
+			}
+
+			if _, taken := seen[strings.ToLower(value)]; taken {
+				return nil, servererrors.ErrInvalidProductOptions
+			}
+			seen[strings.ToLower(value)] = struct{}{}
+
+			values = append(values, value)
+		}
+
+		variantCount *= len(values)
+		if variantCount > maxVariants {
+			return nil, servererrors.ErrTooManyVariants
+		}
+
+		options = append(options, &Option{
+			OptionID:  uuid.New(),
+			ProductID: productID,
+			Name:      name,
+			Values:    values,
+			Position:  i,
+		})
+	}
+
+	return options, nil
+}
+
+// combinations returns every combination of option values, the first option
+// varying slowest. No options means no combinations.
+func combinations(options []*Option) []map[string]string {
+	if len(options) == 0 {
+		return nil
+	}
+
+	result := []map[string]string{{}}
+	for _, option := range options {
+		next := make([]map[string]string, 0, len(result)*len(option.Values))
+		for _, partial := range result {
+			for _, value := range option.Values {
+				combination := make(map[string]string, len(partial)+1)
+				for name, v := range partial {
+					combination[name] = v
+				}
+				combination[option.Name] = value
+
+				next = append(next, combination)
+			}
+		}
+		result = next
+	}
+
+	return result
+}
+
+// variantSKU derives a sku from the product sku and the option values, e.g.
+// TEE-001-M-RED, falling back to a short id when that is too long.
+func variantSKU(productSKU string, options []*Option, combination map[string]string, variantID uuid.UUID) string {
+	parts := []string{productSKU}
+	for _, option := range options {
+		parts = append(parts, skuSegment(combination[option.Name]))
+	}
+
+	sku := strings.Join(parts, "-")
+	if len(sku) <= maxSKULength {
+		return sku
+	}
+
+	suffix := "-" + strings.ToUpper(variantID.String()[:8])
+	return productSKU[:min(len(productSKU), maxSKULength-len(suffix))] + suffix
+}
+
+// skuSegment keeps the letters and digits of an option value, upper-cased.
+func skuSegment(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(value) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// canonicalSelection maps a selection using any letter case to the stored
+// option names and values.
+func canonicalSelection(options []*Option, selection map[string]string) (map[string]string, error) {
+	selected := make(map[string]string, len(selection))
+	for name, value := range selection {
+		option := findOption(options, name)
+		if option == nil {
+			return nil, fmt.Errorf("%w: unknown option %s", servererrors.ErrInvalidQueryParams, name)
+		}
+
+		canonical := ""
+		for _, v := range option.Values {
+			if strings.EqualFold(v, strings.TrimSpace(value)) {
+				canonical = v
+				break
+			}
+		}
+
+		if canonical == "" {
+			return nil, fmt.Errorf("%w: unknown value %s for option %s", servererrors.ErrInvalidQueryParams, value, name)
+		}
+
+		selected[option.Name] = canonical
+	}
+
+	return selected, nil
+}
+
+func findOption(options []*Option, name string) *Option {
+	for _, option := range options {
+		if strings.EqualFold(option.Name, strings.TrimSpace(name)) {
+			return option
+		}
+	}
+
+	return nil
+}
+
+// matchesSelection reports whether variant has every selected value, ignoring
+// the option named except.
+func matchesSelection(variant *Variant, selected map[string]string, except string) bool {
+	for name, value := range selected {
+		if name != except && variant.Options[name] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func anyVariant(variants []*Variant, fn func(variant *Variant) bool) bool {
+	for _, variant := range variants {
+		if fn(variant) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newStorefrontVariant(product *Product, variant *Variant) *StorefrontVariant {
+	return &StorefrontVariant{
+		VariantID: variant.VariantID,
+		SKU:       variant.SKU,
+		Options:   variant.Options,
+		Price:     variant.Price,
+		Currency:  product.Currency,
+		Available: variant.inStock(),
+	}
+}
@@ -0,0 +1,332 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/stockledger"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	optionFields  = "option_id, product_id, name, option_values, position"
+	variantFields = "v.variant_id, v.product_id, v.sku, v.option_values, v.price_override, COALESCE(v.price_override, p.price), v.barcode, v.weight_grams, COALESCE(vs.quantity, 0), COALESCE(vs.reserved, 0), v.created_at, v.updated_at"
+	variantJoins  = "product_variants v JOIN products p ON p.product_id = v.product_id LEFT JOIN variant_stock vs ON vs.variant_id = v.variant_id"
+)
+
+func (s *store) findOptions(ctx context.Context, productID uuid.UUID) ([]*Option, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM product_options WHERE product_id = $1 ORDER BY position", optionFields),
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find options in product store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	options := []*Option{}
+	for rows.Next() {
+		option := new(Option)
+		err = rows.Scan(
+			&option.OptionID,
+			&option.ProductID,
+			&option.Name,
+			pq.Array(&option.Values),
+			&option.Position,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into option in product store: %w",
+				err,
+			)
+		}
+
+		options = append(options, option)
+	}
+
+	return options, rows.Err()
+}
+
+func (s *store) findVariants(ctx context.Context, productID uuid.UUID) ([]*Variant, error) {
+	variants, err := s.getVariantsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE v.product_id = $1 ORDER BY v.created_at, v.sku", variantFields, variantJoins),
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find variants in product store: %w",
+			err,
+		)
+	}
+
+	return variants, nil
+}
+
+// replaceOptions swaps the options of a product and makes variants the full
+// set of its variants: missing ones are deleted, new ones created with an
+// empty stock record and kept ones get their option values refreshed.
+func (s *store) replaceOptions(ctx context.Context, productID uuid.UUID, options []*Option, variants []*Variant) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM product_options WHERE product_id = $1",
+			productID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete options in product store: %w", err)
+		}
+
+		for _, option := range options {
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO product_options(option_id, product_id, name, option_values, position) VALUES($1, $2, $3, $4, $5)",
+				option.OptionID,
+				productID,
+				option.Name,
+				pq.Array(option.Values),
+				option.Position,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert option in product store: %w", err)
+			}
+		}
+
+		variantIDs := make([]uuid.UUID, 0, len(variants))
+		for _, variant := range variants {
+			variantIDs = append(variantIDs, variant.VariantID)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"DELETE FROM product_variants WHERE product_id = $1 AND NOT (variant_id = ANY($2))",
+			productID,
+			pq.Array(variantIDs),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete variants in product store: %w", err)
+		}
+
+		for _, variant := range variants {
+			optionValues, err := json.Marshal(variant.Options)
+			if err != nil {
+				return fmt.Errorf("failed to marshal variant options in product store: %w", err)
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO product_variants(variant_id, product_id, sku, option_values, combination_key) VALUES($1, $2, $3, $4, $5) ON CONFLICT (variant_id) DO UPDATE SET option_values = EXCLUDED.option_values, combination_key = EXCLUDED.combination_key, updated_at = NOW()",
+				variant.VariantID,
+				productID,
+				variant.SKU,
+				optionValues,
+				combinationKey(variant.Options),
+			)
+			if err != nil {
+				if isUniqueViolation(err) {
+					return servererrors.ErrVariantAlreadyExists
+				}
+
+				return fmt.Errorf("failed to upsert variant in product store: %w", err)
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO variant_stock(variant_id) VALUES($1) ON CONFLICT (variant_id) DO NOTHING",
+				variant.VariantID,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert variant stock in product store: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// updateVariants saves the editable fields and stock of variants atomically.
+func (s *store) updateVariants(ctx context.Context, variants []*Variant) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, variant := range variants {
+			_, err := tx.ExecContext(
+				ctx,
+				"UPDATE product_variants SET sku = $1, price_override = $2, barcode = $3, weight_grams = $4, updated_at = NOW() WHERE variant_id = $5",
+				variant.SKU,
+				variant.PriceOverride,
+				variant.Barcode,
+				variant.WeightGrams,
+				variant.VariantID,
+			)
+			if err != nil {
+				if isUniqueViolation(err) {
+					return servererrors.ErrVariantAlreadyExists
+				}
+
+				return fmt.Errorf("failed to update variant in product store: %w", err)
+			}
+
+			// the stock of variants kept at locations is the sum over the
+			// locations and is set there, the stock row is locked first as
+			// the inventory feature does before changing location stock
+			var previousQuantity int64
+			err = tx.QueryRowContext(
+				ctx,
+				"SELECT quantity FROM variant_stock WHERE variant_id = $1 FOR UPDATE",
+				variant.VariantID,
+			).Scan(&previousQuantity)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to lock variant stock in product store: %w", err)
+			}
+
+			var byLocation bool
+			err = tx.QueryRowContext(
+				ctx,
+				"SELECT EXISTS(SELECT 1 FROM location_stock ls JOIN variant_stock vs ON vs.variant_id = ls.variant_id WHERE ls.variant_id = $1 AND vs.quantity <> $2)",
+				variant.VariantID,
+				variant.StockQuantity,
+			).Scan(&byLocation)
+			if err != nil {
+				return fmt.Errorf("failed to find location stock in product store: %w", err)
+			}
+
+			if byLocation {
+				return servererrors.ErrStockManagedByLocations
+			}
+
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO variant_stock(variant_id, quantity) VALUES($1, $2) ON CONFLICT (variant_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()",
+				variant.VariantID,
+				variant.StockQuantity,
+			)
+			if err != nil {
+				// the stock can not drop below what checkouts reserved
+				if isPQError(err, checkViolation) {
+					return servererrors.ErrStockBelowReserved
+				}
+
+				return fmt.Errorf("failed to update variant stock in product store: %w", err)
+			}
+
+			err = stockledger.Record(ctx, tx, &stockledger.Movement{
+				VariantID: variant.VariantID,
+				Quantity:  variant.StockQuantity - previousQuantity,
+				Reason:    stockledger.ReasonAdjustment,
+				Reference: variant.ProductID.String(),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to record stock adjustment in product store: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *store) getVariantsWithContext(ctx context.Context, query string, args ...any) ([]*Variant, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in product store getVariantsWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	variants := []*Variant{}
+	for rows.Next() {
+		variant := new(Variant)
+		if err = scanRowsIntoVariant(rows, variant); err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, variant)
+	}
+
+	return variants, rows.Err()
+}
+
+func scanRowsIntoVariant(rows *sql.Rows, variant *Variant) error {
+	if variant == nil {
+		return errors.New(
+			"scanRowsIntoVariant err in product store",
+		)
+	}
+
+	var optionValues []byte
+	err := rows.Scan(
+		&variant.VariantID,
+		&variant.ProductID,
+		&variant.SKU,
+		&optionValues,
+		&variant.PriceOverride,
+		&variant.Price,
+		&variant.Barcode,
+		&variant.WeightGrams,
+		&variant.StockQuantity,
+		&variant.ReservedQuantity,
+		&variant.CreatedAt,
+		&variant.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to scan row into variant in product store: %w",
+			err,
+		)
+	}
+
+	if err = json.Unmarshal(optionValues, &variant.Options); err != nil {
+		return fmt.Errorf(
+			"failed to unmarshal variant options in product store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// withTx runs fn in a transaction, committing when it returns nil.
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in product store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in product store: %w", err)
+	}
+
+	return nil
+}
+
+// combinationKey identifies a combination of option values regardless of
+// letter case and option order, e.g. "color=red|size=m".
+func combinationKey(options map[string]string) string {
+	pairs := make([]string, 0, len(options))
+	for name, value := range options {
+		pairs = append(pairs, strings.ToLower(name)+"="+strings.ToLower(value))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "|")
+}
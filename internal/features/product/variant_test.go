@@ -0,0 +1,206 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestVariants(t *testing.T) {
+	router, productStore := newTestRouter(t)
+
+	tee := &Product{ProductID: uuid.New(), SKU: "TEE", Name: "Tee", Price: 2000, Currency: "USD", Status: StatusActive}
+	productStore.Products[tee.ProductID] = tee
+	productPath := "/admin/products/" + tee.ProductID.String()
+
+	rr := testutil.Send(t, router, http.MethodPut, productPath+"/options", SetOptionsRequest{
+		Options: []OptionRequest{
+			{Name: "Size", Values: []string{"S", "M"}},
+			{Name: "Color", Values: []string{"Red", "Navy Blue"}},
+		},
+	}, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var setResp struct {
+		Data SetOptionsResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&setResp); err != nil {
+		t.Fatal(err)
+	}
+
+	skus := []string{}
+	variantIDs := map[string]uuid.UUID{}
+	for _, variant := range setResp.Data.Variants {
+		skus = append(skus, variant.SKU)
+		variantIDs[variant.SKU] = variant.VariantID
+	}
+
+	expectedSKUs := []string{"TEE-M-NAVYBLUE", "TEE-M-RED", "TEE-S-NAVYBLUE", "TEE-S-RED"}
+	if !slices.Equal(skus, expectedSKUs) {
+		t.Fatalf("expected variants %v, got %v", expectedSKUs, skus)
+	}
+
+	t.Run("should keep surviving variants when options change", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodPut, productPath+"/options", SetOptionsRequest{
+			Options: []OptionRequest{
+				{Name: "size", Values: []string{"s", "M", "L"}},
+				{Name: "Color", Values: []string{"Red"}},
+			},
+		}, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		if _, ok := productStore.Variants[variantIDs["TEE-S-RED"]]; !ok {
+			t.Error("expected the S/Red variant to be kept")
+		}
+
+		if _, ok := productStore.Variants[variantIDs["TEE-S-NAVYBLUE"]]; ok {
+			t.Error("expected the S/Navy Blue variant to be removed")
+		}
+
+		if len(productStore.Variants) != 3 {
+			t.Errorf("expected 3 variants, got %d", len(productStore.Variants))
+		}
+	})
+
+	t.Run("should reject duplicate option values", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodPut, productPath+"/options", SetOptionsRequest{
+			Options: []OptionRequest{{Name: "Size", Values: []string{"S", "s"}}},
+		}, nil)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+	})
+
+	t.Run("should reject too many variants", func(t *testing.T) {
+		values := []string{}
+		for i := 0; i < 50; i++ {
+			values = append(values, uuid.NewString()[:8])
+		}
+
+		rr := testutil.Send(t, router, http.MethodPut, productPath+"/options", SetOptionsRequest{
+			Options: []OptionRequest{
+				{Name: "A", Values: values},
+				{Name: "B", Values: values[:6]},
+			},
+		}, nil)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+	})
+
+	smallRed := variantIDs["TEE-S-RED"]
+	var mediumRed uuid.UUID
+	for _, variant := range productStore.Variants {
+		if variant.Options["size"] == "M" {
+			mediumRed = variant.VariantID
+		}
+	}
+
+	t.Run("should bulk update variants", func(t *testing.T) {
+		price := int64(2500)
+		stock := int64(4)
+		barcode := "00012345678905"
+		rr := testutil.Send(t, router, http.MethodPatch, productPath+"/variants", BulkUpdateVariantsRequest{
+			Variants: []VariantUpdate{
+				{VariantID: smallRed, PriceOverride: &price, StockQuantity: &stock, Barcode: &barcode},
+				{VariantID: mediumRed, StockQuantity: &stock},
+			},
+		}, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		variant := productStore.Variants[smallRed]
+		if variant.PriceOverride == nil || *variant.PriceOverride != price || variant.StockQuantity != stock {
+			t.Errorf("expected the price override and stock to be saved, got %+v", variant)
+		}
+	})
+
+	t.Run("should reject duplicate skus in a bulk update", func(t *testing.T) {
+		sku := "TEE-DUP"
+		rr := testutil.Send(t, router, http.MethodPatch, productPath+"/variants", BulkUpdateVariantsRequest{
+			Variants: []VariantUpdate{
+				{VariantID: smallRed, SKU: &sku},
+				{VariantID: mediumRed, SKU: &sku},
+			},
+		}, nil)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should reject variants of other products", func(t *testing.T) {
+		stock := int64(1)
+		rr := testutil.Send(t, router, http.MethodPatch, productPath+"/variants", BulkUpdateVariantsRequest{
+			Variants: []VariantUpdate{{VariantID: uuid.New(), StockQuantity: &stock}},
+		}, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	resolvePath := "/products/" + tee.ProductID.String() + "/variants/resolve"
+
+	t.Run("should report availability for a partial selection", func(t *testing.T) {
+		resp := resolve(t, router, resolvePath+"?color=red")
+		if resp.Variant != nil {
+			t.Error("expected no variant for a partial selection")
+		}
+
+		available := map[string]bool{}
+		for _, value := range resp.Options[0].Values {
+			available[value.Value] = value.Available
+		}
+
+		expected := map[string]bool{"s": true, "M": true, "L": false}
+		for value, isAvailable := range expected {
+			if available[value] != isAvailable {
+				t.Errorf("expected size %s available=%t, got %t", value, isAvailable, available[value])
+			}
+		}
+	})
+
+	t.Run("should resolve a full selection", func(t *testing.T) {
+		resp := resolve(t, router, resolvePath+"?Size=S&COLOR=RED")
+		if resp.Variant == nil || resp.Variant.VariantID != smallRed {
+			t.Fatalf("expected variant %s, got %+v", smallRed, resp.Variant)
+		}
+
+		if resp.Variant.Price != 2500 || !resp.Variant.Available {
+			t.Errorf("expected an available variant at the override price, got %+v", resp.Variant)
+		}
+	})
+
+	t.Run("should reject unknown options", func(t *testing.T) {
+		rr := testutil.Send(t, router, http.MethodGet, resolvePath+"?material=cotton", nil, nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+func resolve(t *testing.T, router http.Handler, path string) *ResolveVariantResponse {
+	t.Helper()
+
+	rr := testutil.Send(t, router, http.MethodGet, path, nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var body struct {
+		Data ResolveVariantResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	return &body.Data
+}
@@ -0,0 +1,42 @@
+package recommendation
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Requests
+
+// RelatedProductsRequest is read from the query string, e.g. ?limit=8
+type RelatedProductsRequest struct {
+	ProductID uuid.UUID
+	Limit     int64 `validate:"min=1,max=24"`
+}
+
+// CartRecommendationsRequest is read from the query string, one productId per
+// product in the cart, e.g. ?productId=...&productId=...&limit=8
+type CartRecommendationsRequest struct {
+	ProductIDs []uuid.UUID `validate:"required,min=1,max=50"`
+	Limit      int64       `validate:"min=1,max=24"`
+}
+
+// SetOverridesRequest replaces the overrides of a product. Pins are
+// recommended first in the order given, excludes are never recommended.
+type SetOverridesRequest struct {
+	Pins     []uuid.UUID `json:"pins" validate:"max=24,unique"`
+	Excludes []uuid.UUID `json:"excludes" validate:"max=200,unique"`
+}
+
+// Responses
+
+type OverridesResponse struct {
+	Pins     []uuid.UUID `json:"pins"`
+	Excludes []uuid.UUID `json:"excludes"`
+}
+
+type ComputeScoresResponse struct {
+	Products   int       `json:"products"`
+	Pairs      int       `json:"pairs"`
+	ComputedAt time.Time `json:"computedAt"`
+}
@@ -0,0 +1,52 @@
+package recommendation
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Where a recommendation comes from, in the order they are filled in.
+const (
+	SourcePinned     = "pinned"
+	SourceCoPurchase = "co_purchase"
+	SourceCategory   = "category"
+)
+
+// Override kinds.
+const (
+	OverridePin     = "pin"
+	OverrideExclude = "exclude"
+)
+
+// Recommendation is a product suggested alongside others, Score ranks the
+// recommendations of the same source.
+type Recommendation struct {
+	ProductID uuid.UUID `json:"product_id"`
+	SKU       string    `json:"sku"`
+	Name      string    `json:"name"`
+	Brand     string    `json:"brand"`
+	Price     int64     `json:"price"` // in minor units of currency e.g. cents
+	Currency  string    `json:"currency"`
+	Source    string    `json:"source"`
+	Score     float64   `json:"score"`
+}
+
+// Override pins RelatedProductID to the recommendations of ProductID, at
+// Position among the pins, or excludes it from them.
+type Override struct {
+	ProductID        uuid.UUID `json:"product_id"`
+	RelatedProductID uuid.UUID `json:"related_product_id"`
+	Kind             string    `json:"kind"`
+	Position         int       `json:"position"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// coPurchase scores how often RelatedProductID is bought along with
+// ProductID, from 0 to 1.
+type coPurchase struct {
+	ProductID        uuid.UUID
+	RelatedProductID uuid.UUID
+	Score            float64
+	CoPurchases      int
+}
@@ -0,0 +1,299 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	computeScores(ctx context.Context) (*ComputeScoresResponse, error)
+	related(ctx context.Context, payload *RelatedProductsRequest) ([]*Recommendation, error)
+	cartRecommendations(ctx context.Context, payload *CartRecommendationsRequest) ([]*Recommendation, error)
+	getOverrides(ctx context.Context, productID uuid.UUID) (*OverridesResponse, error)
+	setOverrides(ctx context.Context, productID uuid.UUID, payload *SetOverridesRequest) (*OverridesResponse, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+const defaultLimit = 8
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	router.Get(
+		"/products/{productID}/related",
+		handlerutils.MakeHandler(h.relatedHandler),
+	)
+	router.Get(
+		"/recommendations/cart",
+		handlerutils.MakeHandler(h.cartRecommendationsHandler),
+	)
+}
+
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/products/{productID}/recommendations",
+		handlerutils.MakeHandler(h.getOverridesHandler),
+	)
+	authenticated.Put(
+		"/products/{productID}/recommendations",
+		handlerutils.MakeHandler(h.setOverridesHandler),
+	)
+	authenticated.Post(
+		"/recommendations/recompute",
+		handlerutils.MakeHandler(h.computeScoresHandler),
+	)
+}
+
+// relatedHandler lists the products frequently bought together with a
+// product, e.g. ?limit=8
+func (h *handler) relatedHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	limit, err := handlerutils.ParseQueryInt(r, "limit", defaultLimit)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	payload := &RelatedProductsRequest{
+		ProductID: productID,
+		Limit:     limit,
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	recommendations, err := h.service.related(ctx, payload)
+	if err != nil {
+		return recommendationError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"related products found",
+		recommendations,
+	)
+}
+
+// cartRecommendationsHandler lists the products to suggest alongside a cart,
+// e.g. ?productId=...&productId=...&limit=8
+func (h *handler) cartRecommendationsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productIDs := []uuid.UUID{}
+	for _, value := range r.URL.Query()["productId"] {
+		productID, err := uuid.Parse(value)
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		productIDs = append(productIDs, productID)
+	}
+
+	limit, err := handlerutils.ParseQueryInt(r, "limit", defaultLimit)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	payload := &CartRecommendationsRequest{
+		ProductIDs: productIDs,
+		Limit:      limit,
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	recommendations, err := h.service.cartRecommendations(ctx, payload)
+	if err != nil {
+		return recommendationError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"recommendations found",
+		recommendations,
+	)
+}
+
+func (h *handler) getOverridesHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	overrides, err := h.service.getOverrides(ctx, productID)
+	if err != nil {
+		return recommendationError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"recommendation overrides found",
+		overrides,
+	)
+}
+
+func (h *handler) setOverridesHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *SetOverridesRequest
+	defer r.Body.Close()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	overrides, err := h.service.setOverrides(ctx, productID, payload)
+	if err != nil {
+		return recommendationError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"recommendation overrides updated",
+		overrides,
+	)
+}
+
+// computeScoresHandler recomputes the co-purchase scores now rather than
+// waiting for the next scheduled run.
+func (h *handler) computeScoresHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(5 * time.Minute),
+	)
+	defer cancel()
+
+	response, err := h.service.computeScores(ctx)
+	if err != nil {
+		return recommendationError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"co-purchase scores computed",
+		response,
+	)
+}
+
+// recommendationError maps the errors of the recommendation service to
+// responses.
+func recommendationError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrProductNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrProductNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidRecommendationOverrides):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidRecommendationOverrides.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
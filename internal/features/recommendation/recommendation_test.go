@@ -0,0 +1,424 @@
+package recommendation
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func newTestRouter(recommendationStore *mockStore, history OrderHistory) *chi.Mux {
+	recommendationHandler := NewHandler(NewService(recommendationStore, history), nil)
+
+	router := chi.NewRouter()
+	router.Get(
+		"/products/{productID}/related",
+		handlerutils.MakeHandler(recommendationHandler.relatedHandler),
+	)
+	router.Get(
+		"/recommendations/cart",
+		handlerutils.MakeHandler(recommendationHandler.cartRecommendationsHandler),
+	)
+	router.Get(
+		"/admin/products/{productID}/recommendations",
+		handlerutils.MakeHandler(recommendationHandler.getOverridesHandler),
+	)
+	router.Put(
+		"/admin/products/{productID}/recommendations",
+		handlerutils.MakeHandler(recommendationHandler.setOverridesHandler),
+	)
+	router.Post(
+		"/admin/recommendations/recompute",
+		handlerutils.MakeHandler(recommendationHandler.computeScoresHandler),
+	)
+
+	return router
+}
+
+// baskets is an OrderHistory of fixed orders.
+type baskets [][]uuid.UUID
+
+func (b baskets) Baskets(ctx context.Context, since time.Time, fn func(productIDs []uuid.UUID) error) error {
+	for _, basket := range b {
+		if err := fn(basket); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type mockProduct struct {
+	status     string
+	categoryID uuid.UUID
+	parentID   uuid.UUID
+	createdAt  time.Time
+}
+
+type mockStore struct {
+	Products    map[uuid.UUID]*mockProduct
+	Overrides   map[uuid.UUID][]*Override
+	CoPurchases []*coPurchase
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{
+		Products:  map[uuid.UUID]*mockProduct{},
+		Overrides: map[uuid.UUID][]*Override{},
+	}
+}
+
+func (m *mockStore) addProduct(status string, categoryID uuid.UUID, parentID uuid.UUID) uuid.UUID {
+	productID := uuid.New()
+	m.Products[productID] = &mockProduct{
+		status:     status,
+		categoryID: categoryID,
+		parentID:   parentID,
+		createdAt:  time.Now().Add(time.Duration(len(m.Products)) * time.Second),
+	}
+
+	return productID
+}
+
+func (m *mockStore) recommendation(productID uuid.UUID, score float64) *Recommendation {
+	return &Recommendation{
+		ProductID: productID,
+		SKU:       productID.String()[:8],
+		Name:      "product " + productID.String()[:8],
+		Price:     1000,
+		Currency:  "USD",
+		Score:     score,
+	}
+}
+
+func (m *mockStore) productStatus(ctx context.Context, productID uuid.UUID) (string, error) {
+	if product, ok := m.Products[productID]; ok {
+		return product.status, nil
+	}
+
+	return "", nil
+}
+
+func (m *mockStore) findOverrides(ctx context.Context, productIDs []uuid.UUID) ([]*Override, error) {
+	overrides := []*Override{}
+	for _, productID := range productIDs {
+		overrides = append(overrides, m.Overrides[productID]...)
+	}
+
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return overrides[i].Position < overrides[j].Position
+	})
+
+	return overrides, nil
+}
+
+func (m *mockStore) replaceOverrides(ctx context.Context, productID uuid.UUID, overrides []*Override) error {
+	for _, override := range overrides {
+		if _, ok := m.Products[override.RelatedProductID]; !ok {
+			return servererrors.ErrProductNotFound
+		}
+	}
+
+	m.Overrides[productID] = overrides
+	return nil
+}
+
+func (m *mockStore) findProducts(ctx context.Context, productIDs []uuid.UUID) ([]*Recommendation, error) {
+	recommendations := []*Recommendation{}
+	for _, productID := range productIDs {
+		if product, ok := m.Products[productID]; ok && product.status == "active" {
+			recommendations = append(recommendations, m.recommendation(productID, 0))
+		}
+	}
+
+	return recommendations, nil
+}
+
+func (m *mockStore) findCoPurchased(ctx context.Context, productIDs []uuid.UUID, exclude []uuid.UUID, limit int64) ([]*Recommendation, error) {
+	scores := map[uuid.UUID]float64{}
+	for _, score := range m.CoPurchases {
+		product, ok := m.Products[score.RelatedProductID]
+		if !slices.Contains(productIDs, score.ProductID) || !ok || product.status != "active" || slices.Contains(exclude, score.RelatedProductID) {
+			continue
+		}
+
+		scores[score.RelatedProductID] += score.Score
+	}
+
+	return m.ranked(scores, limit), nil
+}
+
+func (m *mockStore) findSameCategory(ctx context.Context, productIDs []uuid.UUID, exclude []uuid.UUID, limit int64) ([]*Recommendation, error) {
+	scores := map[uuid.UUID]float64{}
+	for _, productID := range productIDs {
+		source, ok := m.Products[productID]
+		if !ok {
+			continue
+		}
+
+		for relatedID, product := range m.Products {
+			if product.status != "active" || slices.Contains(exclude, relatedID) {
+				continue
+			}
+
+			switch {
+			case product.categoryID == source.categoryID:
+				scores[relatedID] = 2
+			case product.parentID == source.parentID && scores[relatedID] < 1:
+				scores[relatedID] = 1
+			}
+		}
+	}
+
+	return m.ranked(scores, limit), nil
+}
+
+func (m *mockStore) ranked(scores map[uuid.UUID]float64, limit int64) []*Recommendation {
+	recommendations := []*Recommendation{}
+	for productID, score := range scores {
+		recommendations = append(recommendations, m.recommendation(productID, score))
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return m.Products[recommendations[i].ProductID].createdAt.After(m.Products[recommendations[j].ProductID].createdAt)
+	})
+
+	return recommendations[:min(int64(len(recommendations)), limit)]
+}
+
+func (m *mockStore) replaceCoPurchases(ctx context.Context, scores []*coPurchase) error {
+	m.CoPurchases = scores
+	return nil
+}
+
+func productIDs(recommendations []*Recommendation) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		ids = append(ids, recommendation.ProductID)
+	}
+
+	return ids
+}
+
+func TestScoreBaskets(t *testing.T) {
+	phone, phoneCase, charger, cable := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	bulk := []uuid.UUID{}
+	for range maxBasketSize + 1 {
+		bulk = append(bulk, uuid.New())
+	}
+	bulk[0], bulk[1] = phone, cable
+
+	history := baskets{
+		{phone, phoneCase, charger},
+		{phone, phoneCase},
+		{phone, phoneCase, phoneCase},
+		{phone, charger},
+		{charger, cable},
+		bulk,
+		bulk,
+	}
+
+	scores, err := scoreBaskets(context.Background(), history, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byPair := map[[2]uuid.UUID]*coPurchase{}
+	for _, score := range scores {
+		byPair[[2]uuid.UUID{score.ProductID, score.RelatedProductID}] = score
+	}
+
+	caseScore, ok := byPair[[2]uuid.UUID{phone, phoneCase}]
+	if !ok || caseScore.CoPurchases != 3 {
+		t.Fatalf("expected phone and case bought together 3 times, got %+v", caseScore)
+	}
+
+	if back := byPair[[2]uuid.UUID{phoneCase, phone}]; back == nil || back.Score != caseScore.Score {
+		t.Errorf("expected the score to be symmetric, got %+v", back)
+	}
+
+	chargerScore := byPair[[2]uuid.UUID{phone, charger}]
+	if chargerScore == nil || chargerScore.Score >= caseScore.Score {
+		t.Errorf("expected the case to score above the charger, got %+v and %+v", caseScore, chargerScore)
+	}
+
+	if _, ok := byPair[[2]uuid.UUID{charger, cable}]; ok {
+		t.Errorf("expected a pair bought together once to be ignored")
+	}
+
+	if _, ok := byPair[[2]uuid.UUID{phone, cable}]; ok {
+		t.Errorf("expected bulk orders to be skipped")
+	}
+}
+
+func TestRelated(t *testing.T) {
+	bulbs, lamps, lighting := uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("it falls back to the category without orders", func(t *testing.T) {
+		store := newMockStore()
+		router := newTestRouter(store, baskets{})
+		lamp := store.addProduct("active", lamps, lighting)
+		otherLamp := store.addProduct("active", lamps, lighting)
+		bulb := store.addProduct("active", bulbs, lighting)
+		store.addProduct("draft", lamps, lighting)
+		store.addProduct("active", uuid.New(), uuid.New())
+
+		var recommendations []*Recommendation
+		status := testutil.Serve(t, router, http.MethodGet, "/products/"+lamp.String()+"/related", nil, nil, &recommendations)
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+		}
+
+		if got := productIDs(recommendations); !slices.Equal(got, []uuid.UUID{otherLamp, bulb}) {
+			t.Fatalf("expected the other lamp then the bulb, got %v", got)
+		}
+
+		if recommendations[0].Source != SourceCategory {
+			t.Errorf("expected source %q, got %q", SourceCategory, recommendations[0].Source)
+		}
+	})
+
+	t.Run("it ranks co-purchases then pins and excludes overrides", func(t *testing.T) {
+		store := newMockStore()
+		lamp := store.addProduct("active", lamps, lighting)
+		bulb := store.addProduct("active", bulbs, lighting)
+		shade := store.addProduct("active", uuid.New(), uuid.New())
+		otherLamp := store.addProduct("active", lamps, lighting)
+		featured := store.addProduct("active", uuid.New(), uuid.New())
+
+		history := baskets{
+			{lamp, bulb}, {lamp, bulb}, {lamp, bulb},
+			{lamp, shade}, {lamp, shade}, {shade}, {shade},
+		}
+
+		router := newTestRouter(store, history)
+
+		var computed ComputeScoresResponse
+		if status := testutil.Serve(t, router, http.MethodPost, "/admin/recommendations/recompute", nil, nil, &computed); status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+		}
+
+		if computed.Pairs != 4 || computed.Products != 3 {
+			t.Errorf("expected 4 pairs over 3 products, got %+v", computed)
+		}
+
+		var recommendations []*Recommendation
+		testutil.Serve(t, router, http.MethodGet, "/products/"+lamp.String()+"/related", nil, nil, &recommendations)
+		if got := productIDs(recommendations); !slices.Equal(got, []uuid.UUID{bulb, shade, otherLamp}) {
+			t.Fatalf("expected co-purchases then the category, got %v", got)
+		}
+
+		overrides := &SetOverridesRequest{Pins: []uuid.UUID{featured}, Excludes: []uuid.UUID{bulb}}
+		if status := testutil.Serve(t, router, http.MethodPut, "/admin/products/"+lamp.String()+"/recommendations", nil, overrides, nil); status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+		}
+
+		testutil.Serve(t, router, http.MethodGet, "/products/"+lamp.String()+"/related?limit=2", nil, nil, &recommendations)
+		if got := productIDs(recommendations); !slices.Equal(got, []uuid.UUID{featured, shade}) {
+			t.Fatalf("expected the pin then the best co-purchase left, got %v", got)
+		}
+
+		if recommendations[0].Source != SourcePinned || recommendations[1].Source != SourceCoPurchase {
+			t.Errorf("expected sources pinned and co_purchase, got %q and %q", recommendations[0].Source, recommendations[1].Source)
+		}
+	})
+
+	t.Run("it hides drafts and validates the limit", func(t *testing.T) {
+		store := newMockStore()
+		router := newTestRouter(store, baskets{})
+		draft := store.addProduct("draft", lamps, lighting)
+		lamp := store.addProduct("active", lamps, lighting)
+
+		testCases := []struct {
+			name   string
+			path   string
+			status int
+		}{
+			{"draft", "/products/" + draft.String() + "/related", http.StatusNotFound},
+			{"unknown", "/products/" + uuid.NewString() + "/related", http.StatusNotFound},
+			{"invalid id", "/products/lamp/related", http.StatusBadRequest},
+			{"limit too big", "/products/" + lamp.String() + "/related?limit=100", http.StatusUnprocessableEntity},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if status := testutil.Serve(t, router, http.MethodGet, tc.path, nil, nil, nil); status != tc.status {
+					t.Errorf("expected status %d, got %d", tc.status, status)
+				}
+			})
+		}
+	})
+}
+
+func TestCartRecommendations(t *testing.T) {
+	lamps, lighting := uuid.New(), uuid.New()
+	store := newMockStore()
+	router := newTestRouter(store, baskets{})
+	lamp := store.addProduct("active", lamps, lighting)
+	otherLamp := store.addProduct("active", lamps, lighting)
+	thirdLamp := store.addProduct("active", lamps, lighting)
+	store.Overrides[otherLamp] = []*Override{{ProductID: otherLamp, RelatedProductID: thirdLamp, Kind: OverrideExclude}}
+
+	var recommendations []*Recommendation
+	path := "/recommendations/cart?productId=" + lamp.String() + "&productId=" + otherLamp.String()
+	if status := testutil.Serve(t, router, http.MethodGet, path, nil, nil, &recommendations); status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if len(recommendations) != 0 {
+		t.Errorf("expected no recommendation, the cart holds one lamp and excludes the other, got %v", productIDs(recommendations))
+	}
+
+	if status := testutil.Serve(t, router, http.MethodGet, "/recommendations/cart", nil, nil, nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d for an empty cart, got %d", http.StatusUnprocessableEntity, status)
+	}
+}
+
+func TestSetOverrides(t *testing.T) {
+	store := newMockStore()
+	router := newTestRouter(store, baskets{})
+	lamp := store.addProduct("active", uuid.New(), uuid.New())
+	bulb := store.addProduct("active", uuid.New(), uuid.New())
+	shade := store.addProduct("active", uuid.New(), uuid.New())
+
+	testCases := []struct {
+		name    string
+		product uuid.UUID
+		payload *SetOverridesRequest
+		status  int
+	}{
+		{"pins itself", lamp, &SetOverridesRequest{Pins: []uuid.UUID{lamp}}, http.StatusUnprocessableEntity},
+		{"pins and excludes", lamp, &SetOverridesRequest{Pins: []uuid.UUID{bulb}, Excludes: []uuid.UUID{bulb}}, http.StatusUnprocessableEntity},
+		{"duplicate pins", lamp, &SetOverridesRequest{Pins: []uuid.UUID{bulb, bulb}}, http.StatusUnprocessableEntity},
+		{"unknown related product", lamp, &SetOverridesRequest{Pins: []uuid.UUID{uuid.New()}}, http.StatusNotFound},
+		{"unknown product", uuid.New(), &SetOverridesRequest{}, http.StatusNotFound},
+		{"valid", lamp, &SetOverridesRequest{Pins: []uuid.UUID{shade, bulb}}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := "/admin/products/" + tc.product.String() + "/recommendations"
+			if status := testutil.Serve(t, router, http.MethodPut, path, nil, tc.payload, nil); status != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, status)
+			}
+		})
+	}
+
+	var overrides OverridesResponse
+	testutil.Serve(t, router, http.MethodGet, "/admin/products/"+lamp.String()+"/recommendations", nil, nil, &overrides)
+	if !slices.Equal(overrides.Pins, []uuid.UUID{shade, bulb}) || len(overrides.Excludes) != 0 {
+		t.Errorf("expected the pins in order, got %+v", overrides)
+	}
+}
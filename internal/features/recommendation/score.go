@@ -0,0 +1,80 @@
+package recommendation
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxBasketSize skips bulk orders, whose products say little about
+	// each other and whose pairs grow with the square of their size
+	maxBasketSize = 50
+	// minCoPurchases ignores pairs bought together by chance
+	minCoPurchases = 2
+	// maxRelated is how many related products are kept per product
+	maxRelated = 20
+)
+
+// scoreBaskets computes the item to item co-purchase scores of the baskets
+// history streams. A pair scores the number of baskets holding both products
+// over the geometric mean of the baskets holding each, the cosine similarity
+// of their purchase vectors, so best sellers do not top every list.
+func scoreBaskets(ctx context.Context, history OrderHistory, since time.Time) ([]*coPurchase, error) {
+	purchases := map[uuid.UUID]int{}
+	pairs := map[[2]uuid.UUID]int{}
+
+	err := history.Baskets(ctx, since, func(productIDs []uuid.UUID) error {
+		basket := slices.Clone(productIDs)
+		slices.SortFunc(basket, func(a, b uuid.UUID) int {
+			return bytes.Compare(a[:], b[:])
+		})
+		basket = slices.Compact(basket)
+
+		if len(basket) > maxBasketSize {
+			return nil
+		}
+
+		for i, productID := range basket {
+			purchases[productID]++
+			for _, relatedID := range basket[i+1:] {
+				pairs[[2]uuid.UUID{productID, relatedID}]++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := map[uuid.UUID][]*coPurchase{}
+	for pair, count := range pairs {
+		if count < minCoPurchases {
+			continue
+		}
+
+		score := float64(count) / math.Sqrt(float64(purchases[pair[0]])*float64(purchases[pair[1]]))
+		byProduct[pair[0]] = append(byProduct[pair[0]], &coPurchase{pair[0], pair[1], score, count})
+		byProduct[pair[1]] = append(byProduct[pair[1]], &coPurchase{pair[1], pair[0], score, count})
+	}
+
+	scores := []*coPurchase{}
+	for _, related := range byProduct {
+		sort.Slice(related, func(i, j int) bool {
+			if related[i].Score != related[j].Score {
+				return related[i].Score > related[j].Score
+			}
+			return bytes.Compare(related[i].RelatedProductID[:], related[j].RelatedProductID[:]) < 0
+		})
+
+		scores = append(scores, related[:min(len(related), maxRelated)]...)
+	}
+
+	return scores, nil
+}
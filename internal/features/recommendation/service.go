@@ -0,0 +1,267 @@
+package recommendation
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type recommendationStorer interface {
+	productStatus(ctx context.Context, productID uuid.UUID) (string, error)
+	findOverrides(ctx context.Context, productIDs []uuid.UUID) ([]*Override, error)
+	replaceOverrides(ctx context.Context, productID uuid.UUID, overrides []*Override) error
+	findProducts(ctx context.Context, productIDs []uuid.UUID) ([]*Recommendation, error)
+	findCoPurchased(ctx context.Context, productIDs []uuid.UUID, exclude []uuid.UUID, limit int64) ([]*Recommendation, error)
+	findSameCategory(ctx context.Context, productIDs []uuid.UUID, exclude []uuid.UUID, limit int64) ([]*Recommendation, error)
+	replaceCoPurchases(ctx context.Context, scores []*coPurchase) error
+}
+
+// OrderHistory streams the baskets of the orders placed since a time, the
+// distinct products of each order, to fn.
+type OrderHistory interface {
+	Baskets(ctx context.Context, since time.Time, fn func(productIDs []uuid.UUID) error) error
+}
+
+// scoreLookback is how far back orders count towards co-purchase scores, so
+// they follow what sells together now.
+const scoreLookback = 365 * 24 * time.Hour
+
+type service struct {
+	recommendationStore recommendationStorer
+	history             OrderHistory
+}
+
+func NewService(recommendationStore recommendationStorer, history OrderHistory) *service {
+	return &service{
+		recommendationStore: recommendationStore,
+		history:             history,
+	}
+}
+
+// RunScoreJob recomputes the co-purchase scores on start and every interval
+// after. It blocks until ctx is done.
+func (s *service) RunScoreJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.computeScores(ctx); err != nil {
+			log.Println(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// computeScores replaces the co-purchase scores with those of the orders
+// placed within the lookback.
+func (s *service) computeScores(ctx context.Context) (*ComputeScoresResponse, error) {
+	computedAt := time.Now().UTC()
+
+	scores, err := scoreBaskets(ctx, s.history, computedAt.Add(-scoreLookback))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.recommendationStore.replaceCoPurchases(ctx, scores); err != nil {
+		return nil, err
+	}
+
+	products := map[uuid.UUID]bool{}
+	for _, score := range scores {
+		products[score.ProductID] = true
+	}
+
+	return &ComputeScoresResponse{
+		Products:   len(products),
+		Pairs:      len(scores),
+		ComputedAt: computedAt,
+	}, nil
+}
+
+// related recommends the products frequently bought together with a
+// product.
+func (s *service) related(ctx context.Context, payload *RelatedProductsRequest) ([]*Recommendation, error) {
+	status, err := s.recommendationStore.productStatus(ctx, payload.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != product.StatusActive {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	return s.recommend(ctx, []uuid.UUID{payload.ProductID}, payload.Limit)
+}
+
+// cartRecommendations recommends products to go with a whole cart, never
+// one already in it.
+func (s *service) cartRecommendations(ctx context.Context, payload *CartRecommendationsRequest) ([]*Recommendation, error) {
+	return s.recommend(ctx, payload.ProductIDs, payload.Limit)
+}
+
+// recommend fills up to limit recommendations for productIDs: the products
+// pinned to them first, in their order, then the most co-purchased ones and
+// then products of the same or nearby categories. Products excluded from
+// any of productIDs are left out.
+func (s *service) recommend(ctx context.Context, productIDs []uuid.UUID, limit int64) ([]*Recommendation, error) {
+	overrides, err := s.recommendationStore.findOverrides(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := slices.Clone(productIDs)
+	pins := []uuid.UUID{}
+	for _, override := range overrides {
+		switch override.Kind {
+		case OverrideExclude:
+			exclude = append(exclude, override.RelatedProductID)
+		case OverridePin:
+			pins = append(pins, override.RelatedProductID)
+		}
+	}
+
+	recommendations := []*Recommendation{}
+	if len(pins) > 0 {
+		pinned, err := s.recommendationStore.findProducts(ctx, pins)
+		if err != nil {
+			return nil, err
+		}
+
+		byID := make(map[uuid.UUID]*Recommendation, len(pinned))
+		for _, recommendation := range pinned {
+			byID[recommendation.ProductID] = recommendation
+		}
+
+		for _, productID := range pins {
+			recommendation, ok := byID[productID]
+			if !ok || slices.Contains(exclude, productID) || int64(len(recommendations)) == limit {
+				continue
+			}
+
+			recommendation.Source = SourcePinned
+			recommendations = append(recommendations, recommendation)
+			exclude = append(exclude, productID)
+		}
+	}
+
+	sources := []struct {
+		source string
+		find   func(ctx context.Context, productIDs []uuid.UUID, exclude []uuid.UUID, limit int64) ([]*Recommendation, error)
+	}{
+		{SourceCoPurchase, s.recommendationStore.findCoPurchased},
+		{SourceCategory, s.recommendationStore.findSameCategory},
+	}
+
+	for _, source := range sources {
+		remaining := limit - int64(len(recommendations))
+		if remaining <= 0 {
+			break
+		}
+
+		found, err := source.find(ctx, productIDs, exclude, remaining)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, recommendation := range found {
+			recommendation.Source = source.source
+			recommendations = append(recommendations, recommendation)
+			exclude = append(exclude, recommendation.ProductID)
+		}
+	}
+
+	return recommendations, nil
+}
+
+func (s *service) getOverrides(ctx context.Context, productID uuid.UUID) (*OverridesResponse, error) {
+	if err := s.findProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	overrides, err := s.recommendationStore.findOverrides(ctx, []uuid.UUID{productID})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &OverridesResponse{
+		Pins:     []uuid.UUID{},
+		Excludes: []uuid.UUID{},
+	}
+	for _, override := range overrides {
+		switch override.Kind {
+		case OverridePin:
+			response.Pins = append(response.Pins, override.RelatedProductID)
+		case OverrideExclude:
+			response.Excludes = append(response.Excludes, override.RelatedProductID)
+		}
+	}
+
+	return response, nil
+}
+
+// setOverrides replaces the pinned and excluded products of a product.
+func (s *service) setOverrides(ctx context.Context, productID uuid.UUID, payload *SetOverridesRequest) (*OverridesResponse, error) {
+	if err := s.findProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(payload.Pins, productID) || slices.Contains(payload.Excludes, productID) {
+		return nil, servererrors.ErrInvalidRecommendationOverrides
+	}
+
+	for _, pin := range payload.Pins {
+		if slices.Contains(payload.Excludes, pin) {
+			return nil, servererrors.ErrInvalidRecommendationOverrides
+		}
+	}
+
+	overrides := make([]*Override, 0, len(payload.Pins)+len(payload.Excludes))
+	for position, pin := range payload.Pins {
+		overrides = append(overrides, &Override{
+			ProductID:        productID,
+			RelatedProductID: pin,
+			Kind:             OverridePin,
+			Position:         position,
+		})
+	}
+
+	for _, exclude := range payload.Excludes {
+		overrides = append(overrides, &Override{
+			ProductID:        productID,
+			RelatedProductID: exclude,
+			Kind:             OverrideExclude,
+		})
+	}
+
+	if err := s.recommendationStore.replaceOverrides(ctx, productID, overrides); err != nil {
+		return nil, err
+	}
+
+	return &OverridesResponse{
+		Pins:     append([]uuid.UUID{}, payload.Pins...),
+		Excludes: append([]uuid.UUID{}, payload.Excludes...),
+	}, nil
+}
+
+func (s *service) findProduct(ctx context.Context, productID uuid.UUID) error {
+	status, err := s.recommendationStore.productStatus(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if status == "" {
+		return servererrors.ErrProductNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package recommendation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	recommendationFields = "p.product_id, p.sku, p.name, p.brand, p.price, p.currency"
+
+	// foreignKeyViolation is the postgres error code raised when a
+	// referenced product does not exist
+	foreignKeyViolation = "23503"
+
+	// scoreBatchSize is how many scores are inserted per statement
+	scoreBatchSize = 5000
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+func (s *store) productStatus(ctx context.Context, productID uuid.UUID) (string, error) {
+	var status string
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT status FROM products WHERE product_id = $1",
+		productID,
+	).Scan(&status)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf(
+			"failed to find product status in recommendation store: %w",
+			err,
+		)
+	}
+
+	return status, nil
+}
+
+// findOverrides returns the overrides of productIDs, pins in position order.
+func (s *store) findOverrides(ctx context.Context, productIDs []uuid.UUID) ([]*Override, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT product_id, related_product_id, kind, position, created_at FROM recommendation_overrides WHERE product_id = ANY($1) ORDER BY position, product_id, related_product_id",
+		pq.Array(productIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find recommendation overrides in recommendation store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	overrides := []*Override{}
+	for rows.Next() {
+		override := new(Override)
+		err = rows.Scan(
+			&override.ProductID,
+			&override.RelatedProductID,
+			&override.Kind,
+			&override.Position,
+			&override.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into override in recommendation store: %w",
+				err,
+			)
+		}
+
+		overrides = append(overrides, override)
+	}
+
+	return overrides, rows.Err()
+}
+
+func (s *store) replaceOverrides(ctx context.Context, productID uuid.UUID, overrides []*Override) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recommendation_overrides WHERE product_id = $1", productID); err != nil {
+			return fmt.Errorf("failed to delete recommendation overrides in recommendation store: %w", err)
+		}
+
+		for _, override := range overrides {
+			_, err := tx.ExecContext(
+				ctx,
+				"INSERT INTO recommendation_overrides(product_id, related_product_id, kind, position) VALUES($1, $2, $3, $4)",
+				productID,
+				override.RelatedProductID,
+				override.Kind,
+				override.Position,
+			)
+			if err != nil {
+				if isPQError(err, foreignKeyViolation) {
+					return servererrors.ErrProductNotFound
+				}
+
+				return fmt.Errorf("failed to insert recommendation override in recommendation store: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// findProducts returns the active products among productIDs, in no
+// particular order.
+func (s *store) findProducts(ctx context.Context, productIDs []uuid.UUID) ([]*Recommendation, error) {
+	recommendations, err := s.getRecommendationsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s, 0 FROM products p WHERE p.product_id = ANY($1) AND p.status = 'active'", recommendationFields),
+		pq.Array(productIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find products in recommendation store: %w",
+			err,
+		)
+	}
+
+	return recommendations, nil
+}
+
+// findCoPurchased returns the active products most often bought with
+// productIDs, their scores summed over productIDs.
+func (s *store) findCoPurchased(ctx context.Context, productIDs []uuid.UUID, exclude []uuid.UUID, limit int64) ([]*Recommendation, error) {
+	recommendations, err := s.getRecommendationsWithContext(
+		ctx,
+		fmt.Sprintf(
+			`SELECT %s, SUM(cp.score) AS score
+			FROM product_co_purchases cp
+			JOIN products p ON p.product_id = cp.related_product_id
+			WHERE cp.product_id = ANY($1) AND p.status = 'active' AND NOT p.product_id = ANY($2)
+			GROUP BY p.product_id
+			ORDER BY score DESC, p.product_id
+			LIMIT $3`,
+			recommendationFields,
+		),
+		pq.Array(productIDs),
+		pq.Array(exclude),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find co-purchased products in recommendation store: %w",
+			err,
+		)
+	}
+
+	return recommendations, nil
+}
+
+// findSameCategory returns active products from the categories of
+// productIDs, scoring 2, or else from the subtree of their parent
+// categories, scoring 1. Newer products come first among equals, they are
+// the ones co-purchases know least about.
+func (s *store) findSameCategory(ctx context.Context, productIDs []uuid.UUID, exclude []uuid.UUID, limit int64) ([]*Recommendation, error) {
+	recommendations, err := s.getRecommendationsWithContext(
+		ctx,
+		fmt.Sprintf(
+			`WITH own AS (
+				SELECT DISTINCT pc.category_id FROM product_categories pc WHERE pc.product_id = ANY($1)
+			), nearby AS (
+				SELECT cc.descendant_id AS category_id FROM own
+				JOIN categories c ON c.category_id = own.category_id
+				JOIN category_closure cc ON cc.ancestor_id = COALESCE(c.parent_id, c.category_id)
+			)
+			SELECT %s, MAX(CASE WHEN pc.category_id IN (SELECT category_id FROM own) THEN 2 ELSE 1 END)::float8 AS score
+			FROM products p
+			JOIN product_categories pc ON pc.product_id = p.product_id
+			WHERE pc.category_id IN (SELECT category_id FROM nearby) AND p.status = 'active' AND NOT p.product_id = ANY($2)
+			GROUP BY p.product_id
+			ORDER BY score DESC, MAX(p.created_at) DESC, p.product_id
+			LIMIT $3`,
+			recommendationFields,
+		),
+		pq.Array(productIDs),
+		pq.Array(exclude),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find products in the same category in recommendation store: %w",
+			err,
+		)
+	}
+
+	return recommendations, nil
+}
+
+// replaceCoPurchases swaps every co-purchase score for scores at once, so
+// readers see either the previous batch or the new one. Scores of products
+// deleted since the orders were read are skipped.
+func (s *store) replaceCoPurchases(ctx context.Context, scores []*coPurchase) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM product_co_purchases"); err != nil {
+			return fmt.Errorf("failed to delete co-purchase scores in recommendation store: %w", err)
+		}
+
+		for start := 0; start < len(scores); start += scoreBatchSize {
+			batch := scores[start:min(start+scoreBatchSize, len(scores))]
+
+			productIDs := make([]uuid.UUID, 0, len(batch))
+			relatedIDs := make([]uuid.UUID, 0, len(batch))
+			values := make([]float64, 0, len(batch))
+			counts := make([]int64, 0, len(batch))
+			for _, score := range batch {
+				productIDs = append(productIDs, score.ProductID)
+				relatedIDs = append(relatedIDs, score.RelatedProductID)
+				values = append(values, score.Score)
+				counts = append(counts, int64(score.CoPurchases))
+			}
+
+			_, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO product_co_purchases(product_id, related_product_id, score, co_purchases)
+				SELECT s.product_id, s.related_product_id, s.score, s.co_purchases
+				FROM UNNEST($1::uuid[], $2::uuid[], $3::float8[], $4::int[]) AS s(product_id, related_product_id, score, co_purchases)
+				WHERE EXISTS(SELECT 1 FROM products WHERE product_id = s.product_id)
+				AND EXISTS(SELECT 1 FROM products WHERE product_id = s.related_product_id)`,
+				pq.Array(productIDs),
+				pq.Array(relatedIDs),
+				pq.Array(values),
+				pq.Array(counts),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert co-purchase scores in recommendation store: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *store) getRecommendationsWithContext(ctx context.Context, query string, args ...any) ([]*Recommendation, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in recommendation store getRecommendationsWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	recommendations := []*Recommendation{}
+	for rows.Next() {
+		recommendation := new(Recommendation)
+		err = rows.Scan(
+			&recommendation.ProductID,
+			&recommendation.SKU,
+			&recommendation.Name,
+			&recommendation.Brand,
+			&recommendation.Price,
+			&recommendation.Currency,
+			&recommendation.Score,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into recommendation in recommendation store: %w",
+				err,
+			)
+		}
+
+		recommendations = append(recommendations, recommendation)
+	}
+
+	return recommendations, rows.Err()
+}
+
+// withTx runs fn in a transaction, committing when it returns nil.
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in recommendation store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in recommendation store: %w", err)
+	}
+
+	return nil
+}
+
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
@@ -0,0 +1,55 @@
+package review
+
+import "github.com/google/uuid"
+
+// Requests
+
+type CreateReviewRequest struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Title  string `json:"title" validate:"max=150"`
+	Body   string `json:"body" validate:"max=5000"`
+}
+
+// UpdateReviewRequest only changes the fields that are set, an edited review
+// goes back to the moderation queue.
+type UpdateReviewRequest struct {
+	Rating *int    `json:"rating" validate:"omitempty,min=1,max=5"`
+	Title  *string `json:"title" validate:"omitempty,max=150"`
+	Body   *string `json:"body" validate:"omitempty,max=5000"`
+}
+
+type VoteRequest struct {
+	Helpful bool `json:"helpful"`
+}
+
+type ModerateReviewRequest struct {
+	Note string `json:"note" validate:"max=500"`
+}
+
+// ListReviewsRequest is read from the query string, e.g.
+// ?rating=5&sort=-helpful&page=2
+type ListReviewsRequest struct {
+	ProductID uuid.UUID
+	Rating    *int   `validate:"omitempty,min=1,max=5"`
+	Sort      string `validate:"omitempty,oneof=created_at -created_at rating -rating helpful -helpful"`
+	Page      int64  `validate:"min=1"`
+	PageSize  int64  `validate:"min=1,max=100"`
+}
+
+// ModerationQueueRequest lists reviews in Status, or every pending and
+// flagged review when it is empty.
+type ModerationQueueRequest struct {
+	Status   string `validate:"omitempty,oneof=pending approved rejected flagged"`
+	Page     int64  `validate:"min=1"`
+	PageSize int64  `validate:"min=1,max=100"`
+}
+
+// Responses
+
+type ListReviewsResponse struct {
+	Summary    *RatingSummary `json:"summary,omitempty"`
+	Reviews    []*Review      `json:"reviews"`
+	Page       int64          `json:"page"`
+	PageSize   int64          `json:"pageSize"`
+	TotalCount int64          `json:"totalCount"`
+}
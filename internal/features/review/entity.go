@@ -0,0 +1,72 @@
+package review
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Review statuses. New and edited reviews wait in the moderation queue as
+// pending, or flagged when the content filter objects, and only approved
+// reviews are shown and counted in the rating summary.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusFlagged  = "flagged"
+)
+
+type Review struct {
+	ReviewID         uuid.UUID `json:"review_id"`
+	ProductID        uuid.UUID `json:"product_id"`
+	UserID           uuid.UUID `json:"user_id"`
+	Rating           int       `json:"rating"`
+	Title            string    `json:"title"`
+	Body             string    `json:"body"`
+	Status           string    `json:"status"`
+	ModerationNote   string    `json:"moderation_note,omitempty"`
+	VerifiedPurchase bool      `json:"verified_purchase"`
+	HelpfulCount     int       `json:"helpful_count"`
+	UnhelpfulCount   int       `json:"unhelpful_count"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// RatingSummary aggregates the approved reviews of a product, Distribution
+// counts the reviews per star rating.
+type RatingSummary struct {
+	ProductID    uuid.UUID   `json:"product_id"`
+	ReviewCount  int         `json:"review_count"`
+	Average      float64     `json:"average"`
+	Distribution map[int]int `json:"distribution"`
+}
+
+// newRatingSummary builds a summary from the sum of the ratings and the
+// number of reviews per star, counts[0] being one star reviews.
+func newRatingSummary(productID uuid.UUID, sum int, counts []int) *RatingSummary {
+	summary := &RatingSummary{
+		ProductID:    productID,
+		Distribution: map[int]int{},
+	}
+
+	for i, count := range counts {
+		summary.Distribution[i+1] = count
+		summary.ReviewCount += count
+	}
+
+	if summary.ReviewCount > 0 {
+		summary.Average = math.Round(float64(sum)/float64(summary.ReviewCount)*100) / 100
+	}
+
+	return summary
+}
+
+type reviewFilter struct {
+	ProductID *uuid.UUID
+	Statuses  []string
+	Rating    *int
+	Sort      string
+	Limit     int64
+	Offset    int64
+}
@@ -0,0 +1,65 @@
+package review
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ContentFilter is the hook reviews pass through before they reach the
+// moderation queue. It returns why a text is objectionable, a review with any
+// reason is flagged for a closer look rather than rejected outright.
+type ContentFilter interface {
+	Check(text string) []string
+}
+
+var (
+	linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+\.(com|net|org|info|biz|io|ru|xyz|top)\b`)
+	wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+)
+
+// defaultBlockedWords is deliberately short, deployments with stricter needs
+// plug in their own ContentFilter.
+var defaultBlockedWords = []string{
+	"asshole",
+	"bastard",
+	"bitch",
+	"cunt",
+	"fuck",
+	"fucking",
+	"shit",
+}
+
+type wordFilter struct {
+	blocked map[string]bool
+}
+
+// NewWordFilter returns a ContentFilter objecting to links and to any of
+// blocked as a whole word, or to a short default list when blocked is empty.
+func NewWordFilter(blocked ...string) ContentFilter {
+	if len(blocked) == 0 {
+		blocked = defaultBlockedWords
+	}
+
+	f := &wordFilter{blocked: map[string]bool{}}
+	for _, word := range blocked {
+		f.blocked[strings.ToLower(word)] = true
+	}
+
+	return f
+}
+
+func (f *wordFilter) Check(text string) []string {
+	reasons := []string{}
+	if linkPattern.MatchString(text) {
+		reasons = append(reasons, "contains a link")
+	}
+
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		if f.blocked[strings.ToLower(word)] {
+			reasons = append(reasons, "contains profanity")
+			break
+		}
+	}
+
+	return reasons
+}
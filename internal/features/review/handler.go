@@ -0,0 +1,579 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	createReview(ctx context.Context, userID uuid.UUID, productID uuid.UUID, payload *CreateReviewRequest) (*Review, error)
+	updateReview(ctx context.Context, userID uuid.UUID, reviewID uuid.UUID, payload *UpdateReviewRequest) (*Review, error)
+	deleteReview(ctx context.Context, userID uuid.UUID, reviewID uuid.UUID) error
+	voteReview(ctx context.Context, userID uuid.UUID, reviewID uuid.UUID, helpful *bool) (*Review, error)
+	listProductReviews(ctx context.Context, payload *ListReviewsRequest) (*ListReviewsResponse, error)
+	getRatingSummary(ctx context.Context, productID uuid.UUID) (*RatingSummary, error)
+	moderationQueue(ctx context.Context, payload *ModerationQueueRequest) (*ListReviewsResponse, error)
+	moderateReview(ctx context.Context, reviewID uuid.UUID, status string, payload *ModerateReviewRequest) (*Review, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+const defaultPageSize = 20
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *chi.Mux) {
+	router.Get(
+		"/products/{productID}/reviews",
+		handlerutils.MakeHandler(h.listProductReviewsHandler),
+	)
+	router.Get(
+		"/products/{productID}/rating",
+		handlerutils.MakeHandler(h.getRatingSummaryHandler),
+	)
+
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeUser))
+	authenticated.Post(
+		"/products/{productID}/reviews",
+		handlerutils.MakeHandler(h.createReviewHandler),
+	)
+	authenticated.Patch(
+		"/reviews/{reviewID}",
+		handlerutils.MakeHandler(h.updateReviewHandler),
+	)
+	authenticated.Delete(
+		"/reviews/{reviewID}",
+		handlerutils.MakeHandler(h.deleteReviewHandler),
+	)
+	authenticated.Put(
+		"/reviews/{reviewID}/vote",
+		handlerutils.MakeHandler(h.voteReviewHandler),
+	)
+	authenticated.Delete(
+		"/reviews/{reviewID}/vote",
+		handlerutils.MakeHandler(h.removeVoteHandler),
+	)
+}
+
+// RegisterAdminRoutes registers the moderation routes relative to the admin
+// route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/reviews",
+		handlerutils.MakeHandler(h.moderationQueueHandler),
+	)
+	authenticated.Post(
+		"/reviews/{reviewID}/approve",
+		handlerutils.MakeHandler(h.moderateReviewHandler(StatusApproved)),
+	)
+	authenticated.Post(
+		"/reviews/{reviewID}/reject",
+		handlerutils.MakeHandler(h.moderateReviewHandler(StatusRejected)),
+	)
+	authenticated.Post(
+		"/reviews/{reviewID}/flag",
+		handlerutils.MakeHandler(h.moderateReviewHandler(StatusFlagged)),
+	)
+}
+
+func (h *handler) createReviewHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *CreateReviewRequest
+	defer r.Body.Close()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	review, err := h.service.createReview(ctx, userID, productID, payload)
+	if err != nil {
+		return reviewError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"review submitted",
+		review,
+	)
+}
+
+func (h *handler) updateReviewHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *UpdateReviewRequest
+	defer r.Body.Close()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	reviewID, err := uuid.Parse(chi.URLParam(r, "reviewID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	review, err := h.service.updateReview(ctx, userID, reviewID, payload)
+	if err != nil {
+		return reviewError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"review updated",
+		review,
+	)
+}
+
+func (h *handler) deleteReviewHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	reviewID, err := uuid.Parse(chi.URLParam(r, "reviewID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = h.service.deleteReview(ctx, userID, reviewID); err != nil {
+		return reviewError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"review deleted",
+		nil,
+	)
+}
+
+func (h *handler) voteReviewHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *VoteRequest
+	defer r.Body.Close()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	reviewID, err := uuid.Parse(chi.URLParam(r, "reviewID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	review, err := h.service.voteReview(ctx, userID, reviewID, &payload.Helpful)
+	if err != nil {
+		return reviewError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"vote recorded",
+		review,
+	)
+}
+
+func (h *handler) removeVoteHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	reviewID, err := uuid.Parse(chi.URLParam(r, "reviewID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	review, err := h.service.voteReview(ctx, userID, reviewID, nil)
+	if err != nil {
+		return reviewError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"vote removed",
+		review,
+	)
+}
+
+// listProductReviewsHandler lists the approved reviews of a product, e.g.
+// ?rating=5&sort=-helpful&page=2&pageSize=20
+func (h *handler) listProductReviewsHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	page, err := handlerutils.ParseQueryInt(r, "page", 1)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	pageSize, err := handlerutils.ParseQueryInt(r, "pageSize", defaultPageSize)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	rating, err := handlerutils.ParseOptionalQueryInt(r, "rating")
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	payload := &ListReviewsRequest{
+		ProductID: productID,
+		Sort:      r.URL.Query().Get("sort"),
+		Page:      page,
+		PageSize:  pageSize,
+	}
+
+	if rating != nil {
+		stars := int(*rating)
+		payload.Rating = &stars
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	reviews, err := h.service.listProductReviews(ctx, payload)
+	if err != nil {
+		return reviewError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"reviews found",
+		reviews,
+	)
+}
+
+func (h *handler) getRatingSummaryHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	summary, err := h.service.getRatingSummary(ctx, productID)
+	if err != nil {
+		return reviewError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"rating found",
+		summary,
+	)
+}
+
+// moderationQueueHandler lists the pending and flagged reviews, or those in
+// ?status, oldest first.
+func (h *handler) moderationQueueHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	page, err := handlerutils.ParseQueryInt(r, "page", 1)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	pageSize, err := handlerutils.ParseQueryInt(r, "pageSize", defaultPageSize)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	payload := &ModerationQueueRequest{
+		Status:   r.URL.Query().Get("status"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	reviews, err := h.service.moderationQueue(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"reviews found",
+		reviews,
+	)
+}
+
+// moderateReviewHandler moves a review to status, the body with an optional
+// note is optional too.
+func (h *handler) moderateReviewHandler(status string) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		payload := new(ModerateReviewRequest)
+		defer r.Body.Close()
+
+		reviewID, err := uuid.Parse(chi.URLParam(r, "reviewID"))
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		if r.ContentLength != 0 {
+			if err = handlerutils.ParseJSON(r, payload); err != nil {
+				return servererrors.New(
+					http.StatusBadRequest,
+					servererrors.ErrInvalidRequestPayload.Error(),
+					nil,
+				)
+			}
+		}
+
+		if err = validate.StructFields(payload); err != nil {
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrValidationFailed.Error(),
+				err,
+			)
+		}
+
+		review, err := h.service.moderateReview(ctx, reviewID, status, payload)
+		if err != nil {
+			return reviewError(err)
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"review "+status,
+			review,
+		)
+	}
+}
+
+// userIDFromContext returns the id of the user authenticated by the auth
+// middleware.
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, servererrors.New(
+			http.StatusUnauthorized,
+			servererrors.ErrUnauthorized.Error(),
+			nil,
+		)
+	}
+
+	return uuid.Parse(claims.EntityID)
+}
+
+// reviewError maps the errors of the review service to responses.
+func reviewError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrProductNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrProductNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrReviewNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrReviewNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrReviewAlreadyExists):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrReviewAlreadyExists.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrOwnReviewVote):
+		return servererrors.New(
+			http.StatusForbidden,
+			servererrors.ErrOwnReviewVote.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
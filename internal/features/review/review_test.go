@@ -0,0 +1,458 @@
+package review
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func newTestRouter(purchases PurchaseVerifier) (*chi.Mux, *mockStore) {
+	reviewStore := newMockReviewStore()
+	reviewHandler := NewHandler(NewService(reviewStore, purchases, NewWordFilter()), nil)
+
+	router := chi.NewRouter()
+	router.Get(
+		"/products/{productID}/reviews",
+		handlerutils.MakeHandler(reviewHandler.listProductReviewsHandler),
+	)
+	router.Get(
+		"/products/{productID}/rating",
+		handlerutils.MakeHandler(reviewHandler.getRatingSummaryHandler),
+	)
+	router.Get(
+		"/admin/reviews",
+		handlerutils.MakeHandler(reviewHandler.moderationQueueHandler),
+	)
+	router.Post(
+		"/admin/reviews/{reviewID}/approve",
+		handlerutils.MakeHandler(reviewHandler.moderateReviewHandler(StatusApproved)),
+	)
+	router.Post(
+		"/admin/reviews/{reviewID}/reject",
+		handlerutils.MakeHandler(reviewHandler.moderateReviewHandler(StatusRejected)),
+	)
+
+	authenticated := router.With(testutil.AuthenticateFromHeader)
+	authenticated.Post(
+		"/products/{productID}/reviews",
+		handlerutils.MakeHandler(reviewHandler.createReviewHandler),
+	)
+	authenticated.Patch(
+		"/reviews/{reviewID}",
+		handlerutils.MakeHandler(reviewHandler.updateReviewHandler),
+	)
+	authenticated.Put(
+		"/reviews/{reviewID}/vote",
+		handlerutils.MakeHandler(reviewHandler.voteReviewHandler),
+	)
+	authenticated.Delete(
+		"/reviews/{reviewID}/vote",
+		handlerutils.MakeHandler(reviewHandler.removeVoteHandler),
+	)
+
+	return router, reviewStore
+}
+
+type purchases map[uuid.UUID]bool
+
+func (p purchases) HasPurchased(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (bool, error) {
+	return p[userID], nil
+}
+
+func TestReviewLifecycle(t *testing.T) {
+	buyer, browser, voter := uuid.New(), uuid.New(), uuid.New()
+	router, reviewStore := newTestRouter(purchases{buyer: true})
+
+	productID := uuid.New()
+	reviewStore.Products[productID] = product.StatusActive
+	productPath := "/products/" + productID.String()
+
+	submit := func(userID uuid.UUID, payload CreateReviewRequest) *Review {
+		t.Helper()
+
+		review := new(Review)
+		if code := testutil.Serve(t, router, http.MethodPost, productPath+"/reviews", &userID, payload, review); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		return review
+	}
+
+	listed := func() *ListReviewsResponse {
+		t.Helper()
+
+		resp := new(ListReviewsResponse)
+		if code := testutil.Serve(t, router, http.MethodGet, productPath+"/reviews", nil, nil, resp); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		return resp
+	}
+
+	bought := submit(buyer, CreateReviewRequest{Rating: 5, Title: "Great", Body: "Fits perfectly."})
+	browsed := submit(browser, CreateReviewRequest{Rating: 2, Body: "Too small."})
+
+	t.Run("should mark verified purchases", func(t *testing.T) {
+		if !bought.VerifiedPurchase || browsed.VerifiedPurchase {
+			t.Errorf("expected only the buyer's review to be verified, got %v and %v", bought.VerifiedPurchase, browsed.VerifiedPurchase)
+		}
+	})
+
+	t.Run("should allow one review per product", func(t *testing.T) {
+		code := testutil.Serve(t, router, http.MethodPost, productPath+"/reviews", &buyer, CreateReviewRequest{Rating: 4}, nil)
+		if code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, code)
+		}
+	})
+
+	t.Run("should hide reviews until approved", func(t *testing.T) {
+		if resp := listed(); len(resp.Reviews) != 0 || resp.Summary.ReviewCount != 0 {
+			t.Fatalf("expected no reviews, got %d", len(resp.Reviews))
+		}
+
+		queue := new(ListReviewsResponse)
+		testutil.Serve(t, router, http.MethodGet, "/admin/reviews", nil, nil, queue)
+		if queue.TotalCount != 2 {
+			t.Errorf("expected 2 reviews in the queue, got %d", queue.TotalCount)
+		}
+	})
+
+	t.Run("should aggregate approved reviews", func(t *testing.T) {
+		for _, review := range []*Review{bought, browsed} {
+			code := testutil.Serve(t, router, http.MethodPost, "/admin/reviews/"+review.ReviewID.String()+"/approve", nil, nil, nil)
+			if code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+			}
+		}
+
+		summary := new(RatingSummary)
+		testutil.Serve(t, router, http.MethodGet, productPath+"/rating", nil, nil, summary)
+		if summary.ReviewCount != 2 || summary.Average != 3.5 || summary.Distribution[5] != 1 || summary.Distribution[2] != 1 {
+			t.Errorf("expected 2 reviews averaging 3.5, got %+v", summary)
+		}
+	})
+
+	t.Run("should count helpfulness votes", func(t *testing.T) {
+		review := new(Review)
+		testutil.Serve(t, router, http.MethodPut, "/reviews/"+browsed.ReviewID.String()+"/vote", &voter, VoteRequest{Helpful: true}, review)
+		testutil.Serve(t, router, http.MethodPut, "/reviews/"+browsed.ReviewID.String()+"/vote", &buyer, VoteRequest{Helpful: false}, review)
+		if review.HelpfulCount != 1 || review.UnhelpfulCount != 1 {
+			t.Fatalf("expected 1 helpful and 1 unhelpful vote, got %d and %d", review.HelpfulCount, review.UnhelpfulCount)
+		}
+
+		testutil.Serve(t, router, http.MethodPut, "/reviews/"+browsed.ReviewID.String()+"/vote", &buyer, VoteRequest{Helpful: true}, review)
+		if review.HelpfulCount != 2 || review.UnhelpfulCount != 0 {
+			t.Errorf("expected a changed vote to replace the old one, got %d and %d", review.HelpfulCount, review.UnhelpfulCount)
+		}
+
+		resp := new(ListReviewsResponse)
+		testutil.Serve(t, router, http.MethodGet, productPath+"/reviews?sort=-helpful", nil, nil, resp)
+		if len(resp.Reviews) != 2 || resp.Reviews[0].ReviewID != browsed.ReviewID {
+			t.Errorf("expected the most helpful review first")
+		}
+	})
+
+	t.Run("should not vote on your own review", func(t *testing.T) {
+		code := testutil.Serve(t, router, http.MethodPut, "/reviews/"+bought.ReviewID.String()+"/vote", &buyer, VoteRequest{Helpful: true}, nil)
+		if code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, code)
+		}
+	})
+
+	t.Run("should send edited reviews back to moderation", func(t *testing.T) {
+		rating := 1
+		review := new(Review)
+		code := testutil.Serve(t, router, http.MethodPatch, "/reviews/"+bought.ReviewID.String(), &buyer, UpdateReviewRequest{Rating: &rating}, review)
+		if code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if review.Status != StatusPending || review.Rating != 1 {
+			t.Errorf("expected a pending 1 star review, got a %s %d star review", review.Status, review.Rating)
+		}
+
+		if summary := listed().Summary; summary.ReviewCount != 1 || summary.Average != 2 {
+			t.Errorf("expected only the other review to count, got %+v", summary)
+		}
+	})
+
+	t.Run("should not edit the review of someone else", func(t *testing.T) {
+		code := testutil.Serve(t, router, http.MethodPatch, "/reviews/"+bought.ReviewID.String(), &browser, UpdateReviewRequest{}, nil)
+		if code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, code)
+		}
+	})
+}
+
+func TestReviewRoutes(t *testing.T) {
+	router, reviewStore := newTestRouter(purchases{})
+
+	productID := uuid.New()
+	reviewStore.Products[productID] = product.StatusActive
+	draftID := uuid.New()
+	reviewStore.Products[draftID] = product.StatusDraft
+
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		userID   *uuid.UUID
+		payload  any
+		expected int
+	}{
+		{
+			name:     "should require a signed in user",
+			method:   http.MethodPost,
+			path:     "/products/" + productID.String() + "/reviews",
+			payload:  CreateReviewRequest{Rating: 4},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "should reject a rating out of range",
+			method:   http.MethodPost,
+			path:     "/products/" + productID.String() + "/reviews",
+			userID:   &userID,
+			payload:  CreateReviewRequest{Rating: 6},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should not review a draft",
+			method:   http.MethodPost,
+			path:     "/products/" + draftID.String() + "/reviews",
+			userID:   &userID,
+			payload:  CreateReviewRequest{Rating: 4},
+			expected: http.StatusNotFound,
+		},
+		{
+			name:     "should not list the reviews of a draft",
+			method:   http.MethodGet,
+			path:     "/products/" + draftID.String() + "/reviews",
+			expected: http.StatusNotFound,
+		},
+		{
+			name:     "should reject an unknown sort",
+			method:   http.MethodGet,
+			path:     "/products/" + productID.String() + "/reviews?sort=author",
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "should not moderate an unknown review",
+			method:   http.MethodPost,
+			path:     "/admin/reviews/" + uuid.NewString() + "/reject",
+			payload:  ModerateReviewRequest{Note: "spam"},
+			expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := testutil.Serve(t, router, tc.method, tc.path, tc.userID, tc.payload, nil); code != tc.expected {
+				t.Errorf("expected status code %d, got %d", tc.expected, code)
+			}
+		})
+	}
+
+	t.Run("should flag reviews the filter objects to", func(t *testing.T) {
+		review := new(Review)
+		testutil.Serve(t, router, http.MethodPost, "/products/"+productID.String()+"/reviews", &userID, CreateReviewRequest{Rating: 1, Body: "Cheaper at www.example.com"}, review)
+		if review.Status != StatusFlagged || review.ModerationNote == "" {
+			t.Errorf("expected a flagged review with a note, got %s %q", review.Status, review.ModerationNote)
+		}
+	})
+}
+
+func TestWordFilter(t *testing.T) {
+	filter := NewWordFilter()
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{text: "Lovely shirt, great fit", expected: []string{}},
+		{text: "Shipping was a Shitshow", expected: []string{}},
+		{text: "What a SHIT product", expected: []string{"contains profanity"}},
+		{text: "see https://example.com/deal", expected: []string{"contains a link"}},
+		{text: "buy at cheap-shoes.net, shit quality", expected: []string{"contains a link", "contains profanity"}},
+	}
+
+	for _, tc := range tests {
+		if reasons := filter.Check(tc.text); !slices.Equal(reasons, tc.expected) {
+			t.Errorf("expected %v for %q, got %v", tc.expected, tc.text, reasons)
+		}
+	}
+}
+
+func TestRefreshSummary(t *testing.T) {
+	tx := new(recordingTx)
+	productID := uuid.New()
+	if err := refreshSummary(context.Background(), tx, productID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the summary is only recomputed once the product is locked, within the
+	// same transaction
+	if len(tx.queries) != 2 || tx.queries[0] != lockSummary || !strings.Contains(tx.queries[1], "product_rating_summaries") {
+		t.Fatalf("expected the lock then the refresh, got %q", tx.queries)
+	}
+
+	if len(tx.args[0]) != 1 || tx.args[0][0] != productID {
+		t.Errorf("expected the lock keyed by product %s, got %v", productID, tx.args[0])
+	}
+
+	tx = &recordingTx{err: errors.New("canceled")}
+	if err := refreshSummary(context.Background(), tx, productID); err == nil || len(tx.queries) != 1 {
+		t.Errorf("expected no refresh without the lock, got %v after %d queries", err, len(tx.queries))
+	}
+}
+
+// recordingTx records the statements run through it, failing them all with
+// err when set.
+type recordingTx struct {
+	queries []string
+	args    [][]any
+	err     error
+}
+
+func (r *recordingTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	r.args = append(r.args, args)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+type mockStore struct {
+	Products map[uuid.UUID]string
+	Reviews  map[uuid.UUID]*Review
+	Votes    map[uuid.UUID]map[uuid.UUID]bool
+}
+
+func newMockReviewStore() *mockStore {
+	return &mockStore{
+		Products: map[uuid.UUID]string{},
+		Reviews:  map[uuid.UUID]*Review{},
+		Votes:    map[uuid.UUID]map[uuid.UUID]bool{},
+	}
+}
+
+func (m *mockStore) productStatus(ctx context.Context, productID uuid.UUID) (string, error) {
+	return m.Products[productID], nil
+}
+
+func (m *mockStore) create(ctx context.Context, review *Review) error {
+	for _, existing := range m.Reviews {
+		if existing.ProductID == review.ProductID && existing.UserID == review.UserID {
+			return servererrors.ErrReviewAlreadyExists
+		}
+	}
+
+	review.CreatedAt, review.UpdatedAt = time.Now(), time.Now()
+	m.Reviews[review.ReviewID] = review
+	return nil
+}
+
+func (m *mockStore) findByID(ctx context.Context, reviewID uuid.UUID) (*Review, error) {
+	review, ok := m.Reviews[reviewID]
+	if !ok {
+		return new(Review), nil
+	}
+
+	copied := *review
+	return &copied, nil
+}
+
+func (m *mockStore) find(ctx context.Context, filter *reviewFilter) ([]*Review, int64, error) {
+	reviews := []*Review{}
+	for _, review := range m.Reviews {
+		if !slices.Contains(filter.Statuses, review.Status) ||
+			(filter.ProductID != nil && review.ProductID != *filter.ProductID) ||
+			(filter.Rating != nil && review.Rating != *filter.Rating) {
+			continue
+		}
+
+		copied := *review
+		reviews = append(reviews, &copied)
+	}
+
+	sort.Slice(reviews, func(i, j int) bool {
+		if filter.Sort == "-helpful" {
+			return reviews[i].HelpfulCount-reviews[i].UnhelpfulCount > reviews[j].HelpfulCount-reviews[j].UnhelpfulCount
+		}
+
+		return reviews[i].CreatedAt.Before(reviews[j].CreatedAt)
+	})
+
+	totalCount := int64(len(reviews))
+	start := min(filter.Offset, totalCount)
+	end := min(start+filter.Limit, totalCount)
+
+	return reviews[start:end], totalCount, nil
+}
+
+func (m *mockStore) update(ctx context.Context, review *Review) error {
+	review.UpdatedAt = time.Now()
+	m.Reviews[review.ReviewID] = review
+	return nil
+}
+
+func (m *mockStore) delete(ctx context.Context, review *Review) error {
+	delete(m.Reviews, review.ReviewID)
+	return nil
+}
+
+func (m *mockStore) vote(ctx context.Context, reviewID uuid.UUID, userID uuid.UUID, helpful *bool) error {
+	if m.Votes[reviewID] == nil {
+		m.Votes[reviewID] = map[uuid.UUID]bool{}
+	}
+
+	if helpful == nil {
+		delete(m.Votes[reviewID], userID)
+	} else {
+		m.Votes[reviewID][userID] = *helpful
+	}
+
+	review := m.Reviews[reviewID]
+	review.HelpfulCount, review.UnhelpfulCount = 0, 0
+	for _, helpful := range m.Votes[reviewID] {
+		if helpful {
+			review.HelpfulCount++
+		} else {
+			review.UnhelpfulCount++
+		}
+	}
+
+	return nil
+}
+
+// findSummary derives the summary from the approved reviews, which the real
+// store keeps up to date on every change.
+func (m *mockStore) findSummary(ctx context.Context, productID uuid.UUID) (*RatingSummary, error) {
+	sum := 0
+	counts := make([]int, 5)
+	for _, review := range m.Reviews {
+		if review.ProductID == productID && review.Status == StatusApproved {
+			sum += review.Rating
+			counts[review.Rating-1]++
+		}
+	}
+
+	return newRatingSummary(productID, sum, counts), nil
+}
@@ -0,0 +1,282 @@
+package review
+
+import (
+	"context"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/features/product"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type reviewStorer interface {
+	productStatus(ctx context.Context, productID uuid.UUID) (string, error)
+	create(ctx context.Context, review *Review) error
+	findByID(ctx context.Context, reviewID uuid.UUID) (*Review, error)
+	find(ctx context.Context, filter *reviewFilter) ([]*Review, int64, error)
+	update(ctx context.Context, review *Review) error
+	delete(ctx context.Context, review *Review) error
+	vote(ctx context.Context, reviewID uuid.UUID, userID uuid.UUID, helpful *bool) error
+	findSummary(ctx context.Context, productID uuid.UUID) (*RatingSummary, error)
+}
+
+// PurchaseVerifier tells whether a user has ordered a product, which earns
+// their review the verified purchase badge.
+type PurchaseVerifier interface {
+	HasPurchased(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (bool, error)
+}
+
+type service struct {
+	reviewStore reviewStorer
+	purchases   PurchaseVerifier
+	filter      ContentFilter
+}
+
+func NewService(reviewStore reviewStorer, purchases PurchaseVerifier, filter ContentFilter) *service {
+	return &service{
+		reviewStore: reviewStore,
+		purchases:   purchases,
+		filter:      filter,
+	}
+}
+
+// createReview posts the one review a user may write per product, it waits
+// for moderation before being shown.
+func (s *service) createReview(ctx context.Context, userID uuid.UUID, productID uuid.UUID, payload *CreateReviewRequest) (*Review, error) {
+	status, err := s.reviewStore.productStatus(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != product.StatusActive {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	review := &Review{
+		ReviewID:  uuid.New(),
+		ProductID: productID,
+		UserID:    userID,
+		Rating:    payload.Rating,
+		Title:     strings.TrimSpace(payload.Title),
+		Body:      strings.TrimSpace(payload.Body),
+	}
+
+	if err = s.submit(ctx, review); err != nil {
+		return nil, err
+	}
+
+	if err = s.reviewStore.create(ctx, review); err != nil {
+		return nil, err
+	}
+
+	return s.reviewStore.findByID(ctx, review.ReviewID)
+}
+
+// updateReview lets a user edit their own review, which sends it back to the
+// moderation queue.
+func (s *service) updateReview(ctx context.Context, userID uuid.UUID, reviewID uuid.UUID, payload *UpdateReviewRequest) (*Review, error) {
+	review, err := s.findOwnReview(ctx, userID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Rating != nil {
+		review.Rating = *payload.Rating
+	}
+
+	if payload.Title != nil {
+		review.Title = strings.TrimSpace(*payload.Title)
+	}
+
+	if payload.Body != nil {
+		review.Body = strings.TrimSpace(*payload.Body)
+	}
+
+	if err = s.submit(ctx, review); err != nil {
+		return nil, err
+	}
+
+	if err = s.reviewStore.update(ctx, review); err != nil {
+		return nil, err
+	}
+
+	return s.reviewStore.findByID(ctx, reviewID)
+}
+
+func (s *service) deleteReview(ctx context.Context, userID uuid.UUID, reviewID uuid.UUID) error {
+	review, err := s.findOwnReview(ctx, userID, reviewID)
+	if err != nil {
+		return err
+	}
+
+	return s.reviewStore.delete(ctx, review)
+}
+
+// voteReview records whether a user found a review helpful, or removes their
+// vote when helpful is nil. Only approved reviews of others can be voted on.
+func (s *service) voteReview(ctx context.Context, userID uuid.UUID, reviewID uuid.UUID, helpful *bool) (*Review, error) {
+	review, err := s.reviewStore.findByID(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	if review.ReviewID == uuid.Nil || review.Status != StatusApproved {
+		return nil, servererrors.ErrReviewNotFound
+	}
+
+	if review.UserID == userID {
+		return nil, servererrors.ErrOwnReviewVote
+	}
+
+	if err = s.reviewStore.vote(ctx, reviewID, userID, helpful); err != nil {
+		return nil, err
+	}
+
+	review, err = s.reviewStore.findByID(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	return publicReview(review), nil
+}
+
+// listProductReviews returns a page of the approved reviews of a product
+// along with its rating summary.
+func (s *service) listProductReviews(ctx context.Context, payload *ListReviewsRequest) (*ListReviewsResponse, error) {
+	status, err := s.reviewStore.productStatus(ctx, payload.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != product.StatusActive {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	reviews, totalCount, err := s.reviewStore.find(ctx, &reviewFilter{
+		ProductID: &payload.ProductID,
+		Statuses:  []string{StatusApproved},
+		Rating:    payload.Rating,
+		Sort:      payload.Sort,
+		Limit:     payload.PageSize,
+		Offset:    (payload.Page - 1) * payload.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, review := range reviews {
+		reviews[i] = publicReview(review)
+	}
+
+	summary, err := s.reviewStore.findSummary(ctx, payload.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListReviewsResponse{
+		Summary:    summary,
+		Reviews:    reviews,
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
+
+func (s *service) getRatingSummary(ctx context.Context, productID uuid.UUID) (*RatingSummary, error) {
+	status, err := s.reviewStore.productStatus(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != product.StatusActive {
+		return nil, servererrors.ErrProductNotFound
+	}
+
+	return s.reviewStore.findSummary(ctx, productID)
+}
+
+// moderationQueue lists the reviews waiting for a moderator, oldest first.
+func (s *service) moderationQueue(ctx context.Context, payload *ModerationQueueRequest) (*ListReviewsResponse, error) {
+	statuses := []string{StatusPending, StatusFlagged}
+	if payload.Status != "" {
+		statuses = []string{payload.Status}
+	}
+
+	reviews, totalCount, err := s.reviewStore.find(ctx, &reviewFilter{
+		Statuses: statuses,
+		Sort:     "created_at",
+		Limit:    payload.PageSize,
+		Offset:   (payload.Page - 1) * payload.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListReviewsResponse{
+		Reviews:    reviews,
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// moderateReview moves a review to status, approving it makes it count in
+// the rating summary.
+func (s *service) moderateReview(ctx context.Context, reviewID uuid.UUID, status string, payload *ModerateReviewRequest) (*Review, error) {
+	review, err := s.reviewStore.findByID(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	if review.ReviewID == uuid.Nil {
+		return nil, servererrors.ErrReviewNotFound
+	}
+
+	review.Status = status
+	review.ModerationNote = strings.TrimSpace(payload.Note)
+
+	if err = s.reviewStore.update(ctx, review); err != nil {
+		return nil, err
+	}
+
+	return s.reviewStore.findByID(ctx, reviewID)
+}
+
+// submit queues a new or edited review for moderation, flagging it when the
+// content filter objects, and refreshes its verified purchase badge.
+func (s *service) submit(ctx context.Context, review *Review) error {
+	review.Status = StatusPending
+	review.ModerationNote = ""
+
+	if reasons := s.filter.Check(review.Title + "\n" + review.Body); len(reasons) > 0 {
+		review.Status = StatusFlagged
+		review.ModerationNote = "filter: " + strings.Join(reasons, ", ")
+	}
+
+	verified, err := s.purchases.HasPurchased(ctx, review.UserID, review.ProductID)
+	if err != nil {
+		return err
+	}
+	review.VerifiedPurchase = verified
+
+	return nil
+}
+
+func (s *service) findOwnReview(ctx context.Context, userID uuid.UUID, reviewID uuid.UUID) (*Review, error) {
+	review, err := s.reviewStore.findByID(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	if review.ReviewID == uuid.Nil || review.UserID != userID {
+		return nil, servererrors.ErrReviewNotFound
+	}
+
+	return review, nil
+}
+
+// publicReview hides the moderation details of a review shown to customers.
+func publicReview(review *Review) *Review {
+	review.ModerationNote = ""
+	return review
+}
@@ -0,0 +1,385 @@
+package review
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	reviewFields = "review_id, product_id, user_id, rating, title, body, status, moderation_note, verified_purchase, helpful_count, unhelpful_count, created_at, updated_at"
+
+	// postgres error codes
+	uniqueViolation = "23505"
+
+	// lockSummary serialises the refreshes of the rating summary of a
+	// product so concurrent moderations can not overwrite each other's
+	// counts
+	lockSummary = "SELECT pg_advisory_xact_lock(hashtext('product_rating_summaries'), hashtext($1::text))"
+)
+
+// reviewSortColumns maps the sort parameter onto ORDER BY clauses, newer
+// reviews break ties.
+var reviewSortColumns = map[string]string{
+	"created_at":  "created_at ASC",
+	"-created_at": "created_at DESC",
+	"rating":      "rating ASC, created_at DESC",
+	"-rating":     "rating DESC, created_at DESC",
+	"helpful":     "helpful_count - unhelpful_count ASC, created_at DESC",
+	"-helpful":    "helpful_count - unhelpful_count DESC, created_at DESC",
+}
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+// productStatus returns the status of a product, or an empty string when it
+// does not exist.
+func (s *store) productStatus(ctx context.Context, productID uuid.UUID) (string, error) {
+	var status string
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT status FROM products WHERE product_id = $1",
+		productID,
+	).Scan(&status)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf(
+			"failed to find product status in review store: %w",
+			err,
+		)
+	}
+
+	return status, nil
+}
+
+func (s *store) create(ctx context.Context, review *Review) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO product_reviews(review_id, product_id, user_id, rating, title, body, status, moderation_note, verified_purchase)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		review.ReviewID,
+		review.ProductID,
+		review.UserID,
+		review.Rating,
+		review.Title,
+		review.Body,
+		review.Status,
+		review.ModerationNote,
+		review.VerifiedPurchase,
+	)
+	if err != nil {
+		if isPQError(err, uniqueViolation) {
+			return servererrors.ErrReviewAlreadyExists
+		}
+
+		return fmt.Errorf(
+			"failed to insert new review in review store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findByID(ctx context.Context, reviewID uuid.UUID) (*Review, error) {
+	reviews, err := s.getReviewsWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM product_reviews WHERE review_id = $1", reviewFields),
+		reviewID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find review by id in review store: %w",
+			err,
+		)
+	}
+
+	if len(reviews) == 0 {
+		return new(Review), nil
+	}
+
+	return reviews[0], nil
+}
+
+// find returns a page of the reviews matching filter and how many match in
+// total.
+func (s *store) find(ctx context.Context, filter *reviewFilter) ([]*Review, int64, error) {
+	conditions := []string{"status = ANY($1)"}
+	args := []any{pq.Array(filter.Statuses)}
+
+	if filter.ProductID != nil {
+		args = append(args, *filter.ProductID)
+		conditions = append(conditions, fmt.Sprintf("product_id = $%d", len(args)))
+	}
+
+	if filter.Rating != nil {
+		args = append(args, *filter.Rating)
+		conditions = append(conditions, fmt.Sprintf("rating = $%d", len(args)))
+	}
+
+	where := strings.Join(conditions, " AND ")
+
+	var totalCount int64
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM product_reviews WHERE "+where,
+		args...,
+	).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to count reviews in review store: %w",
+			err,
+		)
+	}
+
+	orderBy, ok := reviewSortColumns[filter.Sort]
+	if !ok {
+		orderBy = reviewSortColumns["-created_at"]
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	reviews, err := s.getReviewsWithContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT %s FROM product_reviews WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d",
+			reviewFields,
+			where,
+			orderBy,
+			len(args)-1,
+			len(args),
+		),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to find reviews in review store: %w",
+			err,
+		)
+	}
+
+	return reviews, totalCount, nil
+}
+
+// update saves every editable field of a review, including its moderation
+// status, and refreshes the rating summary of its product.
+func (s *store) update(ctx context.Context, review *Review) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`UPDATE product_reviews SET rating = $1, title = $2, body = $3, status = $4, moderation_note = $5,
+			verified_purchase = $6, updated_at = NOW() WHERE review_id = $7`,
+			review.Rating,
+			review.Title,
+			review.Body,
+			review.Status,
+			review.ModerationNote,
+			review.VerifiedPurchase,
+			review.ReviewID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update review in review store: %w", err)
+		}
+
+		return refreshSummary(ctx, tx, review.ProductID)
+	})
+}
+
+func (s *store) delete(ctx context.Context, review *Review) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM product_reviews WHERE review_id = $1", review.ReviewID); err != nil {
+			return fmt.Errorf("failed to delete review in review store: %w", err)
+		}
+
+		return refreshSummary(ctx, tx, review.ProductID)
+	})
+}
+
+// vote records whether a user found a review helpful, replacing their
+// previous vote, or removes their vote when helpful is nil.
+func (s *store) vote(ctx context.Context, reviewID uuid.UUID, userID uuid.UUID, helpful *bool) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if helpful == nil {
+			_, err = tx.ExecContext(
+				ctx,
+				"DELETE FROM review_votes WHERE review_id = $1 AND user_id = $2",
+				reviewID,
+				userID,
+			)
+		} else {
+			_, err = tx.ExecContext(
+				ctx,
+				`INSERT INTO review_votes(review_id, user_id, helpful) VALUES($1, $2, $3)
+				ON CONFLICT (review_id, user_id) DO UPDATE SET helpful = EXCLUDED.helpful, created_at = NOW()`,
+				reviewID,
+				userID,
+				*helpful,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save review vote in review store: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE product_reviews SET
+				helpful_count = (SELECT COUNT(*) FROM review_votes WHERE review_id = $1 AND helpful),
+				unhelpful_count = (SELECT COUNT(*) FROM review_votes WHERE review_id = $1 AND NOT helpful)
+			WHERE review_id = $1`,
+			reviewID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to count review votes in review store: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *store) findSummary(ctx context.Context, productID uuid.UUID) (*RatingSummary, error) {
+	var count, sum int
+	counts := make([]int, 5)
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT review_count, rating_sum, rating_1, rating_2, rating_3, rating_4, rating_5
+		FROM product_rating_summaries WHERE product_id = $1`,
+		productID,
+	).Scan(
+		&count,
+		&sum,
+		&counts[0],
+		&counts[1],
+		&counts[2],
+		&counts[3],
+		&counts[4],
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"failed to find rating summary in review store: %w",
+			err,
+		)
+	}
+
+	return newRatingSummary(productID, sum, counts), nil
+}
+
+// execer is what refreshSummary needs of a *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// refreshSummary recomputes the rating summary of a product from its
+// approved reviews. It waits for any other refresh of the product to commit
+// first, so the counts read include its changes.
+func refreshSummary(ctx context.Context, tx execer, productID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, lockSummary, productID); err != nil {
+		return fmt.Errorf("failed to lock rating summary in review store: %w", err)
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO product_rating_summaries(product_id, review_count, rating_sum, rating_1, rating_2, rating_3, rating_4, rating_5)
+		SELECT $1::uuid, COUNT(*), COALESCE(SUM(rating), 0),
+			COUNT(*) FILTER (WHERE rating = 1), COUNT(*) FILTER (WHERE rating = 2), COUNT(*) FILTER (WHERE rating = 3),
+			COUNT(*) FILTER (WHERE rating = 4), COUNT(*) FILTER (WHERE rating = 5)
+		FROM product_reviews WHERE product_id = $1::uuid AND status = $2
+		ON CONFLICT (product_id) DO UPDATE SET
+			review_count = EXCLUDED.review_count, rating_sum = EXCLUDED.rating_sum,
+			rating_1 = EXCLUDED.rating_1, rating_2 = EXCLUDED.rating_2, rating_3 = EXCLUDED.rating_3,
+			rating_4 = EXCLUDED.rating_4, rating_5 = EXCLUDED.rating_5, updated_at = NOW()`,
+		productID,
+		StatusApproved,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refresh rating summary in review store: %w", err)
+	}
+
+	return nil
+}
+
+func (s *store) getReviewsWithContext(ctx context.Context, query string, args ...any) ([]*Review, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in review store getReviewsWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	reviews := []*Review{}
+	for rows.Next() {
+		review := new(Review)
+		err = rows.Scan(
+			&review.ReviewID,
+			&review.ProductID,
+			&review.UserID,
+			&review.Rating,
+			&review.Title,
+			&review.Body,
+			&review.Status,
+			&review.ModerationNote,
+			&review.VerifiedPurchase,
+			&review.HelpfulCount,
+			&review.UnhelpfulCount,
+			&review.CreatedAt,
+			&review.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into review in review store: %w",
+				err,
+			)
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in review store: %w",
+			err,
+		)
+	}
+
+	return reviews, nil
+}
+
+func (s *store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in review store: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in review store: %w", err)
+	}
+
+	return nil
+}
+
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
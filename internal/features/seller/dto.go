@@ -0,0 +1,59 @@
+package seller
+
+import "github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
+
+// Requests
+
+type RegisterSellerRequest struct {
+	StoreName string `json:"storeName" validate:"required,min=2,max=100"`
+	FirstName string `json:"firstName" validate:"required,min=2,max=15,noAllRepeatingChars"`
+	LastName  string `json:"lastName" validate:"required,min=2,max=15,noAllRepeatingChars"`
+	Email     string `json:"email" validate:"required,email"`
+	Password  string `json:"password" validate:"required,min=5,max=10"`
+}
+
+type LoginSellerRequest struct {
+	Email     string `json:"email" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+	UserAgent string `json:"userAgent" validate:"required"`
+	ClientIP  string `json:"clientIP" validate:"required"`
+}
+
+// ReviewSellerRequest moves a seller to a new status, Reason tells the
+// seller why it was rejected or suspended.
+type ReviewSellerRequest struct {
+	Status string `json:"status" validate:"required,oneof=approved rejected suspended"`
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+type ListSellersRequest struct {
+	Status   string `validate:"omitempty,oneof=pending approved rejected suspended"`
+	Page     int64  `validate:"min=1"`
+	PageSize int64  `validate:"min=1,max=100"`
+}
+
+type ListOrdersRequest struct {
+	Page     int64 `validate:"min=1"`
+	PageSize int64 `validate:"min=1,max=100"`
+}
+
+// Responses
+
+type LoginSellerCookiesResponse struct {
+	AccessToken  interfaces.TokenDetails `json:"accessToken"`
+	RefreshToken interfaces.TokenDetails `json:"refreshToken"`
+}
+
+type ListSellersResponse struct {
+	Sellers    []*Seller `json:"sellers"`
+	Page       int64     `json:"page"`
+	PageSize   int64     `json:"pageSize"`
+	TotalCount int64     `json:"totalCount"`
+}
+
+type ListOrdersResponse struct {
+	Orders     []*Order `json:"orders"`
+	Page       int64    `json:"page"`
+	PageSize   int64    `json:"pageSize"`
+	TotalCount int64    `json:"totalCount"`
+}
@@ -0,0 +1,63 @@
+package seller
+
+import (
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/google/uuid"
+)
+
+// A seller registers as pending and is approved or rejected by an admin,
+// only approved sellers can log in. Approved sellers can be suspended and
+// reinstated.
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusSuspended = "suspended"
+)
+
+type Seller struct {
+	SellerID       uuid.UUID `json:"seller_id"`
+	StoreName      string    `json:"store_name"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Email          string    `json:"email"`
+	HashedPassword string    `json:"-"`
+	Status         string    `json:"status"`
+	// StatusReason is why an admin rejected or suspended the seller
+	StatusReason string     `json:"status_reason"`
+	ReviewedAt   *time.Time `json:"reviewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (s *Seller) comparePassword(passwordStr string) bool {
+	return auth.ComparePassword(s.HashedPassword, passwordStr)
+}
+
+// Order is a paid checkout as a seller sees it, with only the lines of its
+// own products. Checkouts do not record the prices paid so orders carry no
+// amounts.
+type Order struct {
+	// OrderID is the checkout the order was paid through
+	OrderID  uuid.UUID    `json:"order_id"`
+	Lines    []*OrderLine `json:"lines"`
+	PlacedAt time.Time    `json:"placed_at"`
+}
+
+type OrderLine struct {
+	ProductID uuid.UUID `json:"product_id"`
+	SKU       string    `json:"sku"`
+	Name      string    `json:"name"`
+	Quantity  int64     `json:"quantity"`
+}
+
+// Dashboard sums up the catalog and orders of a seller.
+type Dashboard struct {
+	Seller *Seller `json:"seller"`
+	// Products counts the products of the seller by status
+	Products     map[string]int64 `json:"products"`
+	TotalOrders  int64            `json:"total_orders"`
+	RecentOrders []*Order         `json:"recent_orders"`
+}
@@ -0,0 +1,521 @@
+package seller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type servicer interface {
+	registerSeller(ctx context.Context, payload *RegisterSellerRequest) (*Seller, error)
+	loginSeller(ctx context.Context, payload *LoginSellerRequest) (*LoginSellerCookiesResponse, error)
+	logoutSeller(ctx context.Context, refreshToken string, accessToken string) error
+	getSeller(ctx context.Context, sellerID uuid.UUID) (*Seller, error)
+	listSellers(ctx context.Context, payload *ListSellersRequest) (*ListSellersResponse, error)
+	reviewSeller(ctx context.Context, sellerID uuid.UUID, payload *ReviewSellerRequest) (*Seller, error)
+	dashboard(ctx context.Context, sellerID uuid.UUID) (*Dashboard, error)
+	listOrders(ctx context.Context, sellerID uuid.UUID, payload *ListOrdersRequest) (*ListOrdersResponse, error)
+}
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
+}
+
+const defaultPageSize = 20
+
+type handler struct {
+	service       servicer
+	authenticator authenticator
+}
+
+func NewHandler(service servicer, authenticator authenticator) *handler {
+	return &handler{
+		service:       service,
+		authenticator: authenticator,
+	}
+}
+
+// RegisterRoutes registers the seller account and dashboard routes relative
+// to the seller route group, which is mounted at /seller.
+func (h *handler) RegisterRoutes(router chi.Router) {
+	router.Post(
+		"/register",
+		handlerutils.MakeHandler(h.registerSellerHandler),
+	)
+	router.Post(
+		"/login",
+		handlerutils.MakeHandler(h.loginSellerHandler),
+	)
+	router.Post(
+		"/logout",
+		handlerutils.MakeHandler(h.logoutSellerHandler),
+	)
+
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeSeller))
+	authenticated.Get(
+		"/me",
+		handlerutils.MakeHandler(h.getMeHandler),
+	)
+	authenticated.Get(
+		"/dashboard",
+		handlerutils.MakeHandler(h.dashboardHandler),
+	)
+	authenticated.Get(
+		"/orders",
+		handlerutils.MakeHandler(h.listOrdersHandler),
+	)
+}
+
+// RegisterAdminRoutes registers the seller approval routes relative to the
+// admin route group.
+func (h *handler) RegisterAdminRoutes(router chi.Router) {
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeAdmin))
+	authenticated.Get(
+		"/sellers",
+		handlerutils.MakeHandler(h.listSellersHandler),
+	)
+	authenticated.Get(
+		"/sellers/{sellerID}",
+		handlerutils.MakeHandler(h.getSellerHandler),
+	)
+	authenticated.Put(
+		"/sellers/{sellerID}/status",
+		handlerutils.MakeHandler(h.reviewSellerHandler),
+	)
+}
+
+func (h *handler) registerSellerHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *RegisterSellerRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	seller, err := h.service.registerSeller(ctx, payload)
+	if err != nil {
+		return sellerError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"seller registered, awaiting approval",
+		seller,
+	)
+}
+
+func (h *handler) loginSellerHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *LoginSellerRequest
+	var err error
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	payload.ClientIP = handlerutils.GetClientIP(r)
+	payload.UserAgent = r.UserAgent()
+
+	loginResponse, err := h.service.loginSeller(ctx, payload)
+	if err != nil {
+		return sellerError(err)
+	}
+
+	handlerutils.SetCookies(
+		w,
+		[]handlerutils.Cookie{
+			{
+				Name:    "accessToken",
+				Value:   loginResponse.AccessToken.Value,
+				Expires: loginResponse.AccessToken.Expires,
+			},
+			{
+				Name:    "refreshToken",
+				Value:   loginResponse.RefreshToken.Value,
+				Expires: loginResponse.RefreshToken.Expires,
+			},
+		},
+	)
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusCreated,
+		"access and refresh tokens attached to cookies",
+		nil,
+	)
+}
+
+func (h *handler) logoutSellerHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	defer r.Body.Close()
+
+	refreshToken, err := r.Cookie("refreshToken")
+	if err != nil {
+		switch {
+		case errors.Is(err, http.ErrNoCookie):
+			return servererrors.New(
+				http.StatusForbidden,
+				servererrors.ErrNoRefreshTokenCookie.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	var accessToken string
+	if cookie, err := r.Cookie("accessToken"); err == nil {
+		accessToken = cookie.Value
+	}
+
+	cookiesNames := []string{
+		"accessToken",
+		"refreshToken",
+	}
+
+	err = h.service.logoutSeller(ctx, refreshToken.Value, accessToken)
+	handlerutils.ClearCookie(
+		w,
+		&cookiesNames,
+	)
+	if err != nil {
+		return err
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"seller logged out",
+		nil,
+	)
+}
+
+func (h *handler) getMeHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	sellerID, err := sellerIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	seller, err := h.service.getSeller(ctx, sellerID)
+	if err != nil {
+		return sellerError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"seller found",
+		seller,
+	)
+}
+
+func (h *handler) dashboardHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	sellerID, err := sellerIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	dashboard, err := h.service.dashboard(ctx, sellerID)
+	if err != nil {
+		return sellerError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"dashboard found",
+		dashboard,
+	)
+}
+
+// listOrdersHandler reads ?page=2&pageSize=20.
+func (h *handler) listOrdersHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	sellerID, err := sellerIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	page, err := handlerutils.ParseQueryInt(r, "page", 1)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	pageSize, err := handlerutils.ParseQueryInt(r, "pageSize", defaultPageSize)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	payload := &ListOrdersRequest{
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	orders, err := h.service.listOrders(ctx, sellerID, payload)
+	if err != nil {
+		return sellerError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"orders found",
+		orders,
+	)
+}
+
+// listSellersHandler reads ?status=pending&page=2&pageSize=20.
+func (h *handler) listSellersHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	page, err := handlerutils.ParseQueryInt(r, "page", 1)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	pageSize, err := handlerutils.ParseQueryInt(r, "pageSize", defaultPageSize)
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidQueryParams.Error(),
+			nil,
+		)
+	}
+
+	payload := &ListSellersRequest{
+		Status:   r.URL.Query().Get("status"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	sellers, err := h.service.listSellers(ctx, payload)
+	if err != nil {
+		return sellerError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"sellers found",
+		sellers,
+	)
+}
+
+func (h *handler) getSellerHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	sellerID, err := uuid.Parse(chi.URLParam(r, "sellerID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	seller, err := h.service.getSeller(ctx, sellerID)
+	if err != nil {
+		return sellerError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"seller found",
+		seller,
+	)
+}
+
+func (h *handler) reviewSellerHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	sellerID, err := uuid.Parse(chi.URLParam(r, "sellerID"))
+	if err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	var payload *ReviewSellerRequest
+	defer r.Body.Close()
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	seller, err := h.service.reviewSeller(ctx, sellerID, payload)
+	if err != nil {
+		return sellerError(err)
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"seller status changed",
+		seller,
+	)
+}
+
+func sellerError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrSellerNotFound):
+		return servererrors.New(
+			http.StatusNotFound,
+			servererrors.ErrSellerNotFound.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrSellerAlreadyExists):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrSellerAlreadyExists.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidCredentials):
+		return servererrors.New(
+			http.StatusUnauthorized,
+			servererrors.ErrInvalidCredentials.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrSellerNotApproved):
+		return servererrors.New(
+			http.StatusForbidden,
+			servererrors.ErrSellerNotApproved.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidSellerStatusChange):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrInvalidSellerStatusChange.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
+
+// sellerIDFromContext returns the id of the seller authenticated by the auth
+// middleware.
+func sellerIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, servererrors.New(
+			http.StatusUnauthorized,
+			servererrors.ErrUnauthorized.Error(),
+			nil,
+		)
+	}
+
+	return uuid.Parse(claims.EntityID)
+}
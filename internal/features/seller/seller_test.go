@@ -0,0 +1,302 @@
+package seller
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/testutil"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func newTestRouter(t *testing.T) (*chi.Mux, *mockStore, *mockSessionService) {
+	t.Helper()
+
+	sellerStore := newMockSellerStore()
+	sessionService := &mockSessionService{}
+	sellerHandler := NewHandler(
+		NewService(sellerStore, sessionService),
+		testutil.HeaderAuthenticator{},
+	)
+
+	router := chi.NewRouter()
+	router.Route("/seller", sellerHandler.RegisterRoutes)
+	router.Route("/admin", sellerHandler.RegisterAdminRoutes)
+
+	return router, sellerStore, sessionService
+}
+
+func TestSellers(t *testing.T) {
+	router, sellerStore, sessionService := newTestRouter(t)
+	adminID := uuid.New()
+
+	registration := RegisterSellerRequest{
+		StoreName: "Pine Crafts",
+		FirstName: "Ada",
+		LastName:  "Okafor",
+		Email:     "ada@pinecrafts.test",
+		Password:  "secret",
+	}
+	login := LoginSellerRequest{Email: registration.Email, Password: registration.Password}
+
+	var seller Seller
+	t.Run("should register sellers as pending", func(t *testing.T) {
+		if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/register", "", uuid.Nil, registration, &seller); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		if seller.Status != StatusPending {
+			t.Errorf("expected status %q, got %q", StatusPending, seller.Status)
+		}
+
+		if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/register", "", uuid.Nil, registration, nil); code != http.StatusConflict {
+			t.Errorf("expected status code %d for a taken email, got %d", http.StatusConflict, code)
+		}
+
+		invalid := registration
+		invalid.Email = "not-an-email"
+		if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/register", "", uuid.Nil, invalid, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+	})
+
+	t.Run("should only log approved sellers in", func(t *testing.T) {
+		if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/login", "", uuid.Nil, login, nil); code != http.StatusForbidden {
+			t.Errorf("expected status code %d while pending, got %d", http.StatusForbidden, code)
+		}
+
+		wrong := login
+		wrong.Password = "wrong"
+		if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/login", "", uuid.Nil, wrong, nil); code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d for a wrong password, got %d", http.StatusUnauthorized, code)
+		}
+
+		path := "/admin/sellers/" + seller.SellerID.String() + "/status"
+		if code := testutil.ServeAs(t, router, http.MethodPut, path, auth.EntityTypeAdmin, adminID, ReviewSellerRequest{Status: StatusApproved}, nil); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/login", "", uuid.Nil, login, nil); code != http.StatusCreated {
+			t.Errorf("expected status code %d once approved, got %d", http.StatusCreated, code)
+		}
+
+		if len(sessionService.logins) != 1 || sessionService.logins[0].EntityType != auth.EntityTypeSeller {
+			t.Errorf("expected a seller session, got %+v", sessionService.logins)
+		}
+	})
+
+	t.Run("should keep the approval routes to admins", func(t *testing.T) {
+		path := "/admin/sellers/" + seller.SellerID.String() + "/status"
+		if code := testutil.ServeAs(t, router, http.MethodPut, path, auth.EntityTypeSeller, seller.SellerID, ReviewSellerRequest{Status: StatusApproved}, nil); code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, code)
+		}
+
+		if code := testutil.ServeAs(t, router, http.MethodGet, "/seller/dashboard", auth.EntityTypeUser, uuid.New(), nil, nil); code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+
+	t.Run("should show sellers their own dashboard", func(t *testing.T) {
+		sellerStore.products[seller.SellerID] = map[string]int64{"active": 3, "draft": 1}
+		sellerStore.products[uuid.New()] = map[string]int64{"active": 7}
+
+		var dashboard Dashboard
+		if code := testutil.ServeAs(t, router, http.MethodGet, "/seller/dashboard", auth.EntityTypeSeller, seller.SellerID, nil, &dashboard); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if dashboard.Seller.SellerID != seller.SellerID || dashboard.Products["active"] != 3 || dashboard.TotalOrders != 0 {
+			t.Errorf("expected the dashboard of the seller with 3 active products, got %+v", dashboard)
+		}
+	})
+
+	t.Run("should list the orders of the seller only", func(t *testing.T) {
+		for range 7 {
+			sellerStore.orders[seller.SellerID] = append(sellerStore.orders[seller.SellerID], &Order{
+				OrderID:  uuid.New(),
+				Lines:    []*OrderLine{{ProductID: uuid.New(), SKU: "MUG", Name: "Mug", Quantity: 1}},
+				PlacedAt: time.Now(),
+			})
+		}
+		sellerStore.orders[uuid.New()] = []*Order{{OrderID: uuid.New()}}
+
+		var dashboard Dashboard
+		if code := testutil.ServeAs(t, router, http.MethodGet, "/seller/dashboard", auth.EntityTypeSeller, seller.SellerID, nil, &dashboard); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if dashboard.TotalOrders != 7 || len(dashboard.RecentOrders) != recentOrdersCount {
+			t.Errorf("expected %d of 7 orders, got %d of %d", recentOrdersCount, len(dashboard.RecentOrders), dashboard.TotalOrders)
+		}
+
+		var orders ListOrdersResponse
+		if code := testutil.ServeAs(t, router, http.MethodGet, "/seller/orders?page=2&pageSize=5", auth.EntityTypeSeller, seller.SellerID, nil, &orders); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if orders.TotalCount != 7 || len(orders.Orders) != 2 {
+			t.Errorf("expected the last 2 of 7 orders, got %d of %d", len(orders.Orders), orders.TotalCount)
+		}
+	})
+
+	t.Run("should log suspended sellers out", func(t *testing.T) {
+		path := "/admin/sellers/" + seller.SellerID.String() + "/status"
+		if code := testutil.ServeAs(t, router, http.MethodPut, path, auth.EntityTypeAdmin, adminID, ReviewSellerRequest{Status: StatusRejected}, nil); code != http.StatusConflict {
+			t.Errorf("expected status code %d rejecting an approved seller, got %d", http.StatusConflict, code)
+		}
+
+		var suspended Seller
+		payload := ReviewSellerRequest{Status: StatusSuspended, Reason: "unpaid fees"}
+		if code := testutil.ServeAs(t, router, http.MethodPut, path, auth.EntityTypeAdmin, adminID, payload, &suspended); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if suspended.Status != StatusSuspended || suspended.StatusReason != "unpaid fees" {
+			t.Errorf("expected the seller suspended for unpaid fees, got %q for %q", suspended.Status, suspended.StatusReason)
+		}
+
+		if !slices.Contains(sessionService.revoked, seller.SellerID) {
+			t.Error("expected the sessions of the seller revoked")
+		}
+
+		if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/login", "", uuid.Nil, login, nil); code != http.StatusForbidden {
+			t.Errorf("expected status code %d while suspended, got %d", http.StatusForbidden, code)
+		}
+	})
+
+	t.Run("should list sellers by status", func(t *testing.T) {
+		other := registration
+		other.StoreName = "Oak Goods"
+		other.Email = "hello@oakgoods.test"
+		if code := testutil.ServeAs(t, router, http.MethodPost, "/seller/register", "", uuid.Nil, other, nil); code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, code)
+		}
+
+		var resp ListSellersResponse
+		if code := testutil.ServeAs(t, router, http.MethodGet, "/admin/sellers?status=pending", auth.EntityTypeAdmin, adminID, nil, &resp); code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, code)
+		}
+
+		if resp.TotalCount != 1 || resp.Sellers[0].StoreName != "Oak Goods" {
+			t.Errorf("expected only Oak Goods pending, got %d sellers", resp.TotalCount)
+		}
+
+		if code := testutil.ServeAs(t, router, http.MethodGet, "/admin/sellers?status=closed", auth.EntityTypeAdmin, adminID, nil, nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, code)
+		}
+	})
+}
+
+type mockStore struct {
+	sellers  []*Seller
+	products map[uuid.UUID]map[string]int64
+	// orders are kept newest first by seller
+	orders map[uuid.UUID][]*Order
+}
+
+func newMockSellerStore() *mockStore {
+	return &mockStore{
+		products: make(map[uuid.UUID]map[string]int64),
+		orders:   make(map[uuid.UUID][]*Order),
+	}
+}
+
+func (m *mockStore) create(ctx context.Context, seller *Seller) error {
+	for _, existing := range m.sellers {
+		if strings.EqualFold(existing.StoreName, seller.StoreName) {
+			return servererrors.ErrSellerAlreadyExists
+		}
+	}
+
+	seller.CreatedAt = time.Now()
+	seller.UpdatedAt = seller.CreatedAt
+	m.sellers = append(m.sellers, seller)
+	return nil
+}
+
+func (m *mockStore) findByEmail(ctx context.Context, email string) (*Seller, error) {
+	for _, seller := range m.sellers {
+		if strings.EqualFold(seller.Email, email) {
+			return seller, nil
+		}
+	}
+
+	return new(Seller), nil
+}
+
+func (m *mockStore) findByID(ctx context.Context, sellerID uuid.UUID) (*Seller, error) {
+	for _, seller := range m.sellers {
+		if seller.SellerID == sellerID {
+			return seller, nil
+		}
+	}
+
+	return new(Seller), nil
+}
+
+func (m *mockStore) list(ctx context.Context, status string, limit int64, offset int64) ([]*Seller, int64, error) {
+	matches := []*Seller{}
+	for _, seller := range m.sellers {
+		if status == "" || seller.Status == status {
+			matches = append(matches, seller)
+		}
+	}
+
+	totalCount := int64(len(matches))
+	return matches[min(offset, totalCount):min(offset+limit, totalCount)], totalCount, nil
+}
+
+func (m *mockStore) updateStatus(ctx context.Context, sellerID uuid.UUID, status string, reason string) error {
+	seller, _ := m.findByID(ctx, sellerID)
+	now := time.Now()
+	seller.Status = status
+	seller.StatusReason = reason
+	seller.ReviewedAt = &now
+	return nil
+}
+
+func (m *mockStore) productCounts(ctx context.Context, sellerID uuid.UUID) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	for status, count := range m.products[sellerID] {
+		counts[status] = count
+	}
+
+	return counts, nil
+}
+
+func (m *mockStore) findOrders(ctx context.Context, sellerID uuid.UUID, limit int64, offset int64) ([]*Order, int64, error) {
+	orders := m.orders[sellerID]
+	totalCount := int64(len(orders))
+	return orders[min(offset, totalCount):min(offset+limit, totalCount)], totalCount, nil
+}
+
+type mockSessionService struct {
+	logins  []*interfaces.LoginEntityRequest
+	revoked []uuid.UUID
+}
+
+func (m *mockSessionService) LoginEntity(ctx context.Context, payload *interfaces.LoginEntityRequest) (*interfaces.LoginEntityCookiesResponse, error) {
+	m.logins = append(m.logins, payload)
+
+	expires := time.Now().Add(time.Hour)
+	return &interfaces.LoginEntityCookiesResponse{
+		AccessToken:  interfaces.TokenDetails{Value: "access", Expires: expires},
+		RefreshToken: interfaces.TokenDetails{Value: "refresh", Expires: expires},
+	}, nil
+}
+
+func (m *mockSessionService) LogoutEntity(ctx context.Context, refreshToken string, accessToken string) error {
+	return nil
+}
+
+func (m *mockSessionService) RevokeAllEntitySessions(ctx context.Context, entityID uuid.UUID, entityType string) error {
+	m.revoked = append(m.revoked, entityID)
+	return nil
+}
@@ -0,0 +1,233 @@
+package seller
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+type sellerStorer interface {
+	create(ctx context.Context, seller *Seller) error
+	findByEmail(ctx context.Context, email string) (*Seller, error)
+	findByID(ctx context.Context, sellerID uuid.UUID) (*Seller, error)
+	list(ctx context.Context, status string, limit int64, offset int64) ([]*Seller, int64, error)
+	updateStatus(ctx context.Context, sellerID uuid.UUID, status string, reason string) error
+	productCounts(ctx context.Context, sellerID uuid.UUID) (map[string]int64, error)
+	findOrders(ctx context.Context, sellerID uuid.UUID, limit int64, offset int64) ([]*Order, int64, error)
+}
+
+type sessionServicer interface {
+	LoginEntity(ctx context.Context, payload *interfaces.LoginEntityRequest) (*interfaces.LoginEntityCookiesResponse, error)
+	LogoutEntity(ctx context.Context, refreshToken string, accessToken string) error
+	RevokeAllEntitySessions(ctx context.Context, entityID uuid.UUID, entityType string) error
+}
+
+// recentOrdersCount is how many orders the dashboard shows.
+const recentOrdersCount = 5
+
+// statusChanges lists the statuses an admin can move a seller to from each
+// status.
+var statusChanges = map[string][]string{
+	StatusPending:   {StatusApproved, StatusRejected},
+	StatusApproved:  {StatusSuspended},
+	StatusSuspended: {StatusApproved},
+}
+
+type service struct {
+	sellerStore    sellerStorer
+	sessionService sessionServicer
+}
+
+func NewService(sellerStore sellerStorer, sessionService sessionServicer) *service {
+	return &service{
+		sellerStore:    sellerStore,
+		sessionService: sessionService,
+	}
+}
+
+// registerSeller creates a pending seller, it can log in once an admin has
+// approved it.
+func (s *service) registerSeller(ctx context.Context, payload *RegisterSellerRequest) (*Seller, error) {
+	email := strings.TrimSpace(payload.Email)
+
+	existing, err := s.sellerStore.findByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.SellerID != uuid.Nil {
+		return nil, servererrors.ErrSellerAlreadyExists
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	seller := &Seller{
+		SellerID:       uuid.New(),
+		StoreName:      strings.TrimSpace(payload.StoreName),
+		FirstName:      strings.TrimSpace(payload.FirstName),
+		LastName:       strings.TrimSpace(payload.LastName),
+		Email:          email,
+		HashedPassword: hashedPassword,
+		Status:         StatusPending,
+	}
+
+	if err = s.sellerStore.create(ctx, seller); err != nil {
+		return nil, err
+	}
+
+	return s.sellerStore.findByID(ctx, seller.SellerID)
+}
+
+// loginSeller starts a session for an approved seller. The password is
+// checked first so the status of an account is only told to its owner.
+func (s *service) loginSeller(ctx context.Context, payload *LoginSellerRequest) (*LoginSellerCookiesResponse, error) {
+	seller, err := s.sellerStore.findByEmail(ctx, strings.TrimSpace(payload.Email))
+	if err != nil {
+		return nil, err
+	}
+
+	if seller.SellerID == uuid.Nil || !seller.comparePassword(payload.Password) {
+		return nil, servererrors.ErrInvalidCredentials
+	}
+
+	if seller.Status != StatusApproved {
+		return nil, servererrors.ErrSellerNotApproved
+	}
+
+	resp, err := s.sessionService.LoginEntity(
+		ctx,
+		&interfaces.LoginEntityRequest{
+			EntityID:    seller.SellerID,
+			EntityType:  auth.EntityTypeSeller,
+			UserAgent:   payload.UserAgent,
+			ClientIP:    payload.ClientIP,
+			AuthMethods: []string{auth.AuthMethodPassword},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginSellerCookiesResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+	}, nil
+}
+
+func (s *service) logoutSeller(ctx context.Context, refreshToken string, accessToken string) error {
+	return s.sessionService.LogoutEntity(ctx, refreshToken, accessToken)
+}
+
+func (s *service) getSeller(ctx context.Context, sellerID uuid.UUID) (*Seller, error) {
+	seller, err := s.sellerStore.findByID(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if seller.SellerID == uuid.Nil {
+		return nil, servererrors.ErrSellerNotFound
+	}
+
+	return seller, nil
+}
+
+func (s *service) listSellers(ctx context.Context, payload *ListSellersRequest) (*ListSellersResponse, error) {
+	sellers, totalCount, err := s.sellerStore.list(
+		ctx,
+		payload.Status,
+		payload.PageSize,
+		(payload.Page-1)*payload.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListSellersResponse{
+		Sellers:    sellers,
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// reviewSeller moves a seller to a new status. A seller that is rejected or
+// suspended is logged out of every session.
+func (s *service) reviewSeller(ctx context.Context, sellerID uuid.UUID, payload *ReviewSellerRequest) (*Seller, error) {
+	seller, err := s.getSeller(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(statusChanges[seller.Status], payload.Status) {
+		return nil, servererrors.ErrInvalidSellerStatusChange
+	}
+
+	reason := strings.TrimSpace(payload.Reason)
+	if payload.Status == StatusApproved {
+		reason = ""
+	}
+
+	if err = s.sellerStore.updateStatus(ctx, sellerID, payload.Status, reason); err != nil {
+		return nil, err
+	}
+
+	if payload.Status != StatusApproved {
+		err = s.sessionService.RevokeAllEntitySessions(ctx, sellerID, auth.EntityTypeSeller)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sellerStore.findByID(ctx, sellerID)
+}
+
+func (s *service) dashboard(ctx context.Context, sellerID uuid.UUID) (*Dashboard, error) {
+	seller, err := s.getSeller(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.sellerStore.productCounts(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, totalOrders, err := s.sellerStore.findOrders(ctx, sellerID, recentOrdersCount, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dashboard{
+		Seller:       seller,
+		Products:     products,
+		TotalOrders:  totalOrders,
+		RecentOrders: orders,
+	}, nil
+}
+
+func (s *service) listOrders(ctx context.Context, sellerID uuid.UUID, payload *ListOrdersRequest) (*ListOrdersResponse, error) {
+	orders, totalCount, err := s.sellerStore.findOrders(
+		ctx,
+		sellerID,
+		payload.PageSize,
+		(payload.Page-1)*payload.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListOrdersResponse{
+		Orders:     orders,
+		Page:       payload.Page,
+		PageSize:   payload.PageSize,
+		TotalCount: totalCount,
+	}, nil
+}
@@ -0,0 +1,320 @@
+package seller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	sellerFields = "seller_id, store_name, first_name, last_name, email, hashed_password, status, status_reason, reviewed_at, created_at, updated_at"
+
+	// postgres error codes
+	uniqueViolation = "23505"
+)
+
+type store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *store {
+	return &store{
+		db: db,
+	}
+}
+
+func (s *store) create(ctx context.Context, seller *Seller) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO sellers(seller_id, store_name, first_name, last_name, email, hashed_password, status) VALUES($1, $2, $3, $4, $5, $6, $7)",
+		seller.SellerID,
+		seller.StoreName,
+		seller.FirstName,
+		seller.LastName,
+		seller.Email,
+		seller.HashedPassword,
+		seller.Status,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return servererrors.ErrSellerAlreadyExists
+		}
+
+		return fmt.Errorf(
+			"failed to insert new seller in seller store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// findByEmail returns the seller with an email, a zero Seller when there is
+// none.
+func (s *store) findByEmail(ctx context.Context, email string) (*Seller, error) {
+	sellers, err := s.getSellersWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM sellers WHERE LOWER(email) = LOWER($1)", sellerFields),
+		email,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find seller by email in seller store: %w",
+			err,
+		)
+	}
+
+	if len(sellers) == 0 {
+		return new(Seller), nil
+	}
+
+	return sellers[0], nil
+}
+
+// findByID returns a seller, a zero Seller when there is none.
+func (s *store) findByID(ctx context.Context, sellerID uuid.UUID) (*Seller, error) {
+	sellers, err := s.getSellersWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM sellers WHERE seller_id = $1", sellerFields),
+		sellerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find seller by id in seller store: %w",
+			err,
+		)
+	}
+
+	if len(sellers) == 0 {
+		return new(Seller), nil
+	}
+
+	return sellers[0], nil
+}
+
+// list returns one page of sellers, oldest first so the sellers waiting the
+// longest for approval come up first, along with the number of sellers
+// across all pages. An empty status lists every seller.
+func (s *store) list(ctx context.Context, status string, limit int64, offset int64) ([]*Seller, int64, error) {
+	var totalCount int64
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM sellers WHERE $1 = '' OR status = $1",
+		status,
+	).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to count sellers in seller store: %w",
+			err,
+		)
+	}
+
+	sellers, err := s.getSellersWithContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM sellers WHERE $1 = '' OR status = $1 ORDER BY created_at ASC, seller_id ASC LIMIT $2 OFFSET $3", sellerFields),
+		status,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to list sellers in seller store: %w",
+			err,
+		)
+	}
+
+	return sellers, totalCount, nil
+}
+
+func (s *store) updateStatus(ctx context.Context, sellerID uuid.UUID, status string, reason string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE sellers SET status = $1, status_reason = $2, reviewed_at = NOW(), updated_at = NOW() WHERE seller_id = $3",
+		status,
+		reason,
+		sellerID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update seller status in seller store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// productCounts counts the products of a seller by status.
+func (s *store) productCounts(ctx context.Context, sellerID uuid.UUID) (map[string]int64, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT status, COUNT(*) FROM products WHERE seller_id = $1 GROUP BY status",
+		sellerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to count seller products in seller store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var status string
+		var count int64
+		if err = rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into product count in seller store: %w",
+				err,
+			)
+		}
+
+		counts[status] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in seller store: %w",
+			err,
+		)
+	}
+
+	return counts, nil
+}
+
+// findOrders returns one page of the committed checkouts holding products of
+// the seller, newest first, with only the lines of those products, along with
+// the number of such checkouts across all pages.
+func (s *store) findOrders(ctx context.Context, sellerID uuid.UUID, limit int64, offset int64) ([]*Order, int64, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`WITH orders AS (
+			SELECT r.reservation_id, r.checkout_id, r.updated_at, COUNT(*) OVER() AS total_count
+			FROM stock_reservations r
+			WHERE r.status = 'committed' AND EXISTS (
+				SELECT 1 FROM stock_reservation_lines l
+				JOIN product_variants v ON v.variant_id = l.variant_id
+				JOIN products p ON p.product_id = v.product_id
+				WHERE l.reservation_id = r.reservation_id AND p.seller_id = $1
+			)
+			ORDER BY r.updated_at DESC, r.reservation_id ASC
+			LIMIT $2 OFFSET $3
+		)
+		SELECT o.reservation_id, o.checkout_id, o.updated_at, o.total_count, p.product_id, v.sku, p.name, l.quantity
+		FROM orders o
+		JOIN stock_reservation_lines l ON l.reservation_id = o.reservation_id
+		JOIN product_variants v ON v.variant_id = l.variant_id
+		JOIN products p ON p.product_id = v.product_id
+		WHERE p.seller_id = $1
+		ORDER BY o.updated_at DESC, o.reservation_id ASC, p.name ASC, v.sku ASC`,
+		sellerID,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to find seller orders in seller store: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	var totalCount int64
+	var reservationID uuid.UUID
+	orders := []*Order{}
+	for rows.Next() {
+		var rowReservationID uuid.UUID
+		order := new(Order)
+		line := new(OrderLine)
+		if err = rows.Scan(
+			&rowReservationID,
+			&order.OrderID,
+			&order.PlacedAt,
+			&totalCount,
+			&line.ProductID,
+			&line.SKU,
+			&line.Name,
+			&line.Quantity,
+		); err != nil {
+			return nil, 0, fmt.Errorf(
+				"failed to scan row into order line in seller store: %w",
+				err,
+			)
+		}
+
+		// rows of one reservation come together, each starts a new order
+		if len(orders) == 0 || rowReservationID != reservationID {
+			reservationID = rowReservationID
+			orders = append(orders, order)
+		}
+
+		last := orders[len(orders)-1]
+		last.Lines = append(last.Lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf(
+			"failed to iterate rows in seller store: %w",
+			err,
+		)
+	}
+
+	return orders, totalCount, nil
+}
+
+func (s *store) getSellersWithContext(ctx context.Context, query string, args ...any) ([]*Seller, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query db in seller store getSellersWithContext: %w",
+			err,
+		)
+	}
+	defer rows.Close()
+
+	sellers := []*Seller{}
+	for rows.Next() {
+		seller := new(Seller)
+		err = rows.Scan(
+			&seller.SellerID,
+			&seller.StoreName,
+			&seller.FirstName,
+			&seller.LastName,
+			&seller.Email,
+			&seller.HashedPassword,
+			&seller.Status,
+			&seller.StatusReason,
+			&seller.ReviewedAt,
+			&seller.CreatedAt,
+			&seller.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into seller in seller store: %w",
+				err,
+			)
+		}
+
+		sellers = append(sellers, seller)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate rows in seller store: %w",
+			err,
+		)
+	}
+
+	return sellers, nil
+}
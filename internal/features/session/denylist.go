@@ -0,0 +1,187 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/google/uuid"
+)
+
+type denylistStorer interface {
+	createRevokedToken(ctx context.Context, token *RevokedToken) error
+	upsertEntityRevocation(ctx context.Context, revocation *EntityRevocation) error
+	findActiveRevocations(ctx context.Context, now time.Time) ([]*RevokedToken, []*EntityRevocation, error)
+	deleteExpiredRevocations(ctx context.Context, now time.Time) error
+}
+
+// Denylist keeps track of access tokens that were revoked before their expiry.
+// Postgres is the source of truth, every instance keeps an in-memory copy of
+// the non expired entries so that checking a token never hits the database.
+type Denylist struct {
+	store denylistStorer
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time         // jti -> token expiry
+	entities map[string]*EntityRevocation // entity key -> revocation
+}
+
+func NewDenylist(store denylistStorer) *Denylist {
+	return &Denylist{
+		store:    store,
+		tokens:   make(map[string]time.Time),
+		entities: make(map[string]*EntityRevocation),
+	}
+}
+
+// Load merges the active revocations from the store into the in-memory
+// cache and drops the cached ones that expired. Entries cached since the
+// store was read, by RevokeToken or RevokeEntity, are kept.
+func (d *Denylist) Load(ctx context.Context) error {
+	now := time.Now()
+	tokens, entities, err := d.store.findActiveRevocations(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to load token denylist: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for tokenID, expiresAt := range d.tokens {
+		if !expiresAt.After(now) {
+			delete(d.tokens, tokenID)
+		}
+	}
+
+	for key, revocation := range d.entities {
+		if !revocation.ExpiresAt.After(now) {
+			delete(d.entities, key)
+		}
+	}
+
+	for _, token := range tokens {
+		if expiresAt, ok := d.tokens[token.TokenID]; !ok || token.ExpiresAt.After(expiresAt) {
+			d.tokens[token.TokenID] = token.ExpiresAt
+		}
+	}
+
+	for _, revocation := range entities {
+		key := entityKey(revocation.EntityType, revocation.EntityID.String())
+		if cached, ok := d.entities[key]; !ok || revocation.RevokedBefore.After(cached.RevokedBefore) {
+			d.entities[key] = revocation
+		}
+	}
+
+	return nil
+}
+
+// Run purges expired revocations from the store and re-syncs the cache every
+// interval so that revocations made by other instances are picked up. It
+// blocks until ctx is done.
+func (d *Denylist) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.store.deleteExpiredRevocations(ctx, time.Now()); err != nil {
+				log.Println(err)
+			}
+
+			if err := d.Load(ctx); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// RevokeToken adds a single access token to the denylist.
+func (d *Denylist) RevokeToken(ctx context.Context, claims *auth.TokenClaims) error {
+	if claims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	if claims.ExpiresAt.Time.Before(time.Now()) {
+		return nil // already dead
+	}
+
+	entityID, err := uuid.Parse(claims.EntityID)
+	if err != nil {
+		return err
+	}
+
+	err = d.store.createRevokedToken(ctx, &RevokedToken{
+		TokenID:    claims.ID,
+		EntityID:   entityID,
+		EntityType: claims.EntityType,
+		ExpiresAt:  claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.tokens[claims.ID] = claims.ExpiresAt.Time
+	d.mu.Unlock()
+
+	return nil
+}
+
+// RevokeEntity rejects every access token issued to the entity up to now. The
+// entry is kept for tokenLifetime, after which no such token can be valid.
+func (d *Denylist) RevokeEntity(ctx context.Context, entityID uuid.UUID, entityType string, tokenLifetime time.Duration) error {
+	// jwt issued at dates have a precision of one second, so the cut off is
+	// truncated too. Tokens issued in the same second are revoked as well.
+	now := time.Now().Truncate(time.Second)
+
+	revocation := &EntityRevocation{
+		EntityID:      entityID,
+		EntityType:    entityType,
+		RevokedBefore: now,
+		ExpiresAt:     now.Add(tokenLifetime + time.Second),
+	}
+
+	if err := d.store.upsertEntityRevocation(ctx, revocation); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.entities[entityKey(entityType, entityID.String())] = revocation
+	d.mu.Unlock()
+
+	return nil
+}
+
+// IsRevoked reports whether the access token described by claims has been
+// revoked, either on its own or as part of an entity wide revocation.
+func (d *Denylist) IsRevoked(claims *auth.TokenClaims) bool {
+	now := time.Now()
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if expiresAt, ok := d.tokens[claims.ID]; ok && expiresAt.After(now) {
+		return true
+	}
+
+	revocation, ok := d.entities[entityKey(claims.EntityType, claims.EntityID)]
+	if !ok || revocation.ExpiresAt.Before(now) {
+		return false
+	}
+
+	if claims.IssuedAt == nil {
+		return true
+	}
+
+	return !claims.IssuedAt.Time.After(revocation.RevokedBefore)
+}
+
+func entityKey(entityType, entityID string) string {
+	return fmt.Sprintf("%s_%s", entityType, entityID)
+}
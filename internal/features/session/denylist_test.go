@@ -0,0 +1,142 @@
+package session
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestDenylist(t *testing.T) {
+	store := newMockDenylistStore()
+	denylist := NewDenylist(store)
+	ctx := context.Background()
+
+	entityID := uuid.New()
+	issuedAt := time.Now().Add(-time.Minute)
+
+	newClaims := func(issuedAt time.Time) *auth.TokenClaims {
+		return &auth.TokenClaims{
+			EntityID:   entityID.String(),
+			EntityType: "user",
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        uuid.New().String(),
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				ExpiresAt: jwt.NewNumericDate(issuedAt.Add(time.Hour)),
+			},
+		}
+	}
+
+	revoked := newClaims(issuedAt)
+	other := newClaims(issuedAt)
+
+	if err := denylist.RevokeToken(ctx, revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	if !denylist.IsRevoked(revoked) {
+		t.Errorf("expected revoked token to be denied")
+	}
+
+	if denylist.IsRevoked(other) {
+		t.Errorf("expected other token to be allowed")
+	}
+
+	if err := denylist.RevokeEntity(ctx, entityID, "user", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if !denylist.IsRevoked(other) {
+		t.Errorf("expected token issued before entity revocation to be denied")
+	}
+
+	if denylist.IsRevoked(newClaims(time.Now().Add(2 * time.Second))) {
+		t.Errorf("expected token issued after entity revocation to be allowed")
+	}
+
+	// a fresh instance must see the revocations through the store
+	reloaded := NewDenylist(store)
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reloaded.IsRevoked(revoked) || !reloaded.IsRevoked(other) {
+		t.Errorf("expected revocations to survive a reload")
+	}
+}
+
+func TestDenylistLoadKeepsNewerRevocations(t *testing.T) {
+	store := newMockDenylistStore()
+	denylist := NewDenylist(store)
+	ctx := context.Background()
+
+	stale := &RevokedToken{TokenID: uuid.New().String(), ExpiresAt: time.Now().Add(-time.Minute)}
+	denylist.tokens[stale.TokenID] = stale.ExpiresAt
+
+	// a revocation made on this instance after the store was read but before
+	// the cache was refreshed
+	claims := &auth.TokenClaims{
+		EntityID:   uuid.New().String(),
+		EntityType: "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	store.afterFind = func() {
+		if err := denylist.RevokeToken(ctx, claims); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := denylist.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !denylist.IsRevoked(claims) {
+		t.Errorf("expected the token revoked during the load to stay denied")
+	}
+
+	if _, ok := denylist.tokens[stale.TokenID]; ok {
+		t.Errorf("expected the expired token to be dropped from the cache")
+	}
+}
+
+type mockDenylistStore struct {
+	tokens   []*RevokedToken
+	entities []*EntityRevocation
+	// afterFind runs once the active revocations are read
+	afterFind func()
+}
+
+func newMockDenylistStore() *mockDenylistStore {
+	return &mockDenylistStore{}
+}
+
+func (m *mockDenylistStore) createRevokedToken(ctx context.Context, token *RevokedToken) error {
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+func (m *mockDenylistStore) upsertEntityRevocation(ctx context.Context, revocation *EntityRevocation) error {
+	m.entities = append(m.entities, revocation)
+	return nil
+}
+
+func (m *mockDenylistStore) findActiveRevocations(ctx context.Context, now time.Time) ([]*RevokedToken, []*EntityRevocation, error) {
+	tokens, entities := slices.Clone(m.tokens), slices.Clone(m.entities)
+	if m.afterFind != nil {
+		m.afterFind()
+	}
+
+	return tokens, entities, nil
+}
+
+func (m *mockDenylistStore) deleteExpiredRevocations(ctx context.Context, now time.Time) error {
+	return nil
+}
@@ -16,6 +16,29 @@ type Session struct {
 	UserAgent    string    `json:"user_agent"`
 	ClientIP     string    `json:"client_ip"`
 	LastUsedAt   time.Time `json:"last_used_at"`
+	StartedAt    time.Time `json:"started_at"` // login time, carried over on rotation
+	AuthMethods  []string  `json:"auth_methods"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
+
+// RevokedToken is a single access token (identified by its jti) that must be
+// rejected until it expires on its own.
+type RevokedToken struct {
+	TokenID    string    `json:"token_id"`
+	EntityID   uuid.UUID `json:"entity_id"`
+	EntityType string    `json:"entity_type"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EntityRevocation rejects every access token of an entity that was issued at
+// or before RevokedBefore. The record is useless once ExpiresAt has passed as
+// all tokens covered by it have expired by then.
+type EntityRevocation struct {
+	EntityID      uuid.UUID `json:"entity_id"`
+	EntityType    string    `json:"entity_type"`
+	RevokedBefore time.Time `json:"revoked_before"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
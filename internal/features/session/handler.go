@@ -80,6 +80,18 @@ func (h *handler) renewTokensHandler(w http.ResponseWriter, r *http.Request) err
 				servererrors.ErrSessionNotFound.Error(),
 				nil,
 			)
+		case errors.Is(err, servererrors.ErrSessionIdleTimeout):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrSessionIdleTimeout.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrSessionMaxAgeExceeded):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrSessionMaxAgeExceeded.Error(),
+				nil,
+			)
 		default:
 			return err
 		}
@@ -17,24 +17,33 @@ type sessionStorer interface {
 	deleteByID(ctx context.Context, sessionID uuid.UUID) error
 	deleteAllByEntityID(ctx context.Context, entityID uuid.UUID) error
 	findByID(ctx context.Context, sessionID uuid.UUID) (*Session, error)
+	touch(ctx context.Context, sessionID uuid.UUID) error
 }
 
 type tokenServicer interface {
-	GenerateToken(isRefreshToken bool, entityID string, entityType string) (string, *auth.TokenClaims, error)
+	GenerateToken(isRefreshToken bool, entityID string, entityType string, authentication auth.Authentication) (string, *auth.TokenClaims, error)
 	ValidateAccessToken(tokenStr string) (isValid bool, claims *auth.TokenClaims, err error)
 	ValidateRefreshToken(tokenStr string) (isValid bool, claims *auth.TokenClaims, err error)
-	RefreshTokens(entityID string, entityType string) (*auth.RefreshTokens, error)
+	RefreshTokens(entityID string, entityType string, authentication auth.Authentication) (*auth.RefreshTokens, error)
+	LifetimesFor(entityType string) auth.TokenLifetimes
+}
+
+type tokenRevoker interface {
+	RevokeToken(ctx context.Context, claims *auth.TokenClaims) error
+	RevokeEntity(ctx context.Context, entityID uuid.UUID, entityType string, tokenLifetime time.Duration) error
 }
 
 type service struct {
 	sessionStore sessionStorer
 	tokenService tokenServicer
+	denylist     tokenRevoker
 }
 
-func NewService(sessionStore sessionStorer, tokenService tokenServicer) *service {
+func NewService(sessionStore sessionStorer, tokenService tokenServicer, denylist tokenRevoker) *service {
 	return &service{
 		sessionStore: sessionStore,
 		tokenService: tokenService,
+		denylist:     denylist,
 	}
 }
 
@@ -83,6 +92,17 @@ func (s *service) renewTokens(ctx context.Context, payload *RenewTokensRequest)
 		return nil, servererrors.ErrInvalidRefreshToken
 	}
 
+	// sessions end after a period of inactivity and after a maximum age,
+	// whichever comes first
+	lifetimes := s.tokenService.LifetimesFor(session.EntityType)
+	if err := checkSessionAge(session, lifetimes, time.Now()); err != nil {
+		if err := s.sessionStore.deleteByID(ctx, sessionID); err != nil {
+			return nil, err
+		}
+
+		return nil, err
+	}
+
 	// if jwt and session is valid but the info in the payload of req and jwt is
 	// invalid, then its compromised. Delete that session
 	if session.ExpiresAt.Before(time.Now()) ||
@@ -108,6 +128,10 @@ func (s *service) renewTokens(ctx context.Context, payload *RenewTokensRequest)
 	refreshTokens, err := s.tokenService.RefreshTokens(
 		session.EntityID.String(),
 		session.EntityType,
+		auth.Authentication{
+			Time:    session.StartedAt,
+			Methods: session.AuthMethods,
+		},
 	)
 	if err != nil {
 		return nil, err
@@ -128,6 +152,8 @@ func (s *service) renewTokens(ctx context.Context, payload *RenewTokensRequest)
 			ExpiresAt:    refreshTokens.NewRefreshTokenClaims.ExpiresAt.Time,
 			UserAgent:    payload.UserAgent,
 			ClientIP:     payload.ClientIP,
+			StartedAt:    session.StartedAt,
+			AuthMethods:  session.AuthMethods,
 		},
 	)
 	if err != nil {
@@ -147,11 +173,16 @@ func (s *service) renewTokens(ctx context.Context, payload *RenewTokensRequest)
 }
 
 func (s *service) LoginEntity(ctx context.Context, payload *interfaces.LoginEntityRequest) (*interfaces.LoginEntityCookiesResponse, error) {
+	authentication := auth.Authentication{
+		Time:    time.Now(),
+		Methods: payload.AuthMethods,
+	}
 
 	accessToken, accessClaims, err := s.tokenService.GenerateToken(
 		false,
 		payload.EntityID.String(),
 		payload.EntityType,
+		authentication,
 	)
 	if err != nil {
 		return nil, err
@@ -166,8 +197,20 @@ func (s *service) LoginEntity(ctx context.Context, payload *interfaces.LoginEnti
 	}
 
 	if existingSession != nil {
+		isReusable := existingSession.ExpiresAt.After(time.Now()) &&
+			!existingSession.IsRevoked &&
+			checkSessionAge(
+				existingSession,
+				s.tokenService.LifetimesFor(existingSession.EntityType),
+				time.Now(),
+			) == nil
+
 		switch {
-		case existingSession.ExpiresAt.After(time.Now()) && !existingSession.IsRevoked:
+		case existingSession.SessionID != uuid.Nil && isReusable:
+			if err := s.sessionStore.touch(ctx, existingSession.SessionID); err != nil {
+				return nil, err
+			}
+
 			return &interfaces.LoginEntityCookiesResponse{
 				AccessToken: interfaces.TokenDetails{
 					Value:   accessToken,
@@ -188,6 +231,7 @@ func (s *service) LoginEntity(ctx context.Context, payload *interfaces.LoginEnti
 		true,
 		payload.EntityID.String(),
 		payload.EntityType,
+		authentication,
 	)
 	if err != nil {
 		return nil, err
@@ -208,6 +252,8 @@ func (s *service) LoginEntity(ctx context.Context, payload *interfaces.LoginEnti
 			ExpiresAt:    refreshClaims.RegisteredClaims.ExpiresAt.Time,
 			UserAgent:    payload.UserAgent,
 			ClientIP:     payload.ClientIP,
+			StartedAt:    authentication.Time,
+			AuthMethods:  authentication.Methods,
 		},
 	)
 	if err != nil {
@@ -225,7 +271,17 @@ func (s *service) LoginEntity(ctx context.Context, payload *interfaces.LoginEnti
 	}, nil
 }
 
-func (s *service) LogoutEntity(ctx context.Context, refreshToken string) error {
+// LogoutEntity deletes the session tied to refreshToken and revokes the access
+// token issued alongside it. accessToken may be empty or already expired.
+func (s *service) LogoutEntity(ctx context.Context, refreshToken string, accessToken string) error {
+	if accessToken != "" {
+		isValid, accessTokenClaims, err := s.tokenService.ValidateAccessToken(accessToken)
+		if err == nil && isValid {
+			if err := s.denylist.RevokeToken(ctx, accessTokenClaims); err != nil {
+				return err
+			}
+		}
+	}
 
 	isValid, refreshTokenClaims, err := s.tokenService.ValidateRefreshToken(refreshToken)
 	if err != nil {
@@ -266,3 +322,57 @@ func (s *service) LogoutEntity(ctx context.Context, refreshToken string) error {
 
 	return nil
 }
+
+// RevokeAllEntitySessions logs the entity out everywhere: every session is
+// deleted and every access token issued so far is revoked.
+func (s *service) RevokeAllEntitySessions(ctx context.Context, entityID uuid.UUID, entityType string) error {
+	if err := s.sessionStore.deleteAllByEntityID(ctx, entityID); err != nil {
+		return err
+	}
+
+	return s.denylist.RevokeEntity(
+		ctx,
+		entityID,
+		entityType,
+		s.tokenService.LifetimesFor(entityType).AccessTokenExpiry(),
+	)
+}
+
+// IssueElevatedAccessToken issues an access token whose auth_time is now, for
+// an entity that just re-authenticated within its current session. The
+// refresh session is left untouched.
+func (s *service) IssueElevatedAccessToken(ctx context.Context, entityID uuid.UUID, entityType string, authMethods []string) (*interfaces.TokenDetails, error) {
+	accessToken, accessClaims, err := s.tokenService.GenerateToken(
+		false,
+		entityID.String(),
+		entityType,
+		auth.Authentication{
+			Time:    time.Now(),
+			Methods: authMethods,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &interfaces.TokenDetails{
+		Value:   accessToken,
+		Expires: accessClaims.ExpiresAt.Time,
+	}, nil
+}
+
+// checkSessionAge enforces the idle timeout and the absolute maximum age of
+// the entity type on a session.
+func checkSessionAge(session *Session, lifetimes auth.TokenLifetimes, now time.Time) error {
+	if idleTimeout := lifetimes.SessionIdleTimeout(); idleTimeout > 0 &&
+		now.Sub(session.LastUsedAt) > idleTimeout {
+		return servererrors.ErrSessionIdleTimeout
+	}
+
+	if maxAge := lifetimes.SessionMaxAge(); maxAge > 0 &&
+		now.Sub(session.StartedAt) > maxAge {
+		return servererrors.ErrSessionMaxAgeExceeded
+	}
+
+	return nil
+}
@@ -5,12 +5,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const (
-	sessionFields = "session_id, entity_id, entity_type, refresh_token, expires_at, is_revoked, user_agent, client_ip, last_used_at, created_at, updated_at"
+	sessionFields = "session_id, entity_id, entity_type, refresh_token, expires_at, is_revoked, user_agent, client_ip, last_used_at, started_at, auth_methods, created_at, updated_at"
 )
 
 type store struct {
@@ -26,7 +28,7 @@ func NewStore(db *sql.DB) *store {
 func (s *store) create(ctx context.Context, session *Session) error {
 	_, err := s.db.ExecContext(
 		ctx,
-		"INSERT INTO sessions(session_id, entity_id, entity_type, refresh_token, expires_at, user_agent, client_ip) VALUES($1, $2, $3, $4, $5, $6, $7)",
+		"INSERT INTO sessions(session_id, entity_id, entity_type, refresh_token, expires_at, user_agent, client_ip, started_at, auth_methods, last_used_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())",
 		session.SessionID,
 		session.EntityID,
 		session.EntityType,
@@ -34,6 +36,8 @@ func (s *store) create(ctx context.Context, session *Session) error {
 		session.ExpiresAt,
 		session.UserAgent,
 		session.ClientIP,
+		session.StartedAt,
+		pq.Array(session.AuthMethods),
 	)
 	if err != nil {
 		return fmt.Errorf(
@@ -103,6 +107,22 @@ func (s *store) findByEntityIDAndUserAgent(ctx context.Context, entityID uuid.UU
 	return session, nil
 }
 
+func (s *store) touch(ctx context.Context, sessionID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE sessions SET last_used_at = NOW(), updated_at = NOW() WHERE session_id = $1",
+		sessionID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to touch session in session store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
 func (s *store) deleteAllByEntityID(ctx context.Context, entityID uuid.UUID) error {
 	return deleteSessionWithContext(
 		ctx,
@@ -121,6 +141,132 @@ func (s *store) deleteByID(ctx context.Context, sessionID uuid.UUID) error {
 	)
 }
 
+func (s *store) createRevokedToken(ctx context.Context, token *RevokedToken) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO revoked_access_tokens(token_id, entity_id, entity_type, expires_at) VALUES($1, $2, $3, $4) ON CONFLICT (token_id) DO NOTHING",
+		token.TokenID,
+		token.EntityID,
+		token.EntityType,
+		token.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to insert revoked access token in session store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) upsertEntityRevocation(ctx context.Context, revocation *EntityRevocation) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO entity_token_revocations(entity_id, entity_type, revoked_before, expires_at) VALUES($1, $2, $3, $4)
+		ON CONFLICT (entity_id, entity_type) DO UPDATE SET revoked_before = EXCLUDED.revoked_before, expires_at = EXCLUDED.expires_at`,
+		revocation.EntityID,
+		revocation.EntityType,
+		revocation.RevokedBefore,
+		revocation.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to upsert entity token revocation in session store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) findActiveRevocations(ctx context.Context, now time.Time) ([]*RevokedToken, []*EntityRevocation, error) {
+	tokenRows, err := s.db.QueryContext(
+		ctx,
+		"SELECT token_id, entity_id, entity_type, expires_at, created_at FROM revoked_access_tokens WHERE expires_at > $1",
+		now,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"failed to query revoked access tokens in session store: %w",
+			err,
+		)
+	}
+	defer tokenRows.Close()
+
+	tokens := []*RevokedToken{}
+	for tokenRows.Next() {
+		token := new(RevokedToken)
+		err := tokenRows.Scan(
+			&token.TokenID,
+			&token.EntityID,
+			&token.EntityType,
+			&token.ExpiresAt,
+			&token.CreatedAt,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"failed to scan row into revoked access token in session store: %w",
+				err,
+			)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := tokenRows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	entityRows, err := s.db.QueryContext(
+		ctx,
+		"SELECT entity_id, entity_type, revoked_before, expires_at, created_at FROM entity_token_revocations WHERE expires_at > $1",
+		now,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"failed to query entity token revocations in session store: %w",
+			err,
+		)
+	}
+	defer entityRows.Close()
+
+	revocations := []*EntityRevocation{}
+	for entityRows.Next() {
+		revocation := new(EntityRevocation)
+		err := entityRows.Scan(
+			&revocation.EntityID,
+			&revocation.EntityType,
+			&revocation.RevokedBefore,
+			&revocation.ExpiresAt,
+			&revocation.CreatedAt,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"failed to scan row into entity token revocation in session store: %w",
+				err,
+			)
+		}
+		revocations = append(revocations, revocation)
+	}
+
+	return tokens, revocations, entityRows.Err()
+}
+
+func (s *store) deleteExpiredRevocations(ctx context.Context, now time.Time) error {
+	for _, exec := range []string{
+		"DELETE FROM revoked_access_tokens WHERE expires_at <= $1",
+		"DELETE FROM entity_token_revocations WHERE expires_at <= $1",
+	} {
+		if _, err := s.db.ExecContext(ctx, exec, now); err != nil {
+			return fmt.Errorf(
+				"failed to delete expired revocations in session store: %w",
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
 func scanRowsIntoSession(rows *sql.Rows, session *Session) (*Session, error) {
 	if session == nil {
 		return nil, errors.New(
@@ -138,6 +284,8 @@ func scanRowsIntoSession(rows *sql.Rows, session *Session) (*Session, error) {
 		&session.UserAgent,
 		&session.ClientIP,
 		&session.LastUsedAt,
+		&session.StartedAt,
+		pq.Array(&session.AuthMethods),
 		&session.CreatedAt,
 		&session.UpdatedAt,
 	)
@@ -18,6 +18,19 @@ type LoginUserRequest struct {
 	ClientIP  string `json:"clientIP" validate:"required"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=5,max=10"`
+}
+
+type ReauthenticateRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
 func (lu *LoginUserRequest) GetUserAgent() string {
 	return lu.UserAgent
 }
@@ -6,25 +6,43 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/interfaces"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/middleware"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
 	"github.com/go-chi/chi"
+	"github.com/google/uuid"
 )
 
 type servicer interface {
 	registerUser(ctx context.Context, newUser *RegisterUserRequest) error
 	loginUser(ctx context.Context, payload *LoginUserRequest) (*LoginUserCookiesResponse, error)
-	logoutUser(ctx context.Context, refreshToken string) error
+	logoutUser(ctx context.Context, refreshToken string, accessToken string) error
+	changePassword(ctx context.Context, userID uuid.UUID, payload *ChangePasswordRequest) error
+	reauthenticate(ctx context.Context, userID uuid.UUID, payload *ReauthenticateRequest) (*interfaces.TokenDetails, error)
+	changeEmail(ctx context.Context, userID uuid.UUID, payload *ChangeEmailRequest) error
+	deleteAccount(ctx context.Context, userID uuid.UUID) error
+}
+
+// recentAuthMaxAge is how long after a login or re-authentication sensitive
+// account operations are allowed.
+const recentAuthMaxAge = 5 * time.Minute
+
+type authenticator interface {
+	Authenticate(entityTypes ...string) func(http.Handler) http.Handler
 }
 
 type handler struct {
-	service servicer
+	service       servicer
+	authenticator authenticator
 }
 
-func NewHandler(service servicer) *handler {
+func NewHandler(service servicer, authenticator authenticator) *handler {
 	return &handler{
-		service: service,
+		service:       service,
+		authenticator: authenticator,
 	}
 }
 
@@ -41,6 +59,27 @@ func (h *handler) RegisterRoutes(router *chi.Mux) {
 		"/logout",
 		handlerutils.MakeHandler(h.logoutUserHandler),
 	)
+
+	authenticated := router.With(h.authenticator.Authenticate(auth.EntityTypeUser))
+	authenticated.Patch(
+		"/users/me/password",
+		handlerutils.MakeHandler(h.changePasswordHandler),
+	)
+	authenticated.Post(
+		"/users/me/reauth",
+		handlerutils.MakeHandler(h.reauthenticateHandler),
+	)
+
+	// sensitive operations need a fresh password or passkey check
+	recentlyAuthenticated := authenticated.With(middleware.RequireRecentAuth(recentAuthMaxAge))
+	recentlyAuthenticated.Patch(
+		"/users/me/email",
+		handlerutils.MakeHandler(h.changeEmailHandler),
+	)
+	recentlyAuthenticated.Delete(
+		"/users/me",
+		handlerutils.MakeHandler(h.deleteAccountHandler),
+	)
 }
 
 func (h *handler) registerUserHandler(w http.ResponseWriter, r *http.Request) error {
@@ -176,12 +215,17 @@ func (h *handler) logoutUserHandler(w http.ResponseWriter, r *http.Request) erro
 		}
 	}
 
+	var accessToken string
+	if cookie, err := r.Cookie("accessToken"); err == nil {
+		accessToken = cookie.Value
+	}
+
 	cookiesNames := []string{
 		"accessToken",
 		"refreshToken",
 	}
 
-	if err = h.service.logoutUser(ctx, refreshToken.Value); err != nil {
+	if err = h.service.logoutUser(ctx, refreshToken.Value, accessToken); err != nil {
 		handlerutils.ClearCookie(
 			w,
 			&cookiesNames,
@@ -202,3 +246,236 @@ func (h *handler) logoutUserHandler(w http.ResponseWriter, r *http.Request) erro
 		nil,
 	)
 }
+
+func (h *handler) changePasswordHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *ChangePasswordRequest
+	var err error
+	defer r.Body.Close()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	if err = h.service.changePassword(ctx, userID, payload); err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrInvalidCredentials):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrInvalidCredentials.Error(),
+				nil,
+			)
+		case errors.Is(err, servererrors.ErrUserNotFound):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrUserNotFound.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	// every session was revoked, the user has to log in again
+	handlerutils.ClearCookie(
+		w,
+		&[]string{
+			"accessToken",
+			"refreshToken",
+		},
+	)
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"password changed, all sessions revoked",
+		nil,
+	)
+}
+
+func (h *handler) reauthenticateHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *ReauthenticateRequest
+	var err error
+	defer r.Body.Close()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	accessToken, err := h.service.reauthenticate(ctx, userID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrInvalidCredentials):
+			return servererrors.New(
+				http.StatusUnauthorized,
+				servererrors.ErrInvalidCredentials.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	handlerutils.SetCookies(
+		w,
+		[]handlerutils.Cookie{
+			{
+				Name:    "accessToken",
+				Value:   accessToken.Value,
+				Expires: accessToken.Expires,
+			},
+		},
+	)
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"re-authenticated, elevated access token attached to cookies",
+		nil,
+	)
+}
+
+func (h *handler) changeEmailHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	var payload *ChangeEmailRequest
+	var err error
+	defer r.Body.Close()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = handlerutils.ParseJSON(r, &payload); err != nil {
+		return servererrors.New(
+			http.StatusBadRequest,
+			servererrors.ErrInvalidRequestPayload.Error(),
+			nil,
+		)
+	}
+
+	if err = validate.StructFields(payload); err != nil {
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrValidationFailed.Error(),
+			err,
+		)
+	}
+
+	if err = h.service.changeEmail(ctx, userID, payload); err != nil {
+		switch {
+		case errors.Is(err, servererrors.ErrUserAlreadyExists):
+			return servererrors.New(
+				http.StatusConflict,
+				servererrors.ErrUserAlreadyExists.Error(),
+				nil,
+			)
+		default:
+			return err
+		}
+	}
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"email changed",
+		nil,
+	)
+}
+
+func (h *handler) deleteAccountHandler(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		(30 * time.Second),
+	)
+	defer cancel()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = h.service.deleteAccount(ctx, userID); err != nil {
+		return err
+	}
+
+	handlerutils.ClearCookie(
+		w,
+		&[]string{
+			"accessToken",
+			"refreshToken",
+		},
+	)
+
+	return handlerutils.WriteSuccessJSON(
+		w,
+		http.StatusOK,
+		"account deleted",
+		nil,
+	)
+}
+
+// userIDFromContext returns the id of the user authenticated by the auth
+// middleware.
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, servererrors.New(
+			http.StatusUnauthorized,
+			servererrors.ErrUnauthorized.Error(),
+			nil,
+		)
+	}
+
+	return uuid.Parse(claims.EntityID)
+}
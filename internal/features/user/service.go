@@ -14,11 +14,16 @@ type userStorer interface {
 	create(ctx context.Context, user *User) error
 	findByEmail(ctx context.Context, email string) (*User, error)
 	findByID(ctx context.Context, userID uuid.UUID) (*User, error)
+	updatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error
+	updateEmail(ctx context.Context, userID uuid.UUID, email string) error
+	deleteByID(ctx context.Context, userID uuid.UUID) error
 }
 
 type sessionServicer interface {
 	LoginEntity(ctx context.Context, payload *interfaces.LoginEntityRequest) (*interfaces.LoginEntityCookiesResponse, error)
-	LogoutEntity(ctx context.Context, refreshToken string) error
+	LogoutEntity(ctx context.Context, refreshToken string, accessToken string) error
+	RevokeAllEntitySessions(ctx context.Context, entityID uuid.UUID, entityType string) error
+	IssueElevatedAccessToken(ctx context.Context, entityID uuid.UUID, entityType string, authMethods []string) (*interfaces.TokenDetails, error)
 }
 
 type service struct {
@@ -85,10 +90,11 @@ func (s *service) loginUser(ctx context.Context, payload *LoginUserRequest) (*Lo
 	resp, err := s.sessionService.LoginEntity(
 		ctx,
 		&interfaces.LoginEntityRequest{
-			EntityID:   u.UserID,
-			EntityType: "user",
-			UserAgent:  payload.UserAgent,
-			ClientIP:   payload.ClientIP,
+			EntityID:    u.UserID,
+			EntityType:  auth.EntityTypeUser,
+			UserAgent:   payload.UserAgent,
+			ClientIP:    payload.ClientIP,
+			AuthMethods: []string{auth.AuthMethodPassword},
 		},
 	)
 	if err != nil {
@@ -107,6 +113,78 @@ func (s *service) loginUser(ctx context.Context, payload *LoginUserRequest) (*Lo
 	}, nil
 }
 
-func (s *service) logoutUser(ctx context.Context, refreshToken string) error {
-	return s.sessionService.LogoutEntity(ctx, refreshToken)
+func (s *service) logoutUser(ctx context.Context, refreshToken string, accessToken string) error {
+	return s.sessionService.LogoutEntity(ctx, refreshToken, accessToken)
+}
+
+// changePassword replaces the user's password and logs the user out of every
+// session, revoking all access tokens issued with the old password.
+func (s *service) changePassword(ctx context.Context, userID uuid.UUID, payload *ChangePasswordRequest) error {
+	u, err := s.userStore.findByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if u.UserID == uuid.Nil {
+		return servererrors.ErrUserNotFound
+	}
+
+	if !u.comparePassword(payload.CurrentPassword) {
+		return servererrors.ErrInvalidCredentials
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userStore.updatePassword(ctx, u.UserID, hashedPassword); err != nil {
+		return err
+	}
+
+	return s.sessionService.RevokeAllEntitySessions(ctx, u.UserID, auth.EntityTypeUser)
+}
+
+// reauthenticate checks the password of an already logged in user and issues
+// an elevated access token for sensitive operations.
+func (s *service) reauthenticate(ctx context.Context, userID uuid.UUID, payload *ReauthenticateRequest) (*interfaces.TokenDetails, error) {
+	u, err := s.userStore.findByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.UserID == uuid.Nil || !u.comparePassword(payload.Password) {
+		return nil, servererrors.ErrInvalidCredentials
+	}
+
+	return s.sessionService.IssueElevatedAccessToken(
+		ctx,
+		u.UserID,
+		auth.EntityTypeUser,
+		[]string{auth.AuthMethodPassword},
+	)
+}
+
+func (s *service) changeEmail(ctx context.Context, userID uuid.UUID, payload *ChangeEmailRequest) error {
+	email := strings.TrimSpace(payload.Email)
+
+	existing, err := s.userStore.findByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if existing.UserID != uuid.Nil {
+		return servererrors.ErrUserAlreadyExists
+	}
+
+	return s.userStore.updateEmail(ctx, userID, email)
+}
+
+// deleteAccount deletes the user and logs it out of every session.
+func (s *service) deleteAccount(ctx context.Context, userID uuid.UUID) error {
+	if err := s.userStore.deleteByID(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.sessionService.RevokeAllEntitySessions(ctx, userID, auth.EntityTypeUser)
 }
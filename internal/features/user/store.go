@@ -70,6 +70,56 @@ func (s *store) findByID(ctx context.Context, userID uuid.UUID) (*User, error) {
 	return user, nil
 }
 
+func (s *store) updatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE users SET hashed_password = $1, updated_at = NOW() WHERE user_id = $2",
+		hashedPassword,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update user password in user store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) updateEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"UPDATE users SET email = $1, updated_at = NOW() WHERE user_id = $2",
+		email,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to update user email in user store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (s *store) deleteByID(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM users WHERE user_id = $1",
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to delete user in user store: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
 func (s *store) getUserWithContext(ctx context.Context, query string, args ...any) (*User, error) {
 	rows, err := s.db.QueryContext(
 		ctx,
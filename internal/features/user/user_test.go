@@ -84,7 +84,7 @@ func TestUserRoutes(t *testing.T) {
 		userStore,
 		nil,
 	) // todo: add session service
-	userHandler := NewHandler(userService)
+	userHandler := NewHandler(userService, nil)
 
 	// create router
 	router := chi.NewRouter()
@@ -163,3 +163,15 @@ func (m *mockStore) findByEmail(ctx context.Context, email string) (*User, error
 func (m *mockStore) findByID(ctx context.Context, userID uuid.UUID) (*User, error) {
 	return nil, nil
 }
+
+func (m *mockStore) updatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
+	return nil
+}
+
+func (m *mockStore) updateEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	return nil
+}
+
+func (m *mockStore) deleteByID(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
@@ -1,13 +1,17 @@
 package handlerutils
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"time"
 
 	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
 )
 
 type APIHandler func(w http.ResponseWriter, r *http.Request) error
@@ -20,45 +24,13 @@ func MakeHandler(handler APIHandler) http.HandlerFunc {
 			log.Println(err)
 			var serverError *servererrors.ServerError
 
-			if errors.As(err, &serverError) {
-				switch serverError.StatusCode {
-				case http.StatusBadRequest:
-					WriteErrorJSON(
-						w,
-						serverError.StatusCode,
-						serverError.Error(),
-						serverError.Errors,
-					)
-				case http.StatusConflict:
-					WriteErrorJSON(
-						w,
-						serverError.StatusCode,
-						serverError.Error(),
-						serverError.Errors,
-					)
-				case http.StatusUnprocessableEntity:
-					WriteErrorJSON(
-						w,
-						serverError.StatusCode,
-						serverError.Error(),
-						serverError.Errors,
-					)
-
-				case http.StatusUnauthorized:
-					WriteErrorJSON(
-						w,
-						serverError.StatusCode,
-						serverError.Error(),
-						serverError.Errors,
-					)
-				case http.StatusForbidden:
-					WriteErrorJSON(
-						w,
-						serverError.StatusCode,
-						serverError.Error(),
-						serverError.Errors,
-					)
-				}
+			if errors.As(err, &serverError) && serverError.StatusCode >= http.StatusBadRequest && serverError.StatusCode <= 599 {
+				WriteErrorJSON(
+					w,
+					serverError.StatusCode,
+					serverError.Error(),
+					serverError.Errors,
+				)
 			} else {
 				WriteErrorJSON(
 					w,
@@ -120,6 +92,65 @@ func ParseJSON(r *http.Request, payload any) error {
 	return json.NewDecoder(r.Body).Decode(payload)
 }
 
+// ParseQueryInt reads an integer query parameter, returning fallback when it
+// is missing.
+func ParseQueryInt(r *http.Request, key string, fallback int64) (int64, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// ParseOptionalQueryInt reads an integer query parameter, returning nil when
+// it is missing.
+func ParseOptionalQueryInt(r *http.Request, key string) (*int64, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// ParseOptionalQueryTime reads an RFC 3339 time query parameter, returning
+// nil when it is missing.
+func ParseOptionalQueryTime(r *http.Request, key string) (*time.Time, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// ParseOptionalQueryUUID reads a UUID query parameter, returning nil when it
+// is missing.
+func ParseOptionalQueryUUID(r *http.Request, key string) (*uuid.UUID, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
 func writeJSON(w http.ResponseWriter, statusCode int, v any) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -127,15 +158,34 @@ func writeJSON(w http.ResponseWriter, statusCode int, v any) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
-// GetClientIP returns the IP of the request.
+type clientIPContextKey struct{}
+
+// ContextWithClientIP stores the client IP resolved by the client IP
+// middleware.
+func ContextWithClientIP(ctx context.Context, clientIP netip.Addr) context.Context {
+	return context.WithValue(ctx, clientIPContextKey{}, clientIP)
+}
+
+// ClientIPFromContext returns the client IP resolved by the client IP
+// middleware, if any.
+func ClientIPFromContext(ctx context.Context) (netip.Addr, bool) {
+	clientIP, ok := ctx.Value(clientIPContextKey{}).(netip.Addr)
+	return clientIP, ok && clientIP.IsValid()
+}
+
+// GetClientIP returns the IP of the request. Forwarding headers are only
+// honoured through the client IP middleware, which knows the trusted proxies;
+// without it the peer address is used.
 func GetClientIP(r *http.Request) string {
-	clientIP := r.RemoteAddr
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		clientIP = r.Header.Get("X-Forwarded-For")
-	} else if real := r.Header.Get("X-Real-IP"); real != "" {
-		clientIP = real
+	if clientIP, ok := ClientIPFromContext(r.Context()); ok {
+		return clientIP.String()
+	}
+
+	if addrPort, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		return addrPort.Addr().Unmap().String()
 	}
-	return clientIP
+
+	return r.RemoteAddr
 }
 
 type Cookie struct {
@@ -13,6 +13,8 @@ type LoginEntityRequest struct {
 	EntityType string    `json:"entityType" validate:"required"`
 	UserAgent  string    `json:"userAgent" validate:"required"`
 	ClientIP   string    `json:"clientIP" validate:"required"`
+	// AuthMethods lists how the entity proved its identity, e.g. "pwd".
+	AuthMethods []string `json:"authMethods" validate:"required,min=1"`
 }
 
 // Responses
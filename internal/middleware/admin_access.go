@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+)
+
+// AllowCIDRs returns a middleware that only lets through requests whose
+// client IP, as resolved by the ClientIPResolver middleware, falls in one of
+// cidrs. An empty list allows every client.
+func AllowCIDRs(cidrs []string) (func(http.Handler) http.Handler, error) {
+	prefixes, err := ParsePrefixes(cidrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse allowed cidrs: %w", err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(prefixes) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientIP, ok := handlerutils.ClientIPFromContext(r.Context())
+			if !ok {
+				addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+				if err == nil {
+					clientIP, ok = addrPort.Addr().Unmap(), true
+				}
+			}
+
+			if !ok || !containsAddr(prefixes, clientIP) {
+				handlerutils.WriteErrorJSON(
+					w,
+					http.StatusForbidden,
+					servererrors.ErrForbiddenAccess.Error(),
+					nil,
+				)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// RequireClientCert only lets through requests that came over a TLS
+// connection with a client certificate verified against the configured CA.
+func RequireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			handlerutils.WriteErrorJSON(
+				w,
+				http.StatusForbidden,
+				servererrors.ErrClientCertRequired.Error(),
+				nil,
+			)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
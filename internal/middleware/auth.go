@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+)
+
+type accessTokenValidator interface {
+	ValidateAccessToken(tokenStr string) (isValid bool, claims *auth.TokenClaims, err error)
+}
+
+type revocationChecker interface {
+	IsRevoked(claims *auth.TokenClaims) bool
+}
+
+type Authenticator struct {
+	tokenService accessTokenValidator
+	denylist     revocationChecker
+}
+
+func NewAuthenticator(tokenService accessTokenValidator, denylist revocationChecker) *Authenticator {
+	return &Authenticator{
+		tokenService: tokenService,
+		denylist:     denylist,
+	}
+}
+
+// Authenticate returns a middleware that only lets requests carrying a valid,
+// non revoked access token through. The token is read from the accessToken
+// cookie, falling back to a bearer Authorization header. When entityTypes is
+// not empty the token must also belong to one of them.
+// The token claims are available downstream through auth.ClaimsFromContext.
+func (a *Authenticator) Authenticate(entityTypes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr := accessTokenFromRequest(r)
+			if tokenStr == "" {
+				handlerutils.WriteErrorJSON(
+					w,
+					http.StatusUnauthorized,
+					servererrors.ErrNoAccessTokenCookie.Error(),
+					nil,
+				)
+				return
+			}
+
+			isValid, claims, err := a.tokenService.ValidateAccessToken(tokenStr)
+			if err != nil || !isValid {
+				handlerutils.WriteErrorJSON(
+					w,
+					http.StatusUnauthorized,
+					servererrors.ErrInvalidAccessToken.Error(),
+					nil,
+				)
+				return
+			}
+
+			if a.denylist.IsRevoked(claims) {
+				handlerutils.WriteErrorJSON(
+					w,
+					http.StatusUnauthorized,
+					servererrors.ErrRevokedAccessToken.Error(),
+					nil,
+				)
+				return
+			}
+
+			if len(entityTypes) > 0 && !slices.Contains(entityTypes, claims.EntityType) {
+				handlerutils.WriteErrorJSON(
+					w,
+					http.StatusForbidden,
+					servererrors.ErrForbiddenAccess.Error(),
+					nil,
+				)
+				return
+			}
+
+			next.ServeHTTP(
+				w,
+				r.WithContext(auth.ContextWithClaims(r.Context(), claims)),
+			)
+		})
+	}
+}
+
+// Identify returns a middleware for routes open to everyone whose response
+// depends on who is asking. A valid, non revoked access token belonging to one
+// of entityTypes makes its claims available through auth.ClaimsFromContext,
+// any other request goes through anonymously rather than being rejected.
+func (a *Authenticator) Identify(entityTypes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr := accessTokenFromRequest(r)
+			if tokenStr == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			isValid, claims, err := a.tokenService.ValidateAccessToken(tokenStr)
+			if err != nil || !isValid || a.denylist.IsRevoked(claims) ||
+				(len(entityTypes) > 0 && !slices.Contains(entityTypes, claims.EntityType)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(
+				w,
+				r.WithContext(auth.ContextWithClaims(r.Context(), claims)),
+			)
+		})
+	}
+}
+
+func accessTokenFromRequest(r *http.Request) string {
+	if cookie, err := r.Cookie("accessToken"); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+
+	return ""
+}
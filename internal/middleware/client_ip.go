@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+)
+
+// ClientIPResolver works out the address of the client that sent a request.
+// X-Forwarded-For is only trusted when the peer is one of the trusted
+// proxies, and it is read from the right so a client can not spoof its
+// address by sending the header itself.
+type ClientIPResolver struct {
+	trustedProxies []netip.Prefix
+}
+
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	prefixes, err := ParsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trusted proxies: %w", err)
+	}
+
+	return &ClientIPResolver{
+		trustedProxies: prefixes,
+	}, nil
+}
+
+// Resolve returns the client IP of r. The zero Addr is returned when the peer
+// address can not be parsed.
+func (c *ClientIPResolver) Resolve(r *http.Request) netip.Addr {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	clientIP := addrPort.Addr().Unmap()
+	if !c.isTrustedProxy(clientIP) {
+		return clientIP
+	}
+
+	// every proxy appends the address it received the request from, walk
+	// back until the first hop that is not one of ours
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		clientIP = hop.Unmap()
+		if !c.isTrustedProxy(clientIP) {
+			break
+		}
+	}
+
+	return clientIP
+}
+
+// Middleware stores the resolved client IP in the request context, where it
+// is read by handlerutils.GetClientIP.
+func (c *ClientIPResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if clientIP := c.Resolve(r); clientIP.IsValid() {
+			r = r.WithContext(handlerutils.ContextWithClientIP(r.Context(), clientIP))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *ClientIPResolver) isTrustedProxy(ip netip.Addr) bool {
+	return containsAddr(c.trustedProxies, ip)
+}
+
+// ParsePrefixes parses CIDRs, a bare IP is taken as a single host prefix.
+func ParsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, err
+			}
+
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
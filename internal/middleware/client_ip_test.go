@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor []string
+		expected      string
+	}{
+		{
+			name:       "should use the peer address without forwarding headers",
+			remoteAddr: "203.0.113.7:51234",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:          "should ignore forwarding headers from untrusted peers",
+			remoteAddr:    "203.0.113.7:51234",
+			xForwardedFor: []string{"198.51.100.1"},
+			expected:      "203.0.113.7",
+		},
+		{
+			name:          "should use the forwarded address from a trusted proxy",
+			remoteAddr:    "10.1.2.3:443",
+			xForwardedFor: []string{"198.51.100.1"},
+			expected:      "198.51.100.1",
+		},
+		{
+			name:          "should not trust addresses spoofed by the client",
+			remoteAddr:    "10.1.2.3:443",
+			xForwardedFor: []string{"127.0.0.1, 198.51.100.1"},
+			expected:      "198.51.100.1",
+		},
+		{
+			name:          "should skip every trusted proxy in the chain",
+			remoteAddr:    "10.1.2.3:443",
+			xForwardedFor: []string{"198.51.100.1", "192.168.1.1, 10.9.9.9"},
+			expected:      "198.51.100.1",
+		},
+		{
+			name:          "should stop at a malformed hop",
+			remoteAddr:    "10.1.2.3:443",
+			xForwardedFor: []string{"not-an-ip, 10.9.9.9"},
+			expected:      "10.9.9.9",
+		},
+		{
+			name:       "should unmap ipv4 mapped ipv6 peers",
+			remoteAddr: "[::ffff:203.0.113.7]:51234",
+			expected:   "203.0.113.7",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.xForwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			var clientIP string
+			resolver.Middleware(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					clientIP = handlerutils.GetClientIP(r)
+				}),
+			).ServeHTTP(httptest.NewRecorder(), req)
+
+			if clientIP != tc.expected {
+				t.Errorf("expected client ip %s, got %s", tc.expected, clientIP)
+			}
+		})
+	}
+}
+
+func TestAllowCIDRs(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.1"})
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	allowCIDRs, err := AllowCIDRs([]string{"198.51.100.0/24", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("failed to create allowlist: %v", err)
+	}
+
+	handler := resolver.Middleware(allowCIDRs(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	))
+
+	testCases := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor string
+		expected      int
+	}{
+		{
+			name:       "should allow a client in the allowlist",
+			remoteAddr: "198.51.100.20:51234",
+			expected:   http.StatusOK,
+		},
+		{
+			name:       "should allow an ipv6 client in the allowlist",
+			remoteAddr: "[2001:db8::1]:51234",
+			expected:   http.StatusOK,
+		},
+		{
+			name:       "should reject a client outside the allowlist",
+			remoteAddr: "203.0.113.7:51234",
+			expected:   http.StatusForbidden,
+		},
+		{
+			name:          "should allow a client forwarded by a trusted proxy",
+			remoteAddr:    "10.0.0.1:443",
+			xForwardedFor: "198.51.100.20",
+			expected:      http.StatusOK,
+		},
+		{
+			name:          "should reject a client spoofing an allowed address",
+			remoteAddr:    "203.0.113.7:51234",
+			xForwardedFor: "198.51.100.20",
+			expected:      http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/login", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.xForwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.xForwardedFor)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expected {
+				t.Errorf("expected status code %d, got %d", tc.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestAllowCIDRsRejectsInvalidCIDR(t *testing.T) {
+	if _, err := AllowCIDRs([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an error for an invalid cidr")
+	}
+}
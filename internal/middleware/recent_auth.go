@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+)
+
+// RequireRecentAuth returns a middleware for sensitive operations that only
+// lets a request through when the entity authenticated at most maxAge ago,
+// even if its session is otherwise valid. It must run after Authenticate.
+//
+// Rejected requests get a 401 carrying servererrors.CodeReauthRequired so that
+// clients can prompt for re-authentication and retry.
+func RequireRecentAuth(maxAge time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := auth.ClaimsFromContext(r.Context())
+			if !ok {
+				handlerutils.WriteErrorJSON(
+					w,
+					http.StatusUnauthorized,
+					servererrors.ErrUnauthorized.Error(),
+					nil,
+				)
+				return
+			}
+
+			if claims.AuthTime == nil || time.Since(claims.AuthTime.Time) > maxAge {
+				handlerutils.WriteErrorJSON(
+					w,
+					http.StatusUnauthorized,
+					servererrors.ErrReauthRequired.Error(),
+					map[string]any{
+						"code":         servererrors.CodeReauthRequired,
+						"maxAgeInSecs": int64(maxAge.Seconds()),
+					},
+				)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRequireRecentAuth(t *testing.T) {
+	handler := RequireRecentAuth(5 * time.Minute)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	testCases := []struct {
+		name     string
+		authTime *jwt.NumericDate
+		expected int
+	}{
+		{
+			name:     "should allow a recently authenticated entity",
+			authTime: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			expected: http.StatusOK,
+		},
+		{
+			name:     "should ask for re-authentication after the max age",
+			authTime: jwt.NewNumericDate(time.Now().Add(-10 * time.Minute)),
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "should ask for re-authentication without an auth time",
+			expected: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/users/me", nil)
+			req = req.WithContext(auth.ContextWithClaims(
+				req.Context(),
+				&auth.TokenClaims{AuthTime: tc.authTime},
+			))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expected {
+				t.Fatalf("expected status code %d, got %d", tc.expected, rr.Code)
+			}
+
+			if tc.expected != http.StatusUnauthorized {
+				return
+			}
+
+			var resp struct {
+				Errors struct {
+					Code string `json:"code"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.Errors.Code != servererrors.CodeReauthRequired {
+				t.Errorf("expected error code %q, got %q", servererrors.CodeReauthRequired, resp.Errors.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Currency is an ISO 4217 currency along with the number of digits after
+// the decimal point of its minor unit, e.g. 2 for USD cents and 0 for JPY.
+type Currency struct {
+	code   string
+	digits int
+}
+
+// currencies are the ISO 4217 currencies the store accepts.
+var currencies = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2,
+	"GBP": 2, "GHS": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2,
+	"ISK": 0, "JOD": 3, "JPY": 0, "KES": 2, "KRW": 0, "KWD": 3, "MAD": 2,
+	"MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2,
+	"PKR": 2, "PLN": 2, "QAR": 2, "RON": 2, "SAR": 2, "SEK": 2, "SGD": 2,
+	"THB": 2, "TND": 3, "TRY": 2, "TWD": 2, "UAH": 2, "UGX": 0, "USD": 2,
+	"VND": 0, "XAF": 0, "XOF": 0, "ZAR": 2,
+}
+
+// ParseCurrency looks up a currency by its code, ignoring case and
+// surrounding spaces.
+func ParseCurrency(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	digits, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+
+	return Currency{code: code, digits: digits}, nil
+}
+
+// MustParseCurrency is ParseCurrency for codes known at compile time.
+func MustParseCurrency(code string) Currency {
+	currency, err := ParseCurrency(code)
+	if err != nil {
+		panic(err)
+	}
+
+	return currency
+}
+
+func (c Currency) Code() string {
+	return c.code
+}
+
+// Digits is the number of minor unit digits, the exponent of the currency.
+func (c Currency) Digits() int {
+	return c.digits
+}
+
+func (c Currency) IsZero() bool {
+	return c.code == ""
+}
+
+func (c Currency) String() string {
+	return c.code
+}
+
+func (c Currency) MarshalText() ([]byte, error) {
+	return []byte(c.code), nil
+}
+
+func (c *Currency) UnmarshalText(text []byte) error {
+	currency, err := ParseCurrency(string(text))
+	if err != nil {
+		return err
+	}
+
+	*c = currency
+	return nil
+}
+
+// Value stores the currency as its code, e.g. in a CHAR(3) column.
+func (c Currency) Value() (driver.Value, error) {
+	if c.IsZero() {
+		return nil, nil
+	}
+
+	return c.code, nil
+}
+
+func (c *Currency) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return c.UnmarshalText([]byte(v))
+	case []byte:
+		return c.UnmarshalText(v)
+	case nil:
+		*c = Currency{}
+		return nil
+	default:
+		return fmt.Errorf("can not scan %T into currency", src)
+	}
+}
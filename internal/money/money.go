@@ -0,0 +1,327 @@
+// Package money represents amounts as integer minor units of an ISO 4217
+// currency, e.g. 1050 USD is $10.50, so prices never go through floats.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount out of range")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrInvalidRatios    = errors.New("ratios must be non negative and not all zero")
+)
+
+// decimalPattern is a plain decimal number, without exponents or fractions
+// that big.Rat would otherwise accept.
+var decimalPattern = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+
+// Money is an amount in minor units of a currency. The zero value has no
+// currency and is only useful as a placeholder.
+type Money struct {
+	amount   int64
+	currency Currency
+}
+
+// New returns amount minor units of the currency with the given code.
+func New(amount int64, code string) (Money, error) {
+	currency, err := ParseCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{amount: amount, currency: currency}, nil
+}
+
+// MustNew is New for amounts and codes known at compile time.
+func MustNew(amount int64, code string) Money {
+	m, err := New(amount, code)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// ParseDecimal reads a decimal amount in major units such as "10.505",
+// rounding half to even to the currency's minor unit.
+func ParseDecimal(amount string, code string) (Money, error) {
+	currency, err := ParseCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	amount = strings.TrimSpace(amount)
+	if !decimalPattern.MatchString(amount) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	r.Mul(r, new(big.Rat).SetInt(pow10(currency.digits)))
+	minor, err := roundHalfEven(r.Num(), r.Denom())
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{amount: minor, currency: currency}, nil
+}
+
+// Amount is the amount in minor units.
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+
+	sum := m.amount + other.amount
+	if (other.amount > 0 && sum < m.amount) || (other.amount < 0 && sum > m.amount) {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{amount: sum, currency: m.currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+
+	return m.Add(Money{amount: -other.amount, currency: other.currency})
+}
+
+// Multiply multiplies by a whole quantity, e.g. a unit price by the number of
+// units.
+func (m Money) Multiply(quantity int64) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(quantity))
+	if !product.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{amount: product.Int64(), currency: m.currency}, nil
+}
+
+// MulRat multiplies by num/den rounding half to even, e.g. MulRat(15, 100)
+// for 15% of the amount.
+func (m Money) MulRat(num, den int64) (Money, error) {
+	if den == 0 {
+		return Money{}, ErrInvalidAmount
+	}
+
+	amount, err := roundHalfEven(
+		new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(num)),
+		big.NewInt(den),
+	)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{amount: amount, currency: m.currency}, nil
+}
+
+// Allocate splits the amount in proportion to ratios without losing a minor
+// unit: the remainder left by rounding down is handed out one unit at a time
+// starting with the first share.
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	total := new(big.Int)
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, ErrInvalidRatios
+		}
+		total.Add(total, big.NewInt(ratio))
+	}
+
+	if total.Sign() == 0 {
+		return nil, ErrInvalidRatios
+	}
+
+	shares := make([]Money, len(ratios))
+	remainder := m.amount
+	for i, ratio := range ratios {
+		// truncated towards zero so the remainder keeps the sign of the amount
+		share := new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(ratio))
+		share.Quo(share, total)
+
+		shares[i] = Money{amount: share.Int64(), currency: m.currency}
+		remainder -= share.Int64()
+	}
+
+	unit := int64(1)
+	if remainder < 0 {
+		unit = -1
+	}
+
+	for i := 0; remainder != 0; i = (i + 1) % len(shares) {
+		if ratios[i] == 0 {
+			continue
+		}
+
+		shares[i].amount += unit
+		remainder -= unit
+	}
+
+	return shares, nil
+}
+
+// Split divides the amount into n shares differing by at most one minor unit.
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, ErrInvalidRatios
+	}
+
+	ratios := make([]int64, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+
+	return m.Allocate(ratios...)
+}
+
+// Cmp compares two amounts of the same currency, returning -1, 0 or 1.
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case m.amount < other.amount:
+		return -1, nil
+	case m.amount > other.amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Decimal formats the amount in major units, e.g. "10.50" for 1050 USD.
+func (m Money) Decimal() string {
+	digits := m.currency.digits
+	if digits == 0 {
+		return fmt.Sprintf("%d", m.amount)
+	}
+
+	sign := ""
+	abs := new(big.Int).Abs(big.NewInt(m.amount))
+	if m.amount < 0 {
+		sign = "-"
+	}
+
+	quo, rem := new(big.Int).QuoRem(abs, pow10(digits), new(big.Int))
+	return fmt.Sprintf("%s%s.%0*d", sign, quo, digits, rem.Int64())
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency.code
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.currency != other.currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, other.currency)
+	}
+
+	return nil
+}
+
+// jsonMoney is how money looks on the wire and in JSONB columns.
+type jsonMoney struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.amount, Currency: m.currency.code})
+}
+
+// UnmarshalJSON reads {"amount": 1050, "currency": "USD"}, rejecting unknown
+// currencies.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v jsonMoney
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	parsed, err := New(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Value stores money as its JSON form, e.g. in a JSONB column. Tables that
+// filter or sort by amount should rather keep the amount and the currency in
+// separate columns.
+func (m Money) Value() (driver.Value, error) {
+	return m.MarshalJSON()
+}
+
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.UnmarshalJSON(v)
+	case string:
+		return m.UnmarshalJSON([]byte(v))
+	default:
+		return fmt.Errorf("can not scan %T into money", src)
+	}
+}
+
+// roundHalfEven divides num by den rounding ties to the even neighbour, the
+// banker's rounding that avoids drifting upwards over many operations.
+func roundHalfEven(num, den *big.Int) (int64, error) {
+	if den.Sign() < 0 {
+		num, den = new(big.Int).Neg(num), new(big.Int).Neg(den)
+	}
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	// compare twice the remainder with the divisor to find which neighbour
+	// is closer
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	switch twice.Cmp(den) {
+	case 1:
+		quo.Add(quo, big.NewInt(int64(rem.Sign())))
+	case 0:
+		if quo.Bit(0) == 1 {
+			quo.Add(quo, big.NewInt(int64(rem.Sign())))
+		}
+	}
+
+	if !quo.IsInt64() {
+		return 0, ErrOverflow
+	}
+
+	return quo.Int64(), nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
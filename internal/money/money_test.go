@@ -0,0 +1,156 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	testCases := []struct {
+		amount   string
+		currency string
+		expected int64
+	}{
+		{"10.50", "USD", 1050},
+		{"0.125", "USD", 12},  // tie rounds to the even 12
+		{"0.135", "USD", 14},  // tie rounds to the even 14
+		{"0.1251", "USD", 13}, // above the tie rounds up
+		{"-0.125", "USD", -12},
+		{"1500", "JPY", 1500},
+		{"2.5", "JPY", 2},
+		{"1.2345", "BHD", 1234},
+	}
+
+	for _, tc := range testCases {
+		m, err := ParseDecimal(tc.amount, tc.currency)
+		if err != nil {
+			t.Fatalf("failed to parse %s %s: %v", tc.amount, tc.currency, err)
+		}
+
+		if m.Amount() != tc.expected {
+			t.Errorf("expected %s %s to be %d minor units, got %d", tc.amount, tc.currency, tc.expected, m.Amount())
+		}
+	}
+
+	for _, amount := range []string{"", "1e3", "1/3", "0x10", "1.2.3", "abc"} {
+		if _, err := ParseDecimal(amount, "USD"); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("expected %q to be rejected, got %v", amount, err)
+		}
+	}
+
+	if _, err := ParseDecimal("1", "XYZ"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("expected an unknown currency error, got %v", err)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	usd := MustNew(1050, "USD")
+
+	sum, err := usd.Add(MustNew(250, "usd"))
+	if err != nil || sum.Amount() != 1300 {
+		t.Errorf("expected 1300, got %d (%v)", sum.Amount(), err)
+	}
+
+	if _, err = usd.Add(MustNew(100, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected a currency mismatch, got %v", err)
+	}
+
+	if _, err = MustNew(1<<62, "USD").Multiply(4); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected an overflow, got %v", err)
+	}
+
+	// 15% of 10.50 is 1.575 which rounds to the even 1.58
+	tax, err := usd.MulRat(15, 100)
+	if err != nil || tax.Amount() != 158 {
+		t.Errorf("expected 158, got %d (%v)", tax.Amount(), err)
+	}
+
+	if s := MustNew(-5, "USD").String(); s != "-0.05 USD" {
+		t.Errorf("expected -0.05 USD, got %s", s)
+	}
+
+	if s := MustNew(1234, "BHD").String(); s != "1.234 BHD" {
+		t.Errorf("expected 1.234 BHD, got %s", s)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	testCases := []struct {
+		amount   int64
+		ratios   []int64
+		expected []int64
+	}{
+		{100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{5, []int64{3, 7}, []int64{2, 3}},
+		{-100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{10, []int64{0, 1, 1}, []int64{0, 5, 5}},
+		{7, []int64{0, 1, 1}, []int64{0, 4, 3}},
+	}
+
+	for _, tc := range testCases {
+		shares, err := MustNew(tc.amount, "USD").Allocate(tc.ratios...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var total int64
+		for i, share := range shares {
+			total += share.Amount()
+			if share.Amount() != tc.expected[i] {
+				t.Errorf("expected %d allocated %v to be %v, got share %d = %d", tc.amount, tc.ratios, tc.expected, i, share.Amount())
+			}
+		}
+
+		if total != tc.amount {
+			t.Errorf("expected the shares to add up to %d, got %d", tc.amount, total)
+		}
+	}
+
+	if _, err := MustNew(100, "USD").Allocate(0, 0); !errors.Is(err, ErrInvalidRatios) {
+		t.Errorf("expected invalid ratios, got %v", err)
+	}
+}
+
+func TestJSONAndSQL(t *testing.T) {
+	data, err := json.Marshal(MustNew(1050, "EUR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"amount":1050,"currency":"EUR"}` {
+		t.Errorf("unexpected json %s", data)
+	}
+
+	var m Money
+	if err = json.Unmarshal([]byte(`{"amount":99,"currency":"jpy"}`), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Amount() != 99 || m.Currency().Code() != "JPY" {
+		t.Errorf("expected 99 JPY, got %s", m)
+	}
+
+	if err = json.Unmarshal([]byte(`{"amount":99,"currency":"ABC"}`), &m); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("expected an unknown currency error, got %v", err)
+	}
+
+	value, err := MustNew(1050, "EUR").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scanned Money
+	if err = scanned.Scan(value); err != nil {
+		t.Fatal(err)
+	}
+
+	if scanned != MustNew(1050, "EUR") {
+		t.Errorf("expected 10.50 EUR after a round trip, got %s", scanned)
+	}
+
+	var currency Currency
+	if err = currency.Scan("GBP"); err != nil || currency.Digits() != 2 {
+		t.Errorf("expected GBP with 2 digits, got %s (%v)", currency, err)
+	}
+}
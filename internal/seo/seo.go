@@ -0,0 +1,148 @@
+package seo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidLocale = errors.New("invalid locale")
+
+// DefaultLocale is the locale of the slugs generated when a product or
+// category is created.
+const DefaultLocale = "en"
+
+// maxSlugLength bounds the length of generated slugs, in characters.
+const maxSlugLength = 200
+
+// Metadata is what search engines read about a page. A blank Title or
+// Description falls back to the page's own name and description, a blank
+// CanonicalURL to the page's current slug.
+type Metadata struct {
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	CanonicalURL string `json:"canonical_url"`
+}
+
+// ParseLocale normalizes a locale made of a two or three letter language code
+// and an optional two letter region, e.g. "pt_br" to "pt-BR".
+func ParseLocale(locale string) (string, error) {
+	language, region, hasRegion := strings.Cut(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+
+	if len(language) < 2 || len(language) > 3 || !isASCIILetters(language) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocale, locale)
+	}
+
+	if !hasRegion {
+		return strings.ToLower(language), nil
+	}
+
+	if len(region) != 2 || !isASCIILetters(region) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocale, locale)
+	}
+
+	return strings.ToLower(language) + "-" + strings.ToUpper(region), nil
+}
+
+func isASCIILetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// foldings spell the accented latin letters without their accents.
+var foldings = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ł': "l", 'ľ': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o",
+	'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe", 'ř': "r", 'ś': "s",
+	'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th", 'ù': "u",
+	'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ý': "y",
+	'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// Slugify turns a title into a slug: lower case letters and digits separated
+// by single hyphens, e.g. "Crème Brûlée Torch (2-pack)" into
+// "creme-brulee-torch-2-pack". Accented latin letters lose their accents,
+// letters of other scripts are kept as they are. It returns an empty slug
+// when the title holds no letter or digit.
+func Slugify(title string) string {
+	var b strings.Builder
+	length := 0
+	pendingHyphen := false
+
+	for _, r := range strings.ToLower(title) {
+		var part string
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			part = string(r)
+		case foldings[r] != "":
+			part = foldings[r]
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) && length > 0:
+			part = string(r)
+		default:
+			pendingHyphen = length > 0
+			continue
+		}
+
+		if pendingHyphen {
+			part = "-" + part
+			pendingHyphen = false
+		}
+
+		if length+len([]rune(part)) > maxSlugLength {
+			break
+		}
+
+		b.WriteString(part)
+		length += len([]rune(part))
+	}
+
+	return b.String()
+}
+
+// maxSlugCandidates is how many numbered slugs Candidates returns before the
+// one suffixed with the id, e.g. lamp, lamp-2 up to lamp-20.
+const maxSlugCandidates = 20
+
+// Candidates returns the slugs to try in turn for the page with id when
+// base may be taken: base itself, then base-2, base-3 and so on, and last
+// base suffixed with the start of id.
+func Candidates(base string, id uuid.UUID) []string {
+	candidates := make([]string, 0, maxSlugCandidates+1)
+	for n := 1; n <= maxSlugCandidates; n++ {
+		candidates = append(candidates, candidate(base, n))
+	}
+
+	return append(candidates, suffix(base, id.String()[:8]))
+}
+
+// candidate is the nth slug to try for base.
+func candidate(base string, n int) string {
+	if n <= 1 {
+		return base
+	}
+
+	return suffix(base, fmt.Sprint(n))
+}
+
+// suffix appends a hyphen and end to base, shortening base so the slug
+// stays within the max length.
+func suffix(base string, end string) string {
+	end = "-" + end
+	runes := []rune(base)
+	if len(runes)+len(end) > maxSlugLength {
+		base = strings.TrimRight(string(runes[:maxSlugLength-len(end)]), "-")
+	}
+
+	return base + end
+}
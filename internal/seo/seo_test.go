@@ -0,0 +1,76 @@
+package seo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSlugify(t *testing.T) {
+	testCases := []struct {
+		title    string
+		expected string
+	}{
+		{"Running Shoe", "running-shoe"},
+		{"  Crème Brûlée Torch (2-pack)  ", "creme-brulee-torch-2-pack"},
+		{"Straße & Œuvre", "strasse-oeuvre"},
+		{"USB-C -- cable / 2m", "usb-c-cable-2m"},
+		{"Чайник 1.7L", "чайник-1-7l"},
+		{"!!!", ""},
+	}
+
+	for _, tc := range testCases {
+		if slug := Slugify(tc.title); slug != tc.expected {
+			t.Errorf("expected %q to slugify to %q, got %q", tc.title, tc.expected, slug)
+		}
+	}
+
+	long := Slugify(strings.Repeat("word ", 100))
+	if len(long) > maxSlugLength || strings.HasSuffix(long, "-") {
+		t.Errorf("expected a long title to be cut at a word, got %d characters %q", len(long), long[len(long)-10:])
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	id := uuid.MustParse("8f14e45f-ceea-467f-a0e6-2b1e1f3c9d10")
+	candidates := Candidates("lamp", id)
+	if len(candidates) != maxSlugCandidates+1 {
+		t.Fatalf("expected %d candidates, got %d", maxSlugCandidates+1, len(candidates))
+	}
+
+	if candidates[0] != "lamp" || candidates[2] != "lamp-3" || candidates[maxSlugCandidates-1] != "lamp-20" {
+		t.Errorf("expected the base then numbered slugs, got %v", candidates[:3])
+	}
+
+	if last := candidates[maxSlugCandidates]; last != "lamp-8f14e45f" {
+		t.Errorf("expected the id suffixed slug last, got %q", last)
+	}
+
+	if slug := candidate(strings.Repeat("a", maxSlugLength), 12); len(slug) != maxSlugLength || !strings.HasSuffix(slug, "a-12") {
+		t.Errorf("expected the suffix to fit in the max length, got %d characters", len(slug))
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	testCases := map[string]string{
+		"en":     "en",
+		" EN ":   "en",
+		"pt_br":  "pt-BR",
+		"fil-PH": "fil-PH",
+	}
+
+	for locale, expected := range testCases {
+		parsed, err := ParseLocale(locale)
+		if err != nil || parsed != expected {
+			t.Errorf("expected %q to parse as %q, got %q (%v)", locale, expected, parsed, err)
+		}
+	}
+
+	for _, locale := range []string{"", "e", "english", "en-", "en-USA", "e1", "en-U1"} {
+		if _, err := ParseLocale(locale); !errors.Is(err, ErrInvalidLocale) {
+			t.Errorf("expected %q to be rejected, got %v", locale, err)
+		}
+	}
+}
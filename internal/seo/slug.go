@@ -0,0 +1,27 @@
+package seo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Slug is a slug of a page in a locale, the page being the product or
+// category OwnerID. Previous slugs are kept to redirect from, only one per
+// locale is Current.
+type Slug struct {
+	OwnerID   uuid.UUID `json:"owner_id"`
+	Locale    string    `json:"locale"`
+	Slug      string    `json:"slug"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetSlugRequest changes the slug of a page in a locale. Slug is used as
+// given once normalized, e.g. "Oak Desk" becomes "oak-desk". Without one a
+// free slug is generated from Title, or from the page's name when Title is
+// blank too.
+type SetSlugRequest struct {
+	Slug  string `json:"slug" validate:"max=200"`
+	Title string `json:"title" validate:"max=255"`
+}
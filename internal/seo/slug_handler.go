@@ -0,0 +1,136 @@
+package seo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/handlerutils"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/validate"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// ListSlugsHandler serves the slugs of the page named by the idParam URL
+// parameter, e.g. productID. list checks the page exists and mapError turns
+// its errors into responses.
+func ListSlugsHandler(
+	idParam string,
+	list func(ctx context.Context, ownerID uuid.UUID) ([]*Slug, error),
+	mapError func(err error) error,
+) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		ownerID, err := uuid.Parse(chi.URLParam(r, idParam))
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		slugs, err := list(ctx, ownerID)
+		if err != nil {
+			return mapError(err)
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"slugs found",
+			slugs,
+		)
+	}
+}
+
+// SetSlugHandler changes the slug of the page named by the idParam URL
+// parameter in the locale URL parameter. set checks the page exists and
+// mapError turns its errors into responses.
+func SetSlugHandler(
+	idParam string,
+	set func(ctx context.Context, ownerID uuid.UUID, locale string, payload *SetSlugRequest) (*Slug, error),
+	mapError func(err error) error,
+) handlerutils.APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx, cancel := context.WithTimeout(
+			r.Context(),
+			(30 * time.Second),
+		)
+		defer cancel()
+
+		var payload *SetSlugRequest
+		defer r.Body.Close()
+
+		ownerID, err := uuid.Parse(chi.URLParam(r, idParam))
+		if err != nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		if err = handlerutils.ParseJSON(r, &payload); err != nil || payload == nil {
+			return servererrors.New(
+				http.StatusBadRequest,
+				servererrors.ErrInvalidRequestPayload.Error(),
+				nil,
+			)
+		}
+
+		if err = validate.StructFields(payload); err != nil {
+			return servererrors.New(
+				http.StatusUnprocessableEntity,
+				servererrors.ErrValidationFailed.Error(),
+				err,
+			)
+		}
+
+		slug, err := set(ctx, ownerID, chi.URLParam(r, "locale"), payload)
+		if err != nil {
+			return mapError(err)
+		}
+
+		return handlerutils.WriteSuccessJSON(
+			w,
+			http.StatusOK,
+			"slug updated",
+			slug,
+		)
+	}
+}
+
+// SlugError maps the errors of SlugService to responses, other errors are
+// returned as they are.
+func SlugError(err error) error {
+	switch {
+	case errors.Is(err, servererrors.ErrSlugAlreadyExists):
+		return servererrors.New(
+			http.StatusConflict,
+			servererrors.ErrSlugAlreadyExists.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidSlug):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidSlug.Error(),
+			nil,
+		)
+	case errors.Is(err, servererrors.ErrInvalidLocale):
+		return servererrors.New(
+			http.StatusUnprocessableEntity,
+			servererrors.ErrInvalidLocale.Error(),
+			nil,
+		)
+	default:
+		return err
+	}
+}
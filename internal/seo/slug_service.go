@@ -0,0 +1,110 @@
+package seo
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+// SlugStorer keeps the slug history of one kind of page, see SlugStore.
+type SlugStorer interface {
+	FindSlug(ctx context.Context, locale string, slug string) (*Slug, error)
+	FindSlugs(ctx context.Context, ownerID uuid.UUID) ([]*Slug, error)
+	SetSlug(ctx context.Context, ownerID uuid.UUID, locale string, slug string) error
+}
+
+// SlugService resolves and changes the slugs of one kind of page. It does not
+// know the pages themselves, callers check that the page exists first.
+type SlugService struct {
+	slugStore SlugStorer
+	// fallback is the base of the slugs generated from a title without any
+	// letter or digit, e.g. "product"
+	fallback string
+}
+
+func NewSlugService(slugStore SlugStorer, fallback string) *SlugService {
+	return &SlugService{
+		slugStore: slugStore,
+		fallback:  fallback,
+	}
+}
+
+// Resolve returns the slug of a locale, current or not, along with the
+// normalized locale. The Slug is zero when no page has it.
+func (s *SlugService) Resolve(ctx context.Context, locale string, slug string) (*Slug, string, error) {
+	locale, err := ParseLocale(locale)
+	if err != nil {
+		return nil, "", servererrors.ErrInvalidLocale
+	}
+
+	found, err := s.slugStore.FindSlug(ctx, locale, strings.ToLower(slug))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return found, locale, nil
+}
+
+// List returns the current and previous slugs of a page in every locale.
+func (s *SlugService) List(ctx context.Context, ownerID uuid.UUID) ([]*Slug, error) {
+	return s.slugStore.FindSlugs(ctx, ownerID)
+}
+
+// Set changes the current slug of a page in a locale, the previous one keeps
+// redirecting to it. name is the page's name, to generate a slug from when
+// the payload has neither a slug nor a title.
+func (s *SlugService) Set(ctx context.Context, ownerID uuid.UUID, name string, locale string, payload *SetSlugRequest) (*Slug, error) {
+	locale, err := ParseLocale(locale)
+	if err != nil {
+		return nil, servererrors.ErrInvalidLocale
+	}
+
+	var slug string
+	if strings.TrimSpace(payload.Slug) != "" {
+		if slug = Slugify(payload.Slug); slug == "" {
+			return nil, servererrors.ErrInvalidSlug
+		}
+
+		if err = s.slugStore.SetSlug(ctx, ownerID, locale, slug); err != nil {
+			return nil, err
+		}
+	} else {
+		title := strings.TrimSpace(payload.Title)
+		if title == "" {
+			title = name
+		}
+
+		if slug, err = s.Assign(ctx, ownerID, locale, title); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.slugStore.FindSlug(ctx, locale, slug)
+}
+
+// Assign makes the first free slug generated from title the current slug of
+// a page in a locale and returns it.
+func (s *SlugService) Assign(ctx context.Context, ownerID uuid.UUID, locale string, title string) (string, error) {
+	base := Slugify(title)
+	if base == "" {
+		base = s.fallback
+	}
+
+	for _, slug := range Candidates(base, ownerID) {
+		err := s.slugStore.SetSlug(ctx, ownerID, locale, slug)
+		if errors.Is(err, servererrors.ErrSlugAlreadyExists) {
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		return slug, nil
+	}
+
+	return "", servererrors.ErrSlugAlreadyExists
+}
@@ -0,0 +1,216 @@
+package seo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the postgres error code raised by unique constraints.
+const uniqueViolation = "23505"
+
+// SlugStore keeps the slug history of one kind of page in table, e.g.
+// product_slugs, whose ownerColumn, e.g. product_id, names the page. The
+// table has the locale, slug, is_current and created_at columns and is
+// unique on locale and slug.
+type SlugStore struct {
+	db          *sql.DB
+	table       string
+	ownerColumn string
+}
+
+func NewSlugStore(db *sql.DB, table string, ownerColumn string) *SlugStore {
+	return &SlugStore{
+		db:          db,
+		table:       table,
+		ownerColumn: ownerColumn,
+	}
+}
+
+// FindSlug returns the slug of a locale, current or not, a zero Slug when
+// there is none.
+func (s *SlugStore) FindSlug(ctx context.Context, locale string, slug string) (*Slug, error) {
+	found := new(Slug)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s, locale, slug, is_current, created_at FROM %s WHERE locale = $1 AND slug = $2", s.ownerColumn, s.table),
+		locale,
+		slug,
+	).Scan(
+		&found.OwnerID,
+		&found.Locale,
+		&found.Slug,
+		&found.Current,
+		&found.CreatedAt,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"failed to find slug in %s store: %w",
+			s.table,
+			err,
+		)
+	}
+
+	return found, nil
+}
+
+// FindSlugs returns every slug of a page, by locale with the current one
+// first.
+func (s *SlugStore) FindSlugs(ctx context.Context, ownerID uuid.UUID) ([]*Slug, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s, locale, slug, is_current, created_at FROM %s WHERE %s = $1 ORDER BY locale, is_current DESC, created_at DESC, slug", s.ownerColumn, s.table, s.ownerColumn),
+		ownerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to find slugs in %s store: %w",
+			s.table,
+			err,
+		)
+	}
+	defer rows.Close()
+
+	slugs := []*Slug{}
+	for rows.Next() {
+		slug := new(Slug)
+		if err = rows.Scan(
+			&slug.OwnerID,
+			&slug.Locale,
+			&slug.Slug,
+			&slug.Current,
+			&slug.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into slug in %s store: %w",
+				s.table,
+				err,
+			)
+		}
+
+		slugs = append(slugs, slug)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate slug rows in %s store: %w",
+			s.table,
+			err,
+		)
+	}
+
+	return slugs, nil
+}
+
+// SetSlug makes slug the current slug of a page in a locale, the slug it
+// replaces is kept to redirect from. A slug the page had before becomes
+// current again, one of another page is never taken over and returns
+// servererrors.ErrSlugAlreadyExists.
+func (s *SlugStore) SetSlug(ctx context.Context, ownerID uuid.UUID, locale string, slug string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction in %s store: %w", s.table, err)
+	}
+	defer tx.Rollback()
+
+	var currentOwnerID uuid.UUID
+	err = tx.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE locale = $1 AND slug = $2 FOR UPDATE", s.ownerColumn, s.table),
+		locale,
+		slug,
+	).Scan(&currentOwnerID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to lock slug in %s store: %w", s.table, err)
+	}
+
+	if currentOwnerID != uuid.Nil && currentOwnerID != ownerID {
+		return servererrors.ErrSlugAlreadyExists
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		fmt.Sprintf("UPDATE %s SET is_current = FALSE WHERE %s = $1 AND locale = $2 AND is_current AND slug <> $3", s.table, s.ownerColumn),
+		ownerID,
+		locale,
+		slug,
+	); err != nil {
+		return fmt.Errorf("failed to retire slug in %s store: %w", s.table, err)
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		fmt.Sprintf("INSERT INTO %s(locale, slug, %s) VALUES($1, $2, $3) ON CONFLICT (locale, slug) DO UPDATE SET is_current = TRUE", s.table, s.ownerColumn),
+		locale,
+		slug,
+		ownerID,
+	); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return servererrors.ErrSlugAlreadyExists
+		}
+
+		return fmt.Errorf("failed to insert slug in %s store: %w", s.table, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction in %s store: %w", s.table, err)
+	}
+
+	return nil
+}
+
+// CurrentSlugs returns the current slug of each page by locale, in one query.
+func (s *SlugStore) CurrentSlugs(ctx context.Context, ownerIDs []uuid.UUID) (map[uuid.UUID]map[string]string, error) {
+	slugs := make(map[uuid.UUID]map[string]string, len(ownerIDs))
+	if len(ownerIDs) == 0 {
+		return slugs, nil
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT %s, locale, slug FROM %s WHERE %s = ANY($1) AND is_current", s.ownerColumn, s.table, s.ownerColumn),
+		pq.Array(ownerIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to query current slugs in %s store: %w",
+			s.table,
+			err,
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ownerID uuid.UUID
+		var locale, slug string
+		if err = rows.Scan(&ownerID, &locale, &slug); err != nil {
+			return nil, fmt.Errorf(
+				"failed to scan row into current slug in %s store: %w",
+				s.table,
+				err,
+			)
+		}
+
+		if slugs[ownerID] == nil {
+			slugs[ownerID] = map[string]string{}
+		}
+		slugs[ownerID][locale] = slug
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate current slug rows in %s store: %w",
+			s.table,
+			err,
+		)
+	}
+
+	return slugs, nil
+}
@@ -18,12 +18,109 @@ var (
 	ErrExpiredRefreshToken   = errors.New("refresh token expired")
 	ErrInternalServerError   = errors.New("internal server error")
 	ErrSessionNotFound       = errors.New("session not found")
+	ErrSessionIdleTimeout    = errors.New("session expired due to inactivity")
+	ErrSessionMaxAgeExceeded = errors.New("session reached its maximum age")
 	ErrUnauthorizedAccess    = errors.New("unauthorized access")
 	ErrUnauthorized          = errors.New("unauthorized")
 	ErrForbiddenAccess       = errors.New("forbidden access")
+	ErrClientCertRequired    = errors.New("client certificate required")
+	ErrReauthRequired        = errors.New("recent authentication required")
 	ErrRequestTimeout        = errors.New("request timeout")
 	ErrNoRefreshTokenCookie  = errors.New("missing refresh token cookie")
+	ErrNoAccessTokenCookie   = errors.New("missing access token cookie")
+	ErrRevokedAccessToken    = errors.New("access token revoked")
 	ErrProductAlreadyExists  = errors.New("product already exists")
+	ErrProductNotFound       = errors.New("product not found")
+	ErrInvalidQueryParams    = errors.New("invalid query parameters")
+	ErrUnsupportedCurrency   = errors.New("unsupported currency")
+	ErrInvalidPrices         = errors.New("prices must be non negative with one per currency other than the base currency")
+	ErrInvalidSchedule       = errors.New("schedule times must be in the future and publish before unpublishing")
+	ErrInvalidStatusChange   = errors.New("product status can not change this way, only unpublished products are scheduled to publish and only published ones to unpublish")
+
+	ErrVariantNotFound       = errors.New("variant not found")
+	ErrVariantAlreadyExists  = errors.New("variant sku or barcode already exists")
+	ErrInvalidProductOptions = errors.New("invalid product options")
+	ErrTooManyVariants       = errors.New("too many variants")
+
+	ErrCategoryNotFound       = errors.New("category not found")
+	ErrCategoryAlreadyExists  = errors.New("category with this name already exists under the parent")
+	ErrCategoryHasChildren    = errors.New("category has subcategories")
+	ErrInvalidCategoryMove    = errors.New("category can not be moved under itself or its descendants")
+	ErrInvalidCategoryOrder   = errors.New("category order must list every sibling exactly once")
+	ErrAttributeNotFound      = errors.New("attribute not found")
+	ErrAttributeAlreadyExists = errors.New("attribute code already used by the category, its ancestors or descendants")
+	ErrInvalidAttribute       = errors.New("invalid attribute, codes are lower case, enums need values and only numbers take a unit")
+
+	ErrCustomerGroupNotFound      = errors.New("customer group not found")
+	ErrCustomerGroupAlreadyExists = errors.New("customer group already exists")
+	ErrPriceListNotFound          = errors.New("price list not found")
+	ErrInvalidPriceList           = errors.New("price list must end after it starts")
+	ErrInvalidPriceListEntries    = errors.New("price list entries must have one amount per product, variant and min quantity")
+	ErrNoPriceForCurrency         = errors.New("product has no price in the requested currency")
+
+	ErrReviewNotFound      = errors.New("review not found")
+	ErrReviewAlreadyExists = errors.New("product already reviewed")
+	ErrOwnReviewVote       = errors.New("can not vote on your own review")
+
+	ErrInvalidRecommendationOverrides = errors.New("pinned and excluded products must differ from each other and from the product")
+
+	ErrSlugAlreadyExists = errors.New("slug already in use in this locale")
+	ErrInvalidSlug       = errors.New("invalid slug, it needs at least one letter or digit")
+	ErrInvalidLocale     = errors.New("invalid locale, use a language code with an optional region e.g. en or pt-BR")
+
+	ErrImportJobNotFound       = errors.New("import job not found")
+	ErrUnsupportedImportFormat = errors.New("unsupported import format, use csv or jsonl")
+	ErrInvalidImportFile       = errors.New("invalid import file")
+
+	ErrBundleNotFound          = errors.New("bundle not found")
+	ErrInvalidBundle           = errors.New("bundle components must be distinct variants of other products that are not bundles, mix and match bundles take single units and a pick count")
+	ErrInvalidBundleSelection  = errors.New("picks must be components of the bundle adding up to its pick count per bundle, fixed bundles take no picks")
+	ErrInsufficientStock       = errors.New("not enough stock")
+	ErrStockBelowReserved      = errors.New("stock can not drop below the quantity reserved by checkouts")
+	ErrReservationNotFound     = errors.New("reservation not found")
+	ErrReservationExpired      = errors.New("reservation expired or was released, start the checkout again")
+	ErrReservationCommitted    = errors.New("reservation is already committed")
+	ErrStockManagedByLocations = errors.New("stock of variants kept at locations is set per location")
+	ErrLocationNotFound        = errors.New("location not found")
+	ErrLocationAlreadyExists   = errors.New("a location with this code already exists")
+	ErrTransferNotFound        = errors.New("transfer not found")
+	ErrTransferNotPending      = errors.New("transfer is already completed or cancelled")
+	ErrInvalidTransfer         = errors.New("a transfer moves stock between two different locations")
+	ErrBackorderLimitReached   = errors.New("not enough stock and no more units can be backordered or preordered")
+	ErrReservationLimitReached = errors.New("too many checkouts in progress, complete or cancel one first")
+	ErrReservedQuantityLimit   = errors.New("too many units held by checkouts in progress")
+	ErrAvailabilityNotFound    = errors.New("variant has no backorder or preorder settings")
+
+	ErrSellerNotFound            = errors.New("seller not found")
+	ErrSellerAlreadyExists       = errors.New("a seller with this email or store name already exists")
+	ErrSellerNotApproved         = errors.New("seller account is not approved")
+	ErrInvalidSellerStatusChange = errors.New("seller status can not change this way, pending sellers are approved or rejected and approved ones suspended or reinstated")
+
+	ErrNotDigitalProduct    = errors.New("product is not digital")
+	ErrDigitalFileNotFound  = errors.New("file not found")
+	ErrLicenseKeysExhausted = errors.New("not enough license keys left for the product")
+	ErrInvalidDownloadLink  = errors.New("invalid or expired download link")
+	ErrDownloadUnavailable  = errors.New("download limit reached or access expired")
+
+	ErrMediaNotFound        = errors.New("media not found")
+	ErrFileTooLarge         = errors.New("file too large")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrInvalidImage         = errors.New("invalid image or dimensions out of bounds")
+	ErrInvalidMediaOrder    = errors.New("media order must list every product image exactly once")
+	ErrVariantNotForProduct = errors.New("variant does not belong to the product")
+
+	ErrInvalidPasskey           = errors.New("invalid passkey")
+	ErrPasskeyNotFound          = errors.New("passkey not found")
+	ErrPasskeyAlreadyRegistered = errors.New("passkey already registered")
+	ErrPasskeyChallengeNotFound = errors.New("passkey challenge not found or expired")
+)
+
+// Error codes returned alongside an error message when clients are expected to
+// react to a specific condition rather than just display it.
+const (
+	// CodeReauthRequired asks the client to prompt for the password or a
+	// passkey again and retry the request with the elevated access token.
+	CodeReauthRequired = "REAUTH_REQUIRED"
 )
 
 type ServerError struct {
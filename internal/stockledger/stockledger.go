@@ -0,0 +1,107 @@
+// Package stockledger records every change to the stock of variants as an
+// append-only movement. The quantities cached in variant_stock and
+// location_stock are the sums of the movements, which is what reconciliation
+// checks.
+package stockledger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/google/uuid"
+)
+
+// Reasons a stock movement is recorded for.
+const (
+	ReasonSale       = "sale"
+	ReasonReturn     = "return"
+	ReasonAdjustment = "adjustment"
+	ReasonTransfer   = "transfer"
+	ReasonReceipt    = "receipt"
+)
+
+// ActorSystem is recorded for changes made outside of an authenticated
+// request, e.g. from the command line.
+const ActorSystem = "system"
+
+// Movement is a change of Quantity, positive or negative, to the stock of a
+// variant. Movements at a location also change the stock of the variant at
+// that location, the others only its overall stock.
+type Movement struct {
+	MovementID uuid.UUID  `json:"movement_id"`
+	VariantID  uuid.UUID  `json:"variant_id"`
+	SKU        string     `json:"sku"`
+	LocationID *uuid.UUID `json:"location_id"`
+	Quantity   int64      `json:"quantity"`
+	Reason     string     `json:"reason"`
+	ActorType  string     `json:"actor_type"`
+	ActorID    *uuid.UUID `json:"actor_id"`
+	// Reference is what caused the movement, e.g. a reservation or transfer
+	// id
+	Reference string    `json:"reference"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Querier is the part of a *sql.Tx that Record needs.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Record appends movements to the ledger. It is called with the transaction
+// that changes the cached stock so both always move together. The actor is
+// the entity authenticated in ctx, or the system without one. Movements of
+// no quantity are skipped, the others get their sku and creation time.
+func Record(ctx context.Context, tx Querier, movements ...*Movement) error {
+	actorType, actorID := actor(ctx)
+
+	for _, movement := range movements {
+		if movement.Quantity == 0 {
+			continue
+		}
+
+		if movement.MovementID == uuid.Nil {
+			movement.MovementID = uuid.New()
+		}
+
+		movement.ActorType, movement.ActorID = actorType, actorID
+
+		err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO stock_movements(movement_id, variant_id, sku, location_id, quantity, reason, actor_type, actor_id, reference, note)
+			VALUES($1, $2, COALESCE((SELECT sku FROM product_variants WHERE variant_id = $2), ''), $3, $4, $5, $6, $7, $8, $9)
+			RETURNING sku, created_at`,
+			movement.MovementID,
+			movement.VariantID,
+			movement.LocationID,
+			movement.Quantity,
+			movement.Reason,
+			movement.ActorType,
+			movement.ActorID,
+			movement.Reference,
+			movement.Note,
+		).Scan(&movement.SKU, &movement.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record stock movement: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func actor(ctx context.Context) (string, *uuid.UUID) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return ActorSystem, nil
+	}
+
+	actorID, err := uuid.Parse(claims.EntityID)
+	if err != nil {
+		return claims.EntityType, nil
+	}
+
+	return claims.EntityType, &actorID
+}
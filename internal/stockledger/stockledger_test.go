@@ -0,0 +1,47 @@
+package stockledger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/google/uuid"
+)
+
+func TestActor(t *testing.T) {
+	adminID := uuid.New()
+
+	cases := []struct {
+		name     string
+		claims   *auth.TokenClaims
+		wantType string
+		wantID   *uuid.UUID
+	}{
+		{"no claims", nil, ActorSystem, nil},
+		{"admin", &auth.TokenClaims{EntityID: adminID.String(), EntityType: auth.EntityTypeAdmin}, auth.EntityTypeAdmin, &adminID},
+		{"malformed id", &auth.TokenClaims{EntityID: "admin", EntityType: auth.EntityTypeAdmin}, auth.EntityTypeAdmin, nil},
+	}
+
+	for _, c := range cases {
+		ctx := context.Background()
+		if c.claims != nil {
+			ctx = auth.ContextWithClaims(ctx, c.claims)
+		}
+
+		actorType, actorID := actor(ctx)
+		if actorType != c.wantType {
+			t.Errorf("%s: expected actor type %s, got %s", c.name, c.wantType, actorType)
+		}
+
+		if (actorID == nil) != (c.wantID == nil) || (actorID != nil && *actorID != *c.wantID) {
+			t.Errorf("%s: expected actor id %v, got %v", c.name, c.wantID, actorID)
+		}
+	}
+}
+
+func TestRecordSkipsEmptyMovements(t *testing.T) {
+	// a nil transaction would panic if the movement was written
+	if err := Record(context.Background(), nil, &Movement{VariantID: uuid.New(), Reason: ReasonAdjustment}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,117 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/auth"
+	"github.com/google/uuid"
+)
+
+// AuthenticateFromHeader stands in for the auth and Identify middlewares,
+// signing the request in as the user named by the X-User-ID header.
+func AuthenticateFromHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID := r.Header.Get("X-User-ID"); userID != "" {
+			claims := &auth.TokenClaims{EntityID: userID, EntityType: auth.EntityTypeUser}
+			r = r.WithContext(auth.ContextWithClaims(r.Context(), claims))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HeaderAuthenticator stands in for the auth middleware, signing the request
+// in as the entity named by the X-Entity-ID and X-Entity-Type headers when
+// its type is allowed.
+type HeaderAuthenticator struct{}
+
+func (HeaderAuthenticator) Authenticate(entityTypes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entityType := r.Header.Get("X-Entity-Type")
+			if !slices.Contains(entityTypes, entityType) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			claims := &auth.TokenClaims{EntityID: r.Header.Get("X-Entity-ID"), EntityType: entityType}
+			next.ServeHTTP(w, r.WithContext(auth.ContextWithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// Send sends a request with payload encoded as its JSON body and returns the
+// recorded response.
+func Send(t *testing.T, router http.Handler, method, path string, payload any, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, path, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+// Serve sends a request as the user userID, or anonymously when it is nil,
+// decodes the data of a successful response into data and returns the status
+// code.
+func Serve(t *testing.T, router http.Handler, method, path string, userID *uuid.UUID, payload any, data any) int {
+	t.Helper()
+
+	header := http.Header{}
+	if userID != nil {
+		header.Set("X-User-ID", userID.String())
+	}
+
+	return decode(t, Send(t, router, method, path, payload, header), data)
+}
+
+// ServeAs sends a request as the entity of entityType, or anonymously when
+// entityType is empty, decodes the data of a successful response into data
+// and returns the status code.
+func ServeAs(t *testing.T, router http.Handler, method, path string, entityType string, entityID uuid.UUID, payload any, data any) int {
+	t.Helper()
+
+	header := http.Header{}
+	if entityType != "" {
+		header.Set("X-Entity-Type", entityType)
+		header.Set("X-Entity-ID", entityID.String())
+	}
+
+	return decode(t, Send(t, router, method, path, payload, header), data)
+}
+
+func decode(t *testing.T, rr *httptest.ResponseRecorder, data any) int {
+	t.Helper()
+
+	if data != nil && rr.Code < 300 {
+		resp := struct {
+			Data any `json:"data"`
+		}{Data: data}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rr.Code
+}
@@ -0,0 +1,79 @@
+// Package testutil holds the fixtures the feature tests share.
+package testutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/seo"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/servererrors"
+	"github.com/google/uuid"
+)
+
+// SlugStore is an in-memory seo.SlugStorer, embedded in the mock stores of
+// the features with slugs.
+type SlugStore struct {
+	Slugs []*seo.Slug
+}
+
+func NewSlugStore() *SlugStore {
+	return &SlugStore{}
+}
+
+func (m *SlugStore) FindSlug(ctx context.Context, locale string, slug string) (*seo.Slug, error) {
+	for _, found := range m.Slugs {
+		if found.Locale == locale && found.Slug == slug {
+			return found, nil
+		}
+	}
+
+	return new(seo.Slug), nil
+}
+
+func (m *SlugStore) FindSlugs(ctx context.Context, ownerID uuid.UUID) ([]*seo.Slug, error) {
+	slugs := []*seo.Slug{}
+	for _, slug := range m.Slugs {
+		if slug.OwnerID == ownerID {
+			slugs = append(slugs, slug)
+		}
+	}
+
+	return slugs, nil
+}
+
+func (m *SlugStore) SetSlug(ctx context.Context, ownerID uuid.UUID, locale string, slug string) error {
+	existing, _ := m.FindSlug(ctx, locale, slug)
+	if existing.OwnerID != uuid.Nil && existing.OwnerID != ownerID {
+		return servererrors.ErrSlugAlreadyExists
+	}
+
+	for _, found := range m.Slugs {
+		if found.OwnerID == ownerID && found.Locale == locale {
+			found.Current = found.Slug == slug
+		}
+	}
+
+	if existing.OwnerID == uuid.Nil {
+		m.Slugs = append(m.Slugs, &seo.Slug{
+			OwnerID:   ownerID,
+			Locale:    locale,
+			Slug:      slug,
+			Current:   true,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	return nil
+}
+
+// Current returns the current slug of a page by locale.
+func (m *SlugStore) Current(ownerID uuid.UUID) map[string]string {
+	slugs := map[string]string{}
+	for _, slug := range m.Slugs {
+		if slug.OwnerID == ownerID && slug.Current {
+			slugs[slug.Locale] = slug.Slug
+		}
+	}
+
+	return slugs
+}
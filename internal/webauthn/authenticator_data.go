@@ -0,0 +1,100 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent    byte = 0x01
+	flagUserVerified   byte = 0x04
+	flagBackupEligible byte = 0x08
+	flagBackupState    byte = 0x10
+	flagAttestedData   byte = 0x40
+	flagExtensionData  byte = 0x80
+)
+
+const (
+	rpIDHashLength         = 32
+	minAuthDataLength      = rpIDHashLength + 1 + 4
+	aaguidLength           = 16
+	maxCredentialIDLength  = 1023
+	credentialIDLengthSize = 2
+)
+
+// authenticatorData is the parsed form of the authenticator data structure
+// signed by the authenticator in both ceremonies.
+type authenticatorData struct {
+	raw       []byte
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+
+	// only set when flagAttestedData is present
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (ad *authenticatorData) userPresent() bool    { return ad.flags&flagUserPresent != 0 }
+func (ad *authenticatorData) userVerified() bool   { return ad.flags&flagUserVerified != 0 }
+func (ad *authenticatorData) backupEligible() bool { return ad.flags&flagBackupEligible != 0 }
+func (ad *authenticatorData) backupState() bool    { return ad.flags&flagBackupState != 0 }
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < minAuthDataLength {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrMalformedCredential)
+	}
+
+	ad := &authenticatorData{
+		raw:       raw,
+		rpIDHash:  raw[:rpIDHashLength],
+		flags:     raw[rpIDHashLength],
+		signCount: binary.BigEndian.Uint32(raw[rpIDHashLength+1 : minAuthDataLength]),
+	}
+
+	rest := raw[minAuthDataLength:]
+
+	if ad.flags&flagAttestedData != 0 {
+		if len(rest) < aaguidLength+credentialIDLengthSize {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrMalformedCredential)
+		}
+
+		ad.aaguid = rest[:aaguidLength]
+		rest = rest[aaguidLength:]
+
+		idLength := int(binary.BigEndian.Uint16(rest[:credentialIDLengthSize]))
+		rest = rest[credentialIDLengthSize:]
+
+		if idLength > maxCredentialIDLength || idLength > len(rest) {
+			return nil, fmt.Errorf("%w: invalid credential id length", ErrMalformedCredential)
+		}
+
+		ad.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// the cose key length is only known after decoding it
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrMalformedCredential, err)
+		}
+
+		ad.publicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if ad.flags&flagExtensionData != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions: %v", ErrMalformedCredential, err)
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing bytes in authenticator data", ErrMalformedCredential)
+	}
+
+	return ad, nil
+}
@@ -0,0 +1,248 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// maxCBORDepth bounds the nesting of decoded CBOR items. WebAuthn structures
+// are shallow, anything deeper is malformed or hostile.
+const maxCBORDepth = 16
+
+var errCBOR = errors.New("malformed cbor")
+
+// decodeCBOR decodes the first CBOR data item in data and returns it along
+// with the number of bytes it occupied. Only the subset of CBOR used by
+// WebAuthn (CTAP2 canonical encoding) is supported: definite lengths, integer
+// and text map keys, no indefinite length items.
+//
+// Decoded values are int64, []byte, string, []any, map[any]any, bool, float64
+// or nil.
+func decodeCBOR(data []byte) (any, int, error) {
+	d := &cborDecoder{data: data}
+
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return value, d.pos, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, fmt.Errorf("%w: nesting too deep", errCBOR)
+	}
+
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("%w: unexpected end of data", errCBOR)
+	}
+
+	initial := d.data[d.pos]
+	d.pos++
+
+	majorType := initial >> 5
+	info := initial & 0x1f
+
+	if majorType == 7 {
+		return d.decodeSimple(info)
+	}
+
+	arg, err := d.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch majorType {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return int64(arg), nil
+
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return -1 - int64(arg), nil
+
+	case 2:
+		raw, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), raw...), nil
+
+	case 3:
+		raw, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(raw), nil
+
+	case 4:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("%w: array too long", errCBOR)
+		}
+
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+
+	case 5:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("%w: map too long", errCBOR)
+		}
+
+		m := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("%w: unsupported map key type %T", errCBOR, key)
+			}
+
+			if _, exists := m[key]; exists {
+				return nil, fmt.Errorf("%w: duplicate map key %v", errCBOR, key)
+			}
+
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+
+	case 6:
+		// tags carry no meaning for webauthn, return the tagged item
+		return d.decode(depth + 1)
+	}
+
+	return nil, fmt.Errorf("%w: unknown major type %d", errCBOR, majorType)
+}
+
+func (d *cborDecoder) readArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+
+	case info == 24:
+		raw, err := d.readBytes(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(raw[0]), nil
+
+	case info == 25:
+		raw, err := d.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(raw)), nil
+
+	case info == 26:
+		raw, err := d.readBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(raw)), nil
+
+	case info == 27:
+		raw, err := d.readBytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(raw), nil
+	}
+
+	return 0, fmt.Errorf("%w: indefinite or reserved length", errCBOR)
+}
+
+func (d *cborDecoder) decodeSimple(info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+
+	case 25:
+		raw, err := d.readBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat64(binary.BigEndian.Uint16(raw)), nil
+
+	case 26:
+		raw, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+
+	case 27:
+		raw, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported simple value %d", errCBOR, info)
+}
+
+func (d *cborDecoder) readBytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: unexpected end of data", errCBOR)
+	}
+
+	raw := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+
+	return raw, nil
+}
+
+func halfToFloat64(h uint16) float64 {
+	exponent := int(h>>10) & 0x1f
+	mantissa := float64(h & 0x3ff)
+
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 31:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -value
+	}
+
+	return value
+}
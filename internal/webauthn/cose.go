@@ -0,0 +1,153 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers supported by the relying party, in order of
+// preference.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// cose key parameters, see RFC 9053
+const (
+	coseKeyType   int64 = 1
+	coseAlgorithm int64 = 3
+
+	coseEC2Curve int64 = -1
+	coseEC2X     int64 = -2
+	coseEC2Y     int64 = -3
+
+	coseRSAModulus  int64 = -1
+	coseRSAExponent int64 = -2
+
+	coseKeyTypeOKP int64 = 1
+	coseKeyTypeEC2 int64 = 2
+	coseKeyTypeRSA int64 = 3
+
+	coseCurveP256    int64 = 1
+	coseCurveEd25519 int64 = 6
+)
+
+// PublicKey is a credential public key parsed from its COSE encoding.
+type PublicKey struct {
+	Algorithm int64
+	key       crypto.PublicKey
+}
+
+// ParsePublicKey parses a COSE_Key encoded credential public key.
+func ParsePublicKey(coseKey []byte) (*PublicKey, error) {
+	decoded, n, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if n != len(coseKey) {
+		return nil, fmt.Errorf("%w: trailing bytes after cose key", ErrMalformedCredential)
+	}
+
+	return parsePublicKey(decoded)
+}
+
+func parsePublicKey(decoded any) (*PublicKey, error) {
+	params, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: cose key is not a map", ErrMalformedCredential)
+	}
+
+	keyType, _ := params[coseKeyType].(int64)
+	alg, _ := params[coseAlgorithm].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && alg == AlgES256:
+		curve, _ := params[coseEC2Curve].(int64)
+		x, _ := params[coseEC2X].([]byte)
+		y, _ := params[coseEC2Y].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: invalid ec2 key", ErrMalformedCredential)
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: ec2 point is not on the curve", ErrMalformedCredential)
+		}
+
+		return &PublicKey{Algorithm: alg, key: key}, nil
+
+	case keyType == coseKeyTypeOKP && alg == AlgEdDSA:
+		curve, _ := params[coseEC2Curve].(int64)
+		x, _ := params[coseEC2X].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid okp key", ErrMalformedCredential)
+		}
+
+		return &PublicKey{Algorithm: alg, key: ed25519.PublicKey(x)}, nil
+
+	case keyType == coseKeyTypeRSA && alg == AlgRS256:
+		modulus, _ := params[coseRSAModulus].([]byte)
+		exponent, _ := params[coseRSAExponent].([]byte)
+		if len(modulus) < 256 || len(exponent) == 0 || len(exponent) > 4 {
+			return nil, fmt.Errorf("%w: invalid rsa key", ErrMalformedCredential)
+		}
+
+		e := 0
+		for _, b := range exponent {
+			e = e<<8 | int(b)
+		}
+
+		return &PublicKey{
+			Algorithm: alg,
+			key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(modulus),
+				E: e,
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: key type %d, alg %d", ErrUnsupportedAlgorithm, keyType, alg)
+}
+
+// Verify checks sig over data with the public key.
+func (pk *PublicKey) Verify(data, sig []byte) error {
+	return verifySignature(pk.key, pk.Algorithm, data, sig)
+}
+
+func verifySignature(key crypto.PublicKey, alg int64, data, sig []byte) error {
+	digest := sha256.Sum256(data)
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if alg != AlgES256 || !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case ed25519.PublicKey:
+		if alg != AlgEdDSA || !ed25519.Verify(k, data, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case *rsa.PublicKey:
+		if alg != AlgRS256 || rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	return ErrUnsupportedAlgorithm
+}
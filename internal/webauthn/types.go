@@ -0,0 +1,122 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// URLEncodedBase64 is a byte slice that is encoded as unpadded base64url in
+// JSON, the encoding used for binary values by the WebAuthn JSON API.
+type URLEncodedBase64 []byte
+
+func (e URLEncodedBase64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(e))
+}
+
+func (e *URLEncodedBase64) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(str, "="))
+	if err != nil {
+		return err
+	}
+
+	*e = decoded
+	return nil
+}
+
+// Options sent to the client
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          URLEncodedBase64 `json:"id"`
+	Name        string           `json:"name"`
+	DisplayName string           `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string           `json:"type"`
+	ID         URLEncodedBase64 `json:"id"`
+	Transports []string         `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions is the publicKey member passed to navigator.credentials.create.
+type CreationOptions struct {
+	Challenge              URLEncodedBase64       `json:"challenge"`
+	RelyingParty           RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is the publicKey member passed to navigator.credentials.get.
+type RequestOptions struct {
+	Challenge        URLEncodedBase64       `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RelyingPartyID   string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// Credentials returned by the client
+
+type AttestationResponse struct {
+	ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON" validate:"required"`
+	AttestationObject URLEncodedBase64 `json:"attestationObject" validate:"required"`
+	Transports        []string         `json:"transports"`
+}
+
+// RegistrationCredential is the PublicKeyCredential produced by
+// navigator.credentials.create, in its JSON form.
+type RegistrationCredential struct {
+	ID       string              `json:"id" validate:"required"`
+	RawID    URLEncodedBase64    `json:"rawId" validate:"required"`
+	Type     string              `json:"type" validate:"required"`
+	Response AttestationResponse `json:"response" validate:"required"`
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON" validate:"required"`
+	AuthenticatorData URLEncodedBase64 `json:"authenticatorData" validate:"required"`
+	Signature         URLEncodedBase64 `json:"signature" validate:"required"`
+	UserHandle        URLEncodedBase64 `json:"userHandle"`
+}
+
+// AssertionCredential is the PublicKeyCredential produced by
+// navigator.credentials.get, in its JSON form.
+type AssertionCredential struct {
+	ID       string            `json:"id" validate:"required"`
+	RawID    URLEncodedBase64  `json:"rawId" validate:"required"`
+	Type     string            `json:"type" validate:"required"`
+	Response AssertionResponse `json:"response" validate:"required"`
+}
+
+// collectedClientData is the decoded clientDataJSON.
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
@@ -0,0 +1,343 @@
+// Package webauthn implements the relying party side of the WebAuthn
+// registration and authentication ceremonies.
+//
+// Only the "none" and "packed" attestation formats are accepted. The relying
+// party asks for no attestation, so browsers strip it anyway; trust in the
+// credential comes from the origin bound ceremony, not from the authenticator
+// make.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	credentialType = "public-key"
+
+	challengeLength = 32
+)
+
+// user verification requirements
+const (
+	VerificationRequired  = "required"
+	VerificationPreferred = "preferred"
+)
+
+var (
+	ErrMalformedCredential    = errors.New("malformed webauthn credential")
+	ErrInvalidClientData      = errors.New("invalid client data")
+	ErrChallengeMismatch      = errors.New("challenge mismatch")
+	ErrOriginMismatch         = errors.New("origin not allowed")
+	ErrRelyingPartyMismatch   = errors.New("relying party id mismatch")
+	ErrUserNotPresent         = errors.New("user presence flag not set")
+	ErrUserNotVerified        = errors.New("user verification flag not set")
+	ErrUnsupportedAlgorithm   = errors.New("unsupported public key algorithm")
+	ErrUnsupportedAttestation = errors.New("unsupported attestation format")
+	ErrInvalidSignature       = errors.New("invalid signature")
+	ErrSignCountRegression    = errors.New("signature counter did not increase, authenticator may be cloned")
+)
+
+// RelyingParty holds the configuration of this server as a WebAuthn relying
+// party.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+	Timeout time.Duration
+}
+
+func NewRelyingParty(id, name string, origins []string) *RelyingParty {
+	return &RelyingParty{
+		ID:      id,
+		Name:    name,
+		Origins: origins,
+		Timeout: 5 * time.Minute,
+	}
+}
+
+// NewChallenge returns a fresh random ceremony challenge.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// NewCreationOptions builds the options for a registration ceremony. Passkeys
+// are always discoverable so that sign in does not need a username.
+func (rp *RelyingParty) NewCreationOptions(challenge []byte, user UserEntity, excludeCredentialIDs [][]byte, userVerification string) *CreationOptions {
+	exclude := make([]CredentialDescriptor, 0, len(excludeCredentialIDs))
+	for _, id := range excludeCredentialIDs {
+		exclude = append(exclude, CredentialDescriptor{
+			Type: credentialType,
+			ID:   id,
+		})
+	}
+
+	return &CreationOptions{
+		Challenge: challenge,
+		RelyingParty: RelyingPartyEntity{
+			ID:   rp.ID,
+			Name: rp.Name,
+		},
+		User: user,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: credentialType, Algorithm: AlgES256},
+			{Type: credentialType, Algorithm: AlgEdDSA},
+			{Type: credentialType, Algorithm: AlgRS256},
+		},
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   userVerification,
+		},
+		Attestation: "none",
+	}
+}
+
+// NewRequestOptions builds the options for an authentication ceremony. An
+// empty allowCredentialIDs lets the authenticator pick a discoverable
+// credential.
+func (rp *RelyingParty) NewRequestOptions(challenge []byte, allowCredentialIDs [][]byte, userVerification string) *RequestOptions {
+	allow := make([]CredentialDescriptor, 0, len(allowCredentialIDs))
+	for _, id := range allowCredentialIDs {
+		allow = append(allow, CredentialDescriptor{
+			Type: credentialType,
+			ID:   id,
+		})
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout.Milliseconds(),
+		RelyingPartyID:   rp.ID,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// VerifiedCredential is a credential that passed the registration ceremony
+// and can be stored.
+type VerifiedCredential struct {
+	ID             []byte
+	PublicKey      []byte // COSE encoded
+	SignCount      uint32
+	AAGUID         []byte
+	Transports     []string
+	UserVerified   bool
+	BackupEligible bool
+	BackupState    bool
+}
+
+// VerifyRegistration runs the registration ceremony checks of the WebAuthn
+// spec (section 7.1) against the challenge issued for it.
+func (rp *RelyingParty) VerifyRegistration(credential *RegistrationCredential, challenge []byte, requireUserVerification bool) (*VerifiedCredential, error) {
+	if credential.Type != credentialType {
+		return nil, fmt.Errorf("%w: unexpected type %q", ErrMalformedCredential, credential.Type)
+	}
+
+	if err := rp.verifyClientData(credential.Response.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, n, err := decodeCBOR(credential.Response.AttestationObject)
+	if err != nil || n != len(credential.Response.AttestationObject) {
+		return nil, fmt.Errorf("%w: attestation object", ErrMalformedCredential)
+	}
+
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object is not a map", ErrMalformedCredential)
+	}
+
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[any]any)
+	rawAuthData, _ := attestation["authData"].([]byte)
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.verifyAuthenticatorData(authData, requireUserVerification); err != nil {
+		return nil, err
+	}
+
+	if authData.credentialID == nil {
+		return nil, fmt.Errorf("%w: missing attested credential data", ErrMalformedCredential)
+	}
+
+	if !bytes.Equal(authData.credentialID, credential.RawID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrMalformedCredential)
+	}
+
+	publicKey, err := ParsePublicKey(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(credential.Response.ClientDataJSON)
+	signedData := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+
+	if err := verifyAttestationStatement(format, statement, publicKey, signedData); err != nil {
+		return nil, err
+	}
+
+	return &VerifiedCredential{
+		ID:             append([]byte(nil), authData.credentialID...),
+		PublicKey:      append([]byte(nil), authData.publicKey...),
+		SignCount:      authData.signCount,
+		AAGUID:         append([]byte(nil), authData.aaguid...),
+		Transports:     credential.Response.Transports,
+		UserVerified:   authData.userVerified(),
+		BackupEligible: authData.backupEligible(),
+		BackupState:    authData.backupState(),
+	}, nil
+}
+
+// VerifiedAssertion is the outcome of a successful authentication ceremony.
+type VerifiedAssertion struct {
+	SignCount    uint32
+	UserVerified bool
+	BackupState  bool
+}
+
+// VerifyAssertion runs the authentication ceremony checks of the WebAuthn
+// spec (section 7.2) against the challenge issued for it and the stored
+// credential public key and signature counter.
+func (rp *RelyingParty) VerifyAssertion(credential *AssertionCredential, challenge []byte, coseKey []byte, storedSignCount uint32, requireUserVerification bool) (*VerifiedAssertion, error) {
+	if credential.Type != credentialType {
+		return nil, fmt.Errorf("%w: unexpected type %q", ErrMalformedCredential, credential.Type)
+	}
+
+	if err := rp.verifyClientData(credential.Response.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := parseAuthenticatorData(credential.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.verifyAuthenticatorData(authData, requireUserVerification); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ParsePublicKey(coseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(credential.Response.ClientDataJSON)
+	signedData := append(append([]byte(nil), authData.raw...), clientDataHash[:]...)
+
+	if err := publicKey.Verify(signedData, credential.Response.Signature); err != nil {
+		return nil, err
+	}
+
+	// authenticators that do not implement a counter always report zero
+	if (authData.signCount != 0 || storedSignCount != 0) && authData.signCount <= storedSignCount {
+		return nil, ErrSignCountRegression
+	}
+
+	return &VerifiedAssertion{
+		SignCount:    authData.signCount,
+		UserVerified: authData.userVerified(),
+		BackupState:  authData.backupState(),
+	}, nil
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	clientData := new(collectedClientData)
+	if err := json.Unmarshal(raw, clientData); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidClientData, err)
+	}
+
+	if clientData.Type != ceremony {
+		return fmt.Errorf("%w: unexpected type %q", ErrInvalidClientData, clientData.Type)
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+
+	if !slices.Contains(rp.Origins, clientData.Origin) {
+		return fmt.Errorf("%w: %q", ErrOriginMismatch, clientData.Origin)
+	}
+
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData, requireUserVerification bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rpIDHash[:]) != 1 {
+		return ErrRelyingPartyMismatch
+	}
+
+	if !authData.userPresent() {
+		return ErrUserNotPresent
+	}
+
+	if requireUserVerification && !authData.userVerified() {
+		return ErrUserNotVerified
+	}
+
+	return nil
+}
+
+func verifyAttestationStatement(format string, statement map[any]any, credentialKey *PublicKey, signedData []byte) error {
+	switch format {
+	case "none":
+		if len(statement) != 0 {
+			return fmt.Errorf("%w: none attestation with a statement", ErrMalformedCredential)
+		}
+		return nil
+
+	case "packed":
+		alg, _ := statement["alg"].(int64)
+		sig, _ := statement["sig"].([]byte)
+
+		chain, hasChain := statement["x5c"].([]any)
+		if !hasChain {
+			// self attestation, signed with the credential key itself
+			if alg != credentialKey.Algorithm {
+				return fmt.Errorf("%w: self attestation algorithm mismatch", ErrMalformedCredential)
+			}
+			return credentialKey.Verify(signedData, sig)
+		}
+
+		// full attestation, the chain is not checked against trust anchors
+		// as attestation is not used for trust decisions
+		if len(chain) == 0 {
+			return fmt.Errorf("%w: empty x5c", ErrMalformedCredential)
+		}
+
+		rawCert, _ := chain[0].([]byte)
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return fmt.Errorf("%w: attestation certificate: %v", ErrMalformedCredential, err)
+		}
+
+		return verifySignature(cert.PublicKey, alg, signedData, sig)
+	}
+
+	return fmt.Errorf("%w: %q", ErrUnsupportedAttestation, format)
+}
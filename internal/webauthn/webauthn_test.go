@@ -0,0 +1,153 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn"
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn/webauthntest"
+)
+
+const (
+	rpID   = "localhost"
+	origin = "http://localhost:3000"
+)
+
+func newChallenge(t *testing.T) []byte {
+	t.Helper()
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return challenge
+}
+
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.VerifiedCredential {
+	t.Helper()
+
+	challenge := newChallenge(t)
+	options := rp.NewCreationOptions(
+		challenge,
+		webauthn.UserEntity{ID: []byte("user-handle"), Name: "lime@peters.com"},
+		nil,
+		webauthn.VerificationRequired,
+	)
+
+	credential, err := rp.VerifyRegistration(
+		authenticator.Register(options),
+		challenge,
+		true,
+	)
+	if err != nil {
+		t.Fatalf("expected registration to succeed, got %v", err)
+	}
+
+	return credential
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	rp := webauthn.NewRelyingParty(rpID, "Yellow Pines", []string{origin})
+	authenticator := webauthntest.New(origin)
+
+	credential := register(t, rp, authenticator)
+
+	if string(credential.ID) != string(authenticator.CredentialID) {
+		t.Fatalf("expected credential id to match the authenticator's")
+	}
+
+	signCount := credential.SignCount
+	for i := 0; i < 2; i++ {
+		challenge := newChallenge(t)
+		assertion := authenticator.Login(
+			rp.NewRequestOptions(challenge, nil, webauthn.VerificationRequired),
+		)
+
+		verified, err := rp.VerifyAssertion(assertion, challenge, credential.PublicKey, signCount, true)
+		if err != nil {
+			t.Fatalf("expected login %d to succeed, got %v", i, err)
+		}
+
+		signCount = verified.SignCount
+	}
+}
+
+func TestVerifyAssertionFailures(t *testing.T) {
+	rp := webauthn.NewRelyingParty(rpID, "Yellow Pines", []string{origin})
+	authenticator := webauthntest.New(origin)
+	credential := register(t, rp, authenticator)
+
+	testCases := []struct {
+		name     string
+		assert   func(challenge []byte) *webauthn.AssertionCredential
+		stored   uint32
+		expected error
+	}{
+		{
+			name: "should reject an assertion for another challenge",
+			assert: func(challenge []byte) *webauthn.AssertionCredential {
+				return authenticator.Login(rp.NewRequestOptions(newChallenge(t), nil, webauthn.VerificationRequired))
+			},
+			expected: webauthn.ErrChallengeMismatch,
+		},
+		{
+			name: "should reject an assertion from a phishing origin",
+			assert: func(challenge []byte) *webauthn.AssertionCredential {
+				phished := webauthntest.New("https://yellow-pines.evil")
+				return authenticator.Assert(rpID, phished.ClientData("webauthn.get", challenge))
+			},
+			expected: webauthn.ErrOriginMismatch,
+		},
+		{
+			name: "should reject an assertion for another relying party",
+			assert: func(challenge []byte) *webauthn.AssertionCredential {
+				return authenticator.Assert("evil.com", authenticator.ClientData("webauthn.get", challenge))
+			},
+			expected: webauthn.ErrRelyingPartyMismatch,
+		},
+		{
+			name: "should reject a signature counter that did not increase",
+			assert: func(challenge []byte) *webauthn.AssertionCredential {
+				return authenticator.Login(rp.NewRequestOptions(challenge, nil, webauthn.VerificationRequired))
+			},
+			stored:   100,
+			expected: webauthn.ErrSignCountRegression,
+		},
+		{
+			name: "should reject an assertion signed by another key",
+			assert: func(challenge []byte) *webauthn.AssertionCredential {
+				other := webauthntest.New(origin)
+				other.SignCount = 1000
+				return other.Assert(rpID, other.ClientData("webauthn.get", challenge))
+			},
+			expected: webauthn.ErrInvalidSignature,
+		},
+		{
+			name: "should reject an assertion without user verification",
+			assert: func(challenge []byte) *webauthn.AssertionCredential {
+				authenticator.UserVerified = false
+				defer func() { authenticator.UserVerified = true }()
+				return authenticator.Login(rp.NewRequestOptions(challenge, nil, webauthn.VerificationRequired))
+			},
+			expected: webauthn.ErrUserNotVerified,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			challenge := newChallenge(t)
+
+			_, err := rp.VerifyAssertion(
+				tc.assert(challenge),
+				challenge,
+				credential.PublicKey,
+				tc.stored,
+				true,
+			)
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected error %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
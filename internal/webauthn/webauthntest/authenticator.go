@@ -0,0 +1,208 @@
+// Package webauthntest provides a software authenticator for exercising the
+// WebAuthn ceremonies in tests without a browser or a security key.
+package webauthntest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/eng-by-sjb/yellow-pines-e-commerce-backend/internal/webauthn"
+)
+
+// Authenticator is an ES256 platform authenticator holding a single
+// discoverable credential.
+type Authenticator struct {
+	Origin       string
+	CredentialID []byte
+	UserHandle   []byte
+	SignCount    uint32
+
+	// UserVerified controls the UV flag of produced authenticator data.
+	UserVerified bool
+
+	key *ecdsa.PrivateKey
+}
+
+func New(origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		panic(err)
+	}
+
+	return &Authenticator{
+		Origin:       origin,
+		CredentialID: credentialID,
+		UserVerified: true,
+		key:          key,
+	}
+}
+
+// Register answers a registration ceremony with a "none" attestation.
+func (a *Authenticator) Register(options *webauthn.CreationOptions) *webauthn.RegistrationCredential {
+	a.UserHandle = options.User.ID
+
+	clientDataJSON := a.clientData("webauthn.create", options.Challenge)
+
+	attestedData := make([]byte, 16) // zero aaguid
+	attestedData = binary.BigEndian.AppendUint16(attestedData, uint16(len(a.CredentialID)))
+	attestedData = append(attestedData, a.CredentialID...)
+	attestedData = append(attestedData, a.PublicKey()...)
+
+	authData := a.authenticatorData(options.RelyingParty.ID, 0x40)
+	authData = append(authData, attestedData...)
+
+	attestationObject := encode(map[any]any{
+		"fmt":      "none",
+		"attStmt":  map[any]any{},
+		"authData": authData,
+	})
+
+	return &webauthn.RegistrationCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: webauthn.AttestationResponse{
+			ClientDataJSON:    clientDataJSON,
+			AttestationObject: attestationObject,
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+// Login answers an authentication ceremony, incrementing the signature
+// counter.
+func (a *Authenticator) Login(options *webauthn.RequestOptions) *webauthn.AssertionCredential {
+	a.SignCount++
+
+	return a.Assert(options.RelyingPartyID, a.clientData("webauthn.get", options.Challenge))
+}
+
+// Assert signs the given client data without touching the signature counter,
+// which allows tests to forge replays and cloned authenticators.
+func (a *Authenticator) Assert(rpID string, clientDataJSON []byte) *webauthn.AssertionCredential {
+	authData := a.authenticatorData(rpID, 0)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return &webauthn.AssertionCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: webauthn.AssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        a.UserHandle,
+		},
+	}
+}
+
+// ClientData returns the clientDataJSON a browser on Origin would produce.
+func (a *Authenticator) ClientData(ceremony string, challenge []byte) []byte {
+	return a.clientData(ceremony, challenge)
+}
+
+// PublicKey returns the COSE encoding of the credential public key.
+func (a *Authenticator) PublicKey() []byte {
+	return encode(map[any]any{
+		int64(1):  int64(2),  // kty: EC2
+		int64(3):  int64(-7), // alg: ES256
+		int64(-1): int64(1),  // crv: P-256
+		int64(-2): a.key.X.FillBytes(make([]byte, 32)),
+		int64(-3): a.key.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) []byte {
+	clientDataJSON, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return clientDataJSON
+}
+
+func (a *Authenticator) authenticatorData(rpID string, extraFlags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	flags := byte(0x01) | extraFlags // user present
+	if a.UserVerified {
+		flags |= 0x04
+	}
+
+	authData := append([]byte(nil), rpIDHash[:]...)
+	authData = append(authData, flags)
+	authData = binary.BigEndian.AppendUint32(authData, a.SignCount)
+
+	return authData
+}
+
+// encode is a minimal CBOR encoder for the value types used above.
+func encode(value any) []byte {
+	buf := new(bytes.Buffer)
+	encodeInto(buf, value)
+	return buf.Bytes()
+}
+
+func encodeInto(buf *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case int64:
+		if v >= 0 {
+			writeHead(buf, 0, uint64(v))
+		} else {
+			writeHead(buf, 1, uint64(-1-v))
+		}
+	case []byte:
+		writeHead(buf, 2, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case map[any]any:
+		writeHead(buf, 5, uint64(len(v)))
+		for key, item := range v {
+			encodeInto(buf, key)
+			encodeInto(buf, item)
+		}
+	default:
+		panic("webauthntest: unsupported cbor value")
+	}
+}
+
+func writeHead(buf *bytes.Buffer, majorType byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(majorType<<5 | byte(arg))
+	case arg <= 0xff:
+		buf.WriteByte(majorType<<5 | 24)
+		buf.WriteByte(byte(arg))
+	case arg <= 0xffff:
+		buf.WriteByte(majorType<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	default:
+		buf.WriteByte(majorType<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	}
+}